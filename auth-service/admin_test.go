@@ -1,8 +1,14 @@
 package main
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/gin-gonic/gin"
 )
 
 func TestConsumeWipeNonce(t *testing.T) {
@@ -30,3 +36,82 @@ func TestConsumeWipeNonce(t *testing.T) {
 		t.Fatal("expected unknown nonce to be rejected")
 	}
 }
+
+func TestMakeUserAdmin_LastAdminGuard(t *testing.T) {
+	withTestDB(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	admin := r.Group("/admin", authMiddleware(), adminMiddleware())
+	admin.POST("/users/:user_id/admin", makeUserAdminHandler)
+	setAdmin := func(token string, id uint, body string) *httptest.ResponseRecorder {
+		return auditRequest(r, http.MethodPost, fmt.Sprintf("/admin/users/%d/admin", id), token, body)
+	}
+	isAdmin := func(id uint) bool {
+		var u User
+		db.First(&u, id)
+		return u.IsAdmin
+	}
+
+	first, firstToken := createAuditTestUser(t, "first-admin", true)
+	second, _ := createAuditTestUser(t, "second-admin", true)
+
+	// Demoting a non-last admin is fine.
+	if w := setAdmin(firstToken, second.ID, `{"is_admin":false}`); w.Code != http.StatusOK || isAdmin(second.ID) {
+		t.Fatalf("demote second admin: %d %s", w.Code, w.Body)
+	}
+
+	// Self-demotion needs confirmation; the last admin can't be demoted even then.
+	if w := setAdmin(firstToken, first.ID, `{"is_admin":false}`); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "confirm_required") {
+		t.Fatalf("unconfirmed self-demotion: %d %s", w.Code, w.Body)
+	}
+	if w := setAdmin(firstToken, first.ID, `{"is_admin":false,"confirm":true}`); w.Code != http.StatusConflict || !isAdmin(first.ID) {
+		t.Fatalf("demote last admin: %d %s", w.Code, w.Body)
+	}
+
+	// With another admin in place, a confirmed self-demotion goes through.
+	if w := setAdmin(firstToken, second.ID, `{"is_admin":true}`); w.Code != http.StatusOK {
+		t.Fatalf("re-promote: %d %s", w.Code, w.Body)
+	}
+	if w := setAdmin(firstToken, first.ID, `{"is_admin":false,"confirm":true}`); w.Code != http.StatusOK || isAdmin(first.ID) {
+		t.Fatalf("confirmed self-demotion: %d %s", w.Code, w.Body)
+	}
+
+	if w := setAdmin(firstToken, 999999, `{"is_admin":true}`); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown user: %d", w.Code)
+	}
+}
+
+// is_admin is a *bool: an explicit false is a demotion, only a missing field
+// is rejected.
+func TestMakeUserAdmin_RequiresIsAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/admin/users/:user_id/admin", makeUserAdminHandler)
+	for _, body := range []string{`{}`, `{"confirm":true}`, `{"is_admin":"no"}`} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/users/5/admin", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", body, w.Code)
+		}
+	}
+}
+
+func TestMakeUserAdmin_FalseDemotes(t *testing.T) {
+	withTestDB(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	admin := r.Group("/admin", authMiddleware(), adminMiddleware())
+	admin.POST("/users/:user_id/admin", makeUserAdminHandler)
+
+	_, token := createAuditTestUser(t, "demoter", true)
+	target, _ := createAuditTestUser(t, "demoted", true)
+	w := auditRequest(r, http.MethodPost, fmt.Sprintf("/admin/users/%d/admin", target.ID), token, `{"is_admin": false}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"is_admin":false`) {
+		t.Fatalf("demote: %d %s", w.Code, w.Body)
+	}
+	var u User
+	db.First(&u, target.ID)
+	if u.IsAdmin {
+		t.Error("user is still admin")
+	}
+}
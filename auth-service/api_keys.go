@@ -0,0 +1,330 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"gorm.io/gorm"
+)
+
+// ---- Per-user API keys ----
+//
+// A user can mint long-lived keys for scripts and integrations and send one
+// as X-API-Key instead of a Bearer JWT. Only the SHA-256 of a key is stored;
+// the key itself is returned once, at creation. A key carries scopes —
+// "read" allows GET/HEAD, "write" everything else — never admin rights, and
+// is revoked by stamping revoked_at (the row stays for the audit trail).
+//
+// content-service resolves keys through POST /internal/api-keys/verify.
+
+const (
+	apiKeyHeader      = "X-API-Key"
+	apiKeyPrefix      = "sak_" // makes a leaked key easy to grep for
+	apiKeyDisplayLen  = len(apiKeyPrefix) + 8
+	maxAPIKeysPerUser = 10
+
+	apiKeyScopeRead  = "read"
+	apiKeyScopeWrite = "write"
+)
+
+// APIKey is one user-issued key. KeyHash is hex(sha256(key)); Prefix is the
+// first few characters of the key so users can tell their keys apart.
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index;not null" json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `gorm:"uniqueIndex;not null" json:"-"`
+	Scopes     string     `gorm:"not null" json:"scopes"` // comma-separated, e.g. "read,write"
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `gorm:"index" json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+var errAPIKeyInvalid = errors.New("invalid or revoked API key")
+
+// hashAPIKey is the stored form of a key.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateAPIKey returns a new random key.
+func generateAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + hex.EncodeToString(b), nil
+}
+
+// normalizeAPIKeyScopes validates requested scopes and returns them sorted
+// and deduplicated. No scopes means both.
+func normalizeAPIKeyScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return []string{apiKeyScopeRead, apiKeyScopeWrite}, nil
+	}
+	seen := map[string]bool{}
+	var out []string
+	for _, s := range scopes {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s != apiKeyScopeRead && s != apiKeyScopeWrite {
+			return nil, errors.New("scopes must be read and/or write")
+		}
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// scopeList splits the stored Scopes column.
+func (k APIKey) scopeList() []string {
+	if k.Scopes == "" {
+		return nil
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+// apiKeyScopeFor is the scope a request method needs.
+func apiKeyScopeFor(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return apiKeyScopeRead
+	}
+	return apiKeyScopeWrite
+}
+
+// apiKeyAllows reports whether scopes permit method.
+func apiKeyAllows(scopes []string, method string) bool {
+	need := apiKeyScopeFor(method)
+	for _, s := range scopes {
+		if s == need {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveAPIKey looks up an unrevoked key and its owner, and records the use.
+func resolveAPIKey(key string) (*APIKey, *User, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, nil, errAPIKeyInvalid
+	}
+	var k APIKey
+	if err := db.Where("key_hash = ? AND revoked_at IS NULL", hashAPIKey(key)).First(&k).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errAPIKeyInvalid
+		}
+		return nil, nil, err
+	}
+	var user User
+	if err := db.First(&user, k.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errAPIKeyInvalid
+		}
+		return nil, nil, err
+	}
+	now := time.Now()
+	if err := db.Model(&APIKey{}).Where("id = ?", k.ID).UpdateColumn("last_used_at", now).Error; err != nil {
+		log.Printf("⚠️ api key %d: failed to record use: %v", k.ID, err)
+	}
+	k.LastUsedAt = &now
+	return &k, &user, nil
+}
+
+// apiKeyClaims are the claims a key-authenticated request runs with — the
+// same shape handlers read from a JWT, minus is_admin.
+func apiKeyClaims(k *APIKey, user *User) jwt.MapClaims {
+	scopes := make([]interface{}, 0, len(k.scopeList()))
+	for _, s := range k.scopeList() {
+		scopes = append(scopes, s)
+	}
+	return jwt.MapClaims{
+		"user_id":      float64(user.ID),
+		"username":     user.Username,
+		"account_type": effectiveAccountType(user),
+		"api_key_id":   float64(k.ID),
+		"scopes":       scopes,
+	}
+}
+
+// apiKeyID is the api_key_id claim of a key-authenticated request, or 0.
+func apiKeyID(c *gin.Context) uint {
+	if claims, ok := c.Get("claims"); ok {
+		if mc, ok := claims.(jwt.MapClaims); ok {
+			if f, ok := mc["api_key_id"].(float64); ok {
+				return uint(f)
+			}
+		}
+	}
+	return 0
+}
+
+// authenticateAPIKey is authMiddleware's X-API-Key branch.
+func authenticateAPIKey(c *gin.Context, key string) {
+	k, user, err := resolveAPIKey(key)
+	if err != nil {
+		if !errors.Is(err, errAPIKeyInvalid) {
+			log.Printf("❌ api key lookup: %v", err)
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	if !apiKeyAllows(k.scopeList(), c.Request.Method) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API key lacks the " + apiKeyScopeFor(c.Request.Method) + " scope"})
+		return
+	}
+	c.Set("claims", apiKeyClaims(k, user))
+	c.Set("user_id", user.ID)
+	c.Next()
+}
+
+// denyAPIKey rejects key-authenticated requests on routes that need an
+// interactive login — above all key management, so a leaked key can't mint
+// more keys or revoke the owner's others. It runs after authMiddleware.
+func denyAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiKeyID(c) != 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Not allowed with an API key"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// createAPIKeyHandler mints a key. The plaintext is in this response only.
+// POST /user/api-keys {"name": "...", "scopes": ["read"]}
+func createAPIKeyHandler(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	var req struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required (max 100 characters)"})
+		return
+	}
+	scopes, err := normalizeAPIKeyScopes(req.Scopes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var active int64
+	if err := db.Model(&APIKey{}).Where("user_id = ? AND revoked_at IS NULL", userID).Count(&active).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+	if active >= maxAPIKeysPerUser {
+		c.JSON(http.StatusConflict, gin.H{"error": "API key limit reached; revoke one first", "limit": maxAPIKeysPerUser})
+		return
+	}
+
+	key, err := generateAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+	k := APIKey{
+		UserID:  userID,
+		Name:    name,
+		Prefix:  key[:apiKeyDisplayLen],
+		KeyHash: hashAPIKey(key),
+		Scopes:  strings.Join(scopes, ","),
+	}
+	if err := db.Create(&k).Error; err != nil {
+		log.Printf("❌ Failed to create API key for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+	recordAudit(c, auditAPIKeyCreate, userID, gin.H{"api_key_id": k.ID, "scopes": k.Scopes})
+	c.JSON(http.StatusCreated, gin.H{
+		"api_key": k,
+		"key":     key,
+		"message": "Store this key now; it will not be shown again",
+	})
+}
+
+// listAPIKeysHandler lists the caller's keys, newest first, revoked included.
+// GET /user/api-keys
+func listAPIKeysHandler(c *gin.Context) {
+	keys := []APIKey{}
+	if err := db.Where("user_id = ?", c.GetUint("user_id")).Order("id DESC").Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list API keys"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// revokeAPIKeyHandler revokes one of the caller's keys. Revoking an already
+// revoked key is a no-op; another user's key is a 404.
+// DELETE /user/api-keys/:key_id
+func revokeAPIKeyHandler(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	keyID, err := strconv.ParseUint(c.Param("key_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid key ID"})
+		return
+	}
+	var k APIKey
+	if err := db.Where("id = ? AND user_id = ?", keyID, userID).First(&k).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	if k.RevokedAt == nil {
+		now := time.Now()
+		if err := db.Model(&k).Update("revoked_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+			return
+		}
+		k.RevokedAt = &now
+		recordAudit(c, auditAPIKeyRevoke, userID, gin.H{"api_key_id": k.ID})
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked", "api_key": k})
+}
+
+// internalVerifyAPIKeyHandler resolves a key for content-service.
+// POST /internal/api-keys/verify {"key": "..."}
+func internalVerifyAPIKeyHandler(c *gin.Context) {
+	var req struct {
+		Key string `json:"key"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "key is required"})
+		return
+	}
+	k, user, err := resolveAPIKey(req.Key)
+	if errors.Is(err, errAPIKeyInvalid) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("❌ api key lookup: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "lookup failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"user_id":      user.ID,
+		"account_type": effectiveAccountType(user),
+		"api_key_id":   k.ID,
+		"scopes":       k.scopeList(),
+	})
+}
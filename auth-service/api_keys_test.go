@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyRouter mirrors the /user wiring in main for key management, plus a
+// read and a write route to authenticate against.
+func apiKeyRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ok := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"user_id": c.GetUint("user_id")}) }
+	user := r.Group("/user", authMiddleware())
+	user.GET("/profile", ok)
+	user.POST("/visibility", ok)
+	user.POST("/delete", denyImpersonated(), denyAPIKey(), ok)
+	user.GET("/api-keys", denyAPIKey(), listAPIKeysHandler)
+	user.POST("/api-keys", denyImpersonated(), denyAPIKey(), createAPIKeyHandler)
+	user.DELETE("/api-keys/:key_id", denyImpersonated(), denyAPIKey(), revokeAPIKeyHandler)
+	r.GET("/admin/stats", authMiddleware(), adminMiddleware(), ok)
+	return r
+}
+
+func doWithAPIKey(r http.Handler, method, path, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set(apiKeyHeader, key)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestNormalizeAPIKeyScopes(t *testing.T) {
+	cases := []struct {
+		in   []string
+		want []string
+		ok   bool
+	}{
+		{nil, []string{"read", "write"}, true},
+		{[]string{"read"}, []string{"read"}, true},
+		{[]string{" Write ", "read", "write"}, []string{"read", "write"}, true},
+		{[]string{"admin"}, nil, false},
+	}
+	for _, tc := range cases {
+		got, err := normalizeAPIKeyScopes(tc.in)
+		if (err == nil) != tc.ok || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("normalizeAPIKeyScopes(%q) = %q, %v", tc.in, got, err)
+		}
+	}
+	if !apiKeyAllows([]string{"read"}, http.MethodHead) || apiKeyAllows([]string{"read"}, http.MethodDelete) {
+		t.Error("read scope must allow HEAD and refuse DELETE")
+	}
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	withTestDB(t)
+	owner := User{Username: "scripter", Email: "scripter@example.com", AccountType: "paid", IsAdmin: true}
+	if err := db.Create(&owner).Error; err != nil {
+		t.Fatal(err)
+	}
+	token, err := generateJWTToken(&owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := apiKeyRouter()
+
+	// Create: the plaintext comes back once; only its hash is stored.
+	req := httptest.NewRequest(http.MethodPost, "/user/api-keys", strings.NewReader(`{"name":"backup script"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create = %d %s", w.Code, w.Body)
+	}
+	var created struct {
+		Key    string `json:"key"`
+		APIKey APIKey `json:"api_key"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if !strings.HasPrefix(created.Key, apiKeyPrefix) || created.APIKey.Scopes != "read,write" ||
+		!strings.HasPrefix(created.Key, created.APIKey.Prefix) {
+		t.Fatalf("created key %q, record %+v", created.Key, created.APIKey)
+	}
+	if strings.Contains(w.Body.String(), hashAPIKey(created.Key)) {
+		t.Fatal("key hash leaked in the response")
+	}
+	var stored APIKey
+	db.First(&stored, created.APIKey.ID)
+	if stored.KeyHash != hashAPIKey(created.Key) || strings.Contains(stored.KeyHash, created.Key) {
+		t.Fatalf("stored hash %q", stored.KeyHash)
+	}
+
+	// Authenticate: the key resolves to its owner, without admin rights and
+	// without access to account lifecycle or key management.
+	if w := doWithAPIKey(r, http.MethodGet, "/user/profile", created.Key); w.Code != http.StatusOK ||
+		w.Body.String() != `{"user_id":`+fmt.Sprint(owner.ID)+`}` {
+		t.Fatalf("profile with key = %d %s", w.Code, w.Body)
+	}
+	if w := doWithAPIKey(r, http.MethodPost, "/user/visibility", created.Key); w.Code != http.StatusOK {
+		t.Fatalf("write with a read,write key = %d", w.Code)
+	}
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/admin/stats"},
+		{http.MethodPost, "/user/delete"},
+		{http.MethodPost, "/user/api-keys"},
+		{http.MethodGet, "/user/api-keys"},
+	} {
+		if w := doWithAPIKey(r, route.method, route.path, created.Key); w.Code != http.StatusForbidden {
+			t.Errorf("%s %s with key = %d, want 403", route.method, route.path, w.Code)
+		}
+	}
+	db.First(&stored, created.APIKey.ID)
+	if stored.LastUsedAt == nil {
+		t.Error("last_used_at not recorded")
+	}
+
+	// Revoke: the key stops working immediately; a second revoke is a no-op.
+	path := "/user/api-keys/" + fmt.Sprint(created.APIKey.ID)
+	if w := doWithToken(r, http.MethodDelete, path, token); w.Code != http.StatusOK {
+		t.Fatalf("revoke = %d %s", w.Code, w.Body)
+	}
+	if w := doWithAPIKey(r, http.MethodGet, "/user/profile", created.Key); w.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked key = %d, want 401", w.Code)
+	}
+	if w := doWithToken(r, http.MethodDelete, path, token); w.Code != http.StatusOK {
+		t.Fatalf("second revoke = %d", w.Code)
+	}
+	if w := doWithAPIKey(r, http.MethodGet, "/user/profile", apiKeyPrefix+"deadbeef"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("unknown key = %d, want 401", w.Code)
+	}
+
+	// Another user can neither see nor revoke it.
+	other := User{Username: "other", Email: "other@example.com"}
+	db.Create(&other)
+	otherToken, _ := generateJWTToken(&other)
+	if w := doWithToken(r, http.MethodDelete, path, otherToken); w.Code != http.StatusNotFound {
+		t.Fatalf("revoke someone else's key = %d, want 404", w.Code)
+	}
+	if w := doWithToken(r, http.MethodGet, "/user/api-keys", otherToken); w.Body.String() != `{"api_keys":[]}` {
+		t.Fatalf("other user's list = %s", w.Body)
+	}
+}
+
+func TestAPIKey_ReadScopeRefusesWrites(t *testing.T) {
+	withTestDB(t)
+	owner := User{Username: "reader", Email: "reader@example.com"}
+	db.Create(&owner)
+	key, _ := generateAPIKey()
+	db.Create(&APIKey{UserID: owner.ID, Name: "ro", Prefix: key[:apiKeyDisplayLen], KeyHash: hashAPIKey(key), Scopes: "read"})
+
+	r := apiKeyRouter()
+	if w := doWithAPIKey(r, http.MethodGet, "/user/profile", key); w.Code != http.StatusOK {
+		t.Fatalf("read = %d", w.Code)
+	}
+	if w := doWithAPIKey(r, http.MethodPost, "/user/visibility", key); w.Code != http.StatusForbidden {
+		t.Fatalf("write with read-only key = %d, want 403", w.Code)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func createAuditTestUser(t *testing.T, name string, isAdmin bool) (User, string) {
+	t.Helper()
+	hash, _ := bcrypt.GenerateFromPassword([]byte("hunter22"), bcrypt.MinCost)
+	u := User{Username: name, Email: name + "@example.com", Password: string(hash), AccountType: "free", IsAdmin: isAdmin}
+	if err := db.Create(&u).Error; err != nil {
+		t.Fatal(err)
+	}
+	token, err := generateJWTToken(&u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u, token
+}
+
+func auditRequest(r http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func lastAudit(t *testing.T, action string) AuditLog {
+	t.Helper()
+	var entry AuditLog
+	if err := db.Where("action = ?", action).Order("id DESC").First(&entry).Error; err != nil {
+		t.Fatalf("no %s audit record: %v", action, err)
+	}
+	return entry
+}
+
+func TestSensitiveActionsWriteAuditRecords(t *testing.T) {
+	withTestDB(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	user := r.Group("/user", authMiddleware())
+	user.POST("/deactivate", deactivateAccountHandler)
+	user.POST("/delete", deleteAccountHandler)
+	admin := r.Group("/admin", authMiddleware(), adminMiddleware(), auditMiddleware())
+	admin.POST("/users/:user_id/admin", makeUserAdminHandler)
+	admin.GET("/audit-log", getAuditLogHandler)
+
+	deactivated, token := createAuditTestUser(t, "leaving", false)
+	if w := auditRequest(r, http.MethodPost, "/user/deactivate", token, `{"password":"hunter22","reason":"break"}`); w.Code != http.StatusOK {
+		t.Fatalf("deactivate: %d %s", w.Code, w.Body)
+	}
+	if e := lastAudit(t, auditAccountDeactivate); e.ActorID != deactivated.ID || e.TargetID != deactivated.ID ||
+		!strings.Contains(e.Metadata, `"reason":"break"`) {
+		t.Fatalf("deactivate audit = %+v", e)
+	}
+
+	deleted, token := createAuditTestUser(t, "gone", false)
+	if w := auditRequest(r, http.MethodPost, "/user/delete", token, `{"password":"hunter22"}`); w.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", w.Code, w.Body)
+	}
+	if e := lastAudit(t, auditAccountDelete); e.ActorID != deleted.ID || e.TargetID != deleted.ID {
+		t.Fatalf("delete audit = %+v", e)
+	}
+
+	// A rejected deletion (wrong password) is not recorded.
+	_, token = createAuditTestUser(t, "stays", false)
+	auditRequest(r, http.MethodPost, "/user/delete", token, `{"password":"wrong"}`)
+	var deletes int64
+	db.Model(&AuditLog{}).Where("action = ?", auditAccountDelete).Count(&deletes)
+	if deletes != 1 {
+		t.Fatalf("%d delete audit rows, want 1", deletes)
+	}
+
+	boss, adminToken := createAuditTestUser(t, "boss", true)
+	promoted, _ := createAuditTestUser(t, "promoted", false)
+	path := fmt.Sprintf("/admin/users/%d/admin", promoted.ID)
+	if w := auditRequest(r, http.MethodPost, path, adminToken, `{"is_admin":true}`); w.Code != http.StatusOK {
+		t.Fatalf("make admin: %d %s", w.Code, w.Body)
+	}
+	if e := lastAudit(t, auditAdminGrant); e.ActorID != boss.ID || e.TargetID != promoted.ID || e.Path != "/admin/users/:user_id/admin" {
+		t.Fatalf("make-admin audit = %+v", e)
+	}
+	var generic int64
+	db.Model(&AuditLog{}).Where("action = ?", auditAdminRequest).Count(&generic)
+	if generic != 0 {
+		t.Fatalf("make-admin also wrote %d generic rows", generic)
+	}
+
+	w := auditRequest(r, http.MethodGet, fmt.Sprintf("/admin/audit-log?target_id=%d", promoted.ID), adminToken, "")
+	var page struct {
+		Entries []AuditLog `json:"entries"`
+		Total   int64      `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil || page.Total != 1 || page.Entries[0].Action != auditAdminGrant {
+		t.Fatalf("GET /admin/audit-log?target_id: %s (%v)", w.Body, err)
+	}
+	w = auditRequest(r, http.MethodGet, "/admin/audit-log?action="+auditAccountDelete+"&limit=1", adminToken, "")
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil || page.Total != 1 || page.Entries[0].TargetID != deleted.ID {
+		t.Fatalf("GET /admin/audit-log?action: %s (%v)", w.Body, err)
+	}
+	if w := auditRequest(r, http.MethodGet, "/admin/audit-log?actor_id=abc", adminToken, ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("bad actor_id: %d", w.Code)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stripe/stripe-go/v78"
 )
@@ -22,3 +23,106 @@ func TestAccountTypeForSubStatus(t *testing.T) {
 		}
 	}
 }
+
+func subWithPrice(status stripe.SubscriptionStatus, priceID string) *stripe.Subscription {
+	return &stripe.Subscription{
+		Status: status,
+		Items: &stripe.SubscriptionItemList{Data: []*stripe.SubscriptionItem{
+			{Price: &stripe.Price{ID: priceID}},
+		}},
+	}
+}
+
+func TestAccountTypeForSubscription_MapsPricesToTiers(t *testing.T) {
+	t.Setenv("STRIPE_PRICE_TIERS", "price_basic=starter, price_plus=PREMIUM, price_bad=gold, garbage")
+
+	cases := []struct {
+		sub  *stripe.Subscription
+		want string
+	}{
+		{subWithPrice(stripe.SubscriptionStatusActive, "price_basic"), "starter"},
+		{subWithPrice(stripe.SubscriptionStatusTrialing, "price_plus"), "premium"},
+		{subWithPrice(stripe.SubscriptionStatusActive, "price_legacy"), "paid"}, // unmapped → legacy tier
+		{subWithPrice(stripe.SubscriptionStatusActive, "price_bad"), "paid"},    // unknown tier ignored
+		{subWithPrice(stripe.SubscriptionStatusPastDue, "price_plus"), "free"},
+		{&stripe.Subscription{Status: stripe.SubscriptionStatusActive}, "paid"}, // no items
+	}
+	for i, tc := range cases {
+		if got := accountTypeForSubscription(tc.sub); got != tc.want {
+			t.Errorf("case %d: accountTypeForSubscription = %q, want %q", i, got, tc.want)
+		}
+	}
+
+	if got := priceForTier("premium"); got != "price_plus" {
+		t.Errorf("priceForTier(premium) = %q", got)
+	}
+	if got := priceForTier("gold"); got != "" {
+		t.Errorf("priceForTier(gold) = %q, want none", got)
+	}
+}
+
+func TestTrialLifecycle(t *testing.T) {
+	t.Setenv("STRIPE_PRICE_TIERS", "price_plus=premium")
+	t.Setenv("TRIAL_GRACE_HOURS", "24")
+	now := time.Now()
+
+	// Trialing: tier granted, expiry recorded.
+	trialEnd := now.Add(7 * 24 * time.Hour).Truncate(time.Second)
+	sub := subWithPrice(stripe.SubscriptionStatusTrialing, "price_plus")
+	sub.TrialEnd = trialEnd.Unix()
+	var user User
+	applySubscription(&user, sub)
+	if user.AccountType != "premium" || user.TrialEndsAt == nil || !user.TrialEndsAt.Equal(trialEnd) {
+		t.Fatalf("trialing: account=%q trialEndsAt=%v, want premium until %v", user.AccountType, user.TrialEndsAt, trialEnd)
+	}
+	if got := effectiveAccountType(&user); got != "premium" {
+		t.Errorf("trialing: effectiveAccountType = %q, want premium", got)
+	}
+
+	// Converted: trialing→active clears the expiry, so the tier outlives it.
+	applySubscription(&user, subWithPrice(stripe.SubscriptionStatusActive, "price_plus"))
+	if user.AccountType != "premium" || user.TrialEndsAt != nil {
+		t.Fatalf("converted: account=%q trialEndsAt=%v", user.AccountType, user.TrialEndsAt)
+	}
+	if trialLapsed(&user, now.Add(30*24*time.Hour)) {
+		t.Error("converted trial reported as lapsed")
+	}
+}
+
+func TestTrialExpiredWithoutPaymentDowngrades(t *testing.T) {
+	t.Setenv("TRIAL_GRACE_HOURS", "24")
+	now := time.Now()
+
+	// Within the grace window Stripe may still be charging the card.
+	recent := now.Add(-2 * time.Hour)
+	user := User{AccountType: "premium", TrialEndsAt: &recent}
+	if got := effectiveAccountType(&user); got != "premium" {
+		t.Errorf("within grace: effectiveAccountType = %q, want premium", got)
+	}
+
+	// Past the grace with no conversion → free even though the row still
+	// says premium (the sweeper hasn't run / the webhook never came).
+	expired := now.Add(-48 * time.Hour)
+	user.TrialEndsAt = &expired
+	if !trialLapsed(&user, now) {
+		t.Fatal("expired trial not reported as lapsed")
+	}
+	if got := effectiveAccountType(&user); got != "free" {
+		t.Errorf("expired trial: effectiveAccountType = %q, want free", got)
+	}
+
+	// Referral credit still applies on top of a lapsed trial.
+	credit := now.AddDate(0, 0, 10)
+	user.PremiumUntil = &credit
+	if got := effectiveAccountType(&user); got != "premium" {
+		t.Errorf("lapsed trial + referral credit: %q, want premium", got)
+	}
+
+	// Stripe's own downgrade (trial ended, no payment method → canceled)
+	// clears the trial and lands on free.
+	user = User{AccountType: "premium", TrialEndsAt: &expired}
+	applySubscription(&user, subWithPrice(stripe.SubscriptionStatusCanceled, "price_plus"))
+	if user.AccountType != "free" || user.TrialEndsAt != nil {
+		t.Errorf("canceled after trial: account=%q trialEndsAt=%v", user.AccountType, user.TrialEndsAt)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Request body limits. Handlers bind JSON straight off the request, so one
+// giant body would be read into memory whole. bodyLimitMiddleware reads every
+// body up to MAX_JSON_BODY_BYTES (default 1 MB) before the handler runs:
+// anything larger is answered 413, and JSON nested deeper than maxJSONDepth
+// is answered 400. The Stripe webhook keeps its own tighter cap.
+
+// maxJSONDepth is how deeply a request's JSON may nest.
+const maxJSONDepth = 32
+
+// maxJSONBodyBytes is the body cap (MAX_JSON_BODY_BYTES, default 1 MB).
+func maxJSONBodyBytes() int64 { return int64(envInt("MAX_JSON_BODY_BYTES", 1<<20)) }
+
+// bodyLimitMiddleware enforces the body cap and the JSON depth guard.
+func bodyLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		max := maxJSONBodyBytes()
+		if c.Request.ContentLength > max {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large", "max_bytes": max})
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, max+1))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Could not read request body"})
+			return
+		}
+		if int64(len(body)) > max {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large", "max_bytes": max})
+			return
+		}
+		if jsonDepth(body) > maxJSONDepth {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Request JSON is nested too deeply"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
+
+// jsonDepth is the deepest object/array nesting in body, ignoring brackets
+// inside strings. It doesn't validate; binding reports malformed JSON.
+func jsonDepth(body []byte) int {
+	depth, deepest := 0, 0
+	inString, escaped := false, false
+	for _, b := range body {
+		switch {
+		case inString:
+			if escaped {
+				escaped = false
+			} else if b == '\\' {
+				escaped = true
+			} else if b == '"' {
+				inString = false
+			}
+		case b == '"':
+			inString = true
+		case b == '{' || b == '[':
+			depth++
+			if depth > deepest {
+				deepest = depth
+			}
+		case b == '}' || b == ']':
+			depth--
+		}
+	}
+	return deepest
+}
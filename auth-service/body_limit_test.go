@@ -0,0 +1,47 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBodyLimit_OversizedLoginIs413(t *testing.T) {
+	t.Setenv("MAX_JSON_BODY_BYTES", "2048")
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(bodyLimitMiddleware())
+	r.POST("/login", loginHandler)
+
+	huge := `{"username":"` + strings.Repeat("a", 4096) + `","password":"x"}`
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(huge)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body: %d %s", w.Code, w.Body)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/login", io.MultiReader(strings.NewReader(huge)))
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("streamed oversized body: %d", w.Code)
+	}
+
+	deep := `{"username":` + strings.Repeat("[", maxJSONDepth+1) + strings.Repeat("]", maxJSONDepth+1) + `}`
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(deep)))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "nested") {
+		t.Errorf("deep JSON: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestJSONDepth(t *testing.T) {
+	if d := jsonDepth([]byte(`{"q":"[[[[{{{{\"]","a":[{"b":1}]}`)); d != 3 {
+		t.Errorf("jsonDepth = %d, want 3", d)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Content cleanup for deleted accounts. An account's books and audio live in
+// content-service and are kept for accountRestoreWindow after deletion, so
+// a restored account comes back with its library. Once the window has
+// passed, runContentCleanupSweeper asks content-service to remove them
+// (DELETE /internal/users/:user_id/content, signed with SERVICE_SECRET).
+// That call is idempotent, so a failure is simply retried on the next hourly
+// sweep. Success is recorded on the history row (content_purged_at).
+
+// accountRestoreWindow is how long a deleted account can be restored; its
+// content is purged only after this.
+const accountRestoreWindow = 90 * 24 * time.Hour
+
+// contentServiceURL is content-service's base URL.
+func contentServiceURL() string {
+	return getEnv("CONTENT_SERVICE_URL", "http://content-service:8083")
+}
+
+// requestContentPurge asks content-service to delete userID's content;
+// swappable in tests.
+var requestContentPurge = func(userID uint) error {
+	secret := getEnv("SERVICE_SECRET", "")
+	if secret == "" {
+		return errors.New("SERVICE_SECRET not set")
+	}
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/internal/users/%d/content", contentServiceURL(), userID), nil)
+	if err != nil {
+		return err
+	}
+	if err := signServiceRequest(req, secret, nil); err != nil {
+		return err
+	}
+	resp, err := (&http.Client{Timeout: 2 * time.Minute}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("content-service returned %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// tryContentPurge makes one purge attempt for a deleted account and records
+// success on its history row.
+func tryContentPurge(history UserHistory) error {
+	if err := requestContentPurge(history.OriginalUserID); err != nil {
+		return err
+	}
+	if err := db.Model(&UserHistory{}).Where("id = ?", history.ID).Update("content_purged_at", time.Now()).Error; err != nil {
+		log.Printf("⚠️ content purged for user %d but not recorded: %v", history.OriginalUserID, err)
+	}
+	log.Printf("🗑️ Content of deleted user %d removed", history.OriginalUserID)
+	return nil
+}
+
+// sweepContentCleanups purges the content of deletions older than
+// accountRestoreWindow that haven't been purged or restored.
+func sweepContentCleanups(now time.Time) {
+	var pending []UserHistory
+	err := db.Where("status = ? AND content_purged_at IS NULL AND restored_at IS NULL AND deleted_at < ?",
+		"deleted", now.Add(-accountRestoreWindow)).
+		Find(&pending).Error
+	if err != nil {
+		log.Printf("⚠️ content cleanup sweep: %v", err)
+		return
+	}
+	for _, h := range pending {
+		if err := tryContentPurge(h); err != nil {
+			log.Printf("⚠️ content cleanup for deleted user %d: %v", h.OriginalUserID, err)
+		}
+	}
+}
+
+// runContentCleanupSweeper purges expired deletions hourly.
+func runContentCleanupSweeper() {
+	for {
+		sweepContentCleanups(time.Now())
+		time.Sleep(time.Hour)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stubContentService stands in for content-service's purge endpoint, checking
+// signatures the way content-service does. It answers the queued statuses in
+// order (then 200) and records the user IDs it was asked to purge.
+func stubContentService(t *testing.T, statuses ...int) func() []string {
+	t.Helper()
+	t.Setenv("SERVICE_SECRET", "s3cret")
+	var mu sync.Mutex
+	var calls []string
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.DELETE("/internal/users/:user_id/content", serviceAuthMiddleware(), func(c *gin.Context) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, c.Param("user_id"))
+		status := http.StatusOK
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		c.JSON(status, gin.H{"books_deleted": 1})
+	})
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	t.Setenv("CONTENT_SERVICE_URL", srv.URL)
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), calls...)
+	}
+}
+
+func TestRequestContentPurge_Signed(t *testing.T) {
+	calls := stubContentService(t, http.StatusServiceUnavailable)
+	if err := requestContentPurge(42); err == nil {
+		t.Error("503 from content-service not reported")
+	}
+	if err := requestContentPurge(42); err != nil {
+		t.Fatalf("signed purge rejected: %v", err)
+	}
+	if got := calls(); len(got) != 2 || got[1] != "42" {
+		t.Errorf("calls = %v", got)
+	}
+
+	t.Setenv("SERVICE_SECRET", "")
+	if err := requestContentPurge(42); err == nil {
+		t.Error("purge sent without a SERVICE_SECRET")
+	}
+}
+
+// Content must survive the deletion itself so a restore within the window
+// brings the library back.
+func TestDeleteAccount_KeepsContentForRestoreWindow(t *testing.T) {
+	withTestDB(t)
+	calls := stubContentService(t)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/user/delete", authMiddleware(), deleteAccountHandler)
+	user, token := createAuditTestUser(t, "leaver", false)
+	if w := auditRequest(r, http.MethodPost, "/user/delete", token, `{"password":"hunter22"}`); w.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", w.Code, w.Body)
+	}
+
+	sweepContentCleanups(time.Now())
+	if got := calls(); len(got) != 0 {
+		t.Fatalf("content purged at deletion: %v", got)
+	}
+	sweepContentCleanups(time.Now().Add(accountRestoreWindow + time.Hour))
+	if got := calls(); len(got) != 1 || got[0] != fmt.Sprint(user.ID) {
+		t.Errorf("calls = %v, want a purge of %d once the window closed", got, user.ID)
+	}
+}
+
+func TestSweepContentCleanups(t *testing.T) {
+	withTestDB(t)
+	calls := stubContentService(t)
+	now := time.Now()
+	expired := now.Add(-accountRestoreWindow - time.Hour)
+	purged, restored := now.Add(-time.Hour), now.Add(-time.Hour)
+	rows := map[string]UserHistory{
+		"pending":    {OriginalUserID: 101, DeletedAt: expired},
+		"purged":     {OriginalUserID: 102, DeletedAt: expired, ContentPurgedAt: &purged},
+		"restored":   {OriginalUserID: 103, DeletedAt: expired, RestoredAt: &restored},
+		"restorable": {OriginalUserID: 104, DeletedAt: now.Add(-accountRestoreWindow + time.Hour)},
+		"deactivate": {OriginalUserID: 106, DeletedAt: expired, Status: "deactivated"},
+	}
+	for name, h := range rows {
+		h.Email = name + "@example.com"
+		if h.Status == "" {
+			h.Status = "deleted"
+		}
+		if err := db.Create(&h).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sweepContentCleanups(now)
+	if got := calls(); len(got) != 1 || got[0] != "101" {
+		t.Fatalf("swept %v, want only 101", got)
+	}
+	var h UserHistory
+	db.Where("original_user_id = ?", 101).First(&h)
+	if h.ContentPurgedAt == nil {
+		t.Error("sweep didn't record the purge")
+	}
+	sweepContentCleanups(now)
+	if got := calls(); len(got) != 1 {
+		t.Errorf("purged row retried: %v", got)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+// Support impersonation. An admin can mint a short-lived token for a user to
+// reproduce what that user sees without knowing their password. The token is
+// an ordinary user token (authMiddleware accepts it) with an impersonated_by
+// claim naming the admin; it never carries admin rights, and routes wrapped
+// in denyImpersonated (account deletion, billing, contact details) refuse it.
+// Every token issued is recorded in the audit log (audit.go).
+
+// impersonationTTL bounds how long a support session lasts.
+const impersonationTTL = 30 * time.Minute
+
+// generateImpersonationToken mints a token acting as target on behalf of
+// adminID, valid for impersonationTTL.
+func generateImpersonationToken(target *User, adminID uint) (string, time.Time, error) {
+	now := time.Now()
+	exp := now.Add(impersonationTTL)
+	claims := jwt.MapClaims{
+		"username":        target.Username,
+		"user_id":         target.ID,
+		"is_admin":        false, // never carries admin rights, even for an admin target
+		"account_type":    effectiveAccountType(target),
+		"impersonated_by": adminID,
+		"exp":             exp.Unix(),
+		"iat":             now.Unix(),
+	}
+	token, err := signJWT(claims)
+	return token, exp, err
+}
+
+// impersonatorID returns the admin behind an impersonation token, or 0 for a
+// token the user obtained themselves.
+func impersonatorID(c *gin.Context) uint {
+	claims, ok := c.Get("claims")
+	if !ok {
+		return 0
+	}
+	mc, ok := claims.(jwt.MapClaims)
+	if !ok {
+		return 0
+	}
+	if f, ok := mc["impersonated_by"].(float64); ok {
+		return uint(f)
+	}
+	return 0
+}
+
+// denyImpersonated rejects impersonation tokens on routes support must not
+// exercise on a user's behalf. It runs after authMiddleware.
+func denyImpersonated() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if adminID := impersonatorID(c); adminID != 0 {
+			log.Printf("🚫 impersonation: admin %d blocked from %s %s", adminID, c.Request.Method, c.FullPath())
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Not allowed while impersonating a user"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// impersonateUserHandler issues an impersonation token for a user
+// POST /admin/users/:user_id/impersonate
+func impersonateUserHandler(c *gin.Context) {
+	targetID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	var adminID uint
+	if claims, ok := c.Get("claims"); ok {
+		if f, ok := claims.(jwt.MapClaims)["user_id"].(float64); ok {
+			adminID = uint(f)
+		}
+	}
+	if uint(targetID) == adminID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot impersonate yourself"})
+		return
+	}
+
+	var target User
+	if err := db.First(&target, targetID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	token, exp, err := generateImpersonationToken(&target, adminID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	recordAudit(c, auditImpersonate, target.ID, gin.H{"expires_at": exp.Format(time.RFC3339)})
+	log.Printf("🕵️ impersonation: admin %d issued a token for user %d (%s), expires %s",
+		adminID, target.ID, target.Username, exp.Format(time.RFC3339))
+	c.JSON(http.StatusOK, gin.H{
+		"token":           token,
+		"user_id":         target.ID,
+		"username":        target.Username,
+		"impersonated_by": adminID,
+		"expires_at":      exp.Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+// impersonationRouter mirrors the /user and /admin wiring in main with stub
+// handlers, so the middleware chain is what's under test.
+func impersonationRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ok := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"user_id": c.GetUint("user_id")}) }
+	user := r.Group("/user", authMiddleware())
+	user.GET("/profile", ok)
+	user.POST("/delete", denyImpersonated(), ok)
+	r.GET("/admin/stats", authMiddleware(), adminMiddleware(), ok)
+	return r
+}
+
+func doWithToken(r http.Handler, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestImpersonationToken_Privileges(t *testing.T) {
+	target := &User{ID: 42, Username: "reader", AccountType: "free", IsAdmin: true}
+	token, _, err := generateImpersonationToken(target, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := jwt.Parse(token, jwtKeyFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if by := parsed.Claims.(jwt.MapClaims)["impersonated_by"]; by != float64(7) {
+		t.Fatalf("impersonated_by = %v, want 7", by)
+	}
+
+	r := impersonationRouter()
+	w := doWithToken(r, http.MethodGet, "/user/profile", token)
+	if w.Code != http.StatusOK || w.Body.String() != `{"user_id":42}` {
+		t.Fatalf("read endpoint: %d %s", w.Code, w.Body)
+	}
+	if w := doWithToken(r, http.MethodPost, "/user/delete", token); w.Code != http.StatusForbidden {
+		t.Fatalf("account deletion while impersonating: %d, want 403", w.Code)
+	}
+	if w := doWithToken(r, http.MethodGet, "/admin/stats", token); w.Code != http.StatusForbidden {
+		t.Fatalf("admin route with impersonation of an admin: %d, want 403", w.Code)
+	}
+
+	// The user's own token is unaffected.
+	own, err := generateJWTToken(&User{ID: 42, Username: "reader", AccountType: "free"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := doWithToken(r, http.MethodPost, "/user/delete", own); w.Code != http.StatusOK {
+		t.Fatalf("own token blocked from deletion: %d", w.Code)
+	}
+}
+
+func TestImpersonateUser_WritesAuditLog(t *testing.T) {
+	withTestDB(t)
+	admin := User{Username: "support", Email: "support@example.com", AccountType: "free", IsAdmin: true}
+	target := User{Username: "reader", Email: "reader@example.com", AccountType: "free"}
+	db.Create(&admin)
+	db.Create(&target)
+	adminToken, err := generateJWTToken(&admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/admin/users/:user_id/impersonate", authMiddleware(), adminMiddleware(), auditMiddleware(), impersonateUserHandler)
+
+	w := doWithToken(r, http.MethodPost, fmt.Sprintf("/admin/users/%d/impersonate", target.ID), adminToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("impersonate: %d %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Token  string `json:"token"`
+		UserID uint   `json:"user_id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Token == "" || resp.UserID != target.ID {
+		t.Fatalf("response %s: %v", w.Body, err)
+	}
+
+	var entry AuditLog
+	if err := db.Where("action = ?", auditImpersonate).First(&entry).Error; err != nil {
+		t.Fatalf("no audit record: %v", err)
+	}
+	if entry.ActorID != admin.ID || entry.TargetID != target.ID || entry.Path != "/admin/users/:user_id/impersonate" {
+		t.Fatalf("audit record = %+v", entry)
+	}
+	var rows int64
+	db.Model(&AuditLog{}).Count(&rows)
+	if rows != 1 {
+		t.Fatalf("%d audit rows for one request, want 1", rows)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// JWT signing. JWT_ALG selects the scheme:
+//
+//	HS256 (default) — shared JWT_SECRET; every verifier could also mint tokens.
+//	RS256           — auth-service signs with JWT_PRIVATE_KEY (PEM, or a path
+//	                  in JWT_PRIVATE_KEY_FILE); content-service only needs the
+//	                  public half (JWT_PUBLIC_KEY), so it can verify but never mint.
+//
+// Verification pins the configured algorithm: a token whose alg differs is
+// rejected outright (no HS256 fallback once RS256 is on).
+var jwtAlg = strings.ToUpper(getEnv("JWT_ALG", "HS256"))
+
+var jwtSecretKey, jwtPrivateKey = loadJWTSigningKeys(jwtAlg)
+
+// loadJWTSigningKeys reads the key material for alg, exiting on a missing or
+// malformed key — the service must never start unable to sign.
+func loadJWTSigningKeys(alg string) ([]byte, *rsa.PrivateKey) {
+	switch alg {
+	case "HS256":
+		return []byte(mustEnv("JWT_SECRET")), nil
+	case "RS256":
+		pemBytes, err := pemFromEnv("JWT_PRIVATE_KEY")
+		if err != nil {
+			log.Fatalf("FATAL: JWT_ALG=RS256: %v", err)
+		}
+		key, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			log.Fatalf("FATAL: JWT_ALG=RS256: invalid JWT_PRIVATE_KEY: %v", err)
+		}
+		return nil, key
+	default:
+		log.Fatalf("FATAL: unsupported JWT_ALG %q (want HS256 or RS256)", alg)
+		return nil, nil
+	}
+}
+
+// pemFromEnv returns a PEM block from NAME (literal "\n" escapes allowed, for
+// single-line .env files) or from the file named by NAME_FILE.
+func pemFromEnv(name string) ([]byte, error) {
+	if v := os.Getenv(name); v != "" {
+		return []byte(strings.ReplaceAll(v, `\n`, "\n")), nil
+	}
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		return os.ReadFile(path)
+	}
+	return nil, fmt.Errorf("%s or %s_FILE must be set", name, name)
+}
+
+// signJWT signs claims with the configured algorithm and key.
+func signJWT(claims jwt.MapClaims) (string, error) {
+	if jwtAlg == "RS256" {
+		return jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(jwtPrivateKey)
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecretKey)
+}
+
+// jwtKeyFunc is the jwt.Parse key function: it returns the verification key
+// for the configured algorithm and rejects any token signed with another
+// (alg=none, HS256-with-the-public-key confusion, or a stale HS256 token
+// after switching to RS256).
+func jwtKeyFunc(token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() != jwtAlg {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	if jwtAlg == "RS256" {
+		return &jwtPrivateKey.PublicKey, nil
+	}
+	return jwtSecretKey, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func TestSignJWT_RS256RoundTrip(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	origAlg, origPriv := jwtAlg, jwtPrivateKey
+	defer func() { jwtAlg, jwtPrivateKey = origAlg, origPriv }()
+	jwtAlg, jwtPrivateKey = "RS256", priv
+
+	signed, err := signJWT(jwt.MapClaims{"user_id": 7, "exp": time.Now().Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, err := jwt.Parse(signed, jwtKeyFunc)
+	if err != nil || !tok.Valid {
+		t.Fatalf("RS256 token rejected: %v", err)
+	}
+	if tok.Method.Alg() != "RS256" {
+		t.Errorf("signed with %s, want RS256", tok.Method.Alg())
+	}
+
+	hs, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": 7}).SignedString(jwtSecretKey)
+	if _, err := jwt.Parse(hs, jwtKeyFunc); err == nil {
+		t.Error("HS256 token accepted while RS256 is configured")
+	}
+}
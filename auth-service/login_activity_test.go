@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLoginActivity(t *testing.T) {
+	withTestDB(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/login", loginHandler)
+	r.GET("/user/security/activity", authMiddleware(), getLoginActivityHandler)
+
+	user, token := createAuditTestUser(t, "watchful", false)
+	login := func(device, country string) {
+		t.Helper()
+		body := `{"username":"watchful","password":"hunter22","device_model":"` + device + `","os_version":"iOS 18.1"}`
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "StreamAudio/3.2")
+		req.Header.Set("CF-IPCountry", country)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("login = %d %s", w.Code, w.Body)
+		}
+	}
+
+	login("iPhone 15", "US")
+	var first LoginEvent
+	if err := db.Where("user_id = ?", user.ID).First(&first).Error; err != nil {
+		t.Fatalf("login wrote no event: %v", err)
+	}
+	if first.Method != "password" || first.DeviceModel != "iPhone 15" || first.OSVersion != "iOS 18.1" ||
+		first.Location != "US" || first.UserAgent != "StreamAudio/3.2" || first.IP == "" {
+		t.Fatalf("event = %+v", first)
+	}
+
+	// A failed attempt is not a login.
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"username":"watchful","password":"wrong"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	db.Model(&first).Update("created_at", time.Now().Add(-time.Hour))
+	login("Pixel 9", "DE")
+
+	w := auditRequest(r, http.MethodGet, "/user/security/activity", token, "")
+	var resp struct {
+		Events []LoginEvent `json:"events"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || len(resp.Events) != 2 {
+		t.Fatalf("activity = %d %s", w.Code, w.Body)
+	}
+	if resp.Events[0].DeviceModel != "Pixel 9" || resp.Events[1].DeviceModel != "iPhone 15" {
+		t.Fatalf("not newest-first: %s, %s", resp.Events[0].DeviceModel, resp.Events[1].DeviceModel)
+	}
+
+	if w := auditRequest(r, http.MethodGet, "/user/security/activity?limit=1", token, ""); !strings.Contains(w.Body.String(), "Pixel 9") ||
+		strings.Contains(w.Body.String(), "iPhone 15") {
+		t.Fatalf("limit=1: %s", w.Body)
+	}
+
+	// Other users see only their own history.
+	_, otherToken := createAuditTestUser(t, "bystander", false)
+	if w := auditRequest(r, http.MethodGet, "/user/security/activity", otherToken, ""); !strings.Contains(w.Body.String(), `"events":[]`) {
+		t.Fatalf("other user's activity: %s", w.Body)
+	}
+}
+
+func TestTruncate_KeepsRunesWhole(t *testing.T) {
+	cases := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"Mozilla", 10, "Mozilla"},
+		{"Mozilla", 3, "Moz"},
+		{"Zürich", 2, "Z"}, // ü is two bytes; cutting at 2 would split it
+		{"Zürich", 3, "Zü"},
+		{"日本", 4, "日"},
+	}
+	for _, tc := range cases {
+		got := truncate(tc.s, tc.n)
+		if got != tc.want || !utf8.ValidString(got) {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tc.s, tc.n, got, tc.want)
+		}
+	}
+}
@@ -32,11 +32,9 @@ import (
 	"github.com/stripe/stripe-go/v78/checkout/session"
 	"github.com/stripe/stripe-go/v78/customer"
 	"github.com/stripe/stripe-go/v78/subscription"
-	"github.com/stripe/stripe-go/v78/webhook"
 )
 
-// Global variables
-var jwtSecretKey = []byte(mustEnv("JWT_SECRET"))
+// Global variables (JWT keys: jwt_keys.go)
 var db *gorm.DB
 
 // mustEnv returns the env var value or exits — services must never run
@@ -55,7 +53,7 @@ type User struct {
 	Username         string    `gorm:"unique;not null"`
 	Email            string    `gorm:"unique;not null"`
 	Password         string    // stored as a bcrypt hash (empty for social login users)
-	AccountType      string    `gorm:"not null"` // e.g., "free" or "paid"
+	AccountType      string    `gorm:"not null"` // "free", "starter", "premium" (or legacy "paid")
 	IsPublic         bool      `gorm:"default:true"`
 	State            string    // user's state or location
 	StripeCustomerID string    // for paid accounts
@@ -83,6 +81,11 @@ type User struct {
 	ReferralCode *string    `gorm:"uniqueIndex"` // shareable invite code, lazily generated
 	ReferredBy   uint       `gorm:"index"`       // user id of the referrer; 0 = organic signup
 	PremiumUntil *time.Time                      // referral-credit premium entitlement expiry
+	TrialEndsAt  *time.Time                      // Stripe trial expiry while trialing; nil once converted (see stripe_trial.go)
+	// Last Stripe subscription state seen by the webhook: "active", "trialing",
+	// "past_due", "paused", "canceled", … ("" = never subscribed via Stripe).
+	SubscriptionStatus string `gorm:"size:32"`
+	CancelAtPeriodEnd  bool   `gorm:"default:false"` // subscription ends at period end; access continues until then
 	CreatedAt        time.Time
 	UpdatedAt        time.Time
 }
@@ -112,6 +115,7 @@ type UserHistory struct {
 	OriginalCreatedAt time.Time                                       // Original account creation date
 	RestoredAt       *time.Time                                       // If account was restored
 	RestoredToUserID *uint                                            // New user ID if restored
+	ContentPurgedAt  *time.Time                                       // When content-service removed the account's books (content_cleanup.go)
 }
 
 // UserBookHistory stores book progress for deleted/deactivated accounts
@@ -139,6 +143,8 @@ type SignupRequest struct {
 	Email       string `json:"email" binding:"required,email"`
 	Password    string `json:"password" binding:"required,min=6"`
 	State       string `json:"state" binding:"required"`
+	// ISO 3166-1 country for State validation (state.go); empty means US.
+	Country string `json:"country"`
 	// Device information for account restoration
 	PhoneNumber string `json:"phone_number"`
 	DeviceModel string `json:"device_model"`
@@ -148,6 +154,8 @@ type SignupRequest struct {
 	AppVersion  string `json:"app_version"`  // App version
 	// Optional invite code from the referral program (see referral.go).
 	ReferralCode string `json:"referral_code"`
+	// ReferredBy is accepted as an alias of ReferralCode (the referrer's code).
+	ReferredBy string `json:"referred_by"`
 }
 
 type LoginRequest struct {
@@ -289,6 +297,11 @@ func main() {
 	// Surface any missing social-login configuration up front.
 	validateSocialLoginConfig()
 
+	// Downgrade Stripe trials that lapsed without a conversion webhook.
+	go runTrialExpirySweeper()
+	// Purge content of deleted accounts past the restore window (content_cleanup.go).
+	go runContentCleanupSweeper()
+
 	// Set Gin mode based on environment variable; default to release
 	ginMode := os.Getenv("GIN_MODE")
 	if ginMode == "" {
@@ -297,6 +310,8 @@ func main() {
 	gin.SetMode(ginMode)
 
 	router := gin.Default()
+	// Body size and JSON depth caps for every route (body_limit.go)
+	router.Use(bodyLimitMiddleware())
 
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
@@ -309,6 +324,8 @@ func main() {
 	router.POST("/restore-account", restoreAccountHandler)
 	// Referral invite link → download destination (public; see referral.go)
 	router.GET("/invite/:code", inviteRedirectHandler)
+	// Books-read leaderboard of public profiles (public; reading_stats.go)
+	router.GET("/leaderboard", leaderboardHandler)
 
 	// Social login endpoints (public)
 	auth := router.Group("/auth")
@@ -324,33 +341,47 @@ func main() {
 	{
 		authorized.GET("/profile", profileHandler)
 		// adding stripe checkout session
-		authorized.POST("/stripe/create-checkout-session", createCheckoutSessionHandler)
+		authorized.POST("/stripe/create-checkout-session", denyImpersonated(), denyAPIKey(), createCheckoutSessionHandler)
 		authorized.GET("/account-type", getAccountTypeHandler)
 		// Subscription management
 		authorized.GET("/subscription/status", getSubscriptionStatusHandler)
-		authorized.POST("/subscription/cancel", cancelSubscriptionHandler)
+		authorized.POST("/subscription/cancel", denyImpersonated(), denyAPIKey(), cancelSubscriptionHandler)
 		// Apple IAP receipt validation (the iOS app has always called this;
 		// it 404'd until the referral work implemented it — referral.go)
-		authorized.POST("/subscription/validate-receipt", validateReceiptHandler)
+		authorized.POST("/subscription/validate-receipt", denyImpersonated(), denyAPIKey(), validateReceiptHandler)
 		// Referral program: code, invite link, stats
 		authorized.GET("/referral", getReferralInfoHandler)
+		authorized.GET("/referrals", getReferralsHandler)
 		// Activity tracking
 		authorized.POST("/activity/ping", updateUserActivityHandler)
+		// Recent sign-ins, for spotting unrecognised access (login_activity.go).
+		authorized.GET("/security/activity", getLoginActivityHandler)
+		// Finished-books count and leaderboard position (reading_stats.go).
+		// Not under /user/stats/: nginx sends that prefix to content-service.
+		authorized.GET("/reading-stats", readingStatsHandler)
 		// Phone number (used by contact discovery — see content-service
 		// discovery.go for the hashing contract)
-		authorized.POST("/phone", updatePhoneHandler)
+		authorized.POST("/phone", denyImpersonated(), denyAPIKey(), updatePhoneHandler)
 		// SMS OTP verification (Twilio Verify) — only verified numbers are
 		// matchable in contact discovery (twilio.go)
-		authorized.POST("/phone/start", startPhoneVerificationHandler)
-		authorized.POST("/phone/verify", checkPhoneVerificationHandler)
+		authorized.POST("/phone/start", denyImpersonated(), denyAPIKey(), startPhoneVerificationHandler)
+		authorized.POST("/phone/verify", denyImpersonated(), denyAPIKey(), checkPhoneVerificationHandler)
 		// Profile visibility (public = discoverable/followable)
 		authorized.POST("/visibility", updateVisibilityHandler)
-		// Account deactivation and deletion
-		authorized.POST("/deactivate", deactivateAccountHandler)
-		authorized.POST("/delete", deleteAccountHandler)
+		// Account deactivation and deletion. Billing, phone and account
+		// lifecycle routes refuse support impersonation tokens (impersonation.go)
+		// and API keys (api_keys.go).
+		authorized.POST("/deactivate", denyImpersonated(), denyAPIKey(), deactivateAccountHandler)
+		authorized.POST("/delete", denyImpersonated(), denyAPIKey(), deleteAccountHandler)
+
+		// Programmatic access keys (api_keys.go); managed only from a login.
+		authorized.GET("/api-keys", denyAPIKey(), listAPIKeysHandler)
+		authorized.POST("/api-keys", denyImpersonated(), denyAPIKey(), createAPIKeyHandler)
+		authorized.DELETE("/api-keys/:key_id", denyImpersonated(), denyAPIKey(), revokeAPIKeyHandler)
 	}
 
-	// Admin routes group. auditMiddleware records every mutating call (S10).
+	// Admin routes group. auditMiddleware records every mutating call (S10,
+	// audit.go).
 	admin := router.Group("/admin")
 	admin.Use(authMiddleware(), adminMiddleware(), auditMiddleware())
 	{
@@ -358,6 +389,8 @@ func main() {
 		admin.GET("/users", listUsersHandler)
 		admin.GET("/users/active", getActiveUsersHandler)
 		admin.POST("/users/:user_id/admin", makeUserAdminHandler)
+		admin.POST("/users/:user_id/impersonate", impersonateUserHandler)
+		admin.GET("/audit-log", getAuditLogHandler)
 
 		// File tree endpoint
 		admin.GET("/files/tree", getFileTreeHandler)
@@ -376,6 +409,16 @@ func main() {
 
 	router.POST("/stripe/webhook", stripeWebhookHandler)
 
+	// Service-to-service endpoints: HMAC-signed with SERVICE_SECRET plus a
+	// single-use nonce (service_auth.go). Never reachable with a user JWT.
+	internal := router.Group("/internal")
+	internal.Use(serviceAuthMiddleware())
+	{
+		internal.GET("/users/:user_id/account-type", internalAccountTypeHandler)
+		internal.POST("/api-keys/verify", internalVerifyAPIKeyHandler)
+		internal.POST("/users/:user_id/books-read", internalBooksReadHandler)
+	}
+
 	// Use port from env or default to 8082
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -421,17 +464,6 @@ func configureConnPool(g *gorm.DB) {
 	sqlDB.SetConnMaxLifetime(30 * time.Minute)
 }
 
-// AuditLog records every admin mutation (who/what/when/target) for S10.
-type AuditLog struct {
-	ID          uint `gorm:"primaryKey"`
-	AdminUserID uint `gorm:"index"`
-	Method      string
-	Path        string
-	Target      string
-	StatusCode  int
-	CreatedAt   time.Time
-}
-
 // ---- S10: admin auditability ----
 
 // wipeNonceStore holds short-lived, single-use confirmation tokens for the
@@ -483,37 +515,6 @@ func consumeWipeNonce(nonce string) bool {
 	return time.Now().Before(exp)
 }
 
-// auditMiddleware records mutating admin requests (POST/DELETE) to audit_logs
-// after the handler runs, capturing who, what, the target param, and status.
-func auditMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Next()
-
-		if c.Request.Method != http.MethodPost && c.Request.Method != http.MethodDelete {
-			return
-		}
-		var adminID uint
-		if claims, ok := c.Get("claims"); ok {
-			if mc, ok := claims.(jwt.MapClaims); ok {
-				if f, ok := mc["user_id"].(float64); ok {
-					adminID = uint(f)
-				}
-			}
-		}
-		entry := AuditLog{
-			AdminUserID: adminID,
-			Method:      c.Request.Method,
-			Path:        c.FullPath(),
-			Target:      c.Param("user_id"),
-			StatusCode:  c.Writer.Status(),
-			CreatedAt:   time.Now(),
-		}
-		if err := db.Create(&entry).Error; err != nil {
-			log.Printf("⚠️ failed to write audit log: %v", err)
-		}
-	}
-}
-
 func setupDatabase() {
 	// Read from env, or default to sensible values
 	dbHost := getEnv("DB_HOST", "localhost")
@@ -538,9 +539,16 @@ func setupDatabase() {
 	}
 	configureConnPool(db)
 
-	// Run migrations
-	if err := db.AutoMigrate(&User{}, &UserHistory{}, &UserBookHistory{}, &ProcessedStripeEvent{}, &AuditLog{}, &ReferralCredit{}); err != nil {
-		log.Fatalf("AutoMigrate failed: %v", err)
+	// Versioned migrations (migrations.go). RUN_MODE=migrate-down reverts
+	// the latest one and exits.
+	if getEnv("RUN_MODE", "") == "migrate-down" {
+		if err := rollbackLastMigration(db, authMigrations); err != nil {
+			log.Fatalf("Rollback failed: %v", err)
+		}
+		os.Exit(0)
+	}
+	if err := runMigrations(db, authMigrations); err != nil {
+		log.Fatalf("Migrations failed: %v", err)
 	}
 
 	log.Println("✅ Database connected and migrated (users, user_histories, user_book_histories)")
@@ -553,6 +561,12 @@ func signupHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid signup data", "details": err.Error()})
 		return
 	}
+	state, err := normalizeState(req.State, req.Country)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid state", "details": err.Error()})
+		return
+	}
+	req.State = state
 
 	// Extract client IP address
 	clientIP := c.ClientIP()
@@ -608,7 +622,7 @@ func signupHandler(c *gin.Context) {
 	// Resolve an optional referral code. Invalid/self-referral codes are
 	// ignored (never block signup); the credit is only awarded later, when
 	// this account converts to paid (referral.go).
-	referredBy := resolveReferralCode(req.ReferralCode, req.DeviceID)
+	referredBy := resolveReferralCode(req.referralCode(), req.DeviceID)
 
 	// Create a new user with default free account type and public profile
 	user := User{
@@ -634,8 +648,15 @@ func signupHandler(c *gin.Context) {
 		return
 	}
 
+	// Every account gets its shareable code up front; a failure here is not
+	// fatal since GET /user/referral generates it lazily too.
+	code, err := ensureReferralCode(&user)
+	if err != nil {
+		log.Printf("⚠️ referral: could not generate code for new user %d: %v", user.ID, err)
+	}
+
 	log.Printf("✅ New user registered: %s (ID: %d) from %s", user.Username, user.ID, clientIP)
-	c.JSON(http.StatusOK, gin.H{"message": "User registered", "user_id": user.ID})
+	c.JSON(http.StatusOK, gin.H{"message": "User registered", "user_id": user.ID, "referral_code": code})
 }
 
 // loginHandler validates credentials and returns a JWT token
@@ -683,6 +704,7 @@ func loginHandler(c *gin.Context) {
 
 	db.Model(&user).Updates(updates)
 	log.Printf("✅ User %s logged in from %s (%s)", user.Username, clientIP, req.DeviceModel)
+	recordLoginEvent(c, user.ID, "password", loginDevice{Model: req.DeviceModel, ID: req.DeviceID, OSVersion: req.OSVersion, AppVersion: req.AppVersion})
 
 	// Create JWT token with user claims
 	claims := jwt.MapClaims{
@@ -693,8 +715,7 @@ func loginHandler(c *gin.Context) {
 		"exp":          time.Now().Add(time.Hour * 72).Unix(),
 		"iat":          time.Now().Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtSecretKey)
+	tokenString, err := signJWT(claims)
 	if err != nil {
 		log.Printf("Error signing token: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
@@ -744,13 +765,32 @@ func createCheckoutSessionHandler(c *gin.Context) {
 
 	// 5. Create Stripe Checkout session.
 	// B7: bill a SINGLE subscription price from config — the previous code
-	// added two line items, double-charging every subscriber.
+	// added two line items, double-charging every subscriber. An optional
+	// {"tier": "starter"|"premium"} picks that tier's price (stripe_tiers.go);
+	// without it the legacy STRIPE_PRICE_ID is sold.
+	var req struct {
+		Tier string `json:"tier"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+			return
+		}
+	}
 	priceID := getEnv("STRIPE_PRICE_ID", "")
+	if req.Tier != "" {
+		priceID = priceForTier(strings.ToLower(req.Tier))
+		if priceID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown tier", "tier": req.Tier})
+			return
+		}
+	}
 	if priceID == "" {
 		log.Printf("❌ STRIPE_PRICE_ID not configured")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Billing is not configured"})
 		return
 	}
+	tier := tierForPrice(priceID)
 	params := &stripe.CheckoutSessionParams{
 		Customer:           stripe.String(customerID),
 		PaymentMethodTypes: stripe.StringSlice([]string{"card"}),
@@ -764,10 +804,11 @@ func createCheckoutSessionHandler(c *gin.Context) {
 		SuccessURL: stripe.String(getEnv("STRIPE_SUCCESS_URL", "https://narrafied.com/thank-you-page")),
 		CancelURL:  stripe.String(getEnv("STRIPE_CANCEL_URL", "https://narrafied.com/cancel")),
 	}
-	// Carry user_id so the user is recoverable from events.
-	params.Metadata = map[string]string{"user_id": strconv.FormatUint(uint64(userID), 10)}
+	// Carry user_id so the user is recoverable from events, and the tier so
+	// checkout.session.completed can grant it without another API call.
+	params.Metadata = map[string]string{"user_id": strconv.FormatUint(uint64(userID), 10), "tier": tier}
 	params.SubscriptionData = &stripe.CheckoutSessionSubscriptionDataParams{
-		Metadata: map[string]string{"user_id": strconv.FormatUint(uint64(userID), 10)},
+		Metadata: map[string]string{"user_id": strconv.FormatUint(uint64(userID), 10), "tier": tier},
 	}
 	s, err := session.New(params)
 	if err != nil {
@@ -820,18 +861,14 @@ func stripeWebhookHandler(c *gin.Context) {
 	endpointSecret := getEnv("STRIPE_WEBHOOK_SECRET", "")
 	sigHeader := c.GetHeader("Stripe-Signature")
 
-	// Use ConstructEventWithOptions to ignore API version mismatch
-	event, err := webhook.ConstructEventWithOptions(payload, sigHeader, endpointSecret, webhook.ConstructEventOptions{
-		IgnoreAPIVersionMismatch: true,
-	})
-
+	// Clock-skew tolerance and test-mode handling: stripe_webhook.go.
+	event, err := constructStripeEvent(payload, sigHeader, endpointSecret)
 	if err != nil {
 		log.Printf("⚠️ Webhook signature verification failed: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Signature verification failed"})
 		return
 	}
-
-	log.Printf("✅ Webhook received: %s (%s)", event.Type, event.ID)
+	logStripeEvent(event)
 
 	// B8 idempotency: claim the event atomically. If the row already exists
 	// (Stripe retried), RowsAffected is 0 and we skip reprocessing.
@@ -861,21 +898,52 @@ func stripeWebhookHandler(c *gin.Context) {
 			return
 		}
 		customerID := session.Customer.ID
-		updateUserAccountType(customerID, "paid")
+		// The tier was stamped into metadata at checkout; sessions from before
+		// tiers (or created elsewhere) fall back to the legacy "paid".
+		tier := session.Metadata["tier"]
+		if !billingTiers[tier] {
+			tier = "paid"
+		}
+		updateUserAccountType(customerID, tier)
 		// First paid conversion of a referred user → credit the referrer
 		// (idempotent; see referral.go).
 		awardReferralForStripeCustomer(customerID)
 
-	case "customer.subscription.updated":
-		// Renewal/cancel/reactivation: reconcile tier from the live status so a
-		// failed renewal (past_due) downgrades and a recovery re-upgrades.
+	case "customer.subscription.created", "customer.subscription.updated":
+		// Renewal/cancel/reactivation/plan change: reconcile tier from the live
+		// status and price so a failed renewal (past_due) downgrades, a recovery
+		// re-upgrades, and a Starter↔Premium switch lands on the new tier.
+		// Also tracks the trial: trialing records TrialEndsAt, and the
+		// trialing→active conversion clears it.
 		var sub stripe.Subscription
 		if err := json.Unmarshal(event.Data.Raw, &sub); err != nil {
 			log.Printf("⚠️ Failed to parse subscription update: %v", err)
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse subscription"})
 			return
 		}
-		updateUserAccountType(sub.Customer.ID, accountTypeForSubStatus(sub.Status))
+		syncUserSubscription(&sub)
+
+	case "customer.subscription.trial_will_end":
+		// Sent ~3 days before the trial ends. Refresh TrialEndsAt in case an
+		// earlier event was missed; the charge outcome arrives as an update.
+		var sub stripe.Subscription
+		if err := json.Unmarshal(event.Data.Raw, &sub); err != nil {
+			log.Printf("⚠️ Failed to parse subscription trial_will_end: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse subscription"})
+			return
+		}
+		syncUserSubscription(&sub)
+
+	case "customer.subscription.paused", "customer.subscription.resumed":
+		// Paused → no access until resumed (status paused maps to free);
+		// resumed → active again on the subscription's tier.
+		var sub stripe.Subscription
+		if err := json.Unmarshal(event.Data.Raw, &sub); err != nil {
+			log.Printf("⚠️ Failed to parse subscription %s: %v", event.Type, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse subscription"})
+			return
+		}
+		syncUserSubscription(&sub)
 
 	case "customer.subscription.deleted":
 		var sub stripe.Subscription
@@ -884,14 +952,23 @@ func stripeWebhookHandler(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse subscription"})
 			return
 		}
-		updateUserAccountType(sub.Customer.ID, "free")
+		// Status is canceled → free, and any trial expiry is cleared.
+		syncUserSubscription(&sub)
 
 	case "invoice.payment_failed":
 		// Grace: do NOT downgrade here. Stripe's dunning retries the charge;
 		// the eventual subscription.updated/deleted handles the downgrade.
+		// The account is only flagged past_due so clients can prompt for a
+		// new card.
 		var inv stripe.Invoice
-		if err := json.Unmarshal(event.Data.Raw, &inv); err == nil {
+		if err := json.Unmarshal(event.Data.Raw, &inv); err != nil {
+			log.Printf("⚠️ Failed to parse invoice: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse invoice"})
+			return
+		}
+		if inv.Customer != nil {
 			log.Printf("⚠️ invoice.payment_failed for customer %s (grace; awaiting retry)", inv.Customer.ID)
+			markSubscriptionPastDue(inv.Customer.ID)
 		}
 
 	default:
@@ -990,21 +1067,21 @@ func profileHandler(c *gin.Context) {
 	})
 }
 
-// authMiddleware validates the JWT token from the Authorization header.
+// authMiddleware validates the JWT token from the Authorization header, or
+// an API key (api_keys.go) sent as X-API-Key when there is no such header.
 func authMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := c.GetHeader(apiKeyHeader); key != "" && c.GetHeader("Authorization") == "" {
+			authenticateAPIKey(c, key)
+			return
+		}
 		tokenString, err := extractToken(c.GetHeader("Authorization"))
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			return
 		}
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Ensure that the token method conforms to what you expect:
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, errors.New("unexpected signing method")
-			}
-			return jwtSecretKey, nil
-		})
+		// jwtKeyFunc pins the configured algorithm (JWT_ALG).
+		token, err := jwt.Parse(tokenString, jwtKeyFunc)
 		if err != nil || !token.Valid {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 			return
@@ -1116,6 +1193,10 @@ func getSubscriptionStatusHandler(c *gin.Context) {
 			"subscription_status": "inactive",
 			"message":             "No active subscription found",
 		}
+		if user.SubscriptionStatus != "" {
+			// e.g. past_due or paused, as last reported by the webhook.
+			resp["subscription_status"] = user.SubscriptionStatus
+		}
 		if user.PremiumUntil != nil && user.PremiumUntil.After(time.Now()) {
 			resp["premium_until"] = user.PremiumUntil.Format(time.RFC3339)
 			resp["message"] = "Premium via referral credit"
@@ -1305,6 +1386,7 @@ func deactivateAccountHandler(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, auditAccountDeactivate, user.ID, gin.H{"reason": req.Reason, "history_id": history.ID})
 	log.Printf("⏸️  Account deactivated: %s (ID: %d) - Reason: %s", user.Email, user.ID, req.Reason)
 	c.JSON(http.StatusOK, gin.H{
 		"message":    "Account deactivated successfully",
@@ -1364,6 +1446,9 @@ func deleteAccountHandler(c *gin.Context) {
 				}
 			}
 		}
+		// Optionally drop the customer and its payment methods as well
+		// (stripe_customer.go).
+		removeStripeCustomer(&user)
 	}
 
 	// 6. Start transaction
@@ -1405,7 +1490,12 @@ func deleteAccountHandler(c *gin.Context) {
 		return
 	}
 
-	// 8. Delete user from active table
+	// 8. Delete user from active table, with its login history
+	if err := deleteLoginEvents(tx, user.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
 	if err := tx.Delete(&user).Error; err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
@@ -1418,7 +1508,10 @@ func deleteAccountHandler(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, auditAccountDelete, user.ID, gin.H{"reason": req.Reason, "history_id": history.ID})
 	log.Printf("🗑️  Account deleted: %s (ID: %d) - Reason: %s", user.Email, user.ID, req.Reason)
+	// Books and audio stay in content-service until the restore window
+	// closes; runContentCleanupSweeper purges them then (content_cleanup.go).
 	c.JSON(http.StatusOK, gin.H{
 		"message":    "Account deleted successfully",
 		"history_id": history.ID,
@@ -1468,9 +1561,8 @@ func restoreAccountHandlerDisabled(c *gin.Context) {
 		return
 	}
 
-	// 2. Check if restoration window has expired (optional: 90 days)
-	daysSinceDeletion := time.Since(history.DeletedAt).Hours() / 24
-	if daysSinceDeletion > 90 {
+	// 2. Check if restoration window has expired; content is purged after it
+	if time.Since(history.DeletedAt) > accountRestoreWindow {
 		c.JSON(http.StatusGone, gin.H{
 			"error":   "Restoration period expired",
 			"message": "Account data was deleted more than 90 days ago and can no longer be restored",
@@ -1547,8 +1639,7 @@ func restoreAccountHandlerDisabled(c *gin.Context) {
 		"exp":      time.Now().Add(time.Hour * 72).Unix(),
 		"iat":      time.Now().Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtSecretKey)
+	tokenString, err := signJWT(claims)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"message":      "Account restored successfully",
@@ -1646,7 +1737,7 @@ func getAdminStatsHandler(c *gin.Context) {
 	db.Model(&User{}).Where("is_admin = ?", false).Count(&stats.TotalUsers)
 
 	// Paid users (excluding admins)
-	db.Model(&User{}).Where("account_type = ? AND is_admin = ?", "paid", false).Count(&stats.PaidUsers)
+	db.Model(&User{}).Where("account_type IN ? AND is_admin = ?", []string{"paid", "starter", "premium"}, false).Count(&stats.PaidUsers)
 
 	// Free users (excluding admins)
 	db.Model(&User{}).Where("account_type = ? AND is_admin = ?", "free", false).Count(&stats.FreeUsers)
@@ -1777,9 +1868,17 @@ func getActiveUsersHandler(c *gin.Context) {
 // POST /admin/users/:user_id/admin
 func makeUserAdminHandler(c *gin.Context) {
 	userID := c.Param("user_id")
+	targetID, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
 
 	type AdminRequest struct {
-		IsAdmin bool `json:"is_admin" binding:"required"`
+		// A pointer so an explicit false passes "required".
+		IsAdmin *bool `json:"is_admin" binding:"required"`
+		// Confirm must be true for an admin to revoke their own access.
+		Confirm bool `json:"confirm"`
 	}
 
 	var req AdminRequest
@@ -1787,26 +1886,63 @@ func makeUserAdminHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
 		return
 	}
+	isAdmin := *req.IsAdmin
+
+	actorID, _ := c.Get("user_id")
+	if !isAdmin && actorID == uint(targetID) && !req.Confirm {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":            "You are about to revoke your own admin access. Resend with \"confirm\": true to proceed.",
+			"confirm_required": true,
+		})
+		return
+	}
 
-	// Update user admin status
-	if err := db.Model(&User{}).Where("id = ?", userID).Update("is_admin", req.IsAdmin).Error; err != nil {
+	// Update user admin status. Admin rows are locked first so two concurrent
+	// demotions can't each see the other as the remaining admin.
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var admins []User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+			Where("is_admin = ?", true).Find(&admins).Error; err != nil {
+			return err
+		}
+		var target User
+		if err := tx.Select("id", "is_admin").First(&target, targetID).Error; err != nil {
+			return err
+		}
+		if !isAdmin && target.IsAdmin && len(admins) <= 1 {
+			return errLastAdmin
+		}
+		return tx.Model(&User{}).Where("id = ?", targetID).Update("is_admin", isAdmin).Error
+	})
+	switch {
+	case errors.Is(err, errLastAdmin):
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot revoke admin access from the last remaining admin"})
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update admin status"})
 		return
 	}
 
-	action := "granted"
-	if !req.IsAdmin {
-		action = "revoked"
+	action, auditAction := "granted", auditAdminGrant
+	if !isAdmin {
+		action, auditAction = "revoked", auditAdminRevoke
 	}
+	recordAudit(c, auditAction, uint(targetID), nil)
 
 	log.Printf("✅ Admin access %s for user ID %s", action, userID)
 	c.JSON(http.StatusOK, gin.H{
 		"message":  fmt.Sprintf("Admin access %s successfully", action),
 		"user_id":  userID,
-		"is_admin": req.IsAdmin,
+		"is_admin": isAdmin,
 	})
 }
 
+// errLastAdmin refuses a demotion that would leave no admin to reach /admin.
+var errLastAdmin = errors.New("last admin")
+
 // ============================================================================
 // MAINTENANCE ENDPOINTS
 // ============================================================================
@@ -1915,6 +2051,13 @@ func wipeSystemHandler(c *gin.Context) {
 		return
 	}
 
+	if err := deleteLoginEvents(tx, userIDs...); err != nil {
+		tx.Rollback()
+		log.Printf("❌ Failed to delete login events: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete login events", "details": err.Error()})
+		return
+	}
+
 	// Delete all non-admin users
 	if err := tx.Where("is_admin = ?", false).Delete(&User{}).Error; err != nil {
 		tx.Rollback()
@@ -2046,6 +2189,12 @@ func deleteUserDataHandler(c *gin.Context) {
 		return
 	}
 
+	if err := deleteLoginEvents(tx, uint(userID)); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete login history"})
+		return
+	}
+
 	// Delete the user
 	if err := tx.Delete(&user).Error; err != nil {
 		tx.Rollback()
@@ -2129,6 +2278,7 @@ func deleteUserCompleteHandler(c *gin.Context) {
 
 	// Delete user_histories (B2: keyed by original_user_id, not user_id).
 	tx.Where("original_user_id = ?", userID).Delete(&UserHistory{})
+	deleteLoginEvents(tx, uint(userID))
 
 	// Delete the user
 	if err := tx.Delete(&user).Error; err != nil {
@@ -2400,6 +2550,7 @@ func appleSignInHandler(c *gin.Context) {
 	}
 
 	log.Printf("✅ Apple Sign In successful for user %s (ID: %d, new: %v)", user.Username, user.ID, isNewUser)
+	recordLoginEvent(c, user.ID, "apple", loginDevice{})
 
 	c.JSON(http.StatusOK, gin.H{
 		"token": token,
@@ -2452,6 +2603,7 @@ func googleSignInHandler(c *gin.Context) {
 	}
 
 	log.Printf("✅ Google Sign In successful for user %s (ID: %d, new: %v)", user.Username, user.ID, isNewUser)
+	recordLoginEvent(c, user.ID, "google", loginDevice{})
 
 	c.JSON(http.StatusOK, gin.H{
 		"token": token,
@@ -2512,6 +2664,7 @@ func facebookLoginHandler(c *gin.Context) {
 	}
 
 	log.Printf("✅ Facebook Login successful for user %s (ID: %d, new: %v)", user.Username, user.ID, isNewUser)
+	recordLoginEvent(c, user.ID, "facebook", loginDevice{})
 
 	c.JSON(http.StatusOK, gin.H{
 		"token": token,
@@ -2982,6 +3135,5 @@ func generateJWTToken(user *User) (string, error) {
 		"iat":          time.Now().Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecretKey)
+	return signJWT(claims)
 }
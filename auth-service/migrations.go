@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Versioned schema migrations. AutoMigrate can only add tables and columns;
+// it can't drop or rename them or backfill data, so every schema change is a
+// migration here instead. Each runs once, in ID order, inside its own
+// transaction, and is recorded in schema_migrations.
+//
+// Rules for new migrations:
+//   - append to authMigrations with the next ID; never edit or reorder
+//     one that has shipped;
+//   - make Up tolerate a fresh database too: 0001 migrates the current
+//     models, so a column added to a model in the same change already exists
+//     there — guard with tx.Migrator().HasColumn/HasTable;
+//   - give it a Down that undoes it, for rollbackLastMigration.
+
+// migration is one versioned schema change.
+type migration struct {
+	ID   string
+	Up   func(tx *gorm.DB) error
+	Down func(tx *gorm.DB) error
+}
+
+// schemaMigration is a row of schema_migrations: one applied migration.
+type schemaMigration struct {
+	ID        string `gorm:"primaryKey;size:255"`
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// migrationLockKey is the Postgres advisory lock that serializes migration
+// runs, so two instances starting together don't apply one twice.
+const migrationLockKey = 7461_0002
+
+// authModels are the tables the initial migration creates.
+func authModels() []interface{} {
+	return []interface{}{
+		&User{}, &UserHistory{}, &UserBookHistory{}, &ProcessedStripeEvent{}, &AuditLog{}, &ReferralCredit{},
+	}
+}
+
+var authMigrations = []migration{
+	{
+		// Baseline: the schema AutoMigrate used to maintain. On a database
+		// that AutoMigrate already manages this is a no-op apart from adding
+		// anything it had not yet added.
+		ID: "0001_initial_schema",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(authModels()...)
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(authModels()...)
+		},
+	},
+	{
+		// audit_logs grows from an admin request log (admin_user_id, target)
+		// into an action log: actor_id, action, target_id, metadata, ip.
+		ID: "0002_audit_log_actions",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if m.HasColumn(&AuditLog{}, "admin_user_id") && !m.HasColumn(&AuditLog{}, "actor_id") {
+				if err := m.RenameColumn(&AuditLog{}, "admin_user_id", "actor_id"); err != nil {
+					return err
+				}
+			}
+			if err := tx.AutoMigrate(&AuditLog{}); err != nil {
+				return err
+			}
+			if m.HasColumn(&AuditLog{}, "admin_user_id") {
+				// Both existed (AutoMigrate ran against the new model first).
+				if err := tx.Exec(`UPDATE audit_logs SET actor_id = admin_user_id WHERE COALESCE(actor_id, 0) = 0`).Error; err != nil {
+					return err
+				}
+				if err := m.DropColumn(&AuditLog{}, "admin_user_id"); err != nil {
+					return err
+				}
+			}
+			if m.HasColumn(&AuditLog{}, "target") {
+				if err := tx.Exec(`UPDATE audit_logs SET target_id = target::bigint
+					WHERE target ~ '^[0-9]+$' AND COALESCE(target_id, 0) = 0`).Error; err != nil {
+					return err
+				}
+				if err := m.DropColumn(&AuditLog{}, "target"); err != nil {
+					return err
+				}
+			}
+			return tx.Exec(`UPDATE audit_logs SET action = ? WHERE COALESCE(action, '') = ''`, auditAdminRequest).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec(`ALTER TABLE audit_logs RENAME COLUMN actor_id TO admin_user_id;
+				ALTER TABLE audit_logs ADD COLUMN target text;
+				UPDATE audit_logs SET target = target_id::text WHERE target_id <> 0;
+				ALTER TABLE audit_logs DROP COLUMN action, DROP COLUMN target_id,
+					DROP COLUMN metadata, DROP COLUMN ip`).Error
+		},
+	},
+	{
+		// Per-user API keys (api_keys.go).
+		ID: "0003_api_keys",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&APIKey{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&APIKey{})
+		},
+	},
+	{
+		// users.subscription_status / cancel_at_period_end: the Stripe
+		// subscription state the webhook last reported.
+		ID: "0004_user_subscription_status",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, col := range []string{"SubscriptionStatus", "CancelAtPeriodEnd"} {
+				if !m.HasColumn(&User{}, col) {
+					if err := m.AddColumn(&User{}, col); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec(`ALTER TABLE users DROP COLUMN subscription_status, DROP COLUMN cancel_at_period_end`).Error
+		},
+	},
+	{
+		// Sign-in history (login_activity.go).
+		ID: "0005_login_events",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&LoginEvent{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&LoginEvent{})
+		},
+	},
+	{
+		// user_histories.content_purged_at: when a deleted account's books
+		// were removed from content-service (content_cleanup.go).
+		ID: "0006_user_history_content_purged_at",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&UserHistory{}, "ContentPurgedAt") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&UserHistory{}, "ContentPurgedAt")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&UserHistory{}, "ContentPurgedAt")
+		},
+	},
+}
+
+// runMigrations applies every migration in ms not yet recorded in
+// schema_migrations. Each one takes the advisory lock and re-checks inside
+// its transaction, so concurrent runners apply it exactly once.
+func runMigrations(db *gorm.DB, ms []migration) error {
+	if err := validateMigrations(ms); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	for _, m := range ms {
+		applied := false
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockKey).Error; err != nil {
+				return err
+			}
+			var n int64
+			if err := tx.Model(&schemaMigration{}).Where("id = ?", m.ID).Count(&n).Error; err != nil {
+				return err
+			}
+			if n > 0 {
+				return nil
+			}
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			applied = true
+			return tx.Create(&schemaMigration{ID: m.ID, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", m.ID, err)
+		}
+		if applied {
+			log.Printf("🗄️ Applied migration %s", m.ID)
+		}
+	}
+	return nil
+}
+
+// rollbackLastMigration reverts the most recently applied migration in ms.
+func rollbackLastMigration(db *gorm.DB, ms []migration) error {
+	var last schemaMigration
+	if err := db.Order("id DESC").First(&last).Error; err != nil {
+		return fmt.Errorf("no applied migration to roll back: %w", err)
+	}
+	for _, m := range ms {
+		if m.ID != last.ID {
+			continue
+		}
+		if m.Down == nil {
+			return fmt.Errorf("migration %s has no down step", m.ID)
+		}
+		return db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockKey).Error; err != nil {
+				return err
+			}
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			log.Printf("🗄️ Rolled back migration %s", m.ID)
+			return tx.Delete(&schemaMigration{}, "id = ?", m.ID).Error
+		})
+	}
+	return fmt.Errorf("applied migration %s is not defined in this build", last.ID)
+}
+
+// validateMigrations rejects a list whose IDs are empty, duplicated or out
+// of order, or whose entries lack an Up step.
+func validateMigrations(ms []migration) error {
+	for i, m := range ms {
+		if m.ID == "" || m.Up == nil {
+			return fmt.Errorf("migration %d needs an ID and an Up step", i)
+		}
+		if i > 0 && m.ID <= ms[i-1].ID {
+			return fmt.Errorf("migration %s must sort after %s", m.ID, ms[i-1].ID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Reading stats and the books-read leaderboard. users.books_read is the
+// finished-books counter content-service moves through
+// POST /internal/users/:user_id/books-read. The leaderboard is opt-in: only
+// public profiles (is_public) that have finished at least one book are
+// ranked, and it shows nothing beyond username and count. Ties share a rank
+// (1, 2, 2, 4), and equal counts list the earlier account first.
+
+// leaderboardEntry is one ranked reader.
+type leaderboardEntry struct {
+	Rank      int    `json:"rank"`
+	Username  string `json:"username"`
+	BooksRead int    `json:"books_read"`
+}
+
+// leaderboardWhere scopes a query to the users eligible for ranking.
+const leaderboardWhere = "is_public = ? AND books_read > 0"
+
+// leaderboardPage returns ranked readers from offset, at most limit of them,
+// and how many readers are ranked in all.
+func leaderboardPage(limit, offset int) ([]leaderboardEntry, int64, error) {
+	var total int64
+	if err := db.Model(&User{}).Where(leaderboardWhere, true).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	entries := []leaderboardEntry{}
+	err := db.Model(&User{}).
+		Select("RANK() OVER (ORDER BY books_read DESC) AS rank, username, books_read").
+		Where(leaderboardWhere, true).
+		Order("books_read DESC, id ASC").
+		Limit(limit).Offset(offset).
+		Scan(&entries).Error
+	return entries, total, err
+}
+
+// leaderboardRank is user's rank, or 0 when they aren't on the leaderboard.
+func leaderboardRank(user User) (int, error) {
+	if !user.IsPublic || user.BooksRead <= 0 {
+		return 0, nil
+	}
+	var ahead int64
+	if err := db.Model(&User{}).Where(leaderboardWhere+" AND books_read > ?", true, user.BooksRead).
+		Count(&ahead).Error; err != nil {
+		return 0, err
+	}
+	return int(ahead) + 1, nil
+}
+
+// leaderboardHandler lists the top public readers.
+// GET /leaderboard?limit=20&offset=0 (public)
+func leaderboardHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative number"})
+		return
+	}
+	entries, total, err := leaderboardPage(limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load leaderboard"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "total": total, "limit": limit, "offset": offset})
+}
+
+// readingStatsHandler returns the caller's books_read and leaderboard
+// position (null while their profile is private or they haven't finished a
+// book).
+// GET /user/reading-stats
+func readingStatsHandler(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	var user User
+	if err := db.Select("id", "is_public", "books_read").First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	rank, err := leaderboardRank(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load leaderboard position"})
+		return
+	}
+	resp := gin.H{
+		"books_read":     user.BooksRead,
+		"is_public":      user.IsPublic,
+		"on_leaderboard": rank > 0,
+		"rank":           nil,
+	}
+	if rank > 0 {
+		resp["rank"] = rank
+	} else if !user.IsPublic {
+		resp["note"] = "Make your profile public to appear on the leaderboard"
+	}
+	c.JSON(http.StatusOK, resp)
+}
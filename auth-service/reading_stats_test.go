@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func createReader(t *testing.T, name string, booksRead int, public bool) (User, string) {
+	t.Helper()
+	u, token := createAuditTestUser(t, name, false)
+	if err := db.Model(&u).Updates(map[string]interface{}{"books_read": booksRead, "is_public": public}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return u, token
+}
+
+func TestLeaderboard_PublicOnlyAndOrdered(t *testing.T) {
+	withTestDB(t)
+	createReader(t, "ada", 3, true)
+	createReader(t, "hidden", 9, false) // most books, but private
+	createReader(t, "bo", 7, true)
+	createReader(t, "cy", 3, true)
+	createReader(t, "none", 0, true) // nothing finished yet
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/leaderboard", leaderboardHandler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/leaderboard", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Entries []leaderboardEntry `json:"entries"`
+		Total   int64              `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	want := []leaderboardEntry{{1, "bo", 7}, {2, "ada", 3}, {2, "cy", 3}}
+	if body.Total != 3 || len(body.Entries) != len(want) {
+		t.Fatalf("leaderboard = %+v (total %d), want %+v", body.Entries, body.Total, want)
+	}
+	for i := range want {
+		if body.Entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, body.Entries[i], want[i])
+		}
+	}
+
+	// Ranks are global, not per page.
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/leaderboard?limit=1&offset=2", nil))
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if len(body.Entries) != 1 || body.Entries[0] != want[2] {
+		t.Errorf("page 3 = %+v, want %+v", body.Entries, want[2])
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/leaderboard?limit=500", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("limit=500: status %d, want 400", w.Code)
+	}
+}
+
+func TestReadingStats(t *testing.T) {
+	withTestDB(t)
+	createReader(t, "leader", 10, true)
+	_, publicToken := createReader(t, "runner", 4, true)
+	_, privateToken := createReader(t, "quiet", 20, false)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/user/reading-stats", authMiddleware(), readingStatsHandler)
+	stats := func(token string) map[string]interface{} {
+		w := auditRequest(r, http.MethodGet, "/user/reading-stats", token, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body)
+		}
+		var m map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &m)
+		return m
+	}
+
+	if m := stats(publicToken); m["books_read"] != float64(4) || m["rank"] != float64(2) || m["on_leaderboard"] != true {
+		t.Errorf("public stats = %v", m)
+	}
+	// The private reader's count is theirs to see, but they aren't ranked and
+	// don't push anyone down.
+	if m := stats(privateToken); m["books_read"] != float64(20) || m["rank"] != nil || m["on_leaderboard"] != false {
+		t.Errorf("private stats = %v", m)
+	}
+}
@@ -4,7 +4,8 @@ package main
 //
 //   1. Every user has a shareable referral code (lazily generated) and an
 //      invite link https://narrafied.com/invite/{code}.
-//   2. A new user passes referral_code at signup → users.referred_by is set.
+//   2. A new user passes referral_code (or referred_by) at signup →
+//      users.referred_by is set. The new account gets its own code at signup.
 //   3. When the referred account converts to PAID (Stripe checkout completes
 //      or an Apple IAP receipt is validated), the referrer earns ONE free
 //      month of premium, tracked as a ReferralCredit row and materialized as
@@ -90,6 +91,15 @@ func ensureReferralCode(user *User) (string, error) {
 	return "", lastErr
 }
 
+// referralCode is the invite code a signup carries, from referral_code or
+// its referred_by alias.
+func (req SignupRequest) referralCode() string {
+	if strings.TrimSpace(req.ReferralCode) != "" {
+		return req.ReferralCode
+	}
+	return req.ReferredBy
+}
+
 // resolveReferralCode maps a signup's referral code to the referrer's user id.
 // Returns 0 (organic signup) for empty/unknown codes or a device-fingerprint
 // self-referral. Invalid codes never block signup.
@@ -117,14 +127,17 @@ func resolveReferralCode(rawCode, signupDeviceID string) uint {
 // /user/account-type, profile, subscription status) must go through this, and
 // content-service's metering keys its per-tier limits on the exact value.
 func effectiveAccountType(user *User) string {
+	lapsed := trialLapsed(user, time.Now()) // Stripe trial ended unconverted (stripe_trial.go)
 	switch user.AccountType {
 	case "starter", "premium", "paid": // "paid" retained for pre-tier subscribers
-		return user.AccountType
+		if !lapsed {
+			return user.AccountType
+		}
 	}
 	if user.PremiumUntil != nil && user.PremiumUntil.After(time.Now()) {
 		return "premium"
 	}
-	if user.AccountType == "" {
+	if user.AccountType == "" || lapsed {
 		return "free"
 	}
 	return user.AccountType
@@ -227,14 +240,7 @@ func getReferralInfoHandler(c *gin.Context) {
 		return
 	}
 
-	var invitedCount int64
-	db.Model(&User{}).Where("referred_by = ?", user.ID).Count(&invitedCount)
-
-	var convertedCount int64
-	var monthsEarned int64
-	db.Model(&ReferralCredit{}).Where("referrer_user_id = ?", user.ID).Count(&convertedCount)
-	db.Model(&ReferralCredit{}).Where("referrer_user_id = ?", user.ID).
-		Select("COALESCE(SUM(months), 0)").Scan(&monthsEarned)
+	stats := referralStatsFor(user.ID)
 
 	inviteBase := strings.TrimRight(getEnv("INVITE_BASE_URL", "https://narrafied.com/invite"), "/")
 	inviteURL := inviteBase + "/" + code
@@ -243,9 +249,9 @@ func getReferralInfoHandler(c *gin.Context) {
 		"referral_code":      code,
 		"invite_url":         inviteURL,
 		"share_text":         "I turn my documents into AI-narrated audiobooks with Narrafied. Sign up with my invite code " + code + ": " + inviteURL,
-		"invited_count":      invitedCount,
-		"converted_count":    convertedCount,
-		"free_months_earned": monthsEarned,
+		"invited_count":      stats.Invited,
+		"converted_count":    stats.Converted,
+		"free_months_earned": stats.MonthsEarned,
 	}
 	if user.PremiumUntil != nil {
 		resp["premium_until"] = user.PremiumUntil.Format(time.RFC3339)
@@ -253,6 +259,36 @@ func getReferralInfoHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// referralStats summarizes one referrer's program results.
+type referralStats struct {
+	Invited      int64 `json:"invited_count"`      // accounts that signed up with the code
+	Converted    int64 `json:"converted_count"`    // of those, accounts that went paid
+	MonthsEarned int64 `json:"free_months_earned"` // credit months awarded
+}
+
+func referralStatsFor(userID uint) referralStats {
+	var s referralStats
+	db.Model(&User{}).Where("referred_by = ?", userID).Count(&s.Invited)
+	db.Model(&ReferralCredit{}).Where("referrer_user_id = ?", userID).Count(&s.Converted)
+	db.Model(&ReferralCredit{}).Where("referrer_user_id = ?", userID).
+		Select("COALESCE(SUM(months), 0)").Scan(&s.MonthsEarned)
+	return s
+}
+
+// getReferralsHandler — GET /user/referrals
+// How many accounts the caller has referred, and how many of them converted:
+// the counts of GET /user/referral, under the same names, without minting a
+// code.
+func getReferralsHandler(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	userID := uint(claims.(jwt.MapClaims)["user_id"].(float64))
+	c.JSON(http.StatusOK, referralStatsFor(userID))
+}
+
 // ValidateReceiptRequest matches what the iOS AppleIAPManager already sends.
 type ValidateReceiptRequest struct {
 	// SignedTransaction is the StoreKit 2 JWS (Transaction.jwsRepresentation) —
@@ -1,9 +1,15 @@
 package main
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
 )
 
 func TestGenerateReferralCode(t *testing.T) {
@@ -75,3 +81,88 @@ func TestEffectiveAccountType(t *testing.T) {
 		}
 	}
 }
+
+func TestSignupRequestReferralCode(t *testing.T) {
+	cases := []struct {
+		req  SignupRequest
+		want string
+	}{
+		{SignupRequest{ReferralCode: "ABC234"}, "ABC234"},
+		{SignupRequest{ReferredBy: "XYZ789"}, "XYZ789"},
+		{SignupRequest{ReferralCode: "ABC234", ReferredBy: "XYZ789"}, "ABC234"},
+		{SignupRequest{ReferralCode: "  ", ReferredBy: "XYZ789"}, "XYZ789"},
+		{SignupRequest{}, ""},
+	}
+	for _, tc := range cases {
+		if got := tc.req.referralCode(); got != tc.want {
+			t.Errorf("%+v.referralCode() = %q, want %q", tc.req, got, tc.want)
+		}
+	}
+}
+
+// withTestDB points the package db at a freshly migrated schema.
+func withTestDB(t *testing.T) {
+	t.Helper()
+	tx := freshSchemaDB(t)
+	if err := runMigrations(tx, authMigrations); err != nil {
+		t.Fatal(err)
+	}
+	orig := db
+	db = tx
+	t.Cleanup(func() { db = orig })
+}
+
+func TestReferralAttributionAndCount(t *testing.T) {
+	withTestDB(t)
+
+	referrer := User{Username: "referrer", Email: "referrer@example.com", DeviceID: "device-a"}
+	if err := db.Create(&referrer).Error; err != nil {
+		t.Fatal(err)
+	}
+	code, err := ensureReferralCode(&referrer)
+	if err != nil || code == "" {
+		t.Fatalf("ensureReferralCode = %q, %v", code, err)
+	}
+
+	// Codes are unique per account.
+	other := User{Username: "other", Email: "other@example.com"}
+	db.Create(&other)
+	if otherCode, _ := ensureReferralCode(&other); otherCode == code {
+		t.Fatalf("two users share code %q", code)
+	}
+
+	// Lower-case input resolves; the referrer's own device does not.
+	if got := resolveReferralCode(strings.ToLower(code), "device-b"); got != referrer.ID {
+		t.Fatalf("resolveReferralCode = %d, want %d", got, referrer.ID)
+	}
+	if got := resolveReferralCode(code, "device-a"); got != 0 {
+		t.Fatalf("self-referral resolved to %d", got)
+	}
+	if got := resolveReferralCode("NOPE99", ""); got != 0 {
+		t.Fatalf("unknown code resolved to %d", got)
+	}
+
+	for _, name := range []string{"invitee1", "invitee2"} {
+		db.Create(&User{Username: name, Email: name + "@example.com", ReferredBy: referrer.ID})
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	asReferrer := func(c *gin.Context) { c.Set("claims", jwt.MapClaims{"user_id": float64(referrer.ID)}) }
+	r.GET("/user/referrals", asReferrer, getReferralsHandler)
+	r.GET("/user/referral", asReferrer, getReferralInfoHandler)
+	for _, path := range []string{"/user/referrals", "/user/referral"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", path, w.Code, w.Body)
+		}
+		var got map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got["invited_count"] != float64(2) || got["converted_count"] != float64(0) {
+			t.Fatalf("GET %s = %v, want invited_count 2", path, got)
+		}
+	}
+}
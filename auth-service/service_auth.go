@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ---- Service-to-service auth (internal endpoints) ----
+//
+// Internal callers (content-service) sign each request with the shared
+// SERVICE_SECRET, and our calls to content-service's /internal group are
+// signed the same way (signServiceRequest):
+//
+//	X-Service-Timestamp: unix seconds
+//	X-Service-Nonce:     random, single-use
+//	X-Service-Signature: hex(HMAC-SHA256(secret, method\ntarget\ntimestamp\nnonce\nhex(sha256(body))))
+//
+// target is the path plus "?" and the raw query when there is one, so the
+// query is signed too. A request is accepted once, within serviceAuthMaxSkew
+// of its timestamp.
+// content-service/service_auth.go mirrors this; keep them in sync.
+
+const serviceAuthMaxSkew = 5 * time.Minute
+
+// serviceNonceStore remembers nonces seen inside the skew window so a captured
+// request can't be replayed (same shape as wipeNonceStore).
+var serviceNonceStore = struct {
+	sync.Mutex
+	seen map[string]time.Time // nonce -> expiry
+}{seen: map[string]time.Time{}}
+
+// serviceTarget is the signed request target: path, plus the raw query.
+func serviceTarget(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Path
+	}
+	return u.Path + "?" + u.RawQuery
+}
+
+// serviceSignature computes the request signature for the scheme above.
+func serviceSignature(secret, method, target, timestamp, nonce string, body []byte) string {
+	bodySum := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n" + target + "\n" + timestamp + "\n" + nonce + "\n" + hex.EncodeToString(bodySum[:])))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signServiceRequest stamps req with a fresh timestamp, nonce and signature.
+// body must be the exact bytes sent (nil for no body).
+func signServiceRequest(req *http.Request, secret string, body []byte) error {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := hex.EncodeToString(b)
+	req.Header.Set("X-Service-Timestamp", ts)
+	req.Header.Set("X-Service-Nonce", nonce)
+	req.Header.Set("X-Service-Signature", serviceSignature(secret, req.Method, serviceTarget(req.URL), ts, nonce, body))
+	return nil
+}
+
+// rememberServiceNonce records a nonce; false if it was already used.
+func rememberServiceNonce(nonce string, now time.Time) bool {
+	serviceNonceStore.Lock()
+	defer serviceNonceStore.Unlock()
+	for n, exp := range serviceNonceStore.seen {
+		if now.After(exp) {
+			delete(serviceNonceStore.seen, n)
+		}
+	}
+	if exp, ok := serviceNonceStore.seen[nonce]; ok && now.Before(exp) {
+		return false
+	}
+	// Entries must outlive the whole window a timestamp is accepted in.
+	serviceNonceStore.seen[nonce] = now.Add(2 * serviceAuthMaxSkew)
+	return true
+}
+
+// serviceAuthMiddleware guards internal endpoints with the HMAC scheme above.
+// Fails closed: with no SERVICE_SECRET configured every call is refused.
+func serviceAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := getEnv("SERVICE_SECRET", "")
+		if secret == "" {
+			log.Printf("⚠️ internal call to %s refused: SERVICE_SECRET not set", c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "service auth not configured"})
+			return
+		}
+
+		ts := c.GetHeader("X-Service-Timestamp")
+		nonce := c.GetHeader("X-Service-Nonce")
+		sig := c.GetHeader("X-Service-Signature")
+		if ts == "" || nonce == "" || sig == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing service signature"})
+			return
+		}
+		unix, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid service timestamp"})
+			return
+		}
+		now := time.Now()
+		if d := now.Sub(time.Unix(unix, 0)); d > serviceAuthMaxSkew || d < -serviceAuthMaxSkew {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "stale service request"})
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			if body, err = io.ReadAll(c.Request.Body); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "could not read body"})
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		want := serviceSignature(secret, c.Request.Method, serviceTarget(c.Request.URL), ts, nonce, body)
+		if !hmac.Equal([]byte(sig), []byte(want)) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid service signature"})
+			return
+		}
+		// Only a correctly signed request may burn a nonce.
+		if !rememberServiceNonce(nonce, now) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "replayed service request"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// internalAccountTypeHandler (GET /internal/users/:user_id/account-type)
+// reports a user's effective tier to a trusted service.
+func internalAccountTypeHandler(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+	var user User
+	if err := db.First(&user, uint(userID)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"account_type": effectiveAccountType(&user)})
+}
+
+// internalBooksReadHandler (POST /internal/users/:user_id/books-read)
+// moves a user's books_read counter when content-service marks a book
+// finished (+1) or unfinished (-1). The counter never goes below zero.
+func internalBooksReadHandler(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+	var req struct {
+		Delta int `json:"delta"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Delta != 1 && req.Delta != -1) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "delta must be 1 or -1"})
+		return
+	}
+	res := db.Model(&User{}).Where("id = ?", uint(userID)).
+		UpdateColumn("books_read", gorm.Expr("GREATEST(books_read + ?, 0)", req.Delta))
+	if res.Error != nil {
+		log.Printf("❌ books_read update for user %d: %v", userID, res.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
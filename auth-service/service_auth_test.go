@@ -0,0 +1,117 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Shared vector — content-service/service_auth_test.go asserts the same value
+// so the signer and verifier can't drift apart.
+func TestServiceSignatureVector(t *testing.T) {
+	got := serviceSignature("s3cret", "GET", "/internal/users/7/account-type", "1700000000", "n1", nil)
+	if want := "b58a11abc116428124fc37afafb85e7f6c96e72436ed8e4a740ea120d9e29962"; got != want {
+		t.Fatalf("serviceSignature = %q, want %q", got, want)
+	}
+}
+
+func TestServiceAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("SERVICE_SECRET", "s3cret")
+	r := gin.New()
+	r.POST("/internal/ping", serviceAuthMiddleware(), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	send := func(path, body, ts, nonce, sig string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("X-Service-Timestamp", ts)
+		req.Header.Set("X-Service-Nonce", nonce)
+		req.Header.Set("X-Service-Signature", sig)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	body := `{"book_id":7}`
+	sig := serviceSignature("s3cret", "POST", "/internal/ping", now, "nonce-a", []byte(body))
+
+	if code := send("/internal/ping", body, now, "nonce-a", sig); code != http.StatusNoContent {
+		t.Fatalf("valid signed call: got %d", code)
+	}
+	if code := send("/internal/ping", body, now, "nonce-a", sig); code != http.StatusUnauthorized {
+		t.Errorf("replayed call: got %d, want 401", code)
+	}
+
+	sigB := serviceSignature("s3cret", "POST", "/internal/ping", now, "nonce-b", []byte(body))
+	if code := send("/internal/ping", `{"book_id":8}`, now, "nonce-b", sigB); code != http.StatusUnauthorized {
+		t.Errorf("tampered body: got %d, want 401", code)
+	}
+	// The tampered attempt must not have burned the nonce.
+	if code := send("/internal/ping", body, now, "nonce-b", sigB); code != http.StatusNoContent {
+		t.Errorf("nonce burned by rejected call: got %d", code)
+	}
+
+	// The query is signed: changing it invalidates the signature.
+	sigQ := serviceSignature("s3cret", "POST", "/internal/ping?dry_run=1", now, "nonce-q", []byte(body))
+	if code := send("/internal/ping?dry_run=0", body, now, "nonce-q", sigQ); code != http.StatusUnauthorized {
+		t.Errorf("tampered query: got %d, want 401", code)
+	}
+	if code := send("/internal/ping?dry_run=1", body, now, "nonce-q", sigQ); code != http.StatusNoContent {
+		t.Errorf("signed query: got %d", code)
+	}
+
+	old := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+	sigOld := serviceSignature("s3cret", "POST", "/internal/ping", old, "nonce-c", []byte(body))
+	if code := send("/internal/ping", body, old, "nonce-c", sigOld); code != http.StatusUnauthorized {
+		t.Errorf("stale timestamp: got %d, want 401", code)
+	}
+	if code := send("/internal/ping", body, now, "nonce-d", ""); code != http.StatusUnauthorized {
+		t.Errorf("unsigned call: got %d, want 401", code)
+	}
+
+	t.Setenv("SERVICE_SECRET", "")
+	if code := send("/internal/ping", body, now, "nonce-e", sig); code != http.StatusServiceUnavailable {
+		t.Errorf("no secret configured: got %d, want 503", code)
+	}
+}
+
+func TestInternalBooksRead(t *testing.T) {
+	withTestDB(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/internal/users/:user_id/books-read", internalBooksReadHandler)
+	user := User{Username: "bookworm", Email: "bookworm@example.com", AccountType: "free"}
+	db.Create(&user)
+	send := func(id uint, body string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/internal/users/"+strconv.Itoa(int(id))+"/books-read", strings.NewReader(body)))
+		return w.Code
+	}
+	booksRead := func() int {
+		var u User
+		db.First(&u, user.ID)
+		return u.BooksRead
+	}
+
+	send(user.ID, `{"delta":1}`)
+	send(user.ID, `{"delta":1}`)
+	if got := booksRead(); got != 2 {
+		t.Fatalf("books_read after two finishes = %d, want 2", got)
+	}
+	for i := 0; i < 3; i++ {
+		send(user.ID, `{"delta":-1}`)
+	}
+	if got := booksRead(); got != 0 {
+		t.Fatalf("books_read = %d, want floored at 0", got)
+	}
+	if code := send(user.ID, `{"delta":5}`); code != http.StatusBadRequest {
+		t.Errorf("delta 5 = %d, want 400", code)
+	}
+	if code := send(user.ID+1000, `{"delta":1}`); code != http.StatusNotFound {
+		t.Errorf("unknown user = %d, want 404", code)
+	}
+}
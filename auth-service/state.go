@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Signup State normalization. The field was free text, so one state arrived
+// as "CA", "California", "calif" and "Cali", which split geo analytics and
+// state-based discovery. For countries with a table below the value must name
+// a known region and is stored as its two-letter code; elsewhere any
+// plausible region name is kept, tidied.
+
+// regionAliases maps country → lookup key → canonical code. Keys are
+// lower-cased with periods dropped (see stateKey); each code also matches its
+// full name and the listed abbreviations.
+var regionAliases = map[string]map[string]string{
+	"US": buildRegionAliases([][]string{
+		{"AL", "alabama", "ala"}, {"AK", "alaska"}, {"AZ", "arizona", "ariz"},
+		{"AR", "arkansas", "ark"}, {"CA", "california", "calif", "cal", "cali"},
+		{"CO", "colorado", "colo"}, {"CT", "connecticut", "conn"},
+		{"DE", "delaware", "del"}, {"FL", "florida", "fla"}, {"GA", "georgia"},
+		{"HI", "hawaii"}, {"ID", "idaho"}, {"IL", "illinois", "ill"},
+		{"IN", "indiana", "ind"}, {"IA", "iowa"}, {"KS", "kansas", "kan", "kans"},
+		{"KY", "kentucky"}, {"LA", "louisiana"}, {"ME", "maine"},
+		{"MD", "maryland"}, {"MA", "massachusetts", "mass"},
+		{"MI", "michigan", "mich"}, {"MN", "minnesota", "minn"},
+		{"MS", "mississippi", "miss"}, {"MO", "missouri"}, {"MT", "montana", "mont"},
+		{"NE", "nebraska", "neb", "nebr"}, {"NV", "nevada", "nev"},
+		{"NH", "new hampshire"}, {"NJ", "new jersey"}, {"NM", "new mexico"},
+		{"NY", "new york"}, {"NC", "north carolina"}, {"ND", "north dakota"},
+		{"OH", "ohio"}, {"OK", "oklahoma", "okla"}, {"OR", "oregon", "ore"},
+		{"PA", "pennsylvania", "penn", "penna"}, {"RI", "rhode island"},
+		{"SC", "south carolina"}, {"SD", "south dakota"}, {"TN", "tennessee", "tenn"},
+		{"TX", "texas", "tex"}, {"UT", "utah"}, {"VT", "vermont"},
+		{"VA", "virginia"}, {"WA", "washington", "wash"},
+		{"WV", "west virginia", "west va"}, {"WI", "wisconsin", "wis", "wisc"},
+		{"WY", "wyoming", "wyo"},
+		{"DC", "district of columbia", "washington dc", "dc"},
+		{"PR", "puerto rico"}, {"GU", "guam"}, {"VI", "us virgin islands", "virgin islands"},
+		{"AS", "american samoa"}, {"MP", "northern mariana islands"},
+	}),
+	"CA": buildRegionAliases([][]string{
+		{"AB", "alberta", "alta"}, {"BC", "british columbia"}, {"MB", "manitoba", "man"},
+		{"NB", "new brunswick"}, {"NL", "newfoundland and labrador", "newfoundland"},
+		{"NS", "nova scotia"}, {"NT", "northwest territories"}, {"NU", "nunavut"},
+		{"ON", "ontario", "ont"}, {"PE", "prince edward island", "pei"},
+		{"QC", "quebec", "québec", "que"}, {"SK", "saskatchewan", "sask"},
+		{"YT", "yukon"},
+	}),
+}
+
+// countryAliases maps common country inputs to ISO 3166-1 alpha-2.
+var countryAliases = map[string]string{
+	"": "US", "usa": "US", "united states": "US", "united states of america": "US",
+	"can": "CA", "canada": "CA",
+}
+
+// directionWords expand a leading "N Carolina", "S Dakota", "W Va".
+var directionWords = map[string]string{"n": "north", "s": "south", "w": "west"}
+
+func buildRegionAliases(rows [][]string) map[string]string {
+	m := map[string]string{}
+	for _, row := range rows {
+		for _, key := range row {
+			m[stateKey(key)] = row[0]
+		}
+	}
+	return m
+}
+
+// stateKey folds an input for lookup: lower-case, periods dropped, inner
+// whitespace collapsed ("  Calif. " → "calif", "Washington, D.C." → "washington dc").
+func stateKey(s string) string {
+	s = strings.NewReplacer(".", "", ",", " ").Replace(strings.ToLower(s))
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// normalizeCountry returns the ISO code for country ("" means US, the app's
+// main market); unknown inputs are upper-cased and passed through.
+func normalizeCountry(country string) string {
+	if iso, ok := countryAliases[stateKey(country)]; ok {
+		return iso
+	}
+	return strings.ToUpper(strings.TrimSpace(country))
+}
+
+// normalizeState returns the canonical State for a signup in country. For a
+// country in regionAliases the value must be a known region (code, name or
+// common abbreviation) and comes back as its code. Elsewhere it is accepted
+// if it looks like a place name — letters, spaces and - ' . , only — and is
+// returned with whitespace tidied.
+func normalizeState(state, country string) (string, error) {
+	country = normalizeCountry(country)
+	key := stateKey(state)
+	if key == "" {
+		return "", fmt.Errorf("state is required")
+	}
+	if aliases, ok := regionAliases[country]; ok {
+		if first, rest, ok := strings.Cut(key, " "); ok && directionWords[first] != "" {
+			key = directionWords[first] + " " + rest
+		}
+		if code, ok := aliases[key]; ok {
+			return code, nil
+		}
+		return "", fmt.Errorf("%q is not a recognized state or province for %s", strings.TrimSpace(state), country)
+	}
+
+	tidy := strings.Join(strings.Fields(state), " ")
+	if len(tidy) > 64 {
+		return "", fmt.Errorf("state is too long")
+	}
+	for _, r := range tidy {
+		if !unicode.IsLetter(r) && !unicode.IsMark(r) && !strings.ContainsRune(" -'.,", r) {
+			return "", fmt.Errorf("state contains invalid characters")
+		}
+	}
+	return tidy, nil
+}
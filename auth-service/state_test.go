@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestNormalizeState_AliasesMapToCode(t *testing.T) {
+	cases := []struct{ state, country, want string }{
+		{"CA", "", "CA"},
+		{"California", "", "CA"},
+		{"  calif. ", "US", "CA"},
+		{"Cali", "usa", "CA"},
+		{"n carolina", "", "NC"},
+		{"N. Carolina", "", "NC"},
+		{"W Va", "", "WV"},
+		{"Washington, D.C.", "", "DC"},
+		{"penna", "United States", "PA"},
+		{"Québec", "CA", "QC"},
+		{"ont", "Canada", "ON"},
+		{"  Île-de-France ", "FR", "Île-de-France"},
+		{"Greater   London", "GB", "Greater London"},
+	}
+	for _, tc := range cases {
+		got, err := normalizeState(tc.state, tc.country)
+		if err != nil || got != tc.want {
+			t.Errorf("normalizeState(%q, %q) = %q, %v; want %q", tc.state, tc.country, got, err, tc.want)
+		}
+	}
+}
+
+func TestNormalizeState_RejectsInvalid(t *testing.T) {
+	cases := []struct{ state, country string }{
+		{"Narnia", ""},
+		{"ZZ", "US"},
+		{"California", "CA"}, // a US state isn't a Canadian province
+		{"   ", "FR"},
+		{"<script>", "FR"},
+		{"12345", "DE"},
+	}
+	for _, tc := range cases {
+		if got, err := normalizeState(tc.state, tc.country); err == nil {
+			t.Errorf("normalizeState(%q, %q) = %q, want an error", tc.state, tc.country, got)
+		}
+	}
+}
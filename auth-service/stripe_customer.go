@@ -0,0 +1,44 @@
+package main
+
+import (
+	"log"
+
+	"github.com/stripe/stripe-go/v78"
+	"github.com/stripe/stripe-go/v78/customer"
+)
+
+// Stripe customer cleanup on account deletion. Canceling the subscriptions
+// leaves the Customer object, with its saved payment methods, in Stripe
+// indefinitely. With STRIPE_DELETE_CUSTOMER_ON_ACCOUNT_DELETE=true the
+// customer is deleted too and the stored customer ID dropped, so the history
+// kept for restores no longer points at it; a restored account gets a new
+// customer at its next checkout. Off by default.
+
+// stripeDeleteCustomerOnAccountDelete reports whether deletion removes the
+// Stripe customer.
+func stripeDeleteCustomerOnAccountDelete() bool {
+	return getEnv("STRIPE_DELETE_CUSTOMER_ON_ACCOUNT_DELETE", "false") == "true"
+}
+
+// deleteStripeCustomer deletes one Stripe customer; swappable in tests.
+var deleteStripeCustomer = func(customerID string) error {
+	stripe.Key = getEnv("STRIPE_SECRET_KEY", "")
+	_, err := customer.Del(customerID, nil)
+	return err
+}
+
+// removeStripeCustomer deletes user's Stripe customer when enabled and clears
+// user.StripeCustomerID on success. A Stripe error is logged and the ID kept,
+// so account deletion still goes ahead and the customer can be removed by
+// hand later.
+func removeStripeCustomer(user *User) {
+	if user.StripeCustomerID == "" || !stripeDeleteCustomerOnAccountDelete() {
+		return
+	}
+	if err := deleteStripeCustomer(user.StripeCustomerID); err != nil {
+		log.Printf("⚠️  Failed to delete Stripe customer %s for user %d: %v", user.StripeCustomerID, user.ID, err)
+		return
+	}
+	log.Printf("✅ Deleted Stripe customer %s for user %d", user.StripeCustomerID, user.ID)
+	user.StripeCustomerID = ""
+}
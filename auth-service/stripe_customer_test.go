@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func stubDeleteStripeCustomer(t *testing.T, err error) *[]string {
+	t.Helper()
+	var deleted []string
+	orig := deleteStripeCustomer
+	deleteStripeCustomer = func(id string) error {
+		deleted = append(deleted, id)
+		return err
+	}
+	t.Cleanup(func() { deleteStripeCustomer = orig })
+	return &deleted
+}
+
+func TestRemoveStripeCustomer_FlagOn(t *testing.T) {
+	t.Setenv("STRIPE_DELETE_CUSTOMER_ON_ACCOUNT_DELETE", "true")
+	deleted := stubDeleteStripeCustomer(t, nil)
+
+	user := User{ID: 7, StripeCustomerID: "cus_123"}
+	removeStripeCustomer(&user)
+	if len(*deleted) != 1 || (*deleted)[0] != "cus_123" {
+		t.Fatalf("deleted = %v, want [cus_123]", *deleted)
+	}
+	if user.StripeCustomerID != "" {
+		t.Errorf("StripeCustomerID = %q, want cleared", user.StripeCustomerID)
+	}
+}
+
+func TestRemoveStripeCustomer_FlagOff(t *testing.T) {
+	t.Setenv("STRIPE_DELETE_CUSTOMER_ON_ACCOUNT_DELETE", "")
+	deleted := stubDeleteStripeCustomer(t, nil)
+
+	user := User{ID: 7, StripeCustomerID: "cus_123"}
+	removeStripeCustomer(&user)
+	if len(*deleted) != 0 || user.StripeCustomerID != "cus_123" {
+		t.Errorf("deleted = %v, id = %q; want no delete", *deleted, user.StripeCustomerID)
+	}
+}
+
+// A Stripe failure must not block account deletion; the ID is kept so the
+// customer can still be found and removed.
+func TestRemoveStripeCustomer_ErrorKeepsID(t *testing.T) {
+	t.Setenv("STRIPE_DELETE_CUSTOMER_ON_ACCOUNT_DELETE", "true")
+	deleted := stubDeleteStripeCustomer(t, errors.New("stripe down"))
+
+	user := User{ID: 7, StripeCustomerID: "cus_123"}
+	removeStripeCustomer(&user)
+	if len(*deleted) != 1 || user.StripeCustomerID != "cus_123" {
+		t.Errorf("deleted = %v, id = %q", *deleted, user.StripeCustomerID)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/stripe/stripe-go/v78"
+)
+
+// Stripe prices → billing tiers. Like productTier for the App Store, each
+// Stripe price maps to a metering tier that content-service's plan_limits rows
+// key on. Configured as STRIPE_PRICE_TIERS="price_a=starter,price_b=premium"
+// (first price listed for a tier is the one checkout sells). A price missing
+// from the map — e.g. the single STRIPE_PRICE_ID subscribers bought before
+// tiers — keeps the legacy "paid" tier.
+
+// billingTiers are the tiers a Stripe price may grant.
+var billingTiers = map[string]bool{"starter": true, "premium": true, "paid": true}
+
+type stripePriceTier struct {
+	PriceID string
+	Tier    string
+}
+
+// parseStripePriceTiers parses the STRIPE_PRICE_TIERS format, skipping (and
+// logging) malformed pairs and unknown tiers.
+func parseStripePriceTiers(raw string) []stripePriceTier {
+	var out []stripePriceTier
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		price, tier, ok := strings.Cut(pair, "=")
+		price, tier = strings.TrimSpace(price), strings.ToLower(strings.TrimSpace(tier))
+		if !ok || price == "" || !billingTiers[tier] {
+			log.Printf("⚠️ STRIPE_PRICE_TIERS: ignoring %q", pair)
+			continue
+		}
+		out = append(out, stripePriceTier{PriceID: price, Tier: tier})
+	}
+	return out
+}
+
+func stripePriceTiers() []stripePriceTier {
+	return parseStripePriceTiers(getEnv("STRIPE_PRICE_TIERS", ""))
+}
+
+// tierForPrice is the tier a Stripe price grants ("paid" when unmapped).
+func tierForPrice(priceID string) string {
+	for _, pt := range stripePriceTiers() {
+		if pt.PriceID == priceID {
+			return pt.Tier
+		}
+	}
+	return "paid"
+}
+
+// priceForTier is the price checkout sells for tier; "" if none is configured.
+func priceForTier(tier string) string {
+	for _, pt := range stripePriceTiers() {
+		if pt.Tier == tier {
+			return pt.PriceID
+		}
+	}
+	return ""
+}
+
+// subscriptionPriceID is the price of a subscription's (single) line item.
+func subscriptionPriceID(sub *stripe.Subscription) string {
+	if sub == nil || sub.Items == nil {
+		return ""
+	}
+	for _, item := range sub.Items.Data {
+		if item != nil && item.Price != nil {
+			return item.Price.ID
+		}
+	}
+	return ""
+}
+
+// accountTypeForSubscription is the tier a subscription grants right now:
+// its price's tier while the status allows access, otherwise "free".
+func accountTypeForSubscription(sub *stripe.Subscription) string {
+	if accountTypeForSubStatus(sub.Status) == "free" {
+		return "free"
+	}
+	return tierForPrice(subscriptionPriceID(sub))
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/stripe/stripe-go/v78"
+)
+
+// Stripe trials. While a subscription is "trialing" the user gets its tier and
+// User.TrialEndsAt records when the trial runs out. Conversion arrives as a
+// customer.subscription.updated to "active", which clears TrialEndsAt. If that
+// never comes — no card on file, or a missed webhook — the trial is treated as
+// lapsed once TrialEndsAt plus a grace window has passed: effectiveAccountType
+// stops honoring the tier and the sweeper persists the downgrade to free.
+
+// trialGracePeriod is TRIAL_GRACE_HOURS (default 24h). It covers Stripe's
+// first charge attempt at trial end and webhook delivery lag.
+func trialGracePeriod() time.Duration {
+	return time.Duration(envInt("TRIAL_GRACE_HOURS", 24)) * time.Hour
+}
+
+// trialEndsAtFor is the trial expiry a subscription carries, nil unless it is
+// currently trialing.
+func trialEndsAtFor(sub *stripe.Subscription) *time.Time {
+	if sub == nil || sub.Status != stripe.SubscriptionStatusTrialing || sub.TrialEnd == 0 {
+		return nil
+	}
+	t := time.Unix(sub.TrialEnd, 0).UTC()
+	return &t
+}
+
+// trialLapsed reports whether user's trial ended (past the grace window)
+// without Stripe confirming a conversion.
+func trialLapsed(user *User, now time.Time) bool {
+	return user.TrialEndsAt != nil && now.After(user.TrialEndsAt.Add(trialGracePeriod()))
+}
+
+// applySubscription reconciles user's tier, trial expiry and subscription
+// state with sub.
+func applySubscription(user *User, sub *stripe.Subscription) {
+	user.AccountType = accountTypeForSubscription(sub)
+	user.TrialEndsAt = trialEndsAtFor(sub)
+	user.SubscriptionStatus = string(sub.Status)
+	user.CancelAtPeriodEnd = sub.CancelAtPeriodEnd && sub.Status != stripe.SubscriptionStatusCanceled
+}
+
+// syncUserSubscription applies sub to the user owning its Stripe customer.
+func syncUserSubscription(sub *stripe.Subscription) {
+	if sub.Customer == nil {
+		log.Printf("⚠️ subscription %s has no customer", sub.ID)
+		return
+	}
+	var user User
+	if err := db.Where("stripe_customer_id = ?", sub.Customer.ID).First(&user).Error; err != nil {
+		log.Printf("❌ No user found for stripe customer ID: %s", sub.Customer.ID)
+		return
+	}
+	applySubscription(&user, sub)
+	if err := db.Save(&user).Error; err != nil {
+		log.Printf("❌ Failed to sync subscription %s for user %d: %v", sub.ID, user.ID, err)
+		return
+	}
+	if user.TrialEndsAt != nil {
+		log.Printf("✅ User %s on %s trial until %s", user.Email, user.AccountType, user.TrialEndsAt.Format(time.RFC3339))
+	} else {
+		log.Printf("✅ User %s account update to %s", user.Email, user.AccountType)
+	}
+}
+
+// expireLapsedTrials downgrades every user whose trial lapsed without
+// conversion and returns how many were changed.
+func expireLapsedTrials(now time.Time) int64 {
+	res := db.Model(&User{}).
+		Where("trial_ends_at IS NOT NULL AND trial_ends_at < ?", now.Add(-trialGracePeriod())).
+		Updates(map[string]interface{}{"account_type": "free", "trial_ends_at": nil})
+	if res.Error != nil {
+		log.Printf("⚠️ trial expiry sweep failed: %v", res.Error)
+		return 0
+	}
+	if res.RowsAffected > 0 {
+		log.Printf("⏳ Downgraded %d user(s) whose trial lapsed without payment", res.RowsAffected)
+	}
+	return res.RowsAffected
+}
+
+// runTrialExpirySweeper runs expireLapsedTrials hourly for the life of the process.
+func runTrialExpirySweeper() {
+	for {
+		expireLapsedTrials(time.Now())
+		time.Sleep(time.Hour)
+	}
+}
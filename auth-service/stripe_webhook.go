@@ -0,0 +1,67 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/stripe/stripe-go/v78"
+	"github.com/stripe/stripe-go/v78/webhook"
+)
+
+// Stripe webhook verification. The signature's timestamp must fall within
+// STRIPE_WEBHOOK_TOLERANCE_SECONDS (default 300, Stripe's own default) of our
+// clock; raise it if host clock skew makes legitimate deliveries fail.
+//
+// Test-mode events (livemode=false) are signature-checked and processed like
+// live ones, but logged with a 🧪 marker. STRIPE_TEST_MODE=true (staging)
+// marks them as expected; without it the log line also flags that a test-mode
+// event reached this deployment.
+
+// stripeWebhookTolerance is the accepted signature age.
+func stripeWebhookTolerance() time.Duration {
+	secs := envInt("STRIPE_WEBHOOK_TOLERANCE_SECONDS", 300)
+	if secs <= 0 {
+		secs = 300
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// stripeTestMode reports whether test-mode events are expected here.
+func stripeTestMode() bool {
+	return getEnv("STRIPE_TEST_MODE", "false") == "true"
+}
+
+// constructStripeEvent verifies sigHeader over payload and decodes the event.
+// API version mismatches are ignored: the handler only reads stable fields.
+func constructStripeEvent(payload []byte, sigHeader, secret string) (stripe.Event, error) {
+	return webhook.ConstructEventWithOptions(payload, sigHeader, secret, webhook.ConstructEventOptions{
+		Tolerance:                stripeWebhookTolerance(),
+		IgnoreAPIVersionMismatch: true,
+	})
+}
+
+// logStripeEvent logs a verified event, marking test-mode ones.
+func logStripeEvent(event stripe.Event) {
+	switch {
+	case event.Livemode:
+		log.Printf("✅ Webhook received: %s (%s)", event.Type, event.ID)
+	case stripeTestMode():
+		log.Printf("🧪 Test-mode webhook received: %s (%s)", event.Type, event.ID)
+	default:
+		log.Printf("🧪 Test-mode webhook received: %s (%s) — STRIPE_TEST_MODE is off; check which Stripe key sent it", event.Type, event.ID)
+	}
+}
+
+// markSubscriptionPastDue flags the customer's account after a failed
+// renewal charge. The tier is left alone: Stripe's retries decide it.
+func markSubscriptionPastDue(customerID string) {
+	res := db.Model(&User{}).Where("stripe_customer_id = ?", customerID).
+		Update("subscription_status", string(stripe.SubscriptionStatusPastDue))
+	if res.Error != nil {
+		log.Printf("❌ Failed to flag customer %s past_due: %v", customerID, res.Error)
+		return
+	}
+	if res.RowsAffected == 0 {
+		log.Printf("❌ No user found for stripe customer ID: %s", customerID)
+	}
+}
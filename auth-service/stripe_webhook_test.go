@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stripe/stripe-go/v78"
+	"github.com/stripe/stripe-go/v78/webhook"
+)
+
+const testWebhookSecret = "whsec_test"
+
+// signedWebhook signs payload as Stripe would have at ts.
+func signedWebhook(payload string, ts time.Time, secret string) string {
+	return webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{
+		Payload:   []byte(payload),
+		Secret:    secret,
+		Timestamp: ts,
+	}).Header
+}
+
+const testModeEvent = `{"id":"evt_test","object":"event","type":"customer.created","livemode":false,"data":{"object":{}}}`
+
+func TestConstructStripeEvent_Tolerance(t *testing.T) {
+	skewed := time.Now().Add(-7 * time.Minute)
+
+	// Seven minutes behind is outside Stripe's default five...
+	if _, err := constructStripeEvent([]byte(testModeEvent), signedWebhook(testModeEvent, skewed, testWebhookSecret), testWebhookSecret); err == nil {
+		t.Fatal("7-minute-old signature accepted with the default tolerance")
+	}
+	// ...but valid once the tolerance is widened for a skewed clock.
+	t.Setenv("STRIPE_WEBHOOK_TOLERANCE_SECONDS", "600")
+	event, err := constructStripeEvent([]byte(testModeEvent), signedWebhook(testModeEvent, skewed, testWebhookSecret), testWebhookSecret)
+	if err != nil || event.ID != "evt_test" {
+		t.Fatalf("skewed but valid signature: %v", err)
+	}
+
+	// Tolerance never rescues a bad signature or a tampered body.
+	if _, err := constructStripeEvent([]byte(testModeEvent), signedWebhook(testModeEvent, time.Now(), "whsec_other"), testWebhookSecret); err == nil {
+		t.Fatal("signature from another secret accepted")
+	}
+	tampered := bytes.Replace([]byte(testModeEvent), []byte("evt_test"), []byte("evt_evil"), 1)
+	if _, err := constructStripeEvent(tampered, signedWebhook(testModeEvent, time.Now(), testWebhookSecret), testWebhookSecret); err == nil {
+		t.Fatal("tampered payload accepted")
+	}
+}
+
+func TestStripeWebhookHandler_RejectsBadSignature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("STRIPE_WEBHOOK_SECRET", testWebhookSecret)
+	r := gin.New()
+	r.POST("/stripe/webhook", stripeWebhookHandler)
+	post := func(payload, sig string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/stripe/webhook", bytes.NewBufferString(payload))
+		req.Header.Set("Stripe-Signature", sig)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post(testModeEvent, "t=1,v1=deadbeef"); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid signature = %d, want 400", w.Code)
+	}
+}
+
+// Test-mode events are processed either way; STRIPE_TEST_MODE only changes
+// how they're logged.
+func TestLogStripeEvent(t *testing.T) {
+	event, err := constructStripeEvent([]byte(testModeEvent), signedWebhook(testModeEvent, time.Now(), testWebhookSecret), testWebhookSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	logged := func() string {
+		t.Helper()
+		buf.Reset()
+		logStripeEvent(event)
+		return buf.String()
+	}
+
+	if out := logged(); !strings.Contains(out, "🧪 Test-mode webhook received: customer.created (evt_test)") || !strings.Contains(out, "STRIPE_TEST_MODE is off") {
+		t.Errorf("test event, flag unset: %q", out)
+	}
+	t.Setenv("STRIPE_TEST_MODE", "true")
+	if out := logged(); !strings.Contains(out, "🧪 Test-mode webhook received") || strings.Contains(out, "STRIPE_TEST_MODE is off") {
+		t.Errorf("test event, flag on: %q", out)
+	}
+	event.Livemode = true
+	if out := logged(); strings.Contains(out, "🧪") || !strings.Contains(out, "Webhook received: customer.created") {
+		t.Errorf("live event: %q", out)
+	}
+}
+
+func TestApplySubscription_TracksStatus(t *testing.T) {
+	var user User
+	sub := subWithPrice(stripe.SubscriptionStatusActive, "price_plus")
+	sub.CancelAtPeriodEnd = true
+	applySubscription(&user, sub)
+	if user.SubscriptionStatus != "active" || !user.CancelAtPeriodEnd || user.AccountType != "paid" {
+		t.Fatalf("active, canceling: %+v", user)
+	}
+	sub.Status = stripe.SubscriptionStatusCanceled
+	applySubscription(&user, sub)
+	if user.SubscriptionStatus != "canceled" || user.CancelAtPeriodEnd || user.AccountType != "free" {
+		t.Fatalf("canceled: status=%q cancelAtPeriodEnd=%v account=%q", user.SubscriptionStatus, user.CancelAtPeriodEnd, user.AccountType)
+	}
+}
+
+// TestStripeWebhookHandler_SubscriptionLifecycle delivers each lifecycle
+// event in turn and checks the account state it leaves behind.
+func TestStripeWebhookHandler_SubscriptionLifecycle(t *testing.T) {
+	withTestDB(t)
+	gin.SetMode(gin.TestMode)
+	t.Setenv("STRIPE_WEBHOOK_SECRET", testWebhookSecret)
+	t.Setenv("STRIPE_PRICE_TIERS", "price_plus=premium")
+	user := User{Username: "subscriber", Email: "subscriber@example.com", AccountType: "free", StripeCustomerID: "cus_life"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.POST("/stripe/webhook", stripeWebhookHandler)
+
+	n := 0
+	deliver := func(eventType, object string) {
+		t.Helper()
+		n++
+		payload := fmt.Sprintf(`{"id":"evt_life_%d","object":"event","type":%q,"livemode":true,"data":{"object":%s}}`, n, eventType, object)
+		req := httptest.NewRequest(http.MethodPost, "/stripe/webhook", bytes.NewBufferString(payload))
+		req.Header.Set("Stripe-Signature", signedWebhook(payload, time.Now(), testWebhookSecret))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s = %d %s", eventType, w.Code, w.Body)
+		}
+	}
+	sub := func(status string, cancelAtPeriodEnd bool) string {
+		return fmt.Sprintf(`{"id":"sub_life","object":"subscription","customer":"cus_life","status":%q,"cancel_at_period_end":%v,"items":{"object":"list","data":[{"id":"si_1","price":{"id":"price_plus"}}]}}`, status, cancelAtPeriodEnd)
+	}
+	expect := func(step, account, status string, cancelAtPeriodEnd bool) {
+		t.Helper()
+		var got User
+		db.First(&got, user.ID)
+		if got.AccountType != account || got.SubscriptionStatus != status || got.CancelAtPeriodEnd != cancelAtPeriodEnd {
+			t.Fatalf("after %s: account=%q status=%q cancel_at_period_end=%v, want %q %q %v",
+				step, got.AccountType, got.SubscriptionStatus, got.CancelAtPeriodEnd, account, status, cancelAtPeriodEnd)
+		}
+	}
+
+	deliver("customer.subscription.updated", sub("active", false))
+	expect("updated (active)", "premium", "active", false)
+
+	deliver("invoice.payment_failed", `{"id":"in_1","object":"invoice","customer":"cus_life"}`)
+	expect("invoice.payment_failed", "premium", "past_due", false) // flagged, tier kept during dunning
+
+	deliver("customer.subscription.updated", sub("active", true))
+	expect("updated (cancel at period end)", "premium", "active", true)
+
+	deliver("customer.subscription.paused", sub("paused", false))
+	expect("paused", "free", "paused", false)
+
+	deliver("customer.subscription.resumed", sub("active", false))
+	expect("resumed", "premium", "active", false)
+
+	deliver("customer.subscription.deleted", sub("canceled", false))
+	expect("deleted", "free", "canceled", false)
+}
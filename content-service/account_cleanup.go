@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Account deletion cleanup. When auth-service deletes an account it calls
+// DELETE /internal/users/:user_id/content, which removes everything that
+// account owns here: every book with the same row and media cleanup as
+// deleteBookHandler, plus the user's own rows (progress, queued jobs, device
+// tokens, share links, create keys) and upload scratch. Usage ledger, bug
+// reports and cast events stay for billing and support. The call is
+// idempotent — a user with nothing left answers 200 with zero counts — so
+// auth-service can retry it freely.
+
+// userContentPurge is what purgeUserContent removed.
+type userContentPurge struct {
+	BooksDeleted int   `json:"books_deleted"`
+	RowsDeleted  int64 `json:"rows_deleted"` // user-level rows besides the books
+}
+
+// purgeUserContent deletes userID's books, their media and the user's rows.
+func purgeUserContent(userID uint) (userContentPurge, error) {
+	var out userContentPurge
+	var books []Book
+	if err := db.Where("user_id = ?", userID).Find(&books).Error; err != nil {
+		return out, fmt.Errorf("load books: %w", err)
+	}
+	media := make([]bookMedia, 0, len(books))
+	for _, b := range books {
+		media = append(media, snapshotBookMedia(b))
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, b := range books {
+			if err := deleteBookRows(tx, b.ID); err != nil {
+				return fmt.Errorf("book %d: %w", b.ID, err)
+			}
+		}
+		for _, model := range []any{&PlaybackProgress{}, &TTSQueueJob{}, &DeviceToken{}, &BookShare{}, &BookCreateKey{}} {
+			res := tx.Where("user_id = ?", userID).Delete(model)
+			if res.Error != nil {
+				return fmt.Errorf("%T: %w", model, res.Error)
+			}
+			out.RowsDeleted += res.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return userContentPurge{}, err
+	}
+	for _, m := range media {
+		m.purge()
+	}
+	_ = os.RemoveAll(filepath.Join(uploadBaseDir, strconv.FormatUint(uint64(userID), 10)))
+	out.BooksDeleted = len(books)
+	return out, nil
+}
+
+// internalPurgeUserContentHandler (DELETE /internal/users/:user_id/content)
+// removes a deleted account's content.
+func internalPurgeUserContentHandler(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil || userID == 0 {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "invalid user_id")
+		return
+	}
+	out, err := purgeUserContent(uint(userID))
+	if err != nil {
+		respondInternal(c, "Failed to delete user content", err)
+		return
+	}
+	log.Printf("🗑️ Purged content of deleted user %d: %d books, %d other rows", userID, out.BooksDeleted, out.RowsDeleted)
+	c.JSON(http.StatusOK, out)
+}
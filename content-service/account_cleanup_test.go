@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func purgeRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.DELETE("/internal/users/:user_id/content", serviceAuthMiddleware(), internalPurgeUserContentHandler)
+	return r
+}
+
+func signedPurge(r *gin.Engine, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodDelete, target, nil)
+	signServiceRequest(req, "s3cret", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestPurgeUserContent_RequiresSignature(t *testing.T) {
+	t.Setenv("SERVICE_SECRET", "s3cret")
+	w := httptest.NewRecorder()
+	purgeRouter().ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/internal/users/7/content", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("unsigned purge: %d, want 401", w.Code)
+	}
+	if w := signedPurge(purgeRouter(), "/internal/users/abc/content"); w.Code != http.StatusBadRequest {
+		t.Errorf("bad user_id: %d, want 400", w.Code)
+	}
+}
+
+func TestPurgeUserContent_DeletesOnlyThatUserAndIsIdempotent(t *testing.T) {
+	withTestDB(t)
+	t.Setenv("SERVICE_SECRET", "s3cret")
+	t.Chdir(t.TempDir())
+	ms := memStore{}
+	origStore := store
+	store = ms
+	t.Cleanup(func() { store = origStore })
+
+	mine := Book{Title: "Mine", Category: "Fiction", UserID: 7, FilePath: "uploads/7/a.txt"}
+	theirs := Book{Title: "Theirs", Category: "Fiction", UserID: 8, FilePath: "uploads/8/b.txt"}
+	for _, b := range []*Book{&mine, &theirs} {
+		db.Create(b)
+		db.Create(&BookChunk{BookID: b.ID, Index: 0, Content: "Page one.", TTSStatus: "completed"})
+	}
+	ms["uploads/7/a.txt"], ms["uploads/8/b.txt"] = 10, 10
+	db.Create(&PlaybackProgress{UserID: 7, BookID: theirs.ID})
+	db.Create(&DeviceToken{UserID: 7, Token: "tok-7"})
+
+	r := purgeRouter()
+	w := signedPurge(r, "/internal/users/7/content")
+	var got userContentPurge
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if w.Code != http.StatusOK || got.BooksDeleted != 1 || got.RowsDeleted != 2 {
+		t.Fatalf("purge = %d %s", w.Code, w.Body)
+	}
+	var n int64
+	db.Model(&Book{}).Where("user_id = ?", 7).Count(&n)
+	if n != 0 {
+		t.Errorf("%d books left for user 7", n)
+	}
+	if _, ok := ms["uploads/7/a.txt"]; ok {
+		t.Error("user 7's upload not removed from the store")
+	}
+	if _, ok := ms["uploads/8/b.txt"]; !ok {
+		t.Error("another user's upload was removed")
+	}
+	db.Model(&Book{}).Where("id = ?", theirs.ID).Count(&n)
+	if n != 1 {
+		t.Error("another user's book was deleted")
+	}
+
+	// A retry after success finds nothing and still succeeds.
+	w = signedPurge(r, "/internal/users/7/content")
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if w.Code != http.StatusOK || got.BooksDeleted != 0 {
+		t.Errorf("retry = %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Account-type lookups for tokens that predate the account_type claim.
+// auth-service is asked once per user per ACCOUNT_TYPE_CACHE_SECONDS
+// (default 60); each lookup gets ACCOUNT_TYPE_TIMEOUT_SECONDS (default 5)
+// per attempt and ACCOUNT_TYPE_RETRIES extra attempts (default 1) on network
+// errors and 5xx. When auth-service still can't answer,
+// ACCOUNT_TYPE_FAILURE decides:
+//
+//	"free"  (default) fail closed: the user is treated as free tier, so paid
+//	        gates deny and the quota paywall applies
+//	"error" the caller answers 503
+//
+// Fallback answers are never cached, so a recovered auth-service is used on
+// the next request.
+
+// maxAccountTypeResponseBytes bounds how much of auth-service's reply is read.
+const maxAccountTypeResponseBytes = 4 << 10
+
+// errAccountTypeRejected marks a 4xx from auth-service: retrying won't help.
+var errAccountTypeRejected = errors.New("auth-service rejected account type lookup")
+
+// fetchAccountType makes one lookup against auth-service. With SERVICE_SECRET
+// set it uses the HMAC-signed internal endpoint (service_auth.go); otherwise
+// it forwards the user's bearer token to the public /user/account-type route.
+// Swappable in tests.
+var fetchAccountType = func(userID uint, token string) (string, error) {
+	authServiceURL := getEnv("AUTH_SERVICE_URL", "http://auth-service:8082")
+
+	var req *http.Request
+	var err error
+	if secret := getEnv("SERVICE_SECRET", ""); secret != "" {
+		req, err = http.NewRequest("GET", fmt.Sprintf("%s/internal/users/%d/account-type", authServiceURL, userID), nil)
+		if err != nil {
+			return "", err
+		}
+		if err := signServiceRequest(req, secret, nil); err != nil {
+			return "", err
+		}
+	} else {
+		req, err = http.NewRequest("GET", authServiceURL+"/user/account-type", nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{Timeout: accountTypeTimeout()}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return "", fmt.Errorf("%w: status %d", errAccountTypeRejected, resp.StatusCode)
+	default:
+		return "", fmt.Errorf("auth-service returned %d", resp.StatusCode)
+	}
+
+	var result struct {
+		AccountType string `json:"account_type"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxAccountTypeResponseBytes)).Decode(&result); err != nil {
+		return "", err
+	}
+	return validAccountType(result.AccountType)
+}
+
+// validAccountType rejects answers that can't be a tier name, rather than
+// passing them on to the quota tables.
+func validAccountType(at string) (string, error) {
+	at = strings.TrimSpace(at)
+	if at == "" || len(at) > 32 {
+		return "", fmt.Errorf("invalid account type %q from auth-service", at)
+	}
+	return at, nil
+}
+
+func accountTypeTimeout() time.Duration {
+	return time.Duration(envInt("ACCOUNT_TYPE_TIMEOUT_SECONDS", 5)) * time.Second
+}
+
+// accountTypeCache holds successful lookups by user until their expiry.
+var accountTypeCache = struct {
+	sync.Mutex
+	entries map[uint]accountTypeCacheEntry
+}{entries: map[uint]accountTypeCacheEntry{}}
+
+type accountTypeCacheEntry struct {
+	accountType string
+	expires     time.Time
+}
+
+// getUserAccountType returns the user's tier: from the cache when fresh,
+// otherwise from auth-service with retries, otherwise per
+// ACCOUNT_TYPE_FAILURE. An error means the caller should answer 503.
+func getUserAccountType(userID uint, token string) (string, error) {
+	now := time.Now()
+	accountTypeCache.Lock()
+	e, ok := accountTypeCache.entries[userID]
+	accountTypeCache.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.accountType, nil
+	}
+
+	at, err := fetchAccountTypeWithRetry(userID, token)
+	if err != nil {
+		if getEnv("ACCOUNT_TYPE_FAILURE", "free") == "error" {
+			return "", err
+		}
+		log.Printf("⚠️ account type lookup for user %d failed, treating as free: %v", userID, err)
+		return "free", nil
+	}
+
+	ttl := time.Duration(envInt("ACCOUNT_TYPE_CACHE_SECONDS", 60)) * time.Second
+	accountTypeCache.Lock()
+	for id, old := range accountTypeCache.entries {
+		if now.After(old.expires) {
+			delete(accountTypeCache.entries, id)
+		}
+	}
+	if ttl > 0 {
+		accountTypeCache.entries[userID] = accountTypeCacheEntry{accountType: at, expires: now.Add(ttl)}
+	}
+	accountTypeCache.Unlock()
+	return at, nil
+}
+
+// fetchAccountTypeWithRetry retries transient failures with a short linear
+// backoff; a 4xx answer is final.
+func fetchAccountTypeWithRetry(userID uint, token string) (string, error) {
+	retries := envInt("ACCOUNT_TYPE_RETRIES", 1)
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * 200 * time.Millisecond)
+		}
+		var at string
+		if at, err = fetchAccountType(userID, token); err == nil {
+			return at, nil
+		}
+		if errors.Is(err, errAccountTypeRejected) {
+			break
+		}
+	}
+	return "", err
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// resetAccountTypeCache empties the per-user cache for the test and after it.
+func resetAccountTypeCache(t *testing.T) {
+	t.Helper()
+	empty := func() {
+		accountTypeCache.Lock()
+		accountTypeCache.entries = map[uint]accountTypeCacheEntry{}
+		accountTypeCache.Unlock()
+	}
+	empty()
+	t.Cleanup(empty)
+}
+
+func stubFetchAccountType(t *testing.T, fn func(uint, string) (string, error)) {
+	t.Helper()
+	orig := fetchAccountType
+	fetchAccountType = fn
+	t.Cleanup(func() { fetchAccountType = orig })
+}
+
+func TestGetUserAccountType_CacheHit(t *testing.T) {
+	resetAccountTypeCache(t)
+	calls := 0
+	stubFetchAccountType(t, func(uint, string) (string, error) { calls++; return "premium", nil })
+
+	for i := 0; i < 3; i++ {
+		if at, err := getUserAccountType(9, "tok"); err != nil || at != "premium" {
+			t.Fatalf("lookup %d = %q, %v", i, at, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("auth-service called %d times, want 1", calls)
+	}
+	// Another user is looked up separately.
+	getUserAccountType(10, "tok")
+	if calls != 2 {
+		t.Errorf("second user: %d calls, want 2", calls)
+	}
+}
+
+func TestGetUserAccountType_Timeout(t *testing.T) {
+	resetAccountTypeCache(t)
+	hits := 0
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		time.Sleep(1500 * time.Millisecond)
+		w.Write([]byte(`{"account_type":"premium"}`))
+	}))
+	defer slow.Close()
+	t.Setenv("AUTH_SERVICE_URL", slow.URL)
+	t.Setenv("SERVICE_SECRET", "s3cret")
+	t.Setenv("ACCOUNT_TYPE_TIMEOUT_SECONDS", "1")
+	t.Setenv("ACCOUNT_TYPE_RETRIES", "1")
+	t.Setenv("ACCOUNT_TYPE_FAILURE", "error")
+
+	if _, err := getUserAccountType(9, "tok"); err == nil {
+		t.Fatal("slow auth-service: want an error")
+	}
+	if hits != 2 {
+		t.Errorf("attempts = %d, want 2 (one retry)", hits)
+	}
+}
+
+func TestGetUserAccountType_FailClosed(t *testing.T) {
+	resetAccountTypeCache(t)
+	calls := 0
+	stubFetchAccountType(t, func(uint, string) (string, error) { calls++; return "", errors.New("connection refused") })
+	t.Setenv("ACCOUNT_TYPE_RETRIES", "0")
+
+	// Default policy: treated as free, and the fallback isn't cached.
+	if at, err := getUserAccountType(9, "tok"); err != nil || at != "free" {
+		t.Fatalf("fail-closed = %q, %v; want free", at, err)
+	}
+	getUserAccountType(9, "tok")
+	if calls != 2 {
+		t.Errorf("fallback was cached: %d calls, want 2", calls)
+	}
+
+	t.Setenv("ACCOUNT_TYPE_FAILURE", "error")
+	if _, err := getUserAccountType(9, "tok"); err == nil {
+		t.Error(`ACCOUNT_TYPE_FAILURE=error: want an error`)
+	}
+}
+
+func TestFetchAccountType_RejectsBadAnswers(t *testing.T) {
+	body := ""
+	status := http.StatusOK
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer auth.Close()
+	t.Setenv("AUTH_SERVICE_URL", auth.URL)
+	t.Setenv("SERVICE_SECRET", "s3cret")
+
+	body = `{"account_type":""}`
+	if _, err := fetchAccountType(9, ""); err == nil {
+		t.Error("empty account type accepted")
+	}
+	body = `{"account_type":"` + string(make([]byte, maxAccountTypeResponseBytes)) + `"}`
+	if _, err := fetchAccountType(9, ""); err == nil {
+		t.Error("oversized response accepted")
+	}
+	status, body = http.StatusNotFound, `{}`
+	if _, err := fetchAccountType(9, ""); !errors.Is(err, errAccountTypeRejected) {
+		t.Errorf("404 = %v, want errAccountTypeRejected", err)
+	}
+}
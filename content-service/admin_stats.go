@@ -0,0 +1,107 @@
+package main
+
+import (
+	"io/fs"
+	"log"
+	"net/http"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusCount is one GROUP BY status row.
+type statusCount struct {
+	Status string
+	N      int64
+}
+
+// ContentStats is the GET /admin/content-stats payload.
+type ContentStats struct {
+	TotalBooks           int64            `json:"total_books"`
+	BooksByStatus        map[string]int64 `json:"books_by_status"`
+	TotalChunks          int64            `json:"total_chunks"`
+	CompletedChunks      int64            `json:"completed_chunks"`
+	PendingChunks        int64            `json:"pending_chunks"` // anything not yet completed (pending/processing/failed)
+	ChunksByStatus       map[string]int64 `json:"chunks_by_status"`
+	AudioBytesOnDisk     int64            `json:"audio_bytes_on_disk"`    // local ./audio only; media stored in R2 isn't counted
+	AvgProcessingSeconds float64          `json:"avg_processing_seconds"` // created→processed_at, completed books only
+}
+
+// buildContentStats folds the per-status rows into the response. Pure so the
+// aggregation is testable without a database.
+func buildContentStats(books, chunks []statusCount, avgSeconds float64, audioBytes int64) ContentStats {
+	s := ContentStats{
+		BooksByStatus:        map[string]int64{},
+		ChunksByStatus:       map[string]int64{},
+		AudioBytesOnDisk:     audioBytes,
+		AvgProcessingSeconds: avgSeconds,
+	}
+	for _, r := range books {
+		status := r.Status
+		if status == "" {
+			status = "unknown"
+		}
+		s.BooksByStatus[status] += r.N
+		s.TotalBooks += r.N
+	}
+	for _, r := range chunks {
+		status := r.Status
+		if status == "" {
+			status = "pending"
+		}
+		s.ChunksByStatus[status] += r.N
+		s.TotalChunks += r.N
+		if status == "completed" {
+			s.CompletedChunks += r.N
+		} else {
+			s.PendingChunks += r.N
+		}
+	}
+	return s
+}
+
+// dirSize totals regular-file bytes under root (0 if it doesn't exist).
+func dirSize(root string) int64 {
+	var total int64
+	filepath.WalkDir(root, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // skip unreadable entries, keep walking
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
+
+// getContentStatsHandler (GET /admin/content-stats) reports library and
+// processing health: books and chunks by status, local audio footprint and
+// the average time a book takes to finish transcribing. The footprint is the
+// API host's ./audio tree; R2 usage is per user under GET /user/storage, as
+// totalling the whole bucket would mean listing every object.
+func getContentStatsHandler(c *gin.Context) {
+	var books, chunks []statusCount
+	if err := db.Model(&Book{}).Select("status, COUNT(*) AS n").Group("status").Scan(&books).Error; err != nil {
+		respondInternal(c, "Failed to count books", err)
+		return
+	}
+	if err := db.Model(&BookChunk{}).Select("tts_status AS status, COUNT(*) AS n").Group("tts_status").Scan(&chunks).Error; err != nil {
+		respondInternal(c, "Failed to count chunks", err)
+		return
+	}
+	var avg struct{ Seconds *float64 }
+	if err := db.Model(&Book{}).
+		Select("AVG(EXTRACT(EPOCH FROM (processed_at - created_at))) AS seconds").
+		Where("status = ? AND processed_at IS NOT NULL", "completed").Scan(&avg).Error; err != nil {
+		log.Printf("⚠️ content-stats: avg processing time failed: %v", err)
+	}
+	avgSeconds := 0.0
+	if avg.Seconds != nil {
+		avgSeconds = *avg.Seconds
+	}
+
+	c.JSON(http.StatusOK, buildContentStats(books, chunks, avgSeconds, dirSize("./audio")))
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBuildContentStats_MixedStatuses(t *testing.T) {
+	books := []statusCount{{"completed", 3}, {"transcribing", 2}, {"pending", 4}, {"failed", 1}, {"", 1}}
+	chunks := []statusCount{{"completed", 120}, {"pending", 30}, {"processing", 5}, {"failed", 2}, {"", 3}}
+	s := buildContentStats(books, chunks, 412.5, 2048)
+
+	if s.TotalBooks != 11 {
+		t.Errorf("TotalBooks = %d, want 11", s.TotalBooks)
+	}
+	if s.BooksByStatus["completed"] != 3 || s.BooksByStatus["transcribing"] != 2 || s.BooksByStatus["unknown"] != 1 {
+		t.Errorf("BooksByStatus = %v", s.BooksByStatus)
+	}
+	if s.TotalChunks != 160 || s.CompletedChunks != 120 || s.PendingChunks != 40 {
+		t.Errorf("chunks total/completed/pending = %d/%d/%d, want 160/120/40", s.TotalChunks, s.CompletedChunks, s.PendingChunks)
+	}
+	if s.ChunksByStatus["pending"] != 33 {
+		t.Errorf("blank tts_status should count as pending: %v", s.ChunksByStatus)
+	}
+	if s.AvgProcessingSeconds != 412.5 || s.AudioBytesOnDisk != 2048 {
+		t.Errorf("avg/bytes = %v/%d", s.AvgProcessingSeconds, s.AudioBytesOnDisk)
+	}
+}
+
+func TestDirSize(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "hls"), 0755)
+	os.WriteFile(filepath.Join(dir, "a.mp3"), make([]byte, 100), 0644)
+	os.WriteFile(filepath.Join(dir, "hls", "seg0.ts"), make([]byte, 50), 0644)
+	if got := dirSize(dir); got != 150 {
+		t.Errorf("dirSize = %d, want 150", got)
+	}
+	if got := dirSize(filepath.Join(dir, "missing")); got != 0 {
+		t.Errorf("missing dir = %d, want 0", got)
+	}
+}
+
+func TestGetContentStatsHandler_CountsSeededRows(t *testing.T) {
+	withTestDB(t)
+	created := time.Now().Add(-time.Hour)
+	fast, slow := created.Add(100*time.Second), created.Add(300*time.Second)
+	books := []Book{
+		{Title: "A", Category: "Fiction", Status: "completed", CreatedAt: created, ProcessedAt: &fast},
+		{Title: "B", Category: "Fiction", Status: "completed", CreatedAt: created, ProcessedAt: &slow},
+		{Title: "C", Category: "Fiction", Status: "completed", CreatedAt: created}, // finished before processed_at existed
+		{Title: "D", Category: "Fiction", Status: "transcribing", CreatedAt: created},
+		{Title: "E", Category: "Fiction", Status: "pending", CreatedAt: created},
+	}
+	for i := range books {
+		if err := db.Create(&books[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A later edit moves updated_at but must not change the average.
+	db.Model(&books[0]).Update("title", "A (renamed)")
+	for i, status := range []string{"completed", "completed", "completed", "pending", "processing", "failed"} {
+		if err := db.Create(&BookChunk{BookID: books[3].ID, Index: i, TTSStatus: status}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/admin/content-stats", getContentStatsHandler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/content-stats", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var s ContentStats
+	if err := json.Unmarshal(w.Body.Bytes(), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.TotalBooks != 5 || s.BooksByStatus["completed"] != 3 || s.BooksByStatus["transcribing"] != 1 || s.BooksByStatus["pending"] != 1 {
+		t.Errorf("books = %d %v", s.TotalBooks, s.BooksByStatus)
+	}
+	if s.TotalChunks != 6 || s.CompletedChunks != 3 || s.PendingChunks != 3 || s.ChunksByStatus["failed"] != 1 {
+		t.Errorf("chunks total/completed/pending = %d/%d/%d %v", s.TotalChunks, s.CompletedChunks, s.PendingChunks, s.ChunksByStatus)
+	}
+	if s.AvgProcessingSeconds < 199 || s.AvgProcessingSeconds > 201 {
+		t.Errorf("AvgProcessingSeconds = %v, want 200", s.AvgProcessingSeconds)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+// API keys. auth-service issues and stores per-user keys (auth-service/
+// api_keys.go); a client sends one as X-API-Key instead of a Bearer JWT.
+// authMiddleware resolves it through auth-service's signed
+// /internal/api-keys/verify endpoint and caches the answer for
+// API_KEY_CACHE_SECONDS (default 30), so a revoked key stops working here
+// within that window. A key's claims match a JWT's minus is_admin, and its
+// scopes gate the request method: "read" for GET/HEAD, "write" for the rest.
+
+const apiKeyHeader = "X-API-Key"
+
+var errAPIKeyInvalid = errors.New("invalid or revoked API key")
+
+type apiKeyIdentity struct {
+	UserID      uint     `json:"user_id"`
+	AccountType string   `json:"account_type"`
+	APIKeyID    uint     `json:"api_key_id"`
+	Scopes      []string `json:"scopes"`
+}
+
+// verifyAPIKey asks auth-service who owns key. Swappable in tests.
+var verifyAPIKey = func(key string) (*apiKeyIdentity, error) {
+	secret := getEnv("SERVICE_SECRET", "")
+	if secret == "" {
+		return nil, errors.New("SERVICE_SECRET not set")
+	}
+	body, _ := json.Marshal(gin.H{"key": key})
+	req, err := http.NewRequest("POST", getEnv("AUTH_SERVICE_URL", "http://auth-service:8082")+"/internal/api-keys/verify", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := signServiceRequest(req, secret, body); err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return nil, errAPIKeyInvalid
+	default:
+		return nil, fmt.Errorf("auth-service returned %d", resp.StatusCode)
+	}
+	var id apiKeyIdentity
+	if err := json.NewDecoder(resp.Body).Decode(&id); err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// apiKeyCache holds verified keys by hash until their expiry.
+var apiKeyCache = struct {
+	sync.Mutex
+	entries map[string]apiKeyCacheEntry
+}{entries: map[string]apiKeyCacheEntry{}}
+
+type apiKeyCacheEntry struct {
+	id      *apiKeyIdentity
+	expires time.Time
+}
+
+// resolveAPIKey returns key's owner, from the cache when fresh.
+func resolveAPIKey(key string) (*apiKeyIdentity, error) {
+	sum := sha256.Sum256([]byte(key))
+	hash := hex.EncodeToString(sum[:])
+	now := time.Now()
+
+	apiKeyCache.Lock()
+	e, ok := apiKeyCache.entries[hash]
+	apiKeyCache.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.id, nil
+	}
+
+	id, err := verifyAPIKey(key)
+	if err != nil {
+		return nil, err
+	}
+	ttl := time.Duration(envInt("API_KEY_CACHE_SECONDS", 30)) * time.Second
+	apiKeyCache.Lock()
+	for h, old := range apiKeyCache.entries {
+		if now.After(old.expires) {
+			delete(apiKeyCache.entries, h)
+		}
+	}
+	if ttl > 0 {
+		apiKeyCache.entries[hash] = apiKeyCacheEntry{id: id, expires: now.Add(ttl)}
+	}
+	apiKeyCache.Unlock()
+	return id, nil
+}
+
+// apiKeyScopeFor is the scope a request method needs.
+func apiKeyScopeFor(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return "read"
+	}
+	return "write"
+}
+
+// authenticateAPIKey is authMiddleware's X-API-Key branch.
+func authenticateAPIKey(c *gin.Context, key string) {
+	id, err := resolveAPIKey(key)
+	if err != nil {
+		if !errors.Is(err, errAPIKeyInvalid) {
+			log.Printf("⚠️ API key lookup failed: %v", err)
+		}
+		abortWithError(c, http.StatusUnauthorized, errCodeUnauthorized, "Invalid API key")
+		return
+	}
+	need := apiKeyScopeFor(c.Request.Method)
+	scopes := make([]interface{}, 0, len(id.Scopes))
+	allowed := false
+	for _, s := range id.Scopes {
+		scopes = append(scopes, s)
+		allowed = allowed || s == need
+	}
+	if !allowed {
+		abortWithError(c, http.StatusForbidden, errCodeForbidden, "API key lacks the "+need+" scope")
+		return
+	}
+	c.Set("claims", jwt.MapClaims{
+		"user_id":      float64(id.UserID),
+		"account_type": id.AccountType,
+		"api_key_id":   float64(id.APIKeyID),
+		"scopes":       scopes,
+	})
+	c.Set("user_id", id.UserID)
+	c.Next()
+}
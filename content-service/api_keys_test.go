@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stubVerifyAPIKey answers key lookups from keys (nil = revoked/unknown) and
+// counts the calls that reach auth-service.
+func stubVerifyAPIKey(t *testing.T, keys map[string]*apiKeyIdentity) *int {
+	t.Helper()
+	calls := 0
+	orig := verifyAPIKey
+	verifyAPIKey = func(key string) (*apiKeyIdentity, error) {
+		calls++
+		if id := keys[key]; id != nil {
+			return id, nil
+		}
+		return nil, errAPIKeyInvalid
+	}
+	apiKeyCache.entries = map[string]apiKeyCacheEntry{}
+	t.Cleanup(func() {
+		verifyAPIKey = orig
+		apiKeyCache.entries = map[string]apiKeyCacheEntry{}
+	})
+	return &calls
+}
+
+func apiKeyRequest(r http.Handler, method, path, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set(apiKeyHeader, key)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthMiddleware_APIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	calls := stubVerifyAPIKey(t, map[string]*apiKeyIdentity{
+		"sak_rw": {UserID: 7, AccountType: "paid", APIKeyID: 1, Scopes: []string{"read", "write"}},
+		"sak_ro": {UserID: 8, AccountType: "free", APIKeyID: 2, Scopes: []string{"read"}},
+	})
+	r := gin.New()
+	who := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": getUserIDFromContext(c), "account_type": accountTypeFromClaims(c)})
+	}
+	r.GET("/user/books", authMiddleware(), who)
+	r.POST("/user/books", authMiddleware(), who)
+	r.GET("/admin/stats", authMiddleware(), adminMiddleware(), who)
+
+	w := apiKeyRequest(r, http.MethodGet, "/user/books", "sak_rw")
+	if w.Code != http.StatusOK || w.Body.String() != `{"account_type":"paid","user_id":7}` {
+		t.Fatalf("key auth = %d %s", w.Code, w.Body)
+	}
+	if w := apiKeyRequest(r, http.MethodPost, "/user/books", "sak_rw"); w.Code != http.StatusOK {
+		t.Fatalf("write with a read,write key = %d", w.Code)
+	}
+	if *calls != 1 {
+		t.Fatalf("%d lookups for one key, want 1 (cached)", *calls)
+	}
+
+	if w := apiKeyRequest(r, http.MethodPost, "/user/books", "sak_ro"); w.Code != http.StatusForbidden {
+		t.Fatalf("write with a read-only key = %d, want 403", w.Code)
+	}
+	if w := apiKeyRequest(r, http.MethodGet, "/user/books", "sak_revoked"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked key = %d, want 401", w.Code)
+	}
+	if w := apiKeyRequest(r, http.MethodGet, "/admin/stats", "sak_rw"); w.Code != http.StatusForbidden {
+		t.Fatalf("admin route with a key = %d, want 403", w.Code)
+	}
+}
+
+func TestVerifyAPIKey_SignedLookup(t *testing.T) {
+	var gotKey string
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/internal/api-keys/verify" || r.Header.Get("X-Service-Signature") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req struct{ Key string }
+		json.Unmarshal(body, &req)
+		gotKey = req.Key
+		if req.Key != "sak_good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		io.WriteString(w, `{"user_id":3,"account_type":"premium","api_key_id":9,"scopes":["read"]}`)
+	}))
+	defer auth.Close()
+	t.Setenv("AUTH_SERVICE_URL", auth.URL)
+	t.Setenv("SERVICE_SECRET", "s3cret")
+
+	id, err := verifyAPIKey("sak_good")
+	if err != nil || id.UserID != 3 || id.AccountType != "premium" || id.APIKeyID != 9 || len(id.Scopes) != 1 {
+		t.Fatalf("verifyAPIKey = %+v, %v", id, err)
+	}
+	if gotKey != "sak_good" {
+		t.Fatalf("auth-service saw key %q", gotKey)
+	}
+	if _, err := verifyAPIKey("sak_revoked"); err != errAPIKeyInvalid {
+		t.Fatalf("revoked key = %v, want errAPIKeyInvalid", err)
+	}
+}
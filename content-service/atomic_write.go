@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// Media files are served straight off disk (serveMedia's legacy path) and
+// reused by later jobs (music/Foley/ambient caches), so a reader must never
+// observe a half-written file. Every producer writes to a hidden sibling temp
+// file and renames it into place once complete — rename is atomic on the same
+// filesystem, so the final path is either absent, the previous version, or
+// the new complete file.
+
+// createPartial opens a hidden temp file next to path. The random part sits
+// before the base name so the extension is preserved (FFmpeg picks its muxer
+// from the output extension).
+func createPartial(path string) (*os.File, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return os.CreateTemp(dir, ".partial-*-"+filepath.Base(path))
+}
+
+// writeFileAtomic streams r into path via a sibling temp file and renames it
+// into place only after the copy and close both succeed.
+func writeFileAtomic(path string, r io.Reader, perm os.FileMode) error {
+	tmp, err := createPartial(path)
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
+
+// runFFmpegAtomic runs ffmpeg with args followed by a temp output path, then
+// renames the result onto outPath (mode 0644). args must not include the
+// output file.
+// Returns ffmpeg's combined output for error reporting.
+func runFFmpegAtomic(outPath string, args ...string) ([]byte, error) {
+	tmp, err := createPartial(outPath)
+	if err != nil {
+		return nil, err
+	}
+	tmpName := tmp.Name()
+	tmp.Close()
+
+	full := append(append([]string{}, args...), tmpName)
+	if !containsArg(full, "-y") {
+		full = append([]string{"-y"}, full...) // the temp file already exists
+	}
+	o, err := runFFmpegCmd(exec.Command(ffmpegBin, full...))
+	if err != nil {
+		os.Remove(tmpName)
+		return o, err
+	}
+	// CreateTemp's 0600 would otherwise stick; match writeFileAtomic.
+	if err := os.Chmod(tmpName, 0o644); err != nil {
+		os.Remove(tmpName)
+		return o, err
+	}
+	if err := os.Rename(tmpName, outPath); err != nil {
+		os.Remove(tmpName)
+		return o, fmt.Errorf("rename %s: %w", outPath, err)
+	}
+	return o, nil
+}
+
+func containsArg(args []string, want string) bool {
+	for _, a := range args {
+		if a == want {
+			return true
+		}
+	}
+	return false
+}
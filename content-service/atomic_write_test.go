@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// failingReader yields some bytes and then errors, simulating a download or
+// TTS stream that dies mid-write.
+type failingReader struct{ sent bool }
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if !r.sent {
+		r.sent = true
+		return copy(p, "partial audio bytes"), nil
+	}
+	return 0, errors.New("connection reset")
+}
+
+func TestWriteFileAtomic_InterruptedWriteLeavesNoPartial(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "page.mp3")
+
+	if err := writeFileAtomic(path, &failingReader{}, 0o644); err == nil {
+		t.Fatal("expected the interrupted write to fail")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("final path must not exist after an interrupted write (stat err=%v)", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Fatalf("temp file left behind: %v", entries[0].Name())
+	}
+}
+
+func TestWriteFileAtomic_InterruptedRewriteKeepsPreviousFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "page.mp3")
+	if err := writeFileAtomic(path, strings.NewReader("complete v1"), 0o644); err != nil {
+		t.Fatalf("first write: %v", err)
+	}
+	if err := writeFileAtomic(path, &failingReader{}, 0o644); err == nil {
+		t.Fatal("expected the interrupted rewrite to fail")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("previous file vanished: %v", err)
+	}
+	defer f.Close()
+	got, _ := io.ReadAll(f)
+	if string(got) != "complete v1" {
+		t.Fatalf("final path holds %q, want the previous complete file", got)
+	}
+}
+
+func TestCreatePartialKeepsExtension(t *testing.T) {
+	dir := t.TempDir()
+	f, err := createPartial(filepath.Join(dir, "final_with_fx.ogg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if filepath.Ext(f.Name()) != ".ogg" || filepath.Dir(f.Name()) != dir {
+		t.Fatalf("partial %q should be a sibling with the same extension", f.Name())
+	}
+}
+
+// ffmpeg outputs land with the same 0644 as writeFileAtomic, not
+// CreateTemp's 0600.
+func TestRunFFmpegAtomic_OutputIsWorldReadable(t *testing.T) {
+	fakeFFmpeg(t)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "in.mp3")
+	os.WriteFile(src, []byte("ID3"), 0o644)
+	out := filepath.Join(dir, "out.mp3")
+	if o, err := runFFmpegAtomic(out, "-i", src); err != nil {
+		t.Fatalf("runFFmpegAtomic: %v %s", err, o)
+	}
+	fi, err := os.Stat(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o644 {
+		t.Errorf("mode = %v, want 0644", fi.Mode().Perm())
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// audioFormat is one supported container/codec for finished page audio.
+// Every FFmpeg stage that writes a client-facing file (mergeAudio,
+// overlaySoundEvents, mergeAudioSegments) takes its codec args from here so a
+// page never flips container halfway through the pipeline.
+type audioFormat struct {
+	Name      string   // "mp3" | "opus" | "aac"
+	Ext       string   // output extension (drives the FFmpeg muxer + served MIME)
+	CodecArgs []string // FFmpeg encoder args
+	MIME      string
+}
+
+var audioFormats = map[string]audioFormat{
+	"mp3":  {Name: "mp3", Ext: ".mp3", CodecArgs: []string{"-c:a", "libmp3lame", "-q:a", "2"}, MIME: "audio/mpeg"},
+	"opus": {Name: "opus", Ext: ".ogg", CodecArgs: []string{"-c:a", "libopus", "-b:a", "64k"}, MIME: "audio/ogg"},
+	"aac":  {Name: "aac", Ext: ".m4a", CodecArgs: []string{"-c:a", "aac", "-b:a", "96k"}, MIME: "audio/mp4"},
+}
+
+// defaultAudioFormat is the service-wide output format (AUDIO_FORMAT env,
+// default mp3 — the one container every client plays natively).
+func defaultAudioFormat() audioFormat {
+	if f, ok := audioFormats[strings.ToLower(strings.TrimSpace(getEnv("AUDIO_FORMAT", "mp3")))]; ok {
+		return f
+	}
+	return audioFormats["mp3"]
+}
+
+// audioFormatFor resolves a book's output format: its per-book override when
+// set and valid, otherwise the service default.
+func audioFormatFor(book Book) audioFormat {
+	if f, ok := audioFormats[strings.ToLower(book.AudioFormat)]; ok {
+		return f
+	}
+	return defaultAudioFormat()
+}
+
+// isValidAudioFormat reports whether name is an accepted per-book override
+// ("" = use the service default).
+func isValidAudioFormat(name string) bool {
+	if name == "" {
+		return true
+	}
+	_, ok := audioFormats[strings.ToLower(name)]
+	return ok
+}
+
+// audioFormatForPath maps an output path back to its format by extension, for
+// stages (mergeAudioSegments) that only know the destination file.
+func audioFormatForPath(p string) audioFormat {
+	ext := strings.ToLower(filepath.Ext(p))
+	for _, f := range audioFormats {
+		if f.Ext == ext {
+			return f
+		}
+	}
+	return audioFormats["mp3"]
+}
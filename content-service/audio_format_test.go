@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAudioFormatFor_EnvAndOverride(t *testing.T) {
+	t.Setenv("AUDIO_FORMAT", "aac")
+	if got := audioFormatFor(Book{}).Name; got != "aac" {
+		t.Errorf("env default: got %q, want aac", got)
+	}
+	if got := audioFormatFor(Book{AudioFormat: "opus"}).Name; got != "opus" {
+		t.Errorf("per-book override: got %q, want opus", got)
+	}
+	t.Setenv("AUDIO_FORMAT", "flac")
+	if got := audioFormatFor(Book{}).Name; got != "mp3" {
+		t.Errorf("unknown env value: got %q, want mp3 fallback", got)
+	}
+	if isValidAudioFormat("wav") || !isValidAudioFormat("") || !isValidAudioFormat("OPUS") {
+		t.Error("isValidAudioFormat: wrong verdicts")
+	}
+}
+
+func TestAudioFormat_CodecArgsAndMIME(t *testing.T) {
+	cases := map[string]struct{ codec, ext, mime string }{
+		"mp3":  {"libmp3lame", ".mp3", "audio/mpeg"},
+		"opus": {"libopus", ".ogg", "audio/ogg"},
+		"aac":  {"aac", ".m4a", "audio/mp4"},
+	}
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	for name, want := range cases {
+		f := audioFormatFor(Book{AudioFormat: name})
+		args := strings.Join(f.CodecArgs, " ")
+		if !strings.Contains(args, "-c:a "+want.codec) {
+			t.Errorf("%s: codec args %q missing -c:a %s", name, args, want.codec)
+		}
+		if f.Ext != want.ext {
+			t.Errorf("%s: ext %q, want %q", name, f.Ext, want.ext)
+		}
+		// A file written in this format maps back to the same codec…
+		out := fmt.Sprintf("./audio/book_1_page_0_abc%s", f.Ext)
+		if got := audioFormatForPath(out).Name; got != name {
+			t.Errorf("%s: audioFormatForPath(%q) = %q", name, out, got)
+		}
+		// …and is served with the matching Content-Type.
+		if got := contentTypeForExt(out); got != want.mime || f.MIME != want.mime {
+			t.Errorf("%s: MIME %q / %q, want %q", name, got, f.MIME, want.mime)
+		}
+		p := filepath.Join(dir, "page"+f.Ext)
+		if err := os.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/", nil)
+		serveMedia(c, p)
+		if got := w.Header().Get("Content-Type"); got != want.mime {
+			t.Errorf("%s: served Content-Type %q, want %q", name, got, want.mime)
+		}
+	}
+}
@@ -115,7 +115,7 @@ Rules: "fiction" false for history, biography, memoir, self-help, business, refe
 		book.Title, book.Author, book.Category, book.Genre, opening)
 
 	chatResp, err := callOpenAIChat(ChatRequest{
-		Model: classifyModel(),
+		Model: genreModel(),
 		Messages: []ChatMessage{
 			{Role: "system", Content: "Book classification assistant for audio production."},
 			{Role: "user", Content: prompt},
@@ -1,34 +1,62 @@
 package main
 
 import (
-	"fmt"
 	"net/http"
-	"path/filepath"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
-// Serve the final merged audio after sound effects processing
+// Serve the final merged audio for a processed chunk group. The merge
+// pipeline (processMergedChunks) records each group's output in
+// ProcessedChunkGroup, so look it up there rather than guessing file names on
+// disk. ?start=&end= selects a specific range; otherwise the newest group wins.
 func streamMergedChunkAudioHandler(c *gin.Context) {
 	bookIDStr := c.Param("book_id")
 	bookID, err := strconv.Atoi(bookIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid book ID")
 		return
 	}
 
-	// Check for latest merged audio for this book
-	pattern := fmt.Sprintf("./audio/merged_chunk_audio_%d*.mp3", bookID)
-	matches, err := filepath.Glob(pattern)
-	if err != nil || len(matches) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Merged audio file not found for this book"})
+	q := db.Where("book_id = ?", bookID)
+	if s, e := c.Query("start"), c.Query("end"); s != "" || e != "" {
+		start, err1 := strconv.Atoi(s)
+		end, err2 := strconv.Atoi(e)
+		if err1 != nil || err2 != nil {
+			respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "start and end must both be integers")
+			return
+		}
+		q = q.Where("start_idx = ? AND end_idx = ?", start, end)
+	}
+	var groups []ProcessedChunkGroup
+	if err := q.Find(&groups).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "Failed to fetch merged audio")
+		return
+	}
+	group, ok := latestChunkGroup(groups)
+	if !ok {
+		respondError(c, http.StatusNotFound, errCodeAudioNotReady, "Merged audio file not found for this book")
 		return
 	}
 
-	// Serve the latest merged audio (use first match). This legacy endpoint
-	// globs local disk; serveMedia handles the on-disk file.
-	serveMedia(c, matches[len(matches)-1])
+	// Serve from R2 (302 presigned) or legacy disk (fallback).
+	serveMedia(c, group.AudioPath)
+}
+
+// latestChunkGroup returns the most recently merged group that has audio.
+func latestChunkGroup(groups []ProcessedChunkGroup) (ProcessedChunkGroup, bool) {
+	var best ProcessedChunkGroup
+	found := false
+	for _, g := range groups {
+		if g.AudioPath == "" {
+			continue
+		}
+		if !found || g.UpdatedAt.After(best.UpdatedAt) || (g.UpdatedAt.Equal(best.UpdatedAt) && g.ID > best.ID) {
+			best, found = g, true
+		}
+	}
+	return best, found
 }
 
 func streamSinglePageAudioHandler(c *gin.Context) {
@@ -38,7 +66,7 @@ func streamSinglePageAudioHandler(c *gin.Context) {
     bookID, err1 := strconv.Atoi(bookIDStr)
     pageIndex, err2 := strconv.Atoi(pageStr)
     if err1 != nil || err2 != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID or page number"})
+        respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid book ID or page number")
         return
     }
     
@@ -51,13 +79,13 @@ func streamSinglePageAudioHandler(c *gin.Context) {
         First(&chunk).Error
     
     if err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Page not found"})
+        respondError(c, http.StatusNotFound, errCodePageNotFound, "Page not found")
         return
     }
     
     // Check if final_audio_path exists
     if chunk.FinalAudioPath == "" {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Audio not ready for this page"})
+        respondError(c, http.StatusNotFound, errCodeAudioNotReady, "Audio not ready for this page")
         return
     }
 
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The merged-audio endpoint must serve what the merge pipeline actually
+// recorded (book_%d_chunks_%d_%d.mp3 / its R2 key), not a guessed file name.
+func TestLatestChunkGroup_ServesRecordedMerge(t *testing.T) {
+	dir := t.TempDir()
+	older := filepath.Join(dir, fmt.Sprintf("book_%d_chunks_%d_%d.mp3", 7, 0, 9))
+	newer := filepath.Join(dir, fmt.Sprintf("book_%d_chunks_%d_%d.mp3", 7, 10, 19))
+	for _, p := range []string{older, newer} {
+		if err := os.WriteFile(p, []byte(filepath.Base(p)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	groups := []ProcessedChunkGroup{
+		{ID: 1, BookID: 7, StartIdx: 0, EndIdx: 9, AudioPath: older, UpdatedAt: now.Add(-time.Hour)},
+		{ID: 2, BookID: 7, StartIdx: 10, EndIdx: 19, AudioPath: newer, UpdatedAt: now},
+		{ID: 3, BookID: 7, StartIdx: 20, EndIdx: 29, AudioPath: "", UpdatedAt: now.Add(time.Hour)},
+	}
+	g, ok := latestChunkGroup(groups)
+	if !ok || g.ID != 2 {
+		t.Fatalf("latestChunkGroup = %+v, %v; want group 2", g, ok)
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	serveMedia(c, g.AudioPath)
+	if w.Code != 200 || w.Body.String() != filepath.Base(newer) {
+		t.Errorf("served %d %q, want 200 %q", w.Code, w.Body.String(), filepath.Base(newer))
+	}
+
+	if _, ok := latestChunkGroup(nil); ok {
+		t.Error("no groups should report not found")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Audio integrity. FFmpeg occasionally exits 0 after a partial failure and
+// leaves an empty or unreadable file behind; stored as-is, that page is
+// "completed" but can never play. Every generated page file is checked here
+// before its chunk is marked completed, and a bad one fails the chunk so the
+// normal retry paths pick it up again.
+
+var errCorruptAudio = errors.New("generated audio is empty or unreadable")
+
+// verifyAudio checks that path holds a non-empty file with at least one audio
+// stream and a positive duration.
+func verifyAudio(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errCorruptAudio, err)
+	}
+	if info.Size() == 0 {
+		return fmt.Errorf("%w: %s is zero bytes", errCorruptAudio, path)
+	}
+	out, err := exec.Command(ffprobeBin, "-v", "error",
+		"-select_streams", "a",
+		"-show_entries", "stream=codec_type:format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		path).Output()
+	if err != nil {
+		return fmt.Errorf("%w: ffprobe %s: %v", errCorruptAudio, path, err)
+	}
+	hasAudio, duration := false, 0.0
+	for _, line := range strings.Fields(string(out)) {
+		if line == "audio" {
+			hasAudio = true
+		} else if d, err := strconv.ParseFloat(line, 64); err == nil {
+			duration = d
+		}
+	}
+	if !hasAudio || duration <= 0 {
+		return fmt.Errorf("%w: %s has no audio stream or zero duration", errCorruptAudio, path)
+	}
+	return nil
+}
+
+// chunkSynthesize renders a chunk's TTS audio; swappable in tests.
+var chunkSynthesize = convertTextToAudioForChunk
+
+// synthesizeChunkAudio renders a chunk's TTS audio and verifies the result,
+// discarding a corrupt file so it is never stored.
+func synthesizeChunkAudio(ctx context.Context, chunk BookChunk) (string, error) {
+	path, err := chunkSynthesize(ctx, chunk)
+	if err != nil {
+		recordProcessingEvent(chunk.BookID, chunk.Index, stageTTS, eventFailed, err.Error())
+		return "", err
+	}
+	if err := verifyAudio(path); err != nil {
+		log.Printf("❌ book %d page %d: %v", chunk.BookID, chunk.Index, err)
+		recordProcessingEvent(chunk.BookID, chunk.Index, stageTTS, eventFailed, err.Error())
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestVerifyAudio_RejectsEmptyAndMissingFiles(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), "page.mp3")
+	if err := os.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{empty, filepath.Join(t.TempDir(), "missing.mp3")} {
+		if err := verifyAudio(path); !errors.Is(err, errCorruptAudio) {
+			t.Errorf("verifyAudio(%s) = %v, want errCorruptAudio", filepath.Base(path), err)
+		}
+	}
+}
+
+// stubChunkSynthesis makes every chunk render write out (zero bytes when
+// out is empty).
+func stubChunkSynthesis(t *testing.T, out []byte) {
+	t.Helper()
+	dir := t.TempDir()
+	orig := chunkSynthesize
+	chunkSynthesize = func(_ context.Context, chunk BookChunk) (string, error) {
+		path := filepath.Join(dir, "chunk.mp3")
+		return path, os.WriteFile(path, out, 0644)
+	}
+	t.Cleanup(func() { chunkSynthesize = orig })
+}
+
+func TestSynthesizeChunkAudio_DiscardsEmptyOutput(t *testing.T) {
+	dryRunDB(t) // the failure is written to the processing log
+	stubChunkSynthesis(t, nil)
+	path, err := synthesizeChunkAudio(context.Background(), BookChunk{ID: 1, BookID: 1})
+	if !errors.Is(err, errCorruptAudio) || path != "" {
+		t.Fatalf("synthesizeChunkAudio = %q, %v; want errCorruptAudio", path, err)
+	}
+}
+
+// FFmpeg "succeeding" with a zero-length file must leave the page failed
+// (retryable), never completed.
+func TestLookAheadTranscribe_EmptyAudioFailsChunk(t *testing.T) {
+	withTestDB(t)
+	stubChunkSynthesis(t, nil)
+	origRDB := rdb
+	rdb = redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1}) // quota fails open
+	t.Cleanup(func() { rdb = origRDB })
+
+	book := Book{Title: "Broken", Category: "Fiction", UserID: 1}
+	db.Create(&book)
+	chunk := BookChunk{BookID: book.ID, Index: 0, Content: "A page that renders badly.", TTSStatus: "pending"}
+	db.Create(&chunk)
+
+	if err := lookAheadTranscribeChunk(context.Background(), book, chunk, 1, "free"); !errors.Is(err, errCorruptAudio) {
+		t.Fatalf("lookAheadTranscribeChunk = %v, want errCorruptAudio", err)
+	}
+	var got BookChunk
+	db.First(&got, chunk.ID)
+	if got.TTSStatus != "failed" || got.AudioPath != "" {
+		t.Fatalf("chunk after empty render: status=%q audio=%q, want failed with no audio", got.TTSStatus, got.AudioPath)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Upload-to-audio in one call. An upload with auto_process=true (form field
+// or query parameter; the chunked upload's complete call takes the query
+// parameter) queues the whole-book transcription as soon as the file is split
+// into pages — right away for small files, when background chunking finishes
+// for large ones. It goes through the same path as POST /tts/batch
+// (queueBookTranscription), so the quota pre-check, the per-book lock and
+// free-tier pause-ahead all apply. Auto-processing never fails the upload: the
+// response says whether the job was queued and, if not, why.
+
+var (
+	errNothingToTranscribe  = errors.New("book already fully processed")
+	errTranscriptionRunning = errors.New("transcription already in progress for this book")
+)
+
+// queueBookTranscription claims book for batch transcription and enqueues the
+// batch starting at its first unfinished page. Callers run the quota
+// pre-check first.
+func queueBookTranscription(ctx context.Context, book Book, userID uint, accountType string, ov ttsOverride) error {
+	var first BookChunk
+	err := db.Where("book_id = ? AND tts_status != ?", book.ID, "completed").Order("index ASC").First(&first).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errNothingToTranscribe
+	}
+	if err != nil {
+		return fmt.Errorf("fetch pages: %w", err)
+	}
+
+	// The per-book processing lock (book_lock.go) keeps the claim and enqueue
+	// below from racing a concurrent batch request or an on-demand
+	// /process-chunks run; the worker takes it again for each batch.
+	unlock, err := lockBook(ctx, book.ID, false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return claimAndEnqueueTranscription(book, first.Index, userID, accountType, ov)
+}
+
+// claimAndEnqueueTranscription claims book and enqueues its batch chain from
+// page start. The caller holds the book's processing lock.
+func claimAndEnqueueTranscription(book Book, start int, userID uint, accountType string, ov ttsOverride) error {
+	// B6: atomic job lock — only one transcription may run per book. Use a
+	// dedicated 'transcribing' sentinel (NOT 'processing', which upload already
+	// sets to mean "uploaded/ready"); claim only if not already transcribing.
+	claim := db.Model(&Book{}).
+		Where("id = ? AND status <> ?", book.ID, "transcribing").
+		Update("status", "transcribing")
+	if claim.Error != nil {
+		return fmt.Errorf("claim book: %w", claim.Error)
+	}
+	if claim.RowsAffected == 0 {
+		return errTranscriptionRunning
+	}
+
+	// Enqueue the first 20-page batch (durable, on the worker fleet). The
+	// worker auto-enqueues subsequent batches as each completes, fires an MQTT
+	// "pages ready" event, and releases the book lock when done.
+	if err := enqueueTranscribeBatch(book.ID, start, start+batchSizePages-1, userID, accountType, ov); err != nil {
+		db.Model(&Book{}).Where("id = ?", book.ID).Update("status", "pending")
+		return fmt.Errorf("enqueue transcription: %w", err)
+	}
+	return nil
+}
+
+// autoProcessResult is the "auto_process" field of an upload response.
+type autoProcessResult struct {
+	Status   string `json:"status"`           // queued, after_chunking or skipped
+	Reason   string `json:"reason,omitempty"` // why it was skipped
+	ResetsAt string `json:"resets_at,omitempty"`
+}
+
+// autoProcessRequested reports whether the upload asked for auto_process.
+func autoProcessRequested(c *gin.Context) bool {
+	return c.PostForm("auto_process") == "true" || c.Query("auto_process") == "true"
+}
+
+// uploadAccountType resolves the uploader's account type the way
+// BatchTranscribeBookHandler does: the JWT claim, else auth-service.
+func uploadAccountType(c *gin.Context, userID uint) (string, error) {
+	if at := accountTypeFromClaims(c); at != "" {
+		return at, nil
+	}
+	token, err := extractToken(c.GetHeader("Authorization"))
+	if err != nil {
+		return "", err
+	}
+	return getUserAccountType(userID, token)
+}
+
+// autoProcessBook queues book's transcription after its upload.
+func autoProcessBook(ctx context.Context, book Book, userID uint, accountType string) autoProcessResult {
+	if d := checkAndConsume(userID, accountType, "transcribe_seconds", 0, book.ID); !d.Allowed {
+		return autoProcessResult{Status: "skipped", Reason: "quota_exceeded", ResetsAt: d.ResetsAt.UTC().Format(time.RFC3339)}
+	}
+	err := queueBookTranscription(ctx, book, userID, accountType, ttsOverride{})
+	switch {
+	case err == nil:
+		log.Printf("🎧 Auto-processing book %d after upload", book.ID)
+		return autoProcessResult{Status: "queued"}
+	case errors.Is(err, errNothingToTranscribe):
+		return autoProcessResult{Status: "skipped", Reason: "nothing_to_transcribe"}
+	case errors.Is(err, errBookLocked), errors.Is(err, errTranscriptionRunning):
+		return autoProcessResult{Status: "skipped", Reason: "already_processing"}
+	default:
+		log.Printf("⚠️ auto-process of book %d failed: %v", book.ID, err)
+		return autoProcessResult{Status: "skipped", Reason: "queue_unavailable"}
+	}
+}
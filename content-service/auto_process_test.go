@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/redis/go-redis/v9"
+)
+
+// autoProcessUpload uploads a small text file to a new book, with
+// auto_process when auto is set, and returns the response and the batches
+// queued.
+func autoProcessUpload(t *testing.T, auto bool) (*httptest.ResponseRecorder, []int) {
+	t.Helper()
+	book := Book{Title: "Auto", Category: "Fiction", UserID: 1, Status: "awaiting_upload"}
+	if err := db.Create(&book).Error; err != nil {
+		t.Fatal(err)
+	}
+	var queued []int
+	orig := enqueueTranscribeBatch
+	enqueueTranscribeBatch = func(bookID uint, start, _ int, userID uint, accountType string, _ ttsOverride) error {
+		if bookID != book.ID || userID != 1 || accountType != "free" {
+			t.Errorf("enqueued book %d user %d tier %q", bookID, userID, accountType)
+		}
+		queued = append(queued, start)
+		return nil
+	}
+	origRDB := rdb
+	rdb = redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1}) // quota fails open
+	t.Cleanup(func() { enqueueTranscribeBatch, rdb = orig, origRDB })
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("book_id", fmt.Sprint(book.ID))
+	if auto {
+		mw.WriteField("auto_process", "true")
+	}
+	fw, _ := mw.CreateFormFile("file", "story.txt")
+	fw.Write([]byte("It was a dark and stormy night. The rain fell in torrents."))
+	mw.Close()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/user/books/upload", func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"user_id": float64(1), "account_type": "free"})
+		uploadBookFileHandler(c)
+	})
+	req := httptest.NewRequest(http.MethodPost, "/user/books/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w, queued
+}
+
+func TestUploadAutoProcess_QueuesTranscription(t *testing.T) {
+	withTestDB(t)
+	t.Chdir(t.TempDir())
+	origStore := store
+	store = memStore{}
+	t.Cleanup(func() { store = origStore })
+
+	w, queued := autoProcessUpload(t, true)
+	if w.Code != http.StatusOK {
+		t.Fatalf("upload = %d %s", w.Code, w.Body)
+	}
+	var resp struct {
+		BookID      uint              `json:"book_id"`
+		AutoProcess autoProcessResult `json:"auto_process"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(queued) != 1 || queued[0] != 0 || resp.AutoProcess.Status != "queued" {
+		t.Fatalf("queued %v, auto_process %+v", queued, resp.AutoProcess)
+	}
+	var book Book
+	db.First(&book, resp.BookID)
+	if book.Status != "transcribing" {
+		t.Errorf("book status %q, want transcribing", book.Status)
+	}
+}
+
+func TestUploadAutoProcess_AbsentQueuesNothing(t *testing.T) {
+	withTestDB(t)
+	t.Chdir(t.TempDir())
+	origStore := store
+	store = memStore{}
+	t.Cleanup(func() { store = origStore })
+
+	w, queued := autoProcessUpload(t, false)
+	if w.Code != http.StatusOK {
+		t.Fatalf("upload = %d %s", w.Code, w.Body)
+	}
+	if len(queued) != 0 {
+		t.Errorf("queued %v without auto_process", queued)
+	}
+	var resp map[string]any
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp["auto_process"] != nil {
+		t.Errorf("auto_process = %v, want null", resp["auto_process"])
+	}
+}
+
+func TestAutoProcessRequested(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for target, want := range map[string]bool{
+		"/complete":                    false,
+		"/complete?auto_process=true":  true,
+		"/complete?auto_process=false": false,
+	} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, target, nil)
+		if got := autoProcessRequested(c); got != want {
+			t.Errorf("%s: %v, want %v", target, got, want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Request body limits. Handlers bind JSON straight off the request, so one
+// giant body (a million chunk_ids, a megabyte search query) would be read
+// into memory whole. bodyLimitMiddleware reads every body up to
+// MAX_JSON_BODY_BYTES (default 1 MB) before the handler runs: anything larger
+// is answered 413, and JSON nested deeper than maxJSONDepth is answered 400.
+// File bodies are left to the upload handlers, which enforce maxUploadBytes
+// (and exact part sizes) themselves: multipart forms, and the raw chunked
+// upload parts in bodyLimitExempt.
+
+// maxJSONDepth is how deeply a request's JSON may nest; no endpoint takes
+// more than a few levels.
+const maxJSONDepth = 32
+
+// bodyLimitExempt are routes whose raw body is a file.
+var bodyLimitExempt = map[string]bool{
+	"/user/books/:book_id/upload/chunked/:upload_id/parts/:part": true,
+}
+
+// maxJSONBodyBytes is the body cap (MAX_JSON_BODY_BYTES, default 1 MB).
+func maxJSONBodyBytes() int64 { return int64(envInt("MAX_JSON_BODY_BYTES", 1<<20)) }
+
+// bodyLimitMiddleware enforces the body cap and the JSON depth guard.
+func bodyLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody ||
+			bodyLimitExempt[c.FullPath()] ||
+			strings.HasPrefix(c.ContentType(), "multipart/") {
+			c.Next()
+			return
+		}
+		max := maxJSONBodyBytes()
+		if c.Request.ContentLength > max {
+			respondBodyTooLarge(c, max)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, max+1))
+		if err != nil {
+			respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "Could not read request body")
+			c.Abort()
+			return
+		}
+		if int64(len(body)) > max {
+			respondBodyTooLarge(c, max)
+			return
+		}
+		if jsonDepth(body) > maxJSONDepth {
+			respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "Request JSON is nested too deeply")
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
+
+func respondBodyTooLarge(c *gin.Context, max int64) {
+	respondErrorWith(c, http.StatusRequestEntityTooLarge, errCodePayloadTooLarge, "Request body too large", gin.H{"max_bytes": max})
+	c.Abort()
+}
+
+// jsonDepth is the deepest object/array nesting in body, ignoring brackets
+// inside strings. It doesn't validate; binding reports malformed JSON.
+func jsonDepth(body []byte) int {
+	depth, deepest := 0, 0
+	inString, escaped := false, false
+	for _, b := range body {
+		switch {
+		case inString:
+			if escaped {
+				escaped = false
+			} else if b == '\\' {
+				escaped = true
+			} else if b == '"' {
+				inString = false
+			}
+		case b == '"':
+			inString = true
+		case b == '{' || b == '[':
+			depth++
+			if depth > deepest {
+				deepest = depth
+			}
+		case b == '}' || b == ']':
+			depth--
+		}
+	}
+	return deepest
+}
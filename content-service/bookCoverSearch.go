@@ -24,6 +24,7 @@ type CoverSearchRequest struct {
 	Title  string `json:"title" binding:"required"`
 	Author string `json:"author"`
 	BookID uint   `json:"book_id"` // Optional: if provided, include auto-fetched cover
+	ISBN   string `json:"isbn"`    // Optional: exact Open Library cover, listed first
 }
 
 // CoverOption represents a single cover option returned to the user
@@ -52,13 +53,14 @@ type SelectCoverRequest struct {
 func SearchBookCoversHandler(c *gin.Context) {
 	var req CoverSearchRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "title is required"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "title is required")
 		return
 	}
 
 	log.Printf("🔍 Searching covers for: %s by %s (book_id: %d)", req.Title, req.Author, req.BookID)
 
 	var allCovers []CoverOption
+	isbn := req.ISBN
 
 	// Step 1: Check if book already has an auto-fetched cover
 	if req.BookID > 0 {
@@ -66,6 +68,9 @@ func SearchBookCoversHandler(c *gin.Context) {
 		if err := db.First(&book, req.BookID).Error; err != nil {
 			log.Printf("⚠️ Failed to fetch book %d for cover search: %v", req.BookID, err)
 		} else {
+			if isbn == "" {
+				isbn = book.ISBN
+			}
 			log.Printf("📖 Book %d found - CoverURL: '%s', CoverPath: '%s'", req.BookID, book.CoverURL, book.CoverPath)
 			if book.CoverURL != "" && book.CoverURL != "http://placeholder.com/default.jpg" {
 				// Ensure URL uses HTTPS
@@ -90,6 +95,17 @@ func SearchBookCoversHandler(c *gin.Context) {
 		}
 	}
 
+	// Step 1b: Exact cover by ISBN (verified to exist)
+	if isbn != "" {
+		if coverURL := tryOpenLibraryISBNCover(isbn); coverURL != "" {
+			allCovers = append(allCovers, CoverOption{
+				URL:         coverURL,
+				Source:      "openlibrary.org",
+				Description: "Cover for ISBN " + normalizeISBN(isbn),
+			})
+		}
+	}
+
 	// Step 2: Search for additional covers using OpenAI
 	searchCovers, err := searchMultipleCovers(req.Title, req.Author)
 	if err != nil {
@@ -105,10 +121,7 @@ func SearchBookCoversHandler(c *gin.Context) {
 			})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to search for covers",
-			"details": err.Error(),
-		})
+		respondInternal(c, "Failed to search for covers", err)
 		return
 	}
 
@@ -151,35 +164,25 @@ func SearchBookCoversHandler(c *gin.Context) {
 func SelectBookCoverHandler(c *gin.Context) {
 	bookID := c.Param("book_id")
 	if bookID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "book_id is required"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "book_id is required")
 		return
 	}
 
 	var req SelectCoverRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "cover_url is required"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "cover_url is required")
 		return
 	}
 
-	// Validate URL
-	if !strings.HasPrefix(req.CoverURL, "http://") && !strings.HasPrefix(req.CoverURL, "https://") {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cover URL"})
+	// Validate URL (scheme, allowlist, and no private/metadata targets).
+	if err := checkOutboundURL(c.Request.Context(), req.CoverURL); err != nil {
+		log.Printf("🚫 Rejected cover URL for book %s: %v", bookID, err)
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid cover URL")
 		return
 	}
 
-	// Get user ID from JWT
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-		return
-	}
-
-	// Verify book belongs to user
-	var book Book
-	if err := db.Where("id = ? AND user_id = ?", bookID, userID).First(&book).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
-		return
-	}
+	// Ownership already verified by requireBookOwnership(); reuse the book.
+	book := c.MustGet("book").(Book)
 
 	log.Printf("📥 Downloading selected cover for book %s: %s", bookID, req.CoverURL)
 
@@ -190,10 +193,9 @@ func SelectBookCoverHandler(c *gin.Context) {
 	localPath, err := downloadAndSaveImage(req.CoverURL, bookID)
 	if err != nil {
 		log.Printf("⚠️ Could not download selected cover: %v", err)
-		c.JSON(http.StatusUnprocessableEntity, gin.H{
-			"error":   "cover_unavailable",
-			"message": "That image couldn't be fetched. Pick a different cover or skip — you can add one later.",
-		})
+		respondErrorWith(c, http.StatusUnprocessableEntity, errCodeCoverUnavailable,
+			"That image couldn't be fetched. Pick a different cover or skip — you can add one later.",
+			gin.H{"error": "cover_unavailable"})
 		return
 	}
 
@@ -202,10 +204,9 @@ func SelectBookCoverHandler(c *gin.Context) {
 	key, publicURL, err := storeCover(localPath, bookID)
 	if err != nil {
 		log.Printf("❌ Failed to store selected cover in R2 for book %s: %v", bookID, err)
-		c.JSON(http.StatusBadGateway, gin.H{
-			"error":   "cover_store_failed",
-			"message": "Couldn't save that cover. Please try again.",
-		})
+		respondErrorWith(c, http.StatusBadGateway, errCodeCoverStoreFailed,
+			"Couldn't save that cover. Please try again.",
+			gin.H{"error": "cover_store_failed"})
 		return
 	}
 
@@ -214,7 +215,7 @@ func SelectBookCoverHandler(c *gin.Context) {
 	book.CoverURL = publicURL
 	if err := db.Save(&book).Error; err != nil {
 		log.Printf("❌ Failed to update book cover: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update book"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "Failed to update book")
 		return
 	}
 
@@ -265,7 +266,7 @@ Return ONLY the JSON array, no other text.`,
 		title, authorStr)
 
 	requestBody := ResponsesRequest{
-		Model: "gpt-4o",
+		Model: searchModel(),
 		Tools: []ResponseTool{
 			{
 				Type: "web_search",
@@ -21,14 +21,14 @@ func uploadBookCoverHandler(c *gin.Context) {
 	bookID := c.Param("book_id")
 	file, err := c.FormFile("cover")
 	if bookID == "" || err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "book_id and cover file are required"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "book_id and cover file are required")
 		return
 	}
 
 	// validate extensions
 	ext := strings.ToLower(filepath.Ext(file.Filename))
 	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Only JPG, JPEG, PNG allowed"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "Only JPG, JPEG, PNG allowed")
 		return
 	}
 
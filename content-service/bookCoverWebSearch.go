@@ -15,16 +15,25 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 )
 
 // storeCover uploads a freshly-downloaded local cover to R2 and returns the
-// object key + its public URL (covers are public for discovery).
+// object key + its public URL (covers are public for discovery). Covers are
+// content-addressed (cover_dedup.go): if identical bytes were already stored
+// for any book, that object is reused and nothing is uploaded.
 func storeCover(localPath, bookID string) (key string, publicURL string, err error) {
-	bidU, _ := strconv.ParseUint(bookID, 10, 64)
-	key = coverKey(uint(bidU), filepath.Base(localPath), filepath.Ext(localPath))
+	hash, err := fileSHA256(localPath)
+	if err != nil {
+		return "", "", err
+	}
+	key = sharedCoverKey(hash, strings.ToLower(filepath.Ext(localPath)))
+	if ok, eerr := store.Exists(context.Background(), key); eerr == nil && ok {
+		_ = os.Remove(localPath)
+		log.Printf("♻️ Cover for book %s matches stored %s — reusing", bookID, key)
+		return key, store.PublicURL(key), nil
+	}
 	if _, err = uploadArtifact(context.Background(), localPath, key); err != nil {
 		return "", "", err
 	}
@@ -117,7 +126,7 @@ Return ONLY the direct image URL on a single line. Do not include any explanatio
 		title, author)
 
 	requestBody := ResponsesRequest{
-		Model: "gpt-4o",
+		Model: searchModel(),
 		Tools: []ResponseTool{
 			{
 				Type: "web_search",
@@ -345,6 +354,12 @@ func truncateString(s string, maxLen int) string {
 // downloadAndSaveImage downloads an image from a URL and saves it to the local filesystem
 // Returns the local file path and any error encountered
 func downloadAndSaveImage(imageURL, bookID string) (string, error) {
+	// imageURL can be user-supplied (select-cover) — SSRF guard before any GET.
+	if err := checkOutboundURL(context.Background(), imageURL); err != nil {
+		return "", err
+	}
+	client := safeHTTPClient(30 * time.Second)
+
 	// Try different referer strategies to bypass hotlink protection
 	referers := []string{
 		"", // No referer first (some sites prefer this)
@@ -367,7 +382,6 @@ func downloadAndSaveImage(imageURL, bookID string) (string, error) {
 			req.Header.Set("Referer", referer)
 		}
 
-		client := &http.Client{Timeout: 30 * time.Second}
 		resp, err := client.Do(req)
 		if err != nil {
 			lastErr = fmt.Errorf("failed to download image: %w", err)
@@ -443,7 +457,7 @@ func saveImageToFile(imageData []byte, imageURL, bookID string) (string, error)
 	filePath := filepath.Join(uploadDir, filename)
 
 	// Save the image
-	if err := os.WriteFile(filePath, imageData, 0644); err != nil {
+	if err := writeFileAtomic(filePath, bytes.NewReader(imageData), 0644); err != nil {
 		return "", fmt.Errorf("failed to save image: %w", err)
 	}
 
@@ -502,15 +516,32 @@ func tryOpenLibraryCover(title, author string) string {
 
 // fetchAndSaveBookCover is the main entry point for fetching and saving a book cover
 // It searches the web for the cover, downloads it, and returns the local path and public URL
-func fetchAndSaveBookCover(title, author, bookID string) (localPath string, publicURL string, err error) {
+// A known ISBN is an exact match, so its Open Library cover is tried before any
+// title/author search.
+func fetchAndSaveBookCover(title, author, isbn, bookID string) (localPath string, publicURL string, err error) {
 	var imageURL string
 	var downloadErr error
 
+	// Step 0: Exact cover by ISBN
+	if isbn != "" {
+		if imageURL = tryOpenLibraryISBNCover(isbn); imageURL != "" {
+			localPath, downloadErr = fetchRemoteCover(imageURL, bookID)
+			if downloadErr == nil {
+				if key, url, serr := storeCover(localPath, bookID); serr == nil {
+					return key, url, nil
+				} else {
+					downloadErr = serr
+				}
+			}
+			log.Printf("⚠️ Failed to fetch/store ISBN cover: %v, trying title search...", downloadErr)
+		}
+	}
+
 	// Step 1: Try OpenAI web search first
 	imageURL, err = fetchBookCoverFromWeb(title, author)
 	if err == nil && imageURL != "" {
 		// Try to download the found image
-		localPath, downloadErr = downloadAndSaveImage(imageURL, bookID)
+		localPath, downloadErr = fetchRemoteCover(imageURL, bookID)
 		if downloadErr == nil {
 			// Upload to R2; return the object key + public URL.
 			if key, url, serr := storeCover(localPath, bookID); serr == nil {
@@ -527,7 +558,7 @@ func fetchAndSaveBookCover(title, author, bookID string) (localPath string, publ
 	// Step 2: Fallback to Open Library
 	imageURL = tryOpenLibraryCover(title, author)
 	if imageURL != "" {
-		localPath, downloadErr = downloadAndSaveImage(imageURL, bookID)
+		localPath, downloadErr = fetchRemoteCover(imageURL, bookID)
 		if downloadErr == nil {
 			if key, url, serr := storeCover(localPath, bookID); serr == nil {
 				return key, url, nil
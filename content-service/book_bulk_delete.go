@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxBulkDeleteBooks caps one bulk-delete request.
+const maxBulkDeleteBooks = 100
+
+type bulkDeleteRequest struct {
+	BookIDs []uint `json:"book_ids" binding:"required"`
+}
+
+// bulkDeleteResult is one requested ID's outcome. Books that are missing or
+// belong to someone else are both "not_found", as on the single-book routes.
+type bulkDeleteResult struct {
+	BookID uint   `json:"book_id"`
+	Status string `json:"status"` // deleted | not_found
+}
+
+// bulkDeleteBooksHandler deletes several of the caller's books at once, with
+// the same row and media cleanup as deleteBookHandler. The owned books' rows
+// go in one transaction; IDs the caller doesn't own are skipped and reported.
+// POST /user/books/bulk-delete  {"book_ids": [1, 2, 3]}
+func bulkDeleteBooksHandler(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		respondError(c, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
+		return
+	}
+	var req bulkDeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.BookIDs) == 0 {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "book_ids must be a non-empty array of book IDs")
+		return
+	}
+	if len(req.BookIDs) > maxBulkDeleteBooks {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("At most %d books per request", maxBulkDeleteBooks))
+		return
+	}
+
+	var books []Book
+	if err := db.Where("id IN ? AND user_id = ?", req.BookIDs, userID).Find(&books).Error; err != nil {
+		respondInternal(c, "Failed to load books", err)
+		return
+	}
+	owned := make(map[uint]Book, len(books))
+	media := make([]bookMedia, 0, len(books))
+	for _, b := range books {
+		owned[b.ID] = b
+		media = append(media, snapshotBookMedia(b))
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, b := range books {
+			if err := deleteBookRows(tx, b.ID); err != nil {
+				return fmt.Errorf("book %d: %w", b.ID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		respondInternal(c, "Failed to delete books", err)
+		return
+	}
+	for _, m := range media {
+		m.purge()
+	}
+
+	results := make([]bulkDeleteResult, 0, len(req.BookIDs))
+	seen := make(map[uint]bool, len(req.BookIDs))
+	for _, id := range req.BookIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		status := "not_found"
+		if _, ok := owned[id]; ok {
+			status = "deleted"
+		}
+		results = append(results, bulkDeleteResult{BookID: id, Status: status})
+	}
+	log.Printf("🗑️ User %d bulk-deleted %d of %d books", userID, len(books), len(results))
+	c.JSON(http.StatusOK, gin.H{"deleted": len(books), "results": results})
+}
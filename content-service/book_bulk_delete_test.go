@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+func bulkDeleteRouter(userID uint) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/user/books/bulk-delete", func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"user_id": float64(userID)})
+		bulkDeleteBooksHandler(c)
+	})
+	return r
+}
+
+func postBulkDelete(r *gin.Engine, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/user/books/bulk-delete", bytes.NewBufferString(body)))
+	return w
+}
+
+func TestBulkDeleteBooks_RejectsBadRequests(t *testing.T) {
+	r := bulkDeleteRouter(1)
+	for _, body := range []string{``, `{}`, `{"book_ids":[]}`, `{"book_ids":"1,2"}`, `{"book_ids":[` + string(bytes.Repeat([]byte("1,"), maxBulkDeleteBooks)) + `1]}`} {
+		if w := postBulkDelete(r, body); w.Code != http.StatusBadRequest {
+			t.Errorf("body %.40q = %d, want 400", body, w.Code)
+		}
+	}
+}
+
+func TestBulkDeleteBooks_SkipsBooksNotOwned(t *testing.T) {
+	withTestDB(t)
+	t.Chdir(t.TempDir())
+	ms := memStore{}
+	origStore := store
+	store = ms
+	t.Cleanup(func() { store = origStore })
+
+	mine1 := Book{Title: "Mine one", Category: "Fiction", UserID: 1, FilePath: "uploads/1/a.txt"}
+	mine2 := Book{Title: "Mine two", Category: "Fiction", UserID: 1}
+	theirs := Book{Title: "Theirs", Category: "Fiction", UserID: 2, FilePath: "uploads/2/b.txt"}
+	for _, b := range []*Book{&mine1, &mine2, &theirs} {
+		db.Create(b)
+		db.Create(&BookChunk{BookID: b.ID, Index: 0, Content: "Page one.", AudioPath: "audio/p.mp3", TTSStatus: "completed"})
+	}
+	ms["uploads/1/a.txt"], ms["uploads/2/b.txt"] = 10, 10
+
+	body, _ := json.Marshal(map[string][]uint{"book_ids": {mine1.ID, theirs.ID, mine2.ID, 999999, mine1.ID}})
+	w := postBulkDelete(bulkDeleteRouter(1), string(body))
+	if w.Code != http.StatusOK {
+		t.Fatalf("bulk delete = %d %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Deleted int                `json:"deleted"`
+		Results []bulkDeleteResult `json:"results"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	want := []bulkDeleteResult{{mine1.ID, "deleted"}, {theirs.ID, "not_found"}, {mine2.ID, "deleted"}, {999999, "not_found"}}
+	if resp.Deleted != 2 || len(resp.Results) != len(want) {
+		t.Fatalf("response = %+v", resp)
+	}
+	for i, r := range resp.Results {
+		if r != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, r, want[i])
+		}
+	}
+
+	var count int64
+	db.Model(&Book{}).Where("id IN ?", []uint{mine1.ID, mine2.ID}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d owned books still present", count)
+	}
+	db.Model(&BookChunk{}).Where("book_id IN ?", []uint{mine1.ID, mine2.ID}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d chunks of deleted books left", count)
+	}
+	if err := db.First(&Book{}, theirs.ID).Error; err != nil {
+		t.Errorf("another user's book was deleted: %v", err)
+	}
+	db.Model(&BookChunk{}).Where("book_id = ?", theirs.ID).Count(&count)
+	if count != 1 {
+		t.Errorf("another user's chunks = %d, want 1", count)
+	}
+	if _, ok := ms["uploads/1/a.txt"]; ok {
+		t.Error("deleted book's upload still stored")
+	}
+	if _, ok := ms["uploads/2/b.txt"]; !ok {
+		t.Error("another user's upload removed")
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Whole-book audio. The per-page flow leaves a finished file per page
+// (FinalAudioPath) and the occasional chunk group, but never one file for the
+// whole book. POST /user/books/:book_id/compile joins every page, in order,
+// into book_<id>_complete_<fingerprint> and points Book.AudioPath at it. The
+// fingerprint hashes the pages' audio keys (which change whenever a page is
+// re-rendered) and the output format, so compiling an unchanged book is a
+// no-op and any re-rendered page produces a new file.
+
+// completeAudioFingerprint identifies the exact page audio a compiled file was
+// built from.
+func completeAudioFingerprint(chunks []BookChunk, format audioFormat) string {
+	h := sha256.New()
+	h.Write([]byte(format.Name))
+	for _, ch := range chunks {
+		h.Write([]byte("\n" + ch.FinalAudioPath))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func completeAudioKey(bookID uint, fingerprint, ext string) string {
+	return fmt.Sprintf("audio/%d/book_%d_complete_%s%s", bookID, bookID, shortHash(fingerprint), ext)
+}
+
+// isCompleteAudioKey reports whether key is a compiled whole-book file of
+// bookID (as opposed to batch-pipeline audio stored in the same column).
+func isCompleteAudioKey(bookID uint, key string) bool {
+	return strings.HasPrefix(key, fmt.Sprintf("audio/%d/book_%d_complete_", bookID, bookID))
+}
+
+// compileBookAudio concatenates chunks (every page of book, in order) into
+// one stored file and returns its key. compiled is false when book.AudioPath
+// already holds the file for exactly these pages.
+func compileBookAudio(ctx context.Context, book Book, chunks []BookChunk) (key string, compiled bool, err error) {
+	format := audioFormatFor(book)
+	key = completeAudioKey(book.ID, completeAudioFingerprint(chunks, format), format.Ext)
+	if book.AudioPath == key && mediaExists(ctx, key) {
+		return key, false, nil
+	}
+
+	out := fmt.Sprintf("./audio/book_%d_complete%s", book.ID, format.Ext)
+	if err := concatPageAudio(ctx, chunks, out, format); err != nil {
+		return "", false, err
+	}
+	if _, err := uploadArtifact(ctx, out, key); err != nil {
+		os.Remove(out)
+		return "", false, err
+	}
+	return key, true, nil
+}
+
+// compileBookAudioHandler builds (or confirms) the whole-book file.
+//
+// POST /user/books/:book_id/compile
+func compileBookAudioHandler(c *gin.Context) {
+	book := c.MustGet("book").(Book)
+
+	// A synthesis run would change pages underneath the concat.
+	unlock, err := lockBook(c.Request.Context(), book.ID, false)
+	if errors.Is(err, errBookLocked) {
+		respondError(c, http.StatusConflict, errCodeConflict, "Book is already being processed")
+		return
+	}
+	if err != nil {
+		respondInternal(c, "Could not lock book", err)
+		return
+	}
+	defer unlock()
+
+	var chunks []BookChunk
+	if err := db.Where("book_id = ?", book.ID).Order("\"index\" ASC").Find(&chunks).Error; err != nil {
+		respondInternal(c, "Could not fetch pages", err)
+		return
+	}
+	if len(chunks) == 0 {
+		respondError(c, http.StatusNotFound, errCodePageNotFound, "Book has no pages")
+		return
+	}
+	for _, ch := range chunks {
+		if ch.TTSStatus != "completed" || ch.FinalAudioPath == "" {
+			respondErrorWith(c, http.StatusConflict, errCodeAudioNotReady, "Audio not ready for every page", gin.H{"page": ch.Index + 1})
+			return
+		}
+	}
+
+	key, compiled, err := compileBookAudio(c.Request.Context(), book, chunks)
+	if err != nil {
+		respondInternal(c, "Could not compile book audio", err)
+		return
+	}
+	if compiled {
+		if err := db.Model(&Book{}).Where("id = ?", book.ID).Update("audio_path", key).Error; err != nil {
+			deleteStored(key)
+			respondInternal(c, "Could not save book audio", err)
+			return
+		}
+		// Only replace our own earlier compile; batch audio is left alone.
+		if book.AudioPath != key && isCompleteAudioKey(book.ID, book.AudioPath) {
+			deleteStored(book.AudioPath)
+		}
+		log.Printf("📚 Compiled book %d audio from %d pages: %s", book.ID, len(chunks), key)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"book_id":    book.ID,
+		"audio_path": key,
+		"pages":      len(chunks),
+		"compiled":   compiled,
+	})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// diskStore is a memStore that keeps a copy of every uploaded file, so a test
+// can inspect what was stored.
+type diskStore struct {
+	memStore
+	dir string
+}
+
+func (s diskStore) PutFile(ctx context.Context, key, localPath, contentType string) error {
+	if err := s.memStore.PutFile(ctx, key, localPath, contentType); err != nil {
+		return err
+	}
+	src, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(filepath.Join(s.dir, filepath.Base(key)))
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+func TestCompleteAudioKey_TracksPages(t *testing.T) {
+	mp3 := audioFormats["mp3"]
+	pages := []BookChunk{{Index: 0, FinalAudioPath: "audio/4/page_0_aaaa.mp3"}, {Index: 1, FinalAudioPath: "audio/4/page_1_bbbb.mp3"}}
+	key := completeAudioKey(4, completeAudioFingerprint(pages, mp3), mp3.Ext)
+	if !isCompleteAudioKey(4, key) || isCompleteAudioKey(5, key) || isCompleteAudioKey(4, "audio/4/book.mp3") {
+		t.Fatalf("isCompleteAudioKey misclassifies %q", key)
+	}
+	if again := completeAudioKey(4, completeAudioFingerprint(pages, mp3), mp3.Ext); again != key {
+		t.Errorf("same pages gave %q then %q", key, again)
+	}
+	pages[1].FinalAudioPath = "audio/4/page_1_cccc.mp3"
+	if rerendered := completeAudioKey(4, completeAudioFingerprint(pages, mp3), mp3.Ext); rerendered == key {
+		t.Error("re-rendered page kept the old key")
+	}
+	if opus := audioFormats["opus"]; completeAudioFingerprint(pages, opus) == completeAudioFingerprint(pages, mp3) {
+		t.Error("format change kept the old fingerprint")
+	}
+}
+
+// Compiles three one-second pages and checks the stored file is three seconds
+// long, then that compiling again is a no-op. Needs a real ffmpeg/ffprobe.
+func TestCompileBookAudio_ThreePages(t *testing.T) {
+	if _, err := exec.LookPath(ffmpegBin); err != nil {
+		t.Skip("ffmpeg not installed")
+	}
+	if _, err := exec.LookPath(ffprobeBin); err != nil {
+		t.Skip("ffprobe not installed")
+	}
+	t.Chdir(t.TempDir())
+	os.Mkdir("audio", 0755)
+	stored := t.TempDir()
+	orig := store
+	ds := diskStore{memStore: memStore{}, dir: stored}
+	store = ds
+	t.Cleanup(func() { store = orig })
+
+	dir := t.TempDir()
+	var chunks []BookChunk
+	for i, freq := range []string{"440", "550", "660"} {
+		p := filepath.Join(dir, "page"+freq+".mp3")
+		if out, err := exec.Command(ffmpegBin, "-y", "-f", "lavfi", "-i", "sine=frequency="+freq+":duration=1",
+			"-c:a", "libmp3lame", "-q:a", "2", p).CombinedOutput(); err != nil {
+			t.Fatalf("make tone: %v\n%s", err, out)
+		}
+		chunks = append(chunks, BookChunk{BookID: 7, Index: i, TTSStatus: "completed", FinalAudioPath: p})
+	}
+	book := Book{ID: 7, AudioFormat: "mp3"}
+
+	key, compiled, err := compileBookAudio(context.Background(), book, chunks)
+	if err != nil || !compiled {
+		t.Fatalf("compileBookAudio = %q, %v, %v", key, compiled, err)
+	}
+	if len(ds.memStore) != 1 || !isCompleteAudioKey(7, key) {
+		t.Fatalf("stored %v, want one complete file", ds.memStore)
+	}
+	dur, err := getTTSDuration(filepath.Join(stored, filepath.Base(key)))
+	if err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if math.Abs(dur-3) > 0.15 {
+		t.Fatalf("compiled duration %.3fs, want ~3s (sum of pages)", dur)
+	}
+
+	book.AudioPath = key
+	if again, compiled, err := compileBookAudio(context.Background(), book, chunks); err != nil || compiled || again != key {
+		t.Errorf("second compile = %q, %v, %v; want a no-op", again, compiled, err)
+	}
+	if len(ds.memStore) != 1 {
+		t.Errorf("second compile stored another file: %v", ds.memStore)
+	}
+}
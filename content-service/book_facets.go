@@ -0,0 +1,38 @@
+package main
+
+import "gorm.io/gorm"
+
+// Library facets for GET /user/books?include_facets=true: how many of the
+// user's books fall in each category and genre, for the client's filter
+// badges. Counts cover the whole library, not just the filtered page, so the
+// badges stay put while the user switches filters. Books without a genre are
+// counted under "".
+
+// facetCount is one facet value and how many books carry it.
+type facetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+type bookFacets struct {
+	Categories []facetCount `json:"categories"`
+	Genres     []facetCount `json:"genres"`
+}
+
+// bookFacetCounts groups userID's books by category and by genre, largest
+// group first.
+func bookFacetCounts(tx *gorm.DB, userID uint) (bookFacets, error) {
+	facets := bookFacets{Categories: []facetCount{}, Genres: []facetCount{}}
+	for column, out := range map[string]*[]facetCount{"category": &facets.Categories, "genre": &facets.Genres} {
+		err := tx.Model(&Book{}).
+			Select("COALESCE("+column+", '') AS value, COUNT(*) AS count").
+			Where("user_id = ?", userID).
+			Group("COALESCE(" + column + ", '')").
+			Order("count DESC, value ASC").
+			Find(out).Error
+		if err != nil {
+			return bookFacets{}, err
+		}
+	}
+	return facets, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+func TestBookFacetCounts_GroupsInSQL(t *testing.T) {
+	stmts := dryRunDB(t)
+	if _, err := bookFacetCounts(db, 3); err != nil {
+		t.Fatal(err)
+	}
+	joined := strings.Join(*stmts, "\n")
+	for _, want := range []string{"GROUP BY COALESCE(category, '')", "GROUP BY COALESCE(genre, '')", "user_id = 3"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("facet queries missing %q:\n%s", want, joined)
+		}
+	}
+}
+
+func TestListBooks_IncludeFacets(t *testing.T) {
+	withTestDB(t)
+	for _, b := range []Book{
+		{Title: "A", Category: "Fiction", Genre: "Fantasy", UserID: 1},
+		{Title: "B", Category: "Fiction", Genre: "Fantasy", UserID: 1},
+		{Title: "C", Category: "Fiction", Genre: "Mystery", UserID: 1},
+		{Title: "D", Category: "Non-Fiction", Genre: "History", UserID: 1},
+		{Title: "E", Category: "Non-Fiction", UserID: 1},
+		{Title: "Other user's", Category: "Fiction", Genre: "Fantasy", UserID: 2},
+	} {
+		db.Create(&b)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/books", func(c *gin.Context) { c.Set("claims", jwt.MapClaims{"user_id": float64(1)}) }, listBooksHandler)
+	get := func(query string) (map[string]json.RawMessage, int) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/books"+query, nil))
+		var body map[string]json.RawMessage
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return body, w.Code
+	}
+
+	if body, code := get(""); code != http.StatusOK || body["facets"] != nil {
+		t.Fatalf("facets returned without include_facets: %d %v", code, body)
+	}
+
+	// Facets cover the whole library even when the page is filtered.
+	body, code := get("?include_facets=true&genre=Mystery")
+	var books []BookResponse
+	var facets bookFacets
+	json.Unmarshal(body["books"], &books)
+	json.Unmarshal(body["facets"], &facets)
+	if code != http.StatusOK || len(books) != 1 {
+		t.Fatalf("filtered page = %d, %d books", code, len(books))
+	}
+	wantCategories := []facetCount{{"Fiction", 3}, {"Non-Fiction", 2}}
+	wantGenres := []facetCount{{"Fantasy", 2}, {"", 1}, {"History", 1}, {"Mystery", 1}}
+	if !reflect.DeepEqual(facets.Categories, wantCategories) {
+		t.Errorf("categories = %v, want %v", facets.Categories, wantCategories)
+	}
+	if !reflect.DeepEqual(facets.Genres, wantGenres) {
+		t.Errorf("genres = %v, want %v", facets.Genres, wantGenres)
+	}
+}
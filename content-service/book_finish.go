@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Finished books. A progress row is finished once it reaches
+// bookCompletedPercent — by listening, or explicitly via POST
+// /user/books/:book_id/finish — and PlaybackProgress.FinishedAt records when.
+// Un-finishing (POST /user/books/:book_id/unfinish) resets the book to the
+// start. Each flip of FinishedAt is a conditional UPDATE, so only one of two
+// racing devices moves the user's books_read counter in auth-service
+// (+1 on finish, -1 on unfinish).
+
+// adjustBooksRead moves the user's books_read counter by delta through
+// auth-service's signed internal endpoint. Swappable in tests.
+var adjustBooksRead = func(userID uint, delta int) error {
+	secret := getEnv("SERVICE_SECRET", "")
+	if secret == "" {
+		return errors.New("SERVICE_SECRET not set")
+	}
+	body, _ := json.Marshal(gin.H{"delta": delta})
+	url := fmt.Sprintf("%s/internal/users/%d/books-read", getEnv("AUTH_SERVICE_URL", "http://auth-service:8082"), userID)
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := signServiceRequest(req, secret, body); err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("auth-service returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// setProgressFinished flips progress id's finished state and reports whether
+// this call made the change. Moving books_read follows only a real flip.
+func setProgressFinished(tx *gorm.DB, userID, id uint, finished bool) bool {
+	q := tx.Model(&PlaybackProgress{}).Where("id = ?", id)
+	var res *gorm.DB
+	if finished {
+		res = q.Where("finished_at IS NULL").Update("finished_at", time.Now())
+	} else {
+		res = q.Where("finished_at IS NOT NULL").Update("finished_at", nil)
+	}
+	if res.Error != nil {
+		log.Printf("⚠️ Failed to mark progress %d finished=%v: %v", id, finished, res.Error)
+		return false
+	}
+	if res.RowsAffected == 0 {
+		return false
+	}
+	delta := 1
+	if !finished {
+		delta = -1
+	}
+	if err := adjustBooksRead(userID, delta); err != nil {
+		log.Printf("⚠️ books_read %+d for user %d not recorded: %v", delta, userID, err)
+	}
+	return true
+}
+
+// markFinishedIfComplete finishes progress once listening reaches
+// bookCompletedPercent.
+func markFinishedIfComplete(tx *gorm.DB, progress *PlaybackProgress) {
+	if progress.FinishedAt == nil && progress.CompletionPercent >= bookCompletedPercent {
+		setProgressFinished(tx, progress.UserID, progress.ID, true)
+	}
+}
+
+// finishBookHandler marks a book as finished without listening to the end:
+// position jumps to the end and completion to 100%.
+// POST /user/books/:book_id/finish
+func finishBookHandler(c *gin.Context) {
+	book := c.MustGet("book").(Book)
+	userID := getUserIDFromContext(c)
+
+	var last BookChunk
+	db.Where("book_id = ?", book.ID).Order("index DESC").First(&last)
+	duration := float64(last.EndTime)
+
+	var progress PlaybackProgress
+	err := db.Where("user_id = ? AND book_id = ?", userID, book.ID).First(&progress).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		progress = PlaybackProgress{UserID: userID, BookID: book.ID, LastPlayedAt: time.Now()}
+		if err := db.Create(&progress).Error; err != nil {
+			respondInternal(c, "Failed to save progress", err)
+			return
+		}
+	case err != nil:
+		respondInternal(c, "Database error", err)
+		return
+	}
+	if progress.Duration > duration {
+		duration = progress.Duration // client-reported length of the actual audio
+	}
+	if err := db.Model(&PlaybackProgress{}).Where("id = ?", progress.ID).Updates(map[string]interface{}{
+		"current_position":   duration,
+		"duration":           duration,
+		"chunk_index":        last.Index,
+		"completion_percent": 100,
+		"last_played_at":     time.Now(),
+	}).Error; err != nil {
+		respondInternal(c, "Failed to save progress", err)
+		return
+	}
+	setProgressFinished(db, userID, progress.ID, true)
+	if err := db.First(&progress, progress.ID).Error; err != nil {
+		respondInternal(c, "Database error", err)
+		return
+	}
+	publishProgressMilestones(db, &progress)
+
+	log.Printf("🏁 User %d marked book %d finished", userID, book.ID)
+	c.JSON(http.StatusOK, finishedResponse(progress))
+}
+
+// unfinishBookHandler resets a book to unstarted: position, completion and
+// announced milestones go back to zero. Listening history (play count, total
+// listen time) is kept.
+// POST /user/books/:book_id/unfinish
+func unfinishBookHandler(c *gin.Context) {
+	book := c.MustGet("book").(Book)
+	userID := getUserIDFromContext(c)
+
+	var progress PlaybackProgress
+	err := db.Where("user_id = ? AND book_id = ?", userID, book.ID).First(&progress).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusOK, finishedResponse(PlaybackProgress{BookID: book.ID}))
+		return
+	}
+	if err != nil {
+		respondInternal(c, "Database error", err)
+		return
+	}
+	if err := db.Model(&PlaybackProgress{}).Where("id = ?", progress.ID).Updates(map[string]interface{}{
+		"current_position":   0,
+		"chunk_index":        0,
+		"completion_percent": 0,
+		"last_milestone":     0,
+	}).Error; err != nil {
+		respondInternal(c, "Failed to save progress", err)
+		return
+	}
+	setProgressFinished(db, userID, progress.ID, false)
+	if err := db.First(&progress, progress.ID).Error; err != nil {
+		respondInternal(c, "Database error", err)
+		return
+	}
+
+	log.Printf("↩️ User %d marked book %d unfinished", userID, book.ID)
+	c.JSON(http.StatusOK, finishedResponse(progress))
+}
+
+func finishedResponse(p PlaybackProgress) gin.H {
+	return gin.H{
+		"progress": ProgressResponse{
+			BookID:            p.BookID,
+			CurrentPosition:   p.CurrentPosition,
+			Duration:          p.Duration,
+			ChunkIndex:        p.ChunkIndex,
+			CompletionPercent: p.CompletionPercent,
+			LastPlayedAt:      p.LastPlayedAt,
+		},
+		"finished":    p.FinishedAt != nil,
+		"finished_at": p.FinishedAt,
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+// stubBooksRead records books_read adjustments instead of calling
+// auth-service.
+func stubBooksRead(t *testing.T) *[]int {
+	t.Helper()
+	var deltas []int
+	orig := adjustBooksRead
+	adjustBooksRead = func(userID uint, delta int) error { deltas = append(deltas, delta); return nil }
+	t.Cleanup(func() { adjustBooksRead = orig })
+	return &deltas
+}
+
+func finishRouter(userID uint) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"user_id": float64(userID)})
+		c.Set("user_id", userID)
+	})
+	r.POST("/books/:book_id/finish", requireBookOwnership(), finishBookHandler)
+	r.POST("/books/:book_id/unfinish", requireBookOwnership(), unfinishBookHandler)
+	r.POST("/books/:book_id/progress", requireBookOwnership(), UpdatePlaybackProgressHandler)
+	return r
+}
+
+func TestFinishAndUnfinishBook(t *testing.T) {
+	withTestDB(t)
+	deltas := stubBooksRead(t)
+	book := Book{Title: "Dune", Category: "Fiction", UserID: 4}
+	db.Create(&book)
+	db.Create(&BookChunk{BookID: book.ID, Index: 0, EndTime: 300, AudioPath: "a"})
+	db.Create(&BookChunk{BookID: book.ID, Index: 1, EndTime: 600, AudioPath: "b"})
+	r := finishRouter(4)
+	post := func(action string) (int, PlaybackProgress) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/books/%d/%s", book.ID, action), nil))
+		var p PlaybackProgress
+		db.Where("user_id = ? AND book_id = ?", 4, book.ID).First(&p)
+		return w.Code, p
+	}
+
+	// Finish with a few seconds left: completion flips to 100, once.
+	db.Create(&PlaybackProgress{UserID: 4, BookID: book.ID, CurrentPosition: 590, Duration: 600, CompletionPercent: 93.3, ChunkIndex: 1})
+	code, p := post("finish")
+	if code != http.StatusOK || p.CompletionPercent != 100 || p.CurrentPosition != 600 || p.ChunkIndex != 1 || p.FinishedAt == nil {
+		t.Fatalf("finish = %d, progress %+v", code, p)
+	}
+	if code, _ := post("finish"); code != http.StatusOK || len(*deltas) != 1 || (*deltas)[0] != 1 {
+		t.Fatalf("books_read deltas after finishing twice = %v, want [1]", *deltas)
+	}
+
+	// Unfinish: back to the start, and the increment is reverted once.
+	code, p = post("unfinish")
+	if code != http.StatusOK || p.CompletionPercent != 0 || p.CurrentPosition != 0 || p.LastMilestone != 0 || p.FinishedAt != nil {
+		t.Fatalf("unfinish = %d, progress %+v", code, p)
+	}
+	post("unfinish")
+	if fmt.Sprint(*deltas) != "[1 -1]" {
+		t.Fatalf("books_read deltas = %v, want [1 -1]", *deltas)
+	}
+
+	// Listening to the end counts as finishing too.
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/books/%d/progress", book.ID),
+		strings.NewReader(`{"current_position":590,"chunk_index":1}`)))
+	if w.Code != http.StatusOK || fmt.Sprint(*deltas) != "[1 -1 1]" {
+		t.Fatalf("listened to the end: %d, deltas %v", w.Code, *deltas)
+	}
+}
+
+func TestFinishBook_NoProgressYet(t *testing.T) {
+	withTestDB(t)
+	deltas := stubBooksRead(t)
+	book := Book{Title: "Unopened", Category: "Fiction", UserID: 5}
+	db.Create(&book)
+	r := finishRouter(5)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/books/%d/unfinish", book.ID), nil))
+	if w.Code != http.StatusOK || len(*deltas) != 0 {
+		t.Fatalf("unfinish unstarted book = %d, deltas %v", w.Code, *deltas)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/books/%d/finish", book.ID), nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"finished":true`) || len(*deltas) != 1 {
+		t.Fatalf("finish unstarted book = %d %s, deltas %v", w.Code, w.Body, *deltas)
+	}
+}
+
+func TestAdjustBooksRead_SignedCall(t *testing.T) {
+	var path string
+	var body struct{ Delta int }
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Service-Signature") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		path = r.URL.Path
+		b, _ := io.ReadAll(r.Body)
+		json.Unmarshal(b, &body)
+	}))
+	defer auth.Close()
+	t.Setenv("AUTH_SERVICE_URL", auth.URL)
+
+	t.Setenv("SERVICE_SECRET", "")
+	if err := adjustBooksRead(7, 1); err == nil {
+		t.Fatal("unsigned books_read call attempted without SERVICE_SECRET")
+	}
+	t.Setenv("SERVICE_SECRET", "s3cret")
+	if err := adjustBooksRead(7, -1); err != nil || path != "/internal/users/7/books-read" || body.Delta != -1 {
+		t.Fatalf("adjustBooksRead = %v (path %q, delta %d)", err, path, body.Delta)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Retry-safe book creation. A client may send an Idempotency-Key header with
+// POST /user/books; the first request with a key creates the book and records
+// the key against it, and any repeat from the same user within the TTL gets
+// that same book back instead of a duplicate. Reusing a key for a different
+// book is rejected rather than silently answered with the wrong one.
+
+// maxIdempotencyKeyLen bounds the header (the column is varchar(255)).
+const maxIdempotencyKeyLen = 255
+
+var (
+	errIdempotencyKeyReused = errors.New("idempotency key was already used for a different book")
+	errIdempotentBookGone   = errors.New("the book created with this idempotency key has been deleted")
+)
+
+// BookCreateKey records which book an Idempotency-Key created.
+type BookCreateKey struct {
+	UserID         uint   `gorm:"primaryKey;autoIncrement:false"`
+	IdempotencyKey string `gorm:"primaryKey;size:255"`
+	RequestHash    string `gorm:"size:64;not null"`
+	BookID         uint
+	CreatedAt      time.Time `gorm:"index"`
+}
+
+// bookCreateKeyTTL is how long a key is honoured
+// (BOOK_IDEMPOTENCY_TTL_HOURS, default 24).
+func bookCreateKeyTTL() time.Duration {
+	return time.Duration(envInt("BOOK_IDEMPOTENCY_TTL_HOURS", 24)) * time.Hour
+}
+
+// bookRequestHash fingerprints the fields a create request sets, so a key
+// replayed with a different body can be told apart from a genuine retry.
+func bookRequestHash(b Book) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		b.Title, b.Author, strings.ToLower(b.Category), b.Genre, b.AudioFormat, b.ISBN,
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// createBookOnce creates book. With a non-empty key it first claims the key
+// for the book's user; if a live claim already exists, book is loaded from
+// that claim instead and replayed is true. The claim and the book are written
+// in one transaction, and a concurrent request with the same key blocks on
+// the claim's primary key until this one commits, then replays it.
+func createBookOnce(book *Book, key string) (replayed bool, err error) {
+	if key == "" {
+		return false, db.Create(book).Error
+	}
+	hash := bookRequestHash(*book)
+	// An expired claim no longer counts; clear it so the key can be reused.
+	if err := db.Where("user_id = ? AND idempotency_key = ? AND created_at < ?", book.UserID, key, time.Now().Add(-bookCreateKeyTTL())).
+		Delete(&BookCreateKey{}).Error; err != nil {
+		return false, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&BookCreateKey{UserID: book.UserID, IdempotencyKey: key, RequestHash: hash})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			var prior BookCreateKey
+			if err := tx.Where("user_id = ? AND idempotency_key = ?", book.UserID, key).First(&prior).Error; err != nil {
+				return err
+			}
+			if prior.RequestHash != hash {
+				return errIdempotencyKeyReused
+			}
+			if err := tx.Where("id = ? AND user_id = ?", prior.BookID, book.UserID).First(book).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return errIdempotentBookGone
+				}
+				return err
+			}
+			replayed = true
+			return nil
+		}
+		if err := tx.Create(book).Error; err != nil {
+			return err
+		}
+		return tx.Model(&BookCreateKey{}).Where("user_id = ? AND idempotency_key = ?", book.UserID, key).
+			Update("book_id", book.ID).Error
+	})
+	if err != nil {
+		return false, fmt.Errorf("create book with idempotency key: %w", err)
+	}
+	return replayed, nil
+}
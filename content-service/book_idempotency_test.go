@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+func TestBookRequestHash(t *testing.T) {
+	a := Book{Title: "Dune", Author: "Frank Herbert", Category: "Fiction"}
+	b := a
+	b.Category = "fiction" // categories match case-insensitively
+	if bookRequestHash(a) != bookRequestHash(b) {
+		t.Error("category case changed the hash")
+	}
+	b.Title = "Dune Messiah"
+	if bookRequestHash(a) == bookRequestHash(b) {
+		t.Error("different titles hash the same")
+	}
+}
+
+// createBookRouter serves POST /user/books for user 1 and counts cover fetches.
+func createBookRouter(t *testing.T) (*gin.Engine, *int) {
+	t.Helper()
+	covers := 0
+	orig := enqueueFetchCover
+	enqueueFetchCover = func(uint, string, string, string) error { covers++; return nil }
+	t.Cleanup(func() { enqueueFetchCover = orig })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/user/books", func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"user_id": float64(1)})
+	}, createBookHandler)
+	return r, &covers
+}
+
+func postBook(r http.Handler, key, body string) (int, uint, http.Header) {
+	req := httptest.NewRequest(http.MethodPost, "/user/books", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var resp struct {
+		Book struct{ ID uint } `json:"book"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp.Book.ID, w.Header()
+}
+
+func TestCreateBook_RepeatedKeyReturnsSameBook(t *testing.T) {
+	withTestDB(t)
+	r, covers := createBookRouter(t)
+	body := `{"title":"Dune","author":"Frank Herbert","category":"Fiction"}`
+
+	code, first, _ := postBook(r, "retry-1", body)
+	if code != http.StatusOK || first == 0 {
+		t.Fatalf("first create: %d id=%d", code, first)
+	}
+	code, again, hdr := postBook(r, "retry-1", body)
+	if code != http.StatusOK || again != first || hdr.Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("retry: %d id=%d (want %d) replayed=%q", code, again, first, hdr.Get("Idempotent-Replayed"))
+	}
+	var n int64
+	db.Model(&Book{}).Count(&n)
+	if n != 1 || *covers != 1 {
+		t.Fatalf("%d books, %d cover fetches after a retry; want 1 and 1", n, *covers)
+	}
+
+	// The same key with a different book is a client bug, not a retry.
+	if code, _, _ := postBook(r, "retry-1", `{"title":"Emma","category":"Fiction"}`); code != http.StatusConflict {
+		t.Fatalf("reused key with new body: %d, want 409", code)
+	}
+}
+
+func TestCreateBook_DistinctKeysCreateDistinctBooks(t *testing.T) {
+	withTestDB(t)
+	r, _ := createBookRouter(t)
+	body := `{"title":"Dune","author":"Frank Herbert","category":"Fiction"}`
+
+	_, a, _ := postBook(r, "key-a", body)
+	_, b, _ := postBook(r, "key-b", body)
+	_, c, _ := postBook(r, "", body)
+	if a == 0 || b == 0 || c == 0 || a == b || b == c || a == c {
+		t.Fatalf("book ids = %d, %d, %d; want three distinct books", a, b, c)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log"
+)
+
+// Per-book processing lock. Batch transcription and the on-demand
+// /process-chunks path both synthesize a book's pages; run together on one
+// book they process the same chunks twice and clobber each other's files.
+// Each takes this lock first: a Postgres session advisory lock, so it holds
+// across API instances and workers and is released if the holder dies.
+
+// bookLockNamespace occupies the high 32 bits of every book lock key, keeping
+// them clear of small single-purpose keys such as migrationLockKey.
+const bookLockNamespace = 7461
+
+var errBookLocked = errors.New("book is already being processed")
+
+func bookLockKey(bookID uint) int64 {
+	return int64(bookLockNamespace)<<32 | int64(uint32(bookID))
+}
+
+// lockBook takes bookID's processing lock on a dedicated connection and
+// returns the function that releases it. With wait false it fails fast with
+// errBookLocked when another holder has the lock; with wait true it blocks
+// until the lock is free or ctx ends.
+func lockBook(ctx context.Context, bookID uint, wait bool) (unlock func(), err error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// A session lock lives as long as its connection. Returning a connection
+	// to the pool in an unknown state could leak the lock to whichever request
+	// borrows it next, so on any doubt the connection is discarded instead.
+	discard := func() {
+		conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		conn.Close()
+	}
+
+	key := bookLockKey(bookID)
+	if wait {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+			discard()
+			return nil, err
+		}
+	} else {
+		var ok bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&ok); err != nil {
+			discard()
+			return nil, err
+		}
+		if !ok {
+			conn.Close()
+			return nil, errBookLocked
+		}
+	}
+
+	return func() {
+		var released bool
+		if err := conn.QueryRowContext(context.Background(), "SELECT pg_advisory_unlock($1)", key).Scan(&released); err != nil || !released {
+			log.Printf("⚠️ book %d: advisory unlock failed (released=%v): %v", bookID, released, err)
+			discard()
+			return
+		}
+		conn.Close()
+	}, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+// withTestDB points the package db at a freshly migrated schema.
+func withTestDB(t *testing.T) {
+	t.Helper()
+	tx := freshSchemaDB(t)
+	if err := runMigrations(tx, contentMigrations); err != nil {
+		t.Fatal(err)
+	}
+	orig := db
+	db = tx
+	t.Cleanup(func() { db = orig })
+}
+
+func TestBookLockKey_NamespacedPerBook(t *testing.T) {
+	if bookLockKey(1) == bookLockKey(2) {
+		t.Fatal("books share a lock key")
+	}
+	if k := bookLockKey(1); k == migrationLockKey || k>>32 != bookLockNamespace {
+		t.Fatalf("bookLockKey(1) = %d outside the book namespace", k)
+	}
+}
+
+func TestLockBook_ExcludesConcurrentHolders(t *testing.T) {
+	withTestDB(t)
+	ctx := context.Background()
+
+	unlock, err := lockBook(ctx, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lockBook(ctx, 1, false); !errors.Is(err, errBookLocked) {
+		t.Fatalf("second lock on book 1: %v, want errBookLocked", err)
+	}
+	other, err := lockBook(ctx, 2, false)
+	if err != nil {
+		t.Fatalf("lock on book 2 blocked by book 1: %v", err)
+	}
+	other()
+
+	// A waiting holder gets the lock once it is released.
+	got := make(chan error, 1)
+	go func() {
+		u, err := lockBook(ctx, 1, true)
+		if err == nil {
+			u()
+		}
+		got <- err
+	}()
+	select {
+	case err := <-got:
+		t.Fatalf("waiting lock returned while held: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	unlock()
+	if err := <-got; err != nil {
+		t.Fatalf("waiting lock: %v", err)
+	}
+}
+
+// Two batch requests for one book at the same moment: exactly one queues the
+// transcription, the other gets a 409.
+func TestBatchTranscribe_ConcurrentRequestsOneProceeds(t *testing.T) {
+	withTestDB(t)
+	book := Book{Title: "Race", Category: "Fiction", UserID: 1, Status: "pending"}
+	if err := db.Create(&book).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		db.Create(&BookChunk{BookID: book.ID, Index: i, Content: "page", TTSStatus: "pending"})
+	}
+
+	var enqueued atomic.Int32
+	orig := enqueueTranscribeBatch
+	enqueueTranscribeBatch = func(uint, int, int, uint, string, ttsOverride) error {
+		enqueued.Add(1)
+		time.Sleep(100 * time.Millisecond) // hold the handler open across the race
+		return nil
+	}
+	t.Cleanup(func() { enqueueTranscribeBatch = orig })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/books/:book_id/tts/batch", func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"user_id": float64(1), "account_type": "free"})
+		c.Set("book", book)
+	}, BatchTranscribeBookHandler)
+
+	start := make(chan struct{})
+	codes := make([]int, 2)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/books/1/tts/batch", nil)
+			req.Header.Set("Authorization", "Bearer x")
+			w := httptest.NewRecorder()
+			<-start
+			r.ServeHTTP(w, req)
+			codes[i] = w.Code
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	sort.Ints(codes)
+	if codes[0] != http.StatusAccepted || codes[1] != http.StatusConflict {
+		t.Fatalf("status codes = %v, want one 202 and one 409", codes)
+	}
+	if n := enqueued.Load(); n != 1 {
+		t.Fatalf("enqueued %d batches, want 1", n)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func pageParamsFor(rawQuery string) (int, int, bool) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/pages?"+rawQuery, nil)
+	return pageListParams(c)
+}
+
+func TestPageListParams_Clamp(t *testing.T) {
+	cases := []struct {
+		query         string
+		limit, offset int
+	}{
+		{"", 20, 0},
+		{"limit=50&offset=40", 50, 40},
+		{"limit=100000", maxPagesPerRequest, 0},
+		{"limit=0&offset=-3", 20, 0},
+		{"limit=abc", 20, 0},
+	}
+	for _, tc := range cases {
+		limit, offset, _ := pageParamsFor(tc.query)
+		if limit != tc.limit || offset != tc.offset {
+			t.Errorf("%q: limit=%d offset=%d, want %d/%d", tc.query, limit, offset, tc.limit, tc.offset)
+		}
+	}
+}
+
+func TestPageListParams_ContentToggle(t *testing.T) {
+	for query, want := range map[string]bool{
+		"":                      true,
+		"include_content=false": false,
+		"include_content=0":     false,
+		"include_content=true":  true,
+		"include_content=junk":  true,
+	} {
+		if _, _, got := pageParamsFor(query); got != want {
+			t.Errorf("%q: include_content = %v, want %v", query, got, want)
+		}
+	}
+
+	chunk := BookChunk{BookID: 3, Index: 4, Content: "It was a dark and stormy night.", TTSStatus: "completed"}
+	if p := pageEntry(chunk, true); p["content"] != chunk.Content || p["page"] != 5 {
+		t.Errorf("with content: %v", p)
+	}
+	if p := pageEntry(chunk, false); p["content"] != nil || p["status"] != "completed" {
+		t.Errorf("without content: %v", p)
+	}
+}
+
+func TestListBookPagesHandler_QueryIsBounded(t *testing.T) {
+	stmts := dryRunDB(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/books/:book_id/chunks/pages", func(c *gin.Context) { c.Set("book", Book{ID: 7}) }, listBookPagesHandler)
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet,
+		"/books/7/chunks/pages?limit=100000&include_content=false", nil))
+
+	var chunkQuery string
+	for _, s := range *stmts {
+		if strings.Contains(s, `FROM "book_chunks"`) {
+			chunkQuery = s
+		}
+	}
+	if !strings.Contains(chunkQuery, "LIMIT 100") {
+		t.Errorf("limit not clamped: %s", chunkQuery)
+	}
+	if strings.Contains(chunkQuery, `"content"`) || strings.Contains(chunkQuery, "*") {
+		t.Errorf("content column selected despite include_content=false: %s", chunkQuery)
+	}
+}
+
+func TestListBookPagesHandler_TotalFromSameQuery(t *testing.T) {
+	for _, tc := range []struct {
+		book       Book
+		wantWindow bool
+	}{
+		{Book{ID: 7}, true},                 // still chunking / not recorded
+		{Book{ID: 7, PageCount: 40}, false}, // finished: cached total
+	} {
+		stmts := dryRunDB(t)
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.GET("/books/:book_id/chunks/pages", func(c *gin.Context) { c.Set("book", tc.book) }, listBookPagesHandler)
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/books/7/chunks/pages", nil))
+
+		if len(*stmts) != 1 {
+			t.Fatalf("PageCount %d: %d queries, want 1: %v", tc.book.PageCount, len(*stmts), *stmts)
+		}
+		if got := strings.Contains((*stmts)[0], "OVER ()"); got != tc.wantWindow {
+			t.Errorf("PageCount %d: window count = %v, want %v: %s", tc.book.PageCount, got, tc.wantWindow, (*stmts)[0])
+		}
+	}
+}
+
+func TestListBookPagesHandler_TotalPages(t *testing.T) {
+	withTestDB(t)
+	book := Book{Title: "Paged", Category: "Fiction", UserID: 1}
+	db.Create(&book)
+	for i := 0; i < 5; i++ {
+		db.Create(&BookChunk{BookID: book.ID, Index: i, Content: fmt.Sprint("page ", i), TTSStatus: "pending"})
+	}
+	gin.SetMode(gin.TestMode)
+	list := func(query string) (total int64, final, hasMore bool) {
+		t.Helper()
+		var b Book
+		db.First(&b, book.ID)
+		r := gin.New()
+		r.GET("/books/:book_id/chunks/pages", func(c *gin.Context) { c.Set("book", b) }, listBookPagesHandler)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/books/%d/chunks/pages?%s", book.ID, query), nil))
+		var body struct {
+			TotalPages int64 `json:"total_pages"`
+			PagesFinal bool  `json:"pages_final"`
+			HasMore    bool  `json:"has_more"`
+		}
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &body) != nil {
+			t.Fatalf("%s: %d %s", query, w.Code, w.Body)
+		}
+		return body.TotalPages, body.PagesFinal, body.HasMore
+	}
+
+	// Mid-chunking: the total is what exists so far, and says so.
+	if total, final, hasMore := list("limit=2"); total != 5 || final || !hasMore {
+		t.Errorf("in progress: total %d final %v has_more %v", total, final, hasMore)
+	}
+	db.Create(&BookChunk{BookID: book.ID, Index: 5, Content: "page 5", TTSStatus: "pending"})
+	if total, _, _ := list("limit=2&offset=4"); total != 6 {
+		t.Errorf("after another page was inserted: total %d, want 6", total)
+	}
+
+	// Chunking done: the cached count answers every slice the same way.
+	db.Model(&Book{}).Where("id = ?", book.ID).Update("page_count", 6)
+	for _, q := range []string{"limit=2", "limit=2&offset=2", "limit=2&offset=4"} {
+		total, final, hasMore := list(q)
+		if total != 6 || !final || hasMore != (q != "limit=2&offset=4") {
+			t.Errorf("%s: total %d final %v has_more %v", q, total, final, hasMore)
+		}
+	}
+}
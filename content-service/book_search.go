@@ -39,28 +39,33 @@ func SearchBooksHandler(c *gin.Context) {
 	// 1. Parse and validate request
 	var req SearchBooksRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter is required"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "Query parameter is required")
 		return
 	}
 
 	// 2. Validate query is not empty
 	if strings.TrimSpace(req.Query) == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Query cannot be empty"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "Query cannot be empty")
 		return
 	}
 
 	log.Printf("🔍 Searching for books: %s", req.Query)
 
-	// 3. Search for books using OpenAI Chat API (more reliable than Responses API)
-	results, err := searchBooksWithChatCompletion(req.Query)
+	// 3. Search for books using OpenAI Chat API (more reliable than Responses API),
+	// served from the per-query cache when the same search ran recently
+	results, hit, err := cachedBookSearch(req.Query)
 	if err != nil {
 		log.Printf("❌ Failed to search books: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search books", "details": err.Error()})
+		respondInternal(c, "Failed to search books", err)
 		return
 	}
 
 	// 4. Return results (even if empty array)
-	log.Printf("✅ Found %d book results for query: %s", len(results), req.Query)
+	cache := "miss"
+	if hit {
+		cache = "hit"
+	}
+	log.Printf("✅ Found %d book results for query: %s (cache %s)", len(results), req.Query, cache)
 	c.JSON(http.StatusOK, SearchBooksResponse{Results: results})
 }
 
@@ -94,7 +99,7 @@ Requirements:
 
 	// Use OpenAI Responses API with web search
 	requestBody := ResponsesRequest{
-		Model: "gpt-4o",
+		Model: searchModel(),
 		Tools: []ResponseTool{
 			{
 				Type: "web_search",
@@ -245,14 +250,128 @@ func extractJSONArray(text string) string {
 	return text
 }
 
+// bookSearchCompletion runs one chat-completion book search and returns the
+// raw message content. A var so tests can stub the OpenAI round trip.
+var bookSearchCompletion = chatBookSearchOnce
+
 // Alternative implementation using Chat Completions API (fallback option)
-// This can be used if the Responses API is not available or fails
+// This can be used if the Responses API is not available or fails.
+//
+// GPT occasionally returns malformed JSON. The reply is parsed tolerantly
+// (parseBookSuggestions keeps every well-formed entry); only when nothing
+// usable comes back is the search retried once with a stricter prompt.
 func searchBooksWithChatCompletion(query string) ([]BookSuggestion, error) {
+	content, err := bookSearchCompletion(query, false)
+	if err != nil {
+		return nil, err
+	}
+	results, perr := parseBookSuggestions(content)
+	if perr == nil {
+		return results, nil
+	}
+	log.Printf("⚠️ Book search reply unusable (%v) — retrying with strict prompt", perr)
+
+	content, err = bookSearchCompletion(query, true)
+	if err != nil {
+		return nil, err
+	}
+	return parseBookSuggestions(content)
+}
+
+// parseBookSuggestions extracts valid suggestions from a model reply. It
+// accepts the {"books":[...]} object (json_object mode) or a bare array, and
+// decodes entries one by one so a single bad or truncated entry doesn't sink
+// the rest. A well-formed empty list is a valid "no matches"; it errors only
+// when the reply is unparseable or no entry has both a title and an author.
+func parseBookSuggestions(content string) ([]BookSuggestion, error) {
+	jsonText := cleanJSONText(content)
+	log.Printf("📖 Raw JSON response: %s", jsonText)
+
+	var entries []json.RawMessage
+	var wrapper struct {
+		Books []json.RawMessage `json:"books"`
+	}
+	wellFormed := true
+	if err := json.Unmarshal([]byte(jsonText), &wrapper); err == nil && wrapper.Books != nil {
+		entries = wrapper.Books
+	} else if err := json.Unmarshal([]byte(extractJSONArray(jsonText)), &entries); err != nil {
+		// Syntax error somewhere (often a truncated reply): salvage every
+		// complete top-level object we can find.
+		wellFormed = false
+		for _, obj := range scanJSONObjects(extractJSONArray(jsonText)) {
+			entries = append(entries, json.RawMessage(obj))
+		}
+	}
+	if wellFormed && len(entries) == 0 {
+		return []BookSuggestion{}, nil
+	}
+
+	results := make([]BookSuggestion, 0, len(entries))
+	for _, raw := range entries {
+		var b BookSuggestion
+		if err := json.Unmarshal(raw, &b); err != nil {
+			continue
+		}
+		if strings.TrimSpace(b.Title) != "" && strings.TrimSpace(b.Author) != "" {
+			results = append(results, b)
+		}
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no valid book results in reply: %.200s", jsonText)
+	}
+	if len(results) < len(entries) {
+		log.Printf("⚠️ Kept %d of %d book results (rest malformed)", len(results), len(entries))
+	}
+	return results, nil
+}
+
+// scanJSONObjects returns the complete, brace-balanced {...} objects nested
+// one level inside text (string-aware, so braces in summaries don't count).
+func scanJSONObjects(text string) []string {
+	var objs []string
+	depth, start := 0, -1
+	inString, escaped := false, false
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == '"':
+				inString = false
+			}
+			continue
+		}
+		switch ch {
+		case '"':
+			inString = true
+		case '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case '}':
+			if depth > 0 {
+				depth--
+				if depth == 0 && start >= 0 {
+					objs = append(objs, text[start:i+1])
+					start = -1
+				}
+			}
+		}
+	}
+	return objs
+}
+
+// chatBookSearchOnce asks the chat model for up to 5 books. strict tightens
+// the prompt and drops temperature to 0 for the retry after a bad reply.
+func chatBookSearchOnce(query string, strict bool) (string, error) {
 	apiKey := os.Getenv("OPENAI_API_KEY")
 	if apiKey == "" {
-		return nil, errors.New("OPENAI_API_KEY not set")
+		return "", errors.New("OPENAI_API_KEY not set")
 	}
-
 	systemPrompt := `You are a book information expert. Return information about real, published books only.
 
 CRITICAL REQUIREMENTS:
@@ -275,13 +394,21 @@ For each book, provide:
 Return a JSON object with a "books" array. Example format:
 {"books":[{"title":"Book Title","author":"Author Name","cover_url":"https://covers.openlibrary.org/b/isbn/9780439708180-L.jpg","summary":"Book summary."}]}`, query)
 
+	temperature := float32(0.7)
+	if strict {
+		userPrompt += `
+
+Your previous reply was not valid JSON. Respond with ONE JSON object exactly like the example: double-quoted keys and strings, no trailing commas, no comments, no text outside the object.`
+		temperature = 0
+	}
+
 	reqBody := ChatRequest{
-		Model: "gpt-4o",
+		Model: searchModel(),
 		Messages: []ChatMessage{
 			{Role: "system", Content: systemPrompt},
 			{Role: "user", Content: userPrompt},
 		},
-		Temperature:    0.7,
+		Temperature:    temperature,
 		MaxTokens:      2000,
 		ResponseFormat: &ResponseFormat{Type: "json_object"},
 	}
@@ -295,42 +422,22 @@ Return a JSON object with a "books" array. Example format:
 	client := &http.Client{Timeout: 60 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("chat completion request failed: %w", err)
+		return "", fmt.Errorf("chat completion request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		b, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("chat completion returned %d: %s", resp.StatusCode, b)
+		return "", fmt.Errorf("chat completion returned %d: %s", resp.StatusCode, b)
 	}
 
 	var chatResp ChatResponse
 	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
-		return nil, fmt.Errorf("decode chat response: %w", err)
+		return "", fmt.Errorf("decode chat response: %w", err)
 	}
 
 	if len(chatResp.Choices) == 0 {
-		return nil, errors.New("no chat completion choices returned")
-	}
-
-	// Parse the JSON from the response
-	jsonText := cleanJSONText(chatResp.Choices[0].Message.Content)
-	log.Printf("📖 Raw JSON response: %s", jsonText)
-
-	// When using json_object mode, OpenAI returns an object, not an array
-	// First try to unmarshal as an object with a "books" field
-	var wrapper struct {
-		Books []BookSuggestion `json:"books"`
+		return "", errors.New("no chat completion choices returned")
 	}
-	if err := json.Unmarshal([]byte(jsonText), &wrapper); err != nil {
-		// If that fails, try to extract and parse as array (fallback)
-		jsonText = extractJSONArray(jsonText)
-		var results []BookSuggestion
-		if err := json.Unmarshal([]byte(jsonText), &results); err != nil {
-			return nil, fmt.Errorf("failed to parse book results: %w. JSON: %s", err, jsonText)
-		}
-		return results, nil
-	}
-
-	return wrapper.Books, nil
+	return chatResp.Choices[0].Message.Content, nil
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// bookSearchCache memoizes /search-books results per normalized query so a
+// repeated search doesn't pay for (and wait on) another OpenAI round trip.
+// In-process only: each API replica warms its own. Guarded by bookSearchCacheMu.
+type bookSearchEntry struct {
+	results []BookSuggestion
+	expires time.Time
+}
+
+var (
+	bookSearchCache   = map[string]bookSearchEntry{}
+	bookSearchCacheMu sync.Mutex
+)
+
+// bookSearchCacheMax bounds memory; past it, expired entries are dropped and
+// then arbitrary ones until there's room.
+const bookSearchCacheMax = 1000
+
+// bookSearchCacheTTL is BOOK_SEARCH_CACHE_TTL_SECONDS (default 1h; 0 disables).
+func bookSearchCacheTTL() time.Duration {
+	return time.Duration(envInt("BOOK_SEARCH_CACHE_TTL_SECONDS", 3600)) * time.Second
+}
+
+// normalizeSearchQuery folds case and whitespace so "Dune " and "dune" share
+// one cache entry.
+func normalizeSearchQuery(q string) string {
+	return strings.Join(strings.Fields(strings.ToLower(q)), " ")
+}
+
+// cachedBookSearch serves query from the cache when fresh, otherwise runs the
+// OpenAI search and caches a successful result. hit reports which happened.
+func cachedBookSearch(query string) (results []BookSuggestion, hit bool, err error) {
+	ttl := bookSearchCacheTTL()
+	key := normalizeSearchQuery(query)
+	now := time.Now()
+
+	if ttl > 0 {
+		bookSearchCacheMu.Lock()
+		e, ok := bookSearchCache[key]
+		bookSearchCacheMu.Unlock()
+		if ok && now.Before(e.expires) {
+			return append([]BookSuggestion(nil), e.results...), true, nil
+		}
+	}
+
+	results, err = searchBooksWithChatCompletion(query)
+	if err != nil || ttl <= 0 {
+		return results, false, err
+	}
+
+	bookSearchCacheMu.Lock()
+	defer bookSearchCacheMu.Unlock()
+	if len(bookSearchCache) >= bookSearchCacheMax {
+		for k, e := range bookSearchCache {
+			if !now.Before(e.expires) {
+				delete(bookSearchCache, k)
+			}
+		}
+		for k := range bookSearchCache {
+			if len(bookSearchCache) < bookSearchCacheMax {
+				break
+			}
+			delete(bookSearchCache, k)
+		}
+	}
+	bookSearchCache[key] = bookSearchEntry{
+		results: append([]BookSuggestion(nil), results...),
+		expires: now.Add(ttl),
+	}
+	return results, false, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCachedBookSearch_SecondIdenticalQueryServedFromCache(t *testing.T) {
+	orig := bookSearchCompletion
+	defer func() { bookSearchCompletion = orig }()
+	bookSearchCache = map[string]bookSearchEntry{}
+	defer func() { bookSearchCache = map[string]bookSearchEntry{} }()
+
+	calls := 0
+	bookSearchCompletion = func(query string, strict bool) (string, error) {
+		calls++
+		return `{"books":[{"title":"Dune","author":"Frank Herbert"}]}`, nil
+	}
+
+	first, hit, err := cachedBookSearch("Dune")
+	if err != nil || hit || len(first) != 1 {
+		t.Fatalf("first search: results %v hit %v err %v", first, hit, err)
+	}
+	second, hit, err := cachedBookSearch("  dune ")
+	if err != nil || !hit {
+		t.Fatalf("second search: hit %v err %v, want a cache hit", hit, err)
+	}
+	if calls != 1 {
+		t.Fatalf("upstream called %d times, want 1", calls)
+	}
+	if len(second) != 1 || second[0].Title != "Dune" {
+		t.Fatalf("cached results = %v", second)
+	}
+
+	// Once the entry expires the next search goes upstream again.
+	e := bookSearchCache["dune"]
+	e.expires = time.Now().Add(-time.Second)
+	bookSearchCache["dune"] = e
+	if _, hit, _ := cachedBookSearch("Dune"); hit || calls != 2 {
+		t.Fatalf("expired entry: hit %v, calls %d", hit, calls)
+	}
+}
+
+func TestCachedBookSearch_DisabledByZeroTTL(t *testing.T) {
+	orig := bookSearchCompletion
+	defer func() { bookSearchCompletion = orig }()
+	bookSearchCache = map[string]bookSearchEntry{}
+	t.Setenv("BOOK_SEARCH_CACHE_TTL_SECONDS", "0")
+
+	calls := 0
+	bookSearchCompletion = func(query string, strict bool) (string, error) {
+		calls++
+		return `{"books":[]}`, nil
+	}
+	cachedBookSearch("dune")
+	cachedBookSearch("dune")
+	if calls != 2 || len(bookSearchCache) != 0 {
+		t.Fatalf("calls %d, cache size %d; TTL 0 must bypass the cache", calls, len(bookSearchCache))
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+func TestSearchBooksWithChatCompletion_RetriesAfterMalformedReply(t *testing.T) {
+	orig := bookSearchCompletion
+	defer func() { bookSearchCompletion = orig }()
+
+	var calls []bool
+	bookSearchCompletion = func(query string, strict bool) (string, error) {
+		calls = append(calls, strict)
+		if !strict {
+			return `{"books": [{"title": "Dune", "author": "Frank Herbert"`, nil // cut off mid-object
+		}
+		return `{"books":[{"title":"Dune","author":"Frank Herbert","cover_url":"https://covers.openlibrary.org/b/isbn/0441013597-L.jpg","summary":"Desert planet."}]}`, nil
+	}
+
+	got, err := searchBooksWithChatCompletion("dune")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(calls) != 2 || calls[0] || !calls[1] {
+		t.Errorf("calls = %v, want one normal then one strict attempt", calls)
+	}
+	if len(got) != 1 || got[0].Title != "Dune" {
+		t.Errorf("results = %+v", got)
+	}
+}
+
+func TestParseBookSuggestions_KeepsValidSubset(t *testing.T) {
+	// One good entry, one with a wrong-typed field, one missing its author,
+	// and a trailing entry truncated mid-string.
+	reply := "```json\n" + `[
+		{"title": "Emma", "author": "Jane Austen", "summary": "A {match}maker."},
+		{"title": 42, "author": "Nobody"},
+		{"title": "Untitled"},
+		{"title": "Persuasion", "author": "Jane Austen"},
+		{"title": "Sandit` + "\n```"
+	got, err := parseBookSuggestions(reply)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0].Title != "Emma" || got[1].Title != "Persuasion" {
+		t.Errorf("results = %+v, want Emma and Persuasion", got)
+	}
+
+	if got, err := parseBookSuggestions(`{"books":[]}`); err != nil || len(got) != 0 {
+		t.Errorf("empty list: got %v, %v; want no results, no error", got, err)
+	}
+	if _, err := parseBookSuggestions("Sorry, I can't help with that."); err == nil {
+		t.Error("expected error for a reply with no JSON")
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// In-book text search. The searchable text is the book's chunks — the whole
+// book — never Book.Content, which only ever held the first 100,000 chars.
+//
+// With FULLTEXT_INDEX=true, search is a Postgres full-text query over
+// to_tsvector(chunk content), backed by the GIN index from migration
+// 0008_chunk_fts, and Book.Content is no longer written at all (it only
+// duplicated chunk data). Otherwise it is a case-insensitive substring match
+// over the same chunks and Book.Content keeps its legacy preview.
+
+// bookContentPreviewMax is how much extracted text the legacy Book.Content
+// preview keeps.
+const bookContentPreviewMax = 100000
+
+const bookTextSearchMax = 50
+
+func fullTextIndexEnabled() bool { return getEnv("FULLTEXT_INDEX", "false") == "true" }
+
+// storeBookContent writes the legacy Book.Content preview of a book's
+// extracted text, unless FULLTEXT_INDEX mode makes it redundant.
+func storeBookContent(bookID uint, text string) error {
+	if fullTextIndexEnabled() {
+		return nil
+	}
+	if len(text) > bookContentPreviewMax {
+		text = text[:bookContentPreviewMax] + "...[truncated]"
+	}
+	return db.Model(&Book{}).Where("id = ?", bookID).Update("content", text).Error
+}
+
+// bookTextHit is one matching page.
+type bookTextHit struct {
+	Page    int    `json:"page"` // 1-based
+	Snippet string `json:"snippet"`
+}
+
+// chunkTSVector must match the 0008_chunk_fts index expression exactly, or
+// Postgres won't use the index.
+const chunkTSVector = `to_tsvector('english', coalesce(content, ''))`
+
+// searchBookText returns the pages of bookID matching q, in reading order.
+func searchBookText(bookID uint, q string, limit, offset int) ([]bookTextHit, error) {
+	var rows []struct {
+		Index   int
+		Snippet string
+		Content string
+	}
+	if fullTextIndexEnabled() {
+		err := db.Raw(`
+			SELECT "index",
+			       ts_headline('english', content, websearch_to_tsquery('english', ?),
+			                   'MaxFragments=1, MaxWords=25, MinWords=8, StartSel=**, StopSel=**') AS snippet
+			FROM book_chunks
+			WHERE book_id = ? AND `+chunkTSVector+` @@ websearch_to_tsquery('english', ?)
+			ORDER BY "index"
+			LIMIT ? OFFSET ?`, q, bookID, q, limit, offset).Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(q) + "%"
+		err := db.Raw(`
+			SELECT "index", content
+			FROM book_chunks
+			WHERE book_id = ? AND content ILIKE ?
+			ORDER BY "index"
+			LIMIT ? OFFSET ?`, bookID, pattern, limit, offset).Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	hits := make([]bookTextHit, len(rows))
+	for i, r := range rows {
+		snippet := r.Snippet
+		if snippet == "" {
+			snippet = matchSnippet(r.Content, q, 80)
+		}
+		hits[i] = bookTextHit{Page: r.Index + 1, Snippet: snippet}
+	}
+	return hits, nil
+}
+
+// matchSnippet returns up to radius bytes of text either side of the first
+// case-insensitive match of q, trimmed to whole runes and marked like
+// ts_headline's output.
+func matchSnippet(text, q string, radius int) string {
+	at := -1
+	if lower := strings.ToLower(text); len(lower) == len(text) { // same byte offsets
+		at = strings.Index(lower, strings.ToLower(q))
+	}
+	if at < 0 {
+		at = strings.Index(text, q)
+	}
+	mark := text[:0]
+	if at >= 0 {
+		mark = text[at : at+len(q)]
+	} else {
+		at = 0 // ILIKE matched a case fold the byte search can't place
+	}
+	start, end := max(at-radius, 0), min(at+len(mark)+radius, len(text))
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+	s := text[start:at]
+	if mark != "" {
+		s += "**" + mark + "**"
+	}
+	s += text[at+len(mark) : end]
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(text) {
+		s += "…"
+	}
+	return strings.TrimSpace(s)
+}
+
+// searchBookTextHandler finds pages of a book containing a word or phrase.
+// GET /user/books/:book_id/search?q=&limit=20&offset=0
+func searchBookTextHandler(c *gin.Context) {
+	book := c.MustGet("book").(Book)
+	q := strings.TrimSpace(c.Query("q"))
+	if utf8.RuneCountInString(q) < 2 {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "q must be at least 2 characters")
+		return
+	}
+	limit := envIntQuery(c, "limit", 20, bookTextSearchMax)
+	offset := envIntQuery(c, "offset", 0, 1_000_000)
+
+	hits, err := searchBookText(book.ID, q, limit, offset)
+	if err != nil {
+		respondInternal(c, "Search failed", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"book_id": book.ID, "query": q, "results": hits})
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMatchSnippet(t *testing.T) {
+	text := strings.Repeat("filler ", 30) + "the Nautilus surfaced" + strings.Repeat(" filler", 30)
+	got := matchSnippet(text, "nautilus", 20)
+	if !strings.Contains(got, "**Nautilus**") || !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") || len(got) > 80 {
+		t.Errorf("snippet = %q", got)
+	}
+	if got := matchSnippet("Çà et là, Ægir.", "ægir", 3); !strings.Contains(got, "Ægir") {
+		t.Errorf("multibyte snippet = %q", got)
+	}
+}
+
+func TestStoreBookContent_SkippedInFullTextMode(t *testing.T) {
+	stmts := dryRunDB(t)
+	t.Setenv("FULLTEXT_INDEX", "true")
+	if err := storeBookContent(1, "All the text."); err != nil || len(*stmts) != 0 {
+		t.Fatalf("full-text mode wrote Book.Content: %v %v", err, *stmts)
+	}
+	t.Setenv("FULLTEXT_INDEX", "")
+	storeBookContent(1, strings.Repeat("x", bookContentPreviewMax+10))
+	if len(*stmts) != 1 || !strings.Contains((*stmts)[0], "...[truncated]") {
+		t.Fatalf("legacy mode = %v", *stmts)
+	}
+}
+
+func bookSearchRouter(book Book) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/user/books/:book_id/search", func(c *gin.Context) {
+		c.Set("book", book)
+		searchBookTextHandler(c)
+	})
+	return r
+}
+
+// A term that only appears far past Book.Content's old 100,000-char cut-off
+// is found through the chunk tsvector.
+func TestSearchBookText_FindsTermInLateChunk(t *testing.T) {
+	withTestDB(t) // migrations include the 0008_chunk_fts index
+	t.Setenv("FULLTEXT_INDEX", "true")
+
+	book := Book{Title: "Long", Category: "Fiction", UserID: 1}
+	db.Create(&book)
+	for i := 0; i < 150; i++ {
+		content := strings.Repeat("The sea was calm and grey that morning. ", 25)
+		if i == 148 {
+			content += "At last the harpooners sighted the whale."
+		}
+		db.Create(&BookChunk{BookID: book.ID, Index: i, Content: content, TTSStatus: "pending"})
+	}
+
+	for q, wantPage := range map[string]int{"harpooner": 149, "sighted whales": 149, "zeppelin": 0} {
+		w := httptest.NewRecorder()
+		bookSearchRouter(book).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/books/1/search?q="+strings.ReplaceAll(q, " ", "+"), nil))
+		var resp struct {
+			Results []bookTextHit `json:"results"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != http.StatusOK {
+			t.Fatalf("search %q = %d %s", q, w.Code, w.Body)
+		}
+		got := fmt.Sprint(resp.Results)
+		if wantPage == 0 {
+			if len(resp.Results) != 0 {
+				t.Errorf("search %q = %s, want no hits", q, got)
+			}
+			continue
+		}
+		if len(resp.Results) != 1 || resp.Results[0].Page != wantPage || !strings.Contains(resp.Results[0].Snippet, "**") {
+			t.Errorf("search %q = %s, want page %d", q, got, wantPage)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BookUpdateRequest is the PATCH /user/books/:book_id body. Every field is
+// optional; only the ones present are changed.
+type BookUpdateRequest struct {
+	Title        *string  `json:"title"`
+	Author       *string  `json:"author"`
+	Genre        *string  `json:"genre"`
+	Category     *string  `json:"category"`
+	AudioFormat  *string  `json:"audio_format"`  // mp3|opus|aac, "" = service default; regenerates the audio
+	FoleyVolume  *float64 `json:"foley_volume"`  // 0–1 Foley level for this book; regenerates the audio
+	RefetchCover bool     `json:"refetch_cover"` // re-run the cover search after a title/author fix
+}
+
+var errInvalidCategory = errors.New("invalid category")
+
+// applyBookUpdate validates req against book and applies it in place. It
+// returns the changed columns (empty when nothing changed) and whether the
+// title or author — the cover search inputs — changed.
+func applyBookUpdate(book *Book, req BookUpdateRequest) (map[string]interface{}, bool, error) {
+	updates := map[string]interface{}{}
+	identityChanged := false
+
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			return nil, false, errors.New("title cannot be empty")
+		}
+		if title != book.Title {
+			book.Title = title
+			updates["title"] = title
+			identityChanged = true
+		}
+	}
+	if req.Author != nil {
+		author := strings.TrimSpace(*req.Author)
+		if author != book.Author {
+			book.Author = author
+			updates["author"] = author
+			identityChanged = true
+		}
+	}
+	if req.Genre != nil {
+		genre := strings.TrimSpace(*req.Genre)
+		if genre != book.Genre {
+			book.Genre = genre
+			updates["genre"] = genre
+		}
+	}
+	if req.Category != nil {
+		category := strings.TrimSpace(*req.Category)
+		if !isValidCategory(category) {
+			return nil, false, errInvalidCategory
+		}
+		if category != book.Category {
+			book.Category = category
+			updates["category"] = category
+		}
+	}
+	if req.AudioFormat != nil {
+		format := strings.ToLower(strings.TrimSpace(*req.AudioFormat))
+		if !isValidAudioFormat(format) {
+			return nil, false, errors.New("audio_format must be mp3, opus or aac")
+		}
+		if format != book.AudioFormat {
+			book.AudioFormat = format
+			updates["audio_format"] = format
+		}
+	}
+	if req.FoleyVolume != nil {
+		volume := *req.FoleyVolume
+		if !isUnitInterval(volume) {
+			return nil, false, errors.New("foley_volume must be between 0 and 1")
+		}
+		if book.FoleyVolume == nil || *book.FoleyVolume != volume {
+			book.FoleyVolume = &volume
+			updates["foley_volume"] = volume
+		}
+	}
+	if settingsRequireReprocess(updates) {
+		book.NeedsReprocess = true
+		updates["needs_reprocess"] = true
+	}
+	return updates, identityChanged, nil
+}
+
+// updateBookHandler (PATCH /user/books/:book_id) corrects a book's metadata
+// without a delete + re-upload. Ownership is enforced by requireBookOwnership.
+// A title/author change re-runs the cover fetch when the book has no cover
+// yet, or when the client asks for it with refetch_cover. Changing an audio
+// setting (audio_format, foley_volume) flags the book and regenerates its pages.
+func updateBookHandler(c *gin.Context) {
+	book := c.MustGet("book").(Book)
+
+	var req BookUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondErrorWith(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid book data", gin.H{"details": err.Error()})
+		return
+	}
+
+	updates, identityChanged, err := applyBookUpdate(&book, req)
+	if errors.Is(err, errInvalidCategory) {
+		respondErrorWith(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid category", gin.H{"allowed_categories": allowedCategories()})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
+	if identityChanged {
+		// New search inputs earn the book a fresh set of background cover
+		// retries (cover_retry.go).
+		updates["cover_attempts"] = 0
+	}
+	if len(updates) > 0 {
+		if err := db.Model(&Book{}).Where("id = ?", book.ID).Updates(updates).Error; err != nil {
+			log.Printf("❌ Failed to update book %d: %v", book.ID, err)
+			respondError(c, http.StatusInternalServerError, errCodeInternal, "Failed to update book")
+			return
+		}
+		log.Printf("✏️ Book %d metadata updated: %v", book.ID, updates)
+	}
+
+	reprocessing := updates["needs_reprocess"] == true
+	if reprocessing {
+		if err := reprocessBook(book.ID, book.UserID, accountTypeFromClaims(c)); err != nil {
+			log.Printf("⚠️ Failed to queue reprocessing for book %d: %v", book.ID, err)
+		}
+	}
+
+	coverRefetch := identityChanged && (book.CoverPath == "" || req.RefetchCover)
+	if coverRefetch {
+		if err := enqueueFetchCover(book.ID, book.Title, book.Author, book.ISBN); err != nil {
+			log.Printf("⚠️ Failed to enqueue cover refetch for book %d: %v", book.ID, err)
+			coverRefetch = false
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Book updated", "book": withCoverProxy(book), "cover_refetch": coverRefetch, "reprocessing": reprocessing})
+}
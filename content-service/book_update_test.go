@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+func strp(s string) *string { return &s }
+
+func TestApplyBookUpdate_Valid(t *testing.T) {
+	book := Book{ID: 1, Title: "Pride and Prejudise", Author: "Jane Austen", Genre: "Romance", Category: "Fiction"}
+	updates, identity, err := applyBookUpdate(&book, BookUpdateRequest{
+		Title:    strp("  Pride and Prejudice "),
+		Genre:    strp("Romance"), // unchanged — not written
+		Category: strp("Classics"),
+	})
+	if err != nil {
+		t.Fatalf("applyBookUpdate: %v", err)
+	}
+	if !identity {
+		t.Error("title change should report identityChanged (cover refetch input)")
+	}
+	if len(updates) != 2 || updates["title"] != "Pride and Prejudice" || updates["category"] != "Classics" {
+		t.Fatalf("updates = %v", updates)
+	}
+	if book.Title != "Pride and Prejudice" || book.Category != "Classics" || book.Author != "Jane Austen" {
+		t.Fatalf("book not updated in place: %+v", book)
+	}
+
+	// Genre-only change doesn't touch the cover.
+	_, identity, _ = applyBookUpdate(&book, BookUpdateRequest{Genre: strp("Classic Romance")})
+	if identity {
+		t.Error("genre change must not trigger a cover refetch")
+	}
+}
+
+func TestApplyBookUpdate_Rejects(t *testing.T) {
+	book := Book{Title: "T", Category: "Fiction"}
+	if _, _, err := applyBookUpdate(&book, BookUpdateRequest{Category: strp("Cookbooks")}); !errors.Is(err, errInvalidCategory) {
+		t.Fatalf("invalid category: err = %v", err)
+	}
+	if _, _, err := applyBookUpdate(&book, BookUpdateRequest{Title: strp("   ")}); err == nil {
+		t.Fatal("blank title accepted")
+	}
+	if book.Title != "T" || book.Category != "Fiction" {
+		t.Fatalf("rejected update mutated the book: %+v", book)
+	}
+}
+
+// The PATCH route sits behind requireBookOwnership: without an authenticated
+// owner the update handler must never run.
+func TestUpdateBookRoute_RequiresOwnership(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if c.GetHeader("X-Test-User") != "" {
+			c.Set("claims", jwt.MapClaims{"user_id": float64(9)})
+		}
+	})
+	reached := false
+	r.PATCH("/books/:book_id", requireBookOwnership(), func(c *gin.Context) {
+		reached = true
+		updateBookHandler(c)
+	})
+
+	for _, tc := range []struct {
+		path string
+		user bool
+		want int
+	}{
+		{"/books/5", false, http.StatusUnauthorized}, // no claims
+		{"/books/abc", true, http.StatusBadRequest},  // not a book id
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, tc.path, strings.NewReader(`{"title":"x"}`))
+		if tc.user {
+			req.Header.Set("X-Test-User", "1")
+		}
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.path, w.Code, tc.want)
+		}
+	}
+	if reached {
+		t.Fatal("update handler ran without an ownership check")
+	}
+}
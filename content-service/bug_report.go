@@ -39,7 +39,7 @@ func SubmitBugReportHandler(c *gin.Context) {
 		Logs        string `json:"logs"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Message) == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "message is required"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "message is required")
 		return
 	}
 
@@ -61,7 +61,7 @@ func SubmitBugReportHandler(c *gin.Context) {
 	}
 	if err := db.Create(&report).Error; err != nil {
 		log.Printf("❌ failed to save bug report from user %d: %v", userID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not save report"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "could not save report")
 		return
 	}
 
@@ -31,13 +31,13 @@ type castEventRequest struct {
 func RecordCastEventHandler(c *gin.Context) {
 	userID := getUserIDFromContext(c)
 	if userID == 0 {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		respondError(c, http.StatusUnauthorized, errCodeUnauthorized, "unauthenticated")
 		return
 	}
 
 	var req castEventRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cast event", "details": err.Error()})
+		respondErrorWith(c, http.StatusBadRequest, errCodeInvalidRequest, "invalid cast event", gin.H{"details": err.Error()})
 		return
 	}
 
@@ -49,7 +49,7 @@ func RecordCastEventHandler(c *gin.Context) {
 		CreatedAt:  time.Now(),
 	}
 	if err := db.Create(&ev).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record cast event"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "failed to record cast event")
 		return
 	}
 
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Book categories. The list clients may file a book under comes from the
+// BOOK_CATEGORIES env var — a JSON array of names, or of
+// {"name": ..., "genres": [...]} objects to attach a sub-genre taxonomy —
+// and falls back to defaultBookCategories. Clients fetch it from
+// GET /categories rather than hardcoding their own copy.
+
+// bookCategory is one category and its suggested sub-genres.
+type bookCategory struct {
+	Name   string   `json:"name"`
+	Genres []string `json:"genres,omitempty"`
+}
+
+// defaultBookCategories matches the iOS Upload screen's original list
+// (UploadBookView.swift).
+var defaultBookCategories = []bookCategory{
+	{Name: "Fiction"}, {Name: "Non-fiction"}, {Name: "Poetry"}, {Name: "Children's"},
+	{Name: "Young Adult"}, {Name: "Classics"}, {Name: "Drama"},
+	{Name: "Comics & Graphic Novels"}, {Name: "Anthology"}, {Name: "Reference"},
+}
+
+// bookCategories is the active list; set once at startup by initBookCategories.
+var bookCategories = defaultBookCategories
+
+// parseBookCategories decodes a BOOK_CATEGORIES value. Names must be
+// non-empty and unique ignoring case, since that is how they are matched.
+func parseBookCategories(raw string) ([]bookCategory, error) {
+	var cats []bookCategory
+	var names []string
+	if err := json.Unmarshal([]byte(raw), &names); err == nil {
+		for _, n := range names {
+			cats = append(cats, bookCategory{Name: n})
+		}
+	} else if err := json.Unmarshal([]byte(raw), &cats); err != nil {
+		return nil, fmt.Errorf("BOOK_CATEGORIES must be a JSON array of names or {name, genres} objects: %w", err)
+	}
+	if len(cats) == 0 {
+		return nil, fmt.Errorf("BOOK_CATEGORIES is empty")
+	}
+	seen := map[string]bool{}
+	for i := range cats {
+		cats[i].Name = strings.TrimSpace(cats[i].Name)
+		key := strings.ToLower(cats[i].Name)
+		if key == "" {
+			return nil, fmt.Errorf("BOOK_CATEGORIES entry %d has no name", i)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("BOOK_CATEGORIES lists %q twice", cats[i].Name)
+		}
+		seen[key] = true
+	}
+	return cats, nil
+}
+
+// initBookCategories loads BOOK_CATEGORIES, if set. A malformed value is
+// fatal: silently falling back would reject uploads the operator meant to allow.
+func initBookCategories() {
+	raw := strings.TrimSpace(os.Getenv("BOOK_CATEGORIES"))
+	if raw == "" {
+		return
+	}
+	cats, err := parseBookCategories(raw)
+	if err != nil {
+		log.Fatalf("FATAL: %v", err)
+	}
+	bookCategories = cats
+	log.Printf("✅ Loaded %d book categories from BOOK_CATEGORIES", len(cats))
+}
+
+// allowedCategories lists the active category names.
+func allowedCategories() []string {
+	names := make([]string, len(bookCategories))
+	for i, cat := range bookCategories {
+		names[i] = cat.Name
+	}
+	return names
+}
+
+// isValidCategory reports whether category is an active category, ignoring case.
+func isValidCategory(category string) bool {
+	for _, cat := range bookCategories {
+		if strings.EqualFold(category, cat.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// listCategoriesHandler returns the active categories and their sub-genres.
+// GET /categories
+func listCategoriesHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"categories": bookCategories})
+}
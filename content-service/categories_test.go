@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// withCategories swaps the active category list for the test.
+func withCategories(t *testing.T, raw string) {
+	t.Helper()
+	cats, err := parseBookCategories(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := bookCategories
+	bookCategories = cats
+	t.Cleanup(func() { bookCategories = orig })
+}
+
+func TestParseBookCategories(t *testing.T) {
+	cats, err := parseBookCategories(`["Fiction", " Cookbooks "]`)
+	if err != nil || len(cats) != 2 || cats[1].Name != "Cookbooks" {
+		t.Fatalf("names form = %+v, %v", cats, err)
+	}
+	cats, err = parseBookCategories(`[{"name":"Fiction","genres":["Fantasy","Mystery"]}]`)
+	if err != nil || len(cats) != 1 || len(cats[0].Genres) != 2 {
+		t.Fatalf("object form = %+v, %v", cats, err)
+	}
+	for _, bad := range []string{`{}`, `[]`, `["Fiction","fiction"]`, `[{"genres":["x"]}]`, `not json`} {
+		if _, err := parseBookCategories(bad); err == nil {
+			t.Errorf("parseBookCategories(%s) accepted", bad)
+		}
+	}
+}
+
+func TestConfiguredCategory_AcceptedAndUnknownRejected(t *testing.T) {
+	withCategories(t, `[{"name":"Fiction"},{"name":"Cookbooks","genres":["Baking"]}]`)
+
+	book := Book{Title: "T", Category: "Fiction"}
+	if _, _, err := applyBookUpdate(&book, BookUpdateRequest{Category: strp("cookbooks")}); err != nil {
+		t.Fatalf("configured category rejected: %v", err)
+	}
+	if _, _, err := applyBookUpdate(&book, BookUpdateRequest{Category: strp("Poetry")}); !errors.Is(err, errInvalidCategory) {
+		t.Fatalf("category missing from config: err = %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/categories", listCategoriesHandler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/categories", nil))
+	var resp struct {
+		Categories []bookCategory `json:"categories"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || len(resp.Categories) != 2 || resp.Categories[1].Genres[0] != "Baking" {
+		t.Fatalf("GET /categories = %d %s", w.Code, w.Body)
+	}
+}
@@ -42,6 +42,42 @@ type ChatResponse struct {
 	} `json:"choices"`
 }
 
+// openAIChatURL is the chat completions endpoint; swappable in tests.
+var openAIChatURL = "https://api.openai.com/v1/chat/completions"
+
+// Models per task. Each task reads its own OPENAI_<TASK>_MODEL and falls back
+// to the model of its group, so OPENAI_CLASSIFY_MODEL still moves every cheap
+// call at once while a single task can be pinned up or down on its own.
+
+// classifyModel is used for cheap classification/extraction calls (mood,
+// ambient, foley, cue picking). Audit L6: these don't need gpt-4o.
+func classifyModel() string { return envStr("OPENAI_CLASSIFY_MODEL", "gpt-4o-mini") }
+
+// segmentationModel splits a page into background-music mood windows.
+func segmentationModel() string { return envStr("OPENAI_SEGMENTATION_MODEL", classifyModel()) }
+
+// genreModel classifies a book's genre and era for its audio profile.
+func genreModel() string { return envStr("OPENAI_GENRE_MODEL", classifyModel()) }
+
+// ambientModel picks a page's ambient setting.
+func ambientModel() string { return envStr("OPENAI_AMBIENT_MODEL", classifyModel()) }
+
+// foleyModel proposes a page's Foley events.
+func foleyModel() string { return envStr("OPENAI_FOLEY_MODEL", classifyModel()) }
+
+// dialogueModel is used for dialogue analysis — the correctness-sensitive
+// call guarded by segmentsCoverInput.
+func dialogueModel() string { return envStr("OPENAI_DIALOGUE_MODEL", "gpt-4o") }
+
+// narrationModel cleans up narrator text before synthesis.
+func narrationModel() string { return envStr("OPENAI_NARRATION_MODEL", dialogueModel()) }
+
+// paletteModel designs the score palette — one call per book, quality matters.
+func paletteModel() string { return envStr("OPENAI_PALETTE_MODEL", "gpt-4o") }
+
+// searchModel answers book and cover searches, which run on web search.
+func searchModel() string { return envStr("OPENAI_SEARCH_MODEL", "gpt-4o") }
+
 // callOpenAIChat posts a ChatRequest and decodes the response — the shared
 // HTTP plumbing for every prompt in the audio pipeline.
 func callOpenAIChat(reqBody ChatRequest) (*ChatResponse, error) {
@@ -53,7 +89,7 @@ func callOpenAIChat(reqBody ChatRequest) (*ChatResponse, error) {
 	if err != nil {
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewReader(bodyBytes))
+	req, err := http.NewRequest("POST", openAIChatURL, bytes.NewReader(bodyBytes))
 	if err != nil {
 		return nil, fmt.Errorf("build HTTP request: %w", err)
 	}
@@ -111,7 +147,7 @@ func generateOverallSoundPrompt(pageText string) (string, error) {
 	if apiKey == "" {
 		return "", errors.New("OPENAI_API_KEY not set")
 	}
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewReader(bodyBytes))
+	req, err := http.NewRequest("POST", openAIChatURL, bytes.NewReader(bodyBytes))
 	if err != nil {
 		return "", fmt.Errorf("build HTTP request: %w", err)
 	}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// stubChatModels points openAIChatURL at a server that records the model of
+// every request and fails it, so each caller stops right after sending.
+func stubChatModels(t *testing.T) *[]string {
+	t.Helper()
+	var models []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		raw, _ := io.ReadAll(r.Body)
+		json.Unmarshal(raw, &body)
+		models = append(models, body.Model)
+		http.Error(w, "stub", http.StatusServiceUnavailable)
+	}))
+	orig := openAIChatURL
+	openAIChatURL = srv.URL
+	t.Cleanup(func() { openAIChatURL = orig; srv.Close() })
+	t.Setenv("OPENAI_API_KEY", "test")
+	return &models
+}
+
+func TestChatTasks_UseConfiguredModel(t *testing.T) {
+	ctx := context.Background()
+	tasks := []struct {
+		env  string
+		call func()
+	}{
+		{"OPENAI_SEGMENTATION_MODEL", func() { generateSegmentInstructions(ctx, 30, "The rain fell.") }},
+		{"OPENAI_GENRE_MODEL", func() { classifyAudioProfile(Book{Title: "Emma"}, "Emma Woodhouse, handsome") }},
+		{"OPENAI_AMBIENT_MODEL", func() { detectAmbientSetting(ctx, "The rain fell.", "") }},
+		{"OPENAI_FOLEY_MODEL", func() { proposeSoundEvents(ctx, "The door slammed.", "") }},
+		{"OPENAI_DIALOGUE_MODEL", func() { analyzeDialogue(ctx, `"Hello," she said.`, "", nil, false) }},
+		{"OPENAI_NARRATION_MODEL", func() { prepareNarratorText(ctx, "Chapter 1. It was cold.") }},
+		{"OPENAI_SEARCH_MODEL", func() { chatBookSearchOnce("emma", false) }},
+		{"OPENAI_PALETTE_MODEL", func() { callOpenAIChat(ChatRequest{Model: paletteModel()}) }},
+	}
+	for _, task := range tasks {
+		t.Run(task.env, func(t *testing.T) {
+			models := stubChatModels(t)
+			want := "model-for-" + task.env
+			t.Setenv(task.env, want)
+			task.call()
+			if len(*models) == 0 {
+				t.Fatal("no chat request sent")
+			}
+			for _, got := range *models {
+				if got != want {
+					t.Errorf("request model = %q, want %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+// Unset per-task models follow their group: the cheap tasks move with
+// OPENAI_CLASSIFY_MODEL and narration with OPENAI_DIALOGUE_MODEL.
+func TestTaskModels_FallBackToGroup(t *testing.T) {
+	if segmentationModel() != "gpt-4o-mini" || narrationModel() != "gpt-4o" || searchModel() != "gpt-4o" {
+		t.Fatalf("defaults: segmentation=%s narration=%s search=%s", segmentationModel(), narrationModel(), searchModel())
+	}
+	t.Setenv("OPENAI_CLASSIFY_MODEL", "cheap")
+	t.Setenv("OPENAI_DIALOGUE_MODEL", "careful")
+	for name, got := range map[string]string{
+		"segmentation": segmentationModel(), "genre": genreModel(), "ambient": ambientModel(),
+		"foley": foleyModel(), "narration": narrationModel(),
+	} {
+		want := "cheap"
+		if name == "narration" {
+			want = "careful"
+		}
+		if got != want {
+			t.Errorf("%s model = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestTTSRequest_UsesEngineModel(t *testing.T) {
+	cfg := openaiEngine
+	cfg.Model = "tts-configured"
+	req, err := buildTTSRequest(context.Background(), &cfg, "key", "Hello.", cfg.NarratorVoice, "", 1.0, DialogueSegment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body TTSPayload
+	json.NewDecoder(req.Body).Decode(&body)
+	if body.Model != "tts-configured" {
+		t.Fatalf("TTS model = %q", body.Model)
+	}
+}
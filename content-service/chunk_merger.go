@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
@@ -47,7 +46,7 @@ func processMergedChunks(bookID uint) error {
 		mergedText += ch.Content + "\n"
 	}
 	textFile := fmt.Sprintf("./audio/book_%d_chunks_%d_%d.txt", bookID, startIdx, endIdx)
-	if err := os.WriteFile(textFile, []byte(mergedText), 0644); err != nil {
+	if err := writeFileAtomic(textFile, strings.NewReader(mergedText), 0644); err != nil {
 		return fmt.Errorf("failed to write merged text: %w", err)
 	}
 
@@ -91,8 +90,7 @@ func processMergedChunks(bookID uint) error {
 	listHandle.Close()
 
 	mergedAudio := fmt.Sprintf("./audio/book_%d_chunks_%d_%d.mp3", bookID, startIdx, endIdx)
-	cmd := exec.Command("ffmpeg", "-y", "-f", "concat", "-safe", "0", "-i", listFile, "-c", "copy", mergedAudio)
-	if output, err := cmd.CombinedOutput(); err != nil {
+	if output, err := runFFmpegAtomic(mergedAudio, "-y", "-f", "concat", "-safe", "0", "-i", listFile, "-c", "copy"); err != nil {
 		return fmt.Errorf("ffmpeg merge fail: %v\n%s", err, output)
 	}
 
@@ -111,7 +109,7 @@ func processMergedChunks(bookID uint) error {
 		ContentHash: contentHash,
 	}
 
-	go processSoundEffectsAndMerge(book, contentHash, pageIndexes) // Page index is not used in this context
+	go processSoundEffectsAndMerge(context.Background(), book, contentHash, pageIndexes) // Page index is not used in this context
 
 	// 8. Save to processed chunk group table (object key)
 	if err := saveProcessedChunkGroup(bookID, startIdx, endIdx, groupKey); err != nil {
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Resumable chunked upload. Large EPUBs/PDFs sent as one multipart request
+// time out on flaky mobile connections and restart from zero; here the client
+// sends the file in fixed-size parts it can retry one at a time:
+//
+//	POST   /user/books/:book_id/upload/chunked                        {filename, size_bytes, sha256}
+//	PUT    /user/books/:book_id/upload/chunked/:upload_id/parts/:part raw bytes of part (1-based)
+//	GET    /user/books/:book_id/upload/chunked/:upload_id             parts received so far (resume)
+//	POST   /user/books/:book_id/upload/chunked/:upload_id/complete    assemble, verify SHA256, ingest
+//	DELETE /user/books/:book_id/upload/chunked/:upload_id             abort
+//
+// Parts are kept on the API host under chunkedUploadRoot/<upload_id>/ until
+// completion; sessions idle for CHUNKED_UPLOAD_TTL_HOURS (default 24) are
+// swept by chunkedUploadJanitorLoop.
+
+// chunkedUploadRoot holds one directory per in-progress upload.
+var chunkedUploadRoot = filepath.Join(uploadBaseDir, ".chunked")
+
+var (
+	errUploadNotFound     = errors.New("upload not found")
+	errUploadIncomplete   = errors.New("upload is missing parts")
+	errUploadHashMismatch = errors.New("assembled file does not match sha256")
+)
+
+var uploadIDRe = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// chunkedUpload is an upload session, persisted as meta.json in its directory.
+type chunkedUpload struct {
+	ID        string    `json:"id"`
+	UserID    uint      `json:"user_id"`
+	BookID    uint      `json:"book_id"`
+	Ext       string    `json:"ext"`
+	SizeBytes int64     `json:"size_bytes"`
+	SHA256    string    `json:"sha256"`
+	PartSize  int64     `json:"part_size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// uploadPartBytes is the part size handed to clients (default 5 MB),
+// overridable via UPLOAD_PART_BYTES.
+func uploadPartBytes() int64 { return int64(envInt("UPLOAD_PART_BYTES", 5<<20)) }
+
+func (u *chunkedUpload) totalParts() int {
+	return int((u.SizeBytes + u.PartSize - 1) / u.PartSize)
+}
+
+// partBytes is the exact size part n (1-based) must have; only the last part
+// may be short.
+func (u *chunkedUpload) partBytes(n int) int64 {
+	if n == u.totalParts() {
+		return u.SizeBytes - int64(n-1)*u.PartSize
+	}
+	return u.PartSize
+}
+
+func (u *chunkedUpload) dir() string { return filepath.Join(chunkedUploadRoot, u.ID) }
+
+func (u *chunkedUpload) partPath(n int) string {
+	return filepath.Join(u.dir(), fmt.Sprintf("part-%05d", n))
+}
+
+// createChunkedUpload assigns u an ID and persists the session.
+func createChunkedUpload(u *chunkedUpload) error {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	u.ID = hex.EncodeToString(b)
+	u.CreatedAt = time.Now().UTC()
+	if err := os.MkdirAll(u.dir(), 0o755); err != nil {
+		return err
+	}
+	meta, _ := json.Marshal(u)
+	return writeFileAtomic(filepath.Join(u.dir(), "meta.json"), strings.NewReader(string(meta)), 0o644)
+}
+
+// loadChunkedUpload reads a session. A malformed ID is reported as not found
+// so it never reaches the filesystem.
+func loadChunkedUpload(id string) (*chunkedUpload, error) {
+	if !uploadIDRe.MatchString(id) {
+		return nil, errUploadNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(chunkedUploadRoot, id, "meta.json"))
+	if os.IsNotExist(err) {
+		return nil, errUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var u chunkedUpload
+	if err := json.Unmarshal(data, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// writeUploadPart stores part n from r, replacing any earlier attempt, and
+// rejects a part whose size isn't exactly partBytes(n).
+func writeUploadPart(u *chunkedUpload, n int, r io.Reader) error {
+	if n < 1 || n > u.totalParts() {
+		return fmt.Errorf("part must be between 1 and %d", u.totalParts())
+	}
+	want := u.partBytes(n)
+	path := u.partPath(n)
+	if err := writeFileAtomic(path, io.LimitReader(r, want+1), 0o644); err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Size() != want {
+		os.Remove(path)
+		return fmt.Errorf("part %d must be %d bytes, got %d", n, want, info.Size())
+	}
+	return nil
+}
+
+// receivedParts lists the part numbers stored so far, ascending.
+func receivedParts(u *chunkedUpload) []int {
+	parts := []int{}
+	for n := 1; n <= u.totalParts(); n++ {
+		if _, err := os.Stat(u.partPath(n)); err == nil {
+			parts = append(parts, n)
+		}
+	}
+	return parts
+}
+
+// assembleChunkedUpload concatenates every part into dest and checks the
+// result against the SHA256 declared at init. dest is only left in place when
+// the hash matches.
+func assembleChunkedUpload(u *chunkedUpload, dest string) error {
+	if len(receivedParts(u)) != u.totalParts() {
+		return errUploadIncomplete
+	}
+	var readers []io.Reader
+	for n := 1; n <= u.totalParts(); n++ {
+		f, err := os.Open(u.partPath(n))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+	// The hash is checked before writeFileAtomic renames over dest, so a
+	// corrupt upload never touches the book's existing original (replace).
+	r := &hashCheckReader{r: io.MultiReader(readers...), h: sha256.New(), want: strings.ToLower(u.SHA256)}
+	return writeFileAtomic(dest, r, 0o644)
+}
+
+// hashCheckReader hashes what it reads and, at EOF, fails with
+// errUploadHashMismatch instead if the SHA-256 isn't want.
+type hashCheckReader struct {
+	r    io.Reader
+	h    hash.Hash
+	want string
+}
+
+func (hr *hashCheckReader) Read(p []byte) (int, error) {
+	n, err := hr.r.Read(p)
+	hr.h.Write(p[:n])
+	if err == io.EOF && hex.EncodeToString(hr.h.Sum(nil)) != hr.want {
+		return n, errUploadHashMismatch
+	}
+	return n, err
+}
+
+// abortChunkedUpload deletes a session and every part it received.
+func abortChunkedUpload(u *chunkedUpload) error {
+	return os.RemoveAll(u.dir())
+}
+
+// sweepChunkedUploads removes sessions with no activity (a part write bumps
+// the directory's mtime) for longer than ttl.
+func sweepChunkedUploads(ttl time.Duration) int {
+	n, freed := sweepTempFiles(chunkedUploadRoot, []string{"*"}, ttl, nil)
+	if n > 0 {
+		log.Printf("🧹 Removed %d abandoned chunked upload(s), reclaimed %.1f MB", n, float64(freed)/1e6)
+	}
+	return n
+}
+
+// chunkedUploadJanitorLoop sweeps abandoned sessions hourly. Runs in the API
+// process, which is where the parts are stored.
+func chunkedUploadJanitorLoop() {
+	ttl := time.Duration(envInt("CHUNKED_UPLOAD_TTL_HOURS", 24)) * time.Hour
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		sweepChunkedUploads(ttl)
+	}
+}
+
+type initChunkedUploadReq struct {
+	Filename  string `json:"filename" binding:"required"`
+	SizeBytes int64  `json:"size_bytes" binding:"required"`
+	SHA256    string `json:"sha256" binding:"required"`
+	Replace   bool   `json:"replace"` // replace the file the book already has
+}
+
+var sha256HexRe = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// initChunkedUploadHandler starts a session. POST /user/books/:book_id/upload/chunked
+func initChunkedUploadHandler(c *gin.Context) {
+	book := c.MustGet("book").(Book)
+	userID := getUserIDFromContext(c)
+
+	// Uploads quota pre-check (consumed on completion, as with presigned uploads).
+	if d := checkAndConsume(userID, accountTypeFromClaims(c), "uploads", 0, book.ID); !d.Allowed {
+		quota429(c, d)
+		return
+	}
+
+	var req initChunkedUploadReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondErrorWith(c, http.StatusBadRequest, errCodeInvalidRequest, "invalid request", gin.H{"details": err.Error()})
+		return
+	}
+	if !allowFileAttach(c, book, req.Replace) {
+		return
+	}
+	ext := validUploadExt(req.Filename)
+	if ext == "" {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "unsupported file type (pdf, txt, epub, mobi, azw, azw3, docx, doc, html, md)")
+		return
+	}
+	if req.SizeBytes <= 0 || req.SizeBytes > maxUploadBytes() {
+		respondErrorWith(c, http.StatusRequestEntityTooLarge, errCodePayloadTooLarge, "file too large", gin.H{"max_bytes": maxUploadBytes()})
+		return
+	}
+	if !sha256HexRe.MatchString(req.SHA256) {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "sha256 must be 64 hex characters")
+		return
+	}
+
+	u := &chunkedUpload{
+		UserID:    userID,
+		BookID:    book.ID,
+		Ext:       ext,
+		SizeBytes: req.SizeBytes,
+		SHA256:    strings.ToLower(req.SHA256),
+		PartSize:  uploadPartBytes(),
+	}
+	if err := createChunkedUpload(u); err != nil {
+		respondInternal(c, "could not start upload", err)
+		return
+	}
+	log.Printf("📤 Chunked upload %s started for book %d (%d bytes, %d parts)", u.ID, book.ID, u.SizeBytes, u.totalParts())
+	c.JSON(http.StatusCreated, gin.H{
+		"upload_id":   u.ID,
+		"part_size":   u.PartSize,
+		"total_parts": u.totalParts(),
+	})
+}
+
+// chunkedUploadFromRequest loads the :upload_id session, answering 404 unless
+// it belongs to the caller's book.
+func chunkedUploadFromRequest(c *gin.Context) (*chunkedUpload, bool) {
+	book := c.MustGet("book").(Book)
+	u, err := loadChunkedUpload(c.Param("upload_id"))
+	if err == nil && (u.BookID != book.ID || u.UserID != getUserIDFromContext(c)) {
+		err = errUploadNotFound
+	}
+	switch {
+	case errors.Is(err, errUploadNotFound):
+		respondError(c, http.StatusNotFound, errCodeNotFound, "Upload not found")
+		return nil, false
+	case err != nil:
+		respondInternal(c, "could not load upload", err)
+		return nil, false
+	}
+	return u, true
+}
+
+// uploadPartHandler stores one part. Re-sending a part overwrites it.
+// PUT /user/books/:book_id/upload/chunked/:upload_id/parts/:part
+func uploadPartHandler(c *gin.Context) {
+	u, ok := chunkedUploadFromRequest(c)
+	if !ok {
+		return
+	}
+	n, err := strconv.Atoi(c.Param("part"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "part must be a number")
+		return
+	}
+	if err := writeUploadPart(u, n, c.Request.Body); err != nil {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"part": n, "received": len(receivedParts(u)), "total_parts": u.totalParts()})
+}
+
+// chunkedUploadStatusHandler reports which parts have arrived so a client can
+// resume after losing its connection.
+// GET /user/books/:book_id/upload/chunked/:upload_id
+func chunkedUploadStatusHandler(c *gin.Context) {
+	u, ok := chunkedUploadFromRequest(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"upload_id":      u.ID,
+		"part_size":      u.PartSize,
+		"total_parts":    u.totalParts(),
+		"received_parts": receivedParts(u),
+	})
+}
+
+// completeChunkedUploadHandler assembles the parts into the book's upload
+// path, verifies the SHA256 and then ingests it exactly like a multipart
+// upload. POST /user/books/:book_id/upload/chunked/:upload_id/complete
+func completeChunkedUploadHandler(c *gin.Context) {
+	u, ok := chunkedUploadFromRequest(c)
+	if !ok {
+		return
+	}
+	book := c.MustGet("book").(Book)
+
+	bookDir := uploadDirForBook(u.UserID, book.ID)
+	if err := os.MkdirAll(bookDir, 0o755); err != nil {
+		respondInternal(c, "Failed to create upload directory", err)
+		return
+	}
+	dest := filepath.Join(bookDir, "original"+u.Ext)
+	switch err := assembleChunkedUpload(u, dest); {
+	case errors.Is(err, errUploadIncomplete):
+		respondErrorWith(c, http.StatusConflict, errCodeConflict, "Upload is missing parts",
+			gin.H{"received_parts": receivedParts(u), "total_parts": u.totalParts()})
+		return
+	case errors.Is(err, errUploadHashMismatch):
+		// The parts are kept: the client can re-send a corrupted one and retry.
+		respondError(c, http.StatusUnprocessableEntity, errCodeUnprocessable, "Uploaded file does not match sha256")
+		return
+	case err != nil:
+		respondInternal(c, "Failed to assemble upload", err)
+		return
+	}
+	if err := abortChunkedUpload(u); err != nil {
+		log.Printf("⚠️ Could not remove parts of upload %s: %v", u.ID, err)
+	}
+	checkAndConsume(u.UserID, accountTypeFromClaims(c), "uploads", 1, book.ID)
+	log.Printf("📦 Chunked upload %s assembled for book %d", u.ID, book.ID)
+	ingestUploadedFile(c, book, u.UserID, u.Ext, dest)
+}
+
+// abortChunkedUploadHandler discards a session and its parts.
+// DELETE /user/books/:book_id/upload/chunked/:upload_id
+func abortChunkedUploadHandler(c *gin.Context) {
+	u, ok := chunkedUploadFromRequest(c)
+	if !ok {
+		return
+	}
+	if err := abortChunkedUpload(u); err != nil {
+		respondInternal(c, "could not abort upload", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func withChunkedUploadRoot(t *testing.T) {
+	t.Helper()
+	orig := chunkedUploadRoot
+	chunkedUploadRoot = t.TempDir()
+	t.Cleanup(func() { chunkedUploadRoot = orig })
+}
+
+func newTestUpload(t *testing.T, content string, partSize int64) *chunkedUpload {
+	t.Helper()
+	sum := sha256.Sum256([]byte(content))
+	u := &chunkedUpload{UserID: 1, BookID: 2, Ext: ".txt", SizeBytes: int64(len(content)),
+		SHA256: hex.EncodeToString(sum[:]), PartSize: partSize}
+	if err := createChunkedUpload(u); err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestChunkedUpload_AssemblesPartsInOrder(t *testing.T) {
+	withChunkedUploadRoot(t)
+	content := "It was a dark and stormy night."
+	u := newTestUpload(t, content, 8)
+	if u.totalParts() != 4 {
+		t.Fatalf("totalParts = %d, want 4", u.totalParts())
+	}
+
+	loaded, err := loadChunkedUpload(u.ID)
+	if err != nil || !reflect.DeepEqual(*loaded, *u) {
+		t.Fatalf("loadChunkedUpload = %+v, %v; want %+v", loaded, err, u)
+	}
+
+	// Parts arrive out of order, and part 2 is retried after a bad attempt.
+	for _, n := range []int{3, 1, 4} {
+		end := min(n*8, len(content))
+		if err := writeUploadPart(u, n, strings.NewReader(content[(n-1)*8:end])); err != nil {
+			t.Fatalf("part %d: %v", n, err)
+		}
+	}
+	if err := writeUploadPart(u, 2, strings.NewReader("short")); err == nil {
+		t.Fatal("short middle part accepted")
+	}
+	dest := filepath.Join(t.TempDir(), "original.txt")
+	if err := assembleChunkedUpload(u, dest); !errors.Is(err, errUploadIncomplete) {
+		t.Fatalf("assemble with missing part: %v, want errUploadIncomplete", err)
+	}
+	if got := receivedParts(u); !reflect.DeepEqual(got, []int{1, 3, 4}) {
+		t.Fatalf("receivedParts = %v", got)
+	}
+	if err := writeUploadPart(u, 2, strings.NewReader(content[8:16])); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := assembleChunkedUpload(u, dest); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(dest)
+	if string(got) != content {
+		t.Fatalf("assembled %q, want %q", got, content)
+	}
+}
+
+func TestChunkedUpload_HashMismatchLeavesNoFile(t *testing.T) {
+	withChunkedUploadRoot(t)
+	u := newTestUpload(t, "abcdef", 4)
+	writeUploadPart(u, 1, strings.NewReader("abcd"))
+	writeUploadPart(u, 2, strings.NewReader("XX")) // right size, wrong bytes
+
+	dest := filepath.Join(t.TempDir(), "original.txt")
+	if err := assembleChunkedUpload(u, dest); !errors.Is(err, errUploadHashMismatch) {
+		t.Fatalf("assemble = %v, want errUploadHashMismatch", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatalf("mismatched file left at dest: %v", err)
+	}
+}
+
+// With replace, a bad upload must not clobber the book's current original.
+func TestChunkedUpload_HashMismatchKeepsPreviousOriginal(t *testing.T) {
+	withChunkedUploadRoot(t)
+	u := newTestUpload(t, "abcdef", 4)
+	writeUploadPart(u, 1, strings.NewReader("abcd"))
+	writeUploadPart(u, 2, strings.NewReader("XX"))
+
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "original.txt")
+	if err := os.WriteFile(dest, []byte("the good original"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := assembleChunkedUpload(u, dest); !errors.Is(err, errUploadHashMismatch) {
+		t.Fatalf("assemble = %v, want errUploadHashMismatch", err)
+	}
+	if got, err := os.ReadFile(dest); err != nil || string(got) != "the good original" {
+		t.Fatalf("previous original = %q, %v", got, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("partial file left behind: %v", entries)
+	}
+}
+
+func TestChunkedUpload_AbortAndSweep(t *testing.T) {
+	withChunkedUploadRoot(t)
+	aborted := newTestUpload(t, "abcdef", 4)
+	writeUploadPart(aborted, 1, strings.NewReader("abcd"))
+	if err := abortChunkedUpload(aborted); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadChunkedUpload(aborted.ID); !errors.Is(err, errUploadNotFound) {
+		t.Fatalf("aborted upload still loads: %v", err)
+	}
+
+	stale := newTestUpload(t, "abcdef", 4)
+	writeUploadPart(stale, 1, strings.NewReader("abcd"))
+	old := time.Now().Add(-48 * time.Hour)
+	os.Chtimes(stale.dir(), old, old)
+	active := newTestUpload(t, "abcdef", 4)
+
+	if n := sweepChunkedUploads(24 * time.Hour); n != 1 {
+		t.Fatalf("swept %d sessions, want 1", n)
+	}
+	if _, err := os.Stat(stale.dir()); !os.IsNotExist(err) {
+		t.Fatalf("stale session not swept: %v", err)
+	}
+	if _, err := loadChunkedUpload(active.ID); err != nil {
+		t.Fatalf("active session swept: %v", err)
+	}
+}
+
+func TestLoadChunkedUpload_RejectsMalformedID(t *testing.T) {
+	withChunkedUploadRoot(t)
+	for _, id := range []string{"", "../meta", strings.Repeat("g", 32)} {
+		if _, err := loadChunkedUpload(id); !errors.Is(err, errUploadNotFound) {
+			t.Errorf("loadChunkedUpload(%q) = %v, want errUploadNotFound", id, err)
+		}
+	}
+}
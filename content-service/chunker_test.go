@@ -181,3 +181,34 @@ func TestCleanupForTTS_FluencyNormalization(t *testing.T) {
 		t.Fatalf("expected clean flowing text, got: %q", got)
 	}
 }
+
+func TestPageSpans_MergesTinyTail(t *testing.T) {
+	sentence := "It was the best of times, it was the worst of times, it was the age of wisdom. "
+	text := strings.Repeat(sentence, 13) + "The end."
+	runes := []rune(text)
+
+	raw := wordSafeChunks(runes, 1000)
+	if tail := raw[len(raw)-1]; len(raw) < 2 || tail[1]-tail[0] >= minPageRunes() {
+		t.Fatalf("fixture should leave a tiny trailing chunk, got %v", raw)
+	}
+	spans := pageSpans(runes, 1000)
+	if len(spans) != len(raw)-1 {
+		t.Fatalf("%d pages, want %d (tail merged)", len(spans), len(raw)-1)
+	}
+	last := string(runes[spans[len(spans)-1][0]:spans[len(spans)-1][1]])
+	if !strings.HasSuffix(last, "The end.") || len([]rune(last)) < 1000 {
+		t.Errorf("tail not folded into the previous page: %d runes ...%q", len([]rune(last)), last[max0(len(last)-40):])
+	}
+	if got := string(runes[spans[0][0]:spans[len(spans)-1][1]]); got != text {
+		t.Error("pages no longer cover the whole text")
+	}
+
+	// A full-size tail, or a book that fits on one page, is left alone.
+	long := []rune(strings.Repeat(sentence, 20))
+	if got, want := len(pageSpans(long, 1000)), len(wordSafeChunks(long, 1000)); got != want {
+		t.Errorf("long tail merged: %d pages, want %d", got, want)
+	}
+	if got := pageSpans([]rune("Short."), 1000); len(got) != 1 {
+		t.Errorf("single short page = %v", got)
+	}
+}
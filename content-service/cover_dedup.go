@@ -0,0 +1,88 @@
+package main
+
+// Cover dedup: the same cover image (a popular classic's Open Library cover,
+// a search result several users pick) is stored once, at a content-addressed
+// shared key, and every book whose cover has those bytes points its
+// cover_path at it. Like shared page audio, deleteStored never touches
+// shared/ keys; releaseCover deletes a shared cover only when the last book
+// using it goes away.
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+const sharedCoverPrefix = "shared/covers/"
+
+// sharedCoverKey is the book-independent R2 key for a cover image.
+func sharedCoverKey(hash, ext string) string {
+	return sharedCoverPrefix + hash + ext
+}
+
+// fileSHA256 is the hex sha256 of a local file's bytes.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// releaseCover drops a deleted book's cover, unless another book still
+// points at it (a shared cover, or one inherited by reuseCoverByContent).
+// Call it once the book's row is gone; it reports whether the cover was
+// removed.
+func releaseCover(book Book) bool {
+	if book.CoverPath == "" {
+		return false
+	}
+	var others int64
+	if err := db.Model(&Book{}).
+		Where("cover_path = ? AND id <> ?", book.CoverPath, book.ID).
+		Count(&others).Error; err != nil || others > 0 {
+		return false // in use (or unknown) — keep it
+	}
+	if !strings.HasPrefix(book.CoverPath, sharedCoverPrefix) {
+		deleteStored(book.CoverPath)
+		return true
+	}
+	if err := store.Delete(context.Background(), book.CoverPath); err != nil {
+		log.Printf("⚠️ could not delete shared cover %s: %v", book.CoverPath, err)
+		return false
+	}
+	log.Printf("🗑️ Deleted shared cover %s (last reference: book %d)", book.CoverPath, book.ID)
+	return true
+}
+
+// reuseCoverByContent gives a coverless book the cover of another book with
+// the same ContentHash — the same file, so the same book — instead of running
+// the cover search again. It reports whether a cover was copied.
+func reuseCoverByContent(book *Book) bool {
+	if book.CoverURL != "" || book.ContentHash == "" {
+		return false
+	}
+	var donor Book
+	if err := db.Select("id", "cover_path", "cover_url").
+		Where("content_hash = ? AND id <> ? AND cover_url <> ''", book.ContentHash, book.ID).
+		Order("updated_at DESC").First(&donor).Error; err != nil {
+		return false
+	}
+	if err := db.Model(&Book{}).Where("id = ? AND (cover_url IS NULL OR cover_url = '')", book.ID).
+		Updates(map[string]interface{}{"cover_path": donor.CoverPath, "cover_url": donor.CoverURL}).Error; err != nil {
+		log.Printf("⚠️ could not reuse cover of book %d for book %d: %v", donor.ID, book.ID, err)
+		return false
+	}
+	book.CoverPath, book.CoverURL = donor.CoverPath, donor.CoverURL
+	log.Printf("🖼️ Reusing cover of book %d for book %d (same content)", donor.ID, book.ID)
+	return true
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreCover_SharesIdenticalImage(t *testing.T) {
+	orig := store
+	defer func() { store = orig }()
+	ms := memStore{}
+	store = ms
+
+	img := bytes.Repeat([]byte{0xFF, 0xD8, 0x42}, 4000)
+	download := func(bookID string) string {
+		p := filepath.Join(t.TempDir(), bookID+"_cover.jpg")
+		if err := os.WriteFile(p, img, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	k1, u1, err := storeCover(download("1"), "1")
+	if err != nil {
+		t.Fatalf("book 1: %v", err)
+	}
+	second := download("2")
+	k2, u2, err := storeCover(second, "2")
+	if err != nil {
+		t.Fatalf("book 2: %v", err)
+	}
+	if k1 != k2 || u1 != u2 {
+		t.Fatalf("identical covers stored separately: %q vs %q", k1, k2)
+	}
+	if len(ms) != 1 || ms[k1] != int64(len(img)) {
+		t.Fatalf("store = %v, want one shared object", ms)
+	}
+	if _, err := os.Stat(second); !os.IsNotExist(err) {
+		t.Fatalf("reused download left behind: %v", err)
+	}
+
+	// A different image gets its own object.
+	p := filepath.Join(t.TempDir(), "3_cover.jpg")
+	os.WriteFile(p, append(img, 0x01), 0o644)
+	k3, _, err := storeCover(p, "3")
+	if err != nil || k3 == k1 {
+		t.Fatalf("distinct cover: key %q err %v", k3, err)
+	}
+}
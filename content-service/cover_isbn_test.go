@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeISBN(t *testing.T) {
+	cases := map[string]string{
+		"978-0-14-143951-8": "9780141439518",
+		"0 14 143951 3":     "0141439513",
+		"080442957x":        "080442957X",
+		"9780141439519":     "", // bad check digit
+		"014143951":         "", // too short
+		"97801414395X8":     "", // X only valid as an ISBN-10 check digit
+		"isbn9780141439518": "",
+		"":                  "",
+	}
+	for in, want := range cases {
+		if got := normalizeISBN(in); got != want {
+			t.Errorf("normalizeISBN(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// openLibraryFixture serves a cover for one known ISBN and 404s everything
+// else (Open Library's behavior with ?default=false).
+func openLibraryFixture(t *testing.T, isbn string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/b/isbn/"+isbn+"-L.jpg" && r.URL.Query().Get("default") == "false" {
+			w.Header().Set("Content-Type", "image/jpeg")
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	orig := openLibraryCoversURL
+	openLibraryCoversURL = srv.URL
+	t.Cleanup(func() { openLibraryCoversURL = orig })
+	return srv
+}
+
+func TestTryOpenLibraryISBNCover(t *testing.T) {
+	srv := openLibraryFixture(t, "9780141439518")
+
+	if got, want := tryOpenLibraryISBNCover("978-0-14-143951-8"), srv.URL+"/b/isbn/9780141439518-L.jpg"; got != want {
+		t.Fatalf("cover = %q, want %q", got, want)
+	}
+	if got := tryOpenLibraryISBNCover("0141439513"); got != "" {
+		t.Fatalf("ISBN without a cover returned %q", got)
+	}
+	if got := tryOpenLibraryISBNCover("not-an-isbn"); got != "" {
+		t.Fatalf("invalid ISBN returned %q", got)
+	}
+}
+
+func TestFetchAndSaveBookCover_TriesISBNFirst(t *testing.T) {
+	srv := openLibraryFixture(t, "9780141439518")
+	t.Setenv("OPENAI_API_KEY", "")
+
+	origStore, origFetch := store, fetchRemoteCover
+	defer func() { store, fetchRemoteCover = origStore, origFetch }()
+	store = memStore{}
+	var fetched []string
+	fetchRemoteCover = func(imageURL, bookID string) (string, error) {
+		fetched = append(fetched, imageURL)
+		p := filepath.Join(t.TempDir(), bookID+".jpg")
+		return p, os.WriteFile(p, []byte("jpeg"), 0o644)
+	}
+
+	key, _, err := fetchAndSaveBookCover("Pride and Prejudice", "Jane Austen", "9780141439518", "7")
+	if err != nil {
+		t.Fatalf("fetchAndSaveBookCover: %v", err)
+	}
+	if want := srv.URL + "/b/isbn/9780141439518-L.jpg"; len(fetched) != 1 || fetched[0] != want {
+		t.Fatalf("fetched %v, want only the ISBN cover %q", fetched, want)
+	}
+	if !strings.HasPrefix(key, sharedCoverPrefix) {
+		t.Fatalf("key = %q, want a stored cover object", key)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// coverCacheDir holds proxied copies of third-party cover images (public, like
+// the rest of ./uploads/covers). A var so tests can point it at a temp dir.
+var coverCacheDir = "./uploads/covers/cache"
+
+// fetchRemoteCover downloads a cover (SSRF-guarded, hotlink-tolerant) to a
+// local file. A var so tests can stub the network.
+var fetchRemoteCover = downloadAndSaveImage
+
+// coverProxyHandler (GET /covers/proxy?book_id=&h=) serves a book's cover
+// without hotlinking: covers we store are served directly; a remote cover_url
+// (e.g. an Amazon/Goodreads CDN link picked from search) is fetched once,
+// cached on disk, and served from the cache afterwards. Public like /covers —
+// the iOS app loads covers without an auth header — so h, the hash of the
+// book's cover_url, is required: book ids are sequential, and without it
+// anyone could walk them and read every book's cover. Clients get the full
+// URL as cover_proxy_url on the book.
+func coverProxyHandler(c *gin.Context) {
+	bookID, err := strconv.ParseUint(c.Query("book_id"), 10, 64)
+	if err != nil || bookID == 0 || c.Query("h") == "" {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "book_id and h are required")
+		return
+	}
+	var book Book
+	if err := db.Select("id", "cover_path", "cover_url").First(&book, bookID).Error; err != nil ||
+		subtle.ConstantTimeCompare([]byte(c.Query("h")), []byte(coverURLHash(book.CoverURL))) != 1 {
+		// Same answer for a wrong hash as for a missing book.
+		respondError(c, http.StatusNotFound, errCodeBookNotFound, "Book not found")
+		return
+	}
+	serveProxiedCover(c, book)
+}
+
+// coverProxyURL is the /covers/proxy link for a book whose cover is a remote
+// URL, or "" when there's nothing to proxy.
+func coverProxyURL(book Book) string {
+	if book.CoverPath != "" || !isRemoteCoverURL(book.CoverURL) {
+		return ""
+	}
+	return fmt.Sprintf("%s/covers/proxy?book_id=%d&h=%s", getEnv("STREAM_HOST", "https://narrafied.com"), book.ID, coverURLHash(book.CoverURL))
+}
+
+// bookWithCoverProxy is a Book as the create/update endpoints return it:
+// its own fields plus cover_proxy_url, so a client holding just one book
+// still gets a working proxy link.
+type bookWithCoverProxy struct {
+	Book
+	CoverProxyURL string `json:"cover_proxy_url,omitempty"`
+}
+
+func withCoverProxy(book Book) bookWithCoverProxy {
+	return bookWithCoverProxy{Book: book, CoverProxyURL: coverProxyURL(book)}
+}
+
+// coverURLHash is the short hash of a cover_url that keys both the proxy link
+// and its cache entry.
+func coverURLHash(coverURL string) string {
+	sum := sha256.Sum256([]byte(coverURL))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// serveProxiedCover does the cache-or-fetch work for coverProxyHandler.
+func serveProxiedCover(c *gin.Context, book Book) {
+	if book.CoverPath != "" {
+		serveMedia(c, book.CoverPath)
+		return
+	}
+	if !isRemoteCoverURL(book.CoverURL) {
+		respondError(c, http.StatusNotFound, errCodeNotFound, "No cover for this book")
+		return
+	}
+
+	cached := coverCachePath(book.ID, book.CoverURL)
+	if _, err := os.Stat(cached); err != nil {
+		local, ferr := fetchRemoteCover(book.CoverURL, strconv.FormatUint(uint64(book.ID), 10))
+		if ferr != nil {
+			log.Printf("⚠️ Cover proxy fetch failed for book %d: %v", book.ID, ferr)
+			respondErrorWith(c, http.StatusBadGateway, errCodeCoverUnavailable, "Cover unavailable", gin.H{"error": "cover_unavailable"})
+			return
+		}
+		err := os.MkdirAll(filepath.Dir(cached), os.ModePerm)
+		if err == nil {
+			err = os.Rename(local, cached)
+		}
+		if err != nil {
+			// Couldn't cache (e.g. cross-device rename) — still serve this copy.
+			log.Printf("⚠️ Cover proxy could not cache book %d: %v", book.ID, err)
+			defer os.Remove(local)
+			cached = local
+		} else {
+			log.Printf("🖼️ Cover proxy cached book %d: %s", book.ID, cached)
+		}
+	}
+	c.Header("Content-Type", contentTypeForExt(cached))
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.File(cached)
+}
+
+// isRemoteCoverURL reports whether a cover_url points off-site (anything but
+// our own STREAM_HOST / R2 public base / the legacy placeholder).
+func isRemoteCoverURL(u string) bool {
+	if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+		return false
+	}
+	if u == "http://placeholder.com/default.jpg" {
+		return false
+	}
+	for _, own := range []string{getEnv("STREAM_HOST", "https://narrafied.com"), os.Getenv("R2_PUBLIC_BASE")} {
+		if own != "" && strings.HasPrefix(u, strings.TrimRight(own, "/")+"/") {
+			return false
+		}
+	}
+	return true
+}
+
+// coverCachePath keys the cache on book + URL so choosing a new cover_url
+// fetches afresh instead of serving the stale image.
+func coverCachePath(bookID uint, coverURL string) string {
+	ext := strings.ToLower(filepath.Ext(strings.SplitN(coverURL, "?", 2)[0]))
+	switch ext {
+	case ".jpg", ".jpeg", ".png", ".webp":
+	default:
+		ext = ".jpg"
+	}
+	return filepath.Join(coverCacheDir, fmt.Sprintf("%d_%s%s", bookID, coverURLHash(coverURL), ext))
+}
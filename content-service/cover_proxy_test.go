@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestServeProxiedCover_FetchesOnceThenServesCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	origDir, origFetch := coverCacheDir, fetchRemoteCover
+	defer func() { coverCacheDir, fetchRemoteCover = origDir, origFetch }()
+	coverCacheDir = filepath.Join(dir, "cache")
+
+	fetches := 0
+	fetchRemoteCover = func(imageURL, bookID string) (string, error) {
+		fetches++
+		p := filepath.Join(dir, "download_"+bookID+".jpg")
+		return p, os.WriteFile(p, []byte("JPEGDATA"), 0644)
+	}
+
+	book := Book{ID: 42, CoverURL: "https://m.media-amazon.com/images/I/81abc.jpg?x=1"}
+	for i := 1; i <= 2; i++ {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/covers/proxy?book_id=42", nil)
+		serveProxiedCover(c, book)
+		if w.Code != 200 || w.Body.String() != "JPEGDATA" {
+			t.Fatalf("request %d: got %d %q", i, w.Code, w.Body.String())
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "image/jpeg" {
+			t.Errorf("request %d: Content-Type %q", i, ct)
+		}
+		if fetches != 1 {
+			t.Fatalf("request %d: %d upstream fetches, want exactly 1", i, fetches)
+		}
+	}
+	if _, err := os.Stat(coverCachePath(42, book.CoverURL)); err != nil {
+		t.Errorf("cached copy missing: %v", err)
+	}
+}
+
+func TestIsRemoteCoverURL(t *testing.T) {
+	t.Setenv("STREAM_HOST", "https://narrafied.com")
+	t.Setenv("R2_PUBLIC_BASE", "https://media.narrafied.com")
+	cases := map[string]bool{
+		"https://images.gr-assets.com/books/1.jpg":   true,
+		"https://narrafied.com/covers/7_123.jpg":     false,
+		"https://media.narrafied.com/covers/7/a.jpg": false,
+		"http://placeholder.com/default.jpg":         false,
+		"":                                           false,
+	}
+	for u, want := range cases {
+		if got := isRemoteCoverURL(u); got != want {
+			t.Errorf("isRemoteCoverURL(%q) = %v, want %v", u, got, want)
+		}
+	}
+}
+
+// Book ids are sequential, so the proxy only answers with the hash of the
+// book's cover URL, as handed out in cover_proxy_url.
+func TestCoverProxyHandler_RequiresCoverHash(t *testing.T) {
+	withTestDB(t)
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	origDir, origFetch := coverCacheDir, fetchRemoteCover
+	defer func() { coverCacheDir, fetchRemoteCover = origDir, origFetch }()
+	coverCacheDir = filepath.Join(dir, "cache")
+	fetchRemoteCover = func(imageURL, bookID string) (string, error) {
+		p := filepath.Join(dir, "download_"+bookID+".jpg")
+		return p, os.WriteFile(p, []byte("JPEGDATA"), 0644)
+	}
+	t.Setenv("STREAM_HOST", "https://narrafied.com")
+
+	book := Book{Title: "Covered", Category: "Fiction", UserID: 1, CoverURL: "https://images.gr-assets.com/books/9.jpg"}
+	db.Create(&book)
+	proxyURL := coverProxyURL(book)
+	if !strings.HasPrefix(proxyURL, "https://narrafied.com/covers/proxy?") {
+		t.Fatalf("cover_proxy_url = %q", proxyURL)
+	}
+
+	get := func(target string) int {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", target, nil)
+		coverProxyHandler(c)
+		return w.Code
+	}
+	if code := get(strings.TrimPrefix(proxyURL, "https://narrafied.com")); code != http.StatusOK {
+		t.Errorf("cover_proxy_url = %d, want 200", code)
+	}
+	if code := get(fmt.Sprintf("/covers/proxy?book_id=%d", book.ID)); code != http.StatusBadRequest {
+		t.Errorf("no hash = %d, want 400", code)
+	}
+	if code := get(fmt.Sprintf("/covers/proxy?book_id=%d&h=0000000000000000", book.ID)); code != http.StatusNotFound {
+		t.Errorf("wrong hash = %d, want 404", code)
+	}
+	if got := coverProxyURL(Book{ID: 3, CoverPath: "covers/3/a.jpg", CoverURL: book.CoverURL}); got != "" {
+		t.Errorf("stored cover proxied: %q", got)
+	}
+}
+
+// Single-book responses carry the proxy link too, not just the list.
+func TestSingleBookResponses_CarryCoverProxyURL(t *testing.T) {
+	t.Setenv("STREAM_HOST", "https://narrafied.com")
+	book := Book{ID: 7, Title: "Covered", CoverURL: "https://images.gr-assets.com/books/9.jpg"}
+	want := coverProxyURL(book)
+
+	raw, _ := json.Marshal(withCoverProxy(book))
+	var created map[string]interface{}
+	json.Unmarshal(raw, &created)
+	if created["cover_proxy_url"] != want || created["Title"] != "Covered" {
+		t.Errorf("withCoverProxy = %s", raw)
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("book", book)
+	getSingleBookHandler(c)
+	var got struct {
+		Book BookResponse `json:"book"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if got.Book.CoverProxyURL != want {
+		t.Errorf("get book cover_proxy_url = %q, want %q", got.Book.CoverProxyURL, want)
+	}
+}
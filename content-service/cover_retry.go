@@ -0,0 +1,67 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// Background cover retries. A book's cover is looked up once, when it is
+// created; if that fetch fails the book stays coverless. This sweep re-queues
+// the lookup for books still without a cover once they are older than a grace
+// period (so the first fetch has had time to finish), counting each retry on
+// the book and giving up after COVER_RETRY_MAX_ATTEMPTS.
+//
+//	COVER_RETRY_INTERVAL_MINUTES  how often the sweep runs (default 60)
+//	COVER_RETRY_GRACE_MINUTES     minimum book age before a retry (default 30)
+//	COVER_RETRY_MAX_ATTEMPTS      retries per book (default 5; 0 disables)
+
+// coverRetryBatch caps how many books one sweep re-queues.
+const coverRetryBatch = 100
+
+func coverRetryLoop() {
+	interval := time.Duration(envInt("COVER_RETRY_INTERVAL_MINUTES", 60)) * time.Minute
+	grace := time.Duration(envInt("COVER_RETRY_GRACE_MINUTES", 30)) * time.Minute
+	maxAttempts := envInt("COVER_RETRY_MAX_ATTEMPTS", 5)
+	if interval <= 0 || maxAttempts <= 0 {
+		log.Printf("🖼️ cover retries disabled")
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		retryMissingCovers(grace, maxAttempts)
+	}
+}
+
+// retryMissingCovers re-queues the cover fetch for coverless books older than
+// grace that have had fewer than maxAttempts retries, and returns how many it
+// queued. The attempt is counted before the enqueue so a failing queue can't
+// make the sweep retry a book forever.
+func retryMissingCovers(grace time.Duration, maxAttempts int) int {
+	var books []Book
+	if err := db.Select("id", "title", "author", "isbn", "cover_attempts").
+		Where("(cover_url IS NULL OR cover_url = '') AND created_at < ? AND cover_attempts < ?", time.Now().Add(-grace), maxAttempts).
+		Order("cover_attempts ASC, id ASC").Limit(coverRetryBatch).
+		Find(&books).Error; err != nil {
+		log.Printf("⚠️ cover retry sweep: %v", err)
+		return 0
+	}
+	queued := 0
+	for _, b := range books {
+		// Claim the attempt; a concurrent sweep that already counted it wins.
+		res := db.Model(&Book{}).Where("id = ? AND cover_attempts = ?", b.ID, b.CoverAttempts).
+			UpdateColumn("cover_attempts", b.CoverAttempts+1)
+		if res.Error != nil || res.RowsAffected == 0 {
+			continue
+		}
+		if err := enqueueFetchCover(b.ID, b.Title, b.Author, b.ISBN); err != nil {
+			log.Printf("⚠️ cover retry for book %d: %v", b.ID, err)
+			continue
+		}
+		queued++
+	}
+	if queued > 0 {
+		log.Printf("🖼️ re-queued cover lookup for %d book(s)", queued)
+	}
+	return queued
+}
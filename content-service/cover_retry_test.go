@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// A coverless book is retried once per sweep until the cap, then left alone;
+// new books and books with a cover are never touched.
+func TestRetryMissingCovers_StopsAtCap(t *testing.T) {
+	withTestDB(t)
+	var fetched []uint
+	orig := enqueueFetchCover
+	enqueueFetchCover = func(bookID uint, title, author, isbn string) error {
+		fetched = append(fetched, bookID) // the lookup keeps failing: no cover set
+		return nil
+	}
+	t.Cleanup(func() { enqueueFetchCover = orig })
+
+	old := time.Now().Add(-2 * time.Hour)
+	coverless := Book{Title: "Obscure", Category: "Fiction", UserID: 1, CreatedAt: old}
+	covered := Book{Title: "Famous", Category: "Fiction", UserID: 1, CoverURL: "https://cdn/cover.jpg", CreatedAt: old}
+	fresh := Book{Title: "Just added", Category: "Fiction", UserID: 1}
+	for _, b := range []*Book{&coverless, &covered, &fresh} {
+		db.Create(b)
+	}
+
+	const maxAttempts = 3
+	for sweep := 0; sweep < maxAttempts+2; sweep++ {
+		retryMissingCovers(30*time.Minute, maxAttempts)
+	}
+	if len(fetched) != maxAttempts {
+		t.Fatalf("fetches = %v, want %d for book %d only", fetched, maxAttempts, coverless.ID)
+	}
+	for _, id := range fetched {
+		if id != coverless.ID {
+			t.Fatalf("fetched cover for book %d", id)
+		}
+	}
+	db.First(&coverless, coverless.ID)
+	if coverless.CoverAttempts != maxAttempts {
+		t.Fatalf("cover_attempts = %d, want %d", coverless.CoverAttempts, maxAttempts)
+	}
+}
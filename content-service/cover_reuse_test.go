@@ -0,0 +1,120 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stubCoverFetch counts cover lookups instead of queueing them.
+func stubCoverFetch(t *testing.T) *int {
+	t.Helper()
+	calls := 0
+	orig := enqueueFetchCover
+	enqueueFetchCover = func(uint, string, string, string) error { calls++; return nil }
+	t.Cleanup(func() { enqueueFetchCover = orig })
+	return &calls
+}
+
+func TestProcessBookConversion_ReusesAudioAndCover(t *testing.T) {
+	withTestDB(t)
+	fetches := stubCoverFetch(t)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "book.txt")
+	audio := filepath.Join(dir, "donor.mp3")
+	os.WriteFile(src, []byte("Call me Ishmael."), 0644)
+	os.WriteFile(audio, []byte("ID3"), 0644)
+
+	donor := Book{Title: "Moby-Dick", Category: "Fiction", UserID: 1, ContentHash: "h1", AudioPath: audio,
+		CoverPath: "covers/1/cover.jpg", CoverURL: "https://cdn/covers/1/cover.jpg"}
+	book := Book{Title: "Moby Dick", Category: "Fiction", UserID: 2, ContentHash: "h1", FilePath: src}
+	db.Create(&donor)
+	db.Create(&book)
+
+	processBookConversion(book)
+	db.First(&book, book.ID)
+	if book.AudioPath != audio || book.Status != "TTS reused" {
+		t.Fatalf("audio not reused: %q %q", book.AudioPath, book.Status)
+	}
+	if book.CoverPath != donor.CoverPath || book.CoverURL != donor.CoverURL {
+		t.Fatalf("cover not reused: %q %q", book.CoverPath, book.CoverURL)
+	}
+	if *fetches != 0 {
+		t.Fatalf("%d cover fetches for a duplicate", *fetches)
+	}
+}
+
+func TestUploadBookFile_IdenticalFileReusesCover(t *testing.T) {
+	withTestDB(t)
+	t.Chdir(t.TempDir())
+	ms := memStore{}
+	origStore := store
+	store = ms
+	t.Cleanup(func() { store = origStore })
+	fetches := stubCoverFetch(t)
+
+	const text = "It is a truth universally acknowledged."
+	os.WriteFile("same.txt", []byte(text), 0644)
+	hash, err := computeFileHash("same.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	donor := Book{Title: "Pride and Prejudice", Category: "Fiction", UserID: 1, ContentHash: hash,
+		CoverPath: "covers/1/cover.jpg", CoverURL: "https://cdn/covers/1/cover.jpg"}
+	db.Create(&donor)
+	ms[donor.CoverPath] = 100
+	book := Book{Title: "P&P", Category: "Fiction", UserID: 2, CreatedAt: time.Now().Add(-time.Hour)}
+	db.Create(&book)
+
+	if w := uploadRequest(t, 2, book.ID, "pp.txt", text, false); w.Code != 200 {
+		t.Fatalf("upload = %d %s", w.Code, w.Body)
+	}
+	db.First(&book, book.ID)
+	if book.CoverURL != donor.CoverURL || book.CoverPath != donor.CoverPath {
+		t.Fatalf("cover not reused: %q %q", book.CoverPath, book.CoverURL)
+	}
+	if n := retryMissingCovers(0, 5); n != 0 || *fetches != 0 {
+		t.Fatalf("cover retried for a book with a reused cover (%d queued, %d fetches)", n, *fetches)
+	}
+
+	// The donor going away must not take the inherited cover with it.
+	db.Delete(&Book{}, donor.ID)
+	releaseCover(donor)
+	if _, ok := ms[donor.CoverPath]; !ok {
+		t.Fatal("cover still used by the duplicate was deleted")
+	}
+}
+
+// The admin wipe of one user's files must leave a cover another user's book
+// inherited, and drop the covers nobody else uses.
+func TestDeleteUserFiles_KeepsCoverSharedWithAnotherUser(t *testing.T) {
+	withTestDB(t)
+	ms := memStore{"covers/1/cover.jpg": 100, "covers/3/own.jpg": 50}
+	origStore := store
+	store = ms
+	t.Cleanup(func() { store = origStore })
+
+	db.Create(&Book{Title: "Shared", Category: "Fiction", UserID: 1, CoverPath: "covers/1/cover.jpg"})
+	db.Create(&Book{Title: "Own", Category: "Fiction", UserID: 1, CoverPath: "covers/3/own.jpg"})
+	db.Create(&Book{Title: "Inherited", Category: "Fiction", UserID: 2, CoverPath: "covers/1/cover.jpg"})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.DELETE("/admin/users/:user_id/files", deleteUserFilesContentHandler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/users/1/files", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("delete = %d %s", w.Code, w.Body)
+	}
+	if _, ok := ms["covers/1/cover.jpg"]; !ok {
+		t.Error("cover still used by another user's book was deleted")
+	}
+	if _, ok := ms["covers/3/own.jpg"]; ok {
+		t.Error("unshared cover kept")
+	}
+}
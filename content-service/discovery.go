@@ -141,7 +141,7 @@ func DiscoverByStateHandler(c *gin.Context) {
 
 	var callerState string
 	if err := db.Table("users").Select("state").Where("id = ?", userID).Scan(&callerState).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not load profile"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "could not load profile")
 		return
 	}
 	callerState = strings.TrimSpace(callerState)
@@ -157,7 +157,7 @@ func DiscoverByStateHandler(c *gin.Context) {
 		Where("LOWER(TRIM(state)) = LOWER(?) AND is_public = true AND id <> ?", callerState, userID).
 		Limit(discoverPeopleLimit).
 		Scan(&users).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "discovery query failed"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "discovery query failed")
 		return
 	}
 
@@ -180,7 +180,7 @@ func DiscoverContactsHandler(c *gin.Context) {
 
 	var req ContactHashRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "phone_hashes required"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "phone_hashes required")
 		return
 	}
 	if len(req.PhoneHashes) > maxContactHashes {
@@ -201,7 +201,7 @@ func DiscoverContactsHandler(c *gin.Context) {
 		Select("id, username, state, phone_number").
 		Where("phone_number <> '' AND phone_verified = true AND is_public = true AND id <> ?", userID).
 		Scan(&candidates).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "discovery query failed"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "discovery query failed")
 		return
 	}
 
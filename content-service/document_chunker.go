@@ -4,6 +4,7 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
@@ -151,27 +152,64 @@ func wordSafeChunks(runes []rune, chunkSize int) [][2]int {
 	return spans
 }
 
+// minPageRunes is the shortest page the chunker emits on its own
+// (CHUNK_MIN_CHARS, default 200). A document that ends a few words past a
+// page boundary would otherwise leave a near-empty last page that is still
+// synthesized, and charged, as a full TTS request.
+func minPageRunes() int { return envInt("CHUNK_MIN_CHARS", 200) }
+
+// pageSpans is wordSafeChunks for book pages: a last span shorter than
+// minPageRunes (ignoring surrounding whitespace) is folded into the page
+// before it, which may then run up to minPageRunes past chunkSize.
+func pageSpans(runes []rune, chunkSize int) [][2]int {
+	spans := wordSafeChunks(runes, chunkSize)
+	n := len(spans)
+	if n < 2 {
+		return spans
+	}
+	last := spans[n-1]
+	if len([]rune(strings.TrimSpace(string(runes[last[0]:last[1]])))) >= minPageRunes() {
+		return spans
+	}
+	spans[n-2][1] = last[1]
+	return spans[:n-1]
+}
+
 // calibreTimeout bounds ebook-convert so a runaway conversion on a huge/complex
 // file is killed rather than orphaned past the asynq parse timeout (15m).
 const calibreTimeout = 12 * time.Minute
 
+// ebookConvertBin is Calibre's converter; swappable in tests.
+var ebookConvertBin = "ebook-convert"
+
 // runEbookConvert runs Calibre with its own timeout context so the subprocess
-// is terminated (not left running) if it hangs.
+// is terminated (not left running) if it hangs. A file Calibre refuses
+// because of DRM comes back as errDRMProtected.
 func runEbookConvert(src, dst string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), calibreTimeout)
 	defer cancel()
-	cmd := exec.CommandContext(ctx, "ebook-convert", src, dst, "--txt-output-encoding=utf-8")
-	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, ebookConvertBin, src, dst, "--txt-output-encoding=utf-8")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
 			return fmt.Errorf("ebook-convert timed out after %s", calibreTimeout)
 		}
+		if isCalibreDRMError(stderr.String()) || isCalibreDRMError(stdout.String()) {
+			return errDRMProtected
+		}
 		return fmt.Errorf("ebook-convert failed: %w. Details: %s", err, stderr.String())
 	}
 	return nil
 }
 
+// isCalibreDRMError matches Calibre's refusal of an encrypted book
+// ("calibre.ebooks.DRMError: ..." / "This book is locked by DRM").
+func isCalibreDRMError(output string) bool {
+	return strings.Contains(output, "DRMError") || strings.Contains(output, "locked by DRM")
+}
+
 // ChunkDocument extracts text and creates chunks for a book
 // For large books (4K+ pages), this uses batch inserts and runs asynchronously
 func ChunkDocument(bookID uint, filePath string) (int, error) {
@@ -190,12 +228,8 @@ func ChunkDocument(bookID uint, filePath string) (int, error) {
 		return 0, fmt.Errorf("no text content extracted from file")
 	}
 
-	// Update Book.Content with truncated text (for preview/search)
-	contentForBook := text
-	if len(contentForBook) > 100000 {
-		contentForBook = contentForBook[:100000] + "...[truncated]"
-	}
-	if err := db.Model(&Book{}).Where("id = ?", bookID).Update("content", contentForBook).Error; err != nil {
+	// Update Book.Content with truncated text (preview; see storeBookContent)
+	if err := storeBookContent(bookID, text); err != nil {
 		log.Printf("⚠️ Failed to update book content: %v", err)
 	}
 
@@ -210,7 +244,7 @@ func ChunkDocument(bookID uint, filePath string) (int, error) {
 	batchSize := 100
 	count := 0
 
-	for _, span := range wordSafeChunks(runes, chunkSize) {
+	for _, span := range pageSpans(runes, chunkSize) {
 		chunk := BookChunk{
 			BookID:    bookID,
 			Index:     count,
@@ -238,8 +272,9 @@ func ChunkDocument(bookID uint, filePath string) (int, error) {
 }
 
 // ChunkDocumentAsync processes large books in the background
-// Returns immediately with estimated chunk count, actual processing happens async
-func ChunkDocumentAsync(bookID uint, filePath string) (estimatedChunks int, err error) {
+// Returns immediately with estimated chunk count, actual processing happens async.
+// then, if not nil, runs once the pages are saved.
+func ChunkDocumentAsync(bookID uint, filePath string, then func()) (estimatedChunks int, err error) {
 	log.Printf("📖 ChunkDocumentAsync called for book %d, file: %s", bookID, filePath)
 
 	// Quick file size check to estimate chunks
@@ -263,16 +298,15 @@ func ChunkDocumentAsync(bookID uint, filePath string) (estimatedChunks int, err
 		actualChunks, err := ChunkDocumentBatch(bookID, filePath)
 		if err != nil {
 			log.Printf("❌ Async chunking failed for book %d: %v", bookID, err)
-			status := "chunking_failed"
-			if errors.Is(err, errNoTextExtracted) {
-				status = "no_text_extracted" // likely a scanned/image PDF
-			}
-			db.Model(&Book{}).Where("id = ?", bookID).Update("status", status)
+			db.Model(&Book{}).Where("id = ?", bookID).Update("status", chunkingFailedStatus(err))
 			return
 		}
 
 		log.Printf("✅ Async chunking complete for book %d: %d chunks", bookID, actualChunks)
 		db.Model(&Book{}).Where("id = ?", bookID).Update("status", "pending")
+		if then != nil {
+			then()
+		}
 	}()
 
 	return estimatedChunks, nil
@@ -284,8 +318,41 @@ func ChunkDocumentAsync(bookID uint, filePath string) (estimatedChunks int, err
 // can show a tailored message instead of a generic failure.
 var errNoTextExtracted = errors.New("no text content extracted from file")
 
-// ChunkDocumentBatch uses batch inserts for better performance on large books
+// errDRMProtected is returned for an ebook whose text is encrypted (Kindle
+// DRM). Retrying never helps, so callers fail the book as "drm_protected"
+// and tell the user rather than reporting a server error.
+var errDRMProtected = errors.New("this file is DRM-protected and cannot be processed")
+
+// chunkingFailedStatus is the book status for a failed parse: the files we
+// can never read get their own status so the client can explain why.
+func chunkingFailedStatus(err error) string {
+	switch {
+	case errors.Is(err, errDRMProtected):
+		return "drm_protected"
+	case errors.Is(err, errNoTextExtracted):
+		return "no_text_extracted" // likely a scanned/image PDF
+	default:
+		return "chunking_failed"
+	}
+}
+
+// ChunkDocumentBatch uses batch inserts for better performance on large books.
+// Book.PageCount is cleared while pages are being inserted and set to the
+// final count once they all are (listBookPagesHandler reads it).
 func ChunkDocumentBatch(bookID uint, filePath string) (int, error) {
+	recordProcessingEvent(bookID, -1, stageChunking, eventStarted, "")
+	db.Model(&Book{}).Where("id = ?", bookID).Update("page_count", 0)
+	count, err := chunkDocumentBatch(bookID, filePath)
+	if err != nil {
+		recordProcessingEvent(bookID, -1, stageChunking, eventFailed, err.Error())
+	} else {
+		db.Model(&Book{}).Where("id = ?", bookID).Update("page_count", count)
+		recordProcessingEvent(bookID, -1, stageChunking, eventCompleted, fmt.Sprintf("%d pages", count))
+	}
+	return count, err
+}
+
+func chunkDocumentBatch(bookID uint, filePath string) (int, error) {
 	log.Printf("📖 ChunkDocumentBatch called for book %d, file: %s", bookID, filePath)
 
 	text, err := ExtractTextByType(filePath)
@@ -299,11 +366,7 @@ func ChunkDocumentBatch(bookID uint, filePath string) (int, error) {
 	}
 
 	// Update Book.Content
-	contentForBook := text
-	if len(contentForBook) > 100000 {
-		contentForBook = contentForBook[:100000] + "...[truncated]"
-	}
-	db.Model(&Book{}).Where("id = ?", bookID).Update("content", contentForBook)
+	storeBookContent(bookID, text)
 
 	runes := []rune(text)
 	chunkSize := 1000
@@ -312,7 +375,7 @@ func ChunkDocumentBatch(bookID uint, filePath string) (int, error) {
 	var chunks []BookChunk
 	count := 0
 
-	for _, span := range wordSafeChunks(runes, chunkSize) {
+	for _, span := range pageSpans(runes, chunkSize) {
 		chunks = append(chunks, BookChunk{
 			BookID:    bookID,
 			Index:     count,
@@ -370,10 +433,18 @@ func ExtractTextByType(path string) (string, error) {
 		text, err = ExtractTextFromEPUB(path)
 	case strings.HasSuffix(lowerPath, ".azw") || strings.HasSuffix(lowerPath, ".mobi") || strings.HasSuffix(lowerPath, ".azw3"):
 		text, err = ExtractTextFromMOBI(path)
+	case strings.HasSuffix(lowerPath, ".html") || strings.HasSuffix(lowerPath, ".htm"):
+		text, err = ExtractTextFromHTML(path)
+	case strings.HasSuffix(lowerPath, ".md") || strings.HasSuffix(lowerPath, ".markdown"):
+		text, err = ExtractTextFromMarkdown(path)
+	case strings.HasSuffix(lowerPath, ".docx"):
+		text, err = ExtractTextFromDOCX(path)
+	case strings.HasSuffix(lowerPath, ".doc"):
+		text, err = ExtractTextFromDOC(path)
 	case strings.HasSuffix(lowerPath, ".kfx"):
 		return "", errors.New("KFX format is not supported. Please convert to EPUB, PDF, MOBI, or AZW3 format first")
 	default:
-		return "", errors.New("unsupported file type. Supported formats: PDF, TXT, EPUB, MOBI, AZW, AZW3")
+		return "", errors.New("unsupported file type. Supported formats: PDF, TXT, EPUB, MOBI, AZW, AZW3, DOCX, DOC, HTML, MD")
 	}
 	if err != nil {
 		return "", err
@@ -427,22 +498,110 @@ func ExtractTextFromTXT(path string) (string, error) {
 }
 
 // ExtractTextFromPDF extracts text from a PDF. It first tries rsc.io/pdf
-// (fast, in-process) but that library is fragile and fails/panics on many
-// real-world PDFs, so on any error or empty result it falls back to Calibre's
-// ebook-convert (robust). A truly empty result from both (e.g. a scanned/
-// image-only PDF) returns errNoTextExtracted so the client shows the
-// "scanned PDF" message rather than a generic failure.
+// (fast, in-process) but that library is fragile: it fails/panics on many
+// real-world PDFs and returns empty or garbled text for compressed/complex
+// content streams. When its result is unusable (see pdfTextUnusable) poppler's
+// pdftotext is tried next, then Calibre's ebook-convert, then (with
+// ENABLE_OCR) OCR of the rasterized pages. A truly empty result from all of
+// them (e.g. a scanned/image-only PDF without OCR) returns errNoTextExtracted
+// so the client shows the "scanned PDF" message rather than a generic failure.
 func ExtractTextFromPDF(path string) (string, error) {
-	text, err := extractPDFViaRSC(path)
-	if err == nil && strings.TrimSpace(text) != "" {
+	text, err := extractPDFNative(path)
+	var size int64
+	if st, serr := os.Stat(path); serr == nil {
+		size = st.Size()
+	}
+	if err == nil && !pdfTextUnusable(text, size) {
 		return text, nil
 	}
 	if err != nil {
-		log.Printf("ℹ️ rsc.io/pdf failed for %s (%v); falling back to Calibre", path, err)
+		log.Printf("ℹ️ rsc.io/pdf failed for %s (%v); trying pdftotext", path, err)
+		text = ""
 	} else {
-		log.Printf("ℹ️ rsc.io/pdf got no text from %s; falling back to Calibre", path)
+		log.Printf("ℹ️ rsc.io/pdf got %d chars from %d-byte %s; trying pdftotext", len(text), size, path)
+	}
+
+	alt, perr := extractPDFViaPdftotext(path)
+	if perr != nil {
+		log.Printf("ℹ️ pdftotext unavailable for %s: %v", path, perr)
+	} else if len(strings.TrimSpace(alt)) > len(strings.TrimSpace(text)) {
+		return alt, nil
+	}
+	if strings.TrimSpace(text) != "" {
+		return text, nil
+	}
+	log.Printf("ℹ️ No PDF text from rsc.io/pdf or pdftotext for %s; falling back to Calibre", path)
+	text, err = extractPDFViaCalibre(path)
+	if err == nil || !ocrEnabled() {
+		return text, err
 	}
-	return extractPDFViaCalibre(path)
+	log.Printf("ℹ️ No PDF text layer in %s (%v); trying OCR", path, err)
+	ocr, oerr := extractPDFViaOCR(path)
+	if oerr != nil {
+		log.Printf("⚠️ OCR failed for %s: %v", path, oerr)
+		return "", err
+	}
+	if strings.TrimSpace(ocr) == "" {
+		return "", errNoTextExtracted
+	}
+	return ocr, nil
+}
+
+// minPDFTextPerKB is the least text (bytes per KB of PDF) rsc.io/pdf must
+// return before its result is trusted. Text PDFs run far above it; image-heavy
+// ones with a real text layer still clear it comfortably.
+const minPDFTextPerKB = 8
+
+// pdfTextUnusable reports whether native PDF text is too sparse for a file of
+// size bytes, or mostly non-alphanumeric (undecoded font glyphs come out as
+// symbol soup).
+func pdfTextUnusable(text string, size int64) bool {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" || int64(len(trimmed)) < size/1024*minPDFTextPerKB {
+		return true
+	}
+	var visible, alnum int
+	for _, r := range trimmed {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		visible++
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			alnum++
+		}
+	}
+	return alnum*2 < visible
+}
+
+// extractPDFNative is the in-process extractor; swappable in tests.
+var extractPDFNative = extractPDFViaRSC
+
+// pdftotextBin is poppler's pdftotext (set PDFTOTEXT_PATH to override).
+var pdftotextBin = getEnv("PDFTOTEXT_PATH", "pdftotext")
+
+// pdftotextTimeout bounds one pdftotext run; it is much faster than Calibre.
+const pdftotextTimeout = 5 * time.Minute
+
+// extractPDFViaPdftotext converts a PDF to UTF-8 text with poppler's
+// pdftotext, which decodes the compressed streams and embedded fonts that
+// rsc.io/pdf can't. Errors if the binary isn't installed.
+func extractPDFViaPdftotext(path string) (string, error) {
+	bin, err := exec.LookPath(pdftotextBin)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pdftotextTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, bin, "-enc", "UTF-8", path, "-")
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("pdftotext timed out after %s", pdftotextTimeout)
+		}
+		return "", fmt.Errorf("pdftotext failed: %w. Details: %s", err, stderr.String())
+	}
+	return cleanUTF8(stdout.Bytes()), nil
 }
 
 // extractPDFViaRSC uses rsc.io/pdf, recovering from its panics (it panics on
@@ -483,7 +642,7 @@ func extractPDFViaRSC(path string) (text string, err error) {
 // extractPDFViaCalibre converts a PDF to text with Calibre's ebook-convert,
 // which handles the wide range of real PDFs rsc.io/pdf can't.
 func extractPDFViaCalibre(path string) (string, error) {
-	if _, err := exec.LookPath("ebook-convert"); err != nil {
+	if _, err := exec.LookPath(ebookConvertBin); err != nil {
 		return "", fmt.Errorf("could not extract PDF text (rsc.io/pdf failed and Calibre ebook-convert unavailable): %w", err)
 	}
 	tempTxtFile := filepath.Join(os.TempDir(), fmt.Sprintf("pdf_temp_%d_%s.txt", os.Getpid(), filepath.Base(path)))
@@ -505,6 +664,8 @@ func extractPDFViaCalibre(path string) (string, error) {
 	return text, nil
 }
 
+// ExtractTextFromEPUB returns the visible text of an EPUB's chapters in spine
+// order, one blank line between paragraphs.
 func ExtractTextFromEPUB(path string) (string, error) {
 	r, err := zip.OpenReader(path)
 	if err != nil {
@@ -512,81 +673,36 @@ func ExtractTextFromEPUB(path string) (string, error) {
 	}
 	defer r.Close()
 
+	// Q10: parse each chapter's markup so chunks hold readable text, not tags,
+	// and follow the OPF spine so chapters come out in reading order.
 	var sb strings.Builder
-
-	for _, f := range r.File {
-		if strings.HasSuffix(strings.ToLower(f.Name), ".xhtml") || strings.HasSuffix(strings.ToLower(f.Name), ".html") {
-			rc, err := f.Open()
-			if err != nil {
-				continue
-			}
-			content, err := io.ReadAll(rc)
-			rc.Close()
-			if err != nil {
-				continue
-			}
-			// Q10: strip HTML/CSS markup so chunks hold readable text, not tags.
-			sb.WriteString(stripHTML(string(content)))
-			sb.WriteString("\n")
-		}
-	}
-
-	return sb.String(), nil
-}
-
-// stripHTML removes <script>/<style> blocks and all tags from HTML/XHTML,
-// decodes common entities, and collapses whitespace, leaving readable text (Q10).
-func stripHTML(s string) string {
-	// Drop <script>…</script> and <style>…</style> wholesale.
-	for _, tag := range []string{"script", "style"} {
-		for {
-			lower := strings.ToLower(s)
-			open := strings.Index(lower, "<"+tag)
-			if open < 0 {
-				break
-			}
-			close := strings.Index(lower[open:], "</"+tag+">")
-			if close < 0 {
-				s = s[:open]
-				break
-			}
-			end := open + close
-			if gt := strings.Index(lower[end:], ">"); gt >= 0 {
-				end += gt + 1
-			}
-			s = s[:open] + " " + s[end:]
+	for _, name := range epubSpine(r.File) {
+		rc, err := r.Open(name)
+		if err != nil {
+			continue
 		}
-	}
-
-	// Remove all remaining tags.
-	var b strings.Builder
-	inTag := false
-	for _, r := range s {
-		switch {
-		case r == '<':
-			inTag = true
-		case r == '>':
-			inTag = false
-			b.WriteByte(' ')
-		case !inTag:
-			b.WriteRune(r)
+		text, err := htmlToText(rc)
+		rc.Close()
+		if err != nil || text == "" {
+			continue
 		}
+		sb.WriteString(text)
+		sb.WriteString("\n\n")
 	}
 
-	out := b.String()
-	out = strings.NewReplacer(
-		"&nbsp;", " ", "&amp;", "&", "&lt;", "<", "&gt;", ">",
-		"&quot;", "\"", "&#39;", "'", "&apos;", "'",
-	).Replace(out)
-
-	return strings.Join(strings.Fields(out), " ")
+	return sb.String(), nil
 }
 
 // ExtractTextFromMOBI extracts text from MOBI, AZW, and AZW3 files
 // This function uses Calibre's ebook-convert command-line tool
 func ExtractTextFromMOBI(path string) (string, error) {
+	// Encrypted books are refused up front, no Calibre run needed.
+	if mobiEncrypted(path) {
+		return "", errDRMProtected
+	}
+
 	// Check if ebook-convert is available
-	_, err := exec.LookPath("ebook-convert")
+	_, err := exec.LookPath(ebookConvertBin)
 	if err != nil {
 		return "", fmt.Errorf("ebook-convert (Calibre) not found. Please install Calibre to support MOBI/AZW formats. Error: %w", err)
 	}
@@ -598,6 +714,9 @@ func ExtractTextFromMOBI(path string) (string, error) {
 
 	// Run ebook-convert to convert MOBI to TXT (with its own timeout)
 	if err := runEbookConvert(path, tempTxtFile); err != nil {
+		if errors.Is(err, errDRMProtected) {
+			return "", err
+		}
 		return "", fmt.Errorf("failed to convert MOBI file: %w", err)
 	}
 
@@ -614,3 +733,26 @@ func ExtractTextFromMOBI(path string) (string, error) {
 
 	return text, nil
 }
+
+// mobiEncrypted reports whether a MOBI/AZW file's PalmDOC header marks its
+// text as encrypted. The header is record 0 of the Palm database: its offset
+// is the first entry of the record list (byte 78), and the encryption type is
+// the big-endian uint16 at byte 12 of the record (0 = none). Anything that
+// doesn't parse as a MOBI is left to Calibre.
+func mobiEncrypted(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	var head [86]byte
+	if _, err := io.ReadFull(f, head[:]); err != nil || string(head[60:68]) != "BOOKMOBI" {
+		return false
+	}
+	rec0 := int64(binary.BigEndian.Uint32(head[78:82]))
+	var palmDoc [16]byte
+	if _, err := f.ReadAt(palmDoc[:], rec0); err != nil {
+		return false
+	}
+	return binary.BigEndian.Uint16(palmDoc[12:14]) != 0
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Word documents. A .docx is a zip whose body lives in word/document.xml:
+// paragraphs (w:p) hold runs (w:r) whose text is in w:t, with w:tab and
+// w:br/w:cr for tabs and line breaks. Legacy binary .doc files go through
+// antiword when it is installed.
+
+// wordMLNamespace is the WordprocessingML main namespace.
+const wordMLNamespace = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+
+// maxDocxXMLBytes bounds how much of word/document.xml is decompressed, so a
+// zip bomb can't exhaust memory.
+const maxDocxXMLBytes = 200 << 20
+
+// ExtractTextFromDOCX returns the document's text with one blank line
+// between paragraphs.
+func ExtractTextFromDOCX(path string) (string, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return "", fmt.Errorf("open docx: %w", err)
+	}
+	defer zr.Close()
+
+	var doc *zip.File
+	for _, f := range zr.File {
+		if f.Name == "word/document.xml" {
+			doc = f
+			break
+		}
+	}
+	if doc == nil {
+		return "", errors.New("not a Word document: word/document.xml missing")
+	}
+	rc, err := doc.Open()
+	if err != nil {
+		return "", fmt.Errorf("open word/document.xml: %w", err)
+	}
+	defer rc.Close()
+
+	text, err := wordMLToText(io.LimitReader(rc, maxDocxXMLBytes))
+	if err != nil {
+		return "", fmt.Errorf("parse word/document.xml: %w", err)
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", errors.New("no text found in document")
+	}
+	return text, nil
+}
+
+// wordMLToText walks document.xml's tokens, keeping w:t character data and
+// closing each non-empty paragraph with "\n\n". Deleted revisions (w:delText)
+// and field instructions (w:instrText) are not w:t, so they are skipped.
+func wordMLToText(r io.Reader) (string, error) {
+	dec := xml.NewDecoder(r)
+	var out, para strings.Builder
+	inText := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Space != wordMLNamespace {
+				continue
+			}
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				para.WriteByte('\t')
+			case "br", "cr":
+				para.WriteByte('\n')
+			}
+		case xml.EndElement:
+			if t.Name.Space != wordMLNamespace {
+				continue
+			}
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if p := strings.TrimSpace(para.String()); p != "" {
+					out.WriteString(p)
+					out.WriteString("\n\n")
+				}
+				para.Reset()
+			}
+		case xml.CharData:
+			if inText {
+				para.Write(t)
+			}
+		}
+	}
+	return cleanUTF8([]byte(out.String())), nil
+}
+
+// antiwordBin converts legacy .doc files (set ANTIWORD_PATH to override).
+var antiwordBin = getEnv("ANTIWORD_PATH", "antiword")
+
+// antiwordTimeout bounds one antiword run.
+const antiwordTimeout = 2 * time.Minute
+
+// ExtractTextFromDOC reads a Word 97-2003 .doc with antiword. Errors if the
+// binary isn't installed.
+func ExtractTextFromDOC(path string) (string, error) {
+	bin, err := exec.LookPath(antiwordBin)
+	if err != nil {
+		return "", errors.New(".doc files need antiword on the server. Please save the document as .docx and upload it again")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), antiwordTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, bin, "-m", "UTF-8.txt", "-w", "0", path)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("antiword timed out after %s", antiwordTimeout)
+		}
+		return "", fmt.Errorf("antiword failed: %w. Details: %s", err, stderr.String())
+	}
+	text := cleanUTF8(stdout.Bytes())
+	if strings.TrimSpace(text) == "" {
+		return "", errors.New("no text found in document")
+	}
+	return text, nil
+}
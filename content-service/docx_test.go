@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testDocumentXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+  <w:body>
+    <w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Chapter One</w:t></w:r></w:p>
+    <w:p>
+      <w:r><w:t xml:space="preserve">It was a </w:t></w:r>
+      <w:r><w:rPr><w:i/></w:rPr><w:t>dark</w:t></w:r>
+      <w:r><w:t xml:space="preserve"> &amp; stormy night.</w:t></w:r>
+      <w:del><w:r><w:delText>Deleted words.</w:delText></w:r></w:del>
+    </w:p>
+    <w:p></w:p>
+    <w:p><w:r><w:t>Line one</w:t><w:br/><w:t>Line two</w:t><w:tab/><w:t>tabbed</w:t></w:r></w:p>
+    <w:sectPr/>
+  </w:body>
+</w:document>`
+
+func TestExtractTextFromDOCX_RunsAndParagraphs(t *testing.T) {
+	path := writeEPUB(t, [][2]string{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`},
+		{"word/document.xml", testDocumentXML},
+		{"word/styles.xml", `<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:t>Not body</w:t></w:styles>`},
+	})
+
+	got, err := ExtractTextFromDOCX(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Chapter One\n\nIt was a dark & stormy night.\n\nLine one\nLine two\ttabbed\n\n"
+	if got != want {
+		t.Errorf("ExtractTextFromDOCX =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestExtractTextFromDOCX_NotWord(t *testing.T) {
+	path := writeEPUB(t, [][2]string{{"mimetype", "application/epub+zip"}})
+	if _, err := ExtractTextFromDOCX(path); err == nil {
+		t.Error("expected an error for a zip without word/document.xml")
+	}
+}
+
+func TestExtractTextByType_DOCX(t *testing.T) {
+	src := writeEPUB(t, [][2]string{{"word/document.xml", testDocumentXML}})
+	path := filepath.Join(t.TempDir(), "Draft.DOCX")
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ExtractTextByType(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "Chapter One\n\nIt was a dark & stormy night.") {
+		t.Errorf("ExtractTextByType(.docx) = %q, paragraphs not preserved", got)
+	}
+}
+
+func TestExtractTextFromDOC_MissingAntiword(t *testing.T) {
+	orig := antiwordBin
+	antiwordBin = "antiword-not-installed"
+	defer func() { antiwordBin = orig }()
+	if _, err := exec.LookPath(antiwordBin); err == nil {
+		t.Skip("unexpected binary on PATH")
+	}
+
+	_, err := ExtractTextFromDOC(filepath.Join(t.TempDir(), "old.doc"))
+	if err == nil || !strings.Contains(err.Error(), ".docx") {
+		t.Errorf("err = %v, want a hint to save as .docx", err)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// epubContainer is META-INF/container.xml, which points at the OPF package.
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+// epubPackage is the subset of the OPF needed to read chapters in order.
+type epubPackage struct {
+	Manifest []struct {
+		ID        string `xml:"id,attr"`
+		Href      string `xml:"href,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef  string `xml:"idref,attr"`
+		Linear string `xml:"linear,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// epubSpine returns the zip paths of the book's content documents in reading
+// order, as listed by the OPF spine. Non-linear items (pop-up notes, answer
+// keys) are skipped. Only when no OPF can be parsed does it fall back to every
+// .xhtml/.html file in archive order.
+func epubSpine(archive []*zip.File) []string {
+	files := make(map[string]*zip.File, len(archive))
+	for _, f := range archive {
+		files[f.Name] = f
+	}
+	if order, err := epubSpineFromOPF(files, epubOPFPath(archive, files)); err == nil {
+		return order
+	}
+	var order []string
+	for _, f := range archive {
+		lower := strings.ToLower(f.Name)
+		if strings.HasSuffix(lower, ".xhtml") || strings.HasSuffix(lower, ".html") || strings.HasSuffix(lower, ".htm") {
+			order = append(order, f.Name)
+		}
+	}
+	return order
+}
+
+// epubOPFPath locates the OPF package via META-INF/container.xml, or, for
+// EPUBs that omit or break it, the first .opf file in the archive.
+func epubOPFPath(archive []*zip.File, files map[string]*zip.File) string {
+	var container epubContainer
+	if err := decodeZipXML(files["META-INF/container.xml"], &container); err == nil && len(container.Rootfiles) > 0 {
+		if _, ok := files[container.Rootfiles[0].FullPath]; ok {
+			return container.Rootfiles[0].FullPath
+		}
+	}
+	for _, f := range archive {
+		if strings.HasSuffix(strings.ToLower(f.Name), ".opf") {
+			return f.Name
+		}
+	}
+	return ""
+}
+
+func epubSpineFromOPF(files map[string]*zip.File, opfPath string) ([]string, error) {
+	var pkg epubPackage
+	if err := decodeZipXML(files[opfPath], &pkg); err != nil {
+		return nil, err
+	}
+	if len(pkg.Spine) == 0 {
+		return nil, errors.New("epub: OPF has an empty spine")
+	}
+
+	hrefs := make(map[string]string, len(pkg.Manifest))
+	for _, item := range pkg.Manifest {
+		hrefs[item.ID] = item.Href
+	}
+	base := path.Dir(opfPath)
+	var order []string
+	for _, ref := range pkg.Spine {
+		href, ok := hrefs[ref.IDRef]
+		if !ok || ref.Linear == "no" {
+			continue
+		}
+		// Manifest hrefs are URL-encoded and relative to the OPF.
+		if u, err := url.PathUnescape(href); err == nil {
+			href = u
+		}
+		if i := strings.IndexByte(href, '#'); i >= 0 {
+			href = href[:i]
+		}
+		name := path.Clean(path.Join(base, href))
+		if _, ok := files[name]; ok {
+			order = append(order, name)
+		}
+	}
+	return order, nil
+}
+
+func decodeZipXML(f *zip.File, v interface{}) error {
+	if f == nil {
+		return errors.New("epub: missing file")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+// htmlSkipTags hold no narratable text.
+var htmlSkipTags = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true,
+	"svg": true, "math": true, "template": true,
+}
+
+// htmlBlockTags end a paragraph, so their text is separated by a blank line.
+var htmlBlockTags = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "aside": true,
+	"blockquote": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "li": true, "ul": true, "ol": true, "dt": true,
+	"dd": true, "pre": true, "table": true, "tr": true, "figure": true,
+	"figcaption": true, "header": true, "footer": true, "hr": true, "body": true,
+}
+
+// htmlToText parses an HTML/XHTML document and returns its visible text:
+// script/style and other non-text elements are dropped, entities decoded,
+// runs of whitespace collapsed, and block elements (paragraphs, headings,
+// list items) separated by blank lines so the chunker keeps paragraph breaks.
+func htmlToText(r io.Reader) (string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", err
+	}
+	var paras []string
+	var cur strings.Builder
+	flush := func() {
+		if p := strings.Join(strings.Fields(cur.String()), " "); p != "" {
+			paras = append(paras, p)
+		}
+		cur.Reset()
+	}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			cur.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			if htmlSkipTags[n.Data] {
+				return
+			}
+			if n.Data == "br" {
+				cur.WriteByte(' ')
+				return
+			}
+			if htmlBlockTags[n.Data] {
+				flush()
+				defer flush()
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	flush()
+	return strings.Join(paras, "\n\n"), nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeEPUB builds a minimal EPUB whose zip order differs from its spine
+// order, so the test fails if extraction follows the zip.
+func writeEPUB(t *testing.T, files [][2]string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "book.epub")
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, file := range files {
+		w, err := zw.Create(file[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(file[1]))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return p
+}
+
+const testContainerXML = `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`
+
+const testOPF = `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+  <manifest>
+    <item id="ch2" href="text/chapter%202.xhtml" media-type="application/xhtml+xml"/>
+    <item id="notes" href="text/notes.xhtml" media-type="application/xhtml+xml"/>
+    <item id="ch1" href="text/a-chapter1.xhtml" media-type="application/xhtml+xml"/>
+    <item id="css" href="style.css" media-type="text/css"/>
+  </manifest>
+  <spine>
+    <itemref idref="ch1"/>
+    <itemref idref="notes" linear="no"/>
+    <itemref idref="ch2"/>
+  </spine>
+</package>`
+
+func TestExtractTextFromEPUB_SpineOrderAndCleanText(t *testing.T) {
+	path := writeEPUB(t, [][2]string{
+		{"mimetype", "application/epub+zip"},
+		{"META-INF/container.xml", testContainerXML},
+		{"OEBPS/content.opf", testOPF},
+		{"OEBPS/text/chapter 2.xhtml", `<?xml version="1.0"?>
+<html xmlns="http://www.w3.org/1999/xhtml"><head><title>Two</title></head>
+<body><h1>Chapter Two</h1><p>The <em>second</em> part.</p></body></html>`},
+		{"OEBPS/text/notes.xhtml", `<html><body><p>Footnote text</p></body></html>`},
+		{"OEBPS/text/a-chapter1.xhtml", `<?xml version="1.0"?>
+<html xmlns="http://www.w3.org/1999/xhtml"><head><title>One</title>
+<style>p { margin: 0 }</style></head>
+<body><h1>Chapter One</h1>
+<p>It was a dark&#160;and
+   stormy night.</p><script>track()</script><p>Rain &amp; wind.</p></body></html>`},
+		{"OEBPS/style.css", "p { color: red }"},
+	})
+
+	got, err := ExtractTextFromEPUB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Chapter One\n\nIt was a dark and stormy night.\n\nRain & wind.\n\n" +
+		"Chapter Two\n\nThe second part.\n\n"
+	if got != want {
+		t.Errorf("ExtractTextFromEPUB =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestExtractTextFromEPUB_OPFWithoutContainer(t *testing.T) {
+	path := writeEPUB(t, [][2]string{
+		{"OEBPS/text/chapter 2.xhtml", "<p>Second</p>"},
+		{"OEBPS/text/a-chapter1.xhtml", "<p>First</p>"},
+		{"OEBPS/text/notes.xhtml", "<p>Notes</p>"},
+		{"OEBPS/content.opf", testOPF},
+	})
+	got, err := ExtractTextFromEPUB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "First\n\nSecond\n\n" {
+		t.Errorf("ExtractTextFromEPUB = %q, want spine order", got)
+	}
+}
+
+func TestExtractTextFromEPUB_UnparseableOPFFallsBackToArchiveOrder(t *testing.T) {
+	path := writeEPUB(t, [][2]string{
+		{"META-INF/container.xml", testContainerXML},
+		{"OEBPS/content.opf", "<package><manifest>"},
+		{"OEBPS/b.html", "<p>First</p>"},
+		{"OEBPS/a.html", "<p>Second</p>"},
+	})
+	got, err := ExtractTextFromEPUB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "First\n\nSecond\n\n" {
+		t.Errorf("ExtractTextFromEPUB = %q, want archive order", got)
+	}
+}
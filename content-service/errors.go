@@ -0,0 +1,94 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Machine-readable error codes. Clients branch on code; message is for
+// humans and may change wording at any time.
+const (
+	errCodeInvalidRequest     = "invalid_request"
+	errCodeUnauthorized       = "unauthorized"
+	errCodeForbidden          = "forbidden"
+	errCodeNotFound           = "not_found"
+	errCodeBookNotFound       = "book_not_found"
+	errCodePageNotFound       = "page_not_found"
+	errCodeAudioNotReady      = "audio_not_ready"
+	errCodeConflict           = "conflict"
+	errCodeGone               = "gone"
+	errCodePayloadTooLarge    = "payload_too_large"
+	errCodeUnprocessable      = "unprocessable"
+	errCodeQuotaExceeded      = "quota_exceeded"
+	errCodeInternal           = "internal_error"
+	errCodeUpstream           = "upstream_error"
+	errCodeCoverUnavailable   = "cover_unavailable"
+	errCodeCoverStoreFailed   = "cover_store_failed"
+	errCodeServiceUnavailable = "service_unavailable"
+	errCodeDRMProtected       = "drm_protected"
+)
+
+// errorEnvelope builds the body every error response shares:
+//
+//	{"code": "book_not_found", "message": "Book not found", "request_id": "…"}
+//
+// "error" repeats message for app builds that predate the envelope; drop it
+// once they're gone. extra adds endpoint-specific fields (quota details,
+// allowed values) and may not override the envelope keys.
+func errorEnvelope(c *gin.Context, code, message string, extra gin.H) gin.H {
+	body := gin.H{}
+	for k, v := range extra {
+		body[k] = v
+	}
+	body["code"] = code
+	body["message"] = message
+	body["request_id"] = c.GetString("request_id")
+	if _, ok := body["error"]; !ok {
+		body["error"] = message
+	}
+	return body
+}
+
+// respondError writes the standard error envelope.
+func respondError(c *gin.Context, status int, code, message string) {
+	c.JSON(status, errorEnvelope(c, code, message, nil))
+}
+
+// respondErrorWith is respondError with endpoint-specific fields alongside
+// the envelope.
+func respondErrorWith(c *gin.Context, status int, code, message string, extra gin.H) {
+	c.JSON(status, errorEnvelope(c, code, message, extra))
+}
+
+// respondInternal answers 500 with a generic message and logs err under the
+// request id, so internal details (SQL, file paths) stay out of responses but
+// can still be found from a user's report.
+func respondInternal(c *gin.Context, message string, err error) {
+	log.Printf("❌ [%s] %s %s: %s: %v", c.GetString("request_id"), c.Request.Method, c.FullPath(), message, err)
+	respondError(c, http.StatusInternalServerError, errCodeInternal, message)
+}
+
+// abortWithError is respondError for middleware: it also stops the chain.
+func abortWithError(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, errorEnvelope(c, code, message, nil))
+}
+
+// requestIDMiddleware keeps the gateway's X-Request-ID (or assigns one when
+// called directly) so error bodies and logs can be matched to a request.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rid := c.GetHeader("X-Request-ID")
+		if rid == "" {
+			b := make([]byte, 8)
+			rand.Read(b)
+			rid = hex.EncodeToString(b)
+		}
+		c.Set("request_id", rid)
+		c.Writer.Header().Set("X-Request-ID", rid)
+		c.Next()
+	}
+}
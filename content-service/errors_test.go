@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+// serveError runs handlers behind requestIDMiddleware and decodes the body.
+func serveError(t *testing.T, path, requestID string, handlers ...gin.HandlerFunc) (int, map[string]interface{}) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(requestIDMiddleware())
+	r.GET("/books/:book_id", handlers...)
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if requestID != "" {
+		req.Header.Set("X-Request-ID", requestID)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body is not JSON: %v (%s)", err, w.Body.String())
+	}
+	return w.Code, body
+}
+
+func assertEnvelope(t *testing.T, body map[string]interface{}, code, requestID string) {
+	t.Helper()
+	if body["code"] != code {
+		t.Errorf("code = %v, want %q", body["code"], code)
+	}
+	if msg, _ := body["message"].(string); msg == "" {
+		t.Errorf("message missing: %v", body)
+	}
+	if body["request_id"] != requestID {
+		t.Errorf("request_id = %v, want %q", body["request_id"], requestID)
+	}
+	if body["error"] == nil {
+		t.Errorf("legacy error field missing: %v", body)
+	}
+}
+
+func TestErrorEnvelope_Ownership(t *testing.T) {
+	status, body := serveError(t, "/books/1", "req-1", requireBookOwnership())
+	if status != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", status)
+	}
+	assertEnvelope(t, body, errCodeUnauthorized, "req-1")
+
+	withClaims := func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"user_id": float64(7)})
+	}
+	status, body = serveError(t, "/books/abc", "req-2", withClaims, requireBookOwnership())
+	if status != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", status)
+	}
+	assertEnvelope(t, body, errCodeInvalidRequest, "req-2")
+}
+
+func TestErrorEnvelope_QuotaKeepsDetails(t *testing.T) {
+	d := QuotaDecision{Metric: "stream_pages", Used: 10, Limit: 10, ResetsAt: time.Now()}
+	status, body := serveError(t, "/books/1", "req-3", func(c *gin.Context) { quota429(c, d) })
+	if status != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", status)
+	}
+	assertEnvelope(t, body, errCodeQuotaExceeded, "req-3")
+	if body["error"] != "quota_exceeded" || body["quota"] != "stream_pages" || body["limit"] != float64(10) {
+		t.Errorf("quota fields not preserved: %v", body)
+	}
+}
+
+func TestErrorEnvelope_AssignsRequestID(t *testing.T) {
+	_, body := serveError(t, "/books/1", "", func(c *gin.Context) {
+		respondError(c, http.StatusNotFound, errCodeBookNotFound, "Book not found")
+	})
+	if rid, _ := body["request_id"].(string); len(rid) != 16 {
+		t.Errorf("request_id = %q, want a generated 16-char id", rid)
+	}
+	if body["code"] != errCodeBookNotFound {
+		t.Errorf("code = %v", body["code"])
+	}
+}
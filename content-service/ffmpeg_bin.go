@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ffmpegBin / ffprobeBin are the resolved binaries every exec.Command uses.
+// They default to a PATH lookup and are pinned at boot by initFFmpeg.
+var (
+	ffmpegBin  = "ffmpeg"
+	ffprobeBin = "ffprobe"
+)
+
+// resolveBinary finds the binary named by envName (a path or a bare name
+// looked up on PATH, default def) and confirms it runs via -version. Returns
+// the resolved path and the first line of its version banner.
+func resolveBinary(envName, def string) (string, string, error) {
+	want := getEnv(envName, def)
+	path, err := exec.LookPath(want)
+	if err != nil {
+		return "", "", fmt.Errorf("%s not found at %q (set %s): %w", def, want, envName, err)
+	}
+	out, err := exec.Command(path, "-version").CombinedOutput()
+	if err != nil {
+		return "", "", fmt.Errorf("%s at %q failed its version check (set %s): %w", def, path, envName, err)
+	}
+	banner, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return path, banner, nil
+}
+
+// initFFmpeg resolves FFMPEG_PATH / FFPROBE_PATH once at startup so a missing
+// binary fails the boot with a clear message instead of the first merge
+// failing deep inside a goroutine.
+func initFFmpeg() error {
+	ffmpeg, fv, err := resolveBinary("FFMPEG_PATH", "ffmpeg")
+	if err != nil {
+		return err
+	}
+	ffprobe, pv, err := resolveBinary("FFPROBE_PATH", "ffprobe")
+	if err != nil {
+		return err
+	}
+	ffmpegBin, ffprobeBin = ffmpeg, ffprobe
+	log.Printf("🎬 %s (%s)", fv, ffmpegBin)
+	log.Printf("🎬 %s (%s)", pv, ffprobeBin)
+	return nil
+}
+
+// ffmpegSlots caps how many ffmpeg processes run at once across the process
+// (FFMPEG_CONCURRENCY, default half the CPUs, at least 1). A page's merge,
+// music and overlay stages each spawn several; with many books in flight the
+// uncapped fan-out exhausts CPU and memory. The slot is held per process, not
+// per stage, so stages that call each other can't deadlock on it.
+var ffmpegSlots = make(chan struct{}, ffmpegConcurrency())
+
+func ffmpegConcurrency() int {
+	if n := envInt("FFMPEG_CONCURRENCY", runtime.NumCPU()/2); n > 0 {
+		return n
+	}
+	return 1
+}
+
+// ffmpegExec runs one prepared ffmpeg command. Swappable in tests.
+var ffmpegExec = func(cmd *exec.Cmd) ([]byte, error) { return cmd.CombinedOutput() }
+
+// runFFmpegCmd runs cmd (an ffmpeg invocation) once a slot is free and
+// returns its combined output.
+func runFFmpegCmd(cmd *exec.Cmd) ([]byte, error) {
+	slots := ffmpegSlots
+	slots <- struct{}{}
+	defer func() { <-slots }()
+	return ffmpegExec(cmd)
+}
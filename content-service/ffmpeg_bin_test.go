@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeBinary writes an executable that prints a version banner.
+func fakeBinary(t *testing.T, name string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell-script fake binaries need a POSIX shell")
+	}
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte("#!/bin/sh\necho \""+name+" version 9.9-test\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestInitFFmpeg_HonorsCustomPaths(t *testing.T) {
+	origF, origP := ffmpegBin, ffprobeBin
+	defer func() { ffmpegBin, ffprobeBin = origF, origP }()
+
+	ff, fp := fakeBinary(t, "ffmpeg"), fakeBinary(t, "ffprobe")
+	t.Setenv("FFMPEG_PATH", ff)
+	t.Setenv("FFPROBE_PATH", fp)
+
+	if err := initFFmpeg(); err != nil {
+		t.Fatalf("initFFmpeg: %v", err)
+	}
+	if ffmpegBin != ff || ffprobeBin != fp {
+		t.Fatalf("resolved %q / %q, want %q / %q", ffmpegBin, ffprobeBin, ff, fp)
+	}
+}
+
+func TestInitFFmpeg_MissingBinaryReported(t *testing.T) {
+	origF, origP := ffmpegBin, ffprobeBin
+	defer func() { ffmpegBin, ffprobeBin = origF, origP }()
+
+	t.Setenv("FFMPEG_PATH", fakeBinary(t, "ffmpeg"))
+	t.Setenv("FFPROBE_PATH", filepath.Join(t.TempDir(), "no-such-ffprobe"))
+
+	err := initFFmpeg()
+	if err == nil {
+		t.Fatal("initFFmpeg succeeded with a missing ffprobe")
+	}
+	if !strings.Contains(err.Error(), "FFPROBE_PATH") {
+		t.Fatalf("error %q doesn't name the env var to fix", err)
+	}
+	if ffmpegBin != origF || ffprobeBin != origP {
+		t.Fatal("a failed preflight must not half-apply resolved paths")
+	}
+}
+
+func TestResolveBinary_FailedVersionCheck(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "ffmpeg")
+	if err := os.WriteFile(p, []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FFMPEG_PATH", p)
+	if _, _, err := resolveBinary("FFMPEG_PATH", "ffmpeg"); err == nil || !strings.Contains(err.Error(), "version check") {
+		t.Fatalf("err = %v, want a version-check failure", err)
+	}
+}
+
+func TestRunFFmpegCmd_RespectsConcurrencyCap(t *testing.T) {
+	origSlots, origExec := ffmpegSlots, ffmpegExec
+	t.Cleanup(func() { ffmpegSlots, ffmpegExec = origSlots, origExec })
+	ffmpegSlots = make(chan struct{}, 3)
+
+	var running, peak, calls int32
+	ffmpegExec = func(cmd *exec.Cmd) ([]byte, error) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&calls, 1)
+		return nil, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runFFmpegCmd(exec.Command(ffmpegBin, "-version"))
+		}()
+	}
+	wg.Wait()
+	if calls != 40 {
+		t.Fatalf("ran %d commands, want 40", calls)
+	}
+	if peak > 3 {
+		t.Errorf("peak concurrency %d, cap is 3", peak)
+	}
+	if peak < 2 {
+		t.Errorf("peak concurrency %d: commands were serialized below the cap", peak)
+	}
+}
+
+func TestFFmpegConcurrency(t *testing.T) {
+	t.Setenv("FFMPEG_CONCURRENCY", "6")
+	if n := ffmpegConcurrency(); n != 6 {
+		t.Errorf("FFMPEG_CONCURRENCY=6 → %d", n)
+	}
+	t.Setenv("FFMPEG_CONCURRENCY", "0")
+	if n := ffmpegConcurrency(); n != 1 {
+		t.Errorf("FFMPEG_CONCURRENCY=0 → %d, want the floor of 1", n)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+// Local directories the admin file endpoints (tree + delete) may touch. Each
+// root maps the name used in admin paths ("audio/book_21_chunk_5.mp3") to its
+// directory on disk, from ADMIN_FILE_ROOTS — comma-separated name=dir pairs,
+// e.g. "audio=/app/audio,covers=./uploads/covers,uploads=./uploads". Unset, it
+// matches the Docker layout, with audio following AUDIO_STORAGE_PATH.
+
+var (
+	errFileNotAllowed = errors.New("file is not in an allowed directory")
+	errFileTraversal  = errors.New("path traversal not allowed")
+)
+
+// fileRoot is one admin-visible directory.
+type fileRoot struct {
+	Name string
+	Dir  string
+}
+
+// adminFileRoots returns the configured roots in config order. Malformed
+// entries are logged and skipped.
+func adminFileRoots() []fileRoot {
+	spec := getEnv("ADMIN_FILE_ROOTS", "")
+	if strings.TrimSpace(spec) == "" {
+		spec = "audio=" + getEnv("AUDIO_STORAGE_PATH", "./audio") + ",covers=./uploads/covers,uploads=./uploads"
+	}
+	var roots []fileRoot
+	for _, entry := range strings.Split(spec, ",") {
+		name, dir, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		name, dir = strings.TrimSpace(name), strings.TrimSpace(dir)
+		if !ok || name == "" || dir == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+			if entry != "" {
+				log.Printf("⚠️ ADMIN_FILE_ROOTS: ignoring %q (want name=dir)", entry)
+			}
+			continue
+		}
+		roots = append(roots, fileRoot{Name: name, Dir: filepath.Clean(dir)})
+	}
+	return roots
+}
+
+// fileRootNames lists the configured root names, for error messages.
+func fileRootNames(roots []fileRoot) []string {
+	names := make([]string, len(roots))
+	for i, r := range roots {
+		names[i] = r.Name
+	}
+	return names
+}
+
+// resolveAdminFilePath maps an admin path ("<root>/<rest>") to its file on
+// disk, refusing traversal and anything outside the configured roots.
+func resolveAdminFilePath(rel string, roots []fileRoot) (string, error) {
+	for _, seg := range strings.FieldsFunc(rel, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if seg == ".." {
+			return "", errFileTraversal
+		}
+	}
+	name, rest, ok := strings.Cut(rel, "/")
+	if !ok || rest == "" || filepath.IsAbs(rel) {
+		return "", errFileNotAllowed
+	}
+	for _, root := range roots {
+		if root.Name != name {
+			continue
+		}
+		full := filepath.Join(root.Dir, rest)
+		r, err := filepath.Rel(root.Dir, full)
+		if err != nil || strings.HasPrefix(r, "..") {
+			return "", errFileTraversal
+		}
+		if r == "." {
+			return "", errFileNotAllowed // the root itself
+		}
+		return full, nil
+	}
+	return "", fmt.Errorf("%w: %q is not one of %s", errFileNotAllowed, name, strings.Join(fileRootNames(roots), ", "))
+}
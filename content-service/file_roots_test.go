@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminFileRoots(t *testing.T) {
+	t.Setenv("AUDIO_STORAGE_PATH", "/app/audio")
+	t.Setenv("ADMIN_FILE_ROOTS", "")
+	want := []fileRoot{{"audio", "/app/audio"}, {"covers", "uploads/covers"}, {"uploads", "uploads"}}
+	if got := adminFileRoots(); !reflect.DeepEqual(got, want) {
+		t.Errorf("default roots = %+v, want %+v", got, want)
+	}
+
+	t.Setenv("ADMIN_FILE_ROOTS", " audio=/data/audio/ , broken, ../x=/etc, exports=/data/exports")
+	want = []fileRoot{{"audio", "/data/audio"}, {"exports", "/data/exports"}}
+	if got := adminFileRoots(); !reflect.DeepEqual(got, want) {
+		t.Errorf("configured roots = %+v, want %+v", got, want)
+	}
+}
+
+func TestResolveAdminFilePath(t *testing.T) {
+	roots := []fileRoot{{"audio", "/data/audio"}, {"covers", "/data/uploads/covers"}}
+	for path, want := range map[string]error{
+		"audio/book_21_chunk_5.mp3":  nil,
+		"covers/7/cover.jpg":         nil,
+		"uploads/7/3/original.pdf":   errFileNotAllowed, // not a configured root
+		"audiofiles/x.mp3":           errFileNotAllowed,
+		"audio/../../etc/passwd":     errFileTraversal,
+		`covers\..\..\etc\passwd`:    errFileTraversal,
+		"/etc/passwd":                errFileNotAllowed, // outside every root
+		"audio/":                     errFileNotAllowed,
+		"audio/./":                   errFileNotAllowed,
+		"audio/sub/../../covers/a.j": errFileTraversal,
+	} {
+		full, err := resolveAdminFilePath(path, roots)
+		if !errors.Is(err, want) || (want == nil && err != nil) {
+			t.Errorf("resolveAdminFilePath(%q) = %q, %v; want %v", path, full, err, want)
+		}
+	}
+	if full, _ := resolveAdminFilePath("covers/7/cover.jpg", roots); full != "/data/uploads/covers/7/cover.jpg" {
+		t.Errorf("covers path resolved to %q", full)
+	}
+}
+
+func TestDeleteFileContentHandler(t *testing.T) {
+	dir := t.TempDir()
+	audioDir := filepath.Join(dir, "audio")
+	os.MkdirAll(audioDir, 0755)
+	outside := filepath.Join(dir, "secret.txt")
+	os.WriteFile(outside, []byte("keep"), 0644)
+	target := filepath.Join(audioDir, "page.mp3")
+	os.WriteFile(target, []byte("ID3"), 0644)
+	t.Setenv("ADMIN_FILE_ROOTS", "audio="+audioDir)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.DELETE("/admin/files", deleteFileContentHandler)
+	del := func(path string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/files", bytes.NewBufferString(`{"file_path":"`+path+`"}`)))
+		return w.Code
+	}
+
+	for path, want := range map[string]int{
+		"uploads/page.mp3":    http.StatusForbidden, // prefix not configured
+		"audio/../secret.txt": http.StatusForbidden,
+		outside:               http.StatusForbidden, // absolute path outside the roots
+		"audio/missing.mp3":   http.StatusNotFound,
+		"audio/page.mp3":      http.StatusOK,
+	} {
+		if got := del(path); got != want {
+			t.Errorf("delete %q = %d, want %d", path, got, want)
+		}
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Error("allowed file not deleted")
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Error("file outside the roots was deleted")
+	}
+}
@@ -1,13 +1,18 @@
 package main
 
 // fileuploadgo uploadBookFileHandler handles file uploads for books.
-// It expects form-data with keys "book_id" and "file".
+// It expects form-data with keys "book_id" and "file", plus "replace=true" to
+// replace a file the book already has and "auto_process=true" to queue its
+// transcription once it is split into pages (auto_process.go).
 // It saves the file to a specified directory and updates the book record in the database.
 // It also processes the uploaded file by chunking it into smaller parts for further processing.
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
@@ -23,12 +28,12 @@ import (
 func uploadBookFileHandler(c *gin.Context) {
 	bookIDStr := c.PostForm("book_id")
 	if bookIDStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "book_id is required"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "book_id is required")
 		return
 	}
 	bookIDU64, err := strconv.ParseUint(bookIDStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book_id"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid book_id")
 		return
 	}
 
@@ -36,39 +41,41 @@ func uploadBookFileHandler(c *gin.Context) {
 	// 403) so we don't reveal that another user's book exists.
 	userID := getUserIDFromContext(c)
 	if userID == 0 {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		respondError(c, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
 		return
 	}
 	bookPtr, err := verifyBookOwnership(uint(bookIDU64), userID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		respondError(c, http.StatusNotFound, errCodeBookNotFound, "Book not found")
 		return
 	}
 	book := *bookPtr
+	if !allowFileAttach(c, book, c.PostForm("replace") == "true") {
+		return
+	}
 
 	file, err := c.FormFile("file")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File upload error", "details": err.Error()})
+		respondErrorWith(c, http.StatusBadRequest, errCodeInvalidRequest, "File upload error", gin.H{"details": err.Error()})
 		return
 	}
 
 	// SECURITY (S7): enforce a max upload size at the app layer.
 	if file.Size > maxUploadBytes() {
-		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
-			"error": "File too large",
-			"max_bytes": maxUploadBytes(),
-		})
+		respondErrorWith(c, http.StatusRequestEntityTooLarge, errCodePayloadTooLarge, "File too large",
+			gin.H{"max_bytes": maxUploadBytes()})
 		return
 	}
 
 	// Check for unsupported KFX format explicitly (clearer error than the
 	// generic "invalid type" below).
 	if strings.HasSuffix(strings.ToLower(filepath.Base(file.Filename)), ".kfx") {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "KFX format is not supported",
-			"message": "Please convert your KFX file to EPUB, PDF, MOBI, or AZW3 format first",
-			"suggestion": "You can use Calibre or online converters to convert KFX files",
-		})
+		respondErrorWith(c, http.StatusBadRequest, errCodeInvalidRequest,
+			"Please convert your KFX file to EPUB, PDF, MOBI, or AZW3 format first",
+			gin.H{
+				"error":      "KFX format is not supported",
+				"suggestion": "You can use Calibre or online converters to convert KFX files",
+			})
 		return
 	}
 
@@ -76,10 +83,9 @@ func uploadBookFileHandler(c *gin.Context) {
 	// the client filename for anything but its (validated) extension.
 	ext := validUploadExt(file.Filename)
 	if ext == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid file type. Supported formats: PDF, TXT, EPUB, MOBI, AZW, AZW3",
-			"note":  "KFX format is not supported. Please convert to one of the supported formats first.",
-		})
+		respondErrorWith(c, http.StatusBadRequest, errCodeInvalidRequest,
+			"Invalid file type. Supported formats: PDF, TXT, EPUB, MOBI, AZW, AZW3, DOCX, DOC, HTML, MD",
+			gin.H{"note": "KFX format is not supported. Please convert to one of the supported formats first."})
 		return
 	}
 
@@ -88,15 +94,22 @@ func uploadBookFileHandler(c *gin.Context) {
 	// path traversal (../) and cross-user overwrite of a shared filename.
 	bookDir := uploadDirForBook(userID, book.ID)
 	if err := os.MkdirAll(bookDir, 0o755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload directory", "details": err.Error()})
+		respondInternal(c, "Failed to create upload directory", err)
 		return
 	}
 	dest := filepath.Join(bookDir, "original"+ext)
 	if err := c.SaveUploadedFile(file, dest); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file", "details": err.Error()})
+		respondInternal(c, "Failed to save file", err)
 		return
 	}
+	ingestUploadedFile(c, book, userID, ext, dest)
+}
 
+// ingestUploadedFile takes a document already saved at dest (the book's
+// uploadDirForBook/original<ext>), stores it in R2, records it on the book and
+// splits it into pages — synchronously for small files, in the background for
+// large ones. Shared by the multipart and chunked (chunked_upload.go) uploads.
+func ingestUploadedFile(c *gin.Context, book Book, userID uint, ext, dest string) {
 	// Q11: re-uploading replaces content. Clear any existing chunks/processed
 	// groups (and their audio) so we don't duplicate pages on re-upload.
 	resetBookContent(book.ID)
@@ -104,7 +117,7 @@ func uploadBookFileHandler(c *gin.Context) {
 	// Compute file hash
 	hash, err := computeFileHash(dest)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute file hash", "details": err.Error()})
+		respondInternal(c, "Failed to compute file hash", err)
 		return
 	}
 
@@ -113,18 +126,38 @@ func uploadBookFileHandler(c *gin.Context) {
 	// directly), then becomes scratch.
 	srcKey := uploadKey(userID, book.ID, ext)
 	if err := store.PutFile(c.Request.Context(), srcKey, dest, contentTypeForExt(dest)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store upload", "details": err.Error()})
+		respondInternal(c, "Failed to store upload", err)
 		return
 	}
 
+	// Replacing a file under another extension leaves the old object behind.
+	removeReplacedUpload(book, userID, srcKey)
+
 	// Update book record
 	book.FilePath = srcKey
 	book.Status = "processing"
 	book.ContentHash = hash
 	if err := db.Save(&book).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update book record", "details": err.Error()})
+		respondInternal(c, "Failed to update book record", err)
 		return
 	}
+	// A file someone already uploaded brings its cover along.
+	reuseCoverByContent(&book)
+
+	// auto_process: queue the transcription once the pages exist
+	// (auto_process.go). A lookup failure skips it; the upload still stands.
+	autoProcess := autoProcessRequested(c)
+	var accountType string
+	var autoResult *autoProcessResult
+	if autoProcess {
+		at, err := uploadAccountType(c, userID)
+		if err != nil {
+			log.Printf("⚠️ auto-process of book %d skipped: account type: %v", book.ID, err)
+			autoProcess = false
+			autoResult = &autoProcessResult{Status: "skipped", Reason: "account_type_unavailable"}
+		}
+		accountType = at
+	}
 
 	// Check file size to determine sync vs async processing
 	fileInfo, _ := os.Stat(dest)
@@ -139,9 +172,14 @@ func uploadBookFileHandler(c *gin.Context) {
 		// Async processing for large books - returns immediately
 		log.Printf("📚 Large book detected (%.2f MB, ~%d chunks), using async processing", fileSizeMB, estimatedChunks)
 
-		estimatedPages, err := ChunkDocumentAsync(book.ID, dest)
+		var then func()
+		if autoProcess {
+			then = func() { autoProcessBook(context.Background(), book, userID, accountType) }
+			autoResult = &autoProcessResult{Status: "after_chunking"}
+		}
+		estimatedPages, err := ChunkDocumentAsync(book.ID, dest, then)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start document processing", "details": err.Error()})
+			respondInternal(c, "Failed to start document processing", err)
 			return
 		}
 
@@ -155,24 +193,36 @@ func uploadBookFileHandler(c *gin.Context) {
 			"async":            true,
 			"file_size_mb":     fileSizeMB,
 			"note":             "Poll GET /user/books/{book_id} to check status. Status will be 'pending' when chunking is complete.",
+			"auto_process":     autoResult,
 		})
 		return
 	}
 
 	// Sync processing for smaller books (uses batch inserts for efficiency)
 	numPages, err := ChunkDocumentBatch(book.ID, dest)
+	if errors.Is(err, errDRMProtected) {
+		db.Model(&Book{}).Where("id = ?", book.ID).Update("status", chunkingFailedStatus(err))
+		respondError(c, http.StatusUnprocessableEntity, errCodeDRMProtected,
+			"This file is DRM-protected and cannot be processed. Upload a DRM-free copy of the book.")
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to paginate document", "details": err.Error()})
+		respondInternal(c, "Failed to paginate document", err)
 		return
 	}
 
 	// Query the chunk table to confirm all pages saved
 	var actualChunks []BookChunk
 	if err := db.Where("book_id = ?", book.ID).Find(&actualChunks).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify saved pages"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "Failed to verify saved pages")
 		return
 	}
 
+	if autoProcess {
+		r := autoProcessBook(c.Request.Context(), book, userID, accountType)
+		autoResult = &r
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message":      "File uploaded and split into pages successfully",
 		"book_id":      book.ID,
@@ -181,6 +231,7 @@ func uploadBookFileHandler(c *gin.Context) {
 		"content_hash": hash,
 		"page_indices": len(actualChunks),
 		"async":        false,
+		"auto_process": autoResult,
 	})
 }
 
@@ -201,7 +252,7 @@ func uploadDirForBook(userID, bookID uint) string {
 // considered — the rest of the client filename is ignored.
 func validUploadExt(filename string) string {
 	lower := strings.ToLower(filepath.Base(filename))
-	for _, e := range []string{".pdf", ".txt", ".epub", ".mobi", ".azw3", ".azw"} {
+	for _, e := range []string{".pdf", ".txt", ".epub", ".mobi", ".azw3", ".azw", ".docx", ".doc", ".html", ".htm", ".md", ".markdown"} {
 		if strings.HasSuffix(lower, e) {
 			return e
 		}
@@ -220,6 +271,51 @@ func maxUploadBytes() int64 {
 	return 50 << 20
 }
 
+// hasAttachedFile reports whether a source file is already attached to book.
+// A presigned upload that was initiated but never completed doesn't count.
+func hasAttachedFile(book Book) bool {
+	return book.FilePath != "" && book.Status != "awaiting_upload"
+}
+
+// allowFileAttach answers 409 when book already has a file and the client
+// didn't ask to replace it, so a second upload can't silently discard the
+// first book's pages and audio.
+func allowFileAttach(c *gin.Context, book Book, replace bool) bool {
+	if hasAttachedFile(book) && !replace {
+		respondErrorWith(c, http.StatusConflict, errCodeConflict,
+			"Book already has a file; upload with replace=true to replace it",
+			gin.H{"book_id": book.ID})
+		return false
+	}
+	return true
+}
+
+// ownsUploadKey reports whether key is one of this book's own upload objects
+// (uploadKey), as opposed to another book's object reused by hash dedup.
+func ownsUploadKey(key string, userID, bookID uint) bool {
+	return strings.HasPrefix(key, fmt.Sprintf("uploads/%d/%d/", userID, bookID))
+}
+
+// removeReplacedUpload deletes the book's previous source file once newKey
+// replaces it: the R2 object (only the book's own, and only when no other
+// book was deduped onto it) and its local copy. Same-extension replacements
+// overwrite in place and need nothing here.
+func removeReplacedUpload(book Book, userID uint, newKey string) {
+	old := book.FilePath
+	if old == "" || old == newKey || !ownsUploadKey(old, userID, book.ID) {
+		return
+	}
+	var sharers int64
+	db.Model(&Book{}).Where("file_path = ? AND id <> ?", old, book.ID).Count(&sharers)
+	if sharers > 0 {
+		log.Printf("📎 Book %d: old upload %s is shared with %d other book(s); keeping it", book.ID, old, sharers)
+		return
+	}
+	deleteStored(old)
+	removeFileIfExists(filepath.Join(uploadDirForBook(userID, book.ID), filepath.Base(old)))
+	log.Printf("🗑️ Book %d: replaced upload %s removed", book.ID, old)
+}
+
 // removeFileIfExists deletes a file path if it is non-empty, logging (not
 // failing) on error. Used by cascade/reset cleanup.
 func removeFileIfExists(path string) {
@@ -248,6 +344,7 @@ func resetBookContent(bookID uint) {
 	}
 	db.Unscoped().Where("book_id = ?", bookID).Delete(&BookChunk{})
 	db.Unscoped().Where("book_id = ?", bookID).Delete(&ProcessedChunkGroup{})
+	db.Model(&Book{}).Where("id = ?", bookID).Update("page_count", 0)
 }
 
 // computeFileHash computes the SHA256 hash of the file at the given path and returns it as a hex string.
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Admin view of the Foley clip cache. A clip is cached at three levels —
+// this process's effectCache, the local file, and the shared R2 library
+// (getOrGenerateEffect) — so evicting a bad clip clears all three, and the
+// next page that needs the effect renders it afresh. Worker hosts with their
+// own disk keep any local copy they already hold until that disk is reset.
+
+// foleyCacheEntry is one cached clip as the admin endpoint reports it.
+type foleyCacheEntry struct {
+	EventType string `json:"event_type"`
+	Path      string `json:"path"`
+	SizeBytes int64  `json:"size_bytes"`
+	InMemory  bool   `json:"in_memory"`
+}
+
+// foleyCacheEntries lists every clip held in memory or on local disk, sorted
+// by event type.
+func foleyCacheEntries() []foleyCacheEntry {
+	byType := map[string]*foleyCacheEntry{}
+	effectCacheMu.RLock()
+	for eventType, path := range effectCache {
+		byType[eventType] = &foleyCacheEntry{EventType: eventType, Path: path, InMemory: true}
+	}
+	effectCacheMu.RUnlock()
+
+	files, _ := filepath.Glob(filepath.Join(foleyClipDir, "foley_*.mp3"))
+	for _, path := range files {
+		eventType := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "foley_"), ".mp3")
+		if _, ok := byType[eventType]; !ok {
+			byType[eventType] = &foleyCacheEntry{EventType: eventType, Path: path}
+		}
+	}
+
+	out := make([]foleyCacheEntry, 0, len(byType))
+	for _, e := range byType {
+		if info, err := os.Stat(e.Path); err == nil {
+			e.SizeBytes = info.Size()
+		}
+		out = append(out, *e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].EventType < out[j].EventType })
+	return out
+}
+
+// evictFoleyEffect drops eventType's clip from memory, local disk and the R2
+// library. It reports whether anything was cached.
+func evictFoleyEffect(ctx context.Context, eventType string) (bool, error) {
+	found := false
+	effectCacheMu.Lock()
+	path, ok := effectCache[eventType]
+	delete(effectCache, eventType)
+	effectCacheMu.Unlock()
+	if ok {
+		found = true
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return found, err
+		}
+	}
+	if err := os.Remove(foleyClipPath(eventType)); err == nil {
+		found = true
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return found, err
+	}
+	if store != nil {
+		key := foleyLibKey(eventType)
+		if exists, err := store.Exists(ctx, key); err == nil && exists {
+			found = true
+			if err := store.Delete(ctx, key); err != nil {
+				return found, err
+			}
+		}
+	}
+	return found, nil
+}
+
+// listFoleyCacheHandler lists the cached Foley clips.
+// GET /admin/foley/cache
+func listFoleyCacheHandler(c *gin.Context) {
+	entries := foleyCacheEntries()
+	c.JSON(http.StatusOK, gin.H{"effects": entries, "count": len(entries)})
+}
+
+// evictFoleyCacheHandler evicts one Foley clip so it is regenerated on next use.
+// DELETE /admin/foley/cache/:event_type
+func evictFoleyCacheHandler(c *gin.Context) {
+	eventType := c.Param("event_type")
+	if !validFoleyEvents[eventType] {
+		respondError(c, http.StatusNotFound, errCodeNotFound, "Unknown Foley event type")
+		return
+	}
+	found, err := evictFoleyEffect(c.Request.Context(), eventType)
+	if err != nil {
+		respondInternal(c, "Could not evict Foley clip", err)
+		return
+	}
+	if !found {
+		respondError(c, http.StatusNotFound, errCodeNotFound, "Foley clip is not cached")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Foley clip evicted; it will be regenerated on next use", "event_type": eventType})
+}
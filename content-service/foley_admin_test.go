@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+// withFoleyCache points the Foley cache at a temp dir holding clips for
+// eventTypes (all in memory too) and the library at an in-memory store.
+func withFoleyCache(t *testing.T, eventTypes ...string) memStore {
+	t.Helper()
+	origDir, origCache, origStore := foleyClipDir, effectCache, store
+	foleyClipDir = t.TempDir()
+	effectCache = map[string]string{}
+	ms := memStore{}
+	store = ms
+	t.Cleanup(func() { foleyClipDir, effectCache, store = origDir, origCache, origStore })
+	for _, e := range eventTypes {
+		path := foleyClipPath(e)
+		if err := os.WriteFile(path, []byte("ID3"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		effectCache[e] = path
+		ms[foleyLibKey(e)] = 3
+	}
+	return ms
+}
+
+func foleyAdminRouter(isAdmin bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	admin := r.Group("/admin", func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"user_id": float64(1), "is_admin": isAdmin})
+	}, adminMiddleware())
+	admin.GET("/foley/cache", listFoleyCacheHandler)
+	admin.DELETE("/foley/cache/:event_type", evictFoleyCacheHandler)
+	return r
+}
+
+func TestFoleyCache_ListsPopulatedCache(t *testing.T) {
+	withFoleyCache(t, "thunder", "door_creak")
+	// A clip on disk that this process hasn't loaded yet is listed too.
+	os.WriteFile(foleyClipPath("rain"), []byte("ID3 rain"), 0644)
+
+	w := httptest.NewRecorder()
+	foleyAdminRouter(true).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/foley/cache", nil))
+	var resp struct {
+		Effects []foleyCacheEntry `json:"effects"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || len(resp.Effects) != 3 {
+		t.Fatalf("list = %d %s", w.Code, w.Body)
+	}
+	got := resp.Effects
+	if got[0].EventType != "door_creak" || !got[0].InMemory || got[1].EventType != "rain" || got[1].InMemory || got[1].SizeBytes != 8 {
+		t.Fatalf("entries = %+v", got)
+	}
+	if got[2].Path != filepath.Join(foleyClipDir, "foley_thunder.mp3") {
+		t.Fatalf("thunder path = %q", got[2].Path)
+	}
+}
+
+func TestFoleyCache_EvictRemovesEveryCopy(t *testing.T) {
+	ms := withFoleyCache(t, "thunder", "rain")
+	r := foleyAdminRouter(true)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/foley/cache/thunder", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("evict = %d %s", w.Code, w.Body)
+	}
+	if _, ok := effectCache["thunder"]; ok {
+		t.Error("thunder still in memory")
+	}
+	if fileExists(foleyClipPath("thunder")) {
+		t.Error("thunder clip still on disk")
+	}
+	if _, ok := ms[foleyLibKey("thunder")]; ok {
+		t.Error("thunder still in the R2 library")
+	}
+	if _, ok := effectCache["rain"]; !ok || !fileExists(foleyClipPath("rain")) {
+		t.Error("evicting thunder touched rain")
+	}
+
+	for path, want := range map[string]int{
+		"/admin/foley/cache/thunder":   http.StatusNotFound, // already gone
+		"/admin/foley/cache/kazoo":     http.StatusNotFound, // not an effect
+		"/admin/foley/cache/..%2Frain": http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, path, nil))
+		if w.Code != want {
+			t.Errorf("DELETE %s = %d, want %d", path, w.Code, want)
+		}
+	}
+}
+
+func TestFoleyCache_RequiresAdmin(t *testing.T) {
+	withFoleyCache(t, "thunder")
+	w := httptest.NewRecorder()
+	foleyAdminRouter(false).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/foley/cache/thunder", nil))
+	if w.Code != http.StatusForbidden || !fileExists(foleyClipPath("thunder")) {
+		t.Fatalf("non-admin evict = %d (clip kept: %v)", w.Code, fileExists(foleyClipPath("thunder")))
+	}
+}
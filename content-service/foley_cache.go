@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// Deterministic Foley placement. The events GPT proposes for a page (type +
+// trigger quote) are stored per exact page text, so re-running the Foley pass
+// — a reprocess, a retried merge, another book with the same text — reuses
+// the first placement instead of sampling a new one. Only the proposal is
+// cached: timestamps are re-derived from the quotes against the current
+// render, so a re-synthesized page still lines its effects up correctly.
+
+// FoleyPlacement is the cached event proposal for one page text.
+type FoleyPlacement struct {
+	ContentHash string `gorm:"primaryKey;size:64"`
+	Events      string `gorm:"type:text"` // JSON []foleyQuoteEvent; "[]" = no effects
+	CreatedAt   time.Time
+}
+
+// foleyTemperature is the sampling temperature for event extraction
+// (FOLEY_TEMPERATURE, default 0 — extraction wants the same answer every time).
+func foleyTemperature() float64 {
+	if v := os.Getenv("FOLEY_TEMPERATURE"); v != "" {
+		if t, err := strconv.ParseFloat(v, 64); err == nil && t >= 0 && t <= 2 {
+			return t
+		}
+	}
+	return 0
+}
+
+// cachedFoleyEvents returns the stored proposal for hash, if any.
+func cachedFoleyEvents(hash string) ([]foleyQuoteEvent, bool) {
+	var row FoleyPlacement
+	if err := db.Where("content_hash = ?", hash).First(&row).Error; err != nil || row.Events == "" {
+		return nil, false
+	}
+	var events []foleyQuoteEvent
+	if err := json.Unmarshal([]byte(row.Events), &events); err != nil {
+		return nil, false
+	}
+	return events, true
+}
+
+// storeFoleyEvents records the proposal for hash. The first writer wins;
+// extractSoundEvents re-reads after storing, so two pages racing on the same
+// text both use that one placement.
+func storeFoleyEvents(hash string, events []foleyQuoteEvent) {
+	if events == nil {
+		events = []foleyQuoteEvent{}
+	}
+	b, _ := json.Marshal(events)
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&FoleyPlacement{ContentHash: hash, Events: string(b)}).Error; err != nil {
+		log.Printf("⚠️ [Foley] caching placement %s failed: %v", hash[:8], err)
+	}
+}
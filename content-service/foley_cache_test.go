@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestFoleyTemperature(t *testing.T) {
+	for env, want := range map[string]float64{"": 0, "0.3": 0.3, "-1": 0, "5": 0, "warm": 0} {
+		t.Setenv("FOLEY_TEMPERATURE", env)
+		if got := foleyTemperature(); got != want {
+			t.Errorf("FOLEY_TEMPERATURE=%q: %v, want %v", env, got, want)
+		}
+	}
+}
+
+// The model is free to answer differently each time; the second run on the
+// same text must come from the cache and place effects identically.
+func TestExtractSoundEvents_IdenticalTextReusesPlacement(t *testing.T) {
+	withTestDB(t)
+	answers := [][]foleyQuoteEvent{
+		{{Type: "door_creak", Quote: "the door groaned open"}},
+		{{Type: "thunder", Quote: "Outside, the storm"}},
+	}
+	calls := 0
+	orig := proposeSoundEvents
+	proposeSoundEvents = func(context.Context, string, string) ([]foleyQuoteEvent, error) {
+		calls++
+		return answers[(calls-1)%len(answers)], nil
+	}
+	t.Cleanup(func() { proposeSoundEvents = orig })
+
+	text := "Outside, the storm rolled in. Slowly the door groaned open and she stepped inside."
+	first, err := extractSoundEvents(context.Background(), text, 12, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := extractSoundEvents(context.Background(), text, 12, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 || !reflect.DeepEqual(first, second) || len(first["door_creak"]) != 1 {
+		t.Fatalf("runs differ or re-asked the model (%d calls): %v vs %v", calls, first, second)
+	}
+
+	// Different text is a different placement.
+	if _, err := extractSoundEvents(context.Background(), text+" Thunder.", 12, "", nil); err != nil || calls != 2 {
+		t.Fatalf("new text: %v after %d calls, want a fresh proposal", err, calls)
+	}
+}
@@ -43,11 +43,11 @@ func FollowUserHandler(c *gin.Context) {
 
 	var req FollowRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id required"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "user_id required")
 		return
 	}
 	if req.UserID == followerID {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "You can't follow yourself"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "You can't follow yourself")
 		return
 	}
 
@@ -57,11 +57,11 @@ func FollowUserHandler(c *gin.Context) {
 		IsPublic bool
 	}
 	if err := db.Table("users").Select("id, is_public").Where("id = ?", req.UserID).Scan(&target).Error; err != nil || target.ID == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		respondError(c, http.StatusNotFound, errCodeNotFound, "User not found")
 		return
 	}
 	if !target.IsPublic {
-		c.JSON(http.StatusForbidden, gin.H{"error": "This profile is private"})
+		respondError(c, http.StatusForbidden, errCodeForbidden, "This profile is private")
 		return
 	}
 
@@ -71,7 +71,7 @@ func FollowUserHandler(c *gin.Context) {
 		FolloweeID: req.UserID,
 	})
 	if res.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not follow"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "Could not follow")
 		return
 	}
 	log.Printf("➕ user %d followed %d", followerID, req.UserID)
@@ -98,13 +98,13 @@ func UnfollowUserHandler(c *gin.Context) {
 
 	targetID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "invalid user id")
 		return
 	}
 
 	if err := db.Where("follower_id = ? AND followee_id = ?", followerID, uint(targetID)).
 		Delete(&Follow{}).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not unfollow"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "Could not unfollow")
 		return
 	}
 	log.Printf("➖ user %d unfollowed %d", followerID, targetID)
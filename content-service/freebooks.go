@@ -268,7 +268,7 @@ func ImportFreeBookHandler(c *gin.Context) {
 
 	var req ImportFreeBookRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "source and source_id required"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "source and source_id required")
 		return
 	}
 
@@ -276,12 +276,12 @@ func ImportFreeBookHandler(c *gin.Context) {
 	case "gutenberg":
 		id, err := strconv.ParseUint(req.SourceID, 10, 64)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid gutenberg id"})
+			respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "invalid gutenberg id")
 			return
 		}
 		var g GutenbergBook
 		if err := db.First(&g, uint(id)).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Book not found in the free catalog"})
+			respondError(c, http.StatusNotFound, errCodeBookNotFound, "Book not found in the free catalog")
 			return
 		}
 		importTextBook(c, userID, accountType, truncate(g.Title, 250), formatAuthor(g.Authors),
@@ -290,13 +290,13 @@ func ImportFreeBookHandler(c *gin.Context) {
 
 	case "archive":
 		if !archiveIdentifierRe.MatchString(req.SourceID) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid archive identifier"})
+			respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "invalid archive identifier")
 			return
 		}
 		title, author, textFile, err := fetchArchiveItemInfo(c.Request.Context(), req.SourceID)
 		if err != nil {
 			log.Printf("⚠️ freebooks: archive metadata failed for %q: %v", req.SourceID, err)
-			c.JSON(http.StatusBadGateway, gin.H{"error": "Couldn't fetch this book right now. Try again."})
+			respondError(c, http.StatusBadGateway, errCodeUpstream, "Couldn't fetch this book right now. Try again.")
 			return
 		}
 		importTextBook(c, userID, accountType, truncate(title, 250), author,
@@ -304,7 +304,7 @@ func ImportFreeBookHandler(c *gin.Context) {
 		log.Printf("📚 freebooks: user %d imported archive item %q", userID, req.SourceID)
 
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown source"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "unknown source")
 	}
 }
 
@@ -44,18 +44,10 @@ type GutenbergBook struct {
 	UpdatedAt   time.Time `json:"-"`
 }
 
-// initGutenbergCatalog migrates the table, ensures the search index, and
-// ingests the catalog if empty; then refreshes weekly. Call from the API
-// instance only (owns migrations). Non-blocking.
+// initGutenbergCatalog ingests the catalog if empty, then refreshes weekly.
+// The table and its search index come from migrations.go. Call from the API
+// instance only. Non-blocking.
 func initGutenbergCatalog() {
-	if err := db.AutoMigrate(&GutenbergBook{}); err != nil {
-		log.Printf("⚠️ gutenberg: migrate failed: %v", err)
-		return
-	}
-	// Full-text search index over title + authors (created once).
-	db.Exec(`CREATE INDEX IF NOT EXISTS idx_gutenberg_fts ON gutenberg_books
-	         USING GIN (to_tsvector('english', coalesce(title,'') || ' ' || coalesce(authors,'')))`)
-
 	go func() {
 		var count int64
 		db.Model(&GutenbergBook{}).Count(&count)
@@ -209,7 +201,7 @@ func SearchGutenbergHandler(c *gin.Context) {
 
 	rows, err := searchGutenbergBooks(q, limit, offset)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "search failed"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "search failed")
 		return
 	}
 
@@ -238,13 +230,13 @@ func ImportGutenbergHandler(c *gin.Context) {
 
 	var req ImportGutenbergRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "gutenberg_id required"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "gutenberg_id required")
 		return
 	}
 
 	var g GutenbergBook
 	if err := db.First(&g, req.GutenbergID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found in the free catalog"})
+		respondError(c, http.StatusNotFound, errCodeBookNotFound, "Book not found in the free catalog")
 		return
 	}
 
@@ -275,7 +267,7 @@ func importTextBook(c *gin.Context, userID uint, accountType, title, author stri
 	}
 	book.TTSEngine = defaultTTSEngine()
 	if err := db.Create(&book).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create book"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "could not create book")
 		return
 	}
 
@@ -283,7 +275,7 @@ func importTextBook(c *gin.Context, userID uint, accountType, title, author stri
 	text, err := fetchText()
 	if err != nil {
 		db.Model(&Book{}).Where("id = ?", book.ID).Update("status", "chunking_failed")
-		c.JSON(http.StatusBadGateway, gin.H{"error": "Couldn't download this book right now. Try again."})
+		respondError(c, http.StatusBadGateway, errCodeUpstream, "Couldn't download this book right now. Try again.")
 		return
 	}
 
@@ -291,7 +283,7 @@ func importTextBook(c *gin.Context, userID uint, accountType, title, author stri
 	tmp := filepath.Join(os.TempDir(), fmt.Sprintf("freebook_%d_%d.txt", userID, book.ID))
 	if err := os.WriteFile(tmp, []byte(text), 0o600); err != nil {
 		db.Model(&Book{}).Where("id = ?", book.ID).Update("status", "chunking_failed")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "storage error"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "storage error")
 		return
 	}
 	defer os.Remove(tmp)
@@ -299,23 +291,23 @@ func importTextBook(c *gin.Context, userID uint, accountType, title, author stri
 	key := uploadKey(userID, book.ID, ".txt")
 	if err := store.PutFile(c.Request.Context(), key, tmp, "text/plain"); err != nil {
 		db.Model(&Book{}).Where("id = ?", book.ID).Update("status", "chunking_failed")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "storage error"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "storage error")
 		return
 	}
 	db.Model(&Book{}).Where("id = ?", book.ID).Update("file_path", key)
 
 	// Consume the upload credit + kick off cover fetch and parsing.
 	checkAndConsume(userID, accountType, "uploads", 1, book.ID)
-	if err := enqueueFetchCover(book.ID, book.Title, book.Author); err != nil {
+	if err := enqueueFetchCover(book.ID, book.Title, book.Author, book.ISBN); err != nil {
 		log.Printf("⚠️ freebooks: cover enqueue failed for book %d: %v", book.ID, err)
 	}
 	if err := enqueueParseBook(book.ID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not queue parsing"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "could not queue parsing")
 		return
 	}
 
 	log.Printf("📚 freebooks: book %d created for user %d (%s)", book.ID, userID, book.Title)
-	c.JSON(http.StatusOK, gin.H{"message": "Added to your library", "book": book})
+	c.JSON(http.StatusOK, gin.H{"message": "Added to your library", "book": withCoverProxy(book)})
 }
 
 // fetchGutenbergText downloads the UTF-8 plain text and strips PG boilerplate.
@@ -30,12 +30,12 @@ func packageHLS(bookID uint, pageIndex int, finalAudio string) (string, error) {
 	defer cleanup()
 
 	playlist := filepath.Join(jobDir, "page.m3u8")
-	cmd := exec.Command("ffmpeg", "-y", "-i", src,
+	cmd := exec.Command(ffmpegBin, "-y", "-i", src,
 		"-c:a", "aac", "-b:a", "128k",
 		"-f", "hls", "-hls_time", "10", "-hls_playlist_type", "vod",
 		"-hls_segment_filename", filepath.Join(jobDir, "seg_%03d.ts"),
 		playlist)
-	if out, err := cmd.CombinedOutput(); err != nil {
+	if out, err := runFFmpegCmd(cmd); err != nil {
 		return "", fmt.Errorf("ffmpeg hls: %v\n%s", err, out)
 	}
 
@@ -67,19 +67,19 @@ func serveHLSHandler(c *gin.Context) {
 
 	var chunk BookChunk
 	if err := db.Where("book_id = ? AND \"index\" = ?", bookID, chunkIndex).First(&chunk).Error; err != nil || chunk.HLSPath == "" {
-		c.JSON(http.StatusNotFound, gin.H{"error": "HLS not available for this page"})
+		respondError(c, http.StatusNotFound, errCodeAudioNotReady, "HLS not available for this page")
 		return
 	}
 
 	tmp, err := os.CreateTemp("", "pl-*.m3u8")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "tmp"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "tmp")
 		return
 	}
 	tmp.Close()
 	defer os.Remove(tmp.Name())
 	if err := store.GetToFile(c.Request.Context(), chunk.HLSPath, tmp.Name()); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not load playlist"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "could not load playlist")
 		return
 	}
 	data, _ := os.ReadFile(tmp.Name())
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+// Impersonation tokens (issued by auth-service's POST
+// /admin/users/:user_id/impersonate) are ordinary user tokens carrying an
+// impersonated_by claim with the admin's ID. Support may look around as the
+// user, but must not destroy their data: the destructive routes refuse them
+// with denyImpersonated, as auth-service does for billing and deletion.
+
+// impersonatorID returns the admin behind an impersonation token, or 0 for a
+// token the user obtained themselves.
+func impersonatorID(c *gin.Context) uint {
+	claims, ok := c.Get("claims")
+	if !ok {
+		return 0
+	}
+	mc, ok := claims.(jwt.MapClaims)
+	if !ok {
+		return 0
+	}
+	if f, ok := mc["impersonated_by"].(float64); ok {
+		return uint(f)
+	}
+	return 0
+}
+
+// denyImpersonated rejects impersonation tokens. It runs after authMiddleware.
+func denyImpersonated() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if adminID := impersonatorID(c); adminID != 0 {
+			log.Printf("🚫 impersonation: admin %d blocked from %s %s", adminID, c.Request.Method, c.FullPath())
+			abortWithError(c, http.StatusForbidden, errCodeForbidden, "Not allowed while impersonating a user")
+			return
+		}
+		c.Next()
+	}
+}
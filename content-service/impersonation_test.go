@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+func TestDenyImpersonated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := func(claims jwt.MapClaims) *gin.Engine {
+		r := gin.New()
+		user := r.Group("/user", func(c *gin.Context) { c.Set("claims", claims) })
+		ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+		user.DELETE("/books/:book_id", denyImpersonated(), ok)
+		user.POST("/books/bulk-delete", denyImpersonated(), ok)
+		return r
+	}
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodDelete, "/user/books/3", nil),
+		httptest.NewRequest(http.MethodPost, "/user/books/bulk-delete", strings.NewReader(`{"book_ids":[3]}`)),
+	}
+
+	impersonating := router(jwt.MapClaims{"user_id": float64(5), "impersonated_by": float64(1)})
+	own := router(jwt.MapClaims{"user_id": float64(5)})
+	for _, req := range requests {
+		w := httptest.NewRecorder()
+		impersonating.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), `"`+errCodeForbidden+`"`) {
+			t.Errorf("%s %s while impersonating = %d %s, want 403", req.Method, req.URL.Path, w.Code, w.Body)
+		}
+		w = httptest.NewRecorder()
+		own.ServeHTTP(w, httptest.NewRequest(req.Method, req.URL.Path, nil))
+		if w.Code != http.StatusNoContent {
+			t.Errorf("%s %s with the user's own token = %d", req.Method, req.URL.Path, w.Code)
+		}
+	}
+}
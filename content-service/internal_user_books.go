@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GET /internal/users/:user_id/books — the per-book summary auth-service
+// archives into UserBookHistory when an account is deactivated or deleted,
+// and reads back on restore. Signed service calls only (serviceAuthMiddleware);
+// field names follow UserBookHistory so the caller can copy them straight
+// across.
+
+// internalBookSummary is one of the user's books with their playback state.
+// Progress fields are zero for books never played.
+type internalBookSummary struct {
+	BookID            uint       `json:"book_id"`
+	BookTitle         string     `json:"book_title"`
+	BookAuthor        string     `json:"book_author"`
+	Category          string     `json:"category"`
+	Genre             string     `json:"genre"`
+	AudioPath         string     `json:"audio_path"`
+	CoverURL          string     `json:"cover_url"`
+	CurrentPosition   float64    `json:"current_position"`
+	Duration          float64    `json:"duration"`
+	ChunkIndex        int        `json:"chunk_index"`
+	CompletionPercent float64    `json:"completion_percent"`
+	LastPlayedAt      *time.Time `json:"last_played_at"`
+}
+
+func internalUserBooksHandler(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid user_id")
+		return
+	}
+
+	var books []Book
+	if err := db.Select("id", "title", "author", "category", "genre", "audio_path", "cover_url").
+		Where("user_id = ?", userID).Order("id").Find(&books).Error; err != nil {
+		respondInternal(c, "Failed to fetch user books", err)
+		return
+	}
+	var progress []PlaybackProgress
+	if err := db.Where("user_id = ?", userID).Find(&progress).Error; err != nil {
+		respondInternal(c, "Failed to fetch playback progress", err)
+		return
+	}
+	byBook := make(map[uint]PlaybackProgress, len(progress))
+	for _, p := range progress {
+		byBook[p.BookID] = p
+	}
+
+	summaries := make([]internalBookSummary, 0, len(books))
+	for _, b := range books {
+		s := internalBookSummary{
+			BookID:     b.ID,
+			BookTitle:  b.Title,
+			BookAuthor: b.Author,
+			Category:   b.Category,
+			Genre:      b.Genre,
+			AudioPath:  b.AudioPath,
+			CoverURL:   b.CoverURL,
+		}
+		if p, ok := byBook[b.ID]; ok {
+			s.CurrentPosition = p.CurrentPosition
+			s.Duration = p.Duration
+			s.ChunkIndex = p.ChunkIndex
+			s.CompletionPercent = p.CompletionPercent
+			lastPlayed := p.LastPlayedAt
+			s.LastPlayedAt = &lastPlayed
+		}
+		summaries = append(summaries, s)
+	}
+	c.JSON(http.StatusOK, gin.H{"user_id": userID, "books": summaries})
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+func internalRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/internal/users/:user_id/books", serviceAuthMiddleware(), internalUserBooksHandler)
+	return r
+}
+
+func TestInternalUserBooks_RejectsUnsignedCalls(t *testing.T) {
+	t.Setenv("SERVICE_SECRET", "s3cret")
+	t.Setenv("JWT_SECRET", "x")
+	r := internalRouter()
+
+	// A user's own JWT is no substitute for the service signature.
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": float64(7)}).SignedString([]byte("x"))
+	req := httptest.NewRequest(http.MethodGet, "/internal/users/7/books", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("JWT-only call: got %d, want 401", w.Code)
+	}
+
+	// Signed with the wrong secret.
+	req = httptest.NewRequest(http.MethodGet, "/internal/users/7/books", nil)
+	signServiceRequest(req, "wrong", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("wrong secret: got %d, want 401", w.Code)
+	}
+}
+
+func TestInternalUserBooks_Summary(t *testing.T) {
+	withTestDB(t)
+	t.Setenv("SERVICE_SECRET", "s3cret")
+	played := Book{Title: "Dune", Author: "Frank Herbert", Category: "Fiction", Genre: "Sci-Fi", AudioPath: "audio/dune.mp3", UserID: 7}
+	unplayed := Book{Title: "Emma", Category: "Fiction", UserID: 7}
+	db.Create(&played)
+	db.Create(&unplayed)
+	db.Create(&Book{Title: "Someone else's", Category: "Fiction", UserID: 8})
+	db.Create(&PlaybackProgress{UserID: 7, BookID: played.ID, CurrentPosition: 120, Duration: 600, ChunkIndex: 2, CompletionPercent: 20})
+
+	req := httptest.NewRequest(http.MethodGet, "/internal/users/7/books", nil)
+	signServiceRequest(req, "s3cret", nil)
+	w := httptest.NewRecorder()
+	internalRouter().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("signed call: got %d %s", w.Code, w.Body)
+	}
+	var body struct {
+		Books []internalBookSummary `json:"books"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if len(body.Books) != 2 {
+		t.Fatalf("got %d books, want 2: %s", len(body.Books), w.Body)
+	}
+	got := body.Books[0]
+	if got.BookTitle != "Dune" || got.BookAuthor != "Frank Herbert" || got.Genre != "Sci-Fi" || got.AudioPath != "audio/dune.mp3" ||
+		got.CurrentPosition != 120 || got.ChunkIndex != 2 || got.CompletionPercent != 20 || got.LastPlayedAt == nil {
+		t.Errorf("played book summary = %+v", got)
+	}
+	if b := body.Books[1]; b.BookTitle != "Emma" || b.CurrentPosition != 0 || b.LastPlayedAt != nil {
+		t.Errorf("unplayed book summary = %+v", b)
+	}
+}
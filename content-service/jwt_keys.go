@@ -0,0 +1,71 @@
+package main
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// JWT verification. JWT_ALG must match auth-service's:
+//
+//	HS256 (default) — shared JWT_SECRET.
+//	RS256           — verify with auth-service's public key only
+//	                  (JWT_PUBLIC_KEY PEM, or a path in JWT_PUBLIC_KEY_FILE),
+//	                  so this service can check tokens but never mint them.
+//
+// The configured algorithm is pinned: tokens with any other alg are rejected.
+var jwtAlg = strings.ToUpper(getEnv("JWT_ALG", "HS256"))
+
+var jwtSecretKey, jwtPublicKey = loadJWTVerifyKeys(jwtAlg)
+
+// loadJWTVerifyKeys reads the verification key for alg, exiting on a missing
+// or malformed key.
+func loadJWTVerifyKeys(alg string) ([]byte, *rsa.PublicKey) {
+	switch alg {
+	case "HS256":
+		return []byte(mustEnv("JWT_SECRET")), nil
+	case "RS256":
+		pemBytes, err := pemFromEnv("JWT_PUBLIC_KEY")
+		if err != nil {
+			log.Fatalf("FATAL: JWT_ALG=RS256: %v", err)
+		}
+		key, err := jwt.ParseRSAPublicKeyFromPEM(pemBytes)
+		if err != nil {
+			log.Fatalf("FATAL: JWT_ALG=RS256: invalid JWT_PUBLIC_KEY: %v", err)
+		}
+		return nil, key
+	default:
+		log.Fatalf("FATAL: unsupported JWT_ALG %q (want HS256 or RS256)", alg)
+		return nil, nil
+	}
+}
+
+// pemFromEnv returns a PEM block from NAME (literal "\n" escapes allowed, for
+// single-line .env files) or from the file named by NAME_FILE.
+func pemFromEnv(name string) ([]byte, error) {
+	if v := os.Getenv(name); v != "" {
+		return []byte(strings.ReplaceAll(v, `\n`, "\n")), nil
+	}
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		return os.ReadFile(path)
+	}
+	return nil, fmt.Errorf("%s or %s_FILE must be set", name, name)
+}
+
+// jwtKeyFunc is the jwt.Parse key function: it returns the verification key
+// for the configured algorithm and rejects any token signed with another
+// (alg=none, HS256 signed with the public key as a secret, or a stale HS256
+// token after switching to RS256).
+func jwtKeyFunc(token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() != jwtAlg {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	if jwtAlg == "RS256" {
+		return jwtPublicKey, nil
+	}
+	return jwtSecretKey, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func TestJWTKeyFunc_RS256(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	origAlg, origPub := jwtAlg, jwtPublicKey
+	defer func() { jwtAlg, jwtPublicKey = origAlg, origPub }()
+	jwtAlg, jwtPublicKey = "RS256", &priv.PublicKey
+
+	claims := jwt.MapClaims{"user_id": 7, "exp": time.Now().Add(time.Hour).Unix()}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok, err := jwt.Parse(signed, jwtKeyFunc); err != nil || !tok.Valid {
+		t.Fatalf("RS256 token rejected: %v", err)
+	}
+
+	// HS256 tokens are refused once RS256 is configured — both one signed
+	// with the old shared secret and the classic key-confusion forgery that
+	// uses the public key PEM as an HMAC secret.
+	pubDER, _ := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	pubPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+	for name, secret := range map[string][]byte{"shared secret": []byte("x"), "public key as secret": pubPEM} {
+		hs, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+		if _, err := jwt.Parse(hs, jwtKeyFunc); err == nil {
+			t.Errorf("HS256 token (%s) accepted under RS256", name)
+		}
+	}
+}
+
+func TestJWTKeyFunc_HS256RejectsOtherAlgs(t *testing.T) {
+	origAlg, origKey := jwtAlg, jwtSecretKey
+	defer func() { jwtAlg, jwtSecretKey = origAlg, origKey }()
+	jwtAlg, jwtSecretKey = "HS256", []byte("s3cret")
+
+	claims := jwt.MapClaims{"user_id": 7}
+	good, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("s3cret"))
+	if _, err := jwt.Parse(good, jwtKeyFunc); err != nil {
+		t.Fatalf("HS256 token rejected: %v", err)
+	}
+	none, _ := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if _, err := jwt.Parse(none, jwtKeyFunc); err == nil {
+		t.Error("alg=none token accepted")
+	}
+}
@@ -2,9 +2,9 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -23,9 +23,6 @@ import (
 // Global variables
 var db *gorm.DB
 
-// Use the JWT secret from an environment variable.
-var jwtSecretKey = []byte(mustEnv("JWT_SECRET"))
-
 // mustEnv returns the env var value or exits — services must never run
 // with a default/guessable secret.
 func mustEnv(key string) string {
@@ -36,11 +33,6 @@ func mustEnv(key string) string {
 	return v
 }
 
-// Allowed categories for validation
-// Allowed book categories — keep in sync with the iOS Upload screen's `categories`
-// list (UploadBookView.swift). Matched case-insensitively (isValidCategory).
-var allowedCategories = []string{"Fiction", "Non-fiction", "Poetry", "Children's", "Young Adult", "Classics", "Drama", "Comics & Graphic Novels", "Anthology", "Reference"}
-
 // Book represents the model for a book uploaded by a user.
 type Book struct {
 	ID          uint   `gorm:"primaryKey"`
@@ -60,6 +52,14 @@ type Book struct {
 	ScorePalette string `gorm:"type:text"` // JSON []ScoreCue — per-book music palette (audit H2)
 	AudioProfile string `gorm:"type:text"`
 	TTSEngine    string `gorm:"size:32"` // voice engine pinned at creation ("openai"|"kokoro"; empty = openai) // JSON AudioProfile — fiction/genre/era (audit H3)
+	AudioFormat  string `gorm:"size:8"`  // per-book output override ("mp3"|"opus"|"aac"; empty = AUDIO_FORMAT)
+	ISBN         string `gorm:"size:13"` // optional, normalized (digits only); exact Open Library cover lookup
+	NeedsReprocess bool `gorm:"default:false"` // an audio setting changed; pages are regenerating (reprocess.go)
+	FoleyVolume    *float64 // per-book Foley level override (0–1; nil = FOLEY_VOLUME)
+	EffectsSkipped bool `gorm:"default:false"` // some pages are plain narration: the sound API was unavailable
+	CoverAttempts  int  `gorm:"default:0"`     // background cover-fetch retries so far (cover_retry.go)
+	PageCount      int  `gorm:"default:0"`     // final page count, set once chunking completes (0 = chunking or not yet recorded)
+	ProcessedAt    *time.Time // when transcription last finished (status → completed); nil before that
 	Index       int    // Index of the book in the list
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
@@ -67,10 +67,12 @@ type Book struct {
 
 // BookRequest defines the expected JSON structure for creating a book.
 type BookRequest struct {
-	Title    string `json:"title" binding:"required"`
-	Author   string `json:"author"`
-	Category string `json:"category" binding:"required"`
-	Genre    string `json:"genre"`
+	Title       string `json:"title" binding:"required"`
+	Author      string `json:"author"`
+	Category    string `json:"category" binding:"required"`
+	Genre       string `json:"genre"`
+	AudioFormat string `json:"audio_format"` // optional: mp3|opus|aac
+	ISBN        string `json:"isbn"`         // optional: ISBN-10 or ISBN-13, hyphens allowed
 }
 
 // Chunk represents the model for chunks or segments of boook
@@ -87,6 +89,7 @@ type BookChunk struct {
 	FinalAudioPath string `json:"final_audio_path"` // 👈 New field
 	HLSPath        string `json:"hls_path"`         // R2 key of the HLS playlist (Phase 5C)
 	TimingMap      string `gorm:"type:text" json:"-"` // segment rune-span → seconds table (audit 2B)
+	Transcript     string `gorm:"type:text" json:"-"` // JSON pageTranscript: the text actually narrated (transcript.go)
 	TTSStatus      string // values: "pending", "processing", "completed", "failed"
 	StartTime      int64  // Start time in seconds
 	EndTime        int64  // End time in seconds
@@ -104,19 +107,21 @@ type TTSQueueJob struct {
 	UserID    uint `gorm:"index"`
 }
 type BookResponse struct {
-	ID          uint   `json:"id"`
-	Title       string `json:"title"`
-	Author      string `json:"author"`
-	Category    string `json:"category"`
-	Content     string `json:"content,omitempty"` // Optional, can be omitted for public response
-	ContentHash string `json:"content_hash"`
-	Genre       string `json:"genre"`
-	FilePath    string `json:"file_path"`
-	AudioPath   string `json:"audio_path"`
-	Status      string `json:"status"`
-	StreamURL   string `json:"stream_url"`
-	CoverURL    string `json:"cover_url"`
-	CoverPath   string `json:"cover_path"`
+	ID             uint   `json:"id"`
+	Title          string `json:"title"`
+	Author         string `json:"author"`
+	Category       string `json:"category"`
+	Content        string `json:"content,omitempty"` // Optional, can be omitted for public response
+	ContentHash    string `json:"content_hash"`
+	Genre          string `json:"genre"`
+	FilePath       string `json:"file_path"`
+	AudioPath      string `json:"audio_path"`
+	Status         string `json:"status"`
+	StreamURL      string `json:"stream_url"`
+	CoverURL       string `json:"cover_url"`
+	CoverPath      string `json:"cover_path"`
+	CoverProxyURL  string `json:"cover_proxy_url,omitempty"` // remote covers only (cover_proxy.go)
+	EffectsSkipped bool   `json:"effects_skipped"`           // some pages were rendered without music/Foley
 }
 
 func main() {
@@ -137,6 +142,11 @@ func main() {
 	}
 	log.Println("✅ Media store (R2) initialized")
 
+	// Every mode shells out to ffmpeg/ffprobe (merges, HLS, durations).
+	if err := initFFmpeg(); err != nil {
+		log.Fatalf("FATAL: %v", err)
+	}
+
 	// MQTT initialization
 	go InitMQTT()
 
@@ -154,6 +164,9 @@ func main() {
 	// the worker sends pushes; the API registers device tokens.
 	initAPNs()
 
+	// Book categories from BOOK_CATEGORIES (defaults if unset).
+	initBookCategories()
+
 	// RUN_MODE selects the role: api (HTTP only), worker (asynq consumer only),
 	// or both (default — local dev).
 	mode := getEnv("RUN_MODE", "both")
@@ -172,6 +185,9 @@ func main() {
 		}()
 	}
 
+	// Chunked upload parts live on the API host; sweep abandoned sessions here.
+	go chunkedUploadJanitorLoop()
+
 	// Prometheus collectors (asynq queue metrics from Redis).
 	if err := initMetrics(); err != nil {
 		log.Printf("⚠️ metrics init failed: %v", err)
@@ -179,12 +195,20 @@ func main() {
 
 	// Initialize Gin router.
 	router := gin.Default()
+	router.Use(requestIDMiddleware(), bodyLimitMiddleware())
 
 	// Health check/root response
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok", "service": "content-service"})
 	})
 
+	// Book categories (public: clients build their pickers from it).
+	router.GET("/categories", listCategoriesHandler)
+
+	// Voice picker: the voice list and a sample clip of each (voice_catalog.go).
+	router.GET("/voices", listVoicesHandler)
+	router.GET("/voices/:voice/sample", voiceSampleHandler)
+
 	// Prometheus scrape endpoint.
 	router.GET("/metrics", metricsHandler())
 
@@ -200,7 +224,22 @@ func main() {
 
 	// Static cover files: intentionally public (book covers are not paid
 	// content, and the iOS app loads cover_url without an auth header).
-	router.Static("/covers", "./uploads/covers")
+	// /covers/proxy shares the prefix, so dispatch it by hand (gin won't mix a
+	// static catch-all with a fixed sibling route).
+	coverFiles := http.StripPrefix("/covers", http.FileServer(gin.Dir("./uploads/covers", false)))
+	serveCovers := func(c *gin.Context) {
+		if c.Param("filepath") == "/proxy" {
+			coverProxyHandler(c)
+			return
+		}
+		coverFiles.ServeHTTP(c.Writer, c.Request)
+	}
+	router.GET("/covers/*filepath", serveCovers)
+	router.HEAD("/covers/*filepath", serveCovers)
+
+	// Shared book audio: public by design, gated by the signed share token
+	// (see share_links.go) rather than a JWT.
+	router.GET("/shared/:share_token/stream", sharedStreamHandler)
 
 	// Calling Streaming Route outside of the authorized group
 	// router.GET("/user/books/stream/proxy/:id", proxyBookAudioHandler)
@@ -208,114 +247,15 @@ func main() {
 	// Protected routes group.
 	authorized := router.Group("/user")
 	authorized.Use(authMiddleware())
-	{ // handles book creation, listing, and file uploads
-		// SECURITY (S6): every route that targets a specific :book_id is gated
-		// by requireBookOwnership() so a user can only act on their own books.
-		// Routes that take book_id in the body/form (upload, /chunks/tts,
-		// /chunks/audio-by-id) verify ownership inline in their handlers.
-		// Register this device's APNs token for push notifications.
-		authorized.POST("/device-token", RegisterDeviceTokenHandler)
-		// Monthly fresh-transcription budget for the current user (app shows
-		// "X hrs of new transcription left" + drives the upgrade prompt).
-		authorized.GET("/transcription-usage", transcriptionUsageHandler)
-
-		// User-submitted bug/problem report from the app.
-		authorized.POST("/bug-report", SubmitBugReportHandler)
-
-		// Remote config: feature flags, copy, colors, displayed pricing, and the
-		// min-supported-build version gate. Resolved per-tier from the JWT
-		// account_type claim; editable live via SQL (no redeploy). NOTE: needs an
-		// explicit nginx `location /user/config` → 8083 or it 404s (auth-service).
-		authorized.GET("/config", getUserConfigHandler)
-
-		// Casting: record when a user sends playback to an external output
-		// (AirPlay/Bluetooth/Chromecast). Needs an explicit nginx location → 8083.
-		authorized.POST("/cast-events", RecordCastEventHandler)
-
-		authorized.POST("/books/:book_id/cover", requireBookOwnership(), uploadBookCoverHandler)
-
-		// Create a new book
-		authorized.POST("/books", createBookHandler)
-		// List all books for the authenticated user
-		authorized.GET("/books", listBooksHandler)
-
-		// Upload a book file
-		authorized.POST("/books/upload", uploadBookFileHandler)
-		// List all chunks for a book
-		authorized.GET("/books/:book_id/chunks/pages", requireBookOwnership(), listBookPagesHandler) // New handler for listing book pages
-		// authorized.GET("/books/stream/proxy/:id", proxyBookAudioHandler)
-
-		authorized.GET("/books/stream/proxy/:book_id", proxyBookAudioHandler)
-		authorized.POST("/chunks/tts", ProcessChunksTTSHandler)
-		authorized.GET("/chunks/tts/merged-audio/:book_id", requireBookOwnership(), streamMergedChunkAudioHandler)
-		authorized.GET("/books/:book_id/chunks/:start/:end/audio", requireBookOwnership(), streamChunkGroupAudioHandler)
-		//authorized.GET("/chunks/status", checkChunkQueueStatusHandler)
-
-		//Batch Transcribe Book Page-by-Page (Sequentially)
-		authorized.POST("/books/:book_id/tts/batch", requireBookOwnership(), BatchTranscribeBookHandler)
-		// processing old chunks
-		authorized.GET("/books/:book_id/chunks/processed", requireBookOwnership(), listProcessedChunkGroupsHandler)
-		// stream audio by chunk IDs
-		authorized.POST("/chunks/audio-by-id", streamAudioByChunkIDsHandler)
-
-		// adding a new route to delate a book by ID or title
-		authorized.DELETE("/books/:book_id", requireBookOwnership(), deleteBookHandler)
-
-		// adding a new route to pull one book by ID
-		authorized.GET("/books/:book_id", requireBookOwnership(), getSingleBookHandler)
-
-		// Presigned direct-to-R2 upload (Phase 3): client uploads the file
-		// straight to R2, server only mints the URL + parses on completion.
-		authorized.POST("/books/:book_id/upload/initiate", requireBookOwnership(), initiateUploadHandler)
-		authorized.POST("/books/:book_id/upload/complete", requireBookOwnership(), completeUploadHandler)
-
-		// adding a route to pull audio and backgrond music for a book
-		authorized.GET("/books/:book_id/pages/:page/audio", requireBookOwnership(), streamSinglePageAudioHandler)
-		// HLS playlist for a page (Phase 5C) — segments served direct from R2.
-		authorized.GET("/books/:book_id/pages/:page/hls.m3u8", requireBookOwnership(), serveHLSHandler)
-		// HEAD probe (client decides HLS vs MP3). Gin won't serve HEAD on the GET
-		// route, so register it explicitly or HLS is never used on-device.
-		authorized.HEAD("/books/:book_id/pages/:page/hls.m3u8", requireBookOwnership(), headHLSHandler)
-
-		// Book search/discovery endpoint - AI-powered book suggestions
-		authorized.POST("/search-books", SearchBooksHandler)
-
-		// Book cover search and selection endpoints
-		authorized.POST("/search-book-covers", SearchBookCoversHandler)
-		authorized.POST("/books/:book_id/select-cover", SelectBookCoverHandler)
-
-		// Playback progress tracking endpoints
-		authorized.POST("/books/:book_id/progress", UpdatePlaybackProgressHandler)   // Update progress
-		authorized.GET("/books/:book_id/progress", GetPlaybackProgressHandler)       // Get progress for a book
-		authorized.GET("/progress", GetAllPlaybackProgressHandler)                   // Get all progress for user
-		authorized.DELETE("/books/:book_id/progress", DeletePlaybackProgressHandler) // Reset progress for a book
-
-		// Listening statistics endpoints
-		authorized.GET("/stats/most-played", GetMostPlayedBooksHandler) // Get most played books
-		authorized.GET("/stats/by-genre", GetStatsByGenreHandler)       // Get stats grouped by genre
-
-		// Social discovery (Home sections). NOTE: needs an nginx
-		// location /user/discover → :8083 like every content /user/* route.
-		authorized.GET("/discover/state", DiscoverByStateHandler)        // public users in the caller's state
-		authorized.POST("/discover/contacts", DiscoverContactsHandler)   // on-device-hashed contact matching
-
-		// Free books (Project Gutenberg catalog). NOTE: needs an nginx
-		// location /user/gutenberg → :8083.
-		authorized.GET("/gutenberg/search", SearchGutenbergHandler)   // search the free catalog (legacy, build ≤16)
-		authorized.POST("/gutenberg/import", ImportGutenbergHandler)  // import a free book → audiobook (legacy, build ≤16)
-
-		// Unified free books (Gutenberg + Internet Archive). NOTE: needs an
-		// nginx location /user/freebooks → :8083.
-		authorized.GET("/freebooks/search", SearchFreeBooksHandler)  // merged multi-source search
-		authorized.POST("/freebooks/import", ImportFreeBookHandler)  // import {source, source_id}
-
-		// Follow graph
-		authorized.POST("/follow", FollowUserHandler)              // follow {user_id}
-		authorized.DELETE("/follow/:user_id", UnfollowUserHandler) // unfollow
-		authorized.GET("/following", ListFollowingHandler)         // people I follow
-		authorized.GET("/followers", ListFollowersHandler)         // people who follow me
-		authorized.GET("/follow/counts", FollowCountsHandler)      // {following, followers}
+	registerUserRoutes(authorized)
 
+	// Service-to-service routes: HMAC-signed by the caller (service_auth.go),
+	// never reachable with a user JWT.
+	internal := router.Group("/internal")
+	internal.Use(serviceAuthMiddleware())
+	{
+		internal.GET("/users/:user_id/books", internalUserBooksHandler)
+		internal.DELETE("/users/:user_id/content", internalPurgeUserContentHandler)
 	}
 
 	// Admin routes group
@@ -328,6 +268,11 @@ func main() {
 		admin.GET("/bug-reports", ListBugReportsHandler)
 		admin.POST("/gutenberg/refresh", RefreshGutenbergHandler)
 		admin.POST("/gc/shared-audio", gcSharedAudioHandler)
+		admin.GET("/content-stats", getContentStatsHandler)
+		admin.GET("/foley/cache", listFoleyCacheHandler)
+		admin.DELETE("/foley/cache/:event_type", evictFoleyCacheHandler)
+		admin.GET("/books/stuck", listStuckBooksHandler)
+		admin.POST("/books/:book_id/reprocess", reprocessStuckBookHandler)
 	}
 
 	for _, r := range router.Routes() {
@@ -348,7 +293,157 @@ func main() {
 	}
 }
 
-// setupDatabase connects to PostgreSQL and auto migrates the Book model.
+// registerUserRoutes wires the authenticated /user API onto authorized:
+// book creation, listing and file uploads, playback, discovery and follows.
+func registerUserRoutes(authorized *gin.RouterGroup) {
+	// SECURITY (S6): every route that targets a specific :book_id is gated
+	// by requireBookOwnership() so a user can only act on their own books.
+	// A missing book and someone else's book both answer 404 book_not_found
+	// (never 403), so book IDs can't be enumerated; only /admin uses 403.
+	// Routes that take book_id in the body/form (upload, /chunks/tts,
+	// /chunks/audio-by-id) verify ownership inline in their handlers.
+	// Register this device's APNs token for push notifications.
+	authorized.POST("/device-token", RegisterDeviceTokenHandler)
+	// Monthly fresh-transcription budget for the current user (app shows
+	// "X hrs of new transcription left" + drives the upgrade prompt).
+	authorized.GET("/transcription-usage", transcriptionUsageHandler)
+
+	// User-submitted bug/problem report from the app.
+	authorized.POST("/bug-report", SubmitBugReportHandler)
+
+	// Remote config: feature flags, copy, colors, displayed pricing, and the
+	// min-supported-build version gate. Resolved per-tier from the JWT
+	// account_type claim; editable live via SQL (no redeploy). NOTE: needs an
+	// explicit nginx `location /user/config` → 8083 or it 404s (auth-service).
+	authorized.GET("/config", getUserConfigHandler)
+
+	// Casting: record when a user sends playback to an external output
+	// (AirPlay/Bluetooth/Chromecast). Needs an explicit nginx location → 8083.
+	authorized.POST("/cast-events", RecordCastEventHandler)
+
+	authorized.POST("/books/:book_id/cover", requireBookOwnership(), uploadBookCoverHandler)
+
+	// Create a new book
+	authorized.POST("/books", createBookHandler)
+	// List all books for the authenticated user
+	authorized.GET("/books", listBooksHandler)
+
+	// Upload a book file
+	authorized.POST("/books/upload", uploadBookFileHandler)
+	// List all chunks for a book
+	authorized.GET("/books/:book_id/chunks/pages", requireBookOwnership(), listBookPagesHandler) // New handler for listing book pages
+	// authorized.GET("/books/stream/proxy/:id", proxyBookAudioHandler)
+
+	authorized.GET("/books/stream/proxy/:book_id", requireBookOwnership(), proxyBookAudioHandler)
+	authorized.POST("/chunks/tts", ProcessChunksTTSHandler)
+	authorized.GET("/chunks/tts/merged-audio/:book_id", requireBookOwnership(), streamMergedChunkAudioHandler)
+	authorized.GET("/books/:book_id/chunks/:start/:end/audio", requireBookOwnership(), streamChunkGroupAudioHandler)
+	// Join every finished page into one whole-book file (book_compile.go)
+	authorized.POST("/books/:book_id/compile", requireBookOwnership(), compileBookAudioHandler)
+	//authorized.GET("/chunks/status", checkChunkQueueStatusHandler)
+
+	//Batch Transcribe Book Page-by-Page (Sequentially)
+	authorized.POST("/books/:book_id/tts/batch", requireBookOwnership(), BatchTranscribeBookHandler)
+	authorized.GET("/books/:book_id/tts/batch", requireBookOwnership(), transcriptionStatusHandler)
+	// Voice sample before committing to the whole book (tts_preview.go)
+	authorized.POST("/books/:book_id/preview", requireBookOwnership(), previewBookHandler)
+	// Public share links: mint, list and revoke; the link itself is served by
+	// the unauthenticated /shared/:share_token/stream route.
+	authorized.POST("/books/:book_id/share", requireBookOwnership(), createBookShareHandler)
+	authorized.GET("/books/:book_id/shares", requireBookOwnership(), listBookSharesHandler)
+	authorized.DELETE("/books/:book_id/shares/:share_id", denyImpersonated(), requireBookOwnership(), revokeBookShareHandler)
+	// Self-service storage: usage breakdown + drop a book's generated audio
+	authorized.GET("/storage", userStorageHandler)
+	authorized.DELETE("/books/:book_id/audio", denyImpersonated(), requireBookOwnership(), deleteBookAudioHandler)
+	// processing old chunks
+	authorized.GET("/books/:book_id/chunks/processed", requireBookOwnership(), listProcessedChunkGroupsHandler)
+	// stream audio by chunk IDs
+	authorized.POST("/chunks/audio-by-id", streamAudioByChunkIDsHandler)
+
+	// adding a new route to delate a book by ID or title
+	authorized.DELETE("/books/:book_id", denyImpersonated(), requireBookOwnership(), deleteBookHandler)
+	authorized.POST("/books/bulk-delete", denyImpersonated(), bulkDeleteBooksHandler)
+
+	// adding a new route to pull one book by ID
+	authorized.GET("/books/:book_id", requireBookOwnership(), getSingleBookHandler)
+	// Find a word or phrase anywhere in a book (book_text_search.go)
+	authorized.GET("/books/:book_id/search", requireBookOwnership(), searchBookTextHandler)
+	// Correct title/author/genre/category in place (no delete + re-upload).
+	authorized.PATCH("/books/:book_id", requireBookOwnership(), updateBookHandler)
+
+	// Presigned direct-to-R2 upload (Phase 3): client uploads the file
+	// straight to R2, server only mints the URL + parses on completion.
+	authorized.POST("/books/:book_id/upload/initiate", requireBookOwnership(), initiateUploadHandler)
+	authorized.POST("/books/:book_id/upload/complete", requireBookOwnership(), completeUploadHandler)
+	// Resumable chunked upload through the API (chunked_upload.go) for
+	// clients on flaky connections: init, PUT parts, complete.
+	authorized.POST("/books/:book_id/upload/chunked", requireBookOwnership(), initChunkedUploadHandler)
+	authorized.GET("/books/:book_id/upload/chunked/:upload_id", requireBookOwnership(), chunkedUploadStatusHandler)
+	authorized.PUT("/books/:book_id/upload/chunked/:upload_id/parts/:part", requireBookOwnership(), uploadPartHandler)
+	authorized.POST("/books/:book_id/upload/chunked/:upload_id/complete", requireBookOwnership(), completeChunkedUploadHandler)
+	authorized.DELETE("/books/:book_id/upload/chunked/:upload_id", denyImpersonated(), requireBookOwnership(), abortChunkedUploadHandler)
+
+	// adding a route to pull audio and backgrond music for a book
+	authorized.GET("/books/:book_id/pages/:page/audio", requireBookOwnership(), streamSinglePageAudioHandler)
+	// Captions / read-along: the text the page audio was narrated from
+	authorized.GET("/books/:book_id/pages/:page/transcript", requireBookOwnership(), pageTranscriptHandler)
+	// Pages :page..:end (1-based, inclusive) joined into one stream (page_range_stream.go)
+	authorized.GET("/books/:book_id/pages/:page/:end/stream", requireBookOwnership(), streamPageRangeHandler)
+	// HLS playlist for a page (Phase 5C) — segments served direct from R2.
+	authorized.GET("/books/:book_id/pages/:page/hls.m3u8", requireBookOwnership(), serveHLSHandler)
+	// HEAD probe (client decides HLS vs MP3). Gin won't serve HEAD on the GET
+	// route, so register it explicitly or HLS is never used on-device.
+	authorized.HEAD("/books/:book_id/pages/:page/hls.m3u8", requireBookOwnership(), headHLSHandler)
+	// What happened while the book was processed, newest first (processing_log.go)
+	authorized.GET("/books/:book_id/processing-log", requireBookOwnership(), processingLogHandler)
+	// Re-run music + Foley on a page's existing narration, no new TTS (page_effects.go)
+	authorized.POST("/books/:book_id/pages/:page/effects", requireBookOwnership(), applyPageEffectsHandler)
+
+	// Book search/discovery endpoint - AI-powered book suggestions
+	authorized.POST("/search-books", SearchBooksHandler)
+
+	// Book cover search and selection endpoints
+	authorized.POST("/search-book-covers", SearchBookCoversHandler)
+	authorized.POST("/books/:book_id/select-cover", requireBookOwnership(), SelectBookCoverHandler)
+
+	// Playback progress tracking endpoints
+	authorized.POST("/books/:book_id/progress", requireBookOwnership(), UpdatePlaybackProgressHandler)   // Update progress
+	authorized.GET("/books/:book_id/progress", requireBookOwnership(), GetPlaybackProgressHandler)       // Get progress for a book
+	authorized.POST("/books/:book_id/finish", requireBookOwnership(), finishBookHandler)                 // Mark finished (book_finish.go)
+	authorized.POST("/books/:book_id/unfinish", requireBookOwnership(), unfinishBookHandler)             // Reset to unstarted
+	authorized.GET("/progress", GetAllPlaybackProgressHandler)                                           // Get all progress for user
+	authorized.DELETE("/books/:book_id/progress", denyImpersonated(), requireBookOwnership(), DeletePlaybackProgressHandler) // Reset progress for a book
+	authorized.POST("/progress/sync", SyncPlaybackProgressHandler)                                       // Batch sync from offline clients
+
+	// Listening statistics endpoints
+	authorized.GET("/stats/most-played", GetMostPlayedBooksHandler) // Get most played books
+	authorized.GET("/stats/by-genre", GetStatsByGenreHandler)       // Get stats grouped by genre
+	authorized.GET("/stats/summary", GetStatsSummaryHandler)        // Listening dashboard (totals, streak)
+
+	// Social discovery (Home sections). NOTE: needs an nginx
+	// location /user/discover → :8083 like every content /user/* route.
+	authorized.GET("/discover/state", DiscoverByStateHandler)        // public users in the caller's state
+	authorized.POST("/discover/contacts", DiscoverContactsHandler)   // on-device-hashed contact matching
+
+	// Free books (Project Gutenberg catalog). NOTE: needs an nginx
+	// location /user/gutenberg → :8083.
+	authorized.GET("/gutenberg/search", SearchGutenbergHandler)   // search the free catalog (legacy, build ≤16)
+	authorized.POST("/gutenberg/import", ImportGutenbergHandler)  // import a free book → audiobook (legacy, build ≤16)
+
+	// Unified free books (Gutenberg + Internet Archive). NOTE: needs an
+	// nginx location /user/freebooks → :8083.
+	authorized.GET("/freebooks/search", SearchFreeBooksHandler)  // merged multi-source search
+	authorized.POST("/freebooks/import", ImportFreeBookHandler)  // import {source, source_id}
+
+	// Follow graph
+	authorized.POST("/follow", FollowUserHandler)              // follow {user_id}
+	authorized.DELETE("/follow/:user_id", denyImpersonated(), UnfollowUserHandler) // unfollow
+	authorized.GET("/following", ListFollowingHandler)         // people I follow
+	authorized.GET("/followers", ListFollowersHandler)         // people who follow me
+	authorized.GET("/follow/counts", FollowCountsHandler)      // {following, followers}
+}
+
+// setupDatabase connects to PostgreSQL and applies pending migrations.
 func setupDatabase() {
 	dbHost := getEnv("DB_HOST", "")
 	dbUser := getEnv("DB_USER", "")
@@ -374,15 +469,22 @@ func setupDatabase() {
 
 	log.Printf("Connected to database host=%s dbname=%s sslmode=%s", dbHost, dbName, sslMode)
 
-	// Only the API owns schema migrations. Workers skip AutoMigrate so a
-	// co-deploy doesn't race two concurrent CREATE TABLEs (Postgres DDL race).
-	if getEnv("RUN_MODE", "both") != "worker" {
-		if err := db.AutoMigrate(&Book{}, &BookChunk{}, &ProcessedChunkGroup{}, &TTSQueueJob{}, &PlaybackProgress{}, &TranscriptionBatch{}, &PlanLimit{}, &UsageEvent{}, &DeviceToken{}, &BugReport{}, &AppConfig{}, &CastEvent{}, &Follow{}, &RenderedPage{}); err != nil {
-			log.Fatalf("AutoMigrate failed: %v", err)
+	// Only the API owns schema migrations (migrations.go); workers skip them.
+	// RUN_MODE=migrate-down reverts the latest migration and exits.
+	switch getEnv("RUN_MODE", "both") {
+	case "worker":
+	case "migrate-down":
+		if err := rollbackLastMigration(db, contentMigrations); err != nil {
+			log.Fatalf("Rollback failed: %v", err)
+		}
+		os.Exit(0)
+	default:
+		if err := runMigrations(db, contentMigrations); err != nil {
+			log.Fatalf("Migrations failed: %v", err)
 		}
 		seedPlanLimits()
 		seedAppConfig()
-		initGutenbergCatalog() // migrate + ingest the free-books catalog (async)
+		initGutenbergCatalog() // ingest the free-books catalog (async)
 	}
 	log.Println("Database connected and migrated successfully")
 }
@@ -391,32 +493,48 @@ func createBookHandler(c *gin.Context) {
 	var req BookRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.Printf("Error in book request binding: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book data", "details": err.Error()})
+		respondErrorWith(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid book data", gin.H{"details": err.Error()})
 		return
 	}
 
 	if !isValidCategory(req.Category) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category", "allowed_categories": allowedCategories})
+		respondErrorWith(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid category", gin.H{"allowed_categories": allowedCategories()})
+		return
+	}
+	if !isValidAudioFormat(req.AudioFormat) {
+		respondErrorWith(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid audio_format", gin.H{"allowed_formats": []string{"mp3", "opus", "aac"}})
+		return
+	}
+	isbn := normalizeISBN(req.ISBN)
+	if req.ISBN != "" && isbn == "" {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid isbn")
 		return
 	}
 
 	claims, exists := c.Get("claims")
 	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Authentication claims missing"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "Authentication claims missing")
 		return
 	}
 	userClaims, ok := claims.(jwt.MapClaims)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid token claims"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "Invalid token claims")
 		return
 	}
 	userIDFloat, ok := userClaims["user_id"].(float64)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in token"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "User ID not found in token")
 		return
 	}
 	userID := uint(userIDFloat)
 
+	// Optional retry key (book_idempotency.go): a repeat returns the same book.
+	idemKey := strings.TrimSpace(c.GetHeader("Idempotency-Key"))
+	if len(idemKey) > maxIdempotencyKeyLen {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("Idempotency-Key is limited to %d characters", maxIdempotencyKeyLen))
+		return
+	}
+
 	book := Book{
 		Title:    req.Title,
 		Author:   req.Author,
@@ -426,18 +544,30 @@ func createBookHandler(c *gin.Context) {
 		UserID:   userID,
 	}
 	book.TTSEngine = defaultTTSEngine()
-	if err := db.Create(&book).Error; err != nil {
+	book.AudioFormat = strings.ToLower(req.AudioFormat)
+	book.ISBN = isbn
+	replayed, err := createBookOnce(&book, idemKey)
+	switch {
+	case errors.Is(err, errIdempotencyKeyReused), errors.Is(err, errIdempotentBookGone):
+		respondError(c, http.StatusConflict, errCodeConflict, err.Error())
+		return
+	case err != nil:
 		log.Printf("Error creating book record: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save book", "details": err.Error()})
+		respondInternal(c, "Failed to save book", err)
+		return
+	}
+	if replayed {
+		c.Header("Idempotent-Replayed", "true")
+		c.JSON(http.StatusOK, gin.H{"message": "Book already created for this Idempotency-Key", "book": withCoverProxy(book)})
 		return
 	}
 
 	// Automatically fetch the book cover on the worker fleet (durable).
-	if err := enqueueFetchCover(book.ID, book.Title, book.Author); err != nil {
+	if err := enqueueFetchCover(book.ID, book.Title, book.Author, book.ISBN); err != nil {
 		log.Printf("⚠️ Failed to enqueue cover fetch for book %d: %v", book.ID, err)
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Book saved, cover fetching in progress", "book": book})
+	c.JSON(http.StatusOK, gin.H{"message": "Book saved, cover fetching in progress", "book": withCoverProxy(book)})
 }
 
 // deleteBookHandler deletes a book by its ID or title.
@@ -448,44 +578,72 @@ func deleteBookHandler(c *gin.Context) {
 
 	// Snapshot related rows so we can clean up their on-disk files after the
 	// rows are deleted.
-	var chunks []BookChunk
-	db.Where("book_id = ?", book.ID).Find(&chunks)
-	var groups []ProcessedChunkGroup
-	db.Where("book_id = ?", book.ID).Find(&groups)
+	media := snapshotBookMedia(book)
 
 	// Q11: delete all related rows in one transaction so a book never leaves
 	// orphaned chunks/progress/jobs behind.
 	err := db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Where("book_id = ?", book.ID).Delete(&PlaybackProgress{}).Error; err != nil {
-			return err
-		}
-		if err := tx.Where("book_id = ?", book.ID).Delete(&TTSQueueJob{}).Error; err != nil {
-			return err
-		}
-		if err := tx.Unscoped().Where("book_id = ?", book.ID).Delete(&ProcessedChunkGroup{}).Error; err != nil {
-			return err
-		}
-		if err := tx.Where("book_id = ?", book.ID).Delete(&BookChunk{}).Error; err != nil {
-			return err
-		}
-		return tx.Delete(&Book{}, book.ID).Error
+		return deleteBookRows(tx, book.ID)
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete book", "details": err.Error()})
+		respondInternal(c, "Failed to delete book", err)
 		return
 	}
+	media.purge()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Book deleted successfully"})
+}
+
+// bookMedia is what a deleted book leaves in storage: its rows are gone by
+// the time the files are removed, so the paths are captured up front.
+type bookMedia struct {
+	book   Book
+	chunks []BookChunk
+	groups []ProcessedChunkGroup
+}
+
+func snapshotBookMedia(book Book) bookMedia {
+	m := bookMedia{book: book}
+	db.Where("book_id = ?", book.ID).Find(&m.chunks)
+	db.Where("book_id = ?", book.ID).Find(&m.groups)
+	return m
+}
+
+// deleteBookRows removes a book and every row that hangs off it. Run it in a
+// transaction.
+func deleteBookRows(tx *gorm.DB, bookID uint) error {
+	if err := tx.Where("book_id = ?", bookID).Delete(&PlaybackProgress{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("book_id = ?", bookID).Delete(&TTSQueueJob{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("book_id = ?", bookID).Delete(&ProcessedChunkGroup{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("book_id = ?", bookID).Delete(&BookChunk{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("book_id = ?", bookID).Delete(&ProcessingEvent{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&Book{}, bookID).Error
+}
 
-	// Best-effort media cleanup (R2 objects or legacy local files).
-	for _, ch := range chunks {
+// purge is the best-effort media cleanup (R2 objects or legacy local files)
+// after a book's rows are deleted.
+func (m bookMedia) purge() {
+	book := m.book
+	for _, ch := range m.chunks {
 		deleteStored(ch.AudioPath)
 		deleteStored(ch.FinalAudioPath)
 	}
-	for _, g := range groups {
+	for _, g := range m.groups {
 		deleteStored(g.AudioPath)
 	}
 	deleteStored(book.FilePath)
 	deleteStored(book.AudioPath)
-	deleteStored(book.CoverPath)
+	releaseCover(book) // shared covers survive while other books use them
 	_ = os.RemoveAll(uploadDirForBook(book.UserID, book.ID))
 
 	// Sweep the whole R2 media tree for this book: final page audio, score
@@ -499,25 +657,20 @@ func deleteBookHandler(c *gin.Context) {
 			log.Printf("🧹 Removed %d media objects under audio/%d/", n, book.ID)
 		}
 	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Book deleted successfully"})
 }
 
-// adding a new handler for listing book pages
-func listBookPagesHandler(c *gin.Context) {
-	bookID := c.Param("book_id")
-	if bookID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Book ID is required"})
-		return
-	}
-
-	// Optional pagination
-	limit := 20 // default limit
-	offset := 0
+// maxPagesPerRequest caps listBookPagesHandler's limit: every page carries its
+// full chunk text, so an unbounded limit could pull a whole book in one call.
+const maxPagesPerRequest = 100
 
+// pageListParams reads listBookPagesHandler's paging query: limit (default
+// 20, clamped to maxPagesPerRequest), offset (default 0) and include_content
+// (default true; false drops page text for a lightweight listing).
+func pageListParams(c *gin.Context) (limit, offset int, includeContent bool) {
+	limit, includeContent = 20, true
 	if l := c.Query("limit"); l != "" {
 		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 {
-			limit = parsedLimit
+			limit = min(parsedLimit, maxPagesPerRequest)
 		}
 	}
 	if o := c.Query("offset"); o != "" {
@@ -525,53 +678,88 @@ func listBookPagesHandler(c *gin.Context) {
 			offset = parsedOffset
 		}
 	}
-
-	// Fetch the book itself for metadata
-	var book Book
-	if err := db.First(&book, bookID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
-		return
+	if ic, err := strconv.ParseBool(c.DefaultQuery("include_content", "true")); err == nil {
+		includeContent = ic
 	}
+	return limit, offset, includeContent
+}
+
+// pageEntry is one page in listBookPagesHandler's response.
+func pageEntry(chunk BookChunk, includeContent bool) map[string]interface{} {
+	page := map[string]interface{}{
+		"page":   chunk.Index + 1,
+		"status": chunk.TTSStatus,
+		// "audio_url": chunk.AudioPath,
+		// Q8: the /pages/:page/audio route is 1-based (it subtracts 1), so
+		// emit the 1-based page number, not the 0-based chunk index.
+		"audio_url": fmt.Sprintf("%s/user/books/%d/pages/%d/audio",
+			getEnv("STREAM_HOST", "https://narrafied.com"), chunk.BookID, chunk.Index+1),
+	}
+	if includeContent {
+		page["content"] = chunk.Content
+	}
+	return page
+}
 
-	// Fetch chunks for this book with pagination
-	var chunks []BookChunk
-	if err := db.Where("book_id = ?", bookID).
+// pagedChunk is a page row plus the book's page total from the same query.
+type pagedChunk struct {
+	BookChunk  `gorm:"embedded"`
+	TotalPages int64
+}
+
+// adding a new handler for listing book pages
+func listBookPagesHandler(c *gin.Context) {
+	// Ownership already verified by requireBookOwnership(); reuse the book.
+	book := c.MustGet("book").(Book)
+	bookID := book.ID
+
+	// Optional pagination (clamped; see pageListParams)
+	limit, offset, includeContent := pageListParams(c)
+
+	// Fetch chunks for this book with pagination. Skip the text column
+	// entirely when the caller doesn't want it. A finished book's page count
+	// is cached on the row; otherwise the total comes from a window count in
+	// the same statement, so it always agrees with the slice even while
+	// chunking is still inserting pages.
+	columns := []string{"book_chunks.*"}
+	if !includeContent {
+		columns = []string{"id", "book_id", "index", "tts_status"}
+	}
+	if book.PageCount == 0 {
+		columns = append(columns, "COUNT(1) OVER () AS total_pages")
+	}
+	var rows []pagedChunk
+	if err := db.Model(&BookChunk{}).
+		Select(columns).
+		Where("book_id = ?", bookID).
 		Order("index ASC").
 		Limit(limit).
 		Offset(offset).
-		Find(&chunks).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not retrieve book chunks", "details": err.Error()})
+		Find(&rows).Error; err != nil {
+		respondInternal(c, "Could not retrieve book chunks", err)
 		return
 	}
 
-	if len(chunks) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"message": "No pages found for this range"})
+	if len(rows) == 0 {
+		respondError(c, http.StatusNotFound, errCodePageNotFound, "No pages found for this range")
 		return
 	}
 
 	// Check processed status and prepare pages
-	pages := make([]map[string]interface{}, 0, len(chunks))
+	pages := make([]map[string]interface{}, 0, len(rows))
 	fullyProcessed := true
 
-	for _, chunk := range chunks {
-		if chunk.TTSStatus != "completed" {
+	for _, row := range rows {
+		if row.TTSStatus != "completed" {
 			fullyProcessed = false
 		}
-		pages = append(pages, map[string]interface{}{
-			"page":    chunk.Index + 1,
-			"content": chunk.Content,
-			"status":  chunk.TTSStatus,
-			// "audio_url": chunk.AudioPath,
-			// Q8: the /pages/:page/audio route is 1-based (it subtracts 1), so
-			// emit the 1-based page number, not the 0-based chunk index.
-			"audio_url": fmt.Sprintf("%s/user/books/%d/pages/%d/audio",
-				getEnv("STREAM_HOST", "https://narrafied.com"), chunk.BookID, chunk.Index+1),
-		})
+		pages = append(pages, pageEntry(row.BookChunk, includeContent))
 	}
 
-	// Total page count (optional, could cache later for large scale)
-	var totalChunks int64
-	db.Model(&BookChunk{}).Where("book_id = ?", bookID).Count(&totalChunks)
+	totalChunks := int64(book.PageCount)
+	if book.PageCount == 0 {
+		totalChunks = rows[0].TotalPages
+	}
 
 	// Send JSON response
 	c.JSON(http.StatusOK, gin.H{
@@ -581,6 +769,8 @@ func listBookPagesHandler(c *gin.Context) {
 		"total_pages":     totalChunks,
 		"limit":           limit,
 		"offset":          offset,
+		"has_more":        int64(offset+len(rows)) < totalChunks,
+		"pages_final":     book.PageCount > 0, // false while chunking may still add pages
 		"fully_processed": fullyProcessed,
 		"pages":           pages,
 	})
@@ -598,20 +788,21 @@ func listBookPagesHandler(c *gin.Context) {
 // The stream URL is constructed using the STREAM_HOST environment variable, defaulting to "https://narrafied.com"
 // It returns a JSON response with the list of books, each containing its ID, title, author, category, genre, file path, audio path, status, stream URL, cover URL, and cover path.
 // It uses the Gin framework for handling HTTP requests and responses.
+// With include_facets=true it also returns per-category and per-genre counts (book_facets.go).
 func listBooksHandler(c *gin.Context) {
 	claims, exists := c.Get("claims")
 	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Authentication claims missing"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "Authentication claims missing")
 		return
 	}
 	userClaims, ok := claims.(jwt.MapClaims)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid token claims"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "Invalid token claims")
 		return
 	}
 	userIDFloat, ok := userClaims["user_id"].(float64)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in token"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "User ID not found in token")
 		return
 	}
 	userID := uint(userIDFloat)
@@ -629,7 +820,7 @@ func listBooksHandler(c *gin.Context) {
 	}
 	if err := query.Find(&books).Error; err != nil {
 		log.Printf("Error retrieving books for user %d: %v", userID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books", "details": err.Error()})
+		respondInternal(c, "Failed to fetch books", err)
 		return
 	}
 
@@ -639,33 +830,42 @@ func listBooksHandler(c *gin.Context) {
 	for _, book := range books {
 		streamURL := streamHost + "/user/books/stream/proxy/" + fmt.Sprintf("%d", book.ID)
 		response = append(response, BookResponse{
-			ID:        book.ID,
-			Title:     book.Title,
-			Author:    book.Author,
-			Category:  book.Category,
-			Genre:     book.Genre,
-			FilePath:  book.FilePath,
-			AudioPath: book.AudioPath,
-			Status:    book.Status,
-			StreamURL: streamURL,
-			CoverURL:  book.CoverURL,
-			CoverPath: book.CoverPath,
+			ID:             book.ID,
+			Title:          book.Title,
+			Author:         book.Author,
+			Category:       book.Category,
+			Genre:          book.Genre,
+			FilePath:       book.FilePath,
+			AudioPath:      book.AudioPath,
+			Status:         book.Status,
+			StreamURL:      streamURL,
+			CoverURL:       book.CoverURL,
+			CoverPath:      book.CoverPath,
+			CoverProxyURL:  coverProxyURL(book),
+			EffectsSkipped: book.EffectsSkipped,
 		})
 	}
-	c.JSON(http.StatusOK, gin.H{"books": response})
-}
-
-func isValidCategory(category string) bool {
-	for _, allowed := range allowedCategories {
-		if strings.EqualFold(category, allowed) {
-			return true
+	resp := gin.H{"books": response}
+	if includeFacets, _ := strconv.ParseBool(c.Query("include_facets")); includeFacets {
+		facets, err := bookFacetCounts(db, userID)
+		if err != nil {
+			respondInternal(c, "Failed to count books", err)
+			return
 		}
+		resp["facets"] = facets
 	}
-	return false
+	c.JSON(http.StatusOK, resp)
 }
 
 func authMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// An X-API-Key (api_keys.go) stands in for the JWT when no
+		// Authorization header is sent.
+		if key := c.GetHeader(apiKeyHeader); key != "" && c.GetHeader("Authorization") == "" {
+			authenticateAPIKey(c, key)
+			return
+		}
+
 		var tokenString string
 
 		// Try getting token from Authorization header
@@ -680,21 +880,17 @@ func authMiddleware() gin.HandlerFunc {
 		}
 
 		if tokenString == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing token"})
+			abortWithError(c, http.StatusUnauthorized, errCodeUnauthorized, "Missing token")
 			return
 		}
 
-		// Parse and validate token. Pin the signing method to HMAC so a token
-		// presented with a different algorithm (e.g. alg=none, or RS256 using
-		// our secret as a public key) is rejected — matches auth-service.
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return jwtSecretKey, nil
-		})
+		// Parse and validate token. jwtKeyFunc pins the configured JWT_ALG so
+		// a token presented with a different algorithm (e.g. alg=none, or
+		// HS256 using the RS256 public key as a secret) is rejected — matches
+		// auth-service.
+		token, err := jwt.Parse(tokenString, jwtKeyFunc)
 		if err != nil || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			abortWithError(c, http.StatusUnauthorized, errCodeUnauthorized, "Invalid token")
 			return
 		}
 
@@ -709,7 +905,7 @@ func authMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		abortWithError(c, http.StatusUnauthorized, errCodeUnauthorized, "Invalid token claims")
 	}
 }
 
@@ -719,28 +915,28 @@ func adminMiddleware() gin.HandlerFunc {
 		// Get claims from context (set by authMiddleware)
 		claims, exists := c.Get("claims")
 		if !exists {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			abortWithError(c, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
 			return
 		}
 
 		// Extract is_admin from JWT token claims
 		claimsMap, ok := claims.(jwt.MapClaims)
 		if !ok {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+			abortWithError(c, http.StatusUnauthorized, errCodeUnauthorized, "Invalid token claims")
 			return
 		}
 
 		// Check if is_admin claim exists and is true
 		isAdmin, exists := claimsMap["is_admin"]
 		if !exists {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			abortWithError(c, http.StatusForbidden, errCodeForbidden, "Admin access required")
 			return
 		}
 
 		// Validate that is_admin is a boolean and is true
 		adminBool, ok := isAdmin.(bool)
 		if !ok || !adminBool {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			abortWithError(c, http.StatusForbidden, errCodeForbidden, "Admin access required")
 			return
 		}
 
@@ -748,46 +944,21 @@ func adminMiddleware() gin.HandlerFunc {
 	}
 }
 
-// adding helper function to get user account type
-
-func getUserAccountType(token string) (string, error) {
-	authServiceURL := getEnv("AUTH_SERVICE_URL", "http://auth-service:8082")
-
-	req, err := http.NewRequest("GET", authServiceURL+"/user/account-type", nil)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to fetch account type")
-	}
-
-	var result struct {
-		AccountType string `json:"account_type"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
-	}
-	return result.AccountType, nil
-}
-
 func BatchTranscribeBookHandler(c *gin.Context) {
 	// Ownership already verified by requireBookOwnership(); reuse the book.
 	book := c.MustGet("book").(Book)
 	userID := getUserIDFromContext(c)
 
-	authHeader := c.GetHeader("Authorization")
-	token, err := extractToken(authHeader)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid token"})
+	// Optional voice/language for this run only (tts_override.go); an empty
+	// body keeps the book's defaults.
+	var override ttsOverride
+	if err := c.ShouldBindJSON(&override); err != nil && !errors.Is(err, io.EOF) {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid request body")
+		return
+	}
+	override = override.normalized()
+	if err := override.validate(engineFor(book)); err != nil {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 		return
 	}
 
@@ -795,10 +966,15 @@ func BatchTranscribeBookHandler(c *gin.Context) {
 	// the auth-service HTTP lookup only for older tokens that lack the claim.
 	accountType := accountTypeFromClaims(c)
 	if accountType == "" {
-		at, err := getUserAccountType(token)
+		token, err := extractToken(c.GetHeader("Authorization"))
+		if err != nil {
+			respondError(c, http.StatusUnauthorized, errCodeUnauthorized, "Missing or invalid token")
+			return
+		}
+		at, err := getUserAccountType(userID, token)
 		if err != nil {
 			log.Printf("Error checking account type: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify account type"})
+			respondError(c, http.StatusServiceUnavailable, errCodeServiceUnavailable, "Failed to verify account type")
 			return
 		}
 		accountType = at
@@ -813,42 +989,58 @@ func BatchTranscribeBookHandler(c *gin.Context) {
 		return
 	}
 
-	var chunks []BookChunk
-	if err := db.Where("book_id = ? AND tts_status != ?", book.ID, "completed").Order("index ASC").Find(&chunks).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch chunks"})
-		return
-	}
-
-	if len(chunks) == 0 {
+	err := queueBookTranscription(c.Request.Context(), book, userID, accountType, override)
+	switch {
+	case errors.Is(err, errNothingToTranscribe):
 		c.JSON(http.StatusOK, gin.H{"message": "Book already fully processed"})
 		return
-	}
-
-	// B6: atomic job lock — only one transcription may run per book. Use a
-	// dedicated 'transcribing' sentinel (NOT 'processing', which upload already
-	// sets to mean "uploaded/ready"); claim only if not already transcribing.
-	claim := db.Model(&Book{}).
-		Where("id = ? AND status <> ?", book.ID, "transcribing").
-		Update("status", "transcribing")
-	if claim.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not lock book for processing"})
+	case errors.Is(err, errBookLocked):
+		respondError(c, http.StatusConflict, errCodeConflict, "Book is already being processed")
 		return
-	}
-	if claim.RowsAffected == 0 {
-		c.JSON(http.StatusConflict, gin.H{"error": "Transcription already in progress for this book"})
+	case errors.Is(err, errTranscriptionRunning):
+		respondError(c, http.StatusConflict, errCodeConflict, "Transcription already in progress for this book")
+		return
+	case err != nil:
+		respondInternal(c, "Could not queue transcription", err)
 		return
 	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Transcription queued"})
+}
 
-	// Enqueue the first 20-page batch (durable, on the worker fleet). The
-	// worker auto-enqueues subsequent batches as each completes, fires an MQTT
-	// "pages ready" event, and releases the book lock when done.
-	start := chunks[0].Index
-	if err := enqueueTranscribeBatch(book.ID, start, start+batchSizePages-1, userID, accountType); err != nil {
-		db.Model(&Book{}).Where("id = ?", book.ID).Update("status", "pending")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not enqueue transcription", "details": err.Error()})
+// transcriptionStatusHandler reports batch-transcription progress for a book —
+// pages completed vs total plus each recorded 20-page batch — so the client can
+// poll a queued /tts/batch job, including after a worker restart resumes it.
+func transcriptionStatusHandler(c *gin.Context) {
+	book := c.MustGet("book").(Book)
+
+	var total, completed int64
+	db.Model(&BookChunk{}).Where("book_id = ?", book.ID).Count(&total)
+	db.Model(&BookChunk{}).Where("book_id = ? AND tts_status = ?", book.ID, "completed").Count(&completed)
+
+	var batches []TranscriptionBatch
+	if err := db.Where("book_id = ?", book.ID).Order("start_page ASC").Find(&batches).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "Could not fetch batches")
 		return
 	}
-	c.JSON(http.StatusAccepted, gin.H{"message": "Transcription queued"})
+	out := make([]gin.H, 0, len(batches))
+	for _, b := range batches {
+		out = append(out, gin.H{
+			"start_page":   b.StartPage + 1, // 1-based, like every page number we emit
+			"end_page":     b.EndPage + 1,
+			"status":       b.Status,
+			"updated_at":   b.UpdatedAt,
+			"completed_at": b.CompletedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"book_id":         book.ID,
+		"status":          book.Status,
+		"needs_reprocess": book.NeedsReprocess,
+		"total_pages":     total,
+		"completed_pages": completed,
+		"batches":         out,
+	})
 }
 
 // accountTypeFromClaims returns the account_type embedded in the JWT, or "" if
@@ -892,31 +1084,23 @@ func extractToken(authHeader string) (string, error) {
 // getSingleBookHandler retrieves a single book by its ID.
 // getSingleBookHandler retrieves a single book by its ID.
 func getSingleBookHandler(c *gin.Context) {
-	bookID := c.Param("book_id")
-
-	if bookID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Book ID is required"})
-		return
-	}
-
-	var book Book
-	if err := db.First(&book, bookID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
-		return
-	}
+	// Ownership already verified by requireBookOwnership(); reuse the book.
+	book := c.MustGet("book").(Book)
 
 	// add full book data response
 	bookResponse := BookResponse{
-		ID:          book.ID,
-		Title:       book.Title,
-		Author:      book.Author,
-		Category:    book.Category,
-		Content:     book.Content,
-		ContentHash: book.ContentHash,
-		Genre:       book.Genre,
-		FilePath:    book.FilePath,
-		AudioPath:   book.AudioPath,
-		Status:      book.Status,
+		ID:             book.ID,
+		Title:          book.Title,
+		Author:         book.Author,
+		Category:       book.Category,
+		Content:        book.Content,
+		ContentHash:    book.ContentHash,
+		Genre:          book.Genre,
+		FilePath:       book.FilePath,
+		AudioPath:      book.AudioPath,
+		Status:         book.Status,
+		CoverProxyURL:  coverProxyURL(book),
+		EffectsSkipped: book.EffectsSkipped,
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -931,14 +1115,14 @@ func deleteUserFilesContentHandler(c *gin.Context) {
 	userIDStr := c.Param("user_id")
 	userID, err := strconv.ParseUint(userIDStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid user_id")
 		return
 	}
 
 	// Find all books for this user
 	var books []Book
 	if err := db.Where("user_id = ?", userID).Find(&books).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user books"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "Failed to fetch user books")
 		return
 	}
 
@@ -964,14 +1148,6 @@ func deleteUserFilesContentHandler(c *gin.Context) {
 			}
 		}
 
-		// Delete cover file
-		if book.CoverPath != "" {
-			if err := os.Remove(book.CoverPath); err == nil {
-				coversDeleted++
-				log.Printf("🗑️ Deleted cover: %s", book.CoverPath)
-			}
-		}
-
 		// Find and delete chunk audio files
 		var chunks []BookChunk
 		db.Where("book_id = ?", book.ID).Find(&chunks)
@@ -998,7 +1174,7 @@ func deleteUserFilesContentHandler(c *gin.Context) {
 	// Delete database records
 	tx := db.Begin()
 	if tx.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "Failed to start transaction")
 		return
 	}
 
@@ -1015,6 +1191,9 @@ func deleteUserFilesContentHandler(c *gin.Context) {
 	result := tx.Where("book_id IN (SELECT id FROM books WHERE user_id = ?)", userID).Delete(&BookChunk{})
 	totalChunksDeleted = result.RowsAffected
 
+	// Delete processing logs
+	tx.Where("book_id IN (SELECT id FROM books WHERE user_id = ?)", userID).Delete(&ProcessingEvent{})
+
 	// Delete books
 	result = tx.Where("user_id = ?", userID).Delete(&Book{})
 	totalBooksDeleted = result.RowsAffected
@@ -1022,10 +1201,19 @@ func deleteUserFilesContentHandler(c *gin.Context) {
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit deletion"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "Failed to commit deletion")
 		return
 	}
 
+	// Covers go once the rows are gone: releaseCover keeps any still shared
+	// with another user's book.
+	for _, book := range books {
+		if releaseCover(book) {
+			coversDeleted++
+			log.Printf("🗑️ Deleted cover: %s", book.CoverPath)
+		}
+	}
+
 	log.Printf("🗑️ Deleted all files and data for user ID %d by admin", userID)
 	c.JSON(http.StatusOK, gin.H{
 		"message":           "User files deleted successfully",
@@ -1066,74 +1254,37 @@ func deleteFileContentHandler(c *gin.Context) {
 
 	var req DeleteFileRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "file_path is required"})
-		return
-	}
-
-	// Security: Validate that the path is within allowed directories
-	allowedPrefixes := []string{"audio/", "covers/", "uploads/"}
-	isAllowed := false
-	for _, prefix := range allowedPrefixes {
-		if strings.HasPrefix(req.FilePath, prefix) {
-			isAllowed = true
-			break
-		}
-	}
-
-	if !isAllowed {
-		c.JSON(http.StatusForbidden, gin.H{
-			"error":   "Invalid file path",
-			"message": "File must be in audio/, covers/, or uploads/ directory",
-		})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "file_path is required")
 		return
 	}
 
-	// Security: Prevent path traversal attacks
-	if strings.Contains(req.FilePath, "..") {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid file path: path traversal not allowed"})
+	// Security: only files under a configured root (file_roots.go), with
+	// no path traversal.
+	fullPath, err := resolveAdminFilePath(req.FilePath, adminFileRoots())
+	if errors.Is(err, errFileTraversal) {
+		respondError(c, http.StatusForbidden, errCodeForbidden, "Invalid file path: path traversal not allowed")
 		return
 	}
-
-	// Map the relative path to actual container paths
-	// In Docker: audio/ → ./audio/, covers/ → ./uploads/covers/, uploads/ → ./uploads/
-	var fullPath string
-	switch {
-	case strings.HasPrefix(req.FilePath, "audio/"):
-		fullPath = "./" + req.FilePath // ./audio/filename
-	case strings.HasPrefix(req.FilePath, "covers/"):
-		// covers/filename → ./uploads/covers/filename
-		filename := strings.TrimPrefix(req.FilePath, "covers/")
-		fullPath = "./uploads/covers/" + filename
-	case strings.HasPrefix(req.FilePath, "uploads/"):
-		fullPath = "./" + req.FilePath // ./uploads/filename
-	default:
-		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid file path"})
+	if err != nil {
+		respondErrorWith(c, http.StatusForbidden, errCodeForbidden, err.Error(), gin.H{"error": "Invalid file path"})
 		return
 	}
 
 	// Check if file exists
 	info, err := os.Stat(fullPath)
 	if os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":     "File not found",
-			"file_path": req.FilePath,
-		})
+		respondErrorWith(c, http.StatusNotFound, errCodeNotFound, "File not found", gin.H{"file_path": req.FilePath})
 		return
 	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to check file",
-			"details": err.Error(),
-		})
+		respondInternal(c, "Failed to check file", err)
 		return
 	}
 
 	// Don't allow deleting directories
 	if info.IsDir() {
-		c.JSON(http.StatusForbidden, gin.H{
-			"error":   "Cannot delete directories",
-			"message": "Only individual files can be deleted",
-		})
+		respondErrorWith(c, http.StatusForbidden, errCodeForbidden, "Only individual files can be deleted",
+			gin.H{"error": "Cannot delete directories"})
 		return
 	}
 
@@ -1142,10 +1293,7 @@ func deleteFileContentHandler(c *gin.Context) {
 
 	// Delete the file
 	if err := os.Remove(fullPath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to delete file",
-			"details": err.Error(),
-		})
+		respondInternal(c, "Failed to delete file", err)
 		return
 	}
 
@@ -1170,21 +1318,18 @@ type FileTreeNode struct {
 // getFileTreeContentHandler returns the directory tree structure for audio, covers, and uploads
 // GET /admin/files/tree
 func getFileTreeContentHandler(c *gin.Context) {
-	// Directory mappings in Docker container
+	// Directory mappings in Docker container (ADMIN_FILE_ROOTS, file_roots.go)
 	// Host /opt/stream-audio-data/audio → Container ./audio
 	// Host /opt/stream-audio-data/covers → Container ./uploads/covers
 	// Host /opt/stream-audio-data/uploads → Container ./uploads
-	dirMappings := map[string]string{
-		"audio":   "./audio",
-		"covers":  "./uploads/covers",
-		"uploads": "./uploads",
-	}
+	roots := adminFileRoots()
 
 	trees := make(map[string]*FileTreeNode)
 	var totalSize int64
 	var totalFiles int
 
-	for displayName, containerPath := range dirMappings {
+	for _, root := range roots {
+		displayName, containerPath := root.Name, root.Dir
 		// Check if directory exists
 		if _, err := os.Stat(containerPath); os.IsNotExist(err) {
 			// Create empty node for missing directories
@@ -1223,7 +1368,7 @@ func getFileTreeContentHandler(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"trees":       trees,
-		"directories": []string{"audio", "covers", "uploads"},
+		"directories": fileRootNames(roots),
 		"stats": gin.H{
 			"totalSize":  totalSize,
 			"totalFiles": totalFiles,
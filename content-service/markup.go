@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// HTML and Markdown uploads. HTML goes through the same htmlToText the EPUB
+// reader uses (epub.go). Markdown is stripped line by line rather than
+// rendered: headings, quotes and list markers are dropped, links and images
+// keep their visible text, emphasis and inline code lose their markers, and
+// fenced or indented code keeps its text as a paragraph of its own.
+
+// ExtractTextFromHTML returns an HTML file's visible text, one blank line
+// between blocks.
+func ExtractTextFromHTML(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	text, err := htmlToText(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	text = cleanUTF8([]byte(text))
+	if strings.TrimSpace(text) == "" {
+		return "", errors.New("no text found in HTML file")
+	}
+	return text, nil
+}
+
+// ExtractTextFromMarkdown returns a Markdown file as narration-ready plain
+// text, one blank line between blocks.
+func ExtractTextFromMarkdown(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	text := cleanUTF8([]byte(markdownToText(string(data))))
+	if strings.TrimSpace(text) == "" {
+		return "", errors.New("no text found in Markdown file")
+	}
+	return text, nil
+}
+
+var (
+	mdFence       = regexp.MustCompile("^ {0,3}(```+|~~~+)")
+	mdHeading     = regexp.MustCompile(`^ {0,3}#{1,6}(\s+|$)`)
+	mdHeadingTail = regexp.MustCompile(`\s+#+\s*$`)
+	mdRule        = regexp.MustCompile(`^ {0,3}([-*_])(\s*([-*_]))*\s*$`)
+	mdSetext      = regexp.MustCompile(`^ {0,3}(=+|-+)\s*$`)
+	mdQuote       = regexp.MustCompile(`^ {0,3}(>\s?)+`)
+	mdListItem    = regexp.MustCompile(`^\s*([-*+]|\d{1,9}[.)])\s+(\[[ xX]\]\s+)?`)
+	mdLinkDef     = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s*\S+`)
+	mdTableRule   = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+
+	mdImage     = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink      = regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`)
+	mdRefLink   = regexp.MustCompile(`!?\[([^\]]+)\]\[[^\]]*\]`)
+	mdAutolink  = regexp.MustCompile(`<((?:https?|mailto):[^>\s]+)>`)
+	mdHTMLTag   = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	mdCode      = regexp.MustCompile("`+([^`]+)`+")
+	mdStrong    = regexp.MustCompile(`(\*\*|__)(\S(?:.*?\S)?)(\*\*|__)`)
+	mdEmStar    = regexp.MustCompile(`\*(\S(?:[^*]*?\S)?)\*`)
+	mdEmUnder   = regexp.MustCompile(`(^|\W)_(\S(?:[^_]*?\S)?)_(\W|$)`)
+	mdStrike    = regexp.MustCompile(`~~(.+?)~~`)
+	mdEscape    = regexp.MustCompile("\\\\([\\\\`*_{}\\[\\]()#+\\-.!|>~])")
+	mdTablePipe = regexp.MustCompile(`\s*\|\s*`)
+)
+
+// markdownToText converts Markdown source to plain paragraphs.
+func markdownToText(src string) string {
+	var paras []string
+	var cur []string
+	flush := func() {
+		if p := strings.Join(strings.Fields(strings.Join(cur, " ")), " "); p != "" {
+			paras = append(paras, p)
+		}
+		cur = cur[:0]
+	}
+	var code []string
+	flushCode := func() {
+		for len(code) > 0 && strings.TrimSpace(code[len(code)-1]) == "" {
+			code = code[:len(code)-1]
+		}
+		if len(code) > 0 {
+			paras = append(paras, strings.Join(code, "\n"))
+		}
+		code = code[:0]
+	}
+
+	sc := bufio.NewScanner(strings.NewReader(strings.ReplaceAll(src, "\r\n", "\n")))
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	fence := ""
+	first := true
+	inFrontMatter := false
+	for sc.Scan() {
+		line := strings.ReplaceAll(sc.Text(), "\t", "    ")
+
+		// YAML front matter holds metadata, not prose.
+		if first {
+			first = false
+			if strings.TrimSpace(line) == "---" {
+				inFrontMatter = true
+				continue
+			}
+		}
+		if inFrontMatter {
+			if t := strings.TrimSpace(line); t == "---" || t == "..." {
+				inFrontMatter = false
+			}
+			continue
+		}
+
+		if fence != "" {
+			if m := mdFence.FindStringSubmatch(line); m != nil && strings.HasPrefix(m[1], fence) {
+				fence = ""
+				flushCode()
+				continue
+			}
+			code = append(code, strings.TrimRight(line, " "))
+			continue
+		}
+		if m := mdFence.FindStringSubmatch(line); m != nil {
+			flush()
+			fence = m[1]
+			continue
+		}
+
+		// Indented code only starts after a blank line.
+		if len(cur) == 0 && strings.HasPrefix(line, "    ") && strings.TrimSpace(line) != "" && !mdListItem.MatchString(line) {
+			code = append(code, strings.TrimRight(line[4:], " "))
+			continue
+		}
+		if len(code) > 0 {
+			if strings.TrimSpace(line) == "" {
+				code = append(code, "")
+				continue
+			}
+			flushCode()
+		}
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+			continue
+		case mdSetext.MatchString(line) && len(cur) > 0:
+			flush() // underline of a setext heading
+			continue
+		case mdRule.MatchString(line), mdLinkDef.MatchString(line), mdTableRule.MatchString(line) && strings.Contains(line, "|"):
+			flush()
+			continue
+		}
+
+		line = mdQuote.ReplaceAllString(line, "")
+		if mdHeading.MatchString(line) {
+			flush()
+			cur = append(cur, markdownInline(mdHeadingTail.ReplaceAllString(mdHeading.ReplaceAllString(line, ""), "")))
+			flush()
+			continue
+		}
+		if mdListItem.MatchString(line) {
+			flush()
+			line = mdListItem.ReplaceAllString(line, "")
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "|") {
+			// One table row per paragraph, cells read as a list.
+			flush()
+			cur = append(cur, markdownInline(strings.Trim(mdTablePipe.ReplaceAllString(strings.TrimSpace(line), ", "), ", ")))
+			flush()
+			continue
+		}
+		cur = append(cur, markdownInline(line))
+	}
+	flush()
+	flushCode()
+	return strings.Join(paras, "\n\n")
+}
+
+// markdownInline strips inline markup from one line, keeping the words a
+// reader would see.
+func markdownInline(s string) string {
+	s = mdImage.ReplaceAllString(s, "$1")
+	s = mdLink.ReplaceAllString(s, "$1")
+	s = mdRefLink.ReplaceAllString(s, "$1")
+	s = mdAutolink.ReplaceAllString(s, "$1")
+	s = mdHTMLTag.ReplaceAllString(s, "")
+	s = mdCode.ReplaceAllString(s, "$1")
+	s = mdStrong.ReplaceAllString(s, "$2")
+	s = mdEmStar.ReplaceAllString(s, "$1")
+	s = mdEmUnder.ReplaceAllString(s, "$1$2$3")
+	s = mdStrike.ReplaceAllString(s, "$1")
+	s = mdEscape.ReplaceAllString(s, "$1")
+	return s
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTextFile(t *testing.T, name, body string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestExtractTextFromHTML(t *testing.T) {
+	path := writeTextFile(t, "book.html", `<!DOCTYPE html>
+<html><head><title>Ignored</title><style>p { margin: 0 }</style></head>
+<body>
+<h1>Chapter One</h1>
+<p>It was a <a href="https://example.com/night">dark</a>&nbsp;and
+   stormy night.</p>
+<script>track()</script>
+<pre><code>let rain = true;</code></pre>
+<ul><li>Rain</li><li>Wind &amp; thunder</li></ul>
+</body></html>`)
+
+	got, err := ExtractTextByType(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Chapter One\n\nIt was a dark and stormy night.\n\nlet rain = true;\n\nRain\n\nWind & thunder"
+	if got != want {
+		t.Errorf("ExtractTextFromHTML =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestExtractTextFromMarkdown(t *testing.T) {
+	path := writeTextFile(t, "book.md", "---\n"+
+		"title: The Storm\n"+
+		"---\n"+
+		"# Chapter One #\n"+
+		"\n"+
+		"It was a **dark** and _stormy_ night,\n"+
+		"said [the narrator](https://example.com \"n\") to ![a crowd](crowd.png).\n"+
+		"\n"+
+		"> Rain fell on the `roof` of snake_case_house.\n"+
+		"\n"+
+		"- First item\n"+
+		"- Second ~~wrong~~ item\n"+
+		"\n"+
+		"```go\n"+
+		"fmt.Println(\"rain\")\n"+
+		"```\n"+
+		"\n"+
+		"Chapter Two\n"+
+		"===========\n"+
+		"\n"+
+		"* * *\n"+
+		"\n"+
+		"See <https://example.com/more> and [the notes][n]\\*.\n"+
+		"\n"+
+		"[n]: https://example.com/notes\n")
+
+	got, err := ExtractTextByType(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Chapter One\n\n" +
+		"It was a dark and stormy night, said the narrator to a crowd.\n\n" +
+		"Rain fell on the roof of snake_case_house.\n\n" +
+		"First item\n\n" +
+		"Second wrong item\n\n" +
+		"fmt.Println(\"rain\")\n\n" +
+		"Chapter Two\n\n" +
+		"See https://example.com/more and the notes*."
+	if got != want {
+		t.Errorf("ExtractTextFromMarkdown =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestMarkdownToText_IndentedCodeAndTables(t *testing.T) {
+	got := markdownToText("Intro line\n\n    x := 1\n    y := 2\n\n| Name | Role |\n|------|:----:|\n| Ann | Hero |\n")
+	want := "Intro line\n\nx := 1\ny := 2\n\nName, Role\n\nAnn, Hero"
+	if got != want {
+		t.Errorf("markdownToText =\n%q\nwant\n%q", got, want)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"os"
@@ -28,20 +27,22 @@ const signedMediaTTL = 2 * time.Hour
 // legacy on-disk path it serves the file directly (migration fallback).
 func serveMedia(c *gin.Context, stored string) {
 	if stored == "" {
-		c.JSON(http.StatusNotFound, gin.H{"error": "audio not available"})
+		respondError(c, http.StatusNotFound, errCodeAudioNotReady, "audio not available")
 		return
 	}
 	if isLegacyLocalPath(stored) {
 		if _, err := os.Stat(stored); err == nil {
+			// Explicit type: Go's mime table misses .m4a on minimal images.
+			c.Header("Content-Type", contentTypeForExt(stored))
 			c.File(stored)
 			return
 		}
-		c.JSON(http.StatusNotFound, gin.H{"error": "audio file missing on disk"})
+		respondError(c, http.StatusNotFound, errCodeNotFound, "audio file missing on disk")
 		return
 	}
 	url, err := store.PresignGet(c.Request.Context(), stored, signedMediaTTL)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not sign media url"})
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "could not sign media url")
 		return
 	}
 	c.Redirect(http.StatusFound, url)
@@ -60,6 +61,9 @@ type MediaStore interface {
 	// clean a book's media tree on delete — final audio, HLS playlists, and
 	// the HLS segment files whose names aren't tracked in the DB.
 	DeletePrefix(ctx context.Context, prefix string) (int, error)
+	// PrefixSize totals the bytes and object count under a key prefix (used
+	// for per-user storage accounting).
+	PrefixSize(ctx context.Context, prefix string) (int64, int, error)
 	Exists(ctx context.Context, key string) (bool, error)
 	PublicURL(key string) string
 }
@@ -127,16 +131,9 @@ func (s *r2Store) GetToFile(ctx context.Context, key, localPath string) error {
 		return err
 	}
 	defer out.Body.Close()
-	if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
-		return err
-	}
-	f, err := os.Create(localPath)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	_, err = io.Copy(f, out.Body)
-	return err
+	// Atomic: library clips land at shared ./audio cache paths other jobs
+	// read concurrently.
+	return writeFileAtomic(localPath, out.Body, 0o644)
 }
 
 func (s *r2Store) PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error) {
@@ -204,6 +201,28 @@ func (s *r2Store) DeletePrefix(ctx context.Context, prefix string) (int, error)
 	return deleted, nil
 }
 
+func (s *r2Store) PrefixSize(ctx context.Context, prefix string) (int64, int, error) {
+	if strings.TrimSpace(prefix) == "" {
+		return 0, 0, errors.New("PrefixSize: empty prefix")
+	}
+	var total int64
+	count := 0
+	p := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket), Prefix: aws.String(prefix),
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return total, count, err
+		}
+		for _, obj := range page.Contents {
+			total += aws.ToInt64(obj.Size)
+			count++
+		}
+	}
+	return total, count, nil
+}
+
 func (s *r2Store) Exists(ctx context.Context, key string) (bool, error) {
 	_, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(key)})
 	if err != nil {
@@ -273,6 +292,10 @@ func contentTypeForExt(p string) string {
 		return "application/pdf"
 	case ".epub":
 		return "application/epub+zip"
+	case ".docx":
+		return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	case ".doc":
+		return "application/msword"
 	default:
 		return "application/octet-stream"
 	}
@@ -333,6 +356,25 @@ func localizeMedia(ctx context.Context, pathOrKey string) (string, func(), error
 	return tmp.Name(), func() { os.Remove(tmp.Name()) }, nil
 }
 
+// mediaExists reports whether a stored media reference still resolves: os.Stat
+// for a legacy on-disk path, a HEAD against the store for an R2 key. Any
+// lookup error counts as missing — callers use this to decide whether it's
+// safe to point another row at the file.
+func mediaExists(ctx context.Context, pathOrKey string) bool {
+	if pathOrKey == "" {
+		return false
+	}
+	if isLegacyLocalPath(pathOrKey) {
+		_, err := os.Stat(pathOrKey)
+		return err == nil
+	}
+	if store == nil {
+		return false
+	}
+	ok, err := store.Exists(ctx, pathOrKey)
+	return err == nil && ok
+}
+
 // legacyKey maps an old local media path to its migrated R2 key
 // (legacy/{kind}/{basename}); used by the one-time data migration.
 func legacyKey(localPath, kind string) string {
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Versioned schema migrations. AutoMigrate can only add tables and columns;
+// it can't drop or rename them or backfill data, so every schema change is a
+// migration here instead. Each runs once, in ID order, inside its own
+// transaction, and is recorded in schema_migrations.
+//
+// Rules for new migrations:
+//   - append to contentMigrations with the next ID; never edit or reorder
+//     one that has shipped;
+//   - make Up tolerate a fresh database too: 0001 migrates the current
+//     models, so a column added to a model in the same change already exists
+//     there — guard with tx.Migrator().HasColumn/HasTable;
+//   - give it a Down that undoes it, for rollbackLastMigration.
+
+// migration is one versioned schema change.
+type migration struct {
+	ID   string
+	Up   func(tx *gorm.DB) error
+	Down func(tx *gorm.DB) error
+}
+
+// schemaMigration is a row of schema_migrations: one applied migration.
+type schemaMigration struct {
+	ID        string `gorm:"primaryKey;size:255"`
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// migrationLockKey is the Postgres advisory lock that serializes migration
+// runs, so two API instances starting together don't apply one twice.
+const migrationLockKey = 7461_0001
+
+// contentModels are the tables the initial migration creates.
+func contentModels() []interface{} {
+	return []interface{}{
+		&Book{}, &BookChunk{}, &ProcessedChunkGroup{}, &TTSQueueJob{}, &PlaybackProgress{},
+		&TranscriptionBatch{}, &PlanLimit{}, &UsageEvent{}, &DeviceToken{}, &BugReport{},
+		&AppConfig{}, &CastEvent{}, &Follow{}, &RenderedPage{}, &GutenbergBook{},
+	}
+}
+
+var contentMigrations = []migration{
+	{
+		// Baseline: the schema AutoMigrate used to maintain. On a database
+		// that AutoMigrate already manages this is a no-op apart from adding
+		// anything it had not yet added.
+		ID: "0001_initial_schema",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(contentModels()...); err != nil {
+				return err
+			}
+			// Full-text search over the free-books catalog (title + authors).
+			return tx.Exec(`CREATE INDEX IF NOT EXISTS idx_gutenberg_fts ON gutenberg_books
+				USING GIN (to_tsvector('english', coalesce(title,'') || ' ' || coalesce(authors,'')))`).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(contentModels()...)
+		},
+	},
+	{
+		// Public share links (share_links.go).
+		ID: "0002_book_shares",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&BookShare{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&BookShare{})
+		},
+	},
+	{
+		// Idempotency keys for book creation (book_idempotency.go).
+		ID: "0003_book_create_keys",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&BookCreateKey{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&BookCreateKey{})
+		},
+	},
+	{
+		// Cached Foley event proposals per page text (foley_cache.go).
+		ID: "0004_foley_placements",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&FoleyPlacement{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&FoleyPlacement{})
+		},
+	},
+	{
+		// Per-book Foley level override (foleyVolumeFor).
+		ID: "0005_book_foley_volume",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&Book{}, "FoleyVolume") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&Book{}, "FoleyVolume")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&Book{}, "FoleyVolume")
+		},
+	},
+	{
+		// Books with pages rendered without effects (soundEffectsAvailable).
+		ID: "0006_book_effects_skipped",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&Book{}, "EffectsSkipped") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&Book{}, "EffectsSkipped")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&Book{}, "EffectsSkipped")
+		},
+	},
+	{
+		// Per-book processing history (processing_log.go).
+		ID: "0007_processing_events",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&ProcessingEvent{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&ProcessingEvent{})
+		},
+	},
+	{
+		// Full-text index over page text for in-book search (book_text_search.go).
+		ID: "0008_chunk_fts",
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec(`CREATE INDEX IF NOT EXISTS idx_book_chunks_fts ON book_chunks
+				USING GIN (` + chunkTSVector + `)`).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec(`DROP INDEX IF EXISTS idx_book_chunks_fts`).Error
+		},
+	},
+	{
+		// Background cover-fetch retry count (cover_retry.go).
+		ID: "0009_book_cover_attempts",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&Book{}, "CoverAttempts") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&Book{}, "CoverAttempts")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&Book{}, "CoverAttempts")
+		},
+	},
+	{
+		// playback_progresses.finished_at (book_finish.go).
+		ID: "0010_progress_finished_at",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&PlaybackProgress{}, "FinishedAt") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&PlaybackProgress{}, "FinishedAt")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&PlaybackProgress{}, "FinishedAt")
+		},
+	},
+	{
+		// book_chunks.transcript (transcript.go).
+		ID: "0011_chunk_transcript",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&BookChunk{}, "Transcript") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&BookChunk{}, "Transcript")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&BookChunk{}, "Transcript")
+		},
+	},
+	{
+		// books.page_count, the cached final page total (listBookPagesHandler).
+		// Existing books keep 0 and are counted per request until re-chunked.
+		ID: "0012_book_page_count",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&Book{}, "PageCount") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&Book{}, "PageCount")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&Book{}, "PageCount")
+		},
+	},
+	{
+		// rendered_pages.engine widened for the per-book Foley volume suffix
+		// (dedupEngineKey), which can push a key past 32 characters.
+		ID: "0013_rendered_page_engine_width",
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec(`ALTER TABLE rendered_pages ALTER COLUMN engine TYPE varchar(64)`).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec(`ALTER TABLE rendered_pages ALTER COLUMN engine TYPE varchar(32)`).Error
+		},
+	},
+	{
+		// rendered_pages.transcript, handed to pages that reuse the rendering.
+		ID: "0014_rendered_page_transcript",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&RenderedPage{}, "Transcript") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&RenderedPage{}, "Transcript")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&RenderedPage{}, "Transcript")
+		},
+	},
+	{
+		// books.processed_at, when transcription finished (admin content stats).
+		ID: "0015_book_processed_at",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&Book{}, "ProcessedAt") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&Book{}, "ProcessedAt")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&Book{}, "ProcessedAt")
+		},
+	},
+}
+
+// runMigrations applies every migration in ms not yet recorded in
+// schema_migrations. Each one takes the advisory lock and re-checks inside
+// its transaction, so concurrent runners apply it exactly once.
+func runMigrations(db *gorm.DB, ms []migration) error {
+	if err := validateMigrations(ms); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	for _, m := range ms {
+		applied := false
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockKey).Error; err != nil {
+				return err
+			}
+			var n int64
+			if err := tx.Model(&schemaMigration{}).Where("id = ?", m.ID).Count(&n).Error; err != nil {
+				return err
+			}
+			if n > 0 {
+				return nil
+			}
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			applied = true
+			return tx.Create(&schemaMigration{ID: m.ID, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", m.ID, err)
+		}
+		if applied {
+			log.Printf("🗄️ Applied migration %s", m.ID)
+		}
+	}
+	return nil
+}
+
+// rollbackLastMigration reverts the most recently applied migration in ms.
+func rollbackLastMigration(db *gorm.DB, ms []migration) error {
+	var last schemaMigration
+	if err := db.Order("id DESC").First(&last).Error; err != nil {
+		return fmt.Errorf("no applied migration to roll back: %w", err)
+	}
+	for _, m := range ms {
+		if m.ID != last.ID {
+			continue
+		}
+		if m.Down == nil {
+			return fmt.Errorf("migration %s has no down step", m.ID)
+		}
+		return db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockKey).Error; err != nil {
+				return err
+			}
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			log.Printf("🗄️ Rolled back migration %s", m.ID)
+			return tx.Delete(&schemaMigration{}, "id = ?", m.ID).Error
+		})
+	}
+	return fmt.Errorf("applied migration %s is not defined in this build", last.ID)
+}
+
+// validateMigrations rejects a list whose IDs are empty, duplicated or out
+// of order, or whose entries lack an Up step.
+func validateMigrations(ms []migration) error {
+	for i, m := range ms {
+		if m.ID == "" || m.Up == nil {
+			return fmt.Errorf("migration %d needs an ID and an Up step", i)
+		}
+		if i > 0 && m.ID <= ms[i-1].ID {
+			return fmt.Errorf("migration %s must sort after %s", m.ID, ms[i-1].ID)
+		}
+	}
+	return nil
+}
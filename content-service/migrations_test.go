@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestValidateMigrations(t *testing.T) {
+	up := func(*gorm.DB) error { return nil }
+	if err := validateMigrations(contentMigrations); err != nil {
+		t.Fatalf("contentMigrations: %v", err)
+	}
+	bad := map[string][]migration{
+		"missing up":   {{ID: "0001_a"}},
+		"missing id":   {{Up: up}},
+		"duplicate id": {{ID: "0001_a", Up: up}, {ID: "0001_a", Up: up}},
+		"out of order": {{ID: "0002_b", Up: up}, {ID: "0001_a", Up: up}},
+	}
+	for name, ms := range bad {
+		if err := validateMigrations(ms); err == nil {
+			t.Errorf("%s: validateMigrations accepted %v", name, ms)
+		}
+	}
+}
+
+// freshSchemaDB opens TEST_DATABASE_DSN inside a new, empty Postgres schema
+// that is dropped when the test ends.
+func freshSchemaDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN not set")
+	}
+	cfg := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+	admin, err := gorm.Open(postgres.Open(dsn), cfg)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	schema := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	sep := " "
+	if strings.Contains(dsn, "://") {
+		sep = "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+	}
+	scoped, err := gorm.Open(postgres.Open(dsn+sep+"search_path="+schema), cfg)
+	if err != nil {
+		t.Fatalf("open schema: %v", err)
+	}
+	return scoped
+}
+
+// Migrating an empty database must create every table, and running the
+// migrations again must change nothing.
+func TestRunMigrations_FromEmptyAndIdempotent(t *testing.T) {
+	tx := freshSchemaDB(t)
+
+	for run := 1; run <= 2; run++ {
+		if err := runMigrations(tx, contentMigrations); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+	}
+	for _, table := range []string{"books", "book_chunks", "playback_progresses", "gutenberg_books", "schema_migrations"} {
+		if !tx.Migrator().HasTable(table) {
+			t.Errorf("table %s missing after migrating", table)
+		}
+	}
+	if !tx.Migrator().HasIndex("gutenberg_books", "idx_gutenberg_fts") {
+		t.Error("gutenberg search index missing")
+	}
+	var applied int64
+	tx.Model(&schemaMigration{}).Count(&applied)
+	if applied != int64(len(contentMigrations)) {
+		t.Errorf("schema_migrations has %d rows, want %d", applied, len(contentMigrations))
+	}
+
+	if err := rollbackLastMigration(tx, contentMigrations); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if tx.Migrator().HasTable("books") {
+		t.Error("books still present after rolling back the initial migration")
+	}
+}
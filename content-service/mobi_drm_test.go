@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// mobiFile returns a minimal Palm database header whose PalmDOC record has
+// the given encryption type.
+func mobiFile(encryption uint16) []byte {
+	b := make([]byte, 96+16)
+	copy(b, "Some Book")
+	copy(b[60:68], "BOOKMOBI")
+	binary.BigEndian.PutUint16(b[76:78], 1) // one record
+	binary.BigEndian.PutUint32(b[78:82], 96)
+	binary.BigEndian.PutUint16(b[96+12:96+14], encryption)
+	return b
+}
+
+// stubEbookConvert replaces Calibre with a script that prints out to stderr
+// and exits with code.
+func stubEbookConvert(t *testing.T, out string, code int) {
+	t.Helper()
+	script := filepath.Join(t.TempDir(), "ebook-convert")
+	body := fmt.Sprintf("#!/bin/sh\necho '%s' >&2\nexit %d\n", out, code)
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	orig := ebookConvertBin
+	ebookConvertBin = script
+	t.Cleanup(func() { ebookConvertBin = orig })
+}
+
+const calibreDRMOutput = `calibre.ebooks.DRMError: This file is locked by DRM`
+
+func TestMobiEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	for name, tc := range map[string]struct {
+		data []byte
+		want bool
+	}{
+		"locked.azw":  {mobiFile(2), true},
+		"old.mobi":    {mobiFile(1), true},
+		"free.mobi":   {mobiFile(0), false},
+		"garbage.azw": {[]byte("not a palm database"), false},
+	} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, tc.data, 0644)
+		if got := mobiEncrypted(path); got != tc.want {
+			t.Errorf("mobiEncrypted(%s) = %v, want %v", name, got, tc.want)
+		}
+	}
+}
+
+func TestExtractTextFromMOBI_DRM(t *testing.T) {
+	stubEbookConvert(t, "should not run", 1)
+	path := filepath.Join(t.TempDir(), "locked.azw3")
+	os.WriteFile(path, mobiFile(2), 0644)
+	if _, err := ExtractTextFromMOBI(path); !errors.Is(err, errDRMProtected) {
+		t.Errorf("encrypted header = %v, want errDRMProtected", err)
+	}
+
+	// An AZW3 (KF8) header Calibre has to open to find the DRM.
+	stubEbookConvert(t, calibreDRMOutput, 1)
+	os.WriteFile(path, mobiFile(0), 0644)
+	if _, err := ExtractTextFromMOBI(path); !errors.Is(err, errDRMProtected) {
+		t.Errorf("Calibre DRM error = %v, want errDRMProtected", err)
+	}
+
+	stubEbookConvert(t, "Traceback: something else broke", 1)
+	if _, err := ExtractTextFromMOBI(path); err == nil || errors.Is(err, errDRMProtected) {
+		t.Errorf("other Calibre failure = %v, want a generic error", err)
+	}
+}
+
+func TestChunkingFailedStatus(t *testing.T) {
+	for err, want := range map[error]string{
+		errDRMProtected:    "drm_protected",
+		errNoTextExtracted: "no_text_extracted",
+		errors.New("boom"): "chunking_failed",
+		errors.Join(errDRMProtected, errors.New("x")): "drm_protected",
+	} {
+		if got := chunkingFailedStatus(err); got != want {
+			t.Errorf("chunkingFailedStatus(%v) = %q, want %q", err, got, want)
+		}
+	}
+}
+
+func TestUploadBookFile_DRMProtectedIs422(t *testing.T) {
+	withTestDB(t)
+	t.Chdir(t.TempDir())
+	origStore := store
+	store = memStore{}
+	t.Cleanup(func() { store = origStore })
+	stubEbookConvert(t, calibreDRMOutput, 1)
+
+	book := Book{Title: "Locked", Category: "Fiction", UserID: 1}
+	db.Create(&book)
+	w := uploadRequest(t, 1, book.ID, "locked.azw3", string(mobiFile(0)), false)
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), `"`+errCodeDRMProtected+`"`) ||
+		!strings.Contains(w.Body.String(), "DRM-protected") {
+		t.Fatalf("DRM upload = %d %s", w.Code, w.Body)
+	}
+	db.First(&book, book.ID)
+	if book.Status != "drm_protected" {
+		t.Fatalf("book status %q, want drm_protected", book.Status)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"sync"
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
@@ -54,6 +55,9 @@ func InitMQTT() {
 
 	opts.OnConnect = func(c mqtt.Client) {
 		log.Printf("✅ MQTT connected to %s", broker)
+		// Replay events published while we were down. Off the callback
+		// goroutine: paho blocks publishes waiting on it.
+		go flushMQTTBuffer(c)
 	}
 	opts.OnConnectionLost = func(c mqtt.Client, err error) {
 		log.Printf("⚠️ MQTT connection lost: %v", err)
@@ -93,16 +97,83 @@ func InitMQTT() {
 //	}
 
 /*
-Guard publishes (don’t try to publish if disconnecte
-This avoids noisy errors if the broker ever restarts.
+Guard publishes (don’t try to publish if disconnected).
+Events published while the broker is unreachable — not yet connected, or
+mid-reconnect — are held in a small buffer and replayed on reconnect instead
+of being dropped.
 */
 func PublishEvent(topic string, payload []byte) {
-	if mqttClient == nil || !mqttClient.IsConnectionOpen() { // or IsConnected() if your version prefers it
-		log.Printf("⚠️ MQTT not connected; skipping publish to %s", topic)
+	if mqttClient == nil || !mqttClient.IsConnectionOpen() {
+		n, dropped := bufferMQTTEvent(topic, payload)
+		log.Printf("⚠️ MQTT not connected; buffered publish to %s (%d queued, %d dropped)", topic, n, dropped)
 		return
 	}
-	tok := mqttClient.Publish(topic, 1, false, payload)
-	if !tok.WaitTimeout(5*time.Second) || tok.Error() != nil {
-		log.Printf("⚠️ MQTT publish to %s failed: %v", topic, tok.Error())
+	if err := publishMQTT(mqttClient, topic, payload); err != nil {
+		n, _ := bufferMQTTEvent(topic, payload)
+		log.Printf("⚠️ MQTT publish to %s failed: %v (buffered, %d queued)", topic, err, n)
+	}
+}
+
+func publishMQTT(c mqtt.Client, topic string, payload []byte) error {
+	tok := c.Publish(topic, 1, false, payload)
+	if !tok.WaitTimeout(5 * time.Second) {
+		return fmt.Errorf("timed out")
+	}
+	return tok.Error()
+}
+
+// mqttEvent is a publish waiting for the broker to come back.
+type mqttEvent struct {
+	topic   string
+	payload []byte
+}
+
+var (
+	mqttBuffer   []mqttEvent
+	mqttBufferMu sync.Mutex
+)
+
+// mqttBufferSize is MQTT_BUFFER_SIZE (default 100). Events are UI hints, not
+// a durable log, so when it's full the oldest are dropped.
+func mqttBufferSize() int {
+	return envInt("MQTT_BUFFER_SIZE", 100)
+}
+
+// bufferMQTTEvent queues an event for replay, returning the queue length and
+// how many old events were dropped to make room.
+func bufferMQTTEvent(topic string, payload []byte) (queued, dropped int) {
+	mqttBufferMu.Lock()
+	defer mqttBufferMu.Unlock()
+	limit := mqttBufferSize()
+	if limit <= 0 {
+		return 0, 1
+	}
+	mqttBuffer = append(mqttBuffer, mqttEvent{topic: topic, payload: payload})
+	if over := len(mqttBuffer) - limit; over > 0 {
+		mqttBuffer = append([]mqttEvent(nil), mqttBuffer[over:]...)
+		dropped = over
+	}
+	return len(mqttBuffer), dropped
+}
+
+// flushMQTTBuffer publishes buffered events in order through c. It stops at
+// the first failure, keeping that event and the rest for the next reconnect.
+func flushMQTTBuffer(c mqtt.Client) {
+	mqttBufferMu.Lock()
+	pending := mqttBuffer
+	mqttBuffer = nil
+	mqttBufferMu.Unlock()
+
+	for i, ev := range pending {
+		if err := publishMQTT(c, ev.topic, ev.payload); err != nil {
+			log.Printf("⚠️ MQTT replay to %s failed: %v (%d events kept)", ev.topic, err, len(pending)-i)
+			mqttBufferMu.Lock()
+			mqttBuffer = append(append([]mqttEvent(nil), pending[i:]...), mqttBuffer...)
+			mqttBufferMu.Unlock()
+			return
+		}
+	}
+	if len(pending) > 0 {
+		log.Printf("📨 MQTT replayed %d buffered event(s)", len(pending))
 	}
 }
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// fakeMQTTClient implements just what PublishEvent touches; the embedded
+// interface panics if anything else is called.
+type fakeMQTTClient struct {
+	mqtt.Client
+	connected bool
+	failOn    string
+	sent      []string
+}
+
+func (f *fakeMQTTClient) IsConnectionOpen() bool { return f.connected }
+
+func (f *fakeMQTTClient) Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token {
+	if topic == f.failOn {
+		return fakeToken{err: errors.New("broker said no")}
+	}
+	f.sent = append(f.sent, topic)
+	return fakeToken{}
+}
+
+type fakeToken struct{ err error }
+
+func (fakeToken) Wait() bool                     { return true }
+func (fakeToken) WaitTimeout(time.Duration) bool { return true }
+func (fakeToken) Done() <-chan struct{}          { ch := make(chan struct{}); close(ch); return ch }
+func (t fakeToken) Error() error                 { return t.err }
+
+func resetMQTTState(t *testing.T) {
+	orig := mqttClient
+	t.Cleanup(func() {
+		mqttClient = orig
+		mqttBuffer = nil
+	})
+	mqttBuffer = nil
+}
+
+func TestPublishEvent_BuffersWhenUnavailable(t *testing.T) {
+	resetMQTTState(t)
+
+	mqttClient = nil // InitMQTT never ran / failed
+	PublishEvent("users/1/progress", []byte(`{}`))
+
+	fake := &fakeMQTTClient{connected: false}
+	mqttClient = fake // connecting / mid-reconnect
+	PublishEvent("users/2/progress", []byte(`{}`))
+
+	if len(mqttBuffer) != 2 || len(fake.sent) != 0 {
+		t.Fatalf("buffer = %d, sent = %v; want 2 buffered, none sent", len(mqttBuffer), fake.sent)
+	}
+
+	// Reconnect replays in order and empties the buffer.
+	fake.connected = true
+	flushMQTTBuffer(fake)
+	if len(fake.sent) != 2 || fake.sent[0] != "users/1/progress" || fake.sent[1] != "users/2/progress" {
+		t.Fatalf("replayed %v", fake.sent)
+	}
+	if len(mqttBuffer) != 0 {
+		t.Fatalf("buffer not drained: %d", len(mqttBuffer))
+	}
+
+	// Connected: publishes go straight out.
+	PublishEvent("users/3/progress", []byte(`{}`))
+	if len(fake.sent) != 3 || len(mqttBuffer) != 0 {
+		t.Fatalf("connected publish: sent=%v buffer=%d", fake.sent, len(mqttBuffer))
+	}
+}
+
+func TestMQTTBuffer_BoundedAndKeepsFailedReplay(t *testing.T) {
+	resetMQTTState(t)
+	t.Setenv("MQTT_BUFFER_SIZE", "2")
+	mqttClient = nil
+
+	for _, topic := range []string{"a", "b", "c"} {
+		PublishEvent(topic, nil)
+	}
+	if len(mqttBuffer) != 2 || mqttBuffer[0].topic != "b" {
+		t.Fatalf("buffer = %+v, want oldest dropped", mqttBuffer)
+	}
+
+	// A failed replay keeps the failing event and everything after it.
+	fake := &fakeMQTTClient{connected: true, failOn: "c"}
+	flushMQTTBuffer(fake)
+	if len(fake.sent) != 1 || len(mqttBuffer) != 1 || mqttBuffer[0].topic != "c" {
+		t.Fatalf("sent=%v buffer=%+v", fake.sent, mqttBuffer)
+	}
+}
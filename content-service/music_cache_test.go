@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// stubMusicGeneration renders clips into a temp dir, records each
+// generation's prompt and length, and starts from an empty cache.
+func stubMusicGeneration(t *testing.T) *[]string {
+	t.Helper()
+	dir := t.TempDir()
+	origGen, origCache := generateMusicClip, musicCache
+	musicCache = map[string]string{}
+	var calls []string
+	generateMusicClip = func(_ context.Context, prompt string, durationSec float64, id ...interface{}) (string, error) {
+		calls = append(calls, prompt)
+		path := filepath.Join(dir, musicClipKey(prompt, durationSec)+".mp3")
+		return path, os.WriteFile(path, []byte("ID3"), 0644)
+	}
+	t.Cleanup(func() { generateMusicClip, musicCache = origGen, origCache })
+	return &calls
+}
+
+func TestBackgroundMusic_IdenticalPromptReusesClip(t *testing.T) {
+	calls := stubMusicGeneration(t)
+
+	first, err := getOrGenerateBackgroundMusic("soft strings, melancholy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := getOrGenerateBackgroundMusic("soft strings, melancholy")
+	if err != nil || again != first {
+		t.Fatalf("repeat prompt = %q, %v; want cached %q", again, err, first)
+	}
+	if len(*calls) != 1 {
+		t.Fatalf("generated %d clips for one prompt, want 1", len(*calls))
+	}
+
+	other, err := getOrGenerateBackgroundMusic("driving drums, chase")
+	if err != nil || other == first || len(*calls) != 2 {
+		t.Fatalf("new prompt = %q (%d generations), want a fresh clip", other, len(*calls))
+	}
+}
+
+func TestBackgroundMusic_ClipLengthIsPartOfTheKey(t *testing.T) {
+	calls := stubMusicGeneration(t)
+
+	t.Setenv("BG_MUSIC_CLIP_SECONDS", "22")
+	long, _ := getOrGenerateBackgroundMusic("soft strings")
+	t.Setenv("BG_MUSIC_CLIP_SECONDS", "10")
+	short, _ := getOrGenerateBackgroundMusic("soft strings")
+	if long == short || len(*calls) != 2 {
+		t.Fatalf("clip length change reused %q (%d generations)", long, len(*calls))
+	}
+}
+
+func TestBackgroundClipSeconds(t *testing.T) {
+	for env, want := range map[string]float64{"": 22, "12": 12, "0": 22, "90": 22, "junk": 22} {
+		t.Setenv("BG_MUSIC_CLIP_SECONDS", env)
+		if got := backgroundClipSeconds(); got != want {
+			t.Errorf("BG_MUSIC_CLIP_SECONDS=%q: %v, want %v", env, got, want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"log"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// The OpenAI TTS voice set. Every OpenAI voice the pipeline casts (narrator,
+// pools, unattributed dialogue) is named here, and voices arriving from
+// outside — preview and override requests, a book's persisted cast — are
+// checked against the engine's full list instead of being sent as-is and
+// failing at OpenAI. OPENAI_TTS_EXTRA_VOICES (comma-separated) admits voices
+// OpenAI adds later without a code change; they can be requested but are
+// never cast automatically.
+
+// Voice is an OpenAI TTS voice id.
+type Voice string
+
+// gpt-4o-mini-tts voices.
+const (
+	VoiceAlloy   Voice = "alloy"
+	VoiceAsh     Voice = "ash"
+	VoiceBallad  Voice = "ballad"
+	VoiceCoral   Voice = "coral"
+	VoiceEcho    Voice = "echo"
+	VoiceFable   Voice = "fable"
+	VoiceNova    Voice = "nova"
+	VoiceOnyx    Voice = "onyx"
+	VoiceSage    Voice = "sage"
+	VoiceShimmer Voice = "shimmer"
+	VoiceVerse   Voice = "verse"
+)
+
+// openaiVoices is the canonical set.
+var openaiVoices = []Voice{
+	VoiceAlloy, VoiceAsh, VoiceBallad, VoiceCoral, VoiceEcho, VoiceFable,
+	VoiceNova, VoiceOnyx, VoiceSage, VoiceShimmer, VoiceVerse,
+}
+
+// voiceIDPattern is what a configured extra voice id must look like.
+var voiceIDPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,31}$`)
+
+// voiceIDs converts voices to the plain ids engine configs carry.
+func voiceIDs(vs ...Voice) []string {
+	out := make([]string, len(vs))
+	for i, v := range vs {
+		out[i] = string(v)
+	}
+	return out
+}
+
+// openaiVoiceIDs is the canonical set plus OPENAI_TTS_EXTRA_VOICES; invalid
+// or duplicate extras are logged and skipped.
+func openaiVoiceIDs() []string {
+	ids := voiceIDs(openaiVoices...)
+	seen := map[string]bool{}
+	for _, id := range ids {
+		seen[id] = true
+	}
+	for _, raw := range strings.Split(os.Getenv("OPENAI_TTS_EXTRA_VOICES"), ",") {
+		id := strings.ToLower(strings.TrimSpace(raw))
+		switch {
+		case id == "" || seen[id]:
+		case !voiceIDPattern.MatchString(id):
+			log.Printf("⚠️ OPENAI_TTS_EXTRA_VOICES: ignoring invalid voice id %q", raw)
+		default:
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// engineHasVoice reports whether cfg can render voice.
+func engineHasVoice(cfg *ttsEngineConfig, voice string) bool {
+	for _, v := range engineVoices(cfg) {
+		if v == voice {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestOpenAIVoices_CastVoicesAreCanonical(t *testing.T) {
+	canon := voiceIDs(openaiVoices...)
+	cast := []string{VoiceNarrator, VoiceMale, VoiceFemale, unknownDialogueVoice, openaiEngine.NarratorVoice, openaiEngine.UnknownVoice}
+	cast = append(cast, maleVoicePool...)
+	cast = append(cast, femaleVoicePool...)
+	cast = append(cast, unknownVoicePool...)
+	for _, v := range cast {
+		if !slices.Contains(canon, v) {
+			t.Errorf("voice %q is not in the canonical OpenAI set", v)
+		}
+		if !engineHasVoice(&openaiEngine, v) {
+			t.Errorf("openai engine rejects its own voice %q", v)
+		}
+	}
+}
+
+func TestOpenAIVoices_Extras(t *testing.T) {
+	t.Setenv("OPENAI_TTS_EXTRA_VOICES", " Marin, cedar ,alloy,bad voice!,")
+	ids := openaiVoiceIDs()
+	if len(ids) != len(openaiVoices)+2 {
+		t.Fatalf("ids = %v", ids)
+	}
+	for _, want := range []string{"marin", "cedar"} {
+		if !slices.Contains(ids, want) {
+			t.Errorf("extra %q missing from %v", want, ids)
+		}
+	}
+	if slices.Contains(ids, "bad voice!") {
+		t.Errorf("invalid extra accepted: %v", ids)
+	}
+}
+
+func TestEngineHasVoice_RejectsUnknown(t *testing.T) {
+	for _, v := range []string{"", "Alloy", "bm_george", "nova "} {
+		if engineHasVoice(&openaiEngine, v) {
+			t.Errorf("engineHasVoice(openai, %q) = true", v)
+		}
+	}
+	if _, _, err := resolvePreviewSettings(previewRequest{Voice: "unicorn"}, &openaiEngine); err == nil {
+		t.Error("preview accepted an unknown voice")
+	}
+}
+
+func TestAssignSegmentVoices_RecastsUnknownVoice(t *testing.T) {
+	vm := map[string]CharacterVoice{"anna": {Gender: "female", Voice: "bf_emma"}}
+	segs := []DialogueSegment{{IsDialogue: true, Speaker: "Anna", Gender: "male"}}
+	if !assignSegmentVoices(vm, segs, &openaiEngine) {
+		t.Error("recast not reported as a change")
+	}
+	got := vm["anna"]
+	if got.Gender != "female" || !slices.Contains(femaleVoicePool, got.Voice) || segs[0].Voice != got.Voice {
+		t.Errorf("recast = %+v, segment voice %q", got, segs[0].Voice)
+	}
+}
@@ -17,19 +17,19 @@ func requireBookOwnership() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID := getUserIDFromContext(c)
 		if userID == 0 {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			abortWithError(c, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
 			return
 		}
 
 		bookID, err := strconv.ParseUint(c.Param("book_id"), 10, 64)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid book_id"})
+			abortWithError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid book_id")
 			return
 		}
 
 		book, err := verifyBookOwnership(uint(bookID), userID)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+			abortWithError(c, http.StatusNotFound, errCodeBookNotFound, "Book not found")
 			return
 		}
 
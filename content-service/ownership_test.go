@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+// Every /user route addressing a :book_id must run requireBookOwnership, so a
+// missing and a foreign book answer the same 404 instead of leaking which IDs
+// exist (or, worse, serving someone else's book).
+func TestUserRoutes_BookIDRoutesRequireOwnership(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	var chain []string
+	r.Use(func(c *gin.Context) {
+		chain = c.HandlerNames()
+		c.AbortWithStatus(http.StatusNoContent) // inspect only; run nothing
+	})
+	registerUserRoutes(r.Group("/user"))
+
+	checked := 0
+	for _, route := range r.Routes() {
+		if !strings.Contains(route.Path, ":book_id") {
+			continue
+		}
+		path := strings.NewReplacer(":book_id", "1", ":start", "1", ":end", "2", ":page", "1", ":upload_id", "x", ":part", "1", ":share_id", "1").Replace(route.Path)
+		chain = nil
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(route.Method, path, nil))
+		if !strings.Contains(strings.Join(chain, " "), "requireBookOwnership") {
+			t.Errorf("%s %s runs %v without requireBookOwnership", route.Method, route.Path, chain)
+		}
+		checked++
+	}
+	if checked < 20 {
+		t.Fatalf("only %d :book_id routes found — route registration changed?", checked)
+	}
+}
+
+// A book that exists but belongs to someone else is indistinguishable from
+// one that doesn't exist on every read endpoint.
+func TestBookReadRoutes_NonOwnerGets404(t *testing.T) {
+	withTestDB(t)
+	book := Book{Title: "Private", Category: "Fiction", UserID: 1, AudioPath: "audio/private.mp3"}
+	if err := db.Create(&book).Error; err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&BookChunk{BookID: book.ID, Index: 0, Content: "secret", TTSStatus: "completed", AudioPath: "audio/p0.mp3"})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	g := r.Group("/user", func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"user_id": float64(2)})
+		c.Set("user_id", uint(2))
+	})
+	registerUserRoutes(g)
+
+	reads := []string{
+		"/user/books/%d",
+		"/user/books/%d/chunks/pages",
+		"/user/books/%d/chunks/processed",
+		"/user/books/%d/tts/batch",
+		"/user/books/%d/progress",
+		"/user/books/%d/pages/1/audio",
+		"/user/books/%d/pages/1/hls.m3u8",
+		"/user/books/stream/proxy/%d",
+		"/user/chunks/tts/merged-audio/%d",
+	}
+	for _, tmpl := range reads {
+		for _, id := range []uint{book.ID, book.ID + 1000} {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf(tmpl, id), nil))
+			var body struct {
+				Code string `json:"code"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &body)
+			if w.Code != http.StatusNotFound || body.Code != errCodeBookNotFound {
+				t.Errorf("GET %s = %d %s, want 404 %s", fmt.Sprintf(tmpl, id), w.Code, w.Body, errCodeBookNotFound)
+			}
+		}
+	}
+}
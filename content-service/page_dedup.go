@@ -36,9 +36,10 @@ type RenderedPage struct {
 	ID uint `gorm:"primaryKey"`
 	// One row per unique (content_hash, engine).
 	ContentHash string    `gorm:"size:64;uniqueIndex:idx_rendered_page,priority:1"`
-	Engine      string    `gorm:"size:32;uniqueIndex:idx_rendered_page,priority:2"`
+	Engine      string    `gorm:"size:64;uniqueIndex:idx_rendered_page,priority:2"`
 	AudioKey    string    `gorm:"size:255"`      // shared R2 key of the mixed final audio
 	VoiceMap    string    `gorm:"type:text"`     // cast used, so reusers stay consistent
+	Transcript  string    `gorm:"type:text"`     // what was narrated (transcript.go), copied to reusers
 	CreatedAt   time.Time
 }
 
@@ -81,9 +82,26 @@ func dedupEngineKey(book Book) string {
 	if dlg := hybridDialogueEngine(base); dlg != nil {
 		key += "+" + dlg.Name
 	}
+	// Non-default containers get their own namespace so an mp3 rendering is
+	// never served to a book that asked for opus/aac (mp3 keeps the old keys).
+	if f := audioFormatFor(book); f.Name != "mp3" {
+		key += "+" + f.Name
+	}
+	// A per-book Foley level is part of the mix: such a book neither reuses
+	// nor shares audio mixed at another level. Books on the service default
+	// keep the old keys.
+	if book.FoleyVolume != nil && isUnitInterval(*book.FoleyVolume) {
+		key += fmt.Sprintf("+f%.2f", *book.FoleyVolume)
+	}
 	return key + "-r" + renderVersion
 }
 
+// jobDedupEngineKey is dedupEngineKey for a job that may carry a one-off
+// voice/language override (tts_override.go) on ctx.
+func jobDedupEngineKey(ctx context.Context, book Book) string {
+	return dedupEngineKey(book) + ttsOverrideFrom(ctx).dedupSuffix()
+}
+
 // loadVoiceMapJSON returns the book's persisted voice_map as a raw JSON
 // string (empty string if none) for storing alongside a shared rendering.
 func loadVoiceMapJSON(bookID uint) string {
@@ -109,8 +127,8 @@ func lookupRenderedPage(hash, engine string) (*RenderedPage, bool) {
 // registerRenderedPage records a fresh rendering so later books reuse it.
 // Idempotent: a concurrent duplicate insert loses harmlessly (both point at
 // equivalent audio for the same text).
-func registerRenderedPage(hash, engine, audioKey, voiceMapJSON string) {
-	rp := RenderedPage{ContentHash: hash, Engine: engine, AudioKey: audioKey, VoiceMap: voiceMapJSON}
+func registerRenderedPage(hash, engine, audioKey, voiceMapJSON, transcriptJSON string) {
+	rp := RenderedPage{ContentHash: hash, Engine: engine, AudioKey: audioKey, VoiceMap: voiceMapJSON, Transcript: transcriptJSON}
 	if err := db.Where("content_hash = ? AND engine = ?", hash, engine).
 		FirstOrCreate(&rp, rp).Error; err != nil {
 		log.Printf("⚠️ [Dedup] register failed for %s/%s: %v", engine, hash[:8], err)
@@ -267,9 +285,9 @@ func sharedAudioGCLoop() {
 // text+engine was already rendered for any book. Returns true if the chunk
 // was completed by reuse (caller must skip the pipeline). HLS is re-packaged
 // per-book from the shared audio (cheap, no AI cost).
-func reuseRenderedPageForChunk(book Book, chunk BookChunk) bool {
+func reuseRenderedPageForChunk(ctx context.Context, book Book, chunk BookChunk) bool {
 	hash := contentHash(chunk.Content)
-	engine := dedupEngineKey(book)
+	engine := jobDedupEngineKey(ctx, book)
 	rp, ok := lookupRenderedPage(hash, engine)
 	if !ok {
 		return false
@@ -289,6 +307,9 @@ func reuseRenderedPageForChunk(book Book, chunk BookChunk) bool {
 		"final_audio_path": rp.AudioKey,
 		"tts_status":       "completed",
 		"hls_path":         "", // re-package HLS per book below
+		// The donor's transcript, or none: whatever this page had before
+		// described other audio.
+		"transcript": rp.Transcript,
 	}).Error; err != nil {
 		log.Printf("⚠️ [Dedup] chunk update failed for book %d page %d: %v", book.ID, chunk.Index, err)
 		return false
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Re-running a page's music + Foley mix on the narration it already has. A
+// page rendered before effects existed (or before an effect setting changed)
+// gets them without paying for TTS again: the chunk's TTS audio goes through
+// the same mix as a fresh page (mixPageEffects) and replaces its final audio.
+//
+// The remix belongs to this book alone: it is stored under the book's own
+// audio/{book_id}/ prefix, never the shared content-addressed key that other
+// books with the same page text reuse (page_dedup.go), and isn't registered
+// as a rendered page.
+//
+// Only pages that kept their clean narration qualify. The batch pipeline
+// stores the mixed file as the page's audio_path too, so for those pages
+// there is nothing clean to remix and the book has to be reprocessed.
+
+// applyPageEffectsHandler remixes one page from its stored TTS audio.
+// POST /user/books/:book_id/pages/:page/effects (page is 1-based)
+func applyPageEffectsHandler(c *gin.Context) {
+	book := c.MustGet("book").(Book)
+	page, err := strconv.Atoi(c.Param("page"))
+	if err != nil || page < 1 {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid page number")
+		return
+	}
+
+	var chunk BookChunk
+	if err := db.Where("book_id = ? AND \"index\" = ?", book.ID, page-1).First(&chunk).Error; err != nil {
+		respondError(c, http.StatusNotFound, errCodePageNotFound, "Page not found")
+		return
+	}
+	if chunk.TTSStatus != "completed" || chunk.AudioPath == "" {
+		respondError(c, http.StatusConflict, errCodeAudioNotReady, "Page has no narration yet; transcribe it first")
+		return
+	}
+	if !soundEffectsAvailable() {
+		respondError(c, http.StatusServiceUnavailable, errCodeServiceUnavailable, "Sound effects are unavailable right now")
+		return
+	}
+	if chunk.AudioPath == chunk.FinalAudioPath {
+		respondError(c, http.StatusConflict, errCodeConflict, "Page narration was stored already mixed; reprocess the book to add effects")
+		return
+	}
+	if !claimMerge(book.ID, chunk.Index) {
+		respondError(c, http.StatusConflict, errCodeConflict, "Page is being mixed already")
+		return
+	}
+	defer releaseMerge(book.ID, chunk.Index)
+
+	ctx := c.Request.Context()
+	ttsLocal, cleanup, err := localizeMedia(ctx, chunk.AudioPath)
+	if err != nil {
+		respondInternal(c, "Could not load page narration", err)
+		return
+	}
+	defer cleanup()
+
+	mixedPath, skipped, err := mixPageEffects(ctx, ttsLocal, book, chunk, book.ContentHash)
+	if err != nil {
+		respondInternal(c, "Could not apply effects", err)
+		return
+	}
+	if skipped {
+		// The sound API dropped out mid-mix; keep the page as it was.
+		respondError(c, http.StatusServiceUnavailable, errCodeServiceUnavailable, "Sound effects are unavailable right now")
+		return
+	}
+	key, err := publishRemixedPage(book, chunk, mixedPath)
+	if err != nil {
+		respondInternal(c, "Could not store page audio", err)
+		return
+	}
+	log.Printf("🎚️ Book %d page %d remixed with effects → %s", book.ID, page, key)
+	c.JSON(http.StatusOK, gin.H{"message": "Effects applied", "page": page, "final_audio_path": key})
+}
+
+// publishRemixedPage uploads a remix under a per-book key named for its
+// content, points the page at it and drops this book's cached page ranges
+// that include the page.
+func publishRemixedPage(book Book, chunk BookChunk, mixedPath string) (string, error) {
+	hash, err := computeFileHash(mixedPath)
+	if err != nil {
+		return "", err
+	}
+	key := audioPageKey(book.ID, chunk.Index, hash, filepath.Ext(mixedPath))
+	if _, err := uploadArtifact(context.Background(), mixedPath, key); err != nil {
+		return "", err
+	}
+	if err := setPageFinalAudio(book, chunk.Index, key); err != nil {
+		return "", err
+	}
+	if err := db.Where("book_id = ? AND start_idx <= ? AND end_idx >= ?", book.ID, chunk.Index, chunk.Index).
+		Delete(&ProcessedChunkGroup{}).Error; err != nil {
+		log.Printf("⚠️ Book %d: could not clear cached page ranges for page %d: %v", book.ID, chunk.Index, err)
+	}
+	return key, nil
+}
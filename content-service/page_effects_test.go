@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stubPageMix replaces TTS with a failure and the mix with a copy that tags
+// the narration, recording which narration file each mix started from.
+func stubPageMix(t *testing.T) (*[]string, *int) {
+	t.Helper()
+	var mixed []string
+	ttsCalls := 0
+	origMix, origSynth, origStore := mixPageEffects, chunkSynthesize, store
+	mixPageEffects = func(_ context.Context, ttsLocal string, book Book, chunk BookChunk, _ string) (string, bool, error) {
+		mixed = append(mixed, ttsLocal)
+		narration, err := os.ReadFile(ttsLocal)
+		if err != nil {
+			return "", false, err
+		}
+		out := filepath.Join(t.TempDir(), "mixed.mp3")
+		return out, false, os.WriteFile(out, append(narration, " +music +foley"...), 0644)
+	}
+	chunkSynthesize = func(context.Context, BookChunk) (string, error) {
+		ttsCalls++
+		return "", os.ErrInvalid
+	}
+	store = memStore{}
+	t.Setenv("XI_API_KEY", "test")
+	t.Cleanup(func() { mixPageEffects, chunkSynthesize, store = origMix, origSynth, origStore })
+	return &mixed, &ttsCalls
+}
+
+func pageEffectsRouter(book Book) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/user/books/:book_id/pages/:page/effects", func(c *gin.Context) {
+		c.Set("book", book)
+		applyPageEffectsHandler(c)
+	})
+	return r
+}
+
+func TestApplyPageEffects_RemixesWithoutTTS(t *testing.T) {
+	withTestDB(t)
+	mixed, ttsCalls := stubPageMix(t)
+
+	narration := filepath.Join(t.TempDir(), "page2_tts.mp3")
+	os.WriteFile(narration, []byte("ID3 narration"), 0644)
+	book := Book{Title: "Emma", Category: "Fiction", UserID: 7}
+	db.Create(&book)
+	db.Create(&BookChunk{BookID: book.ID, Index: 1, Content: "Emma Woodhouse, handsome, clever, and rich.",
+		AudioPath: narration, FinalAudioPath: "audio/old-clean.mp3", HLSPath: "hls/old.m3u8", TTSStatus: "completed"})
+	db.Create(&ProcessedChunkGroup{BookID: book.ID, StartIdx: 0, EndIdx: 4, AudioPath: "audio/range_0_4.mp3"})
+	db.Create(&ProcessedChunkGroup{BookID: book.ID, StartIdx: 5, EndIdx: 9, AudioPath: "audio/range_5_9.mp3"})
+
+	w := httptest.NewRecorder()
+	pageEffectsRouter(book).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/user/books/1/pages/2/effects", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("effects = %d %s", w.Code, w.Body)
+	}
+	if *ttsCalls != 0 {
+		t.Fatalf("TTS ran %d times; effects must reuse the narration", *ttsCalls)
+	}
+	if len(*mixed) != 1 || (*mixed)[0] != narration {
+		t.Fatalf("mixed %v, want the stored narration %s", *mixed, narration)
+	}
+
+	var chunk BookChunk
+	db.Where("book_id = ? AND \"index\" = ?", book.ID, 1).First(&chunk)
+	if chunk.FinalAudioPath == "audio/old-clean.mp3" || chunk.HLSPath != "" || chunk.AudioPath != narration {
+		t.Fatalf("chunk after remix = final %q hls %q audio %q", chunk.FinalAudioPath, chunk.HLSPath, chunk.AudioPath)
+	}
+	if size := store.(memStore)[chunk.FinalAudioPath]; size != int64(len("ID3 narration +music +foley")) {
+		t.Fatalf("stored %s is %d bytes, want the effects mix", chunk.FinalAudioPath, size)
+	}
+
+	// The remix is this book's own: a per-book key, nothing shared registered.
+	if !strings.HasPrefix(chunk.FinalAudioPath, fmt.Sprintf("audio/%d/", book.ID)) {
+		t.Errorf("remix stored at %q, want a key under audio/%d/", chunk.FinalAudioPath, book.ID)
+	}
+	var shared int64
+	db.Model(&RenderedPage{}).Count(&shared)
+	if shared != 0 {
+		t.Errorf("remix registered as %d shared rendered page(s)", shared)
+	}
+	var ranges []ProcessedChunkGroup
+	db.Where("book_id = ?", book.ID).Find(&ranges)
+	if len(ranges) != 1 || ranges[0].StartIdx != 5 {
+		t.Errorf("cached ranges after remix = %+v, want only 5–9 left", ranges)
+	}
+}
+
+func TestApplyPageEffects_RequiresCleanNarration(t *testing.T) {
+	withTestDB(t)
+	mixed, _ := stubPageMix(t)
+
+	book := Book{Title: "Emma", Category: "Fiction", UserID: 7}
+	db.Create(&book)
+	db.Create(&BookChunk{BookID: book.ID, Index: 0, AudioPath: "", TTSStatus: "pending"})
+	db.Create(&BookChunk{BookID: book.ID, Index: 1, AudioPath: "audio/mixed.mp3", FinalAudioPath: "audio/mixed.mp3", TTSStatus: "completed"})
+
+	r := pageEffectsRouter(book)
+	for page, want := range map[string]string{
+		"1": errCodeAudioNotReady, // not transcribed yet
+		"2": errCodeConflict,      // only the mixed file was kept
+		"9": errCodePageNotFound,
+		"0": errCodeInvalidRequest,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/user/books/1/pages/"+page+"/effects", nil))
+		if !strings.Contains(w.Body.String(), `"`+want+`"`) {
+			t.Errorf("page %s = %d %s, want %s", page, w.Code, w.Body, want)
+		}
+	}
+	if len(*mixed) != 0 {
+		t.Fatalf("mixed %v for pages that can't be remixed", *mixed)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxStreamRangePages bounds one /pages/:page/:end/stream request so a single
+// call can't make the API concatenate a whole book.
+const maxStreamRangePages = 50
+
+// parsePageRange validates 1-based start/end page params and returns the
+// 0-based chunk indexes they cover.
+func parsePageRange(startStr, endStr string) (startIdx, endIdx int, err error) {
+	start, err1 := strconv.Atoi(startStr)
+	end, err2 := strconv.Atoi(endStr)
+	switch {
+	case err1 != nil || err2 != nil:
+		return 0, 0, errors.New("start and end must be page numbers")
+	case start < 1 || start > end:
+		return 0, 0, errors.New("require 1 <= start <= end")
+	case end-start+1 > maxStreamRangePages:
+		return 0, 0, fmt.Errorf("at most %d pages per stream", maxStreamRangePages)
+	}
+	return start - 1, end - 1, nil
+}
+
+func pageRangeAudioKey(bookID uint, start, end int, ext string) string {
+	return fmt.Sprintf("audio/%d/pages_%d_%d%s", bookID, start, end, ext)
+}
+
+// concatPageAudio joins the chunks' finished page audio, in order, into
+// outPath (which carries format's extension). Pages normally already share
+// the book's format, so the streams are copied rather than re-encoded; a range
+// that straddles a format change is re-encoded.
+func concatPageAudio(ctx context.Context, chunks []BookChunk, outPath string, format audioFormat) error {
+	list, err := os.CreateTemp("", "pages-*.txt")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(list.Name())
+
+	sameFormat := true
+	for _, ch := range chunks {
+		local, cleanup, err := localizeMedia(ctx, ch.FinalAudioPath)
+		if err != nil {
+			list.Close()
+			return fmt.Errorf("page %d audio: %w", ch.Index+1, err)
+		}
+		defer cleanup()
+		abs, _ := filepath.Abs(local)
+		fmt.Fprintf(list, "file '%s'\n", abs)
+		if filepath.Ext(ch.FinalAudioPath) != format.Ext {
+			sameFormat = false
+		}
+	}
+	if err := list.Close(); err != nil {
+		return err
+	}
+
+	args := []string{"-y", "-f", "concat", "-safe", "0", "-i", list.Name()}
+	if sameFormat {
+		args = append(args, "-c", "copy")
+	} else {
+		args = append(args, format.CodecArgs...)
+	}
+	if out, err := runFFmpegAtomic(outPath, args...); err != nil {
+		return fmt.Errorf("ffmpeg concat: %v\n%s", err, out)
+	}
+	return nil
+}
+
+// streamPageRangeHandler serves pages [start, end] (1-based, inclusive) of a
+// book as one audio file so a client can play a chapter without stitching
+// pages itself. The joined file is built once, uploaded, and recorded as a
+// ProcessedChunkGroup; later requests for the same range reuse it. Range
+// requests are honored by the store's presigned URL (or http.ServeFile for
+// legacy on-disk audio).
+//
+// GET /user/books/:book_id/pages/:page/:end/stream — gin requires the start
+// wildcard to share the :page name of the sibling per-page routes.
+func streamPageRangeHandler(c *gin.Context) {
+	book := c.MustGet("book").(Book)
+
+	startIdx, endIdx, err := parsePageRange(c.Param("page"), c.Param("end"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
+	// Streaming quota: a range stream counts as the pages it covers, whether
+	// it's built now or served from a cached join.
+	cachedPath, cached := checkIfChunkGroupProcessed(book.ID, startIdx, endIdx)
+	var chunks []BookChunk
+	if !cached {
+		if err := db.Where("book_id = ? AND \"index\" BETWEEN ? AND ?", book.ID, startIdx, endIdx).
+			Order("\"index\" ASC").Find(&chunks).Error; err != nil {
+			respondError(c, http.StatusInternalServerError, errCodeInternal, "Could not fetch pages")
+			return
+		}
+		if len(chunks) != endIdx-startIdx+1 {
+			respondError(c, http.StatusNotFound, errCodePageNotFound, "Page range out of bounds")
+			return
+		}
+		for _, ch := range chunks {
+			if ch.FinalAudioPath == "" {
+				respondErrorWith(c, http.StatusConflict, errCodeAudioNotReady, "Audio not ready for every page in range", gin.H{"page": ch.Index + 1})
+				return
+			}
+		}
+	}
+	if d := checkAndConsume(getUserIDFromContext(c), accountTypeFromClaims(c), "stream_pages", int64(endIdx-startIdx+1), book.ID); !d.Allowed {
+		quota429(c, d)
+		return
+	}
+	if cached {
+		serveMedia(c, cachedPath)
+		return
+	}
+
+	format := audioFormatFor(book)
+	ext := format.Ext
+	out := fmt.Sprintf("./audio/book_%d_pages_%d_%d%s", book.ID, startIdx, endIdx, ext)
+	if err := concatPageAudio(c.Request.Context(), chunks, out, format); err != nil {
+		log.Printf("❌ Page range %d-%d for book %d: %v", startIdx+1, endIdx+1, book.ID, err)
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "Could not build audio for range")
+		return
+	}
+	key, err := uploadArtifact(c.Request.Context(), out, pageRangeAudioKey(book.ID, startIdx, endIdx, ext))
+	if err != nil {
+		os.Remove(out)
+		log.Printf("❌ Upload page range %d-%d for book %d: %v", startIdx+1, endIdx+1, book.ID, err)
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "Could not store audio for range")
+		return
+	}
+	if err := saveProcessedChunkGroup(book.ID, startIdx, endIdx, key); err != nil {
+		log.Printf("⚠️ Could not cache page range %d-%d for book %d: %v", startIdx+1, endIdx+1, book.ID, err)
+	}
+	log.Printf("🎧 Built page range %d-%d for book %d (%d pages)", startIdx+1, endIdx+1, book.ID, len(chunks))
+	serveMedia(c, key)
+}
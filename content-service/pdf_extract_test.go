@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// stubPDFExtractors swaps the native extractor and points pdftotextBin at a
+// script printing out (or at nothing when out is empty).
+func stubPDFExtractors(t *testing.T, native string, out string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell-script fake binaries need a POSIX shell")
+	}
+	origNative, origBin := extractPDFNative, pdftotextBin
+	t.Cleanup(func() { extractPDFNative, pdftotextBin = origNative, origBin })
+
+	extractPDFNative = func(string) (string, error) { return native, nil }
+	dir := t.TempDir()
+	pdftotextBin = filepath.Join(dir, "missing-pdftotext")
+	if out != "" {
+		pdftotextBin = filepath.Join(dir, "pdftotext")
+		script := "#!/bin/sh\nprintf '%s' '" + out + "'\n"
+		if err := os.WriteFile(pdftotextBin, []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pdf := filepath.Join(dir, "book.pdf")
+	if err := os.WriteFile(pdf, make([]byte, 64*1024), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return pdf
+}
+
+func TestExtractTextFromPDF_EmptyNativeFallsBackToPdftotext(t *testing.T) {
+	pdf := stubPDFExtractors(t, "", "Chapter One. It was a dark and stormy night.")
+	got, err := ExtractTextFromPDF(pdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Chapter One. It was a dark and stormy night." {
+		t.Fatalf("ExtractTextFromPDF = %q, want pdftotext output", got)
+	}
+}
+
+func TestExtractTextFromPDF_KeepsSparseNativeWithoutPdftotext(t *testing.T) {
+	pdf := stubPDFExtractors(t, "Title page", "")
+	got, err := ExtractTextFromPDF(pdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Title page" {
+		t.Fatalf("ExtractTextFromPDF = %q, want the native text", got)
+	}
+}
+
+func TestPDFTextUnusable(t *testing.T) {
+	prose := strings.Repeat("It was a dark and stormy night. ", 40) // ~1.3KB
+	cases := []struct {
+		name string
+		text string
+		size int64
+		want bool
+	}{
+		{"empty", "  \n", 1024, true},
+		{"dense prose", prose, 100 * 1024, false},
+		{"sparse for file size", "Title page", 100 * 1024, true},
+		{"glyph soup", strings.Repeat("#$%&'()*+ ", 200), 1024, true},
+	}
+	for _, tc := range cases {
+		if got := pdfTextUnusable(tc.text, tc.size); got != tc.want {
+			t.Errorf("%s: pdfTextUnusable = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestExtractTextFromPDF_TextlessPDFUsesOCR(t *testing.T) {
+	pdf := stubPDFExtractors(t, "", "")
+	dir := t.TempDir()
+	t.Setenv("PATH", dir) // no ebook-convert, so Calibre comes back empty-handed
+	t.Setenv("ENABLE_OCR", "true")
+	t.Setenv("OCR_MAX_PAGES", "2")
+
+	origPPM, origTess := pdftoppmBin, tesseractBin
+	t.Cleanup(func() { pdftoppmBin, tesseractBin = origPPM, origTess })
+	pdftoppmBin = filepath.Join(dir, "pdftoppm")
+	tesseractBin = filepath.Join(dir, "tesseract")
+	// Fake pdftoppm writes one image per page allowed by -l (arg 6); its last
+	// argument is the output prefix. Fake tesseract "reads" the image name.
+	scripts := map[string]string{
+		pdftoppmBin:  "#!/bin/sh\nfor a; do p=$a; done\ni=1; while [ $i -le $6 ]; do : > \"$p-$i.png\"; i=$((i+1)); done\n",
+		tesseractBin: "#!/bin/sh\necho \"Scanned text from ${1##*/}\"\n",
+	}
+	for p, s := range scripts {
+		if err := os.WriteFile(p, []byte(s), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := ExtractTextFromPDF(pdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Scanned text from page-1.png\n\nScanned text from page-2.png\n\n"
+	if got != want {
+		t.Fatalf("ExtractTextFromPDF = %q, want %q", got, want)
+	}
+
+	t.Setenv("ENABLE_OCR", "false")
+	if _, err := ExtractTextFromPDF(pdf); err == nil {
+		t.Fatal("OCR ran with ENABLE_OCR=false")
+	}
+}
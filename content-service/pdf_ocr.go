@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OCR for scanned/image-only PDFs: pages are rasterized with poppler's
+// pdftoppm and read with tesseract. Off unless ENABLE_OCR=true, and only tried
+// once every text extractor has come back empty.
+var (
+	pdftoppmBin  = getEnv("PDFTOPPM_PATH", "pdftoppm")
+	tesseractBin = getEnv("TESSERACT_PATH", "tesseract")
+)
+
+// ocrTimeout bounds a whole OCR run (rasterizing plus every page).
+const ocrTimeout = 20 * time.Minute
+
+func ocrEnabled() bool { return getEnv("ENABLE_OCR", "false") == "true" }
+
+// ocrMaxPages caps how many leading pages are OCR'd; tesseract costs seconds
+// per page, so an unbounded scan of a 1,000-page book would tie up a worker.
+func ocrMaxPages() int { return envInt("OCR_MAX_PAGES", 50) }
+
+// extractPDFViaOCR rasterizes up to ocrMaxPages pages at 300 DPI and returns
+// tesseract's text for each, pages separated by blank lines. OCR_LANG picks
+// the tesseract language pack (default eng).
+func extractPDFViaOCR(path string) (string, error) {
+	for _, bin := range []string{pdftoppmBin, tesseractBin} {
+		if _, err := exec.LookPath(bin); err != nil {
+			return "", fmt.Errorf("OCR unavailable: %w", err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ocrTimeout)
+	defer cancel()
+
+	dir, err := os.MkdirTemp("", "ocr-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	prefix := filepath.Join(dir, "page")
+	if out, err := exec.CommandContext(ctx, pdftoppmBin,
+		"-r", "300", "-gray", "-png", "-l", strconv.Itoa(ocrMaxPages()), path, prefix,
+	).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("pdftoppm failed: %w. Details: %s", err, out)
+	}
+	// pdftoppm zero-pads page numbers to a common width, so name order is
+	// page order.
+	pages, _ := filepath.Glob(prefix + "-*.png")
+	sort.Strings(pages)
+	if len(pages) == 0 {
+		return "", errNoTextExtracted
+	}
+
+	lang := getEnv("OCR_LANG", "eng")
+	var sb strings.Builder
+	for i, page := range pages {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, tesseractBin, page, "stdout", "-l", lang)
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		if err := cmd.Run(); err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return "", fmt.Errorf("OCR timed out after %s", ocrTimeout)
+			}
+			log.Printf("⚠️ tesseract failed on page %d of %s: %v %s", i+1, path, err, stderr.String())
+			continue
+		}
+		if text := strings.TrimSpace(cleanUTF8(stdout.Bytes())); text != "" {
+			sb.WriteString(text)
+			sb.WriteString("\n\n")
+		}
+	}
+	log.Printf("🔎 OCR read %d page(s) of %s", len(pages), path)
+	return sb.String(), nil
+}
@@ -1,10 +1,23 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/redis/go-redis/v9"
 )
 
 func TestShortHash(t *testing.T) {
@@ -21,19 +34,22 @@ func TestShortHash(t *testing.T) {
 	}
 }
 
-func TestStripHTML(t *testing.T) {
+func TestHTMLToText(t *testing.T) {
 	in := `<html><head><style>.a{color:red}</style>
 		<script>var x = 1 < 2;</script></head>
 		<body><p>Hello&nbsp;<b>world</b>.</p><p>Line&amp;two</p></body></html>`
-	got := stripHTML(in)
+	got, err := htmlToText(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
 	if strings.Contains(got, "<") || strings.Contains(got, ">") {
-		t.Fatalf("stripHTML left tags: %q", got)
+		t.Fatalf("htmlToText left tags: %q", got)
 	}
 	if strings.Contains(got, "color:red") || strings.Contains(got, "var x") {
-		t.Fatalf("stripHTML did not drop script/style: %q", got)
+		t.Fatalf("htmlToText did not drop script/style: %q", got)
 	}
-	if got != "Hello world . Line&two" {
-		t.Fatalf("stripHTML = %q", got)
+	if got != "Hello world.\n\nLine&two" {
+		t.Fatalf("htmlToText = %q", got)
 	}
 }
 
@@ -74,3 +90,190 @@ func TestEffectCacheConcurrent(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestSplitForTTS_OverLimitSplitsAtSentences(t *testing.T) {
+	sentence := "The rain had not stopped for three days and the river was rising fast. "
+	text := strings.TrimSpace(strings.Repeat(sentence, 60)) // ~4300 bytes
+	parts := splitForTTS(text, 2000)
+	if len(parts) < 3 {
+		t.Fatalf("expected the chunk to be split into >=3 parts, got %d", len(parts))
+	}
+	for i, p := range parts {
+		if len(p) > 2000 {
+			t.Fatalf("part %d is %d bytes, over the limit", i, len(p))
+		}
+		if !strings.HasSuffix(p, ".") {
+			t.Fatalf("part %d does not end on a sentence boundary: %q", i, p[len(p)-20:])
+		}
+	}
+	if strings.Join(parts, " ") != text {
+		t.Fatal("rejoined parts differ from the input text")
+	}
+}
+
+func TestSplitForTTS_MultiByteRespectsByteLimit(t *testing.T) {
+	text := strings.Repeat("дождь шёл три дня подряд ", 200) // 2-byte runes
+	for i, p := range splitForTTS(text, 500) {
+		if len(p) > 500 {
+			t.Fatalf("part %d is %d bytes, over the limit", i, len(p))
+		}
+	}
+}
+
+func TestSplitForTTS_UnderLimitUntouched(t *testing.T) {
+	if got := splitForTTS("Short page.", 2000); len(got) != 1 || got[0] != "Short page." {
+		t.Fatalf("splitForTTS under limit = %q", got)
+	}
+}
+
+// A segment over TTS_MAX_INPUT_BYTES is sent as its splitForTTS parts, in
+// order, and the parts' audio is joined into the one segment file.
+func TestGenerateSegmentAudio_SynthesizesAndMergesParts(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("TTS_MAX_INPUT_BYTES", "300")
+	t.Setenv("OPENAI_API_KEY", "test")
+
+	// ffmpeg stand-in: concatenate the files named in the concat list.
+	bin := t.TempDir()
+	script := `#!/bin/sh
+in=""; prev=""
+for a in "$@"; do
+	if [ "$prev" = "-i" ] && [ -z "$in" ]; then in="$a"; fi
+	prev="$a"
+done
+sed -n "s/^file '\(.*\)'$/\1/p" "$in" | while read -r f; do cat "$f"; done > "$prev"
+`
+	if err := os.WriteFile(filepath.Join(bin, "ffmpeg"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	origFF := ffmpegBin
+	ffmpegBin = filepath.Join(bin, "ffmpeg")
+	t.Cleanup(func() { ffmpegBin = origFF })
+
+	// TTS stand-in: each request's "audio" is its input, bracketed.
+	var mu sync.Mutex
+	var inputs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Input string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		inputs = append(inputs, body.Input)
+		mu.Unlock()
+		fmt.Fprintf(w, "[%s]", body.Input)
+	}))
+	defer srv.Close()
+	cfg := openaiEngine
+	cfg.Endpoint = srv.URL
+
+	segment := DialogueSegment{Type: "narrator", Text: strings.Repeat("The lamps were lit one by one along the quay. ", 20)}
+	path, err := generateSegmentAudio(context.Background(), segment, 9, 0, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := splitForTTS(spokenSegmentText(segment, &cfg), 300)
+	if len(parts) < 3 {
+		t.Fatalf("only %d part(s); the segment should be over the limit", len(parts))
+	}
+	if strings.Join(inputs, "\x00") != strings.Join(parts, "\x00") {
+		t.Fatalf("TTS inputs %q, want the parts %q", inputs, parts)
+	}
+	var want strings.Builder
+	for _, p := range parts {
+		fmt.Fprintf(&want, "[%s]", p)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil || string(got) != want.String() {
+		t.Fatalf("merged audio = %q (%v), want the parts in order", got, err)
+	}
+	if leftovers, _ := filepath.Glob("audio/*_part*.mp3"); len(leftovers) != 0 {
+		t.Errorf("part files left behind: %v", leftovers)
+	}
+}
+
+func TestFirstReusableAudio_SkipsMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	gone := filepath.Join(dir, "book_1_deleted.mp3")
+	exists := func(p string) bool { return mediaExists(context.Background(), p) }
+
+	// Only duplicate's audio was deleted → no reuse; the book regenerates.
+	if d, ok := firstReusableAudio([]Book{{ID: 1, AudioPath: gone}}, exists); ok {
+		t.Fatalf("reused dead path from book %d", d.ID)
+	}
+
+	live := filepath.Join(dir, "book_2_live.mp3")
+	if err := os.WriteFile(live, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d, ok := firstReusableAudio([]Book{{ID: 1, AudioPath: gone}, {ID: 2, AudioPath: live}}, exists)
+	if !ok || d.ID != 2 {
+		t.Errorf("firstReusableAudio = %d, %v; want book 2", d.ID, ok)
+	}
+}
+
+func TestSynthesizeSpeech_AbortsOnCancel(t *testing.T) {
+	// A TTS provider that never answers until the test ends.
+	hit := make(chan struct{}, 1)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit <- struct{}{}
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	cfg := openaiEngine
+	cfg.Endpoint = srv.URL
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-hit
+		cancel() // client disconnects mid-request
+	}()
+
+	out := filepath.Join(t.TempDir(), "out.mp3")
+	start := time.Now()
+	err := synthesizeSpeech(ctx, &cfg, "key", "hello", "alloy", "", 1.0, DialogueSegment{Type: "narrator"}, out)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("cancelled call took %v; outbound request wasn't aborted", d)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Fatalf("aborted synthesis left output behind: %v", err)
+	}
+}
+
+// A /chunks/tts call that runs past TTS_REQUEST_TIMEOUT_SECONDS answers 504
+// instead of an empty 200.
+func TestProcessChunksTTS_DeadlineIsGatewayTimeout(t *testing.T) {
+	withTestDB(t)
+	t.Setenv("TTS_REQUEST_TIMEOUT_SECONDS", "1")
+	origRDB, origSynth := rdb, chunkSynthesize
+	rdb = redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1}) // quota fails open
+	chunkSynthesize = func(ctx context.Context, _ BookChunk) (string, error) {
+		<-ctx.Done() // provider never answers
+		return "", ctx.Err()
+	}
+	t.Cleanup(func() { rdb, chunkSynthesize = origRDB, origSynth })
+
+	book := Book{Title: "Slow", Category: "Fiction", UserID: 1}
+	db.Create(&book)
+	db.Create(&BookChunk{BookID: book.ID, Index: 0, Content: "A page that never finishes.", TTSStatus: "pending"})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/chunks/tts", func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"user_id": float64(1), "account_type": "free"})
+	}, ProcessChunksTTSHandler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/chunks/tts", strings.NewReader(fmt.Sprintf(`{"book_id":%d,"pages":[1]}`, book.ID))))
+	if w.Code != http.StatusGatewayTimeout || !strings.Contains(w.Body.String(), errCodeUpstream) {
+		t.Fatalf("timed-out request = %d %s, want 504", w.Code, w.Body)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"math"
 	"net/http"
 	"time"
 
@@ -21,6 +22,8 @@ type PlaybackProgress struct {
 	PlayCount          int       `gorm:"not null;default:0" json:"play_count"`           // Number of play sessions
 	TotalListenTime    float64   `gorm:"not null;default:0" json:"total_listen_time"`    // Total time spent listening in seconds
 	LastPlayedAt       time.Time `gorm:"not null" json:"last_played_at"`                 // When the user last played this book
+	LastMilestone      int       `gorm:"not null;default:0" json:"last_milestone"`       // Highest completion milestone published over MQTT (see progress_milestones.go)
+	FinishedAt         *time.Time `json:"finished_at"`                                         // When the book was finished, by listening or explicitly (see book_finish.go)
 	CreatedAt          time.Time `json:"created_at"`
 	UpdatedAt          time.Time `json:"updated_at"`
 }
@@ -49,7 +52,7 @@ func UpdatePlaybackProgressHandler(c *gin.Context) {
 	// 1. Get user ID from JWT token
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		respondError(c, http.StatusUnauthorized, errCodeUnauthorized, "User not authenticated")
 		return
 	}
 
@@ -59,47 +62,27 @@ func UpdatePlaybackProgressHandler(c *gin.Context) {
 	// 3. Parse request body
 	var req UpdateProgressRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		respondErrorWith(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid request body", gin.H{"details": err.Error()})
 		return
 	}
 
 	// 4. Validate that current_position is non-negative
 	if req.CurrentPosition < 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "current_position must be non-negative"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "current_position must be non-negative")
 		return
 	}
 
-	// 5. Verify the book exists and belongs to the user
-	var book Book
-	if err := db.Where("id = ? AND user_id = ?", bookID, userID).First(&book).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Book not found or does not belong to user"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error", "details": err.Error()})
-		}
-		return
-	}
+	// 5. The book, already verified as the caller's by requireBookOwnership
+	book := c.MustGet("book").(Book)
 
 	// 6. Calculate duration if not provided (from book chunks)
 	duration := req.Duration
 	if duration == 0 {
-		var chunks []BookChunk
-		if err := db.Where("book_id = ?", bookID).Order("index").Find(&chunks).Error; err == nil {
-			if len(chunks) > 0 {
-				lastChunk := chunks[len(chunks)-1]
-				duration = float64(lastChunk.EndTime)
-			}
-		}
+		duration = bookDuration(db, book.ID)
 	}
 
 	// 7. Calculate completion percentage
-	completionPercent := 0.0
-	if duration > 0 {
-		completionPercent = (req.CurrentPosition / duration) * 100
-		if completionPercent > 100 {
-			completionPercent = 100
-		}
-	}
+	completionPercent := percentComplete(req.CurrentPosition, duration)
 
 	// 8. Find or create progress record
 	var progress PlaybackProgress
@@ -120,47 +103,37 @@ func UpdatePlaybackProgressHandler(c *gin.Context) {
 		}
 		if err := db.Create(&progress).Error; err != nil {
 			log.Printf("❌ Failed to create progress: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save progress", "details": err.Error()})
+			respondInternal(c, "Failed to save progress", err)
 			return
 		}
 		log.Printf("✅ Created new progress for user %d, book %d at %.2fs (play #1)", userID, book.ID, req.CurrentPosition)
 	} else if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error", "details": result.Error.Error()})
+		respondInternal(c, "Database error", result.Error)
 		return
 	} else {
-		// Calculate listen time delta (time listened since last update)
-		listenDelta := req.CurrentPosition - progress.CurrentPosition
-		if listenDelta < 0 {
-			// User rewound or started from earlier position, don't count negative
-			listenDelta = 0
-		}
-		// Cap delta to prevent unrealistic values (e.g., max 5 minutes between updates)
-		if listenDelta > 300 {
-			listenDelta = 300
-		}
-
-		// Update existing progress record
-		progress.CurrentPosition = req.CurrentPosition
-		progress.Duration = duration
-		progress.ChunkIndex = req.ChunkIndex
-		progress.CompletionPercent = completionPercent
-		progress.TotalListenTime += listenDelta
-		progress.LastPlayedAt = time.Now()
-
-		// Increment play count if this is a new session
+		// Two devices can post progress for the same book at once. The
+		// counters are incremented in SQL (not read-modify-written with
+		// db.Save) so neither device's listen time clobbers the other's.
+		delta := listenDelta(progress.CurrentPosition, req.CurrentPosition)
 		if req.IsNewSession {
-			progress.PlayCount++
-			log.Printf("🎵 New play session for user %d, book %d (play #%d)", userID, book.ID, progress.PlayCount)
+			log.Printf("🎵 New play session for user %d, book %d", userID, book.ID)
 		}
-
-		if err := db.Save(&progress).Error; err != nil {
+		if err := accumulateProgress(db, progress.ID, req, duration, completionPercent, delta); err != nil {
 			log.Printf("❌ Failed to update progress: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update progress", "details": err.Error()})
+			respondInternal(c, "Failed to update progress", err)
+			return
+		}
+		if err := db.First(&progress, progress.ID).Error; err != nil {
+			respondInternal(c, "Database error", err)
 			return
 		}
 		log.Printf("✅ Updated progress for user %d, book %d to %.2fs (%.1f%%, total: %.0fs)", userID, book.ID, req.CurrentPosition, completionPercent, progress.TotalListenTime)
 	}
 
+	// Tell the user's other devices when a 25/50/75/100% milestone is crossed.
+	publishProgressMilestones(db, &progress)
+	markFinishedIfComplete(db, &progress)
+
 	// If this book was paused ahead of the listener, advancing may release the
 	// next transcription batch (Phase 4 pause-ahead resume).
 	maybeResumeTranscription(accountTypeFromClaims(c), book.ID, progress.ChunkIndex)
@@ -180,29 +153,72 @@ func UpdatePlaybackProgressHandler(c *gin.Context) {
 	})
 }
 
+// bookDuration is a book's total audio length in seconds, taken from the end
+// time of its last chunk (0 when it has none).
+func bookDuration(tx *gorm.DB, bookID uint) float64 {
+	var last BookChunk
+	if err := tx.Where("book_id = ?", bookID).Order("index DESC").First(&last).Error; err != nil {
+		return 0
+	}
+	return float64(last.EndTime)
+}
+
+// percentComplete is position as a percentage of duration, capped at 100.
+func percentComplete(position, duration float64) float64 {
+	if duration <= 0 {
+		return 0
+	}
+	return math.Min(position/duration*100, 100)
+}
+
+// listenDelta is the listening time credited for moving from prev to cur:
+// rewinds count as zero and a single update is capped at 5 minutes so a
+// skip-ahead isn't counted as listening.
+func listenDelta(prev, cur float64) float64 {
+	delta := cur - prev
+	if delta < 0 {
+		return 0
+	}
+	if delta > 300 {
+		return 300
+	}
+	return delta
+}
+
+// accumulateProgress writes a progress update to row id in one UPDATE. The
+// position fields are last-writer-wins; total_listen_time and play_count are
+// incremented atomically so concurrent updates from several devices add up.
+func accumulateProgress(tx *gorm.DB, id uint, req UpdateProgressRequest, duration, completionPercent, delta float64) error {
+	playInc := 0
+	if req.IsNewSession {
+		playInc = 1
+	}
+	return tx.Model(&PlaybackProgress{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"current_position":   req.CurrentPosition,
+		"duration":           duration,
+		"chunk_index":        req.ChunkIndex,
+		"completion_percent": completionPercent,
+		"total_listen_time":  gorm.Expr("total_listen_time + ?", delta),
+		"play_count":         gorm.Expr("play_count + ?", playInc),
+		"last_played_at":     time.Now(),
+	}).Error
+}
+
 // GetPlaybackProgressHandler retrieves the user's playback progress for a specific book
 // GET /user/books/:book_id/progress
 func GetPlaybackProgressHandler(c *gin.Context) {
 	// 1. Get user ID from JWT token
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		respondError(c, http.StatusUnauthorized, errCodeUnauthorized, "User not authenticated")
 		return
 	}
 
 	// 2. Get book ID from URL parameter
 	bookID := c.Param("book_id")
 
-	// 3. Verify the book exists and belongs to the user
-	var book Book
-	if err := db.Where("id = ? AND user_id = ?", bookID, userID).First(&book).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Book not found or does not belong to user"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error", "details": err.Error()})
-		}
-		return
-	}
+	// 3. The book, already verified as the caller's by requireBookOwnership
+	book := c.MustGet("book").(Book)
 
 	// 4. Find progress record
 	var progress PlaybackProgress
@@ -220,7 +236,7 @@ func GetPlaybackProgressHandler(c *gin.Context) {
 		})
 		return
 	} else if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error", "details": result.Error.Error()})
+		respondInternal(c, "Database error", result.Error)
 		return
 	}
 
@@ -241,14 +257,14 @@ func GetAllPlaybackProgressHandler(c *gin.Context) {
 	// 1. Get user ID from JWT token
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		respondError(c, http.StatusUnauthorized, errCodeUnauthorized, "User not authenticated")
 		return
 	}
 
 	// 2. Retrieve all progress records for the user, ordered by last played
 	var progressRecords []PlaybackProgress
 	if err := db.Where("user_id = ?", userID).Order("last_played_at DESC").Find(&progressRecords).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve progress", "details": err.Error()})
+		respondInternal(c, "Failed to retrieve progress", err)
 		return
 	}
 
@@ -277,7 +293,7 @@ func DeletePlaybackProgressHandler(c *gin.Context) {
 	// 1. Get user ID from JWT token
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		respondError(c, http.StatusUnauthorized, errCodeUnauthorized, "User not authenticated")
 		return
 	}
 
@@ -287,12 +303,12 @@ func DeletePlaybackProgressHandler(c *gin.Context) {
 	// 3. Delete progress record
 	result := db.Where("user_id = ? AND book_id = ?", userID, bookID).Delete(&PlaybackProgress{})
 	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete progress", "details": result.Error.Error()})
+		respondInternal(c, "Failed to delete progress", result.Error)
 		return
 	}
 
 	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "No progress found for this book"})
+		respondError(c, http.StatusNotFound, errCodeNotFound, "No progress found for this book")
 		return
 	}
 
@@ -327,7 +343,7 @@ func GetMostPlayedBooksHandler(c *gin.Context) {
 	// 1. Get user ID from JWT token
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		respondError(c, http.StatusUnauthorized, errCodeUnauthorized, "User not authenticated")
 		return
 	}
 
@@ -345,7 +361,7 @@ func GetMostPlayedBooksHandler(c *gin.Context) {
 		Order("play_count DESC, total_listen_time DESC").
 		Limit(limit).
 		Find(&progressRecords).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve stats", "details": err.Error()})
+		respondInternal(c, "Failed to retrieve stats", err)
 		return
 	}
 
@@ -392,14 +408,14 @@ func GetStatsByGenreHandler(c *gin.Context) {
 	// 1. Get user ID from JWT token
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		respondError(c, http.StatusUnauthorized, errCodeUnauthorized, "User not authenticated")
 		return
 	}
 
 	// 2. Query all progress records for the user
 	var progressRecords []PlaybackProgress
 	if err := db.Where("user_id = ?", userID).Find(&progressRecords).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve stats", "details": err.Error()})
+		respondInternal(c, "Failed to retrieve stats", err)
 		return
 	}
 
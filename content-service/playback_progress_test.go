@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestListenDelta(t *testing.T) {
+	cases := []struct{ prev, cur, want float64 }{
+		{10, 25, 15},
+		{25, 10, 0},    // rewind
+		{0, 1000, 300}, // skip-ahead capped
+		{42, 42, 0},
+	}
+	for _, tc := range cases {
+		if got := listenDelta(tc.prev, tc.cur); got != tc.want {
+			t.Errorf("listenDelta(%v, %v) = %v, want %v", tc.prev, tc.cur, got, tc.want)
+		}
+	}
+}
+
+// Parallel progress writes must not lose each other's listen time. Needs a
+// real Postgres (row locking is the point), so it runs only when
+// TEST_DATABASE_DSN is set.
+func TestAccumulateProgress_ConcurrentDeltasAddUp(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN not set")
+	}
+	tx, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if err := tx.AutoMigrate(&PlaybackProgress{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	row := PlaybackProgress{UserID: 1, BookID: 1}
+	if err := tx.Create(&row).Error; err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	t.Cleanup(func() { tx.Delete(&PlaybackProgress{}, row.ID) })
+
+	const writers = 20
+	var wg sync.WaitGroup
+	var want float64
+	for i := 1; i <= writers; i++ {
+		delta := float64(i)
+		want += delta
+		wg.Add(1)
+		go func(i int, delta float64) {
+			defer wg.Done()
+			req := UpdateProgressRequest{CurrentPosition: float64(i), IsNewSession: true}
+			if err := accumulateProgress(tx, row.ID, req, 0, 0, delta); err != nil {
+				t.Errorf("writer %d: %v", i, err)
+			}
+		}(i, delta)
+	}
+	wg.Wait()
+
+	var got PlaybackProgress
+	if err := tx.First(&got, row.ID).Error; err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got.TotalListenTime != want {
+		t.Errorf("total_listen_time = %v, want %v (lost update)", got.TotalListenTime, want)
+	}
+	if got.PlayCount != writers {
+		t.Errorf("play_count = %d, want %d", got.PlayCount, writers)
+	}
+}
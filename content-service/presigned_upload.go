@@ -14,6 +14,7 @@ type initiateUploadReq struct {
 	SizeBytes   int64  `json:"size_bytes"`
 	ContentType string `json:"content_type"`
 	SHA256      string `json:"sha256"`
+	Replace     bool   `json:"replace"` // replace the file the book already has
 }
 
 // initiateUploadHandler (POST /user/books/:book_id/upload/initiate) validates the
@@ -32,16 +33,19 @@ func initiateUploadHandler(c *gin.Context) {
 
 	var req initiateUploadReq
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		respondErrorWith(c, http.StatusBadRequest, errCodeInvalidRequest, "invalid request", gin.H{"details": err.Error()})
+		return
+	}
+	if !allowFileAttach(c, book, req.Replace) {
 		return
 	}
 	ext := validUploadExt(req.Filename)
 	if ext == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported file type (pdf, txt, epub, mobi, azw, azw3)"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "unsupported file type (pdf, txt, epub, mobi, azw, azw3, docx, doc, html, md)")
 		return
 	}
 	if req.SizeBytes > maxUploadBytes() {
-		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large", "max_bytes": maxUploadBytes()})
+		respondErrorWith(c, http.StatusRequestEntityTooLarge, errCodePayloadTooLarge, "file too large", gin.H{"max_bytes": maxUploadBytes()})
 		return
 	}
 
@@ -57,7 +61,7 @@ func initiateUploadHandler(c *gin.Context) {
 				"status":       "parsing",
 			})
 			if err := enqueueParseBook(book.ID); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "could not queue parse", "details": err.Error()})
+				respondInternal(c, "could not queue parse", err)
 				return
 			}
 			c.JSON(http.StatusOK, gin.H{"dedup": true, "message": "identical file already uploaded; parsing"})
@@ -68,7 +72,7 @@ func initiateUploadHandler(c *gin.Context) {
 	key := uploadKey(userID, book.ID, ext)
 	url, err := store.PresignPut(c.Request.Context(), key, presignPutTTL, req.ContentType)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not presign upload", "details": err.Error()})
+		respondInternal(c, "could not presign upload", err)
 		return
 	}
 	// Persist the intended key + hash so /complete and the sweeper can find it.
@@ -92,16 +96,16 @@ func initiateUploadHandler(c *gin.Context) {
 func completeUploadHandler(c *gin.Context) {
 	book := c.MustGet("book").(Book)
 	if book.FilePath == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "no upload was initiated for this book"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "no upload was initiated for this book")
 		return
 	}
 	ok, err := store.Exists(c.Request.Context(), book.FilePath)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not verify upload", "details": err.Error()})
+		respondInternal(c, "could not verify upload", err)
 		return
 	}
 	if !ok {
-		c.JSON(http.StatusConflict, gin.H{"error": "uploaded object not found in storage"})
+		respondError(c, http.StatusConflict, errCodeConflict, "uploaded object not found in storage")
 		return
 	}
 	// Count the upload once (only on the first completion — status is still
@@ -111,7 +115,7 @@ func completeUploadHandler(c *gin.Context) {
 	}
 	db.Model(&Book{}).Where("id = ?", book.ID).Update("status", "parsing")
 	if err := enqueueParseBook(book.ID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not queue parse", "details": err.Error()})
+		respondInternal(c, "could not queue parse", err)
 		return
 	}
 	c.JSON(http.StatusAccepted, gin.H{"message": "upload complete; parsing", "book_id": book.ID})
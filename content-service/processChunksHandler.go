@@ -1,29 +1,36 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // convertTextToAudio converts text to audio using OpenAI's TTS API.
 
-func ProcessChunksTTSHandler(c *gin.Context) {
+// ttsRequestTimeout bounds the synchronous TTS work done for one
+// /process-chunks call (TTS_REQUEST_TIMEOUT_SECONDS, default 5 minutes).
+func ttsRequestTimeout() time.Duration {
+	return time.Duration(envInt("TTS_REQUEST_TIMEOUT_SECONDS", 300)) * time.Second
+}
 
-	authHeader := c.GetHeader("Authorization")
-	token, err := extractToken(authHeader)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid token"})
-		return
-	}
+func ProcessChunksTTSHandler(c *gin.Context) {
 
 	accountType := accountTypeFromClaims(c)
 	if accountType == "" {
-		at, err := getUserAccountType(token)
+		token, err := extractToken(c.GetHeader("Authorization"))
+		if err != nil {
+			respondError(c, http.StatusUnauthorized, errCodeUnauthorized, "Missing or invalid token")
+			return
+		}
+		at, err := getUserAccountType(getUserIDFromContext(c), token)
 		if err != nil {
 			log.Printf("Error checking account type: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify account type"})
+			respondError(c, http.StatusServiceUnavailable, errCodeServiceUnavailable, "Failed to verify account type")
 			return
 		}
 		accountType = at
@@ -32,19 +39,42 @@ func ProcessChunksTTSHandler(c *gin.Context) {
 	var req struct {
 		BookID uint  `json:"book_id"`
 		Pages  []int `json:"pages"` // 1-based page numbers
+		// Optional, for this call only (tts_override.go).
+		Voice    string `json:"voice"`
+		Language string `json:"language"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil || len(req.Pages) == 0 || len(req.Pages) > 2 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "You must provide 1 or 2 pages to process"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "You must provide 1 or 2 pages to process")
 		return
 	}
 
 	// SECURITY (S6): the book must belong to the caller. 404 (not 403) so we
 	// don't reveal that another user's book exists.
-	if _, err := verifyBookOwnership(req.BookID, getUserIDFromContext(c)); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+	owned, err := verifyBookOwnership(req.BookID, getUserIDFromContext(c))
+	if err != nil {
+		respondError(c, http.StatusNotFound, errCodeBookNotFound, "Book not found")
 		return
 	}
 
+	override := ttsOverride{Voice: req.Voice, Language: req.Language}.normalized()
+	if err := override.validate(engineFor(*owned)); err != nil {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
+	// One synthesis run per book at a time (book_lock.go): a batch or another
+	// /process-chunks call already on this book gets a 409, not a duplicate.
+	unlock, err := lockBook(c.Request.Context(), req.BookID, false)
+	if errors.Is(err, errBookLocked) {
+		respondError(c, http.StatusConflict, errCodeConflict, "Book is already being processed")
+		return
+	}
+	if err != nil {
+		respondInternal(c, "Could not lock book for processing", err)
+		return
+	}
+	defer unlock()
+
 	// Quota is charged per-page in the loop below — only on a cache MISS (fresh
 	// synthesis), by the rendered audio's duration. Cached pages are free.
 
@@ -53,10 +83,15 @@ func ProcessChunksTTSHandler(c *gin.Context) {
 	if err := db.Where("book_id = ? AND index IN ?", req.BookID, toZeroBasedIndexes(req.Pages)).
 		Order("index ASC").
 		Find(&chunks).Error; err != nil || len(chunks) != len(req.Pages) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page numbers for the given book_id"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid page numbers for the given book_id")
 		return
 	}
 
+	// Outbound TTS/GPT calls inherit the request context: a client that hangs
+	// up (or the deadline) aborts synthesis instead of paying for it.
+	ctx, cancel := context.WithTimeout(withTTSOverride(c.Request.Context(), override), ttsRequestTimeout())
+	defer cancel()
+
 	// Process each chunk. Already-completed pages are a no-op success (look-ahead
 	// may have finished them), not an error.
 	var audioPaths []string
@@ -79,7 +114,7 @@ func ProcessChunksTTSHandler(c *gin.Context) {
 		// Cross-user dedup: if this exact text+engine was already rendered for
 		// any book, reuse the shared audio and skip TTS + the whole merge —
 		// FREE, no quota.
-		if reuseRenderedPageForChunk(book, chunk) {
+		if reuseRenderedPageForChunk(ctx, book, chunk) {
 			continue
 		}
 
@@ -92,9 +127,16 @@ func ProcessChunksTTSHandler(c *gin.Context) {
 			return
 		}
 
-		audioPath, err := convertTextToAudioForChunk(chunk)
+		audioPath, err := synthesizeChunkAudio(ctx, chunk)
 		if err != nil {
-			db.Model(&chunk).Update("TTSStatus", "failed")
+			releaseOrFail(ctx, chunk.ID, err)
+			if ctx.Err() != nil {
+				log.Printf("⏹️ TTS for book %d aborted: %v", req.BookID, ctx.Err())
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					respondError(c, http.StatusGatewayTimeout, errCodeUpstream, "Narration took too long; try again")
+				}
+				return
+			}
 			continue
 		}
 		if dur, derr := getTTSDuration(audioPath); derr == nil {
@@ -107,7 +149,7 @@ func ProcessChunksTTSHandler(c *gin.Context) {
 
 		// Trigger the per-page final merge (music + foley + mix).
 		log.Printf("🚀 Launching effects merge for book ID %d, chunk index %d", book.ID, pageIndex)
-		go processSoundEffectsAndMerge(book, book.ContentHash, []int{chunk.Index})
+		go processSoundEffectsAndMerge(withTTSOverride(context.Background(), override), book, book.ContentHash, []int{chunk.Index})
 	}
 
 	// Attempt to merge (optional). Q7: check the error we actually returned.
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Per-book processing history. Each pipeline stage records what happened to
+// a book — chunking once per parse, and per page the failures, skipped
+// effects and the finished merge — so a client or support can see why a book
+// or page is stuck without reading server logs.
+
+// Pipeline stages a ProcessingEvent can record.
+const (
+	stageChunking = "chunking"
+	stageTTS      = "tts"
+	stageMusic    = "music"
+	stageFoley    = "foley"
+	stageMerge    = "merge"
+)
+
+// Outcomes a ProcessingEvent can record.
+const (
+	eventStarted   = "started"
+	eventCompleted = "completed"
+	eventFailed    = "failed"
+	eventSkipped   = "skipped"
+)
+
+// ProcessingEvent is one entry in a book's processing log.
+type ProcessingEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	BookID    uint      `gorm:"index" json:"book_id"`
+	Page      *int      `json:"page,omitempty"` // 1-based; nil for book-level stages (chunking)
+	Stage     string    `gorm:"size:16" json:"stage"`
+	Status    string    `gorm:"size:16" json:"status"`
+	Message   string    `gorm:"type:text" json:"message,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// recordProcessingEvent appends to bookID's processing log. pageIndex is the
+// chunk index, or -1 for a book-level stage. Best-effort: a failed write is
+// logged and never fails the pipeline.
+func recordProcessingEvent(bookID uint, pageIndex int, stage, status, message string) {
+	ev := ProcessingEvent{BookID: bookID, Stage: stage, Status: status, Message: message}
+	if pageIndex >= 0 {
+		page := pageIndex + 1
+		ev.Page = &page
+	}
+	if err := db.Create(&ev).Error; err != nil {
+		log.Printf("⚠️ processing log for book %d (%s %s) not written: %v", bookID, stage, status, err)
+	}
+}
+
+// processingLogHandler returns a book's processing log, newest first.
+// GET /user/books/:book_id/processing-log?limit=100 (max 500)
+func processingLogHandler(c *gin.Context) {
+	book := c.MustGet("book").(Book)
+	limit := 100
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "limit must be a positive integer")
+			return
+		}
+		limit = min(n, 500)
+	}
+
+	var events []ProcessingEvent
+	if err := db.Where("book_id = ?", book.ID).Order("created_at DESC, id DESC").Limit(limit).Find(&events).Error; err != nil {
+		respondInternal(c, "Failed to load processing log", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"book_id": book.ID, "status": book.Status, "events": events})
+}
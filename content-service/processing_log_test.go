@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+func processingLogRouter(book Book) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/user/books/:book_id/processing-log", func(c *gin.Context) {
+		c.Set("book", book)
+		processingLogHandler(c)
+	})
+	return r
+}
+
+// failingFFmpeg makes every ffmpeg run exit non-zero (ffprobe still works).
+func failingFFmpeg(t *testing.T) {
+	t.Helper()
+	fakeFFmpeg(t)
+	bin, err := exec.LookPath("false")
+	if err != nil {
+		t.Skip("no false binary")
+	}
+	ffmpegBin = bin
+}
+
+func TestMixPageEffects_MergeFailureIsLogged(t *testing.T) {
+	dryRunDB(t)
+	var logged []ProcessingEvent
+	db.Callback().Create().After("gorm:create").Register("test:events", func(tx *gorm.DB) {
+		if ev, ok := tx.Statement.Dest.(*ProcessingEvent); ok {
+			logged = append(logged, *ev)
+		}
+	})
+	failingFFmpeg(t)
+	t.Chdir(t.TempDir())
+	t.Setenv("XI_API_KEY", "")
+	narration := filepath.Join(t.TempDir(), "tts.mp3")
+	os.WriteFile(narration, []byte("ID3 narration"), 0644)
+
+	book := Book{ID: 5, AudioProfile: `{"fiction":false,"genre":"history","era":"modern"}`}
+	if _, _, err := mixPageEffects(context.Background(), narration, book, BookChunk{BookID: 5, Index: 2}, "abc"); err == nil {
+		t.Fatal("mix succeeded with a failing ffmpeg")
+	}
+	if len(logged) == 0 {
+		t.Fatal("nothing logged")
+	}
+	last := logged[len(logged)-1]
+	if last.Stage != stageMerge || last.Status != eventFailed || last.Page == nil || *last.Page != 3 || last.Message == "" {
+		t.Fatalf("last event = %+v (all: %+v)", last, logged)
+	}
+}
+
+func TestProcessingLog_RejectsBadLimit(t *testing.T) {
+	dryRunDB(t)
+	w := httptest.NewRecorder()
+	processingLogRouter(Book{ID: 1}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/books/1/processing-log?limit=0", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("limit=0 = %d", w.Code)
+	}
+}
+
+// A page that fails at the merge stage shows up in the book's log with the
+// stage, the page and the error.
+func TestProcessingLog_RecordsMergeFailure(t *testing.T) {
+	withTestDB(t)
+	failingFFmpeg(t)
+	t.Chdir(t.TempDir())
+	t.Setenv("XI_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	stubChunkSynthesis(t, []byte("ID3 narration"))
+	origRDB := rdb
+	rdb = redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1}) // quota fails open
+	t.Cleanup(func() { rdb = origRDB })
+
+	book := Book{Title: "Broken mix", Category: "Fiction", UserID: 1, Status: "transcribing"}
+	db.Create(&book)
+	db.Create(&BookChunk{BookID: book.ID, Index: 0, Content: "A page whose mix fails.", TTSStatus: "pending"})
+
+	payload, _ := json.Marshal(TaskTranscribeBatch{BookID: book.ID, StartPage: 0, EndPage: batchSizePages - 1, UserID: 1, AccountType: "free"})
+	if err := handleTranscribeBatch(context.Background(), asynq.NewTask(TypeTranscribeBatch, payload)); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	processingLogRouter(book).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/books/1/processing-log", nil))
+	var resp struct {
+		Events []ProcessingEvent `json:"events"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || len(resp.Events) == 0 {
+		t.Fatalf("log = %d %s", w.Code, w.Body)
+	}
+	found := false
+	for _, ev := range resp.Events {
+		if ev.Stage == stageMerge && ev.Status == eventCompleted {
+			t.Fatalf("merge recorded as completed: %+v", ev)
+		}
+		if ev.Stage == stageMerge && ev.Status == eventFailed && ev.Page != nil && *ev.Page == 1 && ev.Message != "" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("no merge failure for page 1 in %+v", resp.Events)
+	}
+}
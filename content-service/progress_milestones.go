@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// progressMilestones are the completion percentages announced on
+// users/<user_id>/progress so a user's other devices can update their UI.
+var progressMilestones = []int{25, 50, 75, 100}
+
+// publishEvent is PublishEvent, swappable in tests.
+var publishEvent = PublishEvent
+
+// crossedMilestones returns the milestones above last that percent has
+// reached, in ascending order. A jump from 10% to 80% crosses 25, 50 and 75;
+// a rewind crosses nothing.
+func crossedMilestones(last int, percent float64) []int {
+	var out []int
+	for _, m := range progressMilestones {
+		if m > last && percent >= float64(m) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// publishProgressMilestones claims and announces each milestone progress has
+// newly crossed. Callers holding a transaction use claimProgressMilestones
+// and publish once it commits instead.
+func publishProgressMilestones(db *gorm.DB, progress *PlaybackProgress) {
+	for _, m := range claimProgressMilestones(db, progress) {
+		publishMilestone(progress, m)
+	}
+}
+
+// claimProgressMilestones returns the milestones progress has newly crossed
+// and records them. progress.LastMilestone is raised with a conditional
+// UPDATE, so when two devices race past the same threshold only the one
+// whose update lands gets the milestones back to publish.
+func claimProgressMilestones(tx *gorm.DB, progress *PlaybackProgress) []int {
+	crossed := crossedMilestones(progress.LastMilestone, progress.CompletionPercent)
+	if len(crossed) == 0 {
+		return nil
+	}
+	top := crossed[len(crossed)-1]
+	res := tx.Model(&PlaybackProgress{}).
+		Where("id = ? AND last_milestone < ?", progress.ID, top).
+		Update("last_milestone", top)
+	if res.Error != nil {
+		log.Printf("⚠️ Failed to record milestone %d for progress %d: %v", top, progress.ID, res.Error)
+		return nil
+	}
+	if res.RowsAffected == 0 {
+		return nil // another update already published it
+	}
+	progress.LastMilestone = top
+	return crossed
+}
+
+// publishMilestone emits one progress milestone event.
+func publishMilestone(progress *PlaybackProgress, milestone int) {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"book_id":            progress.BookID,
+		"milestone":          milestone,
+		"completion_percent": progress.CompletionPercent,
+		"current_position":   progress.CurrentPosition,
+		"chunk_index":        progress.ChunkIndex,
+		"timestamp":          time.Now().UTC().Format(time.RFC3339),
+	})
+	publishEvent(fmt.Sprintf("users/%d/progress", progress.UserID), payload)
+}
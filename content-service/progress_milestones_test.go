@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCrossedMilestones(t *testing.T) {
+	cases := []struct {
+		last    int
+		percent float64
+		want    []int
+	}{
+		{0, 10, nil},
+		{0, 25, []int{25}},
+		{0, 80, []int{25, 50, 75}}, // skip-ahead announces every threshold passed
+		{50, 60, nil},
+		{75, 100, []int{100}},
+		{100, 100, nil},
+		{75, 30, nil}, // rewind
+	}
+	for _, tc := range cases {
+		if got := crossedMilestones(tc.last, tc.percent); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("crossedMilestones(%d, %v) = %v, want %v", tc.last, tc.percent, got, tc.want)
+		}
+	}
+}
+
+// Replays a listening session the way the handler does (LastMilestone is the
+// only state carried between updates) and checks each milestone goes out once.
+func TestProgressMilestones_PublishOncePerThreshold(t *testing.T) {
+	orig := publishEvent
+	t.Cleanup(func() { publishEvent = orig })
+
+	published := map[int]int{}
+	var topics []string
+	publishEvent = func(topic string, payload []byte) {
+		var msg struct {
+			BookID    uint `json:"book_id"`
+			Milestone int  `json:"milestone"`
+		}
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			t.Fatalf("payload: %v", err)
+		}
+		if msg.BookID != 3 {
+			t.Errorf("book_id = %d", msg.BookID)
+		}
+		published[msg.Milestone]++
+		topics = append(topics, topic)
+	}
+
+	progress := PlaybackProgress{UserID: 9, BookID: 3}
+	for _, pct := range []float64{5, 20, 26, 30, 10, 49, 55, 52, 80, 20, 90, 100, 100, 60, 100} {
+		progress.CompletionPercent = pct
+		crossed := crossedMilestones(progress.LastMilestone, pct)
+		for _, m := range crossed {
+			publishMilestone(&progress, m)
+		}
+		if len(crossed) > 0 {
+			progress.LastMilestone = crossed[len(crossed)-1]
+		}
+	}
+
+	want := map[int]int{25: 1, 50: 1, 75: 1, 100: 1}
+	if !reflect.DeepEqual(published, want) {
+		t.Fatalf("published = %v, want each milestone once %v", published, want)
+	}
+	for _, topic := range topics {
+		if topic != "users/9/progress" {
+			t.Errorf("topic = %q", topic)
+		}
+	}
+}
+
+// Claiming inside a transaction must not publish: the sync handler only
+// announces what it claimed once the transaction has committed.
+func TestClaimProgressMilestones_DoesNotPublish(t *testing.T) {
+	stmts := dryRunDB(t)
+	orig := publishEvent
+	t.Cleanup(func() { publishEvent = orig })
+	publishEvent = func(topic string, payload []byte) {
+		t.Errorf("published %s before commit", topic)
+	}
+
+	progress := PlaybackProgress{ID: 4, UserID: 9, BookID: 3, CompletionPercent: 60}
+	crossed := claimProgressMilestones(db, &progress)
+	if !reflect.DeepEqual(crossed, []int{25, 50}) {
+		t.Fatalf("crossed = %v, want [25 50]", crossed)
+	}
+	if progress.LastMilestone != 50 {
+		t.Errorf("LastMilestone = %d, want 50", progress.LastMilestone)
+	}
+	if len(*stmts) != 1 || !strings.Contains((*stmts)[0], "last_milestone") {
+		t.Errorf("statements = %v, want the conditional last_milestone UPDATE", *stmts)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ProgressSyncItem is one book's progress in a POST /user/progress/sync batch.
+// ListenTime is the listening the client accumulated offline; when omitted the
+// position delta is credited, exactly as UpdatePlaybackProgressHandler does.
+type ProgressSyncItem struct {
+	BookID     uint    `json:"book_id"`
+	Position   float64 `json:"position"`
+	ChunkIndex int     `json:"chunk_index"`
+	ListenTime float64 `json:"listen_time"`
+}
+
+// ProgressSyncResult reports what happened to one item.
+type ProgressSyncResult struct {
+	BookID   uint              `json:"book_id"`
+	OK       bool              `json:"ok"`
+	Error    string            `json:"error,omitempty"`
+	Progress *ProgressResponse `json:"progress,omitempty"`
+}
+
+const (
+	maxProgressSyncItems = 100
+	// maxSyncListenTime bounds a single item's offline listen time (24h) so a
+	// bad client clock can't inflate listening stats.
+	maxSyncListenTime = 24 * 60 * 60
+)
+
+var errBookNotOwned = errors.New("book not found or does not belong to user")
+
+// validateSyncItem applies the per-item checks that need no database.
+func validateSyncItem(item ProgressSyncItem) error {
+	switch {
+	case item.BookID == 0:
+		return errors.New("book_id is required")
+	case item.Position < 0:
+		return errors.New("position must be non-negative")
+	case item.ChunkIndex < 0:
+		return errors.New("chunk_index must be non-negative")
+	case item.ListenTime < 0 || item.ListenTime > maxSyncListenTime:
+		return fmt.Errorf("listen_time must be between 0 and %d seconds", maxSyncListenTime)
+	}
+	return nil
+}
+
+// syncProgressItems validates each item and hands the valid ones to apply.
+// A rejected item is reported in its result without affecting the others; an
+// error from apply that isn't a per-item rejection aborts the batch.
+func syncProgressItems(items []ProgressSyncItem, apply func(ProgressSyncItem) (*ProgressResponse, error)) ([]ProgressSyncResult, error) {
+	results := make([]ProgressSyncResult, len(items))
+	for i, item := range items {
+		results[i].BookID = item.BookID
+		if err := validateSyncItem(item); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		progress, err := apply(item)
+		if errors.Is(err, errBookNotOwned) {
+			results[i].Error = err.Error()
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		results[i].OK = true
+		results[i].Progress = progress
+	}
+	return results, nil
+}
+
+// pendingMilestones are milestones claimed inside the sync transaction,
+// published only after it commits.
+type pendingMilestones struct {
+	progress   PlaybackProgress
+	milestones []int
+}
+
+// applySyncItem writes one item's progress inside tx for userID, using the
+// same create-or-accumulate logic as the single-book endpoint. Milestones it
+// crosses are appended to pending rather than published.
+func applySyncItem(tx *gorm.DB, userID uint, item ProgressSyncItem, pending *[]pendingMilestones) (*ProgressResponse, error) {
+	var book Book
+	if err := tx.Where("id = ? AND user_id = ?", item.BookID, userID).First(&book).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errBookNotOwned
+		}
+		return nil, err
+	}
+
+	duration := bookDuration(tx, book.ID)
+	completion := percentComplete(item.Position, duration)
+
+	var progress PlaybackProgress
+	err := tx.Where("user_id = ? AND book_id = ?", userID, book.ID).First(&progress).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		listened := item.ListenTime
+		if listened == 0 {
+			listened = item.Position
+		}
+		progress = PlaybackProgress{
+			UserID:            userID,
+			BookID:            book.ID,
+			CurrentPosition:   item.Position,
+			Duration:          duration,
+			ChunkIndex:        item.ChunkIndex,
+			CompletionPercent: completion,
+			PlayCount:         1,
+			TotalListenTime:   listened,
+			LastPlayedAt:      time.Now(),
+		}
+		if err := tx.Create(&progress).Error; err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	default:
+		delta := item.ListenTime
+		if delta == 0 {
+			delta = listenDelta(progress.CurrentPosition, item.Position)
+		}
+		req := UpdateProgressRequest{CurrentPosition: item.Position, ChunkIndex: item.ChunkIndex}
+		if err := accumulateProgress(tx, progress.ID, req, duration, completion, delta); err != nil {
+			return nil, err
+		}
+		if err := tx.First(&progress, progress.ID).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if crossed := claimProgressMilestones(tx, &progress); len(crossed) > 0 {
+		*pending = append(*pending, pendingMilestones{progress: progress, milestones: crossed})
+	}
+
+	return &ProgressResponse{
+		BookID:            progress.BookID,
+		CurrentPosition:   progress.CurrentPosition,
+		Duration:          progress.Duration,
+		ChunkIndex:        progress.ChunkIndex,
+		CompletionPercent: progress.CompletionPercent,
+		LastPlayedAt:      progress.LastPlayedAt,
+	}, nil
+}
+
+// SyncPlaybackProgressHandler applies a batch of progress updates queued by
+// an offline client in one transaction. Body: a JSON array of
+// ProgressSyncItem. Each item is validated and ownership-checked on its own;
+// invalid items come back with ok=false while the rest are still applied.
+// POST /user/progress/sync
+func SyncPlaybackProgressHandler(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		respondError(c, http.StatusUnauthorized, errCodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	var items []ProgressSyncItem
+	if err := c.ShouldBindJSON(&items); err != nil {
+		respondErrorWith(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid request body", gin.H{"details": err.Error()})
+		return
+	}
+	if len(items) == 0 || len(items) > maxProgressSyncItems {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("expected 1-%d items", maxProgressSyncItems))
+		return
+	}
+
+	var results []ProgressSyncResult
+	var pending []pendingMilestones
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		pending = nil
+		results, err = syncProgressItems(items, func(item ProgressSyncItem) (*ProgressResponse, error) {
+			return applySyncItem(tx, userID.(uint), item, &pending)
+		})
+		return err
+	})
+	if err != nil {
+		log.Printf("❌ Progress sync failed for user %d: %v", userID, err)
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "Failed to sync progress")
+		return
+	}
+	for i := range pending {
+		for _, m := range pending[i].milestones {
+			publishMilestone(&pending[i].progress, m)
+		}
+	}
+
+	applied := 0
+	for _, r := range results {
+		if r.OK {
+			applied++
+			if r.Progress.CompletionPercent >= bookCompletedPercent {
+				// After commit: moving books_read is a call to auth-service.
+				var progress PlaybackProgress
+				if db.Where("user_id = ? AND book_id = ?", userID, r.BookID).First(&progress).Error == nil {
+					markFinishedIfComplete(db, &progress)
+				}
+			}
+		}
+	}
+	log.Printf("🔄 Synced progress for user %d: %d/%d items applied", userID, applied, len(items))
+	c.JSON(http.StatusOK, gin.H{"results": results, "applied": applied})
+}
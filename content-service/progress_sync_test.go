@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSyncProgressItems_ReportsInvalidWithoutFailingOthers(t *testing.T) {
+	owned := map[uint]bool{1: true, 2: true}
+	var applied []uint
+	apply := func(item ProgressSyncItem) (*ProgressResponse, error) {
+		if !owned[item.BookID] {
+			return nil, errBookNotOwned
+		}
+		applied = append(applied, item.BookID)
+		return &ProgressResponse{BookID: item.BookID, CurrentPosition: item.Position}, nil
+	}
+
+	results, err := syncProgressItems([]ProgressSyncItem{
+		{BookID: 1, Position: 120, ChunkIndex: 3, ListenTime: 90},
+		{BookID: 2, Position: -5}, // invalid: never reaches apply
+		{BookID: 2, Position: 40, ChunkIndex: 1},
+	}, apply)
+	if err != nil {
+		t.Fatalf("syncProgressItems: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	if !results[0].OK || results[0].Progress.CurrentPosition != 120 {
+		t.Errorf("item 0: %+v", results[0])
+	}
+	if results[1].OK || results[1].Error == "" || results[1].BookID != 2 {
+		t.Errorf("item 1 should be rejected: %+v", results[1])
+	}
+	if !results[2].OK {
+		t.Errorf("item 2: %+v", results[2])
+	}
+	if len(applied) != 2 || applied[0] != 1 || applied[1] != 2 {
+		t.Errorf("applied = %v, want [1 2]", applied)
+	}
+}
+
+func TestSyncProgressItems_OwnershipPerItem(t *testing.T) {
+	results, err := syncProgressItems([]ProgressSyncItem{{BookID: 7, Position: 1}, {BookID: 8, Position: 1}},
+		func(item ProgressSyncItem) (*ProgressResponse, error) {
+			if item.BookID == 7 {
+				return nil, errBookNotOwned
+			}
+			return &ProgressResponse{BookID: item.BookID}, nil
+		})
+	if err != nil {
+		t.Fatalf("syncProgressItems: %v", err)
+	}
+	if results[0].OK || results[0].Error != errBookNotOwned.Error() || !results[1].OK {
+		t.Fatalf("results = %+v", results)
+	}
+
+	// A database failure isn't a per-item rejection: the batch aborts so the
+	// transaction rolls back.
+	boom := errors.New("connection reset")
+	if _, err := syncProgressItems([]ProgressSyncItem{{BookID: 1}}, func(ProgressSyncItem) (*ProgressResponse, error) {
+		return nil, boom
+	}); !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want %v", err, boom)
+	}
+}
+
+func TestValidateSyncItem(t *testing.T) {
+	for _, item := range []ProgressSyncItem{
+		{},
+		{BookID: 1, ChunkIndex: -1},
+		{BookID: 1, ListenTime: -1},
+		{BookID: 1, ListenTime: maxSyncListenTime + 1},
+	} {
+		if validateSyncItem(item) == nil {
+			t.Errorf("validateSyncItem(%+v) accepted", item)
+		}
+	}
+	if err := validateSyncItem(ProgressSyncItem{BookID: 1, Position: 10, ListenTime: 10}); err != nil {
+		t.Errorf("valid item rejected: %v", err)
+	}
+}
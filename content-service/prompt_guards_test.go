@@ -5,6 +5,8 @@ package main
 // punctuation reformatting) and reject dropped or paraphrased book text.
 
 import (
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 )
@@ -142,6 +144,58 @@ func TestResolveEventTimestamps_OCRLineWrap(t *testing.T) {
 	}
 }
 
+func TestCapFoleyEvents_DropsLeastSpaced(t *testing.T) {
+	// Seven placements; the 10.0/10.5/11.0 cluster and the 30.0/30.2 pair are
+	// the crowded ones.
+	events := EventMap{
+		"thunder":    {2.0, 10.0, 30.0},
+		"door_creak": {10.5, 30.2},
+		"footsteps":  {11.0},
+		"gasp":       {20.0},
+	}
+	got := capFoleyEvents(events, 4)
+	var times []float64
+	for _, ts := range got {
+		times = append(times, ts...)
+	}
+	sort.Float64s(times)
+	want := []float64{2.0, 10.0, 20.0, 30.0}
+	if !reflect.DeepEqual(times, want) {
+		t.Fatalf("kept %v (%v), want %v", times, got, want)
+	}
+
+	under := EventMap{"thunder": {1, 2}}
+	if got := capFoleyEvents(under, 4); !reflect.DeepEqual(got, under) {
+		t.Fatalf("under the cap changed the map: %v", got)
+	}
+}
+
+func TestSpaceFoleyTimes_ShiftsOrDropsClosePlacements(t *testing.T) {
+	// 5.3 is 0.3s after 5.0: pushed to 6.5. 7.0 is then too close to 6.5 and
+	// is pushed to 8.0.
+	got := spaceFoleyTimes([]float64{7.0, 5.0, 5.3}, 1.5, 20)
+	if want := []float64{5.0, 6.5, 8.0}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("spaced = %v, want %v", got, want)
+	}
+	// No room before the end of the page: the late duplicate is dropped.
+	if got := spaceFoleyTimes([]float64{9.0, 9.2}, 1.5, 9.5); !reflect.DeepEqual(got, []float64{9.0}) {
+		t.Fatalf("near the end = %v, want [9]", got)
+	}
+	// Already spaced placements are untouched.
+	if got := spaceFoleyTimes([]float64{1, 4}, 1.5, 0); !reflect.DeepEqual(got, []float64{1, 4}) {
+		t.Fatalf("spaced input changed: %v", got)
+	}
+}
+
+func TestMaxFoleyPerPage(t *testing.T) {
+	for env, want := range map[string]int{"": 3, "5": 5, "0": 0, "-2": 3} {
+		t.Setenv("MAX_FOLEY_PER_PAGE", env)
+		if got := maxFoleyPerPage(); got != want {
+			t.Errorf("MAX_FOLEY_PER_PAGE=%q: %d, want %d", env, got, want)
+		}
+	}
+}
+
 // ---- Phase 3: voice continuity (audit H1) ----
 
 func TestAssignSegmentVoices_StableAcrossChunks(t *testing.T) {
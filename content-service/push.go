@@ -85,7 +85,7 @@ func initAPNs() {
 func RegisterDeviceTokenHandler(c *gin.Context) {
 	userID := getUserIDFromContext(c)
 	if userID == 0 {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		respondError(c, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
 		return
 	}
 	var req struct {
@@ -93,7 +93,7 @@ func RegisterDeviceTokenHandler(c *gin.Context) {
 		Platform string `json:"platform"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Token) == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "token is required")
 		return
 	}
 	platform := req.Platform
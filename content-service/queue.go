@@ -7,7 +7,6 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"path/filepath"
 	"runtime"
 	"time"
 
@@ -33,7 +32,7 @@ func maybeResumeTranscription(accountType string, bookID uint, chunkIndex int) {
 		return // listener still hasn't caught up to the window
 	}
 	db.Model(&Book{}).Where("id = ?", bookID).Update("status", "transcribing")
-	if err := enqueueTranscribeBatch(bookID, start, start+batchSizePages-1, b.UserID, accountType); err != nil {
+	if err := enqueueTranscribeBatch(bookID, start, start+batchSizePages-1, b.UserID, accountType, ttsOverride{}); err != nil {
 		log.Printf("⚠️ resume: enqueue batch for book %d failed: %v", bookID, err)
 	} else {
 		log.Printf("▶️ resumed transcription for book %d at page %d", bookID, start)
@@ -69,6 +68,13 @@ type TaskTranscribeBatch struct {
 	EndPage     int    `json:"end_page"`
 	UserID      uint   `json:"user_id"`
 	AccountType string `json:"account_type"`
+	// One-off voice/language for this book run (tts_override.go).
+	Voice    string `json:"voice,omitempty"`
+	Language string `json:"language,omitempty"`
+}
+
+func (p TaskTranscribeBatch) override() ttsOverride {
+	return ttsOverride{Voice: p.Voice, Language: p.Language}
 }
 
 type TaskMergeChunks struct {
@@ -79,6 +85,7 @@ type TaskFetchCover struct {
 	BookID uint   `json:"book_id"`
 	Title  string `json:"title"`
 	Author string `json:"author"`
+	ISBN   string `json:"isbn,omitempty"`
 }
 
 type TaskParseBook struct {
@@ -101,14 +108,19 @@ type TaskLookAhead struct {
 	AccountType string `json:"account_type"`
 }
 
-// TranscriptionBatch tracks progress of one 20-page transcription batch.
+// TranscriptionBatch tracks progress of one 20-page transcription batch. The
+// owner + tier are persisted so the stall sweeper can re-enqueue the book with
+// the same quota context after a worker restart.
 type TranscriptionBatch struct {
-	ID          uint   `gorm:"primaryKey"`
-	BookID      uint   `gorm:"index"`
+	ID          uint `gorm:"primaryKey"`
+	BookID      uint `gorm:"index"`
 	StartPage   int
 	EndPage     int
 	Status      string `gorm:"default:'queued'"` // queued|processing|ready|failed
+	UserID      uint
+	AccountType string `gorm:"size:32"`
 	CreatedAt   time.Time
+	UpdatedAt   time.Time
 	CompletedAt *time.Time
 }
 
@@ -136,7 +148,11 @@ func startAsyncWorker() error {
 		return err
 	}
 	concurrency := envInt("WORKER_CONCURRENCY", 2*runtime.NumCPU())
-	srv := asynq.NewServer(opt, asynq.Config{Concurrency: concurrency})
+	srv := asynq.NewServer(opt, asynq.Config{
+		Concurrency:    concurrency,
+		RetryDelayFunc: queueRetryDelay,
+		IsFailure:      isQueueFailure,
+	})
 
 	mux := asynq.NewServeMux()
 	mux.HandleFunc(TypeTranscribeBatch, handleTranscribeBatch)
@@ -153,11 +169,18 @@ func startAsyncWorker() error {
 	// Daily GC of orphaned shared page-audio (dedup renderings no book uses).
 	go sharedAudioGCLoop()
 
+	// Frequent sweep of crash-leftover temp files (temp_janitor.go).
+	go tempJanitorLoop()
+
+	// Retry cover lookups for books still without one (cover_retry.go).
+	go coverRetryLoop()
+
 	log.Printf("🛠️  asynq worker starting (concurrency=%d)", concurrency)
 	return srv.Run(mux)
 }
 
-func enqueueParseBook(bookID uint) error {
+// enqueueParseBook queues a book's parse/chunk job; swappable in tests.
+var enqueueParseBook = func(bookID uint) error {
 	b, _ := json.Marshal(TaskParseBook{BookID: bookID})
 	_, err := qClient.Enqueue(asynq.NewTask(TypeParseBook, b),
 		asynq.MaxRetry(3), asynq.Timeout(15*time.Minute), asynq.Queue("default"))
@@ -166,8 +189,10 @@ func enqueueParseBook(bookID uint) error {
 
 // ---- enqueue helpers ----
 
-func enqueueTranscribeBatch(bookID uint, start, end int, userID uint, accountType string) error {
-	b, _ := json.Marshal(TaskTranscribeBatch{BookID: bookID, StartPage: start, EndPage: end, UserID: userID, AccountType: accountType})
+// enqueueTranscribeBatch queues pages start..end of a book; swappable in tests.
+var enqueueTranscribeBatch = func(bookID uint, start, end int, userID uint, accountType string, ov ttsOverride) error {
+	b, _ := json.Marshal(TaskTranscribeBatch{BookID: bookID, StartPage: start, EndPage: end, UserID: userID, AccountType: accountType,
+		Voice: ov.Voice, Language: ov.Language})
 	_, err := qClient.Enqueue(asynq.NewTask(TypeTranscribeBatch, b),
 		asynq.MaxRetry(5), asynq.Timeout(30*time.Minute), asynq.Queue("default"))
 	return err
@@ -206,8 +231,9 @@ func enqueueLookAhead(bookID uint, startIndex, count int, userID uint, accountTy
 	return err
 }
 
-func enqueueFetchCover(bookID uint, title, author string) error {
-	b, _ := json.Marshal(TaskFetchCover{BookID: bookID, Title: title, Author: author})
+// enqueueFetchCover queues a cover lookup for a book; swappable in tests.
+var enqueueFetchCover = func(bookID uint, title, author, isbn string) error {
+	b, _ := json.Marshal(TaskFetchCover{BookID: bookID, Title: title, Author: author, ISBN: isbn})
 	_, err := qClient.Enqueue(asynq.NewTask(TypeFetchCover, b),
 		asynq.MaxRetry(3), asynq.Timeout(2*time.Minute), asynq.Queue("default"))
 	return err
@@ -217,7 +243,7 @@ func enqueueFetchCover(bookID uint, title, author string) error {
 
 // transcribePage runs the full TTS→music→mix→R2 pipeline for one chunk and is
 // idempotent (atomic claim skips already-processing/completed chunks).
-func transcribePage(book Book, chunk BookChunk, userID uint, accountType string) error {
+func transcribePage(ctx context.Context, book Book, chunk BookChunk, userID uint, accountType string) error {
 	claim := db.Model(&BookChunk{}).
 		Where("id = ? AND tts_status NOT IN ?", chunk.ID, []string{"processing", "completed"}).
 		Update("tts_status", "processing")
@@ -225,13 +251,13 @@ func transcribePage(book Book, chunk BookChunk, userID uint, accountType string)
 		return nil // already done or in-flight elsewhere (don't double-consume quota)
 	}
 
-	fail := func() { db.Model(&BookChunk{}).Where("id = ?", chunk.ID).Update("tts_status", "failed") }
+	fail := func(err error) { releaseOrFail(ctx, chunk.ID, err) }
 
 	// Cross-user dedup: if this exact text+engine was already rendered for any
 	// book, reuse the shared audio and skip the whole pipeline (no TTS, brain,
 	// classifiers, foley, or music cost). This is FREE — it costs us nothing —
 	// so it never touches the user's quota (unlimited library listening).
-	if reuseRenderedPageForChunk(book, chunk) {
+	if reuseRenderedPageForChunk(ctx, book, chunk) {
 		return nil
 	}
 
@@ -244,41 +270,32 @@ func transcribePage(book Book, chunk BookChunk, userID uint, accountType string)
 		return errQuotaExceeded
 	}
 
-	audioPath, err := convertTextToAudioForChunk(chunk)
+	audioPath, err := synthesizeChunkAudio(ctx, chunk)
 	if err != nil {
-		fail()
+		fail(err)
 		return err
 	}
 	if dur, derr := getTTSDuration(audioPath); derr == nil {
 		charge(dur) // meter the actual audio-seconds we synthesized
 	}
 	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(chunk.Content)))
-	// Audit H2: score-palette cue (one musical identity per book), with the
-	// legacy per-page prompt path as fallback inside.
-	bgMusic, err := backgroundMusicForPage(book, chunk.Content)
+	// Music + Foley, the same mix as on-demand pages (Foley on the batch
+	// path since audit §4). Plain narration when the sound API is down.
+	mergedAudio, effectsSkipped, err := mixPageEffects(ctx, audioPath, book, chunk, hash)
 	if err != nil {
-		fail()
+		log.Printf("❌ book %d page %d: %v", book.ID, chunk.Index, err)
+		fail(err)
 		return err
 	}
-	mergedAudio, err := mergeAudio(audioPath, bgMusic, book, chunk.Index, chunk.Content, hash)
-	if err != nil {
-		fail()
-		return err
-	}
-	// Foley on the batch path too (decision after audit §4 gap): same
-	// treatment as on-demand pages. Library-cached clips make this ~one
-	// gpt-4o-mini call per fiction page; nonfiction skips inside.
-	mergedAudio = applyFoleyOverlay(mergedAudio, audioPath, book, chunk)
 	// Store the mixed audio at a content-addressed SHARED key so the next book
 	// with identical text+engine reuses it (see page_dedup.go). Register it
-	// after upload so later renders short-circuit.
-	engine := dedupEngineKey(book)
-	key := sharedAudioKey(engine, hash, filepath.Ext(mergedAudio))
-	if _, err := uploadArtifact(context.Background(), mergedAudio, key); err != nil {
-		fail()
+	// after upload so later renders short-circuit. Plain narration (sound API
+	// down) stays per-book and unregistered.
+	key, err := storePageAudio(ctx, book, chunk, mergedAudio, effectsSkipped)
+	if err != nil {
+		fail(err)
 		return err
 	}
-	registerRenderedPage(hash, engine, key, loadVoiceMapJSON(book.ID))
 	db.Model(&BookChunk{}).Where("id = ?", chunk.ID).Updates(map[string]interface{}{
 		"audio_path":       key,
 		"final_audio_path": key,
@@ -288,6 +305,7 @@ func transcribePage(book Book, chunk BookChunk, userID uint, accountType string)
 		// old playlist after a re-render.
 		"hls_path": "",
 	})
+	recordProcessingEvent(book.ID, chunk.Index, stageMerge, eventCompleted, "")
 	// Follow-on: package this page as HLS (non-blocking — doesn't gate playback).
 	if err := enqueueHLSPackage(book.ID, chunk.Index); err != nil {
 		log.Printf("⚠️ failed to enqueue HLS for book %d page %d: %v", book.ID, chunk.Index, err)
@@ -295,10 +313,10 @@ func transcribePage(book Book, chunk BookChunk, userID uint, accountType string)
 	return nil
 }
 
-func upsertBatch(bookID uint, start, end int, status string) {
+func upsertBatch(p TaskTranscribeBatch, status string) {
 	var b TranscriptionBatch
-	if err := db.Where("book_id = ? AND start_page = ? AND end_page = ?", bookID, start, end).First(&b).Error; err != nil {
-		b = TranscriptionBatch{BookID: bookID, StartPage: start, EndPage: end, Status: status}
+	if err := db.Where("book_id = ? AND start_page = ? AND end_page = ?", p.BookID, p.StartPage, p.EndPage).First(&b).Error; err != nil {
+		b = TranscriptionBatch{BookID: p.BookID, StartPage: p.StartPage, EndPage: p.EndPage, Status: status, UserID: p.UserID, AccountType: p.AccountType}
 		db.Create(&b)
 		return
 	}
@@ -315,30 +333,56 @@ func handleTranscribeBatch(ctx context.Context, t *asynq.Task) error {
 	if err := json.Unmarshal(t.Payload(), &p); err != nil {
 		return fmt.Errorf("bad payload: %v: %w", err, asynq.SkipRetry)
 	}
+	ctx = withTTSOverride(ctx, p.override())
 	var book Book
 	if err := db.First(&book, p.BookID).Error; err != nil {
 		return fmt.Errorf("book %d not found: %w", p.BookID, err) // retryable
 	}
-	upsertBatch(p.BookID, p.StartPage, p.EndPage, "processing")
+	// Wait out any on-demand /process-chunks run on this book (book_lock.go).
+	unlock, err := lockBook(ctx, p.BookID, true)
+	if err != nil {
+		return fmt.Errorf("lock book %d: %w", p.BookID, err) // retryable
+	}
+	defer unlock()
+	upsertBatch(p, "processing")
 
 	var chunks []BookChunk
 	db.Where("book_id = ? AND \"index\" BETWEEN ? AND ? AND tts_status <> ?", p.BookID, p.StartPage, p.EndPage, "completed").
 		Order("\"index\" ASC").Find(&chunks)
 
 	capped := false
-	for _, ch := range chunks {
+	waits := 0
+	for i := 0; i < len(chunks); i++ {
+		ch := chunks[i]
 		// transcribePage consumes the per-page quota on a fresh claim; a quota
 		// denial stops the batch.
-		if err := transcribePage(book, ch, p.UserID, p.AccountType); err != nil {
+		if err := transcribePage(ctx, book, ch, p.UserID, p.AccountType); err != nil {
 			if errors.Is(err, errQuotaExceeded) {
 				log.Printf("🛑 transcription quota reached for user %d; stopping book %d", p.UserID, p.BookID)
 				capped = true
 				break
 			}
+			// TTS rate limit: the page is back to pending. Wait out
+			// Retry-After and redo it; past maxRateLimitWaits, hand the
+			// batch back to the queue (re-run after the same delay, not
+			// counted as a failure — see tts_ratelimit.go).
+			if d, limited := ttsRetryAfter(err); limited {
+				if waits++; waits > maxRateLimitWaits {
+					upsertBatch(p, "queued")
+					return err
+				}
+				log.Printf("⏳ TTS rate limited on page %d (book %d); waiting %s", ch.Index, p.BookID, d)
+				if serr := sleepCtx(ctx, d); serr != nil {
+					upsertBatch(p, "queued")
+					return serr
+				}
+				i--
+				continue
+			}
 			log.Printf("⚠️ page %d (book %d) failed: %v", ch.Index, p.BookID, err)
 		}
 	}
-	upsertBatch(p.BookID, p.StartPage, p.EndPage, "ready")
+	upsertBatch(p, "ready")
 
 	// Notify (MQTT): how many pages are now playable.
 	var ready int64
@@ -375,7 +419,7 @@ func handleTranscribeBatch(ctx context.Context, t *asynq.Task) error {
 			log.Printf("⏸️ book %d paused ahead (next page %d, listener+window)", p.BookID, nextStart)
 			return nil
 		}
-		if err := enqueueTranscribeBatch(p.BookID, nextStart, p.EndPage+batchSizePages, p.UserID, p.AccountType); err != nil {
+		if err := enqueueTranscribeBatch(p.BookID, nextStart, p.EndPage+batchSizePages, p.UserID, p.AccountType, p.override()); err != nil {
 			log.Printf("⚠️ failed to enqueue next batch for book %d: %v", p.BookID, err)
 		}
 		return nil
@@ -385,7 +429,7 @@ func handleTranscribeBatch(ctx context.Context, t *asynq.Task) error {
 	var remaining int64
 	db.Model(&BookChunk{}).Where("book_id = ? AND tts_status <> ?", p.BookID, "completed").Count(&remaining)
 	if remaining == 0 {
-		db.Model(&Book{}).Where("id = ?", p.BookID).Update("status", "completed")
+		db.Model(&Book{}).Where("id = ?", p.BookID).Updates(map[string]interface{}{"status": "completed", "needs_reprocess": false, "processed_at": time.Now()})
 		log.Printf("✅ Book %d fully transcribed", p.BookID)
 	} else {
 		db.Model(&Book{}).Where("id = ?", p.BookID).Update("status", "pending")
@@ -407,7 +451,7 @@ func handleFetchCover(ctx context.Context, t *asynq.Task) error {
 		return fmt.Errorf("bad payload: %v: %w", err, asynq.SkipRetry)
 	}
 	bookIDStr := fmt.Sprintf("%d", p.BookID)
-	coverKeyOrPath, publicURL, err := fetchAndSaveBookCover(p.Title, p.Author, bookIDStr)
+	coverKeyOrPath, publicURL, err := fetchAndSaveBookCover(p.Title, p.Author, p.ISBN, bookIDStr)
 	if err != nil {
 		return err // retryable
 	}
@@ -454,14 +498,14 @@ func handleParseBook(ctx context.Context, t *asynq.Task) error {
 	resetBookContent(p.BookID) // idempotent: clear any prior chunks on re-parse
 	pages, err := ChunkDocumentBatch(p.BookID, book.FilePath)
 	if err != nil {
-		// Distinguish "no extractable text" (likely a scanned/image PDF) so the
-		// client can show a tailored message; SkipRetry since retrying the same
-		// textless file will never succeed.
-		if errors.Is(err, errNoTextExtracted) {
-			db.Model(&Book{}).Where("id = ?", p.BookID).Update("status", "no_text_extracted")
+		// Distinguish "no extractable text" (likely a scanned/image PDF) and
+		// DRM-locked ebooks so the client can show a tailored message;
+		// SkipRetry since retrying the same file will never succeed.
+		status := chunkingFailedStatus(err)
+		db.Model(&Book{}).Where("id = ?", p.BookID).Update("status", status)
+		if status != "chunking_failed" {
 			return fmt.Errorf("%w: %v", asynq.SkipRetry, err)
 		}
-		db.Model(&Book{}).Where("id = ?", p.BookID).Update("status", "chunking_failed")
 		return err
 	}
 	db.Model(&Book{}).Where("id = ?", p.BookID).Update("status", "pending")
@@ -540,7 +584,7 @@ func handleLookAhead(ctx context.Context, t *asynq.Task) error {
 			}
 			continue
 		}
-		if err := lookAheadTranscribeChunk(book, ch, p.UserID, p.AccountType); err != nil {
+		if err := lookAheadTranscribeChunk(ctx, book, ch, p.UserID, p.AccountType); err != nil {
 			if errors.Is(err, errQuotaExceeded) {
 				log.Printf("🛑 lookahead quota reached for user %d book %d", p.UserID, p.BookID)
 				break
@@ -551,11 +595,23 @@ func handleLookAhead(ctx context.Context, t *asynq.Task) error {
 	return nil
 }
 
+// releaseOrFail settles a chunk whose synthesis returned an error. A cancelled
+// ctx (client gone, deadline, worker shutdown) isn't the page's fault, so the
+// claim is released back to pending for the next attempt instead of "failed";
+// so is a TTS rate limit (tts_ratelimit.go).
+func releaseOrFail(ctx context.Context, chunkID uint, err error) {
+	status := "failed"
+	if _, limited := ttsRetryAfter(err); ctx.Err() != nil || limited {
+		status = "pending"
+	}
+	db.Model(&BookChunk{}).Where("id = ?", chunkID).Update("tts_status", status)
+}
+
 // lookAheadTranscribeChunk runs the SAME pipeline as the on-demand play path
 // (TTS → music + Foley merge → HLS) for one page, synchronously, so look-ahead
 // pages sound identical and are HLS-ready before the listener arrives. The
 // atomic claim makes it idempotent and safe to race with the play path.
-func lookAheadTranscribeChunk(book Book, chunk BookChunk, userID uint, accountType string) error {
+func lookAheadTranscribeChunk(ctx context.Context, book Book, chunk BookChunk, userID uint, accountType string) error {
 	claim := db.Model(&BookChunk{}).
 		Where("id = ? AND tts_status NOT IN ?", chunk.ID, []string{"processing", "completed"}).
 		Update("tts_status", "processing")
@@ -564,7 +620,7 @@ func lookAheadTranscribeChunk(book Book, chunk BookChunk, userID uint, accountTy
 	}
 	// Cross-user dedup: reuse a shared rendering if this text+engine exists —
 	// FREE, never charged to quota.
-	if reuseRenderedPageForChunk(book, chunk) {
+	if reuseRenderedPageForChunk(ctx, book, chunk) {
 		return nil
 	}
 	// Fresh render: gate on the monthly transcription-time budget.
@@ -573,9 +629,9 @@ func lookAheadTranscribeChunk(book Book, chunk BookChunk, userID uint, accountTy
 		db.Model(&BookChunk{}).Where("id = ?", chunk.ID).Update("tts_status", "pending")
 		return errQuotaExceeded
 	}
-	audioPath, err := convertTextToAudioForChunk(chunk)
+	audioPath, err := synthesizeChunkAudio(ctx, chunk)
 	if err != nil {
-		db.Model(&BookChunk{}).Where("id = ?", chunk.ID).Update("tts_status", "failed")
+		releaseOrFail(ctx, chunk.ID, err)
 		return err
 	}
 	if dur, derr := getTTSDuration(audioPath); derr == nil {
@@ -586,7 +642,7 @@ func lookAheadTranscribeChunk(book Book, chunk BookChunk, userID uint, accountTy
 		"tts_status": "completed",
 	})
 	// Synchronous merge (worker job owns it): sets final_audio_path + enqueues HLS.
-	processSoundEffectsAndMerge(book, book.ContentHash, []int{chunk.Index})
+	processSoundEffectsAndMerge(ctx, book, book.ContentHash, []int{chunk.Index})
 	return nil
 }
 
@@ -599,6 +655,7 @@ func reconcileUploadsLoop() {
 		reconcileStaleUploads()
 		reclaimStalePages()
 		reclaimWedgedParses()
+		reclaimStalledTranscriptions()
 	}
 }
 
@@ -636,6 +693,65 @@ func reclaimWedgedParses() {
 	}
 }
 
+// transcriptionStallAfter is how long a 'transcribing' book may go without any
+// batch activity before the sweeper assumes its task chain was lost (worker
+// restart between batches, failed follow-on enqueue). > batch Timeout (30m).
+const transcriptionStallAfter = 35 * time.Minute
+
+// transcriptionStalled reports whether a book's most recent batch (nil = none
+// recorded yet) has been idle since before cutoff.
+func transcriptionStalled(last *TranscriptionBatch, cutoff time.Time) bool {
+	return last == nil || last.UpdatedAt.Before(cutoff)
+}
+
+// reclaimStalledTranscriptions resumes books left in 'transcribing' whose batch
+// chain stopped moving, re-enqueuing from the first page not yet completed
+// with the owner/tier recorded on the last batch. The per-page claim makes a
+// duplicate enqueue (chain was merely slow) harmless.
+func reclaimStalledTranscriptions() {
+	cutoff := time.Now().Add(-transcriptionStallAfter)
+	var books []Book
+	if err := db.Where("status = ? AND updated_at < ?", "transcribing", cutoff).Find(&books).Error; err != nil {
+		return
+	}
+	for _, b := range books {
+		var last TranscriptionBatch
+		lastPtr := &last
+		if err := db.Where("book_id = ?", b.ID).Order("updated_at DESC").First(&last).Error; err != nil {
+			lastPtr = nil
+		}
+		if !transcriptionStalled(lastPtr, cutoff) {
+			continue
+		}
+		var res struct{ Min *int }
+		db.Model(&BookChunk{}).Select("MIN(\"index\") as min").
+			Where("book_id = ? AND tts_status <> ?", b.ID, "completed").Scan(&res)
+		if res.Min == nil {
+			db.Model(&Book{}).Where("id = ?", b.ID).Updates(map[string]interface{}{"status": "completed", "processed_at": time.Now()})
+			continue
+		}
+		userID, accountType := b.UserID, ""
+		if lastPtr != nil {
+			userID, accountType = last.UserID, last.AccountType
+		}
+		if accountType == "" {
+			// No tier on record (pre-migration batch) — an empty tier would read
+			// as "unlimited", so release the lock and let the user re-trigger.
+			db.Model(&Book{}).Where("id = ?", b.ID).Update("status", "pending")
+			log.Printf("♻️ book %d stalled in 'transcribing' with no tier on record — released", b.ID)
+			continue
+		}
+		start := *res.Min
+		if err := enqueueTranscribeBatch(b.ID, start, start+batchSizePages-1, userID, accountType, ttsOverride{}); err != nil {
+			log.Printf("⚠️ stall sweep: enqueue batch for book %d failed: %v", b.ID, err)
+			continue
+		}
+		// Touch the book so the next sweep waits a full window before retrying.
+		db.Model(&Book{}).Where("id = ?", b.ID).Update("updated_at", time.Now())
+		log.Printf("♻️ resumed stalled transcription for book %d at page %d", b.ID, start)
+	}
+}
+
 func reconcileStaleUploads() {
 	cutoff := time.Now().Add(-15 * time.Minute)
 	var books []Book
@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"testing"
+	"time"
 )
 
 func TestTranscribeBatchPayloadRoundTrip(t *testing.T) {
@@ -48,3 +49,61 @@ func TestParseBookPayloadRoundTrip(t *testing.T) {
 		t.Fatalf("round-trip failed: %v %+v", err, out)
 	}
 }
+
+func TestTranscriptionStalled(t *testing.T) {
+	now := time.Now()
+	cutoff := now.Add(-transcriptionStallAfter)
+	if !transcriptionStalled(nil, cutoff) {
+		t.Fatal("a transcribing book with no recorded batch should count as stalled")
+	}
+	recent := &TranscriptionBatch{Status: "processing", UpdatedAt: now.Add(-time.Minute)}
+	if transcriptionStalled(recent, cutoff) {
+		t.Fatal("a batch updated a minute ago is still moving")
+	}
+	// Worker died mid-book: the last batch never advanced past 'processing'.
+	dead := &TranscriptionBatch{Status: "processing", UpdatedAt: now.Add(-2 * time.Hour)}
+	if !transcriptionStalled(dead, cutoff) {
+		t.Fatal("a batch idle for 2h should be resumed from the queue")
+	}
+}
+
+// A book whose last batch died mid-run is picked up again at its first page
+// not yet completed, for the owner and tier on that batch.
+func TestReclaimStalledTranscriptions_ResumesAtFirstUnfinishedPage(t *testing.T) {
+	withTestDB(t)
+	type batch struct {
+		bookID     uint
+		start, end int
+		userID     uint
+		tier       string
+	}
+	var queued []batch
+	orig := enqueueTranscribeBatch
+	enqueueTranscribeBatch = func(bookID uint, start, end int, userID uint, accountType string, _ ttsOverride) error {
+		queued = append(queued, batch{bookID, start, end, userID, accountType})
+		return nil
+	}
+	t.Cleanup(func() { enqueueTranscribeBatch = orig })
+
+	idle := time.Now().Add(-2 * transcriptionStallAfter)
+	book := Book{Title: "Stalled", Category: "Fiction", UserID: 3, Status: "transcribing"}
+	db.Create(&book)
+	for i, s := range []string{"completed", "completed", "processing", "pending"} {
+		db.Create(&BookChunk{BookID: book.ID, Index: i, Content: "page", TTSStatus: s})
+	}
+	db.Create(&TranscriptionBatch{BookID: book.ID, StartPage: 0, EndPage: batchSizePages - 1, UserID: 3, AccountType: "premium",
+		Status: "processing", UpdatedAt: idle})
+	db.Model(&Book{}).Where("id = ?", book.ID).UpdateColumn("updated_at", idle)
+
+	reclaimStalledTranscriptions()
+
+	want := batch{book.ID, 2, 2 + batchSizePages - 1, 3, "premium"}
+	if len(queued) != 1 || queued[0] != want {
+		t.Fatalf("queued %+v, want %+v", queued, want)
+	}
+	var reloaded Book
+	db.First(&reloaded, book.ID)
+	if !reloaded.UpdatedAt.After(idle) {
+		t.Error("book not touched; the next sweep would re-enqueue it at once")
+	}
+}
@@ -59,6 +59,12 @@ func seedPlanLimits() {
 		{AccountType: "paid", Metric: "transcribe_pages", MonthlyLimit: 1000, HardCap: false},
 		{AccountType: "paid", Metric: "uploads", MonthlyLimit: 20, HardCap: false},
 		{AccountType: "paid", Metric: "stream_pages", MonthlyLimit: 100000, HardCap: false},
+		// Stripe/IAP tiers get the same page/upload allowances as legacy paid
+		// (no row would mean unlimited).
+		{AccountType: "starter", Metric: "transcribe_pages", MonthlyLimit: 1000, HardCap: false},
+		{AccountType: "starter", Metric: "uploads", MonthlyLimit: 20, HardCap: false},
+		{AccountType: "premium", Metric: "transcribe_pages", MonthlyLimit: 1000, HardCap: false},
+		{AccountType: "premium", Metric: "uploads", MonthlyLimit: 20, HardCap: false},
 
 		// Fresh-transcription budget — audio-SECONDS of genuinely-NEW synthesis
 		// (cache-miss renders, our real cost). Cached reuse is unlimited/free and
@@ -225,7 +231,7 @@ func transcriptionUsageHandler(c *gin.Context) {
 
 // quota429 writes the structured paywall response.
 func quota429(c *gin.Context, d QuotaDecision) {
-	c.JSON(http.StatusTooManyRequests, gin.H{
+	respondErrorWith(c, http.StatusTooManyRequests, errCodeQuotaExceeded, fmt.Sprintf("%s quota exceeded", d.Metric), gin.H{
 		"error":       "quota_exceeded",
 		"quota":       d.Metric,
 		"used":        d.Used,
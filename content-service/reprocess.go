@@ -0,0 +1,78 @@
+package main
+
+import (
+	"log"
+)
+
+// Book settings that change the generated audio. Any settings-mutation
+// endpoint that writes one of these columns marks the book NeedsReprocess and
+// calls reprocessBook, so already-rendered pages are regenerated instead of
+// mixing old and new settings within one book.
+var audioSettingColumns = map[string]bool{
+	"audio_format": true,
+	"foley_volume": true,
+	"tts_engine":   true,
+	"voice_map":    true,
+}
+
+// settingsRequireReprocess reports whether a column update touches a setting
+// that affects generated audio.
+func settingsRequireReprocess(updates map[string]interface{}) bool {
+	for col := range updates {
+		if audioSettingColumns[col] {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueueReprocess starts regeneration at page start; swappable in tests.
+var enqueueReprocess = func(bookID uint, start int, userID uint, accountType string) error {
+	return enqueueTranscribeBatch(bookID, start, start+batchSizePages-1, userID, accountType, ttsOverride{})
+}
+
+// reprocessBook resets a book's rendered pages to pending and re-queues them
+// through the TTS batch pipeline, starting at the owner's listening position
+// (earlier pages are picked up again by maybeResumeTranscription). Fresh
+// renders count against the owner's transcription quota like any other.
+// NeedsReprocess is cleared by handleTranscribeBatch once every page is done.
+// Pages mid-render keep the settings they started with.
+func reprocessBook(bookID, userID uint, accountType string) error {
+	// The regenerated pages get effects again if the sound API is back.
+	if err := db.Model(&Book{}).Where("id = ?", bookID).
+		Updates(map[string]interface{}{"needs_reprocess": true, "effects_skipped": false}).Error; err != nil {
+		return err
+	}
+	reset := db.Model(&BookChunk{}).
+		Where("book_id = ? AND tts_status IN ?", bookID, []string{"completed", "failed"}).
+		Updates(map[string]interface{}{"tts_status": "pending", "hls_path": ""})
+	if reset.Error != nil {
+		return reset.Error
+	}
+	// Joined page-range audio (page_range_stream.go) was built from the old
+	// pages; drop it so ranges are rebuilt from the regenerated ones.
+	if err := db.Where("book_id = ?", bookID).Delete(&ProcessedChunkGroup{}).Error; err != nil {
+		log.Printf("⚠️ Book %d: could not clear cached page ranges: %v", bookID, err)
+	}
+
+	// Same lock as BatchTranscribeBookHandler: if a transcription is already
+	// running it will find the reset pages on its own.
+	claim := db.Model(&Book{}).
+		Where("id = ? AND status <> ?", bookID, "transcribing").
+		Update("status", "transcribing")
+	if claim.Error != nil {
+		return claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		log.Printf("🔁 Book %d: %d page(s) reset for reprocessing (transcription already running)", bookID, reset.RowsAffected)
+		return nil
+	}
+
+	start := listenerChunkIndex(userID, bookID)
+	if err := enqueueReprocess(bookID, start, userID, accountType); err != nil {
+		db.Model(&Book{}).Where("id = ?", bookID).Update("status", "pending")
+		return err
+	}
+	log.Printf("🔁 Book %d: %d page(s) queued for reprocessing from page %d", bookID, reset.RowsAffected, start)
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// dryRunDB swaps in a gorm handle that builds SQL without a server and
+// returns the UPDATE statements it would have run. Every UPDATE reports one
+// row affected, so conditional claims succeed. SELECTs are recorded too, and
+// bind values are inlined.
+func dryRunDB(t *testing.T) *[]string {
+	t.Helper()
+	dry, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=test"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("dry-run db: %v", err)
+	}
+	var stmts []string
+	dry.Callback().Update().After("gorm:update").Register("test:record", func(tx *gorm.DB) {
+		stmts = append(stmts, tx.Dialector.Explain(tx.Statement.SQL.String(), tx.Statement.Vars...))
+		tx.RowsAffected = 1
+	})
+	dry.Callback().Query().After("gorm:query").Register("test:record", func(tx *gorm.DB) {
+		stmts = append(stmts, tx.Dialector.Explain(tx.Statement.SQL.String(), tx.Statement.Vars...))
+	})
+	orig := db
+	db = dry
+	t.Cleanup(func() { db = orig })
+	return &stmts
+}
+
+func TestApplyBookUpdate_AudioSettingFlagsReprocess(t *testing.T) {
+	book := Book{Title: "T", Category: "Fiction"}
+	updates, _, err := applyBookUpdate(&book, BookUpdateRequest{AudioFormat: strp("OPUS")})
+	if err != nil {
+		t.Fatalf("applyBookUpdate: %v", err)
+	}
+	if updates["audio_format"] != "opus" || updates["needs_reprocess"] != true || !book.NeedsReprocess {
+		t.Fatalf("updates = %v, book = %+v", updates, book)
+	}
+
+	// Metadata-only edits don't regenerate audio.
+	book = Book{Title: "T", Category: "Fiction"}
+	updates, _, _ = applyBookUpdate(&book, BookUpdateRequest{Title: strp("New")})
+	if _, ok := updates["needs_reprocess"]; ok || book.NeedsReprocess {
+		t.Fatalf("title change flagged reprocess: %v", updates)
+	}
+
+	// Re-sending the current value is not a change.
+	book = Book{Title: "T", Category: "Fiction", AudioFormat: "opus"}
+	if updates, _, _ = applyBookUpdate(&book, BookUpdateRequest{AudioFormat: strp("opus")}); len(updates) != 0 {
+		t.Fatalf("no-op format change produced %v", updates)
+	}
+
+	if _, _, err := applyBookUpdate(&book, BookUpdateRequest{AudioFormat: strp("wav")}); err == nil {
+		t.Fatal("invalid audio_format accepted")
+	}
+}
+
+func TestUpdateBookHandler_SettingChangeEnqueuesReprocess(t *testing.T) {
+	stmts := dryRunDB(t)
+
+	type call struct {
+		bookID, userID uint
+		start          int
+	}
+	var calls []call
+	orig := enqueueReprocess
+	enqueueReprocess = func(bookID uint, start int, userID uint, accountType string) error {
+		calls = append(calls, call{bookID, userID, start})
+		return nil
+	}
+	t.Cleanup(func() { enqueueReprocess = orig })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.PATCH("/books/:book_id", func(c *gin.Context) {
+		c.Set("book", Book{ID: 4, UserID: 9, Title: "T", Category: "Fiction", AudioFormat: "mp3"})
+		updateBookHandler(c)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/books/4", strings.NewReader(`{"audio_format":"aac"}`)))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"reprocessing":true`) {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+
+	sql := strings.Join(*stmts, "\n")
+	if !strings.Contains(sql, `"needs_reprocess"`) {
+		t.Errorf("needs_reprocess never written:\n%s", sql)
+	}
+	if !strings.Contains(sql, `UPDATE "book_chunks" SET "hls_path"`) || !strings.Contains(sql, `"tts_status"`) {
+		t.Errorf("chunk statuses not reset:\n%s", sql)
+	}
+	if len(calls) != 1 || calls[0] != (call{bookID: 4, userID: 9, start: 0}) {
+		t.Errorf("reprocess enqueues = %+v, want one for book 4 / user 9 from page 0", calls)
+	}
+}
@@ -43,17 +43,6 @@ func envStr(name, def string) string {
 	return def
 }
 
-// classifyModel is used for cheap classification/extraction calls (mood,
-// ambient, foley, cue picking). Audit L6: these don't need gpt-4o.
-func classifyModel() string { return envStr("OPENAI_CLASSIFY_MODEL", "gpt-4o-mini") }
-
-// dialogueModel is used for dialogue analysis and narrator text prep — the
-// correctness-sensitive calls guarded by segmentsCoverInput.
-func dialogueModel() string { return envStr("OPENAI_DIALOGUE_MODEL", "gpt-4o") }
-
-// paletteModel designs the score palette — one call per book, quality matters.
-func paletteModel() string { return envStr("OPENAI_PALETTE_MODEL", "gpt-4o") }
-
 func scoreCueKey(bookID uint, mood string) string {
 	return fmt.Sprintf("audio/%d/score/%s.mp3", bookID, mood)
 }
@@ -232,10 +221,10 @@ func getOrCreateScorePalette(book Book) ([]ScoreCue, error) {
 	// Render each cue once and persist to R2.
 	cues := make([]ScoreCue, 0, len(scoreMoods))
 	for _, mood := range scoreMoods {
-		clip, err := generateSoundEffect(prompts[mood], fmt.Sprintf("score_%d_%s", book.ID, mood))
+		clip, err := generateSoundEffect(context.Background(), prompts[mood], backgroundClipSeconds(), fmt.Sprintf("score_%d_%s", book.ID, mood))
 		if err != nil {
 			log.Printf("⚠️ [Palette] cue %q render failed for book %d: %v — retrying with default prompt", mood, book.ID, err)
-			clip, err = generateSoundEffect(defaultCuePrompt(mood), fmt.Sprintf("score_%d_%s", book.ID, mood))
+			clip, err = generateSoundEffect(context.Background(), defaultCuePrompt(mood), backgroundClipSeconds(), fmt.Sprintf("score_%d_%s", book.ID, mood))
 			if err != nil {
 				log.Printf("⚠️ [Palette] cue %q failed twice, skipping: %v", mood, err)
 				continue
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Service-to-service auth: signs calls to other services' /internal/*
+// endpoints with the shared SERVICE_SECRET, and verifies calls to our own
+// /internal group the same way (timestamp window + single-use nonce). The
+// scheme mirrors auth-service/service_auth.go; keep the two in sync.
+//
+//	X-Service-Signature = hex(HMAC-SHA256(secret, method\ntarget\ntimestamp\nnonce\nhex(sha256(body))))
+//
+// target is the path plus "?" and the raw query when there is one, so the
+// query is signed too.
+
+const serviceAuthMaxSkew = 5 * time.Minute
+
+// serviceNonceStore remembers nonces seen inside the skew window so a captured
+// request can't be replayed.
+var serviceNonceStore = struct {
+	sync.Mutex
+	seen map[string]time.Time // nonce -> expiry
+}{seen: map[string]time.Time{}}
+
+// serviceTarget is the signed request target: path, plus the raw query.
+func serviceTarget(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Path
+	}
+	return u.Path + "?" + u.RawQuery
+}
+
+// serviceSignature computes the request signature for the scheme above.
+func serviceSignature(secret, method, target, timestamp, nonce string, body []byte) string {
+	bodySum := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n" + target + "\n" + timestamp + "\n" + nonce + "\n" + hex.EncodeToString(bodySum[:])))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signServiceRequest stamps req with a fresh timestamp, nonce and signature.
+// body must be the exact bytes sent (nil for GET).
+func signServiceRequest(req *http.Request, secret string, body []byte) error {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := hex.EncodeToString(b)
+	req.Header.Set("X-Service-Timestamp", ts)
+	req.Header.Set("X-Service-Nonce", nonce)
+	req.Header.Set("X-Service-Signature", serviceSignature(secret, req.Method, serviceTarget(req.URL), ts, nonce, body))
+	return nil
+}
+
+// rememberServiceNonce records a nonce; false if it was already used.
+func rememberServiceNonce(nonce string, now time.Time) bool {
+	serviceNonceStore.Lock()
+	defer serviceNonceStore.Unlock()
+	for n, exp := range serviceNonceStore.seen {
+		if now.After(exp) {
+			delete(serviceNonceStore.seen, n)
+		}
+	}
+	if exp, ok := serviceNonceStore.seen[nonce]; ok && now.Before(exp) {
+		return false
+	}
+	// Entries must outlive the whole window a timestamp is accepted in.
+	serviceNonceStore.seen[nonce] = now.Add(2 * serviceAuthMaxSkew)
+	return true
+}
+
+// serviceAuthMiddleware guards our /internal endpoints with the HMAC scheme
+// above. Fails closed: with no SERVICE_SECRET configured every call is
+// refused.
+func serviceAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := getEnv("SERVICE_SECRET", "")
+		if secret == "" {
+			log.Printf("⚠️ internal call to %s refused: SERVICE_SECRET not set", c.Request.URL.Path)
+			abortWithError(c, http.StatusServiceUnavailable, errCodeServiceUnavailable, "service auth not configured")
+			return
+		}
+
+		ts := c.GetHeader("X-Service-Timestamp")
+		nonce := c.GetHeader("X-Service-Nonce")
+		sig := c.GetHeader("X-Service-Signature")
+		if ts == "" || nonce == "" || sig == "" {
+			abortWithError(c, http.StatusUnauthorized, errCodeUnauthorized, "missing service signature")
+			return
+		}
+		unix, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			abortWithError(c, http.StatusUnauthorized, errCodeUnauthorized, "invalid service timestamp")
+			return
+		}
+		now := time.Now()
+		if d := now.Sub(time.Unix(unix, 0)); d > serviceAuthMaxSkew || d < -serviceAuthMaxSkew {
+			abortWithError(c, http.StatusUnauthorized, errCodeUnauthorized, "stale service request")
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			if body, err = io.ReadAll(c.Request.Body); err != nil {
+				abortWithError(c, http.StatusBadRequest, errCodeInvalidRequest, "could not read body")
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		want := serviceSignature(secret, c.Request.Method, serviceTarget(c.Request.URL), ts, nonce, body)
+		if !hmac.Equal([]byte(sig), []byte(want)) {
+			abortWithError(c, http.StatusUnauthorized, errCodeUnauthorized, "invalid service signature")
+			return
+		}
+		// Only a correctly signed request may burn a nonce.
+		if !rememberServiceNonce(nonce, now) {
+			abortWithError(c, http.StatusUnauthorized, errCodeUnauthorized, "replayed service request")
+			return
+		}
+		c.Next()
+	}
+}
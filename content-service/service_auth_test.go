@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Same vector as auth-service/service_auth_test.go: the signer here and the
+// verifier there must agree byte-for-byte.
+func TestServiceSignatureVector(t *testing.T) {
+	got := serviceSignature("s3cret", "GET", "/internal/users/7/account-type", "1700000000", "n1", nil)
+	if want := "b58a11abc116428124fc37afafb85e7f6c96e72436ed8e4a740ea120d9e29962"; got != want {
+		t.Fatalf("serviceSignature = %q, want %q", got, want)
+	}
+}
+
+func TestSignServiceRequest(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://auth-service:8082/internal/users/7/account-type", nil)
+	if err := signServiceRequest(req, "s3cret", nil); err != nil {
+		t.Fatal(err)
+	}
+	ts, nonce := req.Header.Get("X-Service-Timestamp"), req.Header.Get("X-Service-Nonce")
+	if ts == "" || nonce == "" {
+		t.Fatal("timestamp/nonce headers missing")
+	}
+	want := serviceSignature("s3cret", "GET", "/internal/users/7/account-type", ts, nonce, nil)
+	if got := req.Header.Get("X-Service-Signature"); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	// A tampered path no longer matches.
+	if serviceSignature("s3cret", "GET", "/internal/users/8/account-type", ts, nonce, nil) == want {
+		t.Error("signature does not bind the path")
+	}
+
+	// The query is part of what's signed.
+	withQuery, _ := http.NewRequest("GET", "http://auth-service:8082/internal/users/7/account-type?fresh=1", nil)
+	signServiceRequest(withQuery, "s3cret", nil)
+	ts, nonce = withQuery.Header.Get("X-Service-Timestamp"), withQuery.Header.Get("X-Service-Nonce")
+	if got := withQuery.Header.Get("X-Service-Signature"); got != serviceSignature("s3cret", "GET", "/internal/users/7/account-type?fresh=1", ts, nonce, nil) ||
+		got == serviceSignature("s3cret", "GET", "/internal/users/7/account-type?fresh=0", ts, nonce, nil) {
+		t.Error("signature does not bind the query")
+	}
+
+	other, _ := http.NewRequest("GET", "http://x/internal/users/7/account-type", nil)
+	signServiceRequest(other, "s3cret", nil)
+	if other.Header.Get("X-Service-Nonce") == nonce {
+		t.Error("nonce reused across requests")
+	}
+}
+
+func TestServiceAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("SERVICE_SECRET", "s3cret")
+	r := gin.New()
+	r.POST("/internal/ping", serviceAuthMiddleware(), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	send := func(body, ts, nonce, sig string) int {
+		req := httptest.NewRequest(http.MethodPost, "/internal/ping", strings.NewReader(body))
+		req.Header.Set("X-Service-Timestamp", ts)
+		req.Header.Set("X-Service-Nonce", nonce)
+		req.Header.Set("X-Service-Signature", sig)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	body := `{"book_id":7}`
+	sig := serviceSignature("s3cret", "POST", "/internal/ping", now, "nonce-a", []byte(body))
+
+	if code := send(body, now, "nonce-a", sig); code != http.StatusNoContent {
+		t.Fatalf("valid signed call: got %d", code)
+	}
+	if code := send(body, now, "nonce-a", sig); code != http.StatusUnauthorized {
+		t.Errorf("replayed call: got %d, want 401", code)
+	}
+	sigB := serviceSignature("s3cret", "POST", "/internal/ping", now, "nonce-b", []byte(body))
+	if code := send(`{"book_id":8}`, now, "nonce-b", sigB); code != http.StatusUnauthorized {
+		t.Errorf("tampered body: got %d, want 401", code)
+	}
+	old := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+	sigOld := serviceSignature("s3cret", "POST", "/internal/ping", old, "nonce-c", []byte(body))
+	if code := send(body, old, "nonce-c", sigOld); code != http.StatusUnauthorized {
+		t.Errorf("stale timestamp: got %d, want 401", code)
+	}
+
+	t.Setenv("SERVICE_SECRET", "")
+	if code := send(body, now, "nonce-d", sig); code != http.StatusServiceUnavailable {
+		t.Errorf("no secret configured: got %d, want 503", code)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Public share links. An owner mints a read-only link to one book's audio
+// (POST /user/books/:book_id/share); anyone holding it can stream the book
+// without an account until it expires or the owner revokes it. The token is
+// server-signed — <base64url(share_id.expires_unix)>.<hex HMAC-SHA256> under
+// SHARE_LINK_SECRET — so it can't be forged or extended, and each one is
+// backed by a BookShare row so it can be revoked before it expires.
+
+const (
+	defaultShareHours = 72
+	maxShareHours     = 720
+)
+
+var errInvalidShareToken = errors.New("invalid share token")
+
+// BookShare is one minted share link.
+type BookShare struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	BookID    uint       `gorm:"index;not null" json:"book_id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// shareLinkSecret is the signing key; empty disables share links.
+func shareLinkSecret() string { return os.Getenv("SHARE_LINK_SECRET") }
+
+func shareSignature(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signShareToken mints the token for share shareID expiring at expires.
+func signShareToken(secret string, shareID uint, expires time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d.%d", shareID, expires.Unix())))
+	return payload + "." + shareSignature(secret, payload)
+}
+
+// parseShareToken verifies token's signature and expiry and returns the share
+// it names. Every failure is errInvalidShareToken, so callers can't tell a
+// forged token from an expired one.
+func parseShareToken(secret, token string, now time.Time) (uint, error) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(shareSignature(secret, payload))) {
+		return 0, errInvalidShareToken
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return 0, errInvalidShareToken
+	}
+	idStr, expStr, ok := strings.Cut(string(raw), ".")
+	if !ok {
+		return 0, errInvalidShareToken
+	}
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil || id == 0 {
+		return 0, errInvalidShareToken
+	}
+	exp, err := strconv.ParseInt(expStr, 10, 64)
+	if err != nil || !now.Before(time.Unix(exp, 0)) {
+		return 0, errInvalidShareToken
+	}
+	return uint(id), nil
+}
+
+// shareUsable reports whether s may still be streamed at now.
+func shareUsable(s BookShare, now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+func shareStreamURL(token string) string {
+	return getEnv("STREAM_HOST", "https://narrafied.com") + "/shared/" + token + "/stream"
+}
+
+// createBookShareHandler mints a share link for one of the caller's books.
+// POST /user/books/:book_id/share  {"expires_in_hours": 72}
+func createBookShareHandler(c *gin.Context) {
+	book := c.MustGet("book").(Book)
+	secret := shareLinkSecret()
+	if secret == "" {
+		respondError(c, http.StatusServiceUnavailable, errCodeServiceUnavailable, "Share links are not configured")
+		return
+	}
+	var req struct {
+		ExpiresInHours int `json:"expires_in_hours"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid request body")
+			return
+		}
+	}
+	if req.ExpiresInHours == 0 {
+		req.ExpiresInHours = defaultShareHours
+	}
+	if req.ExpiresInHours < 1 || req.ExpiresInHours > maxShareHours {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("expires_in_hours must be between 1 and %d", maxShareHours))
+		return
+	}
+	if book.AudioPath == "" {
+		respondError(c, http.StatusConflict, errCodeAudioNotReady, "Book audio is not ready to share")
+		return
+	}
+
+	share := BookShare{
+		BookID:    book.ID,
+		UserID:    book.UserID,
+		ExpiresAt: time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour).Truncate(time.Second),
+	}
+	if err := db.Create(&share).Error; err != nil {
+		respondInternal(c, "Could not create share link", err)
+		return
+	}
+	token := signShareToken(secret, share.ID, share.ExpiresAt)
+	c.JSON(http.StatusCreated, gin.H{
+		"share_id":    share.ID,
+		"share_token": token,
+		"url":         shareStreamURL(token),
+		"expires_at":  share.ExpiresAt,
+	})
+}
+
+// listBookSharesHandler lists a book's share links, newest first.
+// GET /user/books/:book_id/shares
+func listBookSharesHandler(c *gin.Context) {
+	book := c.MustGet("book").(Book)
+	var shares []BookShare
+	if err := db.Where("book_id = ?", book.ID).Order("created_at DESC").Find(&shares).Error; err != nil {
+		respondInternal(c, "Could not list share links", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"shares": shares})
+}
+
+// revokeBookShareHandler disables a share link before it expires.
+// DELETE /user/books/:book_id/shares/:share_id
+func revokeBookShareHandler(c *gin.Context) {
+	book := c.MustGet("book").(Book)
+	var share BookShare
+	if err := db.Where("id = ? AND book_id = ?", c.Param("share_id"), book.ID).First(&share).Error; err != nil {
+		respondError(c, http.StatusNotFound, errCodeNotFound, "Share link not found")
+		return
+	}
+	if share.RevokedAt == nil {
+		now := time.Now()
+		if err := db.Model(&share).Update("revoked_at", now).Error; err != nil {
+			respondInternal(c, "Could not revoke share link", err)
+			return
+		}
+		share.RevokedAt = &now
+	}
+	c.JSON(http.StatusOK, gin.H{"share": share})
+}
+
+// sharedStreamHandler streams a shared book's audio without a JWT.
+// GET /shared/:share_token/stream
+func sharedStreamHandler(c *gin.Context) {
+	secret := shareLinkSecret()
+	notFound := func() {
+		respondError(c, http.StatusNotFound, errCodeNotFound, "Share link is invalid or has expired")
+	}
+	if secret == "" {
+		notFound()
+		return
+	}
+	now := time.Now()
+	shareID, err := parseShareToken(secret, c.Param("share_token"), now)
+	if err != nil {
+		notFound()
+		return
+	}
+	var share BookShare
+	if err := db.First(&share, shareID).Error; err != nil || !shareUsable(share, now) {
+		notFound()
+		return
+	}
+	var book Book
+	if err := db.First(&book, share.BookID).Error; err != nil {
+		notFound()
+		return
+	}
+	if book.AudioPath == "" {
+		respondError(c, http.StatusConflict, errCodeAudioNotReady, "Audio for this book isn't ready yet")
+		return
+	}
+	c.Header("Cache-Control", "private, no-store")
+	serveMedia(c, book.AudioPath)
+}
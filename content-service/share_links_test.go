@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+func TestShareToken_RoundTrip(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	token := signShareToken("secret", 42, now.Add(time.Hour))
+	if id, err := parseShareToken("secret", token, now); err != nil || id != 42 {
+		t.Fatalf("valid token = %d, %v", id, err)
+	}
+	if _, err := parseShareToken("secret", token, now.Add(time.Hour)); err != errInvalidShareToken {
+		t.Fatalf("expired token: %v", err)
+	}
+	if _, err := parseShareToken("other", token, now); err != errInvalidShareToken {
+		t.Fatalf("token under another secret: %v", err)
+	}
+	// Re-encoding the payload with a later expiry must not pass the signature.
+	payload, sig, _ := strings.Cut(token, ".")
+	forged := signShareToken("secret", 42, now.Add(999*time.Hour))
+	fp, _, _ := strings.Cut(forged, ".")
+	for _, bad := range []string{fp + "." + sig, payload, "", "." + sig, payload + ".00"} {
+		if _, err := parseShareToken("secret", bad, now); err != errInvalidShareToken {
+			t.Errorf("parseShareToken(%q) accepted", bad)
+		}
+	}
+}
+
+func TestShareUsable(t *testing.T) {
+	now := time.Now()
+	revoked := now.Add(-time.Minute)
+	cases := []struct {
+		share BookShare
+		want  bool
+	}{
+		{BookShare{ExpiresAt: now.Add(time.Hour)}, true},
+		{BookShare{ExpiresAt: now.Add(-time.Second)}, false},
+		{BookShare{ExpiresAt: now.Add(time.Hour), RevokedAt: &revoked}, false},
+	}
+	for _, tc := range cases {
+		if got := shareUsable(tc.share, now); got != tc.want {
+			t.Errorf("shareUsable(%+v) = %v, want %v", tc.share, got, tc.want)
+		}
+	}
+}
+
+// End to end: the owner mints a link, a caller with no JWT streams through it,
+// and it stops working once revoked or expired.
+func TestSharedStream_ValidExpiredRevoked(t *testing.T) {
+	withTestDB(t)
+	t.Setenv("SHARE_LINK_SECRET", "test-secret")
+	audio := filepath.Join(t.TempDir(), "book.mp3")
+	os.WriteFile(audio, []byte("ID3 shared"), 0644)
+	book := Book{Title: "Shared", Category: "Fiction", UserID: 1, AudioPath: audio}
+	if err := db.Create(&book).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/shared/:share_token/stream", sharedStreamHandler)
+	g := r.Group("/user", func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"user_id": float64(1)})
+		c.Set("user_id", uint(1))
+	})
+	registerUserRoutes(g)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+	mint := func() (uint, string) {
+		w := do(http.MethodPost, fmt.Sprintf("/user/books/%d/share", book.ID), `{"expires_in_hours":1}`)
+		var resp struct {
+			ShareID    uint   `json:"share_id"`
+			ShareToken string `json:"share_token"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); w.Code != http.StatusCreated || err != nil {
+			t.Fatalf("mint: %d %s", w.Code, w.Body)
+		}
+		return resp.ShareID, resp.ShareToken
+	}
+
+	id, token := mint()
+	if w := do(http.MethodGet, "/shared/"+token+"/stream", ""); w.Code != http.StatusOK || w.Body.String() != "ID3 shared" {
+		t.Fatalf("valid share: %d %q", w.Code, w.Body)
+	}
+
+	// Audio dropped since the link was minted: a clear 409, not a broken stream.
+	db.Model(&Book{}).Where("id = ?", book.ID).Update("audio_path", "")
+	if w := do(http.MethodGet, "/shared/"+token+"/stream", ""); w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), errCodeAudioNotReady) {
+		t.Fatalf("share without audio: %d %s, want 409", w.Code, w.Body)
+	}
+	db.Model(&Book{}).Where("id = ?", book.ID).Update("audio_path", audio)
+
+	if w := do(http.MethodDelete, fmt.Sprintf("/user/books/%d/shares/%d", book.ID, id), ""); w.Code != http.StatusOK {
+		t.Fatalf("revoke: %d %s", w.Code, w.Body)
+	}
+	if w := do(http.MethodGet, "/shared/"+token+"/stream", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("revoked share: %d, want 404", w.Code)
+	}
+
+	// The row expiring ends the link even while the token is still in date.
+	id, token = mint()
+	db.Model(&BookShare{}).Where("id = ?", id).Update("expires_at", time.Now().Add(-time.Minute))
+	if w := do(http.MethodGet, "/shared/"+token+"/stream", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expired share: %d, want 404", w.Code)
+	}
+}
@@ -24,10 +24,8 @@ import (
 
 // -------------------- constants & types --------------------
 
-const (
-	elevenLabsSoundEffectsURL = "https://api.elevenlabs.io/v1/sound-generation"
-	openAIChatURL             = "https://api.openai.com/v1/chat/completions"
-)
+// elevenLabsSoundEffectsURL is the sound-generation endpoint; swappable in tests.
+var elevenLabsSoundEffectsURL = "https://api.elevenlabs.io/v1/sound-generation"
 
 type Segment struct {
 	Start float64 `json:"start"`
@@ -51,14 +49,40 @@ var (
 	effectCacheMu sync.RWMutex
 )
 
-// musicCache maps a background-music prompt hash to its generated clip path so
-// identical prompts reuse one ElevenLabs generation instead of regenerating per
-// page (Q3). Guarded by musicCacheMu.
+// foleyClipDir holds generated Foley clips on local disk; swappable in tests.
+var foleyClipDir = "./audio"
+
+// foleyClipPath is the local file for eventType's clip.
+func foleyClipPath(eventType string) string {
+	return filepath.Join(foleyClipDir, "foley_"+eventType+".mp3")
+}
+
+// musicCache maps a background-music prompt+length hash to its generated clip
+// path so identical prompts reuse one ElevenLabs generation instead of
+// regenerating per page (Q3). Guarded by musicCacheMu.
 var (
 	musicCache   = map[string]string{}
 	musicCacheMu sync.RWMutex
 )
 
+// maxMusicClipSeconds is the longest clip the sound-generation API renders.
+const maxMusicClipSeconds = 22
+
+// backgroundClipSeconds is the length of each generated music clip
+// (BG_MUSIC_CLIP_SECONDS, default and maximum 22). Pages longer than the clip
+// loop it (generateDynamicBackgroundWithSegments), so a shorter clip trades
+// variety for cheaper generations.
+func backgroundClipSeconds() float64 {
+	n := envInt("BG_MUSIC_CLIP_SECONDS", maxMusicClipSeconds)
+	if n < 1 || n > maxMusicClipSeconds {
+		n = maxMusicClipSeconds
+	}
+	return float64(n)
+}
+
+// generateMusicClip renders one background clip; swappable in tests.
+var generateMusicClip = generateSoundEffect
+
 // effectPrompts contains high-quality, detailed prompts for common sound effects
 // Format: descriptive, professional foley-style descriptions for clean output
 var effectPrompts = map[string]string{
@@ -126,18 +150,22 @@ var effectPrompts = map[string]string{
 
 // -------------------- background music pipeline --------------------
 
-// generateSoundEffect fetches one 22s music clip from ElevenLabs (for background music).
-func generateSoundEffect(prompt string, id ...interface{}) (string, error) {
+// soundEffectsAvailable reports whether the ElevenLabs sound API is
+// configured. Without it pages are rendered as plain narration.
+func soundEffectsAvailable() bool { return os.Getenv("XI_API_KEY") != "" }
+
+// generateSoundEffect fetches one music clip of durationSec from ElevenLabs (for background music).
+func generateSoundEffect(ctx context.Context, prompt string, durationSec float64, id ...interface{}) (string, error) {
 	apiKey := os.Getenv("XI_API_KEY")
 	if apiKey == "" {
 		return "", errors.New("XI_API_KEY not set")
 	}
-	payload := SoundEffectRequest{Text: prompt, DurationSeconds: 22, PromptInfluence: 0.5}
+	payload := SoundEffectRequest{Text: prompt, DurationSeconds: durationSec, PromptInfluence: 0.5}
 	body, _ := json.Marshal(payload)
 
 	log.Printf("🎵 [Background Music] Generating with prompt: %s", truncateForLog(prompt, 100))
 
-	req, _ := http.NewRequest("POST", elevenLabsSoundEffectsURL, bytes.NewReader(body))
+	req, _ := http.NewRequestWithContext(ctx, "POST", elevenLabsSoundEffectsURL, bytes.NewReader(body))
 	req.Header.Set("xi-api-key", apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
@@ -159,36 +187,52 @@ func generateSoundEffect(prompt string, id ...interface{}) (string, error) {
 		out = fmt.Sprintf("./audio/sound_effect_%v.mp3", id[0])
 	} else {
 		// B4: never write a shared fixed path — concurrent jobs would clobber
-		// each other. Fall back to a unique temp name.
-		f, err := os.CreateTemp("./audio", "sound_effect_*.mp3")
+		// each other. Fall back to a unique temp name; the tmp_ prefix keeps
+		// it apart from cached clips (sound_effect_<id>.mp3) for the janitor.
+		f, err := os.CreateTemp("./audio", "sound_effect_tmp_*.mp3")
 		if err != nil {
 			return "", fmt.Errorf("temp sound file: %w", err)
 		}
 		out = f.Name()
 		f.Close()
 	}
-	if err := os.WriteFile(out, data, 0644); err != nil {
+	if err := writeFileAtomic(out, bytes.NewReader(data), 0644); err != nil {
 		return "", fmt.Errorf("write sound file: %w", err)
 	}
 	return out, nil
 }
 
+// musicClipKey is the cache key for a clip of prompt at durationSec.
+func musicClipKey(prompt string, durationSec float64) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(fmt.Sprintf("%s\x00%.1f", prompt, durationSec))))[:16]
+}
+
 // getOrGenerateBackgroundMusic returns a background-music clip for prompt,
-// reusing a cached generation when the same prompt was already rendered (Q3).
-// The cache key is a hash of the prompt, which also gives each clip a unique,
-// collision-free filename (B4).
+// reusing a cached generation when the same prompt was already rendered at the
+// current clip length (Q3). The cache key also gives each clip a unique,
+// collision-free filename (B4), so a clip left on disk by an earlier process
+// is picked up too.
 func getOrGenerateBackgroundMusic(prompt string) (string, error) {
-	key := fmt.Sprintf("%x", sha256.Sum256([]byte(prompt)))[:16]
+	dur := backgroundClipSeconds()
+	key := musicClipKey(prompt, dur)
 
 	musicCacheMu.RLock()
-	if p, ok := musicCache[key]; ok && fileExists(p) {
-		musicCacheMu.RUnlock()
+	p, ok := musicCache[key]
+	musicCacheMu.RUnlock()
+	if !ok {
+		p = fmt.Sprintf("./audio/sound_effect_%s.mp3", key)
+	}
+	if fileExists(p) {
 		log.Printf("🔄 [Music Cache] Reusing background music for prompt %s", key)
+		musicCacheMu.Lock()
+		musicCache[key] = p
+		musicCacheMu.Unlock()
 		return p, nil
 	}
-	musicCacheMu.RUnlock()
 
-	p, err := generateSoundEffect(prompt, key)
+	// Detached: the clip is shared across books, so finish it even if the
+	// page that asked for it is abandoned.
+	p, err := generateMusicClip(context.Background(), prompt, dur, key)
 	if err != nil {
 		return "", err
 	}
@@ -199,8 +243,8 @@ func getOrGenerateBackgroundMusic(prompt string) (string, error) {
 }
 
 // generateFoleyEffect generates a SHORT sound effect (1-5 seconds) for Foley overlay
-// Uses higher prompt_influence (0.8) for cleaner, more predictable sounds
-func generateFoleyEffect(prompt string, eventType string, durationSec float64) (string, error) {
+// Uses a high prompt_influence (foleyPromptInfluence) for cleaner, more predictable sounds
+func generateFoleyEffect(ctx context.Context, prompt string, eventType string, durationSec float64) (string, error) {
 	apiKey := os.Getenv("XI_API_KEY")
 	if apiKey == "" {
 		return "", errors.New("XI_API_KEY not set")
@@ -214,17 +258,17 @@ func generateFoleyEffect(prompt string, eventType string, durationSec float64) (
 		durationSec = 5.0
 	}
 
-	// Higher prompt_influence (0.8) for cleaner, more predictable Foley sounds
+	// Higher prompt_influence for cleaner, more predictable Foley sounds
 	payload := SoundEffectRequest{
 		Text:            prompt,
 		DurationSeconds: durationSec,
-		PromptInfluence: 0.8,
+		PromptInfluence: foleyPromptInfluence(),
 	}
 	body, _ := json.Marshal(payload)
 
 	log.Printf("🔊 [Foley Effect] Type: %s, Duration: %.1fs, Prompt: %s", eventType, durationSec, truncateForLog(prompt, 80))
 
-	req, _ := http.NewRequest("POST", elevenLabsSoundEffectsURL, bytes.NewReader(body))
+	req, _ := http.NewRequestWithContext(ctx, "POST", elevenLabsSoundEffectsURL, bytes.NewReader(body))
 	req.Header.Set("xi-api-key", apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
@@ -240,9 +284,9 @@ func generateFoleyEffect(prompt string, eventType string, durationSec float64) (
 	}
 
 	data, _ := io.ReadAll(resp.Body)
-	os.MkdirAll("./audio", 0755)
-	out := fmt.Sprintf("./audio/foley_%s.mp3", eventType)
-	if err := os.WriteFile(out, data, 0644); err != nil {
+	os.MkdirAll(foleyClipDir, 0755)
+	out := foleyClipPath(eventType)
+	if err := writeFileAtomic(out, bytes.NewReader(data), 0644); err != nil {
 		return "", fmt.Errorf("write foley file: %w", err)
 	}
 
@@ -322,7 +366,7 @@ func splitTextProportionally(s string, n int) []string {
 // per 22s music clip. GPT never invents timestamps; its only job is to
 // classify the mood of each window's actual text slice (full page text, not a
 // 200-char preview).
-func generateSegmentInstructions(ttsDur float64, excerpt string) ([]Segment, error) {
+func generateSegmentInstructions(ctx context.Context, ttsDur float64, excerpt string) ([]Segment, error) {
 	apiKey := os.Getenv("OPENAI_API_KEY")
 	if apiKey == "" {
 		return nil, errors.New("OPENAI_API_KEY not set")
@@ -348,7 +392,7 @@ Return ONLY a JSON object: {"moods": ["neutral", "action"]}
 Rules: exactly %d entries, in part order; each mood is one of "suspense", "action", "climax", "sad", "neutral".`, num, parts.String(), num)
 
 	reqBody := map[string]interface{}{
-		"model":           classifyModel(), // audit L6: classification runs on mini
+		"model":           segmentationModel(), // audit L6: classification runs on mini
 		"messages":        []map[string]string{{"role": "system", "content": "Audio segmentation assistant."}, {"role": "user", "content": prompt}},
 		"temperature":     0.1, // classification — deterministic (audit M3)
 		"max_tokens":      600, // audit M2: 300 truncated long pages (>8 segments)
@@ -356,7 +400,7 @@ Rules: exactly %d entries, in part order; each mood is one of "suspense", "actio
 		"response_format": map[string]string{"type": "json_object"}, // audit M1
 	}
 	bb, _ := json.Marshal(reqBody)
-	req, _ := http.NewRequest("POST", openAIChatURL, bytes.NewReader(bb))
+	req, _ := http.NewRequestWithContext(ctx, "POST", openAIChatURL, bytes.NewReader(bb))
 	req.Header.Set("Authorization", "Bearer "+apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
@@ -436,6 +480,8 @@ var moodToVolume = map[string]float64{
 // generateDynamicBackgroundWithSegments creates background music with smooth
 // crossfade transitions. All intermediate files are written under jobDir (a
 // per-job temp dir owned by the caller) so concurrent jobs never collide (B4).
+// bgPath is the shared cached clip (getOrGenerateBackgroundMusic) and is only
+// ever read: each segment loops it rather than generating music of its own.
 func generateDynamicBackgroundWithSegments(ttsDur float64, bgPath string, segs []Segment, jobDir string) (string, error) {
 	if len(segs) == 0 {
 		return "", errors.New("no segments provided")
@@ -464,14 +510,14 @@ func generateDynamicBackgroundWithSegments(ttsDur float64, bgPath string, segs [
 			actualDur += 0.5 // overlap for crossfade
 		}
 
-		cmd := exec.Command("ffmpeg", "-y",
+		cmd := exec.Command(ffmpegBin, "-y",
 			"-stream_loop", "-1", "-i", bgPath,
 			"-t", fmt.Sprintf("%.2f", actualDur),
 			"-af", fmt.Sprintf("volume=%.2f", vol),
 			"-c:a", "libopus", "-b:a", "64k",
 			out,
 		)
-		if o, err := cmd.CombinedOutput(); err != nil {
+		if o, err := runFFmpegCmd(cmd); err != nil {
 			return "", fmt.Errorf("segment %d fail: %v\n%s", i, err, o)
 		}
 		segmentPaths = append(segmentPaths, out)
@@ -485,11 +531,11 @@ func generateDynamicBackgroundWithSegments(ttsDur float64, bgPath string, segs [
 	// If only one segment, just use it directly
 	if len(segmentPaths) == 1 {
 		finalBg := fmt.Sprintf("%s/dynamic_background_final.ogg", jobDir)
-		if o, err := exec.Command("ffmpeg", "-y", "-i", segmentPaths[0],
+		if o, err := runFFmpegCmd(exec.Command(ffmpegBin, "-y", "-i", segmentPaths[0],
 			"-af", fmt.Sprintf("atrim=duration=%.2f,afade=t=in:st=0:d=1,afade=t=out:st=%.2f:d=2", ttsDur, ttsDur-2),
 			"-c:a", "libopus", "-b:a", "64k",
 			finalBg,
-		).CombinedOutput(); err != nil {
+		)); err != nil {
 			return "", fmt.Errorf("single segment trim fail: %v\n%s", err, o)
 		}
 		return finalBg, nil
@@ -502,7 +548,7 @@ func generateDynamicBackgroundWithSegments(ttsDur float64, bgPath string, segs [
 		tempOutput := fmt.Sprintf("%s/dyn_crossfade_%d.ogg", jobDir, i)
 		crossfadeDur := 0.5 // 0.5 second crossfade
 
-		cmd := exec.Command("ffmpeg", "-y",
+		cmd := exec.Command(ffmpegBin, "-y",
 			"-i", currentInput,
 			"-i", segmentPaths[i],
 			"-filter_complex", fmt.Sprintf("[0:a][1:a]acrossfade=d=%.1f:c1=tri:c2=tri[out]", crossfadeDur),
@@ -510,7 +556,7 @@ func generateDynamicBackgroundWithSegments(ttsDur float64, bgPath string, segs [
 			"-c:a", "libopus", "-b:a", "64k",
 			tempOutput,
 		)
-		if out, err := cmd.CombinedOutput(); err != nil {
+		if out, err := runFFmpegCmd(cmd); err != nil {
 			log.Printf("⚠️ [Music] Crossfade %d failed: %v\n%s", i, err, out)
 			// Fallback to simple concat if crossfade fails
 			break
@@ -521,11 +567,11 @@ func generateDynamicBackgroundWithSegments(ttsDur float64, bgPath string, segs [
 
 	// Apply final trim and fade out
 	finalBg := fmt.Sprintf("%s/dynamic_background_final.ogg", jobDir)
-	if o, err := exec.Command("ffmpeg", "-y", "-i", currentInput,
+	if o, err := runFFmpegCmd(exec.Command(ffmpegBin, "-y", "-i", currentInput,
 		"-af", fmt.Sprintf("atrim=duration=%.2f,afade=t=in:st=0:d=1,afade=t=out:st=%.2f:d=2", ttsDur, ttsDur-2),
 		"-c:a", "libopus", "-b:a", "64k",
 		finalBg,
-	).CombinedOutput(); err != nil {
+	)); err != nil {
 		return "", fmt.Errorf("final trim fail: %v\n%s", err, o)
 	}
 
@@ -544,7 +590,7 @@ func computeContentHash(filePath string) (string, error) {
 
 // mergeAudio overlays TTS narration with dynamic background music AND ambient soundscape
 // Audio layers: TTS (1.0) + Background Music (dynamic) + Ambient Soundscape (0.08-0.18)
-func mergeAudio(ttsPath, bgPath string, book Book, pageIndex int, excerpt string, hash string) (string, error) {
+func mergeAudio(ctx context.Context, ttsPath, bgPath string, book Book, pageIndex int, excerpt string, hash string) (string, error) {
 	// B4: per-job temp dir for all intermediate files; removed when we return.
 	jobDir, err := os.MkdirTemp("", "narrafied-mix-*")
 	if err != nil {
@@ -552,7 +598,7 @@ func mergeAudio(ttsPath, bgPath string, book Book, pageIndex int, excerpt string
 	}
 	defer os.RemoveAll(jobDir)
 
-	out, err := exec.Command("ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", ttsPath).Output()
+	out, err := exec.Command(ffprobeBin, "-v", "error", "-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", ttsPath).Output()
 	if err != nil {
 		return "", fmt.Errorf("ffprobe: %w", err)
 	}
@@ -573,7 +619,7 @@ func mergeAudio(ttsPath, bgPath string, book Book, pageIndex int, excerpt string
 		// this page's own text, not the first page of the whole book).
 		var segs []Segment
 		if profile.Fiction {
-			segs, err = generateSegmentInstructions(dur, excerpt)
+			segs, err = generateSegmentInstructions(ctx, dur, excerpt)
 			if err != nil {
 				return "", err
 			}
@@ -586,13 +632,16 @@ func mergeAudio(ttsPath, bgPath string, book Book, pageIndex int, excerpt string
 		}
 	}
 
-	outFile := fmt.Sprintf("./audio/book_%d_page_%d_%s.mp3", book.ID, pageIndex, shortHash(hash))
+	format := audioFormatFor(book)
+	outFile := fmt.Sprintf("./audio/book_%d_page_%d_%s%s", book.ID, pageIndex, shortHash(hash), format.Ext)
 
 	// Try to detect and generate ambient soundscape (fiction only).
 	ambientPath := ""
 	var ambientSetting *AmbientSetting
-	if profile.Fiction {
-		ambientSetting, err = detectAmbientSetting(excerpt, profile.promptHint(book))
+	if !soundEffectsAvailable() {
+		ambientSetting, err = &AmbientSetting{Setting: "neutral", Intensity: 0, Description: "sound API unavailable"}, nil
+	} else if profile.Fiction {
+		ambientSetting, err = detectAmbientSetting(ctx, excerpt, profile.promptHint(book))
 	} else {
 		ambientSetting, err = &AmbientSetting{Setting: "neutral", Intensity: 0.2, Description: "nonfiction"}, nil
 	}
@@ -600,7 +649,7 @@ func mergeAudio(ttsPath, bgPath string, book Book, pageIndex int, excerpt string
 		log.Printf("⚠️ [Mix] Ambient detection failed: %v, continuing without ambient", err)
 	} else if ambientSetting.Setting != "neutral" || ambientSetting.Intensity > 0.3 {
 		// Generate ambient soundscape
-		rawAmbient, err := generateAmbientSoundscape(ambientSetting, book.ID)
+		rawAmbient, err := generateAmbientSoundscape(ctx, ambientSetting, book.ID)
 		if err != nil {
 			log.Printf("⚠️ [Mix] Ambient generation failed: %v", err)
 		} else {
@@ -619,32 +668,33 @@ func mergeAudio(ttsPath, bgPath string, book Book, pageIndex int, excerpt string
 
 	// Q5: explicit weights so amix never averages (which would halve narration
 	// volume). Four cases depending on which layers this page actually has.
-	var cmd *exec.Cmd
+	var args []string
 	switch {
 	case dynBg != "" && ambientPath != "":
 		filterComplex := "[0:a]volume=1.0[tts];[1:a]volume=1.0[mus];[2:a]volume=1.0[amb];[tts][mus][amb]amix=inputs=3:duration=first:normalize=0:weights=1.0 0.3 0.15[aout]"
-		cmd = exec.Command("ffmpeg", "-y", "-i", ttsPath, "-i", dynBg, "-i", ambientPath,
-			"-filter_complex", filterComplex, "-map", "[aout]", "-c:a", "libmp3lame", "-q:a", "2", outFile)
+		args = []string{"-y", "-i", ttsPath, "-i", dynBg, "-i", ambientPath,
+			"-filter_complex", filterComplex, "-map", "[aout]"}
 		log.Printf("🎚️ [Mix] 3-layer: TTS + Music + Ambient")
 	case dynBg != "":
 		filterComplex := "[0:a]volume=1.0[tts];[1:a]volume=1.0[mus];[tts][mus]amix=inputs=2:duration=first:normalize=0:weights=1.0 0.3[aout]"
-		cmd = exec.Command("ffmpeg", "-y", "-i", ttsPath, "-i", dynBg,
-			"-filter_complex", filterComplex, "-map", "[aout]", "-c:a", "libmp3lame", "-q:a", "2", outFile)
+		args = []string{"-y", "-i", ttsPath, "-i", dynBg,
+			"-filter_complex", filterComplex, "-map", "[aout]"}
 		log.Printf("🎚️ [Mix] 2-layer: TTS + Music (event)")
 	case ambientPath != "":
 		// No music (neutral page) but there's an ambient bed — subtle
 		// atmosphere under the narration, no score.
 		filterComplex := "[0:a]volume=1.0[tts];[1:a]volume=1.0[amb];[tts][amb]amix=inputs=2:duration=first:normalize=0:weights=1.0 0.15[aout]"
-		cmd = exec.Command("ffmpeg", "-y", "-i", ttsPath, "-i", ambientPath,
-			"-filter_complex", filterComplex, "-map", "[aout]", "-c:a", "libmp3lame", "-q:a", "2", outFile)
+		args = []string{"-y", "-i", ttsPath, "-i", ambientPath,
+			"-filter_complex", filterComplex, "-map", "[aout]"}
 		log.Printf("🎚️ [Mix] 2-layer: TTS + Ambient (no music)")
 	default:
 		// Pure narration — the common case now on neutral pages.
-		cmd = exec.Command("ffmpeg", "-y", "-i", ttsPath, "-c:a", "libmp3lame", "-q:a", "2", outFile)
+		args = []string{"-y", "-i", ttsPath}
 		log.Printf("🎚️ [Mix] narration only (no music, no ambient)")
 	}
 
-	if o, err := cmd.CombinedOutput(); err != nil {
+	args = append(args, format.CodecArgs...)
+	if o, err := runFFmpegAtomic(outFile, args...); err != nil {
 		return "", fmt.Errorf("ffmpeg merge: %v\n%s", err, o)
 	}
 	log.Printf("✅ [Mix] Merged into %s", outFile)
@@ -653,7 +703,7 @@ func mergeAudio(ttsPath, bgPath string, book Book, pageIndex int, excerpt string
 
 // getTTSDuration returns the length of an audio file in seconds.
 func getTTSDuration(path string) (float64, error) {
-	out, err := exec.Command("ffprobe", "-v", "error",
+	out, err := exec.Command(ffprobeBin, "-v", "error",
 		"-show_entries", "format=duration",
 		"-of", "default=noprint_wrappers=1:nokey=1",
 		path).Output()
@@ -737,7 +787,7 @@ var ambientPrompts = map[string]string{
 // detectAmbientSetting uses GPT to identify the scene setting from the supplied
 // page excerpt (Q1). bookHint carries the book's genre/era (audit H3) so a
 // modern thriller stops matching "medieval tavern".
-func detectAmbientSetting(excerpt, bookHint string) (*AmbientSetting, error) {
+func detectAmbientSetting(ctx context.Context, excerpt, bookHint string) (*AmbientSetting, error) {
 	apiKey := os.Getenv("OPENAI_API_KEY")
 	if apiKey == "" {
 		return nil, errors.New("OPENAI_API_KEY not set")
@@ -779,7 +829,7 @@ OUTPUT FORMAT - Return ONLY a JSON object:
 If no clear setting, return: {"setting": "neutral", "intensity": 0.3, "description": "No specific environment"}`, bookHint, text, strings.Join(settingsList, ", "))
 
 	reqBody := map[string]interface{}{
-		"model": classifyModel(), // audit L6
+		"model": ambientModel(), // audit L6
 		"messages": []map[string]string{
 			{"role": "system", "content": "Scene setting detection assistant for audio production."},
 			{"role": "user", "content": prompt},
@@ -789,7 +839,7 @@ If no clear setting, return: {"setting": "neutral", "intensity": 0.3, "descripti
 		"response_format": map[string]string{"type": "json_object"}, // audit M1
 	}
 	bb, _ := json.Marshal(reqBody)
-	req, _ := http.NewRequest("POST", openAIChatURL, bytes.NewReader(bb))
+	req, _ := http.NewRequestWithContext(ctx, "POST", openAIChatURL, bytes.NewReader(bb))
 	req.Header.Set("Authorization", "Bearer "+apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
@@ -846,7 +896,7 @@ If no clear setting, return: {"setting": "neutral", "intensity": 0.3, "descripti
 }
 
 // generateAmbientSoundscape generates a loopable ambient background
-func generateAmbientSoundscape(setting *AmbientSetting, bookID uint) (string, error) {
+func generateAmbientSoundscape(ctx context.Context, setting *AmbientSetting, bookID uint) (string, error) {
 	apiKey := os.Getenv("XI_API_KEY")
 	if apiKey == "" {
 		return "", errors.New("XI_API_KEY not set")
@@ -875,7 +925,7 @@ func generateAmbientSoundscape(setting *AmbientSetting, bookID uint) (string, er
 
 	log.Printf("🌲 [Ambient] Generating %s soundscape: %s", setting.Setting, truncateForLog(prompt, 80))
 
-	req, _ := http.NewRequest("POST", elevenLabsSoundEffectsURL, bytes.NewReader(body))
+	req, _ := http.NewRequestWithContext(ctx, "POST", elevenLabsSoundEffectsURL, bytes.NewReader(body))
 	req.Header.Set("xi-api-key", apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
@@ -893,7 +943,7 @@ func generateAmbientSoundscape(setting *AmbientSetting, bookID uint) (string, er
 
 	data, _ := io.ReadAll(resp.Body)
 	os.MkdirAll("./audio", 0755)
-	if err := os.WriteFile(local, data, 0644); err != nil {
+	if err := writeFileAtomic(local, bytes.NewReader(data), 0644); err != nil {
 		return "", fmt.Errorf("write ambient file: %w", err)
 	}
 	storeInLibrary(ambientLibKey(setting.Setting), local) // audit L3
@@ -923,13 +973,13 @@ func loopAmbientToLength(ambientPath string, ttsDur float64, intensity float64,
 		cur, curDur := ambientPath, clipDur
 		for i := 0; curDur < ttsDur+1 && i < 60; i++ {
 			next := fmt.Sprintf("%s/ambient_xloop_%d.ogg", jobDir, i)
-			cmd := exec.Command("ffmpeg", "-y",
+			cmd := exec.Command(ffmpegBin, "-y",
 				"-i", cur, "-i", ambientPath,
 				"-filter_complex", fmt.Sprintf("[0:a][1:a]acrossfade=d=%.1f:c1=tri:c2=tri[out]", xfade),
 				"-map", "[out]", "-c:a", "libopus", "-b:a", "48k",
 				next,
 			)
-			if o, err := cmd.CombinedOutput(); err != nil {
+			if o, err := runFFmpegCmd(cmd); err != nil {
 				log.Printf("⚠️ [Ambient] crossfade loop %d failed (%v), falling back to hard loop\n%s", i, err, o)
 				cur = ""
 				break
@@ -958,7 +1008,7 @@ func loopAmbientToLength(ambientPath string, ttsDur float64, intensity float64,
 		"-c:a", "libopus", "-b:a", "48k",
 		outPath,
 	)
-	if o, err := exec.Command("ffmpeg", args...).CombinedOutput(); err != nil {
+	if o, err := runFFmpegCmd(exec.Command(ffmpegBin, args...)); err != nil {
 		return "", fmt.Errorf("loop ambient fail: %v\n%s", err, o)
 	}
 
@@ -1065,11 +1115,92 @@ func resolveEventTimestamps(text string, ttsDur float64, evs []foleyQuoteEvent,
 	return out
 }
 
-// extractSoundEvents asks GPT to identify sound moments in the page text and
-// anchors them to the timeline via their trigger quotes (audit C2). The full
-// page text is analyzed — the old 800-char cap placed effects across audio it
-// had never seen.
-func extractSoundEvents(excerpt string, ttsDur float64, bookHint string, tm []SegmentTiming) (EventMap, error) {
+// maxFoleyPerPage caps the effects overlaid on one page (MAX_FOLEY_PER_PAGE,
+// default 3). The prompt asks for no more, but only capFoleyEvents enforces it.
+func maxFoleyPerPage() int {
+	if n := envInt("MAX_FOLEY_PER_PAGE", 3); n >= 0 {
+		return n
+	}
+	return 3
+}
+
+// capFoleyEvents trims events to at most max placements. It repeatedly drops
+// the placement closest to a neighbour (the later one on a tie), so what
+// survives is the most evenly spread subset rather than whichever the model
+// listed first.
+func capFoleyEvents(events EventMap, max int) EventMap {
+	type placement struct {
+		typ string
+		at  float64
+	}
+	var all []placement
+	for typ, times := range events {
+		for _, t := range times {
+			all = append(all, placement{typ, t})
+		}
+	}
+	if len(all) <= max {
+		return events
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].at != all[j].at {
+			return all[i].at < all[j].at
+		}
+		return all[i].typ < all[j].typ
+	})
+	for len(all) > max {
+		drop, best := 0, math.Inf(1)
+		for i := range all {
+			gap := math.Inf(1)
+			if i > 0 {
+				gap = all[i].at - all[i-1].at
+			}
+			if i < len(all)-1 {
+				gap = math.Min(gap, all[i+1].at-all[i].at)
+			}
+			if gap <= best {
+				drop, best = i, gap
+			}
+		}
+		log.Printf("✂️ [Foley] over budget — dropping %s at %.2fs", all[drop].typ, all[drop].at)
+		all = append(all[:drop], all[drop+1:]...)
+	}
+	out := EventMap{}
+	for _, p := range all {
+		out[p.typ] = append(out[p.typ], p.at)
+	}
+	return out
+}
+
+// extractSoundEvents identifies sound moments in the page text and anchors
+// them to the timeline via their trigger quotes (audit C2). The full page
+// text is analyzed — the old 800-char cap placed effects across audio it had
+// never seen. The proposed events are cached per text (foley_cache.go), so
+// reprocessing a page places the same effects at the same lines.
+func extractSoundEvents(ctx context.Context, excerpt string, ttsDur float64, bookHint string, tm []SegmentTiming) (EventMap, error) {
+	hash := contentHash(excerpt)
+	events, ok := cachedFoleyEvents(hash)
+	if !ok {
+		var err error
+		if events, err = proposeSoundEvents(ctx, excerpt, bookHint); err != nil {
+			return nil, err
+		}
+		storeFoleyEvents(hash, events)
+		if stored, found := cachedFoleyEvents(hash); found {
+			events = stored
+		}
+	}
+
+	// Anchor each event to the timeline via its quote (audit C2, Phase A),
+	// then hold the page to its effect budget.
+	validEvents := capFoleyEvents(resolveEventTimestamps(excerpt, ttsDur, events, tm), maxFoleyPerPage())
+	log.Printf("🎬 [Foley Analysis] %d events anchored (%d proposed, cached=%v)", len(validEvents), len(events), ok)
+	return validEvents, nil
+}
+
+// proposeSoundEvents asks GPT for quote-anchored sound events in excerpt;
+// swappable in tests.
+var proposeSoundEvents = func(ctx context.Context, excerpt string, bookHint string) ([]foleyQuoteEvent, error) {
 	apiKey := os.Getenv("OPENAI_API_KEY")
 	if apiKey == "" {
 		return nil, errors.New("OPENAI_API_KEY not set")
@@ -1103,25 +1234,25 @@ AVAILABLE SOUND EFFECTS (use ONLY these exact names):
 RULES:
 1. Only use sound effect names from the list above — no custom names
 2. "quote" must be a short exact substring copied VERBATIM from the text at the moment the sound occurs
-3. Be conservative — only sounds clearly described or implied; at most 3 per text
+3. Be conservative — only sounds clearly described or implied; at most %d per text
 4. If no clear sound effects occur, return {"events": []}
 
 Return ONLY a JSON object:
-{"events": [{"type": "door_creak", "quote": "the door groaned open"}]}`, bookHint, sn, strings.Join(eventTypesList, ", "))
+{"events": [{"type": "door_creak", "quote": "the door groaned open"}]}`, bookHint, sn, strings.Join(eventTypesList, ", "), maxFoleyPerPage())
 
 	reqBody := map[string]interface{}{
-		"model": classifyModel(), // audit L6
+		"model": foleyModel(), // audit L6
 		"messages": []map[string]string{
 			{"role": "system", "content": "Audio event assistant."},
 			{"role": "user", "content": prompt},
 		},
-		"temperature":     0.1, // extraction — 0.7 invited invented events (audit M3)
+		"temperature":     foleyTemperature(), // extraction — 0.7 invited invented events (audit M3)
 		"max_tokens":      250, // quotes cost more tokens than bare timestamps
 		"n":               1,
 		"response_format": map[string]string{"type": "json_object"}, // audit M1
 	}
 	bb, _ := json.Marshal(reqBody)
-	req, _ := http.NewRequest("POST", openAIChatURL, bytes.NewReader(bb))
+	req, _ := http.NewRequestWithContext(ctx, "POST", openAIChatURL, bytes.NewReader(bb))
 	req.Header.Set("Authorization", "Bearer "+apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
@@ -1167,11 +1298,7 @@ Return ONLY a JSON object:
 		log.Printf("⚠️ [Foley Analysis] Failed to parse JSON: %v", err)
 		return nil, fmt.Errorf("unmarshal events: %w\nraw: %s", err, rawC)
 	}
-
-	// Anchor each event to the timeline via its quote (audit C2, Phase A).
-	validEvents := resolveEventTimestamps(excerpt, ttsDur, wrap.Events, tm)
-	log.Printf("🎬 [Foley Analysis] %d events anchored (%d proposed)", len(validEvents), len(wrap.Events))
-	return validEvents, nil
+	return wrap.Events, nil
 }
 
 // foleyLibKey / ambientLibKey — the R2 locations of the generic clip library
@@ -1186,7 +1313,7 @@ func ambientLibKey(setting string) string { return "library/ambient/" + setting
 // library-cached clips. Fail-open: any error returns the input mix unchanged.
 // Shared by the on-demand path (processSoundEffectsAndMerge) and the batch
 // path (transcribePage).
-func applyFoleyOverlay(mixedPath, ttsPath string, book Book, chunk BookChunk) string {
+func applyFoleyOverlay(ctx context.Context, mixedPath, ttsPath string, book Book, chunk BookChunk) string {
 	pageIndex := chunk.Index
 	profile := getOrCreateAudioProfile(book)
 	if !profile.Fiction {
@@ -1204,14 +1331,16 @@ func applyFoleyOverlay(mixedPath, ttsPath string, book Book, chunk BookChunk) st
 	// Audit 2B: per-segment timing map (persisted at TTS time) makes quote
 	// anchors respect real speaking rates; nil → proportional fallback.
 	tm := loadTimingMap(book.ID, pageIndex)
-	events, err := extractSoundEvents(content, ttsDur, profile.promptHint(book), tm)
+	events, err := extractSoundEvents(ctx, content, ttsDur, profile.promptHint(book), tm)
 	if err != nil {
 		log.Printf("⚠️ [Foley] extract failed for book %d page %d: %v", book.ID, pageIndex, err)
+		recordProcessingEvent(book.ID, pageIndex, stageFoley, eventFailed, err.Error())
 		return mixedPath
 	}
 	fxPath, err := overlaySoundEvents(mixedPath, events, book, pageIndex)
 	if err != nil {
 		log.Printf("⚠️ overlaySoundEvents failed for index %d: %v", pageIndex, err)
+		recordProcessingEvent(book.ID, pageIndex, stageFoley, eventFailed, err.Error())
 		return mixedPath
 	}
 	log.Printf("✅ Sound effects overlayed: %s", fxPath)
@@ -1226,7 +1355,7 @@ func fetchFromLibrary(key, localPath string) bool {
 	if ok, err := store.Exists(context.Background(), key); err != nil || !ok {
 		return false
 	}
-	os.MkdirAll("./audio", 0o755)
+	os.MkdirAll(filepath.Dir(localPath), 0o755)
 	if err := store.GetToFile(context.Background(), key, localPath); err != nil {
 		log.Printf("⚠️ [Library] fetch %s failed: %v", key, err)
 		return false
@@ -1260,7 +1389,7 @@ func getOrGenerateEffect(eventType string) (string, error) {
 		return p, nil
 	}
 
-	local := fmt.Sprintf("./audio/foley_%s.mp3", eventType)
+	local := foleyClipPath(eventType)
 	if fileExists(local) || fetchFromLibrary(foleyLibKey(eventType), local) {
 		effectCacheMu.Lock()
 		effectCache[eventType] = local
@@ -1295,7 +1424,9 @@ func getOrGenerateEffect(eventType string) (string, error) {
 	}
 
 	// Use the new Foley-specific generator (short duration, high prompt influence)
-	path, err := generateFoleyEffect(prompt, eventType, duration)
+	// Detached for the same reason as background music: library clips are
+	// shared, and a half-rendered one helps nobody.
+	path, err := generateFoleyEffect(context.Background(), prompt, eventType, duration)
 	if err != nil {
 		return "", err
 	}
@@ -1326,7 +1457,16 @@ func claimMerge(bookID uint, index int) bool {
 	return ok
 }
 
-func processSoundEffectsAndMerge(book Book, hash string, pageIndexes []int) {
+// releaseMerge drops a page's merge claim early, once a caller that must not
+// wait out the claim (applyPageEffectsHandler) is done with the page.
+func releaseMerge(bookID uint, index int) {
+	if rdb == nil {
+		return
+	}
+	rdb.Del(context.Background(), fmt.Sprintf("merge:lock:%d:%d", bookID, index))
+}
+
+func processSoundEffectsAndMerge(ctx context.Context, book Book, hash string, pageIndexes []int) {
 	if book.ContentHash == "" && hash != "" {
 		book.ContentHash = hash
 		db.Model(&Book{}).Where("id = ?", book.ID).Update("content_hash", hash)
@@ -1360,73 +1500,212 @@ func processSoundEffectsAndMerge(book Book, hash string, pageIndexes []int) {
 			log.Printf("🚫 Could not localize TTS audio for chunk index %d: %v", idx, lerr)
 			continue
 		}
-
-		// Audit H2: pick a cue from the book's score palette (one musical
-		// identity per book); falls back to the legacy per-page prompt path
-		// when the palette can't be created.
-		bg, err := backgroundMusicForPage(book, chunk.Content)
+		mixedPath, skipped, err := mixPageEffects(ctx, ttsLocal, book, chunk, hash)
+		cleanupTTS() // TTS input no longer needed
 		if err != nil {
-			log.Printf("music err for chunk index %d: %v", idx, err)
-			cleanupTTS()
+			log.Printf("❌ book %d page %d: %v", book.ID, idx, err)
+			// A corrupt mix fails the page (audio_verify.go) rather than
+			// leaving it completed with nothing playable.
+			if errors.Is(err, errCorruptAudio) {
+				releaseOrFail(ctx, chunk.ID, err)
+			}
 			continue
 		}
+		publishPageAudio(ctx, book, chunk, mixedPath, skipped)
+		// Temp files are cleaned up per-job inside mergeAudio (B4).
+	}
+}
 
-		log.Printf("🎶 Background music ready: %s", bg)
-
-		// Mix audio (Q1: pass the page text for mood/ambient analysis).
-		mixedPath, err := mergeAudio(ttsLocal, bg, book, idx, chunk.Content, hash)
-		if err != nil {
-			log.Printf("mergeAudio err for page index %d: %v", idx, err)
-			cleanupTTS()
-			continue
+// mixPageEffects mixes a page's TTS audio at ttsLocal with its background
+// music and Foley, and verifies the result. Swappable in tests.
+// Effects are optional: when the sound API is unavailable the page is
+// encoded as plain narration, the book is flagged effects_skipped and
+// skipped is true — such audio must not be shared (page_dedup.go), or every
+// later render of the same text would reuse it without effects.
+var mixPageEffects = func(ctx context.Context, ttsLocal string, book Book, chunk BookChunk, hash string) (mixedPath string, skipped bool, err error) {
+	// Audit H2: pick a cue from the book's score palette (one musical
+	// identity per book); falls back to the legacy per-page prompt path
+	// when the palette can't be created.
+	bg, reason := "", "XI_API_KEY not set"
+	skipped = !soundEffectsAvailable()
+	if !skipped {
+		if bg, err = backgroundMusicForPage(book, chunk.Content); err != nil {
+			log.Printf("⚠️ music unavailable for book %d page %d: %v", book.ID, chunk.Index, err)
+			skipped, reason = true, err.Error()
+		} else {
+			log.Printf("🎶 Background music ready: %s", bg)
 		}
+	}
+	if skipped {
+		log.Printf("🔇 book %d page %d: sound API unavailable — plain narration", book.ID, chunk.Index)
+		recordProcessingEvent(book.ID, chunk.Index, stageMusic, eventSkipped, "sound effects unavailable: "+reason)
+		if err := db.Model(&Book{}).Where("id = ?", book.ID).Update("effects_skipped", true).Error; err != nil {
+			log.Printf("⚠️ could not flag book %d effects_skipped: %v", book.ID, err)
+		}
+	}
 
-		// Extract & overlay sound effects (Q1: this page's text). Shared
-		// helper — the batch path (transcribePage) runs the same pass since
-		// the Foley-on-batch decision (July 2026).
-		mixedPath = applyFoleyOverlay(mixedPath, ttsLocal, book, chunk)
-		cleanupTTS() // TTS input no longer needed
+	// Mix audio (Q1: pass the page text for mood/ambient analysis).
+	mixedPath, err = mergeAudio(ctx, ttsLocal, bg, book, chunk.Index, chunk.Content, hash)
+	if err != nil {
+		recordProcessingEvent(book.ID, chunk.Index, stageMerge, eventFailed, err.Error())
+		return "", skipped, fmt.Errorf("mergeAudio: %w", err)
+	}
 
-		// Upload the finished page audio to a content-addressed SHARED key so
-		// the next book with identical text+engine reuses it (page_dedup.go),
-		// then register it. Matches the batch path (transcribePage).
-		pageHash := contentHash(chunk.Content)
-		engine := dedupEngineKey(book)
-		key := sharedAudioKey(engine, pageHash, filepath.Ext(mixedPath))
-		if _, uerr := uploadArtifact(context.Background(), mixedPath, key); uerr != nil {
-			log.Printf("❌ R2 upload failed for book_id=%d page=%d: %v", book.ID, idx, uerr)
-			continue
+	// Extract & overlay sound effects (Q1: this page's text). Shared
+	// helper — the batch path (transcribePage) runs the same pass since
+	// the Foley-on-batch decision (July 2026).
+	if !skipped {
+		mixedPath = applyFoleyOverlay(ctx, mixedPath, ttsLocal, book, chunk)
+	}
+	if err := verifyAudio(mixedPath); err != nil {
+		recordProcessingEvent(book.ID, chunk.Index, stageMerge, eventFailed, err.Error())
+		return "", skipped, fmt.Errorf("mixed audio: %w", err)
+	}
+	return mixedPath, skipped, nil
+}
+
+// publishPageAudio uploads a page's finished audio and points the chunk's
+// final_audio_path at it, returning the stored key.
+func publishPageAudio(ctx context.Context, book Book, chunk BookChunk, mixedPath string, effectsSkipped bool) (string, error) {
+	idx := chunk.Index
+	// Upload the finished page audio — to the shared, registered key unless
+	// its effects were skipped (storePageAudio). Matches the batch path
+	// (transcribePage).
+	key, err := storePageAudio(ctx, book, chunk, mixedPath, effectsSkipped)
+	if err != nil {
+		log.Printf("❌ R2 upload failed for book_id=%d page=%d: %v", book.ID, idx, err)
+		return "", err
+	}
+	if err := setPageFinalAudio(book, idx, key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// storePageAudio uploads a freshly rendered page and returns its key. Full
+// mixes go to the shared content-addressed key and are registered for reuse.
+// A mix whose effects were skipped stays with this book (audio/{book_id}/)
+// and isn't registered, so later renders of the text get effects again.
+func storePageAudio(ctx context.Context, book Book, chunk BookChunk, mixedPath string, effectsSkipped bool) (string, error) {
+	pageHash := contentHash(chunk.Content)
+	if effectsSkipped {
+		key := audioPageKey(book.ID, chunk.Index, pageHash, filepath.Ext(mixedPath))
+		_, err := uploadArtifact(context.Background(), mixedPath, key)
+		return key, err
+	}
+	engine := jobDedupEngineKey(ctx, book)
+	key := sharedAudioKey(engine, pageHash, filepath.Ext(mixedPath))
+	if _, err := uploadArtifact(context.Background(), mixedPath, key); err != nil {
+		return "", err
+	}
+	registerRenderedPage(pageHash, engine, key, loadVoiceMapJSON(book.ID), loadTranscriptJSON(chunk.ID))
+	return key, nil
+}
+
+// setPageFinalAudio points a page's final_audio_path at key and queues its
+// HLS packaging.
+func setPageFinalAudio(book Book, idx int, key string) error {
+	if err := db.Model(&BookChunk{}).
+		Where("book_id = ? AND \"index\" = ?", book.ID, idx).
+		Updates(map[string]interface{}{
+			// Clearing hls_path lets the follow-on packager re-package —
+			// its already-packaged guard would otherwise keep serving the
+			// old playlist after a re-render.
+			"final_audio_path": key,
+			"hls_path":         "",
+		}).Error; err != nil {
+		log.Printf("❌ Failed to update final_audio_path for book_id=%d page=%d: %v", book.ID, idx, err)
+		return err
+	}
+	log.Printf("✅ Updated final_audio_path for book_id=%d page=%d → %s", book.ID, idx, key)
+	recordProcessingEvent(book.ID, idx, stageMerge, eventCompleted, "")
+	// Follow-on: package this page as HLS (non-blocking) so the legacy
+	// play path (/user/chunks/tts → here) gets HLS too, matching the
+	// asynq batch path (transcribePage). The worker consumes the task.
+	if err := enqueueHLSPackage(book.ID, idx); err != nil {
+		log.Printf("⚠️ failed to enqueue HLS for book %d page %d: %v", book.ID, idx, err)
+	}
+	return nil
+}
+
+// foleyMinGap is the least time between two placements of one effect
+// (FOLEY_MIN_GAP_SECONDS, default 1.5). overlaySoundEvents raises it to the
+// clip's own length so an effect never overlaps itself.
+func foleyMinGap() float64 {
+	if v := os.Getenv("FOLEY_MIN_GAP_SECONDS"); v != "" {
+		if g, err := strconv.ParseFloat(v, 64); err == nil && g >= 0 {
+			return g
 		}
-		registerRenderedPage(pageHash, engine, key, loadVoiceMapJSON(book.ID))
-		if err := db.Model(&BookChunk{}).
-			Where("book_id = ? AND \"index\" = ?", book.ID, idx).
-			Updates(map[string]interface{}{
-				// Clearing hls_path lets the follow-on packager re-package —
-				// its already-packaged guard would otherwise keep serving the
-				// old playlist after a re-render.
-				"final_audio_path": key,
-				"hls_path":         "",
-			}).Error; err != nil {
-			log.Printf("❌ Failed to update final_audio_path for book_id=%d page=%d: %v", book.ID, idx, err)
-		} else {
-			log.Printf("✅ Updated final_audio_path for book_id=%d page=%d → %s", book.ID, idx, key)
-			// Follow-on: package this page as HLS (non-blocking) so the legacy
-			// play path (/user/chunks/tts → here) gets HLS too, matching the
-			// asynq batch path (transcribePage). The worker consumes the task.
-			if err := enqueueHLSPackage(book.ID, idx); err != nil {
-				log.Printf("⚠️ failed to enqueue HLS for book %d page %d: %v", book.ID, idx, err)
+	}
+	return 1.5
+}
+
+// spaceFoleyTimes returns times sorted with every pair at least minGap apart.
+// A placement too close to the previous one is pushed back to exactly minGap
+// after it, or dropped if that would land past limit (limit <= 0: no bound).
+func spaceFoleyTimes(times []float64, minGap, limit float64) []float64 {
+	sorted := append([]float64(nil), times...)
+	sort.Float64s(sorted)
+	out := make([]float64, 0, len(sorted))
+	for _, t := range sorted {
+		if n := len(out); n > 0 && t-out[n-1] < minGap {
+			shifted := out[n-1] + minGap
+			if limit > 0 && shifted > limit {
+				log.Printf("✂️ [Foley] dropping placement at %.2fs — no room %.2fs after the previous one", t, minGap)
+				continue
 			}
+			t = shifted
 		}
-		// Temp files are cleaned up per-job inside mergeAudio (B4).
+		out = append(out, t)
+	}
+	return out
+}
+
+// unitEnv reads a 0–1 setting, falling back to def when unset or out of range.
+func unitEnv(name string, def float64) float64 {
+	if v := os.Getenv(name); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && isUnitInterval(f) {
+			return f
+		}
+		log.Printf("⚠️ %s=%q is not between 0 and 1; using %.2f", name, v, def)
+	}
+	return def
+}
+
+func isUnitInterval(f float64) bool { return f >= 0 && f <= 1 }
+
+// foleyPromptInfluence is the ElevenLabs prompt_influence for Foley clips
+// (FOLEY_PROMPT_INFLUENCE, 0–1, default 0.8). Clips are shared across books
+// through the library, so this is service-wide; evict clips
+// (DELETE /admin/foley/cache/:event_type) to re-render them after a change.
+func foleyPromptInfluence() float64 { return unitEnv("FOLEY_PROMPT_INFLUENCE", 0.8) }
+
+// foleyVolume is the level Foley effects are mixed at (FOLEY_VOLUME, 0–1,
+// default 0.30).
+func foleyVolume() float64 { return unitEnv("FOLEY_VOLUME", 0.30) }
+
+// foleyVolumeFor resolves a book's Foley level: its per-book override when
+// set and valid, otherwise the service default.
+func foleyVolumeFor(book Book) float64 {
+	if book.FoleyVolume != nil && isUnitInterval(*book.FoleyVolume) {
+		return *book.FoleyVolume
 	}
+	return foleyVolume()
+}
+
+// foleyOverlayFilter is the filter for one placement of input inputIdx:
+// fade, delay to delayMs, then scale to volume.
+func foleyOverlayFilter(inputIdx, j int, fade string, delayMs int, volume float64) string {
+	return fmt.Sprintf("[%d:a]%s,adelay=%d|%d,volume=%.2f[e%d_%d]", inputIdx, fade, delayMs, delayMs, volume, inputIdx, j)
 }
 
 // overlaySoundEvents adds Foley sound effects with proper volume balance and fade in/out
-// Volume reduced from 0.45 to 0.30, with 0.05s fade in and 0.1s fade out for smoother blending
+// Volume comes from foleyVolumeFor, with 0.05s fade in and 0.1s fade out for smoother blending
 func overlaySoundEvents(baseMix string, events EventMap, book Book, pageIndex int) (string, error) {
 	safeTitle := strings.ReplaceAll(strings.ToLower(book.Title), " ", "_")
 	hashSuffix := shortHash(book.ContentHash)
-	outFile := fmt.Sprintf("./audio/final_with_fx_%s_%d_page_%d_%s.ogg", safeTitle, book.ID, pageIndex, hashSuffix)
+	format := audioFormatFor(book)
+	outFile := fmt.Sprintf("./audio/final_with_fx_%s_%d_page_%d_%s%s", safeTitle, book.ID, pageIndex, hashSuffix, format.Ext)
 
 	// If no events, just return the base mix
 	if len(events) == 0 {
@@ -1438,6 +1717,9 @@ func overlaySoundEvents(baseMix string, events EventMap, book Book, pageIndex in
 	var filters, labels []string
 	inputIdx := 1
 	totalEffects := 0
+	// Spacing may push an effect later; never past the end of the page.
+	pageDur, _ := getTTSDuration(baseMix)
+	volume := foleyVolumeFor(book)
 
 	for evt, times := range events {
 		clip, err := getOrGenerateEffect(evt)
@@ -1445,26 +1727,25 @@ func overlaySoundEvents(baseMix string, events EventMap, book Book, pageIndex in
 			log.Printf("⚠️ [Foley] %s clip error: %v", evt, err)
 			continue
 		}
-		args = append(args, "-i", clip)
 		// Q2: the fade-out must start near the END of the clip, not at t=0.
 		// Compute the clip's real duration; if too short to fade, skip fade-out.
 		clipDur, _ := getTTSDuration(clip)
+		times = spaceFoleyTimes(times, math.Max(foleyMinGap(), clipDur), pageDur-0.5)
+		if len(times) == 0 {
+			continue
+		}
+		args = append(args, "-i", clip)
 		fade := "afade=t=in:d=0.05"
 		if clipDur > 0.15 {
 			fade += fmt.Sprintf(",afade=t=out:st=%.2f:d=0.1", clipDur-0.1)
 		}
 		for j, t := range times {
-			delayMs := int(t * 1000)
-			inLbl := fmt.Sprintf("[%d:a]", inputIdx)
-			outLbl := fmt.Sprintf("[e%d_%d]", inputIdx, j)
-			// Reduced volume (0.30), 0.05s fade-in, 0.1s fade-out at clip end.
-			filters = append(filters, fmt.Sprintf(
-				"%s%s,adelay=%d|%d,volume=0.30%s",
-				inLbl, fade, delayMs, delayMs, outLbl,
-			))
-			labels = append(labels, outLbl)
+			delayMs := int(math.Round(t * 1000))
+			// 0.05s fade-in, 0.1s fade-out at clip end.
+			filters = append(filters, foleyOverlayFilter(inputIdx, j, fade, delayMs, volume))
+			labels = append(labels, fmt.Sprintf("[e%d_%d]", inputIdx, j))
 			totalEffects++
-			log.Printf("🔊 [Foley] Adding %s at %.2fs (volume: 30%%)", evt, t)
+			log.Printf("🔊 [Foley] Adding %s at %.2fs (volume: %.0f%%)", evt, t, volume*100)
 		}
 		inputIdx++
 	}
@@ -1478,11 +1759,12 @@ func overlaySoundEvents(baseMix string, events EventMap, book Book, pageIndex in
 	totalIn := 1 + len(labels)
 	filters = append(filters, fmt.Sprintf("%samix=inputs=%d:duration=first:dropout_transition=0", amixIn, totalIn))
 
-	args = append(args, "-filter_complex", strings.Join(filters, ";"), "-c:a", "libopus", "-b:a", "64k", outFile)
+	args = append(args, "-filter_complex", strings.Join(filters, ";"))
+	args = append(args, format.CodecArgs...)
 
 	log.Printf("🔊 [Foley] Overlaying %d effects onto page %d", totalEffects, pageIndex)
 
-	if o, err := exec.Command("ffmpeg", args...).CombinedOutput(); err != nil {
+	if o, err := runFFmpegAtomic(outFile, args...); err != nil {
 		return "", fmt.Errorf("overlaySoundEvents FFmpeg fail: %v\n%s", err, o)
 	}
 
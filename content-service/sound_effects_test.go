@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestFoleySettings_ValidateRange(t *testing.T) {
+	for env, want := range map[string]float64{"": 0.8, "0.4": 0.4, "0": 0, "1": 1, "1.5": 0.8, "-0.1": 0.8, "high": 0.8} {
+		t.Setenv("FOLEY_PROMPT_INFLUENCE", env)
+		if got := foleyPromptInfluence(); got != want {
+			t.Errorf("FOLEY_PROMPT_INFLUENCE=%q: %v, want %v", env, got, want)
+		}
+	}
+	for env, want := range map[string]float64{"": 0.30, "0.45": 0.45, "2": 0.30} {
+		t.Setenv("FOLEY_VOLUME", env)
+		if got := foleyVolume(); got != want {
+			t.Errorf("FOLEY_VOLUME=%q: %v, want %v", env, got, want)
+		}
+	}
+}
+
+func TestGenerateFoleyEffect_SendsConfiguredPromptInfluence(t *testing.T) {
+	var got SoundEffectRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte("ID3"))
+	}))
+	defer srv.Close()
+	origURL, origDir := elevenLabsSoundEffectsURL, foleyClipDir
+	elevenLabsSoundEffectsURL, foleyClipDir = srv.URL, t.TempDir()
+	t.Cleanup(func() { elevenLabsSoundEffectsURL, foleyClipDir = origURL, origDir })
+	t.Setenv("XI_API_KEY", "test")
+	t.Setenv("FOLEY_PROMPT_INFLUENCE", "0.35")
+
+	if _, err := generateFoleyEffect(context.Background(), "a door creaks", "door_creak", 1); err != nil {
+		t.Fatal(err)
+	}
+	if got.PromptInfluence != 0.35 || got.Text != "a door creaks" {
+		t.Fatalf("payload = %+v, want prompt_influence 0.35", got)
+	}
+}
+
+func TestFoleyOverlayFilter_UsesBookVolume(t *testing.T) {
+	t.Setenv("FOLEY_VOLUME", "0.45")
+	quiet, loud, broken := 0.1, 0.9, 3.0
+	for _, tc := range []struct {
+		book Book
+		want string
+	}{
+		{Book{}, "volume=0.45"},                    // service default
+		{Book{FoleyVolume: &quiet}, "volume=0.10"}, // per-book override
+		{Book{FoleyVolume: &loud}, "volume=0.90"},
+		{Book{FoleyVolume: &broken}, "volume=0.45"}, // out of range: ignored
+	} {
+		filter := foleyOverlayFilter(2, 0, "afade=t=in:d=0.05", 1500, foleyVolumeFor(tc.book))
+		if !strings.Contains(filter, ","+tc.want+"[e2_0]") || !strings.HasPrefix(filter, "[2:a]afade=t=in:d=0.05,adelay=1500|1500,") {
+			t.Errorf("filter = %q, want %s", filter, tc.want)
+		}
+	}
+}
+
+func TestApplyBookUpdate_FoleyVolume(t *testing.T) {
+	book := Book{Title: "T", Category: "Fiction"}
+	v := 0.6
+	updates, _, err := applyBookUpdate(&book, BookUpdateRequest{FoleyVolume: &v})
+	if err != nil || updates["foley_volume"] != 0.6 || updates["needs_reprocess"] != true {
+		t.Fatalf("updates = %v, %v", updates, err)
+	}
+	if updates, _, _ = applyBookUpdate(&book, BookUpdateRequest{FoleyVolume: &v}); len(updates) != 0 {
+		t.Fatalf("unchanged volume produced %v", updates)
+	}
+	bad := 1.2
+	if _, _, err := applyBookUpdate(&book, BookUpdateRequest{FoleyVolume: &bad}); err == nil || *book.FoleyVolume != 0.6 {
+		t.Fatalf("foley_volume 1.2 accepted (err %v)", err)
+	}
+}
+
+// fakeFFmpeg installs stand-in ffmpeg/ffprobe binaries: ffmpeg copies its
+// first input to its output and ffprobe reports a 12.5s audio file.
+func fakeFFmpeg(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	scripts := map[string]string{
+		"ffmpeg": `#!/bin/sh
+in=""; prev=""
+for a in "$@"; do
+	if [ "$prev" = "-i" ] && [ -z "$in" ]; then in="$a"; fi
+	prev="$a"
+done
+cp "$in" "$prev"
+`,
+		"ffprobe": `#!/bin/sh
+case "$*" in *codec_type*) echo audio ;; esac
+echo 12.5
+`,
+	}
+	for name, body := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	origFF, origProbe := ffmpegBin, ffprobeBin
+	ffmpegBin, ffprobeBin = filepath.Join(dir, "ffmpeg"), filepath.Join(dir, "ffprobe")
+	t.Cleanup(func() { ffmpegBin, ffprobeBin = origFF, origProbe })
+}
+
+// Without an ElevenLabs key the page is encoded as plain narration — no
+// music, ambient or Foley — and the book is flagged effects_skipped.
+func TestMixPageEffects_NoSoundAPIServesPlainNarration(t *testing.T) {
+	stmts := dryRunDB(t)
+	fakeFFmpeg(t)
+	t.Chdir(t.TempDir())
+	t.Setenv("XI_API_KEY", "")
+	narration := filepath.Join(t.TempDir(), "tts.mp3")
+	os.WriteFile(narration, []byte("ID3 narration"), 0644)
+
+	book := Book{ID: 3, AudioProfile: `{"fiction":true,"genre":"mystery","era":"modern"}`}
+	out, skipped, err := mixPageEffects(context.Background(), narration, book, BookChunk{BookID: 3, Index: 0, Content: "It was a dark night."}, "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !skipped {
+		t.Error("mix without the sound API not reported as skipped")
+	}
+	if got, _ := os.ReadFile(out); string(got) != "ID3 narration" {
+		t.Fatalf("page audio = %q, want the plain narration", got)
+	}
+	if sql := strings.Join(*stmts, "\n"); !strings.Contains(sql, `"effects_skipped"=true`) {
+		t.Fatalf("book not flagged effects_skipped:\n%s", sql)
+	}
+}
+
+func TestTranscribeBatch_CompletesWithoutSoundAPI(t *testing.T) {
+	withTestDB(t)
+	fakeFFmpeg(t)
+	t.Chdir(t.TempDir())
+	t.Setenv("XI_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	stubChunkSynthesis(t, []byte("ID3 narration"))
+	origRDB, origStore := rdb, store
+	rdb = redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1}) // quota fails open
+	ms := memStore{}
+	store = ms
+	t.Cleanup(func() { rdb, store = origRDB, origStore })
+
+	book := Book{Title: "Quiet", Category: "Fiction", UserID: 1, Status: "transcribing"}
+	db.Create(&book)
+	for i := 0; i < 2; i++ {
+		db.Create(&BookChunk{BookID: book.ID, Index: i, Content: fmt.Sprintf("Page %d of a quiet book.", i), TTSStatus: "pending"})
+	}
+
+	payload, _ := json.Marshal(TaskTranscribeBatch{BookID: book.ID, StartPage: 0, EndPage: batchSizePages - 1, UserID: 1, AccountType: "free"})
+	if err := handleTranscribeBatch(context.Background(), asynq.NewTask(TypeTranscribeBatch, payload)); err != nil {
+		t.Fatal(err)
+	}
+
+	db.First(&book, book.ID)
+	if book.Status != "completed" || !book.EffectsSkipped {
+		t.Fatalf("book status %q effects_skipped %v, want completed + skipped", book.Status, book.EffectsSkipped)
+	}
+	var chunks []BookChunk
+	db.Where("book_id = ?", book.ID).Find(&chunks)
+	for _, ch := range chunks {
+		if ch.TTSStatus != "completed" || ms[ch.FinalAudioPath] != int64(len("ID3 narration")) {
+			t.Errorf("page %d: %s, final %q (%d bytes)", ch.Index, ch.TTSStatus, ch.FinalAudioPath, ms[ch.FinalAudioPath])
+		}
+		if !strings.HasPrefix(ch.FinalAudioPath, fmt.Sprintf("audio/%d/", book.ID)) {
+			t.Errorf("page %d: effect-less audio stored at shared key %q", ch.Index, ch.FinalAudioPath)
+		}
+	}
+	// Nothing effect-less enters the shared cache for other books to reuse.
+	var shared int64
+	db.Model(&RenderedPage{}).Count(&shared)
+	if shared != 0 {
+		t.Errorf("%d effect-less page(s) registered for reuse", shared)
+	}
+}
+
+func TestStorePageAudio_SharesOnlyFullMixes(t *testing.T) {
+	stmts := dryRunDB(t)
+	origStore := store
+	ms := memStore{}
+	store = ms
+	t.Cleanup(func() { store = origStore })
+	mixed := filepath.Join(t.TempDir(), "mixed.mp3")
+	os.WriteFile(mixed, []byte("ID3 mix"), 0644)
+	book := Book{ID: 4}
+	chunk := BookChunk{BookID: 4, Index: 1, Content: "A page of text."}
+
+	key, err := storePageAudio(context.Background(), book, chunk, mixed, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, "audio/4/") || ms[key] == 0 {
+		t.Errorf("effect-less page stored at %q", key)
+	}
+	if sql := strings.Join(*stmts, "\n"); strings.Contains(sql, "rendered_pages") {
+		t.Errorf("effect-less page registered for reuse:\n%s", sql)
+	}
+
+	os.WriteFile(mixed, []byte("ID3 mix"), 0644) // the upload consumed it
+	key, err = storePageAudio(context.Background(), book, chunk, mixed, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasPrefix(key, "audio/4/") {
+		t.Errorf("full mix stored per-book at %q, want the shared key", key)
+	}
+	if sql := strings.Join(*stmts, "\n"); !strings.Contains(sql, "rendered_pages") {
+		t.Errorf("full mix not registered for reuse:\n%s", sql)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatsSummaryResponse is the listening dashboard for GET /user/stats/summary.
+type StatsSummaryResponse struct {
+	TotalBooks        int     `json:"total_books"`        // books in the user's library
+	BooksStarted      int     `json:"books_started"`      // books with any progress
+	BooksCompleted    int     `json:"books_completed"`    // progress at or past bookCompletedPercent
+	TotalListenTime   float64 `json:"total_listen_time"`  // in seconds
+	AverageCompletion float64 `json:"average_completion"` // mean completion_percent over started books
+	CurrentStreak     int     `json:"current_streak_days"`
+	LastActiveDay     string  `json:"last_active_day,omitempty"` // YYYY-MM-DD in the requested zone
+}
+
+// bookCompletedPercent is the completion at which a book counts as finished
+// (end credits and trailing silence mean listeners rarely hit a hard 100).
+const bookCompletedPercent = 95
+
+// listeningStreak counts consecutive active days ending today or yesterday.
+// A day is active when some book was last played on it, in loc. The streak
+// survives until a full calendar day passes with no listening, so it isn't
+// reset at midnight before the user has had a chance to listen today.
+//
+// Only each book's most recent play is stored, so a day whose only listening
+// was later superseded by another play of the same book doesn't count.
+func listeningStreak(playedAt []time.Time, now time.Time, loc *time.Location) int {
+	active := make(map[string]bool, len(playedAt))
+	for _, t := range playedAt {
+		active[t.In(loc).Format("2006-01-02")] = true
+	}
+
+	day := now.In(loc)
+	if !active[day.Format("2006-01-02")] {
+		day = day.AddDate(0, 0, -1)
+	}
+	streak := 0
+	for active[day.Format("2006-01-02")] {
+		streak++
+		day = day.AddDate(0, 0, -1)
+	}
+	return streak
+}
+
+// summarizeListening aggregates a user's progress rows into the dashboard.
+func summarizeListening(progress []PlaybackProgress, totalBooks int, now time.Time, loc *time.Location) StatsSummaryResponse {
+	s := StatsSummaryResponse{TotalBooks: totalBooks, BooksStarted: len(progress)}
+	playedAt := make([]time.Time, 0, len(progress))
+	var completionSum float64
+	var last time.Time
+	for _, p := range progress {
+		s.TotalListenTime += p.TotalListenTime
+		completionSum += p.CompletionPercent
+		if p.CompletionPercent >= bookCompletedPercent {
+			s.BooksCompleted++
+		}
+		playedAt = append(playedAt, p.LastPlayedAt)
+		if p.LastPlayedAt.After(last) {
+			last = p.LastPlayedAt
+		}
+	}
+	if len(progress) > 0 {
+		s.AverageCompletion = completionSum / float64(len(progress))
+		s.LastActiveDay = last.In(loc).Format("2006-01-02")
+	}
+	s.CurrentStreak = listeningStreak(playedAt, now, loc)
+	return s
+}
+
+// GetStatsSummaryHandler returns the user's listening dashboard: library size,
+// total listen time, current streak and average completion. Days are
+// calendar days in the optional ?tz= IANA zone (default UTC).
+// GET /user/stats/summary
+func GetStatsSummaryHandler(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		respondError(c, http.StatusUnauthorized, errCodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	loc := time.UTC
+	if tz := c.Query("tz"); tz != "" {
+		l, err := time.LoadLocation(tz)
+		if err != nil {
+			respondErrorWith(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid tz", gin.H{"details": err.Error()})
+			return
+		}
+		loc = l
+	}
+
+	var totalBooks int64
+	if err := db.Model(&Book{}).Where("user_id = ?", userID).Count(&totalBooks).Error; err != nil {
+		respondInternal(c, "Failed to retrieve stats", err)
+		return
+	}
+	var progressRecords []PlaybackProgress
+	if err := db.Where("user_id = ?", userID).Find(&progressRecords).Error; err != nil {
+		respondInternal(c, "Failed to retrieve stats", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summarizeListening(progressRecords, int(totalBooks), time.Now(), loc))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSummarizeListening_MultiDay(t *testing.T) {
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	day := func(daysAgo int) time.Time { return now.AddDate(0, 0, -daysAgo).Add(-time.Hour) }
+
+	progress := []PlaybackProgress{
+		{BookID: 1, TotalListenTime: 3600, CompletionPercent: 100, LastPlayedAt: day(0)},
+		{BookID: 2, TotalListenTime: 1800, CompletionPercent: 50, LastPlayedAt: day(1)},
+		{BookID: 3, TotalListenTime: 600, CompletionPercent: 20, LastPlayedAt: day(2)},
+		{BookID: 4, TotalListenTime: 300, CompletionPercent: 10, LastPlayedAt: day(4)}, // gap on day 3
+	}
+	got := summarizeListening(progress, 6, now, time.UTC)
+
+	if got.TotalBooks != 6 || got.BooksStarted != 4 || got.BooksCompleted != 1 {
+		t.Errorf("counts = %+v", got)
+	}
+	if got.TotalListenTime != 6300 {
+		t.Errorf("total_listen_time = %v, want 6300", got.TotalListenTime)
+	}
+	if math.Abs(got.AverageCompletion-45) > 1e-9 {
+		t.Errorf("average_completion = %v, want 45", got.AverageCompletion)
+	}
+	if got.CurrentStreak != 3 {
+		t.Errorf("streak = %d, want 3 (today, yesterday, day before; broken by the gap)", got.CurrentStreak)
+	}
+	if got.LastActiveDay != "2026-03-10" {
+		t.Errorf("last_active_day = %q", got.LastActiveDay)
+	}
+}
+
+func TestListeningStreak(t *testing.T) {
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	at := func(daysAgo int) time.Time { return now.AddDate(0, 0, -daysAgo) }
+
+	cases := []struct {
+		name   string
+		played []time.Time
+		want   int
+	}{
+		{"none", nil, 0},
+		{"not yet today, yesterday counts", []time.Time{at(1), at(2)}, 2},
+		{"two books same day count once", []time.Time{at(0), at(0).Add(-time.Hour), at(1)}, 2},
+		{"lapsed two days ago", []time.Time{at(2), at(3)}, 0},
+	}
+	for _, tc := range cases {
+		if got := listeningStreak(tc.played, now, time.UTC); got != tc.want {
+			t.Errorf("%s: streak = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+
+	// Days are the user's calendar days: 23:30 in New York on Mar 9 is
+	// already Mar 10 in UTC.
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("tzdata unavailable")
+	}
+	late := time.Date(2026, 3, 9, 23, 30, 0, 0, ny)
+	noon := time.Date(2026, 3, 10, 12, 0, 0, 0, ny)
+	if got := listeningStreak([]time.Time{late, noon}, noon, ny); got != 2 {
+		t.Errorf("tz streak = %d, want 2", got)
+	}
+	if got := listeningStreak([]time.Time{late, noon}, noon, time.UTC); got != 1 {
+		t.Errorf("utc streak = %d, want 1 (both plays fall on Mar 10 UTC)", got)
+	}
+}
@@ -18,7 +18,7 @@ type StreamByChunkIDsRequest struct {
 func streamAudioByChunkIDsHandler(c *gin.Context) {
 	var req StreamByChunkIDsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		respondErrorWith(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid request body", gin.H{"details": err.Error()})
 		return
 	}
 
@@ -28,17 +28,17 @@ func streamAudioByChunkIDsHandler(c *gin.Context) {
 	// SECURITY (S6): the book must belong to the caller. The chunk query below
 	// is already scoped to book_id, so verifying book ownership closes the IDOR.
 	if _, err := verifyBookOwnership(req.BookID, userID); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		respondError(c, http.StatusNotFound, errCodeBookNotFound, "Book not found")
 		return
 	}
 
 	var chunks []BookChunk
 	if err := db.Where("id IN ? AND book_id = ?", req.ChunkIDs, req.BookID).Find(&chunks).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch chunks", "details": err.Error()})
+		respondInternal(c, "Failed to fetch chunks", err)
 		return
 	}
 	if len(chunks) != len(req.ChunkIDs) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Some chunks not found"})
+		respondError(c, http.StatusNotFound, errCodePageNotFound, "Some chunks not found")
 		return
 	}
 	sort.Slice(chunks, func(i, j int) bool { return chunks[i].Index < chunks[j].Index })
@@ -55,13 +55,13 @@ func streamAudioByChunkIDsHandler(c *gin.Context) {
 		combined.WriteString(chunk.Content)
 	}
 	if len(combined.String()) > 2000 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Combined text exceeds TTS limit (2000 bytes)"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "Combined text exceeds TTS limit (2000 bytes)")
 		return
 	}
 
 	// Enqueue the merge on the worker fleet (durable; replaces TTSQueueJob).
 	if err := enqueueMergeChunks(req.BookID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not queue request", "details": err.Error()})
+		respondInternal(c, "Could not queue request", err)
 		return
 	}
 	c.JSON(http.StatusAccepted, gin.H{"message": "Your request has been queued."})
@@ -18,13 +18,13 @@ func streamChunkGroupAudioHandler(c *gin.Context) {
 	startIdx, err2 := strconv.Atoi(startStr)
 	endIdx, err3 := strconv.Atoi(endStr)
 	if err1 != nil || err2 != nil || err3 != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parameters"})
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid parameters")
 		return
 	}
 
 	audioPath, found := checkIfChunkGroupProcessed(uint(bookID), startIdx, endIdx)
 	if !found {
-		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("No audio found for chunks %d-%d", startIdx, endIdx)})
+		respondError(c, http.StatusNotFound, errCodeAudioNotReady, fmt.Sprintf("No audio found for chunks %d-%d", startIdx, endIdx))
 		return
 	}
 
@@ -3,73 +3,56 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
 )
 
+// proxyBookAudioHandler streams a book's whole-book audio. AVPlayer can't set
+// headers, so authMiddleware also takes the JWT from ?token=; ownership is
+// checked by requireBookOwnership (404 for a missing or another user's book).
+//
+// Books narrated page by page have no whole-book file until they are compiled
+// (book_compile.go). For those it streams the longest merged chunk group that
+// starts at page 1, marked X-Audio-Partial, so playback can begin; with no
+// such group it answers 425 with how many pages are done.
 func proxyBookAudioHandler(c *gin.Context) {
-	bookID := c.Param("book_id")
-	tokenString := c.Query("token")
-
-	if tokenString == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
-		return
-	}
-
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecretKey, nil
-	})
-	if err != nil || !token.Valid {
-		fmt.Println("❌ Invalid or expired token:", err)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+	book := c.MustGet("book").(Book)
+	if book.AudioPath != "" {
+		serveMedia(c, book.AudioPath)
 		return
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		fmt.Println("❌ Failed to extract claims from token")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid token claims"})
+	var group ProcessedChunkGroup
+	err := db.Where("book_id = ? AND start_idx = 0 AND audio_path <> ''", book.ID).
+		Order("end_idx DESC").First(&group).Error
+	if err == nil {
+		c.Header("X-Audio-Partial", "true")
+		c.Header("X-Audio-Pages", strconv.Itoa(group.EndIdx+1))
+		serveMedia(c, group.AudioPath)
 		return
 	}
 
-	userIDFloat, ok := claims["user_id"].(float64)
-	if !ok {
-		fmt.Println("❌ User ID not found in token claims:", claims)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in token"})
+	var total, processed int64
+	if err := db.Model(&BookChunk{}).Where("book_id = ?", book.ID).Count(&total).Error; err != nil {
+		respondInternal(c, "Could not check book progress", err)
 		return
 	}
-	userID := uint(userIDFloat)
-	fmt.Printf("✅ Token user ID: %d\n", userID)
-
-	if bookID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Book ID is required"})
+	if total == 0 {
+		respondError(c, http.StatusNotFound, errCodeAudioNotReady, "Audio file not available for this book")
 		return
 	}
-
-	fmt.Println("🔍 Looking up book with ID:", bookID)
-
-	var book Book
-	if err := db.First(&book, bookID).Error; err != nil {
-		fmt.Println("❌ Book not found:", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found", "details": err.Error()})
+	if err := db.Model(&BookChunk{}).
+		Where("book_id = ? AND tts_status = ? AND final_audio_path <> ''", book.ID, "completed").
+		Count(&processed).Error; err != nil {
+		respondInternal(c, "Could not check book progress", err)
 		return
 	}
-
-	fmt.Printf("📘 Book found: ID=%d, Title=%s, UserID=%d\n", book.ID, book.Title, book.UserID)
-
-	if book.UserID != userID {
-		fmt.Printf("🚫 Unauthorized access attempt. Token UserID=%d, Book Owner=%d\n", userID, book.UserID)
-		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to access this book"})
-		return
-	}
-
-	if book.AudioPath == "" {
-		fmt.Println("❌ Audio path is empty for this book")
-		c.JSON(http.StatusNotFound, gin.H{"error": "Audio file not available for this book"})
-		return
-	}
-
-	fmt.Println("🎧 Serving audio:", book.AudioPath)
-	serveMedia(c, book.AudioPath)
+	respondErrorWith(c, http.StatusTooEarly, errCodeAudioNotReady,
+		fmt.Sprintf("Audio not ready: %d of %d pages processed", processed, total),
+		gin.H{
+			"pages_processed":  processed,
+			"pages_total":      total,
+			"percent_complete": percentComplete(float64(processed), float64(total)),
+		})
 }
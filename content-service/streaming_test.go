@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func proxyAudioRequest(t *testing.T, book Book) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/books/stream/proxy/:book_id", func(c *gin.Context) { c.Set("book", book) }, proxyBookAudioHandler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/books/stream/proxy/%d", book.ID), nil))
+	return w
+}
+
+func TestProxyBookAudio_PartiallyProcessed(t *testing.T) {
+	withTestDB(t)
+	book := Book{Title: "Half done", Category: "Fiction", UserID: 3}
+	db.Create(&book)
+	db.Create(&BookChunk{BookID: book.ID, Index: 0, AudioPath: "a", FinalAudioPath: "audio/p0.mp3", TTSStatus: "completed"})
+	db.Create(&BookChunk{BookID: book.ID, Index: 1, AudioPath: "b", TTSStatus: "processing"})
+	db.Create(&BookChunk{BookID: book.ID, Index: 2, AudioPath: "c", TTSStatus: "pending"})
+
+	w := proxyAudioRequest(t, book)
+	if w.Code != http.StatusTooEarly {
+		t.Fatalf("partially processed book: got %d, want 425: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Code           string `json:"code"`
+		Message        string `json:"message"`
+		PagesProcessed int64  `json:"pages_processed"`
+		PagesTotal     int64  `json:"pages_total"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body.Code != errCodeAudioNotReady || body.PagesProcessed != 1 || body.PagesTotal != 3 ||
+		body.Message != "Audio not ready: 1 of 3 pages processed" {
+		t.Errorf("425 body = %+v", body)
+	}
+
+	// Once the opening pages are merged, they stream as partial audio.
+	merged := filepath.Join(t.TempDir(), "book_chunks_0_1.mp3")
+	os.WriteFile(merged, []byte("ID3"), 0644)
+	saveProcessedChunkGroup(book.ID, 0, 1, merged)
+	w = proxyAudioRequest(t, book)
+	if w.Code != http.StatusOK || w.Header().Get("X-Audio-Partial") != "true" || w.Header().Get("X-Audio-Pages") != "2" {
+		t.Fatalf("merged opening: got %d, headers %v", w.Code, w.Header())
+	}
+}
+
+func TestProxyBookAudio_NoPages(t *testing.T) {
+	withTestDB(t)
+	book := Book{Title: "Empty", Category: "Fiction", UserID: 3}
+	db.Create(&book)
+	if w := proxyAudioRequest(t, book); w.Code != http.StatusNotFound {
+		t.Fatalf("book without pages: got %d, want 404", w.Code)
+	}
+}
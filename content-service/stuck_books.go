@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Operator recovery for books that stopped moving. The sweepers in queue.go
+// catch the common stalls; anything they miss (a crash mid-chunk, a lost
+// whole-book job) shows up in GET /admin/books/stuck and can be pushed on
+// with POST /admin/books/:book_id/reprocess. "processing" is also where
+// synchronously chunked uploads rest, so a book in it only counts as stuck
+// while it has no pages.
+
+// stuckBookStatuses are the in-flight statuses a book can stall in.
+var stuckBookStatuses = []string{"awaiting_upload", "parsing", "chunking", "processing", "transcribing", "TTS completed"}
+
+// stuckBookAfter is the default age (STUCK_BOOK_AFTER_MINUTES, default 60)
+// past which a book in one of those statuses is reported.
+func stuckBookAfter() time.Duration {
+	return time.Duration(envInt("STUCK_BOOK_AFTER_MINUTES", 60)) * time.Minute
+}
+
+// stuckBook is one GET /admin/books/stuck entry.
+type stuckBook struct {
+	BookID         uint      `json:"book_id"`
+	UserID         uint      `json:"user_id"`
+	Title          string    `json:"title"`
+	Status         string    `json:"status"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	StuckMinutes   int       `json:"stuck_minutes"`
+	Pages          int64     `json:"pages"`
+	PagesCompleted int64     `json:"pages_completed"`
+	Action         string    `json:"action"` // what reprocess would do
+}
+
+// stuckBookAction is how reprocess re-drives a book: "parse" (re-chunk from
+// the uploaded file), "transcribe" (resume at the first unfinished page) or
+// "complete" (every page is done; only the status is behind).
+func stuckBookAction(status string, pages, completed int64) string {
+	switch {
+	case status == "awaiting_upload" || status == "parsing" || status == "chunking" || pages == 0:
+		// A parse that died part-way leaves a partial page set; start over.
+		return "parse"
+	case completed >= pages:
+		return "complete"
+	default:
+		return "transcribe"
+	}
+}
+
+// stuckBooksQuery selects books in stuckBookStatuses untouched since cutoff.
+// Transcriptions count only when their batches went quiet too, and
+// "processing" books only while they have no pages.
+func stuckBooksQuery(cutoff time.Time) *gorm.DB {
+	return db.Model(&Book{}).Where("status IN ? AND updated_at < ?", stuckBookStatuses, cutoff).
+		Where("status <> ? OR NOT EXISTS (SELECT 1 FROM book_chunks WHERE book_chunks.book_id = books.id)", "processing").
+		Where("status <> ? OR NOT EXISTS (SELECT 1 FROM transcription_batches tb WHERE tb.book_id = books.id AND tb.updated_at >= ?)", "transcribing", cutoff)
+}
+
+// findStuckBooks lists the stuck books, oldest first.
+func findStuckBooks(cutoff time.Time, limit int) ([]Book, error) {
+	var books []Book
+	err := stuckBooksQuery(cutoff).Order("updated_at ASC").Limit(limit).Find(&books).Error
+	return books, err
+}
+
+// bookIsStuck reports whether bookID is one findStuckBooks would list.
+func bookIsStuck(bookID uint, cutoff time.Time) (bool, error) {
+	var n int64
+	err := stuckBooksQuery(cutoff).Where("books.id = ?", bookID).Count(&n).Error
+	return n > 0, err
+}
+
+// bookPageCounts returns a book's page total and completed pages.
+func bookPageCounts(bookID uint) (pages, completed int64, err error) {
+	var row struct{ Pages, Completed int64 }
+	err = db.Model(&BookChunk{}).
+		Select("COUNT(*) AS pages, COUNT(*) FILTER (WHERE tts_status = ?) AS completed", "completed").
+		Where("book_id = ?", bookID).Scan(&row).Error
+	return row.Pages, row.Completed, err
+}
+
+// listStuckBooksHandler (GET /admin/books/stuck?older_than_minutes=&limit=)
+// lists books stalled in a non-terminal status, oldest first.
+func listStuckBooksHandler(c *gin.Context) {
+	after := stuckBookAfter()
+	if v := c.Query("older_than_minutes"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "older_than_minutes must be a positive integer")
+			return
+		}
+		after = time.Duration(n) * time.Minute
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 500 {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "limit must be between 1 and 500")
+		return
+	}
+
+	now := time.Now()
+	books, err := findStuckBooks(now.Add(-after), limit)
+	if err != nil {
+		respondInternal(c, "Failed to list stuck books", err)
+		return
+	}
+	out := make([]stuckBook, 0, len(books))
+	for _, b := range books {
+		pages, completed, err := bookPageCounts(b.ID)
+		if err != nil {
+			respondInternal(c, "Failed to count pages", err)
+			return
+		}
+		out = append(out, stuckBook{
+			BookID:         b.ID,
+			UserID:         b.UserID,
+			Title:          b.Title,
+			Status:         b.Status,
+			UpdatedAt:      b.UpdatedAt,
+			StuckMinutes:   int(now.Sub(b.UpdatedAt).Minutes()),
+			Pages:          pages,
+			PagesCompleted: completed,
+			Action:         stuckBookAction(b.Status, pages, completed),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"books": out, "older_than_minutes": int(after.Minutes())})
+}
+
+// errNoUploadedFile means a book to re-parse has no source file to parse.
+var errNoUploadedFile = errors.New("book has no uploaded file to process")
+
+// reprocessStuckBook re-drives book through the pipeline as action says and
+// returns the page transcription resumed at (-1 unless transcribing).
+func reprocessStuckBook(ctx context.Context, book Book, action string) (int, error) {
+	switch action {
+	case "parse":
+		if book.FilePath == "" {
+			return -1, errNoUploadedFile
+		}
+		if book.Status == "awaiting_upload" {
+			ok, err := store.Exists(ctx, book.FilePath)
+			if err != nil {
+				return -1, fmt.Errorf("check upload: %w", err)
+			}
+			if !ok {
+				return -1, errNoUploadedFile
+			}
+		}
+		db.Model(&Book{}).Where("id = ?", book.ID).Update("status", "parsing")
+		if err := enqueueParseBook(book.ID); err != nil {
+			db.Model(&Book{}).Where("id = ?", book.ID).Update("status", book.Status)
+			return -1, fmt.Errorf("enqueue parse: %w", err)
+		}
+		return -1, nil
+
+	case "complete":
+		return -1, db.Model(&Book{}).Where("id = ?", book.ID).Update("status", "completed").Error
+	}
+
+	// Transcribe: the book's processing lock comes first, so nothing is reset
+	// while a batch still holds it. Under the lock, pages claimed by the dead
+	// run go back to pending, the book's claim is released and the batch
+	// chain restarts under the owner and tier of the last batch. Without one
+	// the tier is unknown; "free" keeps the pause-ahead limits rather than
+	// reading as unlimited.
+	unlock, err := lockBook(ctx, book.ID, false)
+	if err != nil {
+		return -1, err
+	}
+	defer unlock()
+	if err := db.Model(&BookChunk{}).Where("book_id = ? AND tts_status = ?", book.ID, "processing").
+		Update("tts_status", "pending").Error; err != nil {
+		return -1, fmt.Errorf("reset pages: %w", err)
+	}
+	var first BookChunk
+	err = db.Where("book_id = ? AND tts_status != ?", book.ID, "completed").Order("index ASC").First(&first).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return -1, errNothingToTranscribe
+	}
+	if err != nil {
+		return -1, fmt.Errorf("fetch pages: %w", err)
+	}
+	if err := db.Model(&Book{}).Where("id = ?", book.ID).Update("status", "pending").Error; err != nil {
+		return -1, fmt.Errorf("release book: %w", err)
+	}
+	userID, accountType := book.UserID, "free"
+	var last TranscriptionBatch
+	if err := db.Where("book_id = ?", book.ID).Order("updated_at DESC").First(&last).Error; err == nil && last.AccountType != "" {
+		userID, accountType = last.UserID, last.AccountType
+	}
+	if err := claimAndEnqueueTranscription(book, first.Index, userID, accountType, ttsOverride{}); err != nil {
+		return -1, err
+	}
+	return first.Index, nil
+}
+
+// reprocessStuckBookHandler (POST /admin/books/:book_id/reprocess) re-drives
+// one book that stalled in a non-terminal status.
+func reprocessStuckBookHandler(c *gin.Context) {
+	bookID, err := strconv.ParseUint(c.Param("book_id"), 10, 64)
+	if err != nil || bookID == 0 {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "invalid book_id")
+		return
+	}
+	var book Book
+	if err := db.First(&book, bookID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, errCodeBookNotFound, "Book not found")
+			return
+		}
+		respondInternal(c, "Failed to load book", err)
+		return
+	}
+	// Only a book GET /admin/books/stuck would list: anything younger, at
+	// rest or with batches still moving is left alone.
+	stuck, err := bookIsStuck(book.ID, time.Now().Add(-stuckBookAfter()))
+	if err != nil {
+		respondInternal(c, "Failed to check book", err)
+		return
+	}
+	if !stuck {
+		respondErrorWith(c, http.StatusConflict, errCodeConflict, "Book is not stuck", gin.H{"status": book.Status})
+		return
+	}
+
+	pages, completed, err := bookPageCounts(book.ID)
+	if err != nil {
+		respondInternal(c, "Failed to count pages", err)
+		return
+	}
+	action := stuckBookAction(book.Status, pages, completed)
+	start, err := reprocessStuckBook(c.Request.Context(), book, action)
+	switch {
+	case errors.Is(err, errNoUploadedFile):
+		respondError(c, http.StatusConflict, errCodeConflict, "Book has no uploaded file to process")
+		return
+	case errors.Is(err, errBookLocked), errors.Is(err, errTranscriptionRunning):
+		respondError(c, http.StatusConflict, errCodeConflict, "Book is being processed right now")
+		return
+	case errors.Is(err, errNothingToTranscribe):
+		action = "complete"
+		db.Model(&Book{}).Where("id = ?", book.ID).Update("status", "completed")
+	case err != nil:
+		respondInternal(c, "Failed to reprocess book", err)
+		return
+	}
+
+	log.Printf("🛠️ Admin re-drove stuck book %d (%s → %s)", book.ID, book.Status, action)
+	resp := gin.H{"book_id": book.ID, "previous_status": book.Status, "action": action}
+	if start >= 0 {
+		resp["start_page"] = start
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+func TestStuckBookAction(t *testing.T) {
+	cases := []struct {
+		status           string
+		pages, completed int64
+		want             string
+	}{
+		{"parsing", 0, 0, "parse"},
+		{"chunking", 40, 0, "parse"}, // partial page set from a dead parse
+		{"awaiting_upload", 0, 0, "parse"},
+		{"processing", 0, 0, "parse"},
+		{"transcribing", 10, 4, "transcribe"},
+		{"TTS completed", 10, 10, "complete"},
+		{"transcribing", 0, 0, "parse"},
+	}
+	for _, tc := range cases {
+		if got := stuckBookAction(tc.status, tc.pages, tc.completed); got != tc.want {
+			t.Errorf("stuckBookAction(%q, %d, %d) = %q, want %q", tc.status, tc.pages, tc.completed, got, tc.want)
+		}
+	}
+}
+
+func stuckBooksRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	admin := r.Group("/admin", func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"user_id": float64(1), "is_admin": true})
+	}, adminMiddleware())
+	admin.GET("/books/stuck", listStuckBooksHandler)
+	admin.POST("/books/:book_id/reprocess", reprocessStuckBookHandler)
+	return r
+}
+
+// createAgedBook inserts a book last updated age ago with the given page
+// statuses.
+func createAgedBook(t *testing.T, status string, age time.Duration, pages ...string) Book {
+	t.Helper()
+	book := Book{Title: status, Category: "Fiction", UserID: 7, Status: status, FilePath: "uploads/7/book.txt"}
+	if err := db.Create(&book).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range pages {
+		db.Create(&BookChunk{BookID: book.ID, Index: i, Content: "page", TTSStatus: s})
+	}
+	book.UpdatedAt = time.Now().Add(-age)
+	db.Model(&Book{}).Where("id = ?", book.ID).UpdateColumn("updated_at", book.UpdatedAt)
+	return book
+}
+
+func TestListStuckBooks(t *testing.T) {
+	withTestDB(t)
+	stuckParse := createAgedBook(t, "parsing", 3*time.Hour)
+	stuckEmpty := createAgedBook(t, "processing", 2*time.Hour)
+	stuckTranscribe := createAgedBook(t, "transcribing", 2*time.Hour, "completed", "pending")
+	createAgedBook(t, "parsing", 5*time.Minute)                         // still within the window
+	createAgedBook(t, "completed", 3*time.Hour, "completed")            // terminal
+	createAgedBook(t, "processing", 3*time.Hour, "pending")             // chunked upload at rest
+	active := createAgedBook(t, "transcribing", 3*time.Hour, "pending") // batches still moving
+	db.Create(&TranscriptionBatch{BookID: active.ID, UserID: 7, AccountType: "free", Status: "processing"})
+
+	w := httptest.NewRecorder()
+	stuckBooksRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/books/stuck?older_than_minutes=60", nil))
+	var resp struct {
+		Books []stuckBook `json:"books"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || len(resp.Books) != 3 {
+		t.Fatalf("stuck = %d %s", w.Code, w.Body)
+	}
+	got := resp.Books // oldest first
+	if got[0].BookID != stuckParse.ID || got[0].Action != "parse" || got[0].StuckMinutes < 179 {
+		t.Errorf("first = %+v, want the parse stuck 3h", got[0])
+	}
+	ids := map[uint]stuckBook{got[1].BookID: got[1], got[2].BookID: got[2]}
+	if b, ok := ids[stuckEmpty.ID]; !ok || b.Action != "parse" {
+		t.Errorf("page-less processing book: %+v", b)
+	}
+	if b, ok := ids[stuckTranscribe.ID]; !ok || b.Action != "transcribe" || b.Pages != 2 || b.PagesCompleted != 1 {
+		t.Errorf("stalled transcription: %+v", b)
+	}
+}
+
+func TestReprocessStuckBook_ResumesTranscription(t *testing.T) {
+	withTestDB(t)
+	book := createAgedBook(t, "transcribing", 2*time.Hour, "completed", "processing", "pending")
+	db.Create(&TranscriptionBatch{BookID: book.ID, StartPage: 0, EndPage: 19, UserID: 7, AccountType: "premium", Status: "processing",
+		UpdatedAt: time.Now().Add(-2 * time.Hour)})
+
+	type batch struct {
+		start  int
+		userID uint
+		tier   string
+	}
+	var queued []batch
+	orig := enqueueTranscribeBatch
+	enqueueTranscribeBatch = func(bookID uint, start, _ int, userID uint, accountType string, _ ttsOverride) error {
+		queued = append(queued, batch{start, userID, accountType})
+		return nil
+	}
+	t.Cleanup(func() { enqueueTranscribeBatch = orig })
+
+	w := httptest.NewRecorder()
+	stuckBooksRouter().ServeHTTP(w, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/books/%d/reprocess", book.ID), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("reprocess = %d %s", w.Code, w.Body)
+	}
+	if len(queued) != 1 || queued[0] != (batch{1, 7, "premium"}) {
+		t.Fatalf("queued %+v, want one batch from page 1 for user 7 on premium", queued)
+	}
+	var reloaded Book
+	db.First(&reloaded, book.ID)
+	if reloaded.Status != "transcribing" {
+		t.Errorf("status = %q, want transcribing", reloaded.Status)
+	}
+	var stale int64
+	db.Model(&BookChunk{}).Where("book_id = ? AND tts_status = ?", book.ID, "processing").Count(&stale)
+	if stale != 0 {
+		t.Errorf("%d page(s) still claimed by the dead run", stale)
+	}
+}
+
+func TestReprocessStuckBook_Reparses(t *testing.T) {
+	withTestDB(t)
+	book := createAgedBook(t, "chunking", 2*time.Hour, "pending")
+	var parsed []uint
+	orig := enqueueParseBook
+	enqueueParseBook = func(bookID uint) error {
+		parsed = append(parsed, bookID)
+		return nil
+	}
+	t.Cleanup(func() { enqueueParseBook = orig })
+
+	w := httptest.NewRecorder()
+	stuckBooksRouter().ServeHTTP(w, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/books/%d/reprocess", book.ID), nil))
+	if w.Code != http.StatusOK || len(parsed) != 1 || parsed[0] != book.ID {
+		t.Fatalf("reprocess = %d %s, parses queued %v", w.Code, w.Body, parsed)
+	}
+	var reloaded Book
+	db.First(&reloaded, book.ID)
+	if reloaded.Status != "parsing" {
+		t.Errorf("status = %q, want parsing", reloaded.Status)
+	}
+}
+
+func TestReprocessStuckBook_RejectsSettledBook(t *testing.T) {
+	withTestDB(t)
+	book := createAgedBook(t, "completed", 2*time.Hour, "completed")
+	w := httptest.NewRecorder()
+	stuckBooksRouter().ServeHTTP(w, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/books/%d/reprocess", book.ID), nil))
+	if w.Code != http.StatusConflict {
+		t.Errorf("reprocess of a completed book = %d %s, want 409", w.Code, w.Body)
+	}
+}
+
+// Books that aren't stuck, or whose lock is held, are refused before
+// anything about them changes.
+func TestReprocessStuckBook_LeavesLiveBooksAlone(t *testing.T) {
+	withTestDB(t)
+	orig := enqueueTranscribeBatch
+	enqueueTranscribeBatch = func(uint, int, int, uint, string, ttsOverride) error {
+		t.Error("batch enqueued for a book that isn't stuck")
+		return nil
+	}
+	t.Cleanup(func() { enqueueTranscribeBatch = orig })
+
+	live := createAgedBook(t, "transcribing", 2*time.Hour, "completed", "processing")
+	db.Create(&TranscriptionBatch{BookID: live.ID, UserID: 7, AccountType: "free", Status: "processing"}) // still moving
+	atRest := createAgedBook(t, "processing", 2*time.Hour, "pending")
+	locked := createAgedBook(t, "transcribing", 2*time.Hour, "completed", "processing")
+	unlock, err := lockBook(context.Background(), locked.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	for _, book := range []Book{live, atRest, locked} {
+		w := httptest.NewRecorder()
+		stuckBooksRouter().ServeHTTP(w, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/books/%d/reprocess", book.ID), nil))
+		if w.Code != http.StatusConflict {
+			t.Errorf("book %d (%s): reprocess = %d %s, want 409", book.ID, book.Status, w.Code, w.Body)
+		}
+		var reloaded Book
+		db.First(&reloaded, book.ID)
+		var inFlight int64
+		db.Model(&BookChunk{}).Where("book_id = ? AND tts_status = ?", book.ID, "processing").Count(&inFlight)
+		if reloaded.Status != book.Status || (book.ID != atRest.ID && inFlight != 1) {
+			t.Errorf("book %d: status %q, %d in-flight page(s) after a refused reprocess", book.ID, reloaded.Status, inFlight)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Temp-file janitor. Rendering scatters intermediates (concat lists, per-
+// segment TTS, split-input parts, interrupted atomic writes, mix job dirs)
+// that are normally removed by the code that made them — but a crash, a
+// killed ffmpeg or an early return leaves them behind. The janitor removes
+// files matching known temp patterns once they're older than
+// TEMP_FILE_TTL_MINUTES (default 120), every TEMP_JANITOR_INTERVAL_MINUTES
+// (default 30). Unlike the daily gcOrphanedLocalAudio sweep it never touches
+// arbitrary files, and it always skips anything a Book/chunk/group row still
+// points at.
+
+// audioTempPatterns are the scratch names written into the audio dir.
+var audioTempPatterns = []string{
+	"audio_list_*.txt",       // processMergedChunks concat list
+	"concat_list_*.txt",      // mergeAudioSegments concat list
+	"book_*_chunks_*_*.txt",  // processMergedChunks analysis text
+	"segment_*_*.mp3",        // per-segment TTS
+	"segment_*_*_part*.mp3",  // split over-long TTS input
+	"audio_*_part*.mp3",      // split single-voice input
+	"sound_effect_tmp_*.mp3", // unkeyed generated clip; keyed ones are the music cache
+	".partial-*",             // interrupted atomic write
+	"dyn_seg_*", "dyn_crossfade_*", "dynamic_background_final.*",
+}
+
+// osTempPatterns are per-job scratch files/dirs created in os.TempDir().
+var osTempPatterns = []string{
+	"narrafied-mix-*", // mergeAudio job dir
+	"hls-*",           // HLS packaging job dir
+	"src-*",           // localizeMedia download
+	"pl-*.m3u8",       // rewritten playlist
+}
+
+// sweepTempFiles removes entries of dir matching patterns whose mtime is older
+// than ttl, skipping any absolute path in referenced. Directories (job dirs)
+// are removed whole. Returns the count removed and bytes reclaimed.
+func sweepTempFiles(dir string, patterns []string, ttl time.Duration, referenced map[string]bool) (int, int64) {
+	cutoff := time.Now().Add(-ttl)
+	seen := map[string]bool{}
+	var n int
+	var freed int64
+	for _, pat := range patterns {
+		matches, _ := filepath.Glob(filepath.Join(dir, pat))
+		for _, p := range matches {
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+			abs, err := filepath.Abs(p)
+			if err != nil || referenced[abs] {
+				continue
+			}
+			info, err := os.Lstat(p)
+			if err != nil || info.ModTime().After(cutoff) {
+				continue
+			}
+			size := info.Size()
+			if info.IsDir() {
+				size = dirSize(p)
+			}
+			if err := os.RemoveAll(p); err != nil {
+				log.Printf("⚠️ [Janitor] could not remove %s: %v", p, err)
+				continue
+			}
+			n++
+			freed += size
+		}
+	}
+	return n, freed
+}
+
+// referencedLocalPaths returns the absolute legacy on-disk paths any Book,
+// BookChunk or ProcessedChunkGroup row still points at.
+func referencedLocalPaths() map[string]bool {
+	refs := map[string]bool{}
+	pluck := func(model interface{}, col string) {
+		var paths []string
+		db.Model(model).Where(col+" LIKE ? OR "+col+" LIKE ?", "./%", "/%").Pluck(col, &paths)
+		for _, p := range paths {
+			if abs, err := filepath.Abs(p); err == nil {
+				refs[abs] = true
+			}
+		}
+	}
+	pluck(&Book{}, "audio_path")
+	pluck(&Book{}, "file_path")
+	pluck(&Book{}, "cover_path")
+	pluck(&BookChunk{}, "audio_path")
+	pluck(&BookChunk{}, "final_audio_path")
+	pluck(&ProcessedChunkGroup{}, "audio_path")
+	return refs
+}
+
+// tempJanitorLoop runs the temp sweep on an interval in the worker.
+func tempJanitorLoop() {
+	interval := time.Duration(envInt("TEMP_JANITOR_INTERVAL_MINUTES", 30)) * time.Minute
+	ttl := time.Duration(envInt("TEMP_FILE_TTL_MINUTES", 120)) * time.Minute
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		runTempJanitor(ttl)
+	}
+}
+
+// runTempJanitor sweeps the audio dir and the OS temp dir once.
+func runTempJanitor(ttl time.Duration) (int, int64) {
+	refs := referencedLocalPaths()
+	n1, f1 := sweepTempFiles(getEnv("AUDIO_STORAGE_PATH", "./audio"), audioTempPatterns, ttl, refs)
+	n2, f2 := sweepTempFiles(os.TempDir(), osTempPatterns, ttl, refs)
+	if n1+n2 > 0 {
+		log.Printf("🧹 [Janitor] removed %d temp file(s), reclaimed %.1f MB", n1+n2, float64(f1+f2)/1e6)
+	}
+	return n1 + n2, f1 + f2
+}
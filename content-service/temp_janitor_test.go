@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSweepTempFiles_RemovesOldTempKeepsReferenced(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-3 * time.Hour)
+	write := func(name string, size int, mtime time.Time) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(p, mtime, mtime)
+		return p
+	}
+
+	staleList := write("audio_list_1700000000.txt", 10, old)
+	staleSeg := write("segment_4_2.mp3", 100, old)
+	stalePartial := write(".partial-123-book_4_page_2_ab.mp3", 50, old)
+	referenced := write("segment_9_1.mp3", 70, old)        // old but still a chunk's AudioPath
+	fresh := write("concat_list_42.txt", 5, time.Now())    // in-flight render
+	final := write("book_4_page_2_abcdef12.mp3", 500, old) // not a temp pattern
+	staleClip := write("sound_effect_tmp_81.mp3", 30, old)
+	cachedClip := write("sound_effect_0123456789abcdef.mp3", 80, old) // music cache
+	scoreClip := write("sound_effect_score_4_tense.mp3", 80, old)     // score palette cache
+
+	jobDir := filepath.Join(dir, "narrafied-mix-77")
+	os.Mkdir(jobDir, 0755)
+	os.WriteFile(filepath.Join(jobDir, "dyn_seg_0.ogg"), make([]byte, 40), 0644)
+	os.Chtimes(jobDir, old, old)
+
+	absRef, _ := filepath.Abs(referenced)
+	patterns := append(append([]string{}, audioTempPatterns...), osTempPatterns...)
+	n, freed := sweepTempFiles(dir, patterns, 2*time.Hour, map[string]bool{absRef: true})
+
+	if n != 5 || freed != 10+100+50+30+40 {
+		t.Errorf("removed %d (%d bytes), want 5 (230 bytes)", n, freed)
+	}
+	for _, p := range []string{staleList, staleSeg, stalePartial, staleClip, jobDir} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("stale temp %s not removed", filepath.Base(p))
+		}
+	}
+	for _, p := range []string{referenced, fresh, final, cachedClip, scoreClip} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("%s should have been kept: %v", filepath.Base(p), err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// scriptPunctReplacer maps punctuation from non-Latin scripts to the ASCII
+// forms every TTS engine treats as prosody rather than reading aloud
+// ("full stop", "left corner bracket"). Sentence/clause marks from CJK get a
+// trailing space since those scripts don't put one after them; Arabic and
+// Hebrew already space their punctuation.
+var scriptPunctReplacer = strings.NewReplacer(
+	// CJK sentence and clause punctuation
+	"。", ". ", "｡", ". ", "、", ", ", "､", ", ",
+	"．", ". ", "，", ", ", "！", "! ", "？", "? ", "：", ": ", "；", "; ",
+	"・", " ", "･", " ", "〜", "-", "～", "-",
+	"…", "...", "‥", "..",
+	// CJK brackets and quotes
+	"「", `"`, "」", `"`, "『", `"`, "』", `"`, "｢", `"`, "｣", `"`,
+	"《", `"`, "》", `"`, "〈", `"`, "〉", `"`,
+	"【", "(", "】", ")", "〔", "(", "〕", ")",
+	// Localized quotation marks (Russian/French guillemets, German/Polish low quotes)
+	"«", `"`, "»", `"`, "‹", "'", "›", "'",
+	"„", `"`, "“", `"`, "”", `"`, "‟", `"`,
+	"‚", "'", "‘", "'", "’", "'", "‛", "'",
+	// Arabic script
+	"،", ",", "؛", ";", "؟", "?", "۔", ".", "ـ", "",
+	// Hebrew
+	"־", "-", "׳", "'", "״", `"`, "׃", ".",
+	// Invisible bidi/format controls that some engines spell out or choke on.
+	// ZWJ/ZWNJ are kept: they change letter shaping in Persian and Indic text.
+	"\u200b", "", "\u200e", "", "\u200f", "", "\ufeff", "",
+	"\u202a", "", "\u202b", "", "\u202c", "", "\u202d", "", "\u202e", "",
+	"\u2066", "", "\u2067", "", "\u2068", "", "\u2069", "",
+)
+
+// dialogueDashRe matches the dash that opens a line of dialogue in Russian and
+// other European typography ("— Привет, — сказал он.").
+var dialogueDashRe = regexp.MustCompile(`(?m)^[ \t]*[—–―][ \t]*`)
+
+// spacedDashRe matches a spaced em/en dash used as a clause break, with any
+// punctuation right before it ("Привет, — сказал"). Unspaced English em
+// dashes ("word—word") are left for the engine to pace.
+var spacedDashRe = regexp.MustCompile(`([,.;:!?])? [—–―] `)
+
+// normalizeScriptPunctuation rewrites non-Latin punctuation, full-width
+// characters and localized quotes into TTS-friendly ASCII equivalents so the
+// voice doesn't read symbols aloud. Letters in every script are untouched.
+func normalizeScriptPunctuation(text string) string {
+	text = scriptPunctReplacer.Replace(text)
+	text = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 0xFF01 && r <= 0xFF5E: // remaining full-width ASCII (Ａ１＃) → ASCII
+			return r - 0xFEE0
+		case r == 0x3000: // ideographic space
+			return ' '
+		}
+		return r
+	}, text)
+	text = dialogueDashRe.ReplaceAllString(text, "")
+	return spacedDashRe.ReplaceAllStringFunc(text, func(m string) string {
+		if p := strings.TrimSpace(m)[0]; strings.IndexByte(",.;:!?", p) >= 0 {
+			return string(p) + " " // keep the sentence's own mark
+		}
+		return ", "
+	})
+}
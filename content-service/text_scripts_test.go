@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// symbols a TTS engine would read aloud or stumble on.
+const unvoicedSymbols = "。、「」『』《》【】・！？：；（），«»„“”‚‘’—׳״،؛؟\u200f\u200b\u3000"
+
+func assertVoiceable(t *testing.T, got string) {
+	t.Helper()
+	if i := strings.IndexAny(got, unvoicedSymbols); i >= 0 {
+		t.Errorf("output still contains %q: %q", []rune(got[i:])[0], got)
+	}
+}
+
+func TestCleanupForTTS_Japanese(t *testing.T) {
+	in := "「こんにちは」と彼は言った。元気ですか？　今日は晴れ、とても暖かい！『吾輩は猫である』を読む。"
+	got := cleanupForTTS(in)
+	want := `"こんにちは"と彼は言った. 元気ですか? 今日は晴れ, とても暖かい! "吾輩は猫である"を読む.`
+	if got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+	assertVoiceable(t, got)
+}
+
+func TestCleanupForTTS_Russian(t *testing.T) {
+	in := "— Привет, — сказал он. — Ты читал «Войну и мир»?\n— Да, „очень“ давно."
+	got := cleanupForTTS(in)
+	want := `Привет, сказал он. Ты читал "Войну и мир"? Да, "очень" давно.`
+	if got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+	assertVoiceable(t, got)
+}
+
+func TestNormalizeScriptPunctuation(t *testing.T) {
+	cases := map[string]string{
+		"مرحبا، كيف حالك؟":     "مرحبا, كيف حالك?",     // Arabic comma/question mark
+		"כתב ״הארץ״ בתל־אביב":  `כתב "הארץ" בתל-אביב`,  // Hebrew gershayim/maqaf
+		"\u200fשלום\u200f":     "שלום",                 // RLM stripped
+		"ＡＢＣ１２３＃":              "ABC123#",              // full-width ASCII
+		"He paused—then left.": "He paused—then left.", // unspaced English dash kept
+		"don’t say “never”":    `don't say "never"`,
+		"می\u200cخواهم":        "می\u200cخواهم", // ZWNJ preserved (Persian)
+	}
+	for in, want := range cases {
+		if got := normalizeScriptPunctuation(in); got != want {
+			t.Errorf("normalizeScriptPunctuation(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
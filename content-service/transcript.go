@@ -0,0 +1,125 @@
+package main
+
+// Per-page transcripts for captions and read-along. The text a page was
+// narrated from is not its raw Content: dialogue analysis splits it into
+// speaker segments and cleans each one, and the single-voice fallback reads
+// GPT-prepared narrator text instead. Each TTS path records the text it
+// actually sent, segment by segment, on book_chunks.transcript; GET
+// /user/books/:book_id/pages/:page/transcript serves it, with per-segment
+// times when the page also has a timing map (timing_map.go).
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TranscriptSegment is one stretch of speech in a page's audio.
+type TranscriptSegment struct {
+	Type       string   `json:"type"`              // "narrator" | "dialogue"
+	Speaker    string   `json:"speaker,omitempty"` // character name for dialogue
+	Text       string   `json:"text"`              // exactly what was sent to TTS
+	IsDialogue bool     `json:"is_dialogue"`
+	StartSec   *float64 `json:"start_sec,omitempty"` // filled from the timing map when served
+	EndSec     *float64 `json:"end_sec,omitempty"`
+}
+
+// pageTranscript is what book_chunks.transcript holds. Text is the segment
+// texts joined by single spaces, the same join the timing map counts runes
+// over.
+type pageTranscript struct {
+	Text     string              `json:"text"`
+	Segments []TranscriptSegment `json:"segments"`
+}
+
+// buildTranscript pairs each rendered segment with the text sent for it.
+// segs and spoken must be parallel.
+func buildTranscript(segs []DialogueSegment, spoken []string) pageTranscript {
+	t := pageTranscript{Segments: make([]TranscriptSegment, 0, len(segs))}
+	texts := make([]string, 0, len(segs))
+	for i, s := range segs {
+		typ := s.Type
+		if typ == "" {
+			typ = "narrator"
+		}
+		t.Segments = append(t.Segments, TranscriptSegment{Type: typ, Speaker: s.Speaker, Text: spoken[i], IsDialogue: s.IsDialogue})
+		texts = append(texts, spoken[i])
+	}
+	t.Text = strings.Join(texts, " ")
+	return t
+}
+
+// saveTranscript persists a chunk's transcript (no-op when empty).
+func saveTranscript(chunkID uint, t pageTranscript) {
+	if len(t.Segments) == 0 {
+		return
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return
+	}
+	if err := db.Model(&BookChunk{}).Where("id = ?", chunkID).
+		Update("transcript", string(data)).Error; err != nil {
+		log.Printf("⚠️ [Transcript] chunk %d: save failed: %v", chunkID, err)
+	}
+}
+
+// loadTranscriptJSON returns a chunk's stored transcript, "" if it has none.
+func loadTranscriptJSON(chunkID uint) string {
+	var chunk BookChunk
+	if err := db.Select("transcript").Where("id = ?", chunkID).First(&chunk).Error; err != nil {
+		return ""
+	}
+	return chunk.Transcript
+}
+
+// withSegmentTimes copies start/end seconds from tm onto the segments. The
+// map only exists when every segment was measured, so it lines up one to one;
+// any other shape is ignored.
+func withSegmentTimes(t pageTranscript, tm []SegmentTiming) pageTranscript {
+	if len(tm) != len(t.Segments) {
+		return t
+	}
+	for i := range t.Segments {
+		start, end := tm[i].StartSec, tm[i].EndSec
+		t.Segments[i].StartSec, t.Segments[i].EndSec = &start, &end
+	}
+	return t
+}
+
+// pageTranscriptHandler serves one page's transcript.
+// GET /user/books/:book_id/pages/:page/transcript (page is 1-based)
+func pageTranscriptHandler(c *gin.Context) {
+	book := c.MustGet("book").(Book)
+	page, err := strconv.Atoi(c.Param("page"))
+	if err != nil || page < 1 {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid page number")
+		return
+	}
+
+	var chunk BookChunk
+	if err := db.Select("id", "transcript", "timing_map").
+		Where("book_id = ? AND \"index\" = ?", book.ID, page-1).First(&chunk).Error; err != nil {
+		respondError(c, http.StatusNotFound, errCodePageNotFound, "Page not found")
+		return
+	}
+	var t pageTranscript
+	if strings.TrimSpace(chunk.Transcript) == "" || json.Unmarshal([]byte(chunk.Transcript), &t) != nil {
+		respondError(c, http.StatusNotFound, errCodeAudioNotReady, "No transcript for this page yet")
+		return
+	}
+	var tm []SegmentTiming
+	if chunk.TimingMap != "" && json.Unmarshal([]byte(chunk.TimingMap), &tm) == nil {
+		t = withSegmentTimes(t, tm)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"book_id":  book.ID,
+		"page":     page,
+		"text":     t.Text,
+		"segments": t.Segments,
+	})
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stubTTSInputs points the OpenAI TTS endpoint at a server that records the
+// input text of every request and answers with a few bytes of "audio".
+func stubTTSInputs(t *testing.T) func() []string {
+	t.Helper()
+	var mu sync.Mutex
+	var inputs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Input string `json:"input"`
+		}
+		raw, _ := io.ReadAll(r.Body)
+		json.Unmarshal(raw, &body)
+		mu.Lock()
+		inputs = append(inputs, body.Input)
+		mu.Unlock()
+		w.Write([]byte("ID3"))
+	}))
+	orig := openaiEngine.Endpoint
+	openaiEngine.Endpoint = srv.URL
+	t.Cleanup(func() { openaiEngine.Endpoint = orig; srv.Close() })
+	t.Setenv("OPENAI_API_KEY", "test")
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), inputs...)
+	}
+}
+
+func TestBuildTranscript(t *testing.T) {
+	segs := []DialogueSegment{
+		{Type: "narrator", Text: "raw"},
+		{Type: "dialogue", Speaker: "Elizabeth", Text: `"raw line"`, IsDialogue: true},
+	}
+	tr := buildTranscript(segs, []string{"It was cold.", "Shut the door."})
+	if tr.Text != "It was cold. Shut the door." || len(tr.Segments) != 2 {
+		t.Fatalf("transcript = %+v", tr)
+	}
+	if s := tr.Segments[1]; s.Speaker != "Elizabeth" || !s.IsDialogue || s.Text != "Shut the door." {
+		t.Errorf("dialogue segment = %+v", s)
+	}
+
+	timed := withSegmentTimes(tr, buildTimingMap([]string{"It was cold.", "Shut the door."}, []float64{1.5, 2}))
+	if s := timed.Segments[1]; s.StartSec == nil || *s.StartSec != 1.5 || *s.EndSec != 3.5 {
+		t.Errorf("segment times = %+v", s)
+	}
+	if untimed := withSegmentTimes(buildTranscript(segs, []string{"a", "b"}), buildTimingMap([]string{"a"}, []float64{1})); untimed.Segments[0].StartSec != nil {
+		t.Error("mismatched timing map was applied")
+	}
+}
+
+// The transcript records the cleaned, title-expanded text a segment is
+// synthesized from, not the segment's raw text.
+func TestSpokenSegmentText_IsWhatTTSReceives(t *testing.T) {
+	inputs := stubTTSInputs(t)
+	t.Chdir(t.TempDir())
+	cfg := openaiEngine
+	cfg.ExpandTitles = true
+	seg := DialogueSegment{Type: "narrator", Text: "```ssml\n<speak>Mr. Darcy bowed.</speak>\n```"}
+
+	if _, err := generateSegmentAudio(context.Background(), seg, 1, 0, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	sent := inputs()
+	if len(sent) != 1 || sent[0] != spokenSegmentText(seg, &cfg) {
+		t.Fatalf("TTS input %q, transcript text %q", sent, spokenSegmentText(seg, &cfg))
+	}
+}
+
+func TestTranscript_StoredMatchesTTSInput(t *testing.T) {
+	withTestDB(t)
+	stubChatModels(t) // dialogue analysis and narrator prep fail → single voice, original text
+	inputs := stubTTSInputs(t)
+	t.Chdir(t.TempDir())
+
+	book := Book{Title: "Persuasion", Category: "Fiction", UserID: 2}
+	db.Create(&book)
+	chunk := BookChunk{BookID: book.ID, Index: 0, AudioPath: "", Content: "Sir Walter Elliot, of Kellynch Hall, never took up any book but the Baronetage."}
+	db.Create(&chunk)
+
+	if _, err := convertTextToAudioForChunk(context.Background(), chunk); err != nil {
+		t.Fatal(err)
+	}
+	var stored BookChunk
+	db.First(&stored, chunk.ID)
+	var tr pageTranscript
+	if err := json.Unmarshal([]byte(stored.Transcript), &tr); err != nil {
+		t.Fatalf("stored transcript %q: %v", stored.Transcript, err)
+	}
+	sent := inputs()
+	if len(sent) != 1 || tr.Text != sent[0] {
+		t.Fatalf("stored transcript %q, TTS input %q", tr.Text, sent)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/books/:book_id/pages/:page/transcript", func(c *gin.Context) { c.Set("book", book) }, pageTranscriptHandler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/books/%d/pages/1/transcript", book.ID), nil))
+	var body struct {
+		Text     string              `json:"text"`
+		Segments []TranscriptSegment `json:"segments"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != http.StatusOK || body.Text != sent[0] || len(body.Segments) != 1 || body.Segments[0].Type != "narrator" {
+		t.Fatalf("GET transcript = %d %s", w.Code, w.Body)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/books/%d/pages/2/transcript", book.ID), nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing page: got %d, want 404", w.Code)
+	}
+}
+
+// A page that reuses a shared rendering takes the donor's transcript, and
+// drops its own stale one when the donor has none.
+func TestReuseRenderedPage_CarriesTranscript(t *testing.T) {
+	withTestDB(t)
+	ms := memStore{}
+	origStore := store
+	store = ms
+	t.Cleanup(func() { store = origStore })
+
+	donor := Book{Title: "Donor", Category: "Fiction", UserID: 1}
+	db.Create(&donor)
+	engine := dedupEngineKey(donor)
+	text, bare := "It was a dark and stormy night.", "The rain fell in torrents."
+	withTranscript := `{"text":"It was a dark and stormy night.","segments":[{"type":"narrator","text":"It was a dark and stormy night.","is_dialogue":false}]}`
+	for _, rp := range []struct{ text, transcript string }{{text, withTranscript}, {bare, ""}} {
+		key := sharedAudioKey(engine, contentHash(rp.text), ".mp3")
+		ms[key] = 10
+		registerRenderedPage(contentHash(rp.text), engine, key, "", rp.transcript)
+	}
+
+	book := Book{Title: "Reuser", Category: "Fiction", UserID: 2}
+	db.Create(&book)
+	fresh := BookChunk{BookID: book.ID, Index: 0, Content: text}
+	stale := BookChunk{BookID: book.ID, Index: 1, Content: bare, Transcript: `{"text":"old audio"}`}
+	db.Create(&fresh)
+	db.Create(&stale)
+	for _, ch := range []BookChunk{fresh, stale} {
+		if !reuseRenderedPageForChunk(context.Background(), book, ch) {
+			t.Fatalf("page %d not reused", ch.Index)
+		}
+	}
+
+	var got BookChunk
+	db.First(&got, fresh.ID)
+	if got.Transcript != withTranscript {
+		t.Errorf("reused page transcript = %q, want the donor's", got.Transcript)
+	}
+	db.First(&got, stale.ID)
+	if got.Transcript != "" {
+		t.Errorf("stale transcript kept after reuse: %q", got.Transcript)
+	}
+}
@@ -49,19 +49,23 @@ type ttsEngineConfig struct {
 	MalePool             []string // round-robin per-character pools
 	FemalePool           []string
 	UnknownPool          []string // named characters of unknown gender
+	// Voices is every voice the engine accepts on request (previews,
+	// overrides); nil means just the ones above.
+	Voices []string
 }
 
 var openaiEngine = ttsEngineConfig{
 	Name:                 "openai",
 	Endpoint:             openaiTTSEndpoint,
 	APIKey:               func() string { return os.Getenv("OPENAI_API_KEY") },
-	Model:                "gpt-4o-mini-tts",
+	Model:                envStr("OPENAI_TTS_MODEL", "gpt-4o-mini-tts"),
 	SupportsInstructions: true,
 	NarratorVoice:        VoiceNarrator,
 	UnknownVoice:         unknownDialogueVoice,
 	MalePool:             maleVoicePool,
 	FemalePool:           femaleVoicePool,
 	UnknownPool:          unknownVoicePool,
+	Voices:               openaiVoiceIDs(),
 }
 
 // Kokoro British cast mirrors the winning bake-off sample (bm_george
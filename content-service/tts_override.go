@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// One-off voice/language overrides. POST /user/process-chunks and
+// /user/books/:book_id/tts/batch accept optional "voice" and "language" for
+// that job only: the override rides on the job's context (and on the queued
+// batch payload) down to the TTS request, and is never written to the book.
+// The voice replaces the narrator voice; characters keep their cast so a
+// re-rendered page still matches the rest of the book. The language is passed
+// as a spoken-language instruction (OpenAI) or language_code (ElevenLabs);
+// Kokoro picks its language from the voice, so it takes voice overrides only.
+// Overridden renders get their own cross-user dedup namespace
+// (page_dedup.go), so they are never served to a book that didn't ask.
+
+// ttsOverride is a job's optional voice/language choice.
+type ttsOverride struct {
+	Voice    string `json:"voice"`
+	Language string `json:"language"` // ISO 639-1, e.g. "fr"
+}
+
+// ttsLanguages are the languages an override may request, by ISO 639-1 code.
+var ttsLanguages = map[string]string{
+	"ar": "Arabic", "de": "German", "en": "English", "es": "Spanish",
+	"fr": "French", "hi": "Hindi", "it": "Italian", "ja": "Japanese",
+	"ko": "Korean", "nl": "Dutch", "pl": "Polish", "pt": "Portuguese",
+	"ru": "Russian", "sv": "Swedish", "tr": "Turkish", "zh": "Chinese",
+}
+
+func (o ttsOverride) isZero() bool { return o.Voice == "" && o.Language == "" }
+
+// normalized trims both fields and lower-cases the language code.
+func (o ttsOverride) normalized() ttsOverride {
+	return ttsOverride{Voice: strings.TrimSpace(o.Voice), Language: strings.ToLower(strings.TrimSpace(o.Language))}
+}
+
+// validate checks o against the engine that narrates the book.
+func (o ttsOverride) validate(cfg *ttsEngineConfig) error {
+	if o.Voice != "" {
+		if !engineHasVoice(cfg, o.Voice) {
+			return fmt.Errorf("unknown voice %q for the %s engine", o.Voice, cfg.Name)
+		}
+	}
+	if o.Language != "" {
+		if _, ok := ttsLanguages[o.Language]; !ok {
+			codes := make([]string, 0, len(ttsLanguages))
+			for c := range ttsLanguages {
+				codes = append(codes, c)
+			}
+			sort.Strings(codes)
+			return fmt.Errorf("unsupported language %q (supported: %s)", o.Language, strings.Join(codes, ", "))
+		}
+		if !cfg.SupportsInstructions && cfg.Provider != "elevenlabs" {
+			return fmt.Errorf("the %s engine takes its language from the voice; choose a voice instead", cfg.Name)
+		}
+	}
+	return nil
+}
+
+// narrating returns cfg with the override's narrator voice, or cfg itself.
+func (o ttsOverride) narrating(cfg *ttsEngineConfig) *ttsEngineConfig {
+	if o.Voice == "" {
+		return cfg
+	}
+	c := *cfg
+	c.NarratorVoice = o.Voice
+	return &c
+}
+
+// dedupSuffix keeps overridden renders out of the default shared namespace.
+// Hashed because rendered_pages.engine is only 32 characters.
+func (o ttsOverride) dedupSuffix() string {
+	if o.isZero() {
+		return ""
+	}
+	return "+o" + shortHash(fmt.Sprintf("%x", sha256.Sum256([]byte(o.Voice+"\x00"+o.Language))))
+}
+
+type ttsOverrideKey struct{}
+
+// withTTSOverride attaches o to ctx for every TTS call made under it.
+func withTTSOverride(ctx context.Context, o ttsOverride) context.Context {
+	if o.isZero() {
+		return ctx
+	}
+	return context.WithValue(ctx, ttsOverrideKey{}, o)
+}
+
+// ttsOverrideFrom returns the override on ctx (zero when none).
+func ttsOverrideFrom(ctx context.Context) ttsOverride {
+	o, _ := ctx.Value(ttsOverrideKey{}).(ttsOverride)
+	return o
+}
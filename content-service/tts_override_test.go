@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+func TestTTSOverride_Validate(t *testing.T) {
+	cases := []struct {
+		name string
+		o    ttsOverride
+		cfg  *ttsEngineConfig
+		ok   bool
+	}{
+		{"empty", ttsOverride{}, &openaiEngine, true},
+		{"pool voice", ttsOverride{Voice: "nova"}, &openaiEngine, true},
+		{"other engine's voice", ttsOverride{Voice: "bm_george"}, &openaiEngine, false},
+		{"language on openai", ttsOverride{Language: "fr"}, &openaiEngine, true},
+		{"language on eleven", ttsOverride{Language: "de"}, &elevenEngine, true},
+		{"unknown language", ttsOverride{Language: "xx"}, &openaiEngine, false},
+		{"language on kokoro", ttsOverride{Language: "fr"}, &kokoroEngine, false},
+		{"voice on kokoro", ttsOverride{Voice: "bf_emma"}, &kokoroEngine, true},
+	}
+	for _, tc := range cases {
+		if err := tc.o.validate(tc.cfg); (err == nil) != tc.ok {
+			t.Errorf("%s: validate = %v, want ok=%v", tc.name, err, tc.ok)
+		}
+	}
+	if got := (ttsOverride{Voice: " nova ", Language: " FR"}).normalized(); got != (ttsOverride{Voice: "nova", Language: "fr"}) {
+		t.Errorf("normalized = %+v", got)
+	}
+}
+
+func TestTTSOverride_DedupNamespace(t *testing.T) {
+	book := Book{TTSEngine: "openai"}
+	base := jobDedupEngineKey(context.Background(), book)
+	if base != dedupEngineKey(book) {
+		t.Fatalf("no override: %q, want %q", base, dedupEngineKey(book))
+	}
+	a := jobDedupEngineKey(withTTSOverride(context.Background(), ttsOverride{Voice: "nova"}), book)
+	b := jobDedupEngineKey(withTTSOverride(context.Background(), ttsOverride{Voice: "nova", Language: "fr"}), book)
+	if a == base || b == base || a == b {
+		t.Errorf("keys not distinct: %q %q %q", base, a, b)
+	}
+	if len(b) > 64 {
+		t.Errorf("key %q exceeds rendered_pages.engine", b)
+	}
+}
+
+func TestDedupEngineKey_FoleyVolume(t *testing.T) {
+	quiet, loud := 0.2, 0.8
+	base := dedupEngineKey(Book{TTSEngine: "openai"})
+	a := dedupEngineKey(Book{TTSEngine: "openai", FoleyVolume: &quiet})
+	b := dedupEngineKey(Book{TTSEngine: "openai", FoleyVolume: &loud})
+	if a == base || b == base || a == b {
+		t.Errorf("keys not distinct: %q %q %q", base, a, b)
+	}
+	if again := dedupEngineKey(Book{TTSEngine: "openai", FoleyVolume: &quiet}); again != a {
+		t.Errorf("same volume: %q, want %q", again, a)
+	}
+}
+
+// stubTTSPayloads records every OpenAI TTS request body.
+func stubTTSPayloads(t *testing.T) func() []TTSPayload {
+	t.Helper()
+	var mu sync.Mutex
+	var got []TTSPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p TTSPayload
+		raw, _ := io.ReadAll(r.Body)
+		json.Unmarshal(raw, &p)
+		mu.Lock()
+		got = append(got, p)
+		mu.Unlock()
+		w.Write([]byte("ID3"))
+	}))
+	orig := openaiEngine.Endpoint
+	openaiEngine.Endpoint = srv.URL
+	t.Cleanup(func() { openaiEngine.Endpoint = orig; srv.Close() })
+	t.Setenv("OPENAI_API_KEY", "test")
+	return func() []TTSPayload {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]TTSPayload(nil), got...)
+	}
+}
+
+func TestTTSOverride_ReachesTTSPayload(t *testing.T) {
+	stubChatModels(t) // analysis fails → single narrator voice
+	payloads := stubTTSPayloads(t)
+	t.Chdir(t.TempDir())
+
+	ctx := withTTSOverride(context.Background(), ttsOverride{Voice: "shimmer", Language: "fr"})
+	if _, err := convertTextToAudioMultiVoice(ctx, "Il pleuvait sur la ville.", 1, 0, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	sent := payloads()
+	if len(sent) != 1 {
+		t.Fatalf("%d TTS requests, want 1", len(sent))
+	}
+	if sent[0].Voice != "shimmer" || !strings.HasSuffix(sent[0].Instructions, "Speak in French.") {
+		t.Errorf("payload voice=%q instructions=%q", sent[0].Voice, sent[0].Instructions)
+	}
+
+	// Without an override the same call uses the engine's narrator.
+	if _, err := convertTextToAudioMultiVoice(context.Background(), "Il pleuvait.", 2, 0, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if p := payloads()[1]; p.Voice != openaiEngine.NarratorVoice || strings.Contains(p.Instructions, "Speak in") {
+		t.Errorf("default payload voice=%q instructions=%q", p.Voice, p.Instructions)
+	}
+	if openaiEngine.NarratorVoice != VoiceNarrator {
+		t.Errorf("override leaked into the engine: narrator %q", openaiEngine.NarratorVoice)
+	}
+}
+
+func TestTTSOverride_ElevenLanguageCode(t *testing.T) {
+	ctx := withTTSOverride(context.Background(), ttsOverride{Language: "de"})
+	req, err := buildTTSRequest(ctx, &elevenEngine, "k", "Hallo.", "voice1", "", 1, DialogueSegment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body elevenTTSPayload
+	raw, _ := io.ReadAll(req.Body)
+	json.Unmarshal(raw, &body)
+	if body.LanguageCode != "de" {
+		t.Errorf("language_code = %q, want de", body.LanguageCode)
+	}
+}
+
+func TestBatchTranscribe_RejectsInvalidOverride(t *testing.T) {
+	dryRunDB(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/books/:book_id/tts/batch", func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"user_id": float64(1), "account_type": "free"})
+		c.Set("book", Book{ID: 1, UserID: 1, TTSEngine: "kokoro"})
+	}, BatchTranscribeBookHandler)
+
+	for _, body := range []string{`{"voice":"alloy"}`, `{"language":"fr"}`, `{"voice":`} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/books/1/tts/batch", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", body, w.Code)
+		}
+	}
+}
+
+func TestBatchTranscribe_OverrideQueuedNotPersisted(t *testing.T) {
+	withTestDB(t)
+	book := Book{Title: "Override", Category: "Fiction", UserID: 1, Status: "pending", TTSEngine: "openai"}
+	if err := db.Create(&book).Error; err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&BookChunk{BookID: book.ID, Index: 0, Content: "page", TTSStatus: "pending"})
+
+	var queued ttsOverride
+	orig := enqueueTranscribeBatch
+	enqueueTranscribeBatch = func(_ uint, _, _ int, _ uint, _ string, ov ttsOverride) error {
+		queued = ov
+		return nil
+	}
+	t.Cleanup(func() { enqueueTranscribeBatch = orig })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/books/:book_id/tts/batch", func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"user_id": float64(1), "account_type": "free"})
+		c.Set("book", book)
+	}, BatchTranscribeBookHandler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/books/1/tts/batch", strings.NewReader(`{"voice":"nova","language":"FR"}`)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if queued != (ttsOverride{Voice: "nova", Language: "fr"}) {
+		t.Errorf("queued override = %+v", queued)
+	}
+
+	var stored Book
+	db.First(&stored, book.ID)
+	if stored.TTSEngine != "openai" || stored.VoiceMap != book.VoiceMap {
+		t.Errorf("book voice settings changed: engine=%q voice_map=%q", stored.TTSEngine, stored.VoiceMap)
+	}
+}
+
+func TestTaskTranscribeBatch_CarriesOverride(t *testing.T) {
+	raw, _ := json.Marshal(TaskTranscribeBatch{BookID: 1, Voice: "nova", Language: "fr"})
+	var p TaskTranscribeBatch
+	json.Unmarshal(raw, &p)
+	if p.override() != (ttsOverride{Voice: "nova", Language: "fr"}) {
+		t.Errorf("override after round trip = %+v", p.override())
+	}
+	raw, _ = json.Marshal(TaskTranscribeBatch{BookID: 1})
+	if strings.Contains(string(raw), "voice") {
+		t.Errorf("empty override serialized: %s", raw)
+	}
+}
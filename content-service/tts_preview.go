@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Voice previews. POST /user/books/:book_id/preview renders a short sample —
+// the opening of the book's first page, or text the caller supplies — with a
+// chosen narrator voice and speed on the book's engine, so a listener can
+// audition before transcribing the whole book. A preview never touches
+// chunk state. A fresh render streams to the caller as it is synthesized and
+// is charged to the transcription budget like any other synthesis; identical
+// previews are served from a short-lived cache for free.
+
+// previewCacheDir holds rendered previews; swappable in tests.
+var previewCacheDir = "./audio/previews"
+
+// previewSynthesize renders one preview to path while streaming it to w;
+// swappable in tests.
+var previewSynthesize = streamSpeech
+
+// previewMaxBytes caps preview text (TTS_PREVIEW_MAX_BYTES, default 600).
+func previewMaxBytes() int { return envInt("TTS_PREVIEW_MAX_BYTES", 600) }
+
+// previewCacheTTL is how long an identical preview is served from cache
+// (TTS_PREVIEW_CACHE_MINUTES, default 30).
+func previewCacheTTL() time.Duration {
+	return time.Duration(envInt("TTS_PREVIEW_CACHE_MINUTES", 30)) * time.Minute
+}
+
+type previewRequest struct {
+	Text  string  `json:"text"`  // optional; defaults to the book's first page
+	Voice string  `json:"voice"` // optional; defaults to the engine's narrator
+	Speed float64 `json:"speed"` // optional; 0.5–2.0, default 1.0
+}
+
+// engineVoices lists every voice cfg can render.
+func engineVoices(cfg *ttsEngineConfig) []string {
+	if cfg.Voices != nil {
+		return cfg.Voices
+	}
+	seen := map[string]bool{}
+	var out []string
+	for _, pool := range [][]string{{cfg.NarratorVoice, cfg.UnknownVoice}, cfg.MalePool, cfg.FemalePool, cfg.UnknownPool} {
+		for _, v := range pool {
+			if v != "" && !seen[v] {
+				seen[v] = true
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+// resolvePreviewSettings validates req against cfg and fills the defaults.
+func resolvePreviewSettings(req previewRequest, cfg *ttsEngineConfig) (voice string, speed float64, err error) {
+	voice = strings.TrimSpace(req.Voice)
+	if voice == "" {
+		voice = cfg.NarratorVoice
+	}
+	if !engineHasVoice(cfg, voice) {
+		return "", 0, fmt.Errorf("unknown voice %q for the %s engine", voice, cfg.Name)
+	}
+	speed = req.Speed
+	if speed == 0 {
+		speed = 1.0
+	}
+	if speed < 0.5 || speed > 2.0 {
+		return "", 0, fmt.Errorf("speed must be between 0.5 and 2.0")
+	}
+	return voice, speed, nil
+}
+
+// previewCachePath names the cached preview for one engine/voice/speed/text.
+func previewCachePath(cfg *ttsEngineConfig, voice string, speed float64, text string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%.2f\x00%s", cfg.Name, voice, speed, text)))
+	return filepath.Join(previewCacheDir, "preview_"+hex.EncodeToString(sum[:16])+".mp3")
+}
+
+// cachedPreview reports whether path holds a preview young enough to serve.
+func cachedPreview(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Size() > 0 && time.Since(info.ModTime()) < previewCacheTTL()
+}
+
+// previewText is the sample to render: the caller's text, or the opening of
+// the book's first page cut at a sentence or word boundary.
+func previewText(bookID uint, supplied string) (string, error) {
+	if text := strings.TrimSpace(supplied); text != "" {
+		if len(text) > previewMaxBytes() {
+			return "", fmt.Errorf("preview text is limited to %d bytes", previewMaxBytes())
+		}
+		return text, nil
+	}
+	var first BookChunk
+	if err := db.Where("book_id = ?", bookID).Order("index ASC").First(&first).Error; err != nil {
+		return "", err
+	}
+	text := strings.TrimSpace(first.Content)
+	if text == "" {
+		return "", fmt.Errorf("book has no text to preview")
+	}
+	return splitForTTS(text, previewMaxBytes())[0], nil
+}
+
+// previewBookHandler renders (or serves from cache) a voice sample for a book.
+// POST /user/books/:book_id/preview
+func previewBookHandler(c *gin.Context) {
+	book := c.MustGet("book").(Book)
+	userID := getUserIDFromContext(c)
+
+	var req previewRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid request body")
+			return
+		}
+	}
+	cfg := engineFor(book)
+	voice, speed, err := resolvePreviewSettings(req, cfg)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+	text, err := previewText(book.ID, req.Text)
+	if err != nil {
+		if req.Text != "" {
+			respondError(c, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		} else {
+			respondError(c, http.StatusUnprocessableEntity, errCodeUnprocessable, "Book has no text to preview yet")
+		}
+		return
+	}
+	if cfg.ExpandTitles {
+		text = expandTitleAbbreviations(text)
+	}
+
+	path := previewCachePath(cfg, voice, speed, text)
+	if cachedPreview(path) {
+		c.Header("X-Preview-Cache", "hit")
+		c.File(path)
+		return
+	}
+
+	apiKey := cfg.APIKey()
+	if apiKey == "" {
+		respondError(c, http.StatusServiceUnavailable, errCodeServiceUnavailable, cfg.Name+" TTS is not configured")
+		return
+	}
+	charge, qerr := consumeFreshTranscription(userID, accountTypeFromClaims(c), book.ID)
+	if qerr != nil {
+		quota429(c, checkAndConsume(userID, accountTypeFromClaims(c), "transcribe_seconds", 0, book.ID))
+		return
+	}
+	instructions := ""
+	if cfg.SupportsInstructions {
+		instructions = narratorInstructions
+	}
+	if err := os.MkdirAll(previewCacheDir, 0755); err != nil {
+		respondInternal(c, "Could not prepare preview", err)
+		return
+	}
+	// Opportunistic sweep keeps the cache bounded without a janitor loop.
+	sweepTempFiles(previewCacheDir, []string{"preview_*.mp3"}, previewCacheTTL(), nil)
+
+	// A miss is streamed: the listener hears the first bytes as soon as the
+	// provider sends them instead of waiting for the whole render, and the
+	// same bytes land in the cache.
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+	defer cancel()
+	c.Header("X-Preview-Cache", "miss")
+	c.Header("Content-Type", "audio/mpeg")
+	out := &flushingWriter{c: c}
+	if err := previewSynthesize(ctx, cfg, apiKey, text, voice, instructions, speed, DialogueSegment{Type: "narrator"}, path, out); err != nil {
+		log.Printf("⚠️ preview for book %d (%s/%s) failed: %v", book.ID, cfg.Name, voice, err)
+		if out.started {
+			c.Abort() // mid-stream: the client sees a truncated body
+			return
+		}
+		c.Header("X-Preview-Cache", "")
+		respondError(c, http.StatusBadGateway, errCodeUpstream, "Could not render preview")
+		return
+	}
+	if dur, err := getTTSDuration(path); err == nil {
+		charge(dur)
+	}
+}
+
+// flushingWriter writes a 200 on the first byte and flushes every write
+// through to the client.
+type flushingWriter struct {
+	c       *gin.Context
+	started bool
+}
+
+func (w *flushingWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.c.Status(http.StatusOK)
+	}
+	n, err := w.c.Writer.Write(p)
+	w.c.Writer.Flush()
+	return n, err
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestResolvePreviewSettings(t *testing.T) {
+	voice, speed, err := resolvePreviewSettings(previewRequest{}, &openaiEngine)
+	if err != nil || voice != openaiEngine.NarratorVoice || speed != 1.0 {
+		t.Fatalf("defaults = %q, %v, %v", voice, speed, err)
+	}
+	if voice, _, err := resolvePreviewSettings(previewRequest{Voice: openaiEngine.FemalePool[0], Speed: 1.25}, &openaiEngine); err != nil || voice != openaiEngine.FemalePool[0] {
+		t.Fatalf("pool voice = %q, %v", voice, err)
+	}
+	for _, req := range []previewRequest{{Voice: "no-such-voice"}, {Speed: 0.1}, {Speed: 3}} {
+		if _, _, err := resolvePreviewSettings(req, &openaiEngine); err == nil {
+			t.Errorf("resolvePreviewSettings(%+v) accepted", req)
+		}
+	}
+}
+
+// stubPreviewSynthesis renders previews into a temp cache as fixed bytes and
+// counts the renders.
+func stubPreviewSynthesis(t *testing.T) *int {
+	t.Helper()
+	origDir, origSynth, origRDB := previewCacheDir, previewSynthesize, rdb
+	previewCacheDir = t.TempDir()
+	renders := 0
+	previewSynthesize = func(_ context.Context, _ *ttsEngineConfig, _, text, _, _ string, _ float64, _ DialogueSegment, path string, w io.Writer) error {
+		renders++
+		w.Write([]byte("ID3 " + text))
+		return os.WriteFile(path, []byte("ID3 "+text), 0644)
+	}
+	// Quota counters fail open when Redis is unreachable.
+	rdb = redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	t.Setenv("OPENAI_API_KEY", "test")
+	t.Cleanup(func() { previewCacheDir, previewSynthesize, rdb = origDir, origSynth, origRDB })
+	return &renders
+}
+
+func previewRouter(book Book) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/user/books/:book_id/preview", func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"user_id": float64(book.UserID), "account_type": "free"})
+		c.Set("book", book)
+	}, previewBookHandler)
+	return r
+}
+
+func postPreview(r http.Handler, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/user/books/1/preview", strings.NewReader(body)))
+	return w
+}
+
+func TestPreviewBook_RendersAudioWithoutWritingChunks(t *testing.T) {
+	stmts := dryRunDB(t)
+	renders := stubPreviewSynthesis(t)
+	r := previewRouter(Book{ID: 1, UserID: 7})
+
+	body := `{"text":"Call me Ishmael.","voice":"` + openaiEngine.NarratorVoice + `"}`
+	w := postPreview(r, body)
+	if w.Code != http.StatusOK || w.Body.String() != "ID3 Call me Ishmael." || w.Header().Get("X-Preview-Cache") != "miss" {
+		t.Fatalf("preview: %d %q cache=%s", w.Code, w.Body, w.Header().Get("X-Preview-Cache"))
+	}
+	if w := postPreview(r, body); w.Code != http.StatusOK || w.Header().Get("X-Preview-Cache") != "hit" {
+		t.Fatalf("repeat preview: %d cache=%s", w.Code, w.Header().Get("X-Preview-Cache"))
+	}
+	if *renders != 1 {
+		t.Fatalf("rendered %d times, want 1 (second served from cache)", *renders)
+	}
+	for _, s := range *stmts {
+		if strings.HasPrefix(s, "UPDATE") {
+			t.Fatalf("preview wrote to the database: %s", s)
+		}
+	}
+
+	if w := postPreview(r, `{"text":"`+strings.Repeat("a", previewMaxBytes()+1)+`"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("over-long text: %d, want 400", w.Code)
+	}
+}
+
+func TestPreviewBook_FirstPageLeavesChunkStatus(t *testing.T) {
+	withTestDB(t)
+	renders := stubPreviewSynthesis(t)
+	book := Book{Title: "Moby-Dick", Category: "Fiction", UserID: 7}
+	db.Create(&book)
+	db.Create(&BookChunk{BookID: book.ID, Index: 1, Content: "Second page.", TTSStatus: "pending"})
+	db.Create(&BookChunk{BookID: book.ID, Index: 0, Content: "Call me Ishmael. Some years ago.", TTSStatus: "pending"})
+
+	w := postPreview(previewRouter(book), "")
+	if w.Code != http.StatusOK || w.Body.String() != "ID3 Call me Ishmael. Some years ago." || *renders != 1 {
+		t.Fatalf("preview: %d %q (%d renders)", w.Code, w.Body, *renders)
+	}
+	var changed int64
+	db.Model(&BookChunk{}).Where("book_id = ? AND tts_status <> ?", book.ID, "pending").Count(&changed)
+	if changed != 0 {
+		t.Fatalf("%d chunks changed status after a preview", changed)
+	}
+}
+
+// A preview miss reaches the client while the provider is still sending: the
+// first bytes arrive before the upstream response has finished, and the cache
+// only gets the file once it has.
+func TestPreviewBook_StreamsBeforeUpstreamFinishes(t *testing.T) {
+	dryRunDB(t)
+	stubPreviewSynthesis(t)
+	previewSynthesize = streamSpeech // real provider path against the slow stub
+
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ID3 first"))
+		w.(http.Flusher).Flush()
+		<-release
+		w.Write([]byte(" rest"))
+	}))
+	defer upstream.Close()
+	defer func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	}()
+	origEndpoint := openaiEngine.Endpoint
+	openaiEngine.Endpoint = upstream.URL
+	t.Cleanup(func() { openaiEngine.Endpoint = origEndpoint })
+
+	srv := httptest.NewServer(previewRouter(Book{ID: 1, UserID: 7}))
+	defer srv.Close()
+	resp, err := http.Post(srv.URL+"/user/books/1/preview", "application/json", strings.NewReader(`{"text":"Call me Ishmael."}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Preview-Cache") != "miss" {
+		t.Fatalf("preview: %d cache=%s", resp.StatusCode, resp.Header.Get("X-Preview-Cache"))
+	}
+
+	first := make([]byte, len("ID3 first"))
+	if _, err := io.ReadFull(resp.Body, first); err != nil || string(first) != "ID3 first" {
+		t.Fatalf("first bytes = %q, %v", first, err)
+	}
+	path := previewCachePath(&openaiEngine, openaiEngine.NarratorVoice, 1.0, "Call me Ishmael.")
+	if _, err := os.Stat(path); err == nil {
+		t.Fatal("cache file exists before the upstream finished")
+	}
+
+	close(release)
+	rest, _ := io.ReadAll(resp.Body)
+	if string(rest) != " rest" {
+		t.Fatalf("rest of body = %q", rest)
+	}
+	if b, err := os.ReadFile(path); err != nil || string(b) != "ID3 first rest" {
+		t.Fatalf("cached preview = %q, %v", b, err)
+	}
+}
@@ -17,21 +17,19 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
-
-	"gorm.io/gorm"
 )
 
 const openaiTTSEndpoint = "https://api.openai.com/v1/audio/speech"
 
-// Voice constants for different speaker types
+// Voice constants for different speaker types (openai_voices.go)
 const (
-	VoiceNarrator = "alloy"  // Neutral voice for narration
-	VoiceMale     = "onyx"   // Deep male voice for male characters
-	VoiceFemale   = "nova"   // Female voice for female characters
+	VoiceNarrator = string(VoiceAlloy) // Neutral voice for narration
+	VoiceMale     = string(VoiceOnyx)  // Deep male voice for male characters
+	VoiceFemale   = string(VoiceNova)  // Female voice for female characters
 )
 
 type TTSPayload struct {
@@ -68,7 +66,7 @@ type DialogueAnalysis struct {
 
 // prepareNarratorText enhances raw text for expressive TTS narration
 // OpenAI TTS does NOT support SSML, so we use plain text with natural pauses
-func prepareNarratorText(rawText string) (string, error) {
+func prepareNarratorText(ctx context.Context, rawText string) (string, error) {
 	systemContent := `You are preparing text for an audiobook narrator. Your job is to enhance the text for natural, expressive reading.
 
 Rules:
@@ -83,7 +81,7 @@ Rules:
 Simply return the enhanced plain text ready to be read aloud.`
 
 	reqBody := ChatRequest{
-		Model: dialogueModel(), // audit L6: env-configurable
+		Model: narrationModel(), // audit L6: env-configurable
 		Messages: []ChatMessage{
 			{Role: "system", Content: systemContent},
 			{Role: "user", Content: rawText},
@@ -98,7 +96,7 @@ Simply return the enhanced plain text ready to be read aloud.`
 		return "", errors.New("OPENAI_API_KEY not set")
 	}
 
-	req, _ := http.NewRequest("POST", openAIChatURL, bytes.NewReader(bodyBytes))
+	req, _ := http.NewRequestWithContext(ctx, "POST", openAIChatURL, bytes.NewReader(bodyBytes))
 	req.Header.Set("Authorization", "Bearer "+apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
@@ -199,6 +197,10 @@ func cleanupForTTS(text string) string {
 	}
 	text = result.String()
 
+	// Japanese/Chinese, Cyrillic, Arabic and Hebrew punctuation and quotes →
+	// ASCII so they're voiced as pauses, not read aloud (text_scripts.go).
+	text = normalizeScriptPunctuation(text)
+
 	// Collapse ALL whitespace runs — including OCR mid-sentence line-wrap
 	// newlines — to a single space so the voice reads fluently. A stray "\n"
 	// inside a sentence ("A single man of\nlarge fortune") makes Kokoro pause
@@ -244,7 +246,7 @@ func stripVerseCitations(text string) string {
 
 // page. Pass empty cast/prevTail for context-free analysis. classicalSpeech
 // relaxes the quotes-only rule for scripture/epics (see usesClassicalSpeech).
-func analyzeDialogue(rawText, prevTail string, cast map[string]CharacterVoice, classicalSpeech bool) ([]DialogueSegment, error) {
+func analyzeDialogue(ctx context.Context, rawText, prevTail string, cast map[string]CharacterVoice, classicalSpeech bool) ([]DialogueSegment, error) {
 	apiKey := os.Getenv("OPENAI_API_KEY")
 	if apiKey == "" {
 		return nil, errors.New("OPENAI_API_KEY not set")
@@ -311,7 +313,7 @@ ADDITIONAL RULE for this book (takes precedence over rule 9 for reporting-verb s
 
 	bodyBytes, _ := json.Marshal(reqBody)
 
-	req, _ := http.NewRequest("POST", openAIChatURL, bytes.NewReader(bodyBytes))
+	req, _ := http.NewRequestWithContext(ctx, "POST", openAIChatURL, bytes.NewReader(bodyBytes))
 	req.Header.Set("Authorization", "Bearer "+apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
@@ -525,20 +527,27 @@ func emotionSpeed(emotion string) float64 {
 	}
 }
 
+// spokenSegmentText is the text generateSegmentAudio sends to TTS for
+// segment (and so what its transcript records).
+func spokenSegmentText(segment DialogueSegment, cfg *ttsEngineConfig) string {
+	text := cleanupForTTS(segment.Text)
+	if cfg.ExpandTitles {
+		text = expandTitleAbbreviations(text)
+	}
+	return text
+}
+
 // generateSegmentAudio generates audio for a single dialogue segment
-func generateSegmentAudio(segment DialogueSegment, bookID uint, segmentIndex int, cfg *ttsEngineConfig) (string, error) {
+func generateSegmentAudio(ctx context.Context, segment DialogueSegment, bookID uint, segmentIndex int, cfg *ttsEngineConfig) (string, error) {
 	apiKey := cfg.APIKey()
 	if apiKey == "" {
 		return "", errors.New(cfg.Name + " TTS API key not set")
 	}
 
-	text := cleanupForTTS(segment.Text)
+	text := spokenSegmentText(segment, cfg)
 	if strings.TrimSpace(text) == "" {
 		return "", nil // Skip empty segments
 	}
-	if cfg.ExpandTitles {
-		text = expandTitleAbbreviations(text)
-	}
 
 	voice := getVoiceForSegment(segment, cfg)
 	instructions := ""
@@ -558,47 +567,131 @@ func generateSegmentAudio(segment DialogueSegment, bookID uint, segmentIndex int
 
 	log.Printf("🎙️ Generating segment %d: engine=%s voice=%s, type=%s, speaker=%s, emotion=%s, speed=%.2f", segmentIndex, cfg.Name, voice, segment.Type, segment.Speaker, segment.Emotion, speed)
 
-	req, err := buildTTSRequest(cfg, apiKey, text, voice, instructions, speed, segment)
-	if err != nil {
-		return "", fmt.Errorf("create TTS request: %w", err)
+	if err := os.MkdirAll("./audio", 0755); err != nil {
+		return "", err
 	}
+	path := fmt.Sprintf("./audio/segment_%d_%d.mp3", bookID, segmentIndex)
 
-	client := &http.Client{Timeout: 120 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("TTS API request error: %w", err)
+	// Long segments (a monologue, or a whole page on the narrator fallback)
+	// are synthesized in sentence-aligned pieces under the provider's input
+	// limit and stitched back together.
+	parts := splitForTTS(text, ttsMaxInputBytes())
+	if len(parts) == 1 {
+		if err := synthesizeSpeech(ctx, cfg, apiKey, text, voice, instructions, speed, segment, path); err != nil {
+			return "", err
+		}
+		return path, nil
 	}
-	defer resp.Body.Close()
+	log.Printf("✂️ Segment %d is %d bytes — synthesizing in %d parts", segmentIndex, len(text), len(parts))
+	if err := synthesizeInParts(ctx, cfg, apiKey, parts, voice, instructions, speed, segment, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return "", fmt.Errorf("TTS API returned %d: %s", resp.StatusCode, body)
+// ttsMaxInputBytes caps the text sent in any single TTS request. OpenAI rejects
+// inputs over 4096 characters and Kokoro/Eleven degrade well before that; 2000
+// bytes matches the ceiling streamAudioByChunkIDsHandler already enforces.
+func ttsMaxInputBytes() int { return envInt("TTS_MAX_INPUT_BYTES", 2000) }
+
+// splitForTTS breaks text into pieces of at most maxBytes bytes, cutting at
+// sentence ends (falling back to word boundaries) via the page chunker's
+// wordSafeChunks. The chunker counts runes, so for multi-byte scripts the rune
+// budget is shrunk until every piece fits the byte limit.
+func splitForTTS(text string, maxBytes int) []string {
+	if maxBytes <= 0 || len(text) <= maxBytes {
+		return []string{text}
+	}
+	runes := []rune(text)
+	size := maxBytes
+	for {
+		var parts []string
+		fits := true
+		for _, sp := range wordSafeChunks(runes, size) {
+			p := strings.TrimSpace(string(runes[sp[0]:sp[1]]))
+			if p == "" {
+				continue
+			}
+			if len(p) > maxBytes {
+				fits = false
+				break
+			}
+			parts = append(parts, p)
+		}
+		if fits || size <= 1 {
+			return parts
+		}
+		size = size * 3 / 4
 	}
+}
 
-	if err := os.MkdirAll("./audio", 0755); err != nil {
-		return "", err
+// synthesizeInParts renders each piece with the same voice settings and
+// concatenates them into outputPath. Part files are always cleaned up.
+func synthesizeInParts(ctx context.Context, cfg *ttsEngineConfig, apiKey string, parts []string, voice, instructions string, speed float64, segment DialogueSegment, outputPath string) error {
+	base := strings.TrimSuffix(outputPath, filepath.Ext(outputPath))
+	partPaths := make([]string, 0, len(parts))
+	defer func() {
+		for _, p := range partPaths {
+			os.Remove(p)
+		}
+	}()
+	for i, part := range parts {
+		pp := fmt.Sprintf("%s_part%d.mp3", base, i)
+		if err := synthesizeSpeech(ctx, cfg, apiKey, part, voice, instructions, speed, segment, pp); err != nil {
+			return fmt.Errorf("part %d/%d: %w", i+1, len(parts), err)
+		}
+		partPaths = append(partPaths, pp)
 	}
+	return mergeAudioSegments(partPaths, outputPath)
+}
 
-	filename := fmt.Sprintf("segment_%d_%d.mp3", bookID, segmentIndex)
-	path := "./audio/" + filename
+// synthesizeSpeech performs one TTS request and writes the audio to path.
+func synthesizeSpeech(ctx context.Context, cfg *ttsEngineConfig, apiKey, text, voice, instructions string, speed float64, segment DialogueSegment, path string) error {
+	return streamSpeech(ctx, cfg, apiKey, text, voice, instructions, speed, segment, path, io.Discard)
+}
 
-	outFile, err := os.Create(path)
+// streamSpeech performs one TTS request, copying the audio to w as it arrives
+// from the provider while also writing it to path. path only appears once the
+// whole response is in, so a failed or abandoned stream never leaves a
+// truncated file behind; a write error on w aborts the request.
+func streamSpeech(ctx context.Context, cfg *ttsEngineConfig, apiKey, text, voice, instructions string, speed float64, segment DialogueSegment, path string, w io.Writer) error {
+	if err := awaitTTS(ctx); err != nil {
+		return err
+	}
+	req, err := buildTTSRequest(ctx, cfg, apiKey, text, voice, instructions, speed, segment)
 	if err != nil {
-		return "", fmt.Errorf("create audio file: %w", err)
+		return fmt.Errorf("create TTS request: %w", err)
 	}
-	defer outFile.Close()
 
-	if _, err := io.Copy(outFile, resp.Body); err != nil {
-		return "", fmt.Errorf("write audio: %w", err)
+	client := &http.Client{Timeout: 120 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("TTS API request error: %w", err)
 	}
+	defer resp.Body.Close()
 
-	return path, nil
+	if resp.StatusCode == http.StatusTooManyRequests {
+		body, _ := ioutil.ReadAll(resp.Body)
+		d := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		pauseTTS(d) // backpressure for every TTS caller in this process
+		return &ttsRateLimitError{RetryAfter: d, Body: string(body)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("TTS API returned %d: %s", resp.StatusCode, body)
+	}
+
+	if err := writeFileAtomic(path, io.TeeReader(resp.Body, w), 0644); err != nil {
+		return fmt.Errorf("write audio: %w", err)
+	}
+	return nil
 }
 
 // elevenTTSPayload is the ElevenLabs /text-to-speech request body.
 type elevenTTSPayload struct {
 	Text          string              `json:"text"`
 	ModelID       string              `json:"model_id"`
+	LanguageCode  string              `json:"language_code,omitempty"`
 	VoiceSettings elevenVoiceSettings `json:"voice_settings"`
 }
 
@@ -641,11 +734,13 @@ func elevenEmotionTag(emotion string) string {
 // buildTTSRequest constructs the provider-specific HTTP request for one segment.
 // OpenAI-compatible engines (OpenAI, Kokoro) share one JSON shape; ElevenLabs
 // uses a per-voice URL, an xi-api-key header, and inline emotion tags.
-func buildTTSRequest(cfg *ttsEngineConfig, apiKey, text, voice, instructions string, speed float64, segment DialogueSegment) (*http.Request, error) {
+func buildTTSRequest(ctx context.Context, cfg *ttsEngineConfig, apiKey, text, voice, instructions string, speed float64, segment DialogueSegment) (*http.Request, error) {
+	lang := ttsOverrideFrom(ctx).Language
 	if cfg.Provider == "elevenlabs" {
 		body := elevenTTSPayload{
-			Text:    elevenEmotionTag(segment.Emotion) + text,
-			ModelID: cfg.Model,
+			Text:         elevenEmotionTag(segment.Emotion) + text,
+			ModelID:      cfg.Model,
+			LanguageCode: lang,
 			VoiceSettings: elevenVoiceSettings{
 				Stability:       0.5,
 				SimilarityBoost: 0.75,
@@ -655,7 +750,7 @@ func buildTTSRequest(cfg *ttsEngineConfig, apiKey, text, voice, instructions str
 		}
 		raw, _ := json.Marshal(body)
 		url := strings.TrimRight(cfg.Endpoint, "/") + "/" + voice + "?output_format=mp3_44100_128"
-		req, err := http.NewRequest("POST", url, bytes.NewReader(raw))
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(raw))
 		if err != nil {
 			return nil, err
 		}
@@ -665,6 +760,9 @@ func buildTTSRequest(cfg *ttsEngineConfig, apiKey, text, voice, instructions str
 		return req, nil
 	}
 
+	if lang != "" && cfg.SupportsInstructions {
+		instructions = strings.TrimSpace(instructions + "\nSpeak in " + ttsLanguages[lang] + ".")
+	}
 	payload := TTSPayload{
 		Input:          text,
 		Model:          cfg.Model,
@@ -674,7 +772,7 @@ func buildTTSRequest(cfg *ttsEngineConfig, apiKey, text, voice, instructions str
 		Speed:          speed,
 	}
 	raw, _ := json.Marshal(payload)
-	req, err := http.NewRequest("POST", cfg.Endpoint, bytes.NewReader(raw))
+	req, err := http.NewRequestWithContext(ctx, "POST", cfg.Endpoint, bytes.NewReader(raw))
 	if err != nil {
 		return nil, err
 	}
@@ -691,47 +789,45 @@ func mergeAudioSegments(segmentPaths []string, outputPath string) error {
 
 	if len(segmentPaths) == 1 {
 		// Just copy the single file
-		input, err := os.ReadFile(segmentPaths[0])
+		input, err := os.Open(segmentPaths[0])
 		if err != nil {
 			return err
 		}
-		return os.WriteFile(outputPath, input, 0644)
+		defer input.Close()
+		return writeFileAtomic(outputPath, input, 0644)
 	}
 
-	// Create a file list for FFmpeg concat. Use a unique name in ./audio (the
-	// concat list resolves entries relative to its own dir) so concurrent
-	// merges don't clobber a shared list (B4).
+	// Create a file list for FFmpeg concat. Use a unique name in ./audio so
+	// concurrent merges don't clobber a shared list (B4).
 	listFile, err := os.CreateTemp("./audio", "concat_list_*.txt")
 	if err != nil {
 		return fmt.Errorf("create concat list: %w", err)
 	}
 	listPath := listFile.Name()
 	listFile.Close()
+	defer os.Remove(listPath)
 	var listContent strings.Builder
 	for _, path := range segmentPaths {
-		// Extract just the filename since concat list is relative to its location
-		// path is like "./audio/segment_X_Y.mp3", we need just "segment_X_Y.mp3"
-		filename := path
-		if strings.HasPrefix(path, "./audio/") {
-			filename = strings.TrimPrefix(path, "./audio/")
-		} else if idx := strings.LastIndex(path, "/"); idx >= 0 {
-			filename = path[idx+1:]
+		entry, err := concatListEntry(path)
+		if err != nil {
+			return fmt.Errorf("create concat list: %w", err)
 		}
-		listContent.WriteString(fmt.Sprintf("file '%s'\n", filename))
+		listContent.WriteString(entry)
 	}
 	if err := os.WriteFile(listPath, []byte(listContent.String()), 0644); err != nil {
 		return fmt.Errorf("create concat list: %w", err)
 	}
-	defer os.Remove(listPath)
 
-	// Re-encode (not -c copy) to a uniform 24 kHz mono mp3. Segments may come
+	// Re-encode (not -c copy) to a uniform 24 kHz mono file in the output's
+	// format (by extension; mp3 for TTS intermediates). Segments may come
 	// from different TTS engines in hybrid mode (Kokoro narration at 56 kbps +
 	// OpenAI dialogue at 128 kbps), and stream-copying mixed bitrates can leave
 	// audible clicks at segment seams. A single re-encode guarantees clean,
 	// gapless boundaries; quality loss at -q:a 2 is inaudible.
-	cmd := exec.Command("ffmpeg", "-y", "-f", "concat", "-safe", "0", "-i", listPath,
-		"-c:a", "libmp3lame", "-ar", "24000", "-ac", "1", "-q:a", "2", outputPath)
-	output, err := cmd.CombinedOutput()
+	args := []string{"-y", "-f", "concat", "-safe", "0", "-i", listPath}
+	args = append(args, audioFormatForPath(outputPath).CodecArgs...)
+	args = append(args, "-ar", "24000", "-ac", "1")
+	output, err := runFFmpegAtomic(outputPath, args...)
 	if err != nil {
 		return fmt.Errorf("ffmpeg concat failed: %w, output: %s", err, string(output))
 	}
@@ -740,20 +836,34 @@ func mergeAudioSegments(segmentPaths []string, outputPath string) error {
 	return nil
 }
 
+// concatListEntry is one line of an FFmpeg concat list. FFmpeg resolves
+// relative entries against the list's own directory rather than the working
+// directory, so every segment is written as an absolute path (quotes escaped
+// per the concat syntax); -safe 0 lets FFmpeg accept them.
+func concatListEntry(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("file '%s'\n", strings.ReplaceAll(abs, "'", `'\''`)), nil
+}
+
 // convertTextToAudioForChunk is the chunk-aware TTS entry point (Phase 3).
 // It carries the book's persisted cast into dialogue analysis and the tail of
 // the previous chunk for cross-page speaker attribution, so characters keep
-// one voice for the whole book (audit H1).
-func convertTextToAudioForChunk(chunk BookChunk) (string, error) {
+// one voice for the whole book (audit H1). ctx bounds every outbound TTS and
+// GPT call: cancelling it aborts the page mid-flight instead of finishing
+// (and paying for) audio nobody is waiting on.
+func convertTextToAudioForChunk(ctx context.Context, chunk BookChunk) (string, error) {
 	vm := loadVoiceMap(chunk.BookID)
 	prevTail := prevChunkTail(chunk.BookID, chunk.Index, 400)
-	return convertTextToAudioMultiVoice(chunk.Content, chunk.ID, chunk.BookID, prevTail, vm)
+	return convertTextToAudioMultiVoice(ctx, chunk.Content, chunk.ID, chunk.BookID, prevTail, vm)
 }
 
 // convertTextToAudioMultiVoice converts text to audio with different voices
 // for characters. audioID names the output file (callers pass the chunk ID);
 // bookID==0 disables voice-map persistence (legacy/context-free path).
-func convertTextToAudioMultiVoice(text string, audioID uint, bookID uint, prevTail string, vm map[string]CharacterVoice) (string, error) {
+func convertTextToAudioMultiVoice(ctx context.Context, text string, audioID uint, bookID uint, prevTail string, vm map[string]CharacterVoice) (string, error) {
 	log.Printf("🎭 Starting multi-voice TTS for audio %d (book %d, cast %d)", audioID, bookID, len(vm))
 	if vm == nil {
 		vm = map[string]CharacterVoice{}
@@ -771,6 +881,8 @@ func convertTextToAudioMultiVoice(text string, audioID uint, bookID uint, prevTa
 			cfg = engineFor(book) // bake-off July 18: engine pinned per book
 		}
 	}
+	// A one-off job may narrate in another voice (tts_override.go).
+	cfg = ttsOverrideFrom(ctx).narrating(cfg)
 	if classical {
 		// Verse citations ("Genesis 1:17\t") are metadata — never narrated,
 		// and stripping them BEFORE analysis keeps the coverage guard honest.
@@ -778,16 +890,31 @@ func convertTextToAudioMultiVoice(text string, audioID uint, bookID uint, prevTa
 		prevTail = stripVerseCitations(prevTail)
 	}
 
+	// The single-voice fallback reads one narrator segment; on the chunk path
+	// its transcript is that whole text.
+	singleVoice := func() (string, error) {
+		path, spoken, err := convertTextToAudioSingleVoice(ctx, text, audioID, cfg)
+		if err == nil && bookID != 0 {
+			saveTranscript(audioID, buildTranscript([]DialogueSegment{{Type: "narrator"}}, []string{spoken}))
+		}
+		return path, err
+	}
+
 	// Step 1: Analyze dialogue to identify speakers and genders
-	segments, err := analyzeDialogue(text, prevTail, vm, classical)
+	segments, err := analyzeDialogue(ctx, text, prevTail, vm, classical)
+	if ctx.Err() != nil {
+		// Caller gave up (client disconnect / deadline) — don't fall back
+		// into more paid calls nobody will hear.
+		return "", ctx.Err()
+	}
 	if err != nil {
 		log.Printf("⚠️ Dialogue analysis failed, falling back to single voice: %v", err)
-		return convertTextToAudioSingleVoice(text, audioID, cfg)
+		return singleVoice()
 	}
 
 	if len(segments) == 0 {
 		log.Printf("⚠️ No segments found, falling back to single voice")
-		return convertTextToAudioSingleVoice(text, audioID, cfg)
+		return singleVoice()
 	}
 
 	// Hybrid rendering: narration on the base engine (cheap), dialogue on the
@@ -807,7 +934,9 @@ func convertTextToAudioMultiVoice(text string, audioID uint, bookID uint, prevTa
 
 	// Step 2: Generate audio for each segment
 	var segmentPaths []string
-	segTexts := []string{} // spoken text per rendered segment (timing map, audit 2B)
+	var rendered []DialogueSegment // segments that made it into the audio, in order
+	var spoken []string            // text sent to TTS for each of them (transcript.go)
+	segTexts := []string{}         // spoken text per rendered segment (timing map, audit 2B)
 	segDurs := []float64{} // measured duration per rendered segment
 	for i, segment := range segments {
 		if strings.TrimSpace(segment.Text) == "" {
@@ -818,13 +947,25 @@ func convertTextToAudioMultiVoice(text string, audioID uint, bookID uint, prevTa
 		if segment.IsDialogue {
 			segCfg = dlgCfg // route character lines to the expressive engine
 		}
-		path, err := generateSegmentAudio(segment, audioID, i, segCfg)
+		path, err := generateSegmentAudio(ctx, segment, audioID, i, segCfg)
+		_, limited := ttsRetryAfter(err)
+		if ctx.Err() != nil || limited {
+			for _, p := range segmentPaths {
+				os.Remove(p)
+			}
+			if limited {
+				return "", err // a page with a dropped segment would be stored as complete
+			}
+			return "", ctx.Err()
+		}
 		if err != nil {
 			log.Printf("⚠️ Failed to generate segment %d: %v", i, err)
 			continue
 		}
 		if path != "" {
 			segmentPaths = append(segmentPaths, path)
+			rendered = append(rendered, segment)
+			spoken = append(spoken, spokenSegmentText(segment, segCfg))
 			if segTexts != nil {
 				if d, derr := getTTSDuration(path); derr == nil && d > 0 {
 					segTexts = append(segTexts, segment.Text)
@@ -840,7 +981,7 @@ func convertTextToAudioMultiVoice(text string, audioID uint, bookID uint, prevTa
 
 	if len(segmentPaths) == 0 {
 		log.Printf("⚠️ No audio segments generated, falling back to single voice")
-		return convertTextToAudioSingleVoice(text, audioID, cfg)
+		return singleVoice()
 	}
 
 	// Step 3: Merge all segments into final audio
@@ -853,6 +994,9 @@ func convertTextToAudioMultiVoice(text string, audioID uint, bookID uint, prevTa
 		log.Printf("⚠️ Failed to merge segments: %v", err)
 		// Try to return the first segment at least
 		if len(segmentPaths) > 0 {
+			if bookID != 0 {
+				saveTranscript(audioID, buildTranscript(rendered[:1], spoken[:1]))
+			}
 			return segmentPaths[0], nil
 		}
 		return "", err
@@ -869,15 +1013,28 @@ func convertTextToAudioMultiVoice(text string, audioID uint, bookID uint, prevTa
 		saveTimingMap(audioID, buildTimingMap(segTexts, segDurs))
 		log.Printf("🕐 [Timing] chunk %d: %d-segment timing map saved", audioID, len(segTexts))
 	}
+	if bookID != 0 {
+		saveTranscript(audioID, buildTranscript(rendered, spoken))
+	}
 
 	log.Printf("✅ Multi-voice TTS completed for audio %d: %s", audioID, finalPath)
 	return finalPath, nil
 }
 
-// convertTextToAudioSingleVoice is the fallback single-voice TTS (original behavior)
-func convertTextToAudioSingleVoice(text string, bookID uint, cfg *ttsEngineConfig) (string, error) {
+// narratorInstructions steers instruction-capable engines on single-voice
+// narration (the fallback path and voice previews).
+const narratorInstructions = `You are an expressive audiobook narrator. Read with emotion and drama:
+- Pause naturally at sentence endings and paragraph breaks
+- Use varied pacing: slower for emotional moments, faster for action
+- Emphasize key words and phrases
+- Convey character emotions through tone
+- Add subtle pauses at ellipses (...)`
+
+// convertTextToAudioSingleVoice is the fallback single-voice TTS (original
+// behavior). It also returns the text it narrated, for the transcript.
+func convertTextToAudioSingleVoice(ctx context.Context, text string, bookID uint, cfg *ttsEngineConfig) (string, string, error) {
 	// Prepare text for narration
-	narratorText, err := prepareNarratorText(text)
+	narratorText, err := prepareNarratorText(ctx, text)
 	if err != nil {
 		log.Printf("⚠️ Text preparation failed, using original: %v", err)
 		narratorText = text
@@ -888,73 +1045,51 @@ func convertTextToAudioSingleVoice(text string, bookID uint, cfg *ttsEngineConfi
 
 	apiKey := cfg.APIKey()
 	if apiKey == "" {
-		return "", errors.New(cfg.Name + " TTS API key not set")
+		return "", "", errors.New(cfg.Name + " TTS API key not set")
 	}
 
 	instructions := ""
 	if cfg.SupportsInstructions {
-		instructions = `You are an expressive audiobook narrator. Read with emotion and drama:
-- Pause naturally at sentence endings and paragraph breaks
-- Use varied pacing: slower for emotional moments, faster for action
-- Emphasize key words and phrases
-- Convey character emotions through tone
-- Add subtle pauses at ellipses (...)`
-	}
-
-	payload := TTSPayload{
-		Input:          narratorText,
-		Model:          cfg.Model,
-		Voice:          cfg.NarratorVoice,
-		Instructions:   instructions,
-		ResponseFormat: "mp3",
-		Speed:          1.0,
-	}
-	reqBody, _ := json.Marshal(payload)
-
-	req, err := http.NewRequest("POST", cfg.Endpoint, bytes.NewReader(reqBody))
-	if err != nil {
-		return "", fmt.Errorf("create TTS request: %w", err)
-	}
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 120 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("TTS API request error: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return "", fmt.Errorf("TTS API returned %d: %s", resp.StatusCode, body)
+		instructions = narratorInstructions
 	}
 
 	if err := os.MkdirAll("./audio", 0755); err != nil {
-		return "", err
+		return "", "", err
 	}
+	path := fmt.Sprintf("./audio/audio_%d.mp3", bookID)
 
-	filename := fmt.Sprintf("audio_%d.mp3", bookID)
-	path := "./audio/" + filename
-
-	outFile, err := os.Create(path)
-	if err != nil {
-		return "", fmt.Errorf("create audio file: %w", err)
+	// GPT text prep can lengthen a page past the provider's input limit —
+	// synthesize in sentence-aligned parts when it does.
+	narrator := DialogueSegment{Type: "narrator"}
+	parts := splitForTTS(narratorText, ttsMaxInputBytes())
+	if len(parts) > 1 {
+		if err := synthesizeInParts(ctx, cfg, apiKey, parts, cfg.NarratorVoice, instructions, 1.0, narrator, path); err != nil {
+			return "", "", err
+		}
+		return path, narratorText, nil
 	}
-	defer outFile.Close()
-
-	if _, err := io.Copy(outFile, resp.Body); err != nil {
-		return "", fmt.Errorf("write audio: %w", err)
+	if err := synthesizeSpeech(ctx, cfg, apiKey, narratorText, cfg.NarratorVoice, instructions, 1.0, narrator, path); err != nil {
+		return "", "", err
 	}
-
-	return path, nil
+	return path, narratorText, nil
 }
 
 // convertTextToAudio is the legacy context-free entry point (kept only for
 // processBookConversion, which has no callers). Live paths use
 // convertTextToAudioForChunk for voice continuity.
-func convertTextToAudio(text string, audioID uint) (string, error) {
-	return convertTextToAudioMultiVoice(text, audioID, 0, "", nil)
+func convertTextToAudio(ctx context.Context, text string, audioID uint) (string, error) {
+	return convertTextToAudioMultiVoice(ctx, text, audioID, 0, "", nil)
+}
+
+// firstReusableAudio returns the first content-hash duplicate whose audio
+// still exists, so a conversion never adopts a path that was cleaned up.
+func firstReusableAudio(dups []Book, exists func(string) bool) (Book, bool) {
+	for _, d := range dups {
+		if d.AudioPath != "" && exists(d.AudioPath) {
+			return d, true
+		}
+	}
+	return Book{}, false
 }
 
 func processBookConversion(book Book) {
@@ -979,10 +1114,15 @@ func processBookConversion(book Book) {
 		}
 	}
 
-	// 2) Check if audio already exists for this content hash
-	var dup Book
-	err := db.Where("content_hash = ? AND audio_path IS NOT NULL AND audio_path <> ''", book.ContentHash).First(&dup).Error
-	if err == nil {
+	// 2) Check if audio already exists for this content hash. The donor's
+	// file may since have been deleted (user file cleanup, book delete), so
+	// only reuse a candidate whose audio still resolves — otherwise the new
+	// book would inherit a dead path and every stream would 404.
+	var dups []Book
+	err := db.Where("content_hash = ? AND audio_path IS NOT NULL AND audio_path <> '' AND id <> ?", book.ContentHash, book.ID).
+		Order("updated_at DESC").Find(&dups).Error
+	dup, reusable := firstReusableAudio(dups, func(p string) bool { return mediaExists(context.Background(), p) })
+	if err == nil && reusable {
 		log.Printf("🔁 Reusing audio from book ID %d for book ID %d", dup.ID, book.ID)
 		if err := db.Model(&Book{}).Where("id = ?", book.ID).Updates(Book{
 			AudioPath: dup.AudioPath,
@@ -990,9 +1130,12 @@ func processBookConversion(book Book) {
 		}).Error; err != nil {
 			log.Printf("⚠️ Error saving reused audio for book ID %d: %v", book.ID, err)
 		}
+		reuseCoverByContent(&book) // same book, same cover: skip the cover search
 		return
-	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+	} else if err != nil {
 		log.Printf("⚠️ Error checking for existing audio: %v", err)
+	} else if len(dups) > 0 {
+		log.Printf("🩹 %d duplicate(s) of book ID %d have missing audio — regenerating", len(dups), book.ID)
 	}
 
 	// 3) Read file content (FilePath may be an R2 key — localize first).
@@ -1011,7 +1154,7 @@ func processBookConversion(book Book) {
 	}
 
 	// 4) Convert to TTS
-	ttsPath, err := convertTextToAudio(string(contentBytes), book.ID)
+	ttsPath, err := convertTextToAudio(context.Background(), string(contentBytes), book.ID)
 	if err != nil {
 		log.Printf("🎙️ Error converting text to audio for book ID %d: %v", book.ID, err)
 		updateBookStatus(book.ID, "failed")
@@ -1048,7 +1191,7 @@ func processBookConversion(book Book) {
 		pageIndexes = append(pageIndexes, ch.Index)
 	}
 	log.Printf("🚀 Launching effects merge with hash: %s for book ID %d (%d pages)", book.ContentHash, book.ID, len(pageIndexes))
-	go processSoundEffectsAndMerge(book, book.ContentHash, pageIndexes)
+	go processSoundEffectsAndMerge(context.Background(), book, book.ContentHash, pageIndexes)
 }
 
 // updateBookStatus updates the status of a book in the database.
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// concatFFmpeg stands in for ffmpeg's concat demuxer: it resolves each list
+// entry the way ffmpeg does (relative to the list's directory) and writes
+// their bytes, in order, to the output.
+func concatFFmpeg(t *testing.T) {
+	t.Helper()
+	fakeFFmpeg(t)
+	script := filepath.Join(t.TempDir(), "ffmpeg")
+	body := `#!/bin/sh
+list=""; prev=""
+for a in "$@"; do
+	if [ "$prev" = "-i" ]; then list="$a"; fi
+	prev="$a"
+done
+dir=$(dirname "$list")
+sleep 0.05
+: > "$prev"
+sed -n "s/^file '\(.*\)'$/\1/p" "$list" | while read -r f; do
+	case "$f" in /*) cat "$f" ;; *) cat "$dir/$f" ;; esac >> "$prev"
+done
+`
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	ffmpegBin = script
+}
+
+func TestConcatListEntry(t *testing.T) {
+	t.Chdir(t.TempDir())
+	wd, _ := os.Getwd()
+	entry := func(path string) string {
+		e, err := concatListEntry(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	if got, want := entry("./audio/seg_1.mp3"), fmt.Sprintf("file '%s/audio/seg_1.mp3'\n", wd); got != want {
+		t.Errorf("relative entry = %q, want %q", got, want)
+	}
+	if got, want := entry("/tmp/it's.mp3"), `file '/tmp/it'\''s.mp3'`+"\n"; got != want {
+		t.Errorf("quoted entry = %q, want %q", got, want)
+	}
+}
+
+// Two conversions merging at once each get a concatenation of their own
+// segments, including segments that live outside ./audio.
+func TestMergeAudioSegments_ConcurrentMergesStaySeparate(t *testing.T) {
+	concatFFmpeg(t)
+	t.Chdir(t.TempDir())
+	os.MkdirAll("./audio", 0755)
+	elsewhere := t.TempDir()
+
+	var wg sync.WaitGroup
+	results := make([]string, 2)
+	errs := make([]error, 2)
+	want := make([]string, 2)
+	for n := 0; n < 2; n++ {
+		var segs []string
+		for i := 0; i < 3; i++ {
+			dir := "./audio"
+			if i == 2 {
+				dir = elsewhere
+			}
+			path := filepath.Join(dir, fmt.Sprintf("segment_%d_%d.mp3", n, i))
+			content := fmt.Sprintf("[conversion %d part %d]", n, i)
+			os.WriteFile(path, []byte(content), 0644)
+			segs = append(segs, path)
+			want[n] += content
+		}
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			out := fmt.Sprintf("./audio/audio_%d.mp3", n)
+			if errs[n] = mergeAudioSegments(segs, out); errs[n] == nil {
+				b, _ := os.ReadFile(out)
+				results[n] = string(b)
+			}
+		}(n)
+	}
+	wg.Wait()
+
+	for n := range results {
+		if errs[n] != nil {
+			t.Fatalf("merge %d: %v", n, errs[n])
+		}
+		if results[n] != want[n] {
+			t.Errorf("merge %d = %q, want %q", n, results[n], want[n])
+		}
+	}
+	if left, _ := filepath.Glob("./audio/concat_list_*.txt"); len(left) != 0 {
+		t.Errorf("concat lists left behind: %s", strings.Join(left, ", "))
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// TTS provider rate limits. A 429 from the TTS API is backpressure, not a
+// broken page: the page goes back to pending, every TTS request in this
+// process holds off until the provider's Retry-After has passed, and the
+// transcription batch waits and carries on (or, after repeated limits, is
+// re-queued by asynq after the same delay without counting as a failure).
+
+const (
+	defaultTTSRetryAfter = 30 * time.Second
+	maxTTSRetryAfter     = 10 * time.Minute
+	// maxRateLimitWaits is how many times a batch waits out a 429 in-process
+	// before handing the rest of the batch back to the queue.
+	maxRateLimitWaits = 3
+)
+
+// ttsRateLimitError is a 429 from the TTS provider.
+type ttsRateLimitError struct {
+	RetryAfter time.Duration
+	Body       string
+}
+
+func (e *ttsRateLimitError) Error() string {
+	return fmt.Sprintf("TTS API rate limited (retry after %s): %s", e.RetryAfter, e.Body)
+}
+
+// ttsRetryAfter reports whether err is (or wraps) a TTS rate limit, and how
+// long the provider asked us to wait.
+func ttsRetryAfter(err error) (time.Duration, bool) {
+	var rl *ttsRateLimitError
+	if errors.As(err, &rl) {
+		return rl.RetryAfter, true
+	}
+	return 0, false
+}
+
+// parseRetryAfter reads a Retry-After header (delta-seconds or HTTP-date),
+// falling back to defaultTTSRetryAfter and capping at maxTTSRetryAfter.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	d := defaultTTSRetryAfter
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		d = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(v); err == nil {
+		d = t.Sub(now)
+	}
+	if d <= 0 {
+		d = time.Second
+	}
+	return min(d, maxTTSRetryAfter)
+}
+
+var (
+	ttsPauseMu    sync.Mutex
+	ttsPauseUntil time.Time
+)
+
+// pauseTTS holds off every TTS request in this process for d.
+func pauseTTS(d time.Duration) {
+	ttsPauseMu.Lock()
+	defer ttsPauseMu.Unlock()
+	if until := time.Now().Add(d); until.After(ttsPauseUntil) {
+		ttsPauseUntil = until
+	}
+}
+
+// awaitTTS blocks until any rate-limit pause has passed or ctx is done.
+func awaitTTS(ctx context.Context) error {
+	ttsPauseMu.Lock()
+	wait := time.Until(ttsPauseUntil)
+	ttsPauseMu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	return sleepCtx(ctx, wait)
+}
+
+// sleepCtx sleeps for d, returning early with ctx's error if it is done.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// queueRetryDelay re-queues a rate-limited task after the provider's
+// Retry-After; everything else keeps asynq's default backoff.
+func queueRetryDelay(n int, err error, t *asynq.Task) time.Duration {
+	if d, ok := ttsRetryAfter(err); ok {
+		return d
+	}
+	return asynq.DefaultRetryDelayFunc(n, err, t)
+}
+
+// isQueueFailure keeps rate-limited tasks from using up their retries.
+func isQueueFailure(err error) bool {
+	if _, ok := ttsRetryAfter(err); ok {
+		log.Printf("⏳ task re-queued on TTS rate limit: %v", err)
+		return false
+	}
+	return err != nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+func resetTTSPause(t *testing.T) {
+	t.Cleanup(func() {
+		ttsPauseMu.Lock()
+		ttsPauseUntil = time.Time{}
+		ttsPauseMu.Unlock()
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	for in, want := range map[string]time.Duration{
+		"7":                             7 * time.Second,
+		"":                              defaultTTSRetryAfter,
+		"soon":                          defaultTTSRetryAfter,
+		"0":                             time.Second,
+		"86400":                         maxTTSRetryAfter,
+		"Fri, 02 Jan 2026 15:04:35 GMT": 30 * time.Second,
+	} {
+		if got := parseRetryAfter(in, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestStreamSpeech_RateLimitPausesTTS(t *testing.T) {
+	resetTTSPause(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "2")
+		http.Error(w, `{"error":"rate_limit_exceeded"}`, http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	cfg := openaiEngine
+	cfg.Endpoint = srv.URL
+
+	err := synthesizeSpeech(context.Background(), &cfg, "key", "Hello.", "alloy", "", 1.0, DialogueSegment{}, filepath.Join(t.TempDir(), "a.mp3"))
+	if d, ok := ttsRetryAfter(err); !ok || d != 2*time.Second {
+		t.Fatalf("synthesizeSpeech = %v, want a 2s rate limit", err)
+	}
+	// Until Retry-After passes, further requests wait instead of hitting the API.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := awaitTTS(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("awaitTTS during pause = %v", err)
+	}
+}
+
+func TestQueueRetryPolicy(t *testing.T) {
+	limited := fmt.Errorf("page 3: %w", &ttsRateLimitError{RetryAfter: 42 * time.Second})
+	if d := queueRetryDelay(1, limited, nil); d != 42*time.Second {
+		t.Errorf("retry delay = %s, want Retry-After", d)
+	}
+	if isQueueFailure(limited) {
+		t.Error("a rate limit counted as a failure")
+	}
+	if !isQueueFailure(os.ErrInvalid) {
+		t.Error("a real error not counted as a failure")
+	}
+}
+
+// A 429 on a page in the middle of a batch doesn't fail the job: the page is
+// retried after Retry-After and the batch completes.
+func TestTranscribeBatch_RetriesAfterRateLimit(t *testing.T) {
+	withTestDB(t)
+	fakeFFmpeg(t)
+	t.Chdir(t.TempDir())
+	t.Setenv("XI_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	origRDB, origStore, origSynth := rdb, store, chunkSynthesize
+	rdb = redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1}) // quota fails open
+	store = memStore{}
+	dir := t.TempDir()
+	calls := map[int]int{}
+	chunkSynthesize = func(_ context.Context, chunk BookChunk) (string, error) {
+		if calls[chunk.Index]++; chunk.Index == 1 && calls[chunk.Index] == 1 {
+			return "", fmt.Errorf("segment 0: %w", &ttsRateLimitError{RetryAfter: 10 * time.Millisecond})
+		}
+		path := filepath.Join(dir, fmt.Sprintf("chunk%d.mp3", chunk.Index))
+		return path, os.WriteFile(path, []byte("ID3 narration"), 0644)
+	}
+	t.Cleanup(func() { rdb, store, chunkSynthesize = origRDB, origStore, origSynth })
+
+	book := Book{Title: "Busy", Category: "Fiction", UserID: 1, Status: "transcribing"}
+	db.Create(&book)
+	for i := 0; i < 3; i++ {
+		db.Create(&BookChunk{BookID: book.ID, Index: i, Content: fmt.Sprintf("Page %d of a busy book.", i), TTSStatus: "pending"})
+	}
+
+	payload, _ := json.Marshal(TaskTranscribeBatch{BookID: book.ID, StartPage: 0, EndPage: batchSizePages - 1, UserID: 1, AccountType: "free"})
+	if err := handleTranscribeBatch(context.Background(), asynq.NewTask(TypeTranscribeBatch, payload)); err != nil {
+		t.Fatalf("batch failed on a rate limit: %v", err)
+	}
+	if calls[1] != 2 {
+		t.Fatalf("rate-limited page synthesized %d times, want a retry", calls[1])
+	}
+	db.First(&book, book.ID)
+	if book.Status != "completed" {
+		t.Fatalf("book status %q, want completed", book.Status)
+	}
+	var failed int64
+	db.Model(&BookChunk{}).Where("book_id = ? AND tts_status <> ?", book.ID, "completed").Count(&failed)
+	if failed != 0 {
+		t.Fatalf("%d pages not completed", failed)
+	}
+}
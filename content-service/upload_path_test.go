@@ -33,6 +33,10 @@ func TestValidUploadExt(t *testing.T) {
 		"My Book.EPUB":          ".epub",
 		"novel.AZW3":            ".azw3",
 		"weird.azw":             ".azw",
+		"Draft.DOCX":            ".docx",
+		"old.doc":               ".doc",
+		"page.htm":              ".htm",
+		"README.md":             ".md",
 		"../../etc/passwd.pdf":  ".pdf", // traversal in name → still just the ext
 		"/tmp/../x/story.txt":   ".txt",
 		"malware.kfx":           "",     // unsupported
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+func TestOwnsUploadKey(t *testing.T) {
+	for key, want := range map[string]bool{
+		"uploads/7/3/original.pdf":  true,
+		"uploads/7/31/original.pdf": false, // another book of the same user
+		"uploads/8/3/original.pdf":  false, // deduped onto another user's object
+		"legacy/7/3/original.pdf":   false,
+	} {
+		if got := ownsUploadKey(key, 7, 3); got != want {
+			t.Errorf("ownsUploadKey(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestAllowFileAttach(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		book    Book
+		replace bool
+		want    bool
+	}{
+		{Book{}, false, true},
+		{Book{FilePath: "uploads/1/1/original.pdf", Status: "awaiting_upload"}, false, true}, // presigned retry
+		{Book{FilePath: "uploads/1/1/original.pdf", Status: "completed"}, false, false},
+		{Book{FilePath: "uploads/1/1/original.pdf", Status: "completed"}, true, true},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		if got := allowFileAttach(c, tc.book, tc.replace); got != tc.want || (!got && w.Code != http.StatusConflict) {
+			t.Errorf("allowFileAttach(%+v, replace=%v) = %v (%d), want %v", tc.book, tc.replace, got, w.Code, tc.want)
+		}
+	}
+}
+
+// uploadRequest posts file as the multipart upload of bookID by userID.
+func uploadRequest(t *testing.T, userID, bookID uint, filename, content string, replace bool) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("book_id", fmt.Sprint(bookID))
+	if replace {
+		mw.WriteField("replace", "true")
+	}
+	fw, _ := mw.CreateFormFile("file", filename)
+	fw.Write([]byte(content))
+	mw.Close()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/user/books/upload", func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"user_id": float64(userID)})
+		uploadBookFileHandler(c)
+	})
+	req := httptest.NewRequest(http.MethodPost, "/user/books/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestUploadBookFile_RejectsAnotherUsersBook(t *testing.T) {
+	withTestDB(t)
+	t.Chdir(t.TempDir())
+	origStore := store
+	store = memStore{}
+	t.Cleanup(func() { store = origStore })
+
+	book := Book{Title: "Theirs", Category: "Fiction", UserID: 2}
+	db.Create(&book)
+	w := uploadRequest(t, 1, book.ID, "mine.txt", "Not your book.", false)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("upload to another user's book = %d %s", w.Code, w.Body)
+	}
+	db.First(&book, book.ID)
+	if book.FilePath != "" || len(store.(memStore)) != 0 {
+		t.Fatalf("file attached anyway: %q, store %v", book.FilePath, store)
+	}
+}
+
+func TestUploadBookFile_ReplaceCleansUpOldFile(t *testing.T) {
+	withTestDB(t)
+	t.Chdir(t.TempDir())
+	ms := memStore{}
+	origStore := store
+	store = ms
+	t.Cleanup(func() { store = origStore })
+
+	book := Book{Title: "Draft", Category: "Fiction", UserID: 1, Status: "completed"}
+	db.Create(&book)
+	oldKey := uploadKey(1, book.ID, ".pdf")
+	db.Model(&book).Update("file_path", oldKey)
+	ms[oldKey] = 100
+	ms["audio/old_page.mp3"] = 10
+	db.Create(&BookChunk{BookID: book.ID, Index: 0, Content: "Old first page.", AudioPath: "audio/old_page.mp3", TTSStatus: "completed"})
+
+	if w := uploadRequest(t, 1, book.ID, "draft2.txt", "The new text.", false); w.Code != http.StatusConflict {
+		t.Fatalf("second file without replace = %d %s", w.Code, w.Body)
+	}
+	if _, ok := ms[oldKey]; !ok {
+		t.Fatal("refused upload removed the old file")
+	}
+
+	w := uploadRequest(t, 1, book.ID, "draft2.txt", "The new text.", true)
+	if w.Code != http.StatusOK {
+		t.Fatalf("replace = %d %s", w.Code, w.Body)
+	}
+	newKey := uploadKey(1, book.ID, ".txt")
+	if _, ok := ms[oldKey]; ok {
+		t.Error("old upload still stored")
+	}
+	if _, ok := ms["audio/old_page.mp3"]; ok {
+		t.Error("old page audio still stored")
+	}
+	if _, ok := ms[newKey]; !ok {
+		t.Error("new upload not stored")
+	}
+	var chunks []BookChunk
+	db.Where("book_id = ?", book.ID).Find(&chunks)
+	if len(chunks) != 1 || !strings.Contains(chunks[0].Content, "The new text.") {
+		t.Fatalf("chunks after replace = %+v", chunks)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// SSRF guard for fetching user-supplied URLs (cover_url and friends). A URL is
+// only fetched if its host resolves exclusively to public unicast addresses
+// and, when COVER_HOST_ALLOWLIST is set (comma-separated host suffixes, e.g.
+// "covers.openlibrary.org,books.google.com"), matches the allowlist. The check
+// is repeated at dial time so DNS rebinding and redirects can't slip past it.
+
+var errBlockedURL = errors.New("url not allowed")
+
+// lookupHostIPs is swapped in tests to avoid real DNS.
+var lookupHostIPs = func(ctx context.Context, host string) ([]net.IP, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, a := range addrs {
+		ips = append(ips, a.IP)
+	}
+	return ips, nil
+}
+
+// cgnatNet is the carrier-grade NAT range, which net.IP.IsPrivate omits but
+// which is internal on most clouds.
+var cgnatNet = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPublicIP rejects loopback, private, link-local (incl. 169.254.169.254
+// metadata), unspecified, multicast and CGNAT addresses.
+func isPublicIP(ip net.IP) bool {
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || cgnatNet.Contains(ip) {
+		return false
+	}
+	return true
+}
+
+// hostAllowed applies COVER_HOST_ALLOWLIST (empty = any public host).
+func hostAllowed(host string) bool {
+	list := strings.TrimSpace(getEnv("COVER_HOST_ALLOWLIST", ""))
+	if list == "" {
+		return true
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, s := range strings.Split(list, ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s != "" && (host == s || strings.HasSuffix(host, "."+s)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOutboundURL validates a user-supplied URL before any outbound GET.
+func checkOutboundURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errBlockedURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme %q", errBlockedURL, u.Scheme)
+	}
+	host := u.Hostname()
+	if host == "" {
+		return fmt.Errorf("%w: missing host", errBlockedURL)
+	}
+	if !hostAllowed(host) {
+		return fmt.Errorf("%w: host %s not in allowlist", errBlockedURL, host)
+	}
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		if ips, err = lookupHostIPs(ctx, host); err != nil {
+			return fmt.Errorf("resolve %s: %w", host, err)
+		}
+	}
+	if len(ips) == 0 {
+		return fmt.Errorf("resolve %s: no addresses", host)
+	}
+	for _, ip := range ips {
+		if !isPublicIP(ip) {
+			return fmt.Errorf("%w: %s resolves to non-public %s", errBlockedURL, host, ip)
+		}
+	}
+	return nil
+}
+
+// safeHTTPClient is an http.Client for user-supplied URLs: every connection
+// is re-checked against isPublicIP at dial time and every redirect hop
+// re-validated with checkOutboundURL.
+func safeHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if !isPublicIP(net.ParseIP(host)) {
+				return fmt.Errorf("%w: dial to non-public %s", errBlockedURL, host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // a proxy would dial on our behalf, bypassing Control
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("stopped after 5 redirects")
+			}
+			return checkOutboundURL(req.Context(), req.URL.String())
+		},
+	}
+}
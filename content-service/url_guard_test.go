@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestCheckOutboundURL(t *testing.T) {
+	orig := lookupHostIPs
+	defer func() { lookupHostIPs = orig }()
+	fakeDNS := map[string]string{
+		"covers.openlibrary.org": "207.241.224.2",
+		"internal.example.com":   "10.0.0.12",
+		"rebind.example.com":     "127.0.0.1",
+	}
+	lookupHostIPs = func(_ context.Context, host string) ([]net.IP, error) {
+		if ip, ok := fakeDNS[host]; ok {
+			return []net.IP{net.ParseIP(ip)}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	ctx := context.Background()
+
+	blocked := []string{
+		"http://169.254.169.254/latest/meta-data/", // cloud metadata
+		"http://10.1.2.3/cover.jpg",                // private literal
+		"http://[::1]:8083/admin",                  // loopback v6
+		"http://internal.example.com/cover.jpg",    // resolves private
+		"http://rebind.example.com/cover.jpg",      // resolves loopback
+		"http://100.64.0.1/cover.jpg",              // CGNAT
+		"file:///etc/passwd",
+		"http:///nohost",
+	}
+	for _, u := range blocked {
+		if err := checkOutboundURL(ctx, u); !errors.Is(err, errBlockedURL) {
+			t.Errorf("checkOutboundURL(%q) = %v, want errBlockedURL", u, err)
+		}
+	}
+
+	public := "https://covers.openlibrary.org/b/id/12345-L.jpg"
+	if err := checkOutboundURL(ctx, public); err != nil {
+		t.Errorf("public CDN rejected: %v", err)
+	}
+
+	t.Setenv("COVER_HOST_ALLOWLIST", "openlibrary.org, books.google.com")
+	if err := checkOutboundURL(ctx, public); err != nil {
+		t.Errorf("allowlisted subdomain rejected: %v", err)
+	}
+	if err := checkOutboundURL(ctx, "https://8.8.8.8/x.jpg"); !errors.Is(err, errBlockedURL) {
+		t.Errorf("non-allowlisted host accepted: %v", err)
+	}
+	if hostAllowed("evilopenlibrary.org") {
+		t.Error("allowlist must match on a label boundary")
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// BookStorage is one book's footprint in GET /user/storage. Shared dedup
+// renderings (shared/audio/…) are not counted: they belong to every book that
+// reuses them and are never removed on the user's behalf. A shared cover is
+// counted — it is the book's only cover.
+type BookStorage struct {
+	BookID      uint   `json:"book_id"`
+	Title       string `json:"title"`
+	AudioBytes  int64  `json:"audio_bytes"`
+	AudioFiles  int    `json:"audio_files"`
+	UploadBytes int64  `json:"upload_bytes"`
+	CoverBytes  int64  `json:"cover_bytes"`
+	TotalBytes  int64  `json:"total_bytes"`
+}
+
+// localSize sums the on-disk size of legacy ./ paths, counting each once.
+func localSize(paths ...string) (int64, int) {
+	seen := map[string]bool{}
+	var total int64
+	n := 0
+	for _, p := range paths {
+		if p == "" || seen[p] || !isLegacyLocalPath(p) {
+			continue
+		}
+		seen[p] = true
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() {
+			total += fi.Size()
+			n++
+		}
+	}
+	return total, n
+}
+
+// prefixSize is store.PrefixSize, tolerating a missing store or list error
+// (storage accounting is informational — never fail the request on it).
+func prefixSize(ctx context.Context, prefix string) (int64, int) {
+	if store == nil {
+		return 0, 0
+	}
+	b, n, err := store.PrefixSize(ctx, prefix)
+	if err != nil {
+		log.Printf("⚠️ storage size for %s failed: %v", prefix, err)
+	}
+	return b, n
+}
+
+// measureBookStorage totals a book's audio (R2 audio/{id}/ tree + legacy
+// files), original upload and cover.
+func measureBookStorage(ctx context.Context, book Book, chunks []BookChunk, groups []ProcessedChunkGroup) BookStorage {
+	s := BookStorage{BookID: book.ID, Title: book.Title}
+
+	audioPaths := []string{book.AudioPath}
+	for _, ch := range chunks {
+		audioPaths = append(audioPaths, ch.AudioPath, ch.FinalAudioPath)
+	}
+	for _, g := range groups {
+		audioPaths = append(audioPaths, g.AudioPath)
+	}
+	s.AudioBytes, s.AudioFiles = localSize(audioPaths...)
+	b, n := prefixSize(ctx, fmt.Sprintf("audio/%d/", book.ID))
+	s.AudioBytes += b
+	s.AudioFiles += n
+
+	if isLegacyLocalPath(book.FilePath) {
+		s.UploadBytes, _ = localSize(book.FilePath)
+	} else if book.FilePath != "" {
+		s.UploadBytes, _ = prefixSize(ctx, fmt.Sprintf("uploads/%d/%d/", book.UserID, book.ID))
+	}
+	if isLegacyLocalPath(book.CoverPath) {
+		s.CoverBytes, _ = localSize(book.CoverPath)
+	} else if strings.HasPrefix(book.CoverPath, sharedCoverPrefix) {
+		s.CoverBytes, _ = prefixSize(ctx, book.CoverPath)
+	} else if book.CoverPath != "" {
+		s.CoverBytes, _ = prefixSize(ctx, fmt.Sprintf("covers/%d/", book.ID))
+	}
+
+	s.TotalBytes = s.AudioBytes + s.UploadBytes + s.CoverBytes
+	return s
+}
+
+// userStorageHandler (GET /user/storage) reports the caller's total storage
+// and a per-book breakdown so they can decide what to reclaim.
+func userStorageHandler(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	var books []Book
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&books).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load books")
+		return
+	}
+
+	ctx := c.Request.Context()
+	perBook := make([]BookStorage, 0, len(books))
+	var total int64
+	for _, b := range books {
+		var chunks []BookChunk
+		db.Select("audio_path", "final_audio_path").Where("book_id = ?", b.ID).Find(&chunks)
+		var groups []ProcessedChunkGroup
+		db.Select("audio_path").Where("book_id = ?", b.ID).Find(&groups)
+		s := measureBookStorage(ctx, b, chunks, groups)
+		total += s.TotalBytes
+		perBook = append(perBook, s)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total_bytes": total,
+		"books":       perBook,
+	})
+}
+
+// stripBookAudio clears every generated-audio reference on the book and its
+// chunks (text, upload and cover are kept) and returns the refs that were
+// cleared so the caller can delete the media after persisting.
+func stripBookAudio(book *Book, chunks []BookChunk) []string {
+	var refs []string
+	if book.AudioPath != "" {
+		refs = append(refs, book.AudioPath)
+	}
+	book.AudioPath = ""
+	for i := range chunks {
+		ch := &chunks[i]
+		refs = append(refs, ch.AudioPath, ch.FinalAudioPath)
+		ch.AudioPath, ch.FinalAudioPath, ch.HLSPath, ch.TimingMap, ch.Transcript = "", "", "", "", ""
+		ch.TTSStatus = "pending"
+	}
+	return refs
+}
+
+// bookAudioInUse reports whether a book other than bookID has path as its
+// whole-book audio. Errs on the side of keeping the file.
+func bookAudioInUse(path string, bookID uint) bool {
+	var n int64
+	if err := db.Model(&Book{}).Where("audio_path = ? AND id <> ?", path, bookID).Count(&n).Error; err != nil {
+		return true
+	}
+	return n > 0
+}
+
+// deleteBookAudioHandler (DELETE /user/books/:book_id/audio) drops a book's
+// generated audio to free space; the book and its text stay, and pages can
+// be transcribed again later.
+func deleteBookAudioHandler(c *gin.Context) {
+	// Ownership already verified by requireBookOwnership(); reuse the book.
+	book := c.MustGet("book").(Book)
+	if book.Status == "transcribing" {
+		respondError(c, http.StatusConflict, errCodeConflict, "Book is transcribing; try again when it finishes")
+		return
+	}
+
+	var chunks []BookChunk
+	db.Where("book_id = ?", book.ID).Find(&chunks)
+	var groups []ProcessedChunkGroup
+	db.Where("book_id = ?", book.ID).Find(&groups)
+	before := measureBookStorage(c.Request.Context(), book, chunks, groups)
+
+	bookAudio := book.AudioPath
+	refs := stripBookAudio(&book, chunks)
+	for _, g := range groups {
+		refs = append(refs, g.AudioPath)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&BookChunk{}).Where("book_id = ?", book.ID).Updates(map[string]interface{}{
+			"audio_path":       "",
+			"final_audio_path": "",
+			"hls_path":         "",
+			"timing_map":       "",
+			"transcript":       "",
+			"tts_status":       "pending",
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("book_id = ?", book.ID).Delete(&ProcessedChunkGroup{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Book{}).Where("id = ?", book.ID).Updates(map[string]interface{}{
+			"audio_path": "",
+			"status":     "pending",
+		}).Error
+	})
+	if err != nil {
+		respondInternal(c, "Failed to delete audio", err)
+		return
+	}
+
+	// Best-effort media cleanup after the rows no longer point at it.
+	// deleteStored skips shared/ renderings other books still use; whole-book
+	// audio reused by content hash (processBookConversion) is kept while
+	// another book still points at it. That audio can sit under this book's
+	// audio/{id}/ prefix, so the prefix sweep is skipped when it is kept.
+	kept := bookAudio != "" && bookAudioInUse(bookAudio, book.ID)
+	for _, r := range refs {
+		if kept && r == bookAudio {
+			continue
+		}
+		deleteStored(r)
+	}
+	freed := before.AudioBytes
+	if kept {
+		after := measureBookStorage(c.Request.Context(), Book{ID: book.ID, AudioPath: bookAudio}, nil, nil)
+		freed -= after.AudioBytes
+	} else if store != nil {
+		if _, err := store.DeletePrefix(context.Background(), fmt.Sprintf("audio/%d/", book.ID)); err != nil {
+			log.Printf("⚠️ audio prefix cleanup for book %d failed: %v", book.ID, err)
+		}
+	}
+	log.Printf("🧹 Dropped audio for book %d (%d bytes)", book.ID, freed)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Audio deleted",
+		"freed_bytes": freed,
+	})
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memStore is an in-memory MediaStore keyed by object key → size.
+type memStore map[string]int64
+
+func (m memStore) PutFile(ctx context.Context, key, localPath, contentType string) error {
+	fi, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+	m[key] = fi.Size()
+	return nil
+}
+func (m memStore) GetToFile(ctx context.Context, key, localPath string) error { return nil }
+func (m memStore) PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	return "", nil
+}
+func (m memStore) PresignPut(ctx context.Context, key string, ttl time.Duration, contentType string) (string, error) {
+	return "", nil
+}
+func (m memStore) Delete(ctx context.Context, key string) error { delete(m, key); return nil }
+func (m memStore) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	n := 0
+	for k := range m {
+		if strings.HasPrefix(k, prefix) {
+			delete(m, k)
+			n++
+		}
+	}
+	return n, nil
+}
+func (m memStore) PrefixSize(ctx context.Context, prefix string) (int64, int, error) {
+	var total int64
+	n := 0
+	for k, sz := range m {
+		if strings.HasPrefix(k, prefix) {
+			total += sz
+			n++
+		}
+	}
+	return total, n, nil
+}
+func (m memStore) Exists(ctx context.Context, key string) (bool, error) {
+	_, ok := m[key]
+	return ok, nil
+}
+func (m memStore) PublicURL(key string) string { return key }
+
+func TestMeasureBookStorage_MatchesSeededFiles(t *testing.T) {
+	orig := store
+	defer func() { store = orig }()
+	store = memStore{
+		"audio/7/page_0_aaaa.mp3":    1000,
+		"audio/7/hls/page_0/seg0.ts": 250,
+		"audio/70/page_0_other.mp3":  9999, // different book, same digit prefix
+		"shared/openai-r5/abcd.mp3":  5000, // shared dedup render: not the user's
+		"uploads/3/7/original.pdf":   4000,
+		"covers/7/cafebabe.jpg":      300,
+	}
+
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "book_7_page_1_legacy.mp3")
+	if err := os.WriteFile(legacy, make([]byte, 123), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	book := Book{ID: 7, UserID: 3, FilePath: "uploads/3/7/original.pdf", CoverPath: "covers/7/cafebabe.jpg"}
+	chunks := []BookChunk{
+		{AudioPath: "audio/7/page_0_aaaa.mp3", FinalAudioPath: "audio/7/page_0_aaaa.mp3"},
+		{AudioPath: legacy, FinalAudioPath: legacy},
+		{AudioPath: "shared/openai-r5/abcd.mp3", FinalAudioPath: "shared/openai-r5/abcd.mp3"},
+	}
+	got := measureBookStorage(context.Background(), book, chunks, nil)
+
+	if got.AudioBytes != 1000+250+123 || got.AudioFiles != 3 {
+		t.Errorf("audio = %d bytes / %d files, want 1373 / 3", got.AudioBytes, got.AudioFiles)
+	}
+	if got.UploadBytes != 4000 || got.CoverBytes != 300 {
+		t.Errorf("upload = %d, cover = %d", got.UploadBytes, got.CoverBytes)
+	}
+	if got.TotalBytes != 1373+4000+300 {
+		t.Errorf("total = %d, want %d", got.TotalBytes, 1373+4000+300)
+	}
+}
+
+func TestStripBookAudio_ClearsAudioKeepsText(t *testing.T) {
+	book := Book{ID: 7, AudioPath: "audio/7/book.mp3", FilePath: "uploads/3/7/original.pdf"}
+	chunks := []BookChunk{
+		{Content: "Page one.", AudioPath: "audio/7/p0.mp3", FinalAudioPath: "audio/7/p0_final.mp3", HLSPath: "audio/7/hls/0.m3u8", TTSStatus: "completed"},
+		{Content: "Page two.", AudioPath: "shared/x.mp3", FinalAudioPath: "shared/x.mp3", TTSStatus: "completed"},
+	}
+	refs := stripBookAudio(&book, chunks)
+
+	if book.AudioPath != "" || book.FilePath == "" {
+		t.Errorf("book: AudioPath %q, FilePath %q", book.AudioPath, book.FilePath)
+	}
+	for i, ch := range chunks {
+		if ch.AudioPath != "" || ch.FinalAudioPath != "" || ch.HLSPath != "" || ch.TTSStatus != "pending" {
+			t.Errorf("chunk %d not cleared: %+v", i, ch)
+		}
+		if ch.Content == "" {
+			t.Errorf("chunk %d lost its text", i)
+		}
+	}
+	want := []string{"audio/7/book.mp3", "audio/7/p0.mp3", "audio/7/p0_final.mp3", "shared/x.mp3", "shared/x.mp3"}
+	if strings.Join(refs, ",") != strings.Join(want, ",") {
+		t.Errorf("refs = %v, want %v", refs, want)
+	}
+}
+
+// Whole-book audio reused from another book by content hash stays while that
+// book still points at it.
+func TestDeleteBookAudio_KeepsAudioSharedWithAnotherBook(t *testing.T) {
+	withTestDB(t)
+	ms := memStore{}
+	origStore := store
+	store = ms
+	t.Cleanup(func() { store = origStore })
+
+	donor := Book{Title: "Donor", Category: "Fiction", UserID: 1, Status: "TTS completed"}
+	db.Create(&donor)
+	shared := bookAudioKey(donor.ID)
+	hls := fmt.Sprintf("audio/%d/1/hls/seg0.ts", donor.ID)
+	ms[shared], ms[hls] = 100, 7
+	db.Model(&donor).Update("audio_path", shared)
+	reuser := Book{Title: "Reuser", Category: "Fiction", UserID: 2, Status: "TTS reused", AudioPath: shared}
+	db.Create(&reuser)
+
+	gin.SetMode(gin.TestMode)
+	del := func(book Book) (int, int64) {
+		r := gin.New()
+		r.DELETE("/books/:book_id/audio", func(c *gin.Context) { c.Set("book", book) }, deleteBookAudioHandler)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/books/%d/audio", book.ID), nil))
+		var body struct {
+			FreedBytes int64 `json:"freed_bytes"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body.FreedBytes
+	}
+
+	// The donor drops its audio first: the prefix sweep must not take the
+	// file the reuser still plays, nor count it as freed.
+	if code, freed := del(donor); code != http.StatusOK || freed != 0 {
+		t.Fatalf("delete donor audio = %d, freed %d; want 200, 0", code, freed)
+	}
+	if _, ok := ms[shared]; !ok {
+		t.Fatal("shared audio deleted while another book still uses it")
+	}
+	if code, _ := del(reuser); code != http.StatusOK {
+		t.Fatalf("delete reuser audio = %d", code)
+	}
+	if _, ok := ms[shared]; ok {
+		t.Error("audio kept after its last book dropped it")
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Voice catalog. GET /voices lists the OpenAI voices (openai_voices.go) for a
+// client's voice picker, with the kind of character each suits and a link to
+// a sample clip. GET /voices/:voice/sample renders that clip on its first
+// request and keeps it on disk: every listener hears the same sentence, so
+// each voice is synthesized once (again only if the sample text or model
+// changes). Both are public, like /categories.
+
+// errTTSNotConfigured means the engine has no API key.
+var errTTSNotConfigured = errors.New("TTS is not configured")
+
+// voiceSampleText is what every sample reads.
+const voiceSampleText = "It was a quiet evening when the letter finally arrived. She turned it over twice, then opened it with trembling hands."
+
+// voiceSampleDir holds rendered samples; swappable in tests.
+var voiceSampleDir = "./audio/voice_samples"
+
+// voiceSampleSynthesize renders one sample to path; swappable in tests.
+var voiceSampleSynthesize = streamSpeech
+
+// voiceTrait describes who a voice suits.
+type voiceTrait struct {
+	Gender      string // "male", "female" or "neutral"
+	Age         string // character age it suits
+	Description string
+}
+
+// voiceTraits follows the casting pools: male and female pool voices by
+// gender, the narrator and unknown-gender pool as neutral.
+var voiceTraits = map[Voice]voiceTrait{
+	VoiceAlloy:   {"neutral", "adult", "Balanced and even; the default narrator"},
+	VoiceAsh:     {"male", "young adult", "Warm and conversational"},
+	VoiceBallad:  {"neutral", "adult", "Soft and lyrical"},
+	VoiceCoral:   {"female", "young adult", "Bright and friendly"},
+	VoiceEcho:    {"male", "adult", "Clear and steady"},
+	VoiceFable:   {"neutral", "adult", "Expressive storyteller"},
+	VoiceNova:    {"female", "young adult", "Lively and bright"},
+	VoiceOnyx:    {"male", "mature", "Deep and authoritative"},
+	VoiceSage:    {"neutral", "mature", "Calm and measured"},
+	VoiceShimmer: {"female", "adult", "Gentle and soft"},
+	VoiceVerse:   {"neutral", "adult", "Versatile and animated"},
+}
+
+// voiceInfo is one entry of GET /voices.
+type voiceInfo struct {
+	ID          string `json:"id"`
+	Gender      string `json:"gender"` // "" for voices added via OPENAI_TTS_EXTRA_VOICES
+	Age         string `json:"age"`
+	Description string `json:"description"`
+	Narrator    bool   `json:"narrator"` // the engine's default narrator
+	SampleURL   string `json:"sample_url"`
+}
+
+// voiceCatalog lists every voice the OpenAI engine accepts.
+func voiceCatalog() []voiceInfo {
+	host := getEnv("STREAM_HOST", "https://narrafied.com")
+	out := make([]voiceInfo, 0, len(engineVoices(&openaiEngine)))
+	for _, id := range engineVoices(&openaiEngine) {
+		tr := voiceTraits[Voice(id)]
+		out = append(out, voiceInfo{
+			ID:          id,
+			Gender:      tr.Gender,
+			Age:         tr.Age,
+			Description: tr.Description,
+			Narrator:    id == openaiEngine.NarratorVoice,
+			SampleURL:   host + "/voices/" + id + "/sample",
+		})
+	}
+	return out
+}
+
+// listVoicesHandler returns the voice catalog.
+// GET /voices
+func listVoicesHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"engine": openaiEngine.Name, "voices": voiceCatalog()})
+}
+
+// voiceSamplePath names voice's sample for the current text and model.
+func voiceSamplePath(voice string) string {
+	sum := sha256.Sum256([]byte(openaiEngine.Model + "\x00" + voiceSampleText))
+	return filepath.Join(voiceSampleDir, fmt.Sprintf("sample_%s_%x.mp3", voice, sum[:4]))
+}
+
+// voiceSampleLocks keeps concurrent first requests for one voice from
+// rendering it twice.
+var (
+	voiceSampleMu    sync.Mutex
+	voiceSampleLocks = map[string]*sync.Mutex{}
+)
+
+func voiceSampleLock(voice string) *sync.Mutex {
+	voiceSampleMu.Lock()
+	defer voiceSampleMu.Unlock()
+	if voiceSampleLocks[voice] == nil {
+		voiceSampleLocks[voice] = &sync.Mutex{}
+	}
+	return voiceSampleLocks[voice]
+}
+
+// ensureVoiceSample returns the path of voice's sample, rendering it if it
+// isn't on disk yet; rendered reports whether this call made it.
+func ensureVoiceSample(ctx context.Context, voice string) (path string, rendered bool, err error) {
+	path = voiceSamplePath(voice)
+	if info, err := os.Stat(path); err == nil && info.Size() > 0 {
+		return path, false, nil
+	}
+	mu := voiceSampleLock(voice)
+	mu.Lock()
+	defer mu.Unlock()
+	if info, err := os.Stat(path); err == nil && info.Size() > 0 {
+		return path, false, nil // rendered while we waited
+	}
+	apiKey := openaiEngine.APIKey()
+	if apiKey == "" {
+		return "", false, errTTSNotConfigured
+	}
+	if err := os.MkdirAll(voiceSampleDir, 0755); err != nil {
+		return "", false, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	if err := voiceSampleSynthesize(ctx, &openaiEngine, apiKey, voiceSampleText, voice, narratorInstructions, 1.0, DialogueSegment{Type: "narrator"}, path, io.Discard); err != nil {
+		return "", false, err
+	}
+	log.Printf("🎙️ Rendered voice sample for %s", voice)
+	return path, true, nil
+}
+
+// voiceSampleHandler serves a voice's sample clip, rendering it on first use.
+// GET /voices/:voice/sample
+func voiceSampleHandler(c *gin.Context) {
+	voice := c.Param("voice")
+	if !engineHasVoice(&openaiEngine, voice) {
+		respondError(c, http.StatusNotFound, errCodeNotFound, "Unknown voice")
+		return
+	}
+	path, rendered, err := ensureVoiceSample(c.Request.Context(), voice)
+	if errors.Is(err, errTTSNotConfigured) {
+		respondError(c, http.StatusServiceUnavailable, errCodeServiceUnavailable, openaiEngine.Name+" TTS is not configured")
+		return
+	}
+	if err != nil {
+		log.Printf("⚠️ voice sample for %s failed: %v", voice, err)
+		respondError(c, http.StatusBadGateway, errCodeUpstream, "Could not render voice sample")
+		return
+	}
+	if rendered {
+		c.Header("X-Sample-Cache", "miss")
+	} else {
+		c.Header("X-Sample-Cache", "hit")
+	}
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.File(path)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func voiceCatalogRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/voices", listVoicesHandler)
+	r.GET("/voices/:voice/sample", voiceSampleHandler)
+	return r
+}
+
+func TestListVoices_AllVoices(t *testing.T) {
+	t.Setenv("STREAM_HOST", "https://example.test")
+	w := httptest.NewRecorder()
+	voiceCatalogRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/voices", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d", w.Code)
+	}
+	var resp struct {
+		Voices []voiceInfo `json:"voices"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	listed := map[string]voiceInfo{}
+	for _, v := range resp.Voices {
+		listed[v.ID] = v
+	}
+	for _, v := range openaiVoices {
+		info, ok := listed[string(v)]
+		if !ok {
+			t.Errorf("voice %q not listed", v)
+			continue
+		}
+		if info.Gender == "" || info.Age == "" || info.SampleURL != "https://example.test/voices/"+string(v)+"/sample" {
+			t.Errorf("incomplete entry %+v", info)
+		}
+	}
+	if !listed[VoiceNarrator].Narrator {
+		t.Errorf("narrator %q not flagged", VoiceNarrator)
+	}
+	// Metadata agrees with how the pools cast.
+	for _, v := range maleVoicePool {
+		if listed[v].Gender != "male" {
+			t.Errorf("%s in the male pool but listed %q", v, listed[v].Gender)
+		}
+	}
+	for _, v := range femaleVoicePool {
+		if listed[v].Gender != "female" {
+			t.Errorf("%s in the female pool but listed %q", v, listed[v].Gender)
+		}
+	}
+}
+
+func TestVoiceSample_RenderedOnceThenCached(t *testing.T) {
+	var renders atomic.Int32
+	origDir, origSynth := voiceSampleDir, voiceSampleSynthesize
+	voiceSampleDir = t.TempDir()
+	voiceSampleSynthesize = func(_ context.Context, _ *ttsEngineConfig, _, text, voice, _ string, _ float64, _ DialogueSegment, path string, _ io.Writer) error {
+		renders.Add(1)
+		return os.WriteFile(path, []byte("ID3 "+voice), 0644)
+	}
+	t.Cleanup(func() { voiceSampleDir, voiceSampleSynthesize = origDir, origSynth })
+	t.Setenv("OPENAI_API_KEY", "test")
+
+	r := voiceCatalogRouter()
+	for i, want := range []string{"miss", "hit"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/voices/nova/sample", nil))
+		if w.Code != http.StatusOK || w.Body.String() != "ID3 nova" {
+			t.Fatalf("request %d: status %d body %q", i, w.Code, w.Body)
+		}
+		if got := w.Header().Get("X-Sample-Cache"); got != want {
+			t.Errorf("request %d: cache %q, want %q", i, got, want)
+		}
+	}
+	if renders.Load() != 1 {
+		t.Errorf("rendered %d times, want 1", renders.Load())
+	}
+	if _, err := os.Stat(voiceSamplePath("nova")); err != nil {
+		t.Errorf("sample not cached on disk: %v", err)
+	}
+}
+
+func TestVoiceSample_UnknownVoice(t *testing.T) {
+	w := httptest.NewRecorder()
+	voiceCatalogRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/voices/bm_george/sample", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status %d, want 404", w.Code)
+	}
+}
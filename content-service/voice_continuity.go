@@ -24,22 +24,23 @@ type CharacterVoice struct {
 	Voice  string `json:"voice"`  // OpenAI TTS voice id
 }
 
-// Voice pools (gpt-4o-mini-tts voices). Narrator stays on VoiceNarrator; the
-// pools deliberately exclude it so characters never share the narrator's voice.
+// Voice pools (gpt-4o-mini-tts voices, openai_voices.go). Narrator stays on
+// VoiceNarrator; the pools deliberately exclude it so characters never share
+// the narrator's voice.
 var (
-	maleVoicePool   = []string{"onyx", "echo", "ash"}
-	femaleVoicePool = []string{"nova", "shimmer", "coral"}
+	maleVoicePool   = voiceIDs(VoiceOnyx, VoiceEcho, VoiceAsh)
+	femaleVoicePool = voiceIDs(VoiceNova, VoiceShimmer, VoiceCoral)
 	// unknownVoicePool serves NAMED characters whose gender the model can't
 	// determine (God, the Serpent, "the voice"). Without it they all collapsed
 	// onto the single fallback voice and sounded identical in the same scene.
 	// Uses the gpt-4o-mini-tts voices unused by narrator/male/female pools.
-	unknownVoicePool = []string{"fable", "verse", "ballad", "sage"}
+	unknownVoicePool = voiceIDs(VoiceFable, VoiceVerse, VoiceBallad, VoiceSage)
 )
 
 // unknownDialogueVoice is used for dialogue with NO attributable speaker —
 // distinct from the narrator so conversations don't collapse into narration
 // (the old behavior sent unknown-gender dialogue to the narrator voice).
-const unknownDialogueVoice = "fable"
+const unknownDialogueVoice = string(VoiceFable)
 
 // normalizeSpeaker canonicalizes a speaker name for map keys.
 func normalizeSpeaker(name string) string {
@@ -138,6 +139,16 @@ func assignSegmentVoices(vm map[string]CharacterVoice, segments []DialogueSegmen
 			continue
 		}
 		cv, ok := vm[key]
+		if ok && !engineHasVoice(cfg, cv.Voice) {
+			// A stored voice this engine can't render (hand-edited cast, or
+			// one from another engine) would fail every line; recast it,
+			// keeping the cast gender.
+			log.Printf("⚠️ [VoiceMap] %q has voice %q, unknown to %s — recasting", s.Speaker, cv.Voice, cfg.Name)
+			delete(vm, key)
+			cv.Voice = pickVoice(vm, cv.Gender, cfg)
+			vm[key] = cv
+			changed = true
+		}
 		if !ok {
 			cv = CharacterVoice{
 				Gender: strings.ToLower(strings.TrimSpace(s.Gender)),
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// circuitBreaker stops forwarding to an upstream that keeps failing. After
+// threshold consecutive failures (5xx or no response) within window it opens
+// and requests are answered 503 without touching the upstream. Once cooldown
+// has passed it lets a single probe request through (half-open): success
+// closes it, failure reopens it for another cooldown.
+type circuitBreaker struct {
+	name      string
+	threshold int // 0 disables the breaker
+	window    time.Duration
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu           sync.Mutex
+	failures     int
+	firstFailure time.Time
+	openedAt     time.Time
+	open         bool
+	probing      bool
+}
+
+// newCircuitBreaker configures a breaker from BREAKER_FAILURE_THRESHOLD
+// (default 5), BREAKER_WINDOW_SECONDS (30) and BREAKER_COOLDOWN_SECONDS (15).
+func newCircuitBreaker(name string) *circuitBreaker {
+	return &circuitBreaker{
+		name:      name,
+		threshold: envInt("BREAKER_FAILURE_THRESHOLD", 5),
+		window:    time.Duration(envInt("BREAKER_WINDOW_SECONDS", 30)) * time.Second,
+		cooldown:  time.Duration(envInt("BREAKER_COOLDOWN_SECONDS", 15)) * time.Second,
+		now:       time.Now,
+	}
+}
+
+// allow reports whether a request may go upstream and, if not, how long
+// until the next probe. A true result must be followed by done.
+func (b *circuitBreaker) allow() (bool, time.Duration) {
+	if b.threshold <= 0 {
+		return true, 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return true, 0
+	}
+	if wait := b.cooldown - b.now().Sub(b.openedAt); wait > 0 {
+		return false, wait
+	}
+	if b.probing {
+		return false, b.cooldown
+	}
+	b.probing = true
+	return true, 0
+}
+
+// done records a forwarded request's outcome. counted is false when the
+// result says nothing about the upstream (the client went away).
+func (b *circuitBreaker) done(failed, counted bool) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wasProbe := b.probing
+	b.probing = false
+	if !counted {
+		return
+	}
+	now := b.now()
+	switch {
+	case !failed:
+		b.open, b.failures = false, 0
+	case wasProbe:
+		b.openedAt = now
+	default:
+		if b.failures == 0 || now.Sub(b.firstFailure) > b.window {
+			b.failures, b.firstFailure = 0, now
+		}
+		b.failures++
+		if b.failures >= b.threshold && !b.open {
+			b.open, b.openedAt = true, now
+		}
+	}
+}
+
+// breakerMiddleware guards the proxy handler after it with b.
+func breakerMiddleware(b *circuitBreaker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ok, wait := b.allow()
+		if !ok {
+			c.Header("Retry-After", strconv.Itoa(int(wait.Seconds()+0.999)))
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": b.name + " is temporarily unavailable, try again shortly"})
+			return
+		}
+		c.Next()
+		b.done(c.Writer.Status() >= http.StatusInternalServerError, c.Request.Context().Err() == nil)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCircuitBreaker_TripsAndRecovers(t *testing.T) {
+	var failing atomic.Bool
+	var hits atomic.Int32
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(up.Close)
+
+	clock := time.Now()
+	cb := &circuitBreaker{name: "content-service", threshold: 3, window: time.Minute, cooldown: 10 * time.Second,
+		now: func() time.Time { return clock }}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Any("/*proxyPath", breakerMiddleware(cb), wrapProxy(mustNewProxy(up.URL, "")))
+	gw := httptest.NewServer(r)
+	t.Cleanup(gw.Close)
+
+	get := func() int {
+		t.Helper()
+		resp, err := http.Get(gw.URL + "/user/books")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	failing.Store(true)
+	for i := 0; i < 3; i++ {
+		get()
+	}
+	if hits.Load() != 3 {
+		t.Fatalf("upstream hits = %d, want 3", hits.Load())
+	}
+
+	// Open: answered without reaching the upstream.
+	failing.Store(false)
+	if code := get(); code != http.StatusServiceUnavailable || hits.Load() != 3 {
+		t.Fatalf("open breaker: status %d, upstream hits %d", code, hits.Load())
+	}
+
+	// After the cooldown a probe goes through; its success closes the breaker.
+	clock = clock.Add(11 * time.Second)
+	if code := get(); code != http.StatusOK {
+		t.Fatalf("probe: status %d", code)
+	}
+	if code := get(); code != http.StatusOK || hits.Load() != 5 {
+		t.Errorf("closed breaker: status %d, upstream hits %d", code, hits.Load())
+	}
+}
+
+func TestCircuitBreaker_FailedProbeReopens(t *testing.T) {
+	clock := time.Now()
+	cb := &circuitBreaker{threshold: 2, window: time.Minute, cooldown: 10 * time.Second,
+		now: func() time.Time { return clock }}
+	for i := 0; i < 2; i++ {
+		cb.allow()
+		cb.done(true, true)
+	}
+	if ok, _ := cb.allow(); ok {
+		t.Fatal("breaker didn't open")
+	}
+
+	clock = clock.Add(11 * time.Second)
+	if ok, _ := cb.allow(); !ok {
+		t.Fatal("no probe after cooldown")
+	}
+	if ok, _ := cb.allow(); ok {
+		t.Error("second request let through while probing")
+	}
+	cb.done(true, true)
+	if ok, _ := cb.allow(); ok {
+		t.Error("failed probe didn't reopen the breaker")
+	}
+}
+
+func TestCircuitBreaker_FailuresOutsideWindow(t *testing.T) {
+	clock := time.Now()
+	cb := &circuitBreaker{threshold: 2, window: time.Minute, cooldown: 10 * time.Second,
+		now: func() time.Time { return clock }}
+	cb.done(true, true)
+	clock = clock.Add(2 * time.Minute)
+	cb.done(true, true)
+	if ok, _ := cb.allow(); !ok {
+		t.Error("failures two minutes apart tripped a one-minute window")
+	}
+}
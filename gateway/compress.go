@@ -0,0 +1,190 @@
+package main
+
+import (
+	"compress/gzip"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Response compression. The upstreams send JSON uncompressed, and book lists,
+// file trees and search results get large. gzipMiddleware compresses text and
+// JSON responses of at least GZIP_MIN_BYTES (default 1 KB) for clients that
+// accept gzip. Everything else — audio, images, archives, anything the
+// upstream already encoded, partial content — passes through untouched.
+// Bodies are buffered only until the threshold is reached, so large and
+// streamed responses aren't held in memory.
+
+var gzipWriterPool = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+
+// compressibleTypes are the media types worth compressing.
+var compressibleTypes = map[string]bool{
+	"application/json":       true,
+	"application/javascript": true,
+	"application/xml":        true,
+	"image/svg+xml":          true,
+}
+
+func compressibleType(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	// Event streams are excluded: buffering them would hold back events.
+	return (strings.HasPrefix(mt, "text/") && mt != "text/event-stream") || compressibleTypes[mt]
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if c := strings.TrimSpace(coding); c != "gzip" && c != "*" {
+			continue
+		}
+		q, found := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !found {
+			return true
+		}
+		v, err := strconv.ParseFloat(q, 64)
+		return err == nil && v > 0
+	}
+	return false
+}
+
+// gzipMiddleware compresses eligible responses.
+func gzipMiddleware() gin.HandlerFunc {
+	min := envInt("GZIP_MIN_BYTES", 1024)
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead || !acceptsGzip(c.GetHeader("Accept-Encoding")) {
+			c.Next()
+			return
+		}
+		w := &gzipResponseWriter{ResponseWriter: c.Writer, min: min}
+		c.Writer = w
+		defer w.finish()
+		c.Next()
+	}
+}
+
+// gzipResponseWriter holds back the start of a response until it knows
+// whether to compress it: at once from the headers when they decide it,
+// otherwise once min bytes have arrived or the response ends.
+type gzipResponseWriter struct {
+	gin.ResponseWriter
+	min     int
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer // set once the response is being compressed
+}
+
+// eligible reports whether the headers allow compressing the response at
+// all; sized is true when they also say it's big enough.
+func (w *gzipResponseWriter) eligible() (ok, sized bool) {
+	h := w.Header()
+	switch s := w.Status(); {
+	case s < 200, s == http.StatusNoContent, s == http.StatusPartialContent, s == http.StatusNotModified:
+		return false, false
+	}
+	if h.Get("Content-Encoding") != "" || !compressibleType(h.Get("Content-Type")) {
+		return false, false
+	}
+	if cl := h.Get("Content-Length"); cl != "" {
+		n, err := strconv.Atoi(cl)
+		return err == nil && n >= w.min, true
+	}
+	return true, false
+}
+
+func (w *gzipResponseWriter) decide(compress bool) error {
+	w.decided = true
+	h := w.Header()
+	if compressibleType(h.Get("Content-Type")) {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	if compress {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", "gzip")
+		w.gz = gzipWriterPool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.write(buf)
+	return err
+}
+
+func (w *gzipResponseWriter) write(p []byte) (int, error) {
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if w.decided {
+		return w.write(p)
+	}
+	ok, sized := w.eligible()
+	if !ok || sized {
+		if err := w.decide(ok); err != nil {
+			return 0, err
+		}
+		return w.write(p)
+	}
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= w.min {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *gzipResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow sends the headers; it settles an undecided response as
+// uncompressed, since the body can't be sized any more.
+func (w *gzipResponseWriter) WriteHeaderNow() {
+	if !w.decided {
+		_ = w.decide(false)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Flush pushes out what has been compressed so far. A response still under
+// the threshold stays buffered: flushing it would commit to not compressing.
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		return
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// finish writes out a response that stayed under the threshold and
+// terminates a compressed one.
+func (w *gzipResponseWriter) finish() {
+	if !w.decided {
+		if len(w.buf) > 0 {
+			_ = w.decide(false)
+		}
+		return
+	}
+	if w.gz != nil {
+		_ = w.gz.Close()
+		w.gz.Reset(nil)
+		gzipWriterPool.Put(w.gz)
+		w.gz = nil
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGzipMiddleware(t *testing.T) {
+	bigJSON := `{"books":[` + strings.Repeat(`{"title":"A Tale of Two Cities"},`, 200) + `{}]}`
+	audio := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x44}, 1000)
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/user/books":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			io.WriteString(w, bigJSON)
+		case "/user/small":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			io.WriteString(w, `{"ok":true}`)
+		case "/user/audio":
+			w.Header().Set("Content-Type", "audio/mpeg")
+			w.Write(audio)
+		}
+	}))
+	t.Cleanup(up.Close)
+	gw := httptest.NewServer(newRouter(slog.New(slog.NewTextHandler(io.Discard, nil)), "http://127.0.0.1:1", up.URL, "/content"))
+	t.Cleanup(gw.Close)
+
+	get := func(path, acceptEncoding string) (*http.Response, []byte) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, gw.URL+path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		resp, err := (&http.Client{Transport: &http.Transport{DisableCompression: true}}).Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, body
+	}
+
+	resp, body := get("/content/user/books", "gzip, deflate")
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("large JSON not gzipped: headers %v", resp.Header)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, _ := io.ReadAll(zr)
+	if string(plain) != bigJSON {
+		t.Error("gzipped body doesn't decompress to the upstream's")
+	}
+	if len(body) >= len(bigJSON) {
+		t.Errorf("gzipped %d bytes to %d", len(bigJSON), len(body))
+	}
+
+	resp, body = get("/content/user/audio", "gzip")
+	if resp.Header.Get("Content-Encoding") != "" || !bytes.Equal(body, audio) {
+		t.Errorf("audio altered: Content-Encoding %q, %d bytes", resp.Header.Get("Content-Encoding"), len(body))
+	}
+
+	resp, body = get("/content/user/small", "gzip")
+	if resp.Header.Get("Content-Encoding") != "" || string(body) != `{"ok":true}` {
+		t.Errorf("small JSON: Content-Encoding %q, body %q", resp.Header.Get("Content-Encoding"), body)
+	}
+
+	for _, ae := range []string{"", "identity", "gzip;q=0"} {
+		resp, body = get("/content/user/books", ae)
+		if resp.Header.Get("Content-Encoding") != "" || string(body) != bigJSON {
+			t.Errorf("Accept-Encoding %q: got Content-Encoding %q", ae, resp.Header.Get("Content-Encoding"))
+		}
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	cases := map[string]bool{
+		"gzip":                 true,
+		"deflate, gzip;q=0.8":  true,
+		"*":                    true,
+		"br":                   false,
+		"gzip;q=0":             false,
+		"gzip;q=0.0, identity": false,
+		"":                     false,
+	}
+	for in, want := range cases {
+		if got := acceptsGzip(in); got != want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
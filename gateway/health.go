@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// upstream is a backend probed by GET /health/services.
+type upstream struct {
+	Name string
+	URL  string
+}
+
+// serviceHealth is one upstream's entry in the /health/services response.
+type serviceHealth struct {
+	Status     string `json:"status"` // up or down
+	HTTPStatus int    `json:"http_status,omitempty"`
+	LatencyMS  int64  `json:"latency_ms"`
+	Error      string `json:"error,omitempty"` // timeout, unreachable or bad_status; details are logged, not returned
+}
+
+// servicesHealthResponse is the GET /health/services body.
+type servicesHealthResponse struct {
+	Status   string                   `json:"status"` // up, or degraded if any service is down
+	Services map[string]serviceHealth `json:"services"`
+}
+
+// probeUpstream GETs u's /health; anything but a 200 within ctx counts as
+// down. The detailed error is returned for logging only: it names internal
+// hosts and ports, and /health/services is unauthenticated.
+func probeUpstream(ctx context.Context, client *http.Client, u upstream) (serviceHealth, error) {
+	start := time.Now()
+	out := serviceHealth{Status: "down"}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.URL+"/health", nil)
+	if err == nil {
+		var resp *http.Response
+		if resp, err = client.Do(req); err == nil {
+			resp.Body.Close()
+			out.HTTPStatus = resp.StatusCode
+			if resp.StatusCode == http.StatusOK {
+				out.Status = "up"
+			} else {
+				out.Error = "bad_status"
+				err = fmt.Errorf("unexpected status %d", resp.StatusCode)
+			}
+		}
+	}
+	if err != nil && out.Error == "" {
+		out.Error = "unreachable"
+		var netErr net.Error
+		if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+			out.Error = "timeout"
+		}
+	}
+	out.LatencyMS = time.Since(start).Milliseconds()
+	return out, err
+}
+
+// servicesHealthHandler (GET /health/services) probes every upstream
+// concurrently, each bounded by timeout (HEALTH_PROBE_TIMEOUT_MS), and
+// answers 200 if all are up, else 503 with the per-service breakdown.
+func servicesHealthHandler(logger *slog.Logger, upstreams []upstream, timeout time.Duration) gin.HandlerFunc {
+	client := &http.Client{Timeout: timeout}
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		results := make(map[string]serviceHealth, len(upstreams))
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for _, u := range upstreams {
+			wg.Add(1)
+			go func(u upstream) {
+				defer wg.Done()
+				h, err := probeUpstream(ctx, client, u)
+				if err != nil {
+					logger.Warn("upstream health probe failed", "service", u.Name, "url", u.URL, "error", err)
+				}
+				mu.Lock()
+				results[u.Name] = h
+				mu.Unlock()
+			}(u)
+		}
+		wg.Wait()
+
+		out, code := servicesHealthResponse{Status: "up", Services: results}, http.StatusOK
+		for _, h := range results {
+			if h.Status != "up" {
+				out.Status, code = "degraded", http.StatusServiceUnavailable
+			}
+		}
+		c.JSON(code, out)
+	}
+}
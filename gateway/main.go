@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,36 +21,15 @@ import (
 
 func main() {
 	gin.SetMode(gin.ReleaseMode)
-	router := gin.New()
-
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
-	router.Use(requestIDMiddleware(), structuredLogger(logger), gin.Recovery(), bodyLimitMiddleware())
 
 	gatewayPort := getEnv("GATEWAY_PORT", "8080")
 	authSvcURL := getEnv("AUTH_SERVICE_URL", "http://auth-service:8082")
 	contentSvcURL := getEnv("CONTENT_SERVICE_URL", "http://content-service:8083")
+	contentPrefix := getEnv("CONTENT_PUBLIC_PREFIX", "/content")
+	router := newRouter(logger, authSvcURL, contentSvcURL, contentPrefix)
 
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "up"})
-	})
-
-	authProxy := mustNewProxy(authSvcURL)
-	contentProxy := mustNewProxy(contentSvcURL)
-
-	// Brute-force-sensitive auth endpoints get per-IP rate limiting.
-	authLimiter := newIPRateLimiter()
-	rl := rateLimitMiddleware(authLimiter)
-	router.Any("/signup", rl, wrapProxy(authProxy))
-	router.Any("/login", rl, wrapProxy(authProxy))
-	router.Any("/auth/*proxyPath", rl, wrapProxy(authProxy))
-
-	// Stripe webhook must NOT be rate limited (legitimate bursts on retries).
-	router.POST("/stripe/webhook", wrapProxy(authProxy))
-
-	router.Any("/content/*proxyPath", wrapProxy(contentProxy))
-	router.Any("/admin/*proxyPath", wrapProxy(contentProxy))
-
-	logger.Info("gateway listening", "port", gatewayPort, "auth", authSvcURL, "content", contentSvcURL)
+	logger.Info("gateway listening", "port", gatewayPort, "auth", authSvcURL, "content", contentSvcURL, "content_prefix", contentPrefix)
 
 	srv := &http.Server{
 		Addr:              ":" + gatewayPort,
@@ -63,14 +43,71 @@ func main() {
 	}
 }
 
+// newRouter wires the gateway routes. content-service's API is published
+// under contentPrefix (CONTENT_PUBLIC_PREFIX, default /content), which is
+// stripped before forwarding because content-service mounts its routes at the
+// root (/user, /admin, ...): /content/user/books reaches it as /user/books.
+// /admin/* is forwarded as-is.
+func newRouter(logger *slog.Logger, authSvcURL, contentSvcURL, contentPrefix string) *gin.Engine {
+	if !strings.HasPrefix(contentPrefix, "/") || strings.HasSuffix(contentPrefix, "/") {
+		log.Fatalf("CONTENT_PUBLIC_PREFIX %q must start with / and not end with one", contentPrefix)
+	}
+	router := gin.New()
+	router.Use(requestIDMiddleware(), structuredLogger(logger), gin.Recovery(), bodyLimitMiddleware(), gzipMiddleware())
+
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "up"})
+	})
+	probeTimeout := time.Duration(envInt("HEALTH_PROBE_TIMEOUT_MS", 2000)) * time.Millisecond
+	router.GET("/health/services", servicesHealthHandler(logger, []upstream{
+		{Name: "auth-service", URL: authSvcURL},
+		{Name: "content-service", URL: contentSvcURL},
+	}, probeTimeout))
+
+	authProxy := mustNewProxy(authSvcURL, "")
+	contentProxy := mustNewProxy(contentSvcURL, contentPrefix)
+	adminProxy := mustNewProxy(contentSvcURL, "")
+
+	// Each upstream gets a circuit breaker so a failing service is answered
+	// 503 fast instead of piling on more load.
+	authBreaker := breakerMiddleware(newCircuitBreaker("auth-service"))
+	contentBreaker := breakerMiddleware(newCircuitBreaker("content-service"))
+
+	// Brute-force-sensitive auth endpoints get per-IP rate limiting.
+	authLimiter := newIPRateLimiter()
+	rl := rateLimitMiddleware(authLimiter)
+	router.Any("/signup", rl, authBreaker, wrapProxy(authProxy))
+	router.Any("/login", rl, authBreaker, wrapProxy(authProxy))
+	router.Any("/auth/*proxyPath", rl, authBreaker, wrapProxy(authProxy))
+
+	// Stripe webhook must NOT be rate limited (legitimate bursts on retries).
+	router.POST("/stripe/webhook", authBreaker, wrapProxy(authProxy))
+
+	router.Any(contentPrefix+"/*proxyPath", contentBreaker, wrapProxy(contentProxy))
+	router.Any("/admin/*proxyPath", contentBreaker, wrapProxy(adminProxy))
+	return router
+}
+
 // mustNewProxy parses targetURL and returns a ReverseProxy (with bounded
-// transport timeouts) or exits.
-func mustNewProxy(targetURL string) *httputil.ReverseProxy {
+// transport timeouts) or exits. A non-empty stripPrefix is removed from the
+// request path before it is forwarded, and passed on as X-Forwarded-Prefix.
+func mustNewProxy(targetURL, stripPrefix string) *httputil.ReverseProxy {
 	u, err := url.Parse(targetURL)
 	if err != nil {
 		log.Fatalf("bad proxy URL %q: %v", targetURL, err)
 	}
 	p := httputil.NewSingleHostReverseProxy(u)
+	if stripPrefix != "" {
+		director := p.Director
+		p.Director = func(r *http.Request) {
+			r.URL.Path = stripPathPrefix(r.URL.Path, stripPrefix)
+			if r.URL.RawPath != "" {
+				r.URL.RawPath = stripPathPrefix(r.URL.RawPath, stripPrefix)
+			}
+			r.Header.Set("X-Forwarded-Prefix", stripPrefix)
+			director(r)
+		}
+	}
 	p.Transport = &http.Transport{
 		DialContext:           (&net.Dialer{Timeout: 5 * time.Second}).DialContext,
 		ResponseHeaderTimeout: 30 * time.Second,
@@ -81,6 +118,21 @@ func mustNewProxy(targetURL string) *httputil.ReverseProxy {
 	return p
 }
 
+// stripPathPrefix removes prefix from path at a segment boundary
+// ("/content/x" → "/x", "/content" → "/"); other paths pass unchanged.
+func stripPathPrefix(path, prefix string) string {
+	rest, ok := strings.CutPrefix(path, prefix)
+	switch {
+	case !ok:
+		return path
+	case rest == "":
+		return "/"
+	case rest[0] != '/':
+		return path // "/contentious" isn't under "/content"
+	}
+	return rest
+}
+
 // wrapProxy delegates to the given proxy, forwarding the request ID upstream.
 func wrapProxy(p *httputil.ReverseProxy) gin.HandlerFunc {
 	return func(c *gin.Context) {
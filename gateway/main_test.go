@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// upstreamRecorder is a backend that records the request URI it received.
+func upstreamRecorder(t *testing.T) (*httptest.Server, *string) {
+	t.Helper()
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.RequestURI()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &got
+}
+
+func TestContentPrefixStripped(t *testing.T) {
+	content, got := upstreamRecorder(t)
+	// Served for real: the reverse proxy needs a CloseNotifier, which
+	// httptest.ResponseRecorder isn't.
+	gw := httptest.NewServer(newRouter(slog.New(slog.NewTextHandler(io.Discard, nil)), "http://127.0.0.1:1", content.URL, "/content"))
+	t.Cleanup(gw.Close)
+
+	cases := map[string]string{
+		"/content/user/books":        "/user/books",
+		"/content/user/books?page=2": "/user/books?page=2",
+		"/content/":                  "/",
+		"/admin/users":               "/admin/users",
+	}
+	for in, want := range cases {
+		*got = ""
+		resp, err := http.Get(gw.URL + in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: status %d", in, resp.StatusCode)
+		}
+		if *got != want {
+			t.Errorf("%s forwarded as %q, want %q", in, *got, want)
+		}
+	}
+}
+
+func TestStripPathPrefix(t *testing.T) {
+	cases := map[string]string{
+		"/content/user/books": "/user/books",
+		"/content":            "/",
+		"/contentious":        "/contentious",
+		"/user/books":         "/user/books",
+	}
+	for in, want := range cases {
+		if got := stripPathPrefix(in, "/content"); got != want {
+			t.Errorf("stripPathPrefix(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestServicesHealth(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(healthy.Close)
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(unhealthy.Close)
+
+	get := func(auth, content string) (int, servicesHealthResponse) {
+		t.Helper()
+		gw := httptest.NewServer(newRouter(slog.New(slog.NewTextHandler(io.Discard, nil)), auth, content, "/content"))
+		defer gw.Close()
+		resp, err := http.Get(gw.URL + "/health/services")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body servicesHealthResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, body
+	}
+
+	code, body := get(healthy.URL, healthy.URL)
+	if code != http.StatusOK || body.Status != "up" {
+		t.Errorf("all healthy: %d %+v", code, body)
+	}
+
+	code, body = get(healthy.URL, unhealthy.URL)
+	if code != http.StatusServiceUnavailable || body.Status != "degraded" {
+		t.Errorf("one unhealthy: %d %+v", code, body)
+	}
+	if h := body.Services["auth-service"]; h.Status != "up" {
+		t.Errorf("auth-service = %+v, want up", h)
+	}
+	if h := body.Services["content-service"]; h.Status != "down" || h.HTTPStatus != http.StatusInternalServerError || h.Error != "bad_status" {
+		t.Errorf("content-service = %+v, want down with 500", h)
+	}
+
+	// A dial failure must not leak the upstream's address.
+	gone := httptest.NewServer(http.NotFoundHandler())
+	gone.Close()
+	code, body = get(healthy.URL, gone.URL)
+	h := body.Services["content-service"]
+	if code != http.StatusServiceUnavailable || h.Error != "unreachable" {
+		t.Errorf("unreachable content-service: %d %+v", code, h)
+	}
+	if host := strings.TrimPrefix(gone.URL, "http://"); strings.Contains(fmt.Sprint(body), host) {
+		t.Errorf("response leaks upstream address %s: %+v", host, body)
+	}
+}